@@ -22,6 +22,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/cmd/console"
 	"github.com/palletone/go-palletone/cmd/utils"
 	"github.com/palletone/go-palletone/common"
@@ -315,6 +316,52 @@ nodes.
 				Description: `
     gptn account dumppubkey <address>
     Dump the public key.
+`,
+			},
+			{
+				Name:      "signpst",
+				Usage:     "Sign a partially signed transaction offline",
+				Action:    utils.MigrateFlags(accountSignPartialSignedTx),
+				ArgsUsage: "<address> <pst>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    gptn account signpst <address> <pst>
+Sign all the inputs of a partially signed transaction which the account can sign,
+only the local keystore is needed, and print the new partially signed transaction.
+`,
+			},
+			{
+				Name:      "combinepst",
+				Usage:     "Combine the partially signed transactions signed by different signers",
+				Action:    utils.MigrateFlags(accountCombinePartialSignedTx),
+				ArgsUsage: "<pst> <pst> ...",
+				Description: `
+    gptn account combinepst <pst> <pst> ...
+Combine the signatures of several partially signed transactions of the same transaction.
+`,
+			},
+			{
+				Name:      "finalizepst",
+				Usage:     "Finalize a complete partially signed transaction",
+				Action:    utils.MigrateFlags(accountFinalizePartialSignedTx),
+				ArgsUsage: "<pst>",
+				Description: `
+    gptn account finalizepst <pst>
+Build the unlock scripts and print the signed transaction hex, which can be sent by sendRawTransaction.
+`,
+			},
+			{
+				Name:      "decodepst",
+				Usage:     "Show the content of a partially signed transaction",
+				Action:    utils.MigrateFlags(accountDecodePartialSignedTx),
+				ArgsUsage: "<pst>",
+				Description: `
+    gptn account decodepst <pst>
+Show the inputs, required signers and collected signatures of a partially signed transaction.
 `,
 			},
 		},
@@ -831,3 +878,106 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%s}\n", acct.Address)
 	return nil
 }
+
+func accountSignPartialSignedTx(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("usage: signpst <address> <pst>")
+	}
+	pst, err := tokenengine.DecodePartialSignedTx(ctx.Args()[1])
+	if err != nil {
+		utils.Fatalf("Invalid partially signed transaction:%s", err)
+	}
+	stack, _ := makeConfigNode(ctx, false)
+	ks := stack.GetKeyStore()
+	account, err := utils.MakeAddress(ks, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Invalid address:%s", err)
+	}
+	pwd := getPassPhrase("Please give a password to unlock your account", false, 0, utils.MakePasswordList(ctx))
+	prvKey, err := ks.DumpKey(account, pwd)
+	if err != nil {
+		utils.Fatalf("Unlock account error:%s", err)
+	}
+	pubKey, err := crypto.MyCryptoLib.PrivateKeyToPubKey(prvKey)
+	if err != nil {
+		utils.Fatalf("Get public key error:%s", err)
+	}
+	getPubKeyFn := func(addr common.Address) ([]byte, error) {
+		if addr != account.Address {
+			return nil, fmt.Errorf("not the sign address")
+		}
+		return pubKey, nil
+	}
+	getSignFn := func(addr common.Address, msg []byte) ([]byte, error) {
+		return crypto.MyCryptoLib.Sign(prvKey, msg)
+	}
+	signed, err := pst.Sign(getPubKeyFn, getSignFn)
+	if err != nil {
+		utils.Fatalf("Sign error:%s", err)
+	}
+	result, err := pst.EncodeToString()
+	if err != nil {
+		utils.Fatalf("Encode error:%s", err)
+	}
+	fmt.Printf("Signed %d inputs, complete:%t\n", signed, pst.IsComplete())
+	fmt.Println(result)
+	return nil
+}
+
+func accountCombinePartialSignedTx(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("usage: combinepst <pst> <pst> ...")
+	}
+	psts := []*tokenengine.PartialSignedTx{}
+	for _, arg := range ctx.Args() {
+		pst, err := tokenengine.DecodePartialSignedTx(arg)
+		if err != nil {
+			utils.Fatalf("Invalid partially signed transaction:%s", err)
+		}
+		psts = append(psts, pst)
+	}
+	combined, err := tokenengine.CombinePartialSignedTx(psts...)
+	if err != nil {
+		utils.Fatalf("Combine error:%s", err)
+	}
+	result, err := combined.EncodeToString()
+	if err != nil {
+		utils.Fatalf("Encode error:%s", err)
+	}
+	fmt.Printf("Complete:%t\n", combined.IsComplete())
+	fmt.Println(result)
+	return nil
+}
+
+func accountFinalizePartialSignedTx(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("usage: finalizepst <pst>")
+	}
+	pst, err := tokenengine.DecodePartialSignedTx(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Invalid partially signed transaction:%s", err)
+	}
+	tx, err := pst.Finalize()
+	if err != nil {
+		utils.Fatalf("Finalize error:%s", err)
+	}
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		utils.Fatalf("Encode error:%s", err)
+	}
+	fmt.Println(hex.EncodeToString(data))
+	return nil
+}
+
+func accountDecodePartialSignedTx(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("usage: decodepst <pst>")
+	}
+	pst, err := tokenengine.DecodePartialSignedTx(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Invalid partially signed transaction:%s", err)
+	}
+	data, _ := json.MarshalIndent(ptnjson.ConvertPartialSignedTx2Json(pst), "", "  ")
+	fmt.Println(string(data))
+	return nil
+}
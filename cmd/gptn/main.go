@@ -59,6 +59,7 @@ var (
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.ExternalSignerFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
@@ -157,12 +158,15 @@ func init() {
 		//importPreimagesCommand,
 		//exportPreimagesCommand,
 		copydbCommand,
+		snapshotCommand,
 		removedbCommand,
 		//dumpCommand,	//转储命令
 		// See monitorcmd.go:
 		// monitorCommand,
 		// See accountcmd.go:
 		accountCommand,
+		signerCommand,
+		txpoolCommand,
 		// walletCommand,
 		// See consolecmd.go:
 		consoleCommand, //js控制台命令
@@ -0,0 +1,101 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/palletone/go-palletone/cmd/utils"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/core/accounts/external"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signerPolicyFlag = cli.StringFlag{
+		Name:  "policy",
+		Usage: "Policy file of the signer (allowed recipients, daily limits)",
+	}
+
+	signerCommand = cli.Command{
+		Action:    utils.MigrateFlags(startSigner),
+		Name:      "signer",
+		Usage:     "Start an external signer process holding the account keys",
+		ArgsUsage: "<ipcpath> <address> [<address> ...]",
+		Category:  "ACCOUNT COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.KeyStoreDirFlag,
+			utils.PasswordFileFlag,
+			signerPolicyFlag,
+		},
+		Description: `
+    gptn signer --policy policy.json <ipcpath> <address> [<address> ...]
+
+Unlock the given accounts from the keystore and serve the signing requests of
+a node on the IPC endpoint, so the keys are kept out of the node process.
+Start the node with --signer <ipcpath> to use these accounts.
+
+Every request is checked by the policy file, for example:
+
+    {"allowed_recipients":["P1..."],"daily_limits":{"PTN":100000000000},"allow_unit_sign":true}
+
+The node sends the whole transaction of each input to sign. When recipients or
+daily limits are configured, only SIGHASH_ALL signatures are allowed.
+`,
+	}
+)
+
+func startSigner(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("usage: signer <ipcpath> <address> [<address> ...]")
+	}
+	policy := &external.Policy{}
+	if file := ctx.String(signerPolicyFlag.Name); file != "" {
+		var err error
+		if policy, err = external.LoadPolicy(file); err != nil {
+			utils.Fatalf("Load policy error: %v", err)
+		}
+	} else {
+		log.Warn("No policy file, all the sign requests except unit sign are allowed")
+	}
+
+	stack, _ := makeConfigNode(ctx, false)
+	ks := stack.GetKeyStore()
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range ctx.Args()[1:] {
+		unlockAccount(ks, addr, i, passwords)
+	}
+
+	endpoint := ctx.Args().First()
+	listener, _, err := external.StartSigner(endpoint, ks, policy)
+	if err != nil {
+		utils.Fatalf("Start signer error: %v", err)
+	}
+	log.Infof("Signer started on %s", endpoint)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Signer stopped")
+	return listener.Close()
+}
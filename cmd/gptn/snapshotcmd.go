@@ -0,0 +1,201 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/palletone/go-palletone/cmd/utils"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/ptndb"
+	dagcommon "github.com/palletone/go-palletone/dag/common"
+	"github.com/palletone/go-palletone/dag/dagconfig"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/dag/storage"
+	"github.com/palletone/go-palletone/tokenengine"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotUnitFlag = cli.StringFlag{
+		Name:  "unit",
+		Usage: "Hash of the trusted unit which commits the snapshot hash",
+	}
+	snapshotNoCheckFlag = cli.BoolFlag{
+		Name:  "nocheck",
+		Usage: "Import the snapshot without checking it is committed by a trusted unit",
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Export or import the snapshot of UTXO set and contract state",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+    Export the UTXO set and the contract state at the last committed snapshot point to
+a file, or bootstrap a new node from a snapshot file. Every ` + fmt.Sprint(modules.SnapshotInterval) + ` units the
+full nodes save a snapshot file, and the mediators commit the snapshot hash into
+the extra data of a unit header later. The imported snapshot is verified against
+the hash committed by the trusted unit given by --unit. The node must be stopped.
+`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(exportSnapshot),
+				Name:      "export",
+				Usage:     "Export the snapshot at the last committed snapshot point to a file",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Category: "BLOCKCHAIN COMMANDS",
+			},
+			{
+				Action:    utils.MigrateFlags(importSnapshot),
+				Name:      "import",
+				Usage:     "Bootstrap the initialized node from a snapshot file",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					snapshotUnitFlag,
+					snapshotNoCheckFlag,
+				},
+				Category: "BLOCKCHAIN COMMANDS",
+			},
+		},
+	}
+)
+
+func openSnapshotDb(ctx *cli.Context) ptndb.Database {
+	stack, _ := makeConfigNode(ctx, false)
+	db, err := stack.OpenDatabase(dagconfig.DagConfig.DbPath, 0, 0)
+	if err != nil {
+		utils.Fatalf("Open database error: %v", err)
+	}
+	return db
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	db := openSnapshotDb(ctx)
+	defer db.Close()
+
+	unitRep := dagcommon.NewUnitRepository4Db(db, tokenengine.Instance)
+	propdb := storage.NewPropertyDb(db)
+	gasToken := dagconfig.DagConfig.GetGasToken()
+	newest, err := propdb.GetNewestUnit(gasToken)
+	if err != nil {
+		utils.Fatalf("Get newest stable unit error: %v", err)
+	}
+	//数据库中的状态已经是最新稳定单元的状态，只能导出最近一个已经提交了快照Hash的快照点保存的快照文件
+	stable := newest.ChainIndex.Index
+	var commit *modules.Header
+	point := stable - stable%modules.SnapshotInterval
+	for ; point > 0; point -= modules.SnapshotInterval {
+		if modules.SnapshotCommitIndex(point) > stable {
+			continue
+		}
+		index := &modules.ChainIndex{AssetID: gasToken, Index: modules.SnapshotCommitIndex(point)}
+		header, err := unitRep.GetHeaderByNumber(index)
+		if err != nil {
+			utils.Fatalf("Get header #%d error: %v", index.Index, err)
+		}
+		if _, i, ok := header.GetSnapshotHash(); ok && i == point {
+			commit = header
+			break
+		}
+	}
+	if commit == nil {
+		utils.Fatalf("No snapshot point before the stable unit #%d is committed", stable)
+	}
+
+	path := ctx.Args().First()
+	f, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		utils.Fatalf("Create snapshot file error: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	src := storage.SnapshotFile(storage.SnapshotDir(dagconfig.DagConfig.DbPath), point)
+	snap, err := storage.ExportSnapshotFile(src, commit, w)
+	if err == nil {
+		if err = w.Flush(); err == nil {
+			err = f.Sync()
+		}
+	}
+	f.Close()
+	if err == nil {
+		err = os.Rename(path+".new", path)
+	}
+	if err != nil {
+		os.Remove(path + ".new")
+		utils.Fatalf("Export snapshot of unit #%d error: %v", point, err)
+	}
+	fmt.Printf("Exported snapshot of unit[%s] #%d, entries:%d, hash:%s\n", snap.Unit.Hash().String(),
+		snap.Unit.NumberU64(), snap.Count, snap.Hash.String())
+	fmt.Printf("The snapshot hash is committed by unit[%s] #%d\n", commit.Hash().String(), commit.NumberU64())
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	var trusted common.Hash
+	if str := ctx.String(snapshotUnitFlag.Name); str != "" {
+		if err := trusted.SetHexString(str); err != nil {
+			utils.Fatalf("Invalid unit hash %s: %v", str, err)
+		}
+	}
+	//文件中提交快照Hash的Header没有签名，必须由用户指定可信的单元，否则只能用--nocheck跳过校验
+	if common.EmptyHash(trusted) && !ctx.Bool(snapshotNoCheckFlag.Name) {
+		utils.Fatalf("The hash of the trusted unit which commits the snapshot is required, use --%s to set it, "+
+			"or use --%s to import the snapshot without check", snapshotUnitFlag.Name, snapshotNoCheckFlag.Name)
+	}
+	path := ctx.Args().First()
+
+	//先完整校验快照文件，再写入数据库
+	f, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Open snapshot file error: %v", err)
+	}
+	snap, err := storage.ReadSnapshot(bufio.NewReader(f), trusted)
+	f.Close()
+	if err != nil {
+		utils.Fatalf("Verify snapshot error: %v", err)
+	}
+	db := openSnapshotDb(ctx)
+	defer db.Close()
+	if f, err = os.Open(path); err != nil {
+		utils.Fatalf("Open snapshot file error: %v", err)
+	}
+	defer f.Close()
+	if snap, err = storage.ImportSnapshot(db, bufio.NewReader(f), trusted); err != nil {
+		utils.Fatalf("Import snapshot error: %v", err)
+	}
+	fmt.Printf("Imported snapshot of unit[%s] #%d, entries:%d, hash:%s\n", snap.Unit.Hash().String(),
+		snap.Unit.NumberU64(), snap.Count, snap.Hash.String())
+	if snap.Commit != nil {
+		fmt.Printf("The snapshot hash is committed by unit[%s] #%d\n", snap.Commit.Hash().String(),
+			snap.Commit.NumberU64())
+	}
+	return nil
+}
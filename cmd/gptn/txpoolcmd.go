@@ -0,0 +1,153 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/palletone/go-palletone/cmd/utils"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/txspool"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	txpoolCommand = cli.Command{
+		Name:     "txpool",
+		Usage:    "Manage the transaction pool journal",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+    Dump the journaled transactions of a node to a file, or load a dumped file
+into the journal of another node. The node must be stopped, the loaded
+transactions are replayed into the pool when the node starts.
+`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(dumpTxPool),
+				Name:      "dump",
+				Usage:     "Dump the journaled transactions to a file",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TxPoolJournalFlag,
+				},
+				Category: "MISCELLANEOUS COMMANDS",
+			},
+			{
+				Action:    utils.MigrateFlags(loadTxPool),
+				Name:      "load",
+				Usage:     "Load the transactions of a dump file into the journal",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TxPoolJournalFlag,
+				},
+				Category: "MISCELLANEOUS COMMANDS",
+			},
+		},
+	}
+)
+
+func txPoolJournalPath(ctx *cli.Context) string {
+	stack, cfg := makeConfigNode(ctx, false)
+	if cfg.TxPool.Journal == "" {
+		utils.Fatalf("The transaction journal is disabled")
+	}
+	return stack.ResolvePath(cfg.TxPool.Journal)
+}
+
+func readTxsFile(path string) ([]*modules.Transaction, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	return txspool.ReadTxs(f)
+}
+
+func writeTxsFile(path string, txs []*modules.Transaction) error {
+	f, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err = txspool.WriteTxs(w, txs); err == nil {
+		if err = w.Flush(); err == nil {
+			err = f.Sync()
+		}
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".new", path)
+}
+
+func dumpTxPool(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	journal := txPoolJournalPath(ctx)
+	txs, dropped, err := readTxsFile(journal)
+	if err != nil && len(txs) == 0 {
+		utils.Fatalf("Read journal %s error: %v", journal, err)
+	}
+	if err := writeTxsFile(ctx.Args().First(), txs); err != nil {
+		utils.Fatalf("Dump transactions error: %v", err)
+	}
+	fmt.Printf("Dumped %d transactions, dropped %d corrupted records\n", len(txs), dropped)
+	return nil
+}
+
+func loadTxPool(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	loaded, dropped, err := readTxsFile(ctx.Args().First())
+	if err != nil && len(loaded) == 0 {
+		utils.Fatalf("Read dump file error: %v", err)
+	}
+	journal := txPoolJournalPath(ctx)
+	txs, _, err := readTxsFile(journal)
+	if err != nil && !os.IsNotExist(err) {
+		utils.Fatalf("Read journal %s error: %v", journal, err)
+	}
+	known := make(map[common.Hash]bool)
+	for _, tx := range txs {
+		known[tx.Hash()] = true
+	}
+	added := 0
+	for _, tx := range loaded {
+		if known[tx.Hash()] {
+			continue
+		}
+		known[tx.Hash()] = true
+		txs = append(txs, tx)
+		added++
+	}
+	if err := writeTxsFile(journal, txs); err != nil {
+		utils.Fatalf("Write journal %s error: %v", journal, err)
+	}
+	fmt.Printf("Loaded %d transactions into %s, dropped %d corrupted records\n", added, journal, dropped)
+	return nil
+}
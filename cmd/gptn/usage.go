@@ -73,6 +73,7 @@ var AppHelpFlagGroups = []flagGroup{
 			ConfigFilePathFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.ExternalSignerFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.SyncModeFlag,
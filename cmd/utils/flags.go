@@ -123,6 +123,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer process (IPC path or url) holding the keys of accounts",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -210,12 +214,12 @@ var (
 	}
 	TxPoolJournalFlag = cli.StringFlag{
 		Name:  "txpool.journal",
-		Usage: "Disk journal for local transaction to survive node restarts",
+		Usage: "Disk journal for pool transactions to survive node restarts",
 		Value: txspool.DefaultTxPoolConfig.Journal,
 	}
 	TxPoolRejournalFlag = cli.DurationFlag{
 		Name:  "txpool.rejournal",
-		Usage: "Time interval to regenerate the local transaction journal",
+		Usage: "Time interval to regenerate the transaction journal",
 		Value: txspool.DefaultTxPoolConfig.Rejournal,
 	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
@@ -829,6 +833,10 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config, configDir string) string
 		cfg.KeyStoreDir = common.GetAbsPath(path)
 	}
 
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+
 	// if ctx.GlobalIsSet(LightKDFFlag.Name) {
 	// 	cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	// }
@@ -48,7 +48,7 @@ var DestroyAddress = Address{}
 
 // Address represents the 35 byte address of an PalletOne account.
 // for personal address, start with P1 (version 0), script address start with P3(version 5),
-// contract address start with Pc(version 28)
+// contract address start with Pc(version 28), taproot address start with PT(version 65)
 type Addresses []Address
 type Address [AddressLength]byte
 type AddressType byte
@@ -58,6 +58,7 @@ const (
 	PublicKeyHash AddressType = 0
 	ScriptHash    AddressType = 5
 	ContractHash  AddressType = 28
+	TaprootHash   AddressType = 65
 )
 
 func (a *Address) GetType() AddressType {
@@ -119,6 +120,8 @@ func StringToAddress(a string) (Address, error) {
 		return BytesToAddress(append(addrb, byte(ScriptHash))), nil
 	case 28:
 		return BytesToAddress(append(addrb, byte(ContractHash))), nil
+	case 65:
+		return BytesToAddress(append(addrb, byte(TaprootHash))), nil
 	default:
 		return Address{}, errors.New("Invalid address type")
 	}
@@ -17,9 +17,10 @@
 package ptndb
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
-	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -54,13 +55,13 @@ func (i *MemIterator) Next() bool {
 	return i.idx < len(i.result)
 }
 func (i *MemIterator) Key() []byte {
-	if i.idx == -1 {
+	if i.idx < 0 || i.idx >= len(i.result) {
 		return nil
 	}
 	return i.result[i.idx].Key
 }
 func (i *MemIterator) Value() []byte {
-	if i.idx == -1 {
+	if i.idx < 0 || i.idx >= len(i.result) {
 		return nil
 	}
 	return i.result[i.idx].Value
@@ -81,17 +82,11 @@ func (i *MemIterator) Last() bool {
 	// }
 	// return true
 }
+
+// Seek 与leveldb一致，移动到第一个不小于key的位置，按前缀创建的迭代器是按key排序的
 func (i *MemIterator) Seek(key []byte) bool {
-	if i.idx == -1 {
-		return false
-	}
-	for j := 0; j <= i.idx; j++ {
-		val := i.result[j]
-		if reflect.DeepEqual(key, val.Key) {
-			return true
-		}
-	}
-	return false
+	i.idx = sort.Search(len(i.result), func(j int) bool { return bytes.Compare(i.result[j].Key, key) >= 0 })
+	return i.idx < len(i.result)
 }
 func (i *MemIterator) Prev() bool {
 	return i.idx != -1
@@ -134,6 +129,8 @@ func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
 			result = append(result, kv)
 		}
 	}
+	// 与leveldb一致，按key的顺序遍历
+	sort.Slice(result, func(i, j int) bool { return string(result[i].Key) < string(result[j].Key) })
 	return &MemIterator{result: result, idx: -1}
 }
 func NewMemDatabase() (*MemDatabase, error) {
@@ -45,6 +45,15 @@ func TestMemDatabase_NewIteratorWithPrefix(t *testing.T) {
 	it2 := db.NewIteratorWithPrefix([]byte("x"))
 	assert.False(t, it2.Next())
 
+	//Seek定位到第一个不小于key的位置
+	it3 := db.NewIteratorWithPrefix([]byte("a"))
+	assert.True(t, it3.Seek([]byte("aa")))
+	assert.Equal(t, []byte("ab"), it3.Key())
+	assert.True(t, it3.Next())
+	assert.Equal(t, []byte("abc"), it3.Key())
+	assert.False(t, it3.Next())
+	assert.False(t, it3.Seek([]byte("b")))
+	assert.Nil(t, it3.Key())
 }
 func TestMemDatabase_NewIterator(t *testing.T) {
 	db, _ := NewMemDatabase()
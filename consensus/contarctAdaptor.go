@@ -3,6 +3,7 @@ package consensus
 import (
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/consensus/jury"
+	"github.com/palletone/go-palletone/dag/modules"
 	"time"
 )
 
@@ -22,3 +23,7 @@ func (a *AdapterJury) AdapterFunResult(reqId common.Hash, contractId common.Addr
 func (a *AdapterJury) GetLocalJuryAddrs() []common.Address {
 	return a.Processor.GetLocalJuryAddrs()
 }
+
+func (a *AdapterJury) DecryptPrivateState(ps *modules.PrivateState) ([]byte, error) {
+	return a.Processor.DecryptPrivateState(ps)
+}
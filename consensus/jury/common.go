@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -31,7 +32,10 @@ import (
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/crypto"
 	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
+	alg "github.com/palletone/go-palletone/consensus/jury/vrf/algorithm"
 	"github.com/palletone/go-palletone/contracts"
+	"github.com/palletone/go-palletone/contracts/syscontract"
 	"github.com/palletone/go-palletone/core"
 	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
@@ -68,12 +72,47 @@ func localIsMinSignature(tx *modules.Transaction) bool {
 	}
 	return false
 }
+
+//juryNeededSigNum 陪审团赎回脚本需要的签名数
+func juryNeededSigNum(jury *modules.ElectionNode) int {
+	return int(math.Ceil((float64(len(jury.EleList))*2 + 1) / 3))
+}
+
+//getJurySigNum 交易中属于该陪审团的陪审员签名数量
+func getJurySigNum(tx *modules.Transaction, jury *modules.ElectionNode) int {
+	num := 0
+	for _, addr := range tx.GetContractTxSignatureAddress() {
+		if jury.HasAddrHash(util.RlpHash(addr)) {
+			num++
+		}
+	}
+	return num
+}
+
+//contractSigEnough 签名数量满足系统要求，跨陪审团调用时调用合约和每个被调用合约的陪审团都要满足赎回脚本的签名数
+func (p *Processor) contractSigEnough(ctx *contractTx) bool {
+	if getTxSigNum(ctx.sigTx) < getSysCfgContractSignatureNum(p.dag) {
+		return false
+	}
+	if len(ctx.callees) == 0 {
+		return true
+	}
+	if ctx.eleNode == nil || getJurySigNum(ctx.sigTx, ctx.eleNode) < juryNeededSigNum(ctx.eleNode) {
+		return false
+	}
+	for _, callee := range ctx.callees {
+		if getJurySigNum(ctx.sigTx, callee.ele) < juryNeededSigNum(callee.ele) {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Processor) generateJuryRedeemScript(jury *modules.ElectionNode) []byte {
 	if jury == nil {
 		return nil
 	}
-	count := len(jury.EleList)
-	needed := byte(math.Ceil((float64(count)*2 + 1) / 3))
+	needed := byte(juryNeededSigNum(jury))
 	pubKeys := make([][]byte, 0, len(jury.EleList))
 	for _, ju := range jury.EleList {
 		juror, err := p.dag.GetJurorByAddrHash(ju.AddrHash)
@@ -86,28 +125,67 @@ func (p *Processor) generateJuryRedeemScript(jury *modules.ElectionNode) []byte
 	return tokenengine.Instance.GenerateRedeemScript(needed, pubKeys)
 }
 
-//对于Contract Payout的情况，将SignatureSet转移到Payment的解锁脚本中
-func (p *Processor) processContractPayout(tx *modules.Transaction, ele *modules.ElectionNode) {
+//对于Contract Payout的情况，将SignatureSet转移到Payment的解锁脚本中，
+//跨陪审团调用时被调用合约地址的付款使用其陪审团的赎回脚本，并保留SignaturePayload供验证各陪审团的签名
+func (p *Processor) processContractPayout(tx *modules.Transaction, ele *modules.ElectionNode, callees []*calleeJury) {
 	if tx == nil || ele == nil {
 		log.Error("processContractPayout param is nil")
 		return
 	}
 	reqId := tx.RequestHash()
-	if has, index, payout_msg := tx.HasContractPayoutMsg(); has {
+	if has, _, _ := tx.HasContractPayoutMsg(); has {
 		pubkeys, signs := getSignature(tx)
-		redeem := p.generateJuryRedeemScript(ele)
-
-		signsOrder := SortSigs(pubkeys, signs, redeem)
-		unlock := tokenengine.Instance.MergeContractUnlockScript(signsOrder, redeem)
-		log.DebugDynamic(func() string {
-			unlockStr, _ := tokenengine.Instance.DisasmString(unlock)
-			return fmt.Sprintf("[%s]processContractPayout, Move sign payload to contract payout unlock script:%s",
-				shortId(reqId.String()), unlockStr)
-		})
-		for _, input := range payout_msg.Payload.(*modules.PaymentPayload).Inputs {
-			input.SignatureScript = unlock
-		}
-		tx.ModifiedMsg(index, payout_msg)
+		genUnlock := func(jury *modules.ElectionNode) []byte {
+			redeem := p.generateJuryRedeemScript(jury)
+			signsOrder := SortSigs(pubkeys, signs, redeem)
+			unlock := tokenengine.Instance.MergeContractUnlockScript(signsOrder, redeem)
+			log.DebugDynamic(func() string {
+				unlockStr, _ := tokenengine.Instance.DisasmString(unlock)
+				return fmt.Sprintf("[%s]processContractPayout, Move sign payload to contract payout unlock script:%s",
+					shortId(reqId.String()), unlockStr)
+			})
+			return unlock
+		}
+		unlock := genUnlock(ele)
+		calleeUnlocks := make(map[common.Address][]byte)
+		for _, callee := range callees {
+			calleeUnlocks[callee.contractAddr] = genUnlock(callee.ele)
+		}
+		//多种Token或者被调用合约的付款会产生多个Payment
+		isResult := false
+		for index, payoutMsg := range tx.Messages() {
+			if payoutMsg.App.IsRequest() {
+				isResult = true
+				continue
+			}
+			if !isResult || payoutMsg.App != modules.APP_PAYMENT {
+				continue
+			}
+			payment := payoutMsg.Payload.(*modules.PaymentPayload)
+			if payment.IsCoinbase() {
+				continue
+			}
+			for _, input := range payment.Inputs {
+				input.SignatureScript = unlock
+				if len(calleeUnlocks) == 0 {
+					continue
+				}
+				utxo, err := tx.GetSpendUtxo(input.PreviousOutPoint, p.dag.GetUtxoEntry)
+				if err != nil {
+					log.Errorf("[%s]processContractPayout, get utxo[%s] fail:%s", shortId(reqId.String()),
+						input.PreviousOutPoint.String(), err.Error())
+					continue
+				}
+				from, _ := tokenengine.Instance.GetAddressFromScript(utxo.PkScript)
+				if calleeUnlock, ok := calleeUnlocks[from]; ok {
+					input.SignatureScript = calleeUnlock
+				}
+			}
+			tx.ModifiedMsg(index, payoutMsg)
+		}
+		if len(callees) > 0 {
+			return
+		}
 		//remove signature payload
 		var msgs []*modules.Message
 		for _, msg := range tx.Messages() {
@@ -219,9 +297,15 @@ func genContractErrorMsg(tx *modules.Transaction,
 
 func createContractErrorPayloadMsg(tx *modules.Transaction, errIn error) *modules.Message {
 	contractErr := modules.ContractError{
-		Code:    500, //todo
+		Code:    modules.ContractErrCodeDefault, //todo
 		Message: errIn.Error(),
 	}
+	//Gas耗尽，按Gas上限扣除手续费。只认节点GasMeter返回的错误，合约返回的错误信息无法伪造
+	var gasUsed uint64
+	if gasErr, ok := errIn.(*modules.ContractOutOfGasError); ok {
+		contractErr.Code = modules.ContractErrCodeOutOfGas
+		gasUsed = gasErr.Limit
+	}
 	reqType, _ := getContractTxType(tx)
 	_, contractReq, _ := getContractTxContractInfo(tx, reqType)
 	switch reqType {
@@ -235,17 +319,96 @@ func createContractErrorPayloadMsg(tx *modules.Transaction, errIn error) *module
 	case modules.APP_CONTRACT_INVOKE_REQUEST:
 		req := contractReq.Payload.(*modules.ContractInvokeRequestPayload)
 		payload := modules.NewContractInvokePayload(req.ContractId, nil, nil, nil, contractErr)
+		payload.GasUsed = gasUsed
 		return modules.NewMessage(modules.APP_CONTRACT_INVOKE, payload)
 	case modules.APP_CONTRACT_STOP_REQUEST:
 		req := contractReq.Payload.(*modules.ContractStopRequestPayload)
 		payload := modules.NewContractStopPayload(req.ContractId, nil, nil, contractErr)
 		return modules.NewMessage(modules.APP_CONTRACT_STOP, payload)
+	case modules.APP_CONTRACT_UPGRADE_REQUEST:
+		req := contractReq.Payload.(*modules.ContractUpgradeRequestPayload)
+		payload := modules.NewContractUpgradePayload(req.ContractId, req.TemplateId, nil, nil, contractErr)
+		return modules.NewMessage(modules.APP_CONTRACT_UPGRADE, payload)
 	}
 
 	return nil
 }
 
-//执行合约命令:install、deploy、invoke、stop，同时只支持一种类型
+//runContractReqCmd 与runContractCmd相同，合约调用时还返回私有状态的明文，由陪审员加密后发给陪审团
+func runContractReqCmd(rwM rwset.TxManager, dag iDag, contract *contracts.Contract, tx *modules.Transaction,
+	ele *modules.ElectionNode, errMsgEnable bool) ([]*modules.Message, []*modules.PrivateWrite, error) {
+	if tx == nil || len(tx.Messages()) <= 0 {
+		return nil, nil, errors.New("runContractReqCmd transaction or msg is nil")
+	}
+	for _, msg := range tx.TxMessages() {
+		if msg.App == modules.APP_CONTRACT_INVOKE_REQUEST {
+			reqPay := msg.Payload.(*modules.ContractInvokeRequestPayload)
+			return runContractInvokeCmd(rwM, dag, contract, tx, reqPay, errMsgEnable)
+		}
+	}
+	msgs, err := runContractCmd(rwM, dag, contract, tx, ele, errMsgEnable)
+	return msgs, nil, err
+}
+
+//runContractInvokeCmd 执行合约调用，返回结果消息和私有状态的明文
+func runContractInvokeCmd(rwM rwset.TxManager, dag iDag, contract *contracts.Contract, tx *modules.Transaction,
+	reqPay *modules.ContractInvokeRequestPayload, errMsgEnable bool) ([]*modules.Message,
+	[]*modules.PrivateWrite, error) {
+	if modules.IsContractMigrateCall(reqPay.Args) {
+		return nil, nil, fmt.Errorf("runContractCmd, can't invoke %s, txid=%s",
+			modules.ContractMigrateFunc, tx.RequestHash().String())
+	}
+	if err := checkScheduleRegister(dag, tx, reqPay); err != nil {
+		return nil, nil, fmt.Errorf("runContractCmd, %s, txid=%s", err.Error(), tx.RequestHash().String())
+	}
+	args, scheduled, err := checkScheduledInvoke(dag, tx, reqPay)
+	if err != nil {
+		return nil, nil, fmt.Errorf("runContractCmd, %s, txid=%s", err.Error(), tx.RequestHash().String())
+	}
+	gasLimit, err := calculateContractInvokeGasLimit(dag, tx, reqPay)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := ContractInvokeReq{
+		chainID:  "palletone",
+		deployId: reqPay.ContractId,
+		args:     args,
+		txid:     tx.RequestHash().String(),
+		timeout:  time.Duration(reqPay.Timeout) * time.Second,
+		gasLimit: gasLimit,
+	}
+
+	fullArgs, err := handleMsg0(tx, dag, req.args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scheduled {
+		//定时任务的调用者为定时调用系统合约，而不是发起请求的Mediator
+		if fullArgs[0], err = setInvokeAddress(fullArgs[0], syscontract.SchedulerContractAddress); err != nil {
+			return nil, nil, err
+		}
+	}
+	// add cert id to args
+	newFullArgs, err := handleArg1(tx, fullArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.args = newFullArgs
+	invokeResult, err := ContractProcess(rwM, contract, req)
+	if err != nil {
+		msgs, err := genContractErrorMsg(tx, err, errMsgEnable)
+		return msgs, nil, err
+	}
+	result := invokeResult.(*modules.ContractInvokeResult)
+	msgs, err := invokeResultToMsgs(dag, tx, result)
+	if err != nil {
+		msgs, err := genContractErrorMsg(tx, err, errMsgEnable)
+		return msgs, nil, err
+	}
+	return msgs, result.PrivateWriteSet, nil
+}
+
+//执行合约命令:install、deploy、invoke、stop、upgrade，同时只支持一种类型
 func runContractCmd(rwM rwset.TxManager, dag iDag, contract *contracts.Contract, tx *modules.Transaction,
 	ele *modules.ElectionNode, errMsgEnable bool) ([]*modules.Message, error) {
 	if tx == nil || len(tx.Messages()) <= 0 {
@@ -305,55 +468,9 @@ func runContractCmd(rwM rwset.TxManager, dag iDag, contract *contracts.Contract,
 			}
 		case modules.APP_CONTRACT_INVOKE_REQUEST:
 			{
-				var msgs []*modules.Message
 				reqPay := msg.Payload.(*modules.ContractInvokeRequestPayload)
-				req := ContractInvokeReq{
-					chainID:  "palletone",
-					deployId: reqPay.ContractId,
-					args:     reqPay.Args,
-					txid:     tx.RequestHash().String(),
-					timeout:  time.Duration(reqPay.Timeout) * time.Second,
-				}
-
-				fullArgs, err := handleMsg0(tx, dag, req.args)
-				if err != nil {
-					return nil, err
-				}
-				// add cert id to args
-				newFullArgs, err := handleArg1(tx, fullArgs)
-				if err != nil {
-					return nil, err
-				}
-				req.args = newFullArgs
-				invokeResult, err := ContractProcess(rwM, contract, req)
-				if err != nil {
-					return genContractErrorMsg(tx, err, errMsgEnable)
-				}
-				result := invokeResult.(*modules.ContractInvokeResult)
-				payload := modules.NewContractInvokePayload(result.ContractId, result.ReadSet, result.WriteSet,
-					result.Payload, modules.ContractError{})
-				if payload != nil {
-					msgs = append(msgs, modules.NewMessage(modules.APP_CONTRACT_INVOKE, payload))
-				}
-				toContractPayments, err := resultToContractPayments(dag, tx.GetRequestTx(), result)
-				if err != nil {
-					return genContractErrorMsg(tx, err, errMsgEnable)
-				}
-				if len(toContractPayments) > 0 {
-					for _, contractPayment := range toContractPayments {
-						msgs = append(msgs, modules.NewMessage(modules.APP_PAYMENT, contractPayment))
-					}
-				}
-				cs, err := resultToCoinbase(result)
-				if err != nil {
-					return genContractErrorMsg(tx, err, errMsgEnable)
-				}
-				if len(cs) > 0 {
-					for _, coinbase := range cs {
-						msgs = append(msgs, modules.NewMessage(modules.APP_PAYMENT, coinbase))
-					}
-				}
-				return msgs, nil
+				msgs, _, err := runContractInvokeCmd(rwM, dag, contract, tx, reqPay, errMsgEnable)
+				return msgs, err
 			}
 		case modules.APP_CONTRACT_STOP_REQUEST:
 			{
@@ -373,6 +490,36 @@ func runContractCmd(rwM rwset.TxManager, dag iDag, contract *contracts.Contract,
 				msgs = append(msgs, modules.NewMessage(modules.APP_CONTRACT_STOP, payload))
 				return msgs, nil
 			}
+		case modules.APP_CONTRACT_UPGRADE_REQUEST:
+			{
+				var msgs []*modules.Message
+				reqPay := msg.Payload.(*modules.ContractUpgradeRequestPayload)
+				req := ContractUpgradeReq{
+					chainID:    "palletone",
+					deployId:   reqPay.ContractId,
+					templateId: reqPay.TemplateId,
+					txid:       tx.RequestHash().String(),
+					timeout:    time.Duration(reqPay.Timeout) * time.Second,
+				}
+				//迁移函数与普通调用使用相同的参数格式
+				migrateArgs := append([][]byte{[]byte(modules.ContractMigrateFunc)}, reqPay.Args...)
+				fullArgs, err := handleMsg0(tx, dag, migrateArgs)
+				if err != nil {
+					return nil, err
+				}
+				newFullArgs, err := handleArg1(tx, fullArgs)
+				if err != nil {
+					return nil, err
+				}
+				req.args = newFullArgs
+				upgradeResult, err := ContractProcess(rwM, contract, req)
+				if err != nil {
+					return genContractErrorMsg(tx, err, errMsgEnable)
+				}
+				payload := upgradeResult.(*modules.ContractUpgradePayload)
+				msgs = append(msgs, modules.NewMessage(modules.APP_CONTRACT_UPGRADE, payload))
+				return msgs, nil
+			}
 		}
 	}
 
@@ -421,6 +568,35 @@ func contractPayBack(tx *modules.Transaction, addr []byte) []*modules.Message {
 	}
 	return messages
 }
+//invokeResultToMsgs 把用户调用合约的执行结果转换为合约交易中的消息
+func invokeResultToMsgs(dag iDag, tx *modules.Transaction, result *modules.ContractInvokeResult) (
+	[]*modules.Message, error) {
+	var msgs []*modules.Message
+	payload := modules.NewContractInvokePayload(result.ContractId, result.ReadSet, result.WriteSet,
+		result.Payload, modules.ContractError{})
+	if payload != nil {
+		payload.RangeReadSet = result.RangeReadSet
+		payload.Events = result.Events
+		payload.GasUsed = result.GasUsed
+		msgs = append(msgs, modules.NewMessage(modules.APP_CONTRACT_INVOKE, payload))
+	}
+	toContractPayments, err := resultToContractPayments(dag, tx.GetRequestTx(), result)
+	if err != nil {
+		return nil, err
+	}
+	for _, contractPayment := range toContractPayments {
+		msgs = append(msgs, modules.NewMessage(modules.APP_PAYMENT, contractPayment))
+	}
+	cs, err := resultToCoinbase(result)
+	if err != nil {
+		return nil, err
+	}
+	for _, coinbase := range cs {
+		msgs = append(msgs, modules.NewMessage(modules.APP_PAYMENT, coinbase))
+	}
+	return msgs, nil
+}
+
 func handleMsg0(tx *modules.Transaction, dag iDag, reqArgs [][]byte) ([][]byte, error) {
 	var txArgs [][]byte
 	invokeInfo := modules.InvokeInfo{}
@@ -478,6 +654,16 @@ func handleMsg0(tx *modules.Transaction, dag iDag, reqArgs [][]byte) ([][]byte,
 	return txArgs, nil
 }
 
+//setInvokeAddress 替换合约参数中的调用者地址
+func setInvokeAddress(invokeInfoBytes []byte, addr common.Address) ([]byte, error) {
+	invokeInfo := modules.InvokeInfo{}
+	if err := json.Unmarshal(invokeInfoBytes, &invokeInfo); err != nil {
+		return nil, err
+	}
+	invokeInfo.InvokeAddress = addr
+	return json.Marshal(invokeInfo)
+}
+
 func handleArg1(tx *modules.Transaction, reqArgs [][]byte) ([][]byte, error) {
 	if len(reqArgs) <= 1 {
 		return nil, fmt.Errorf("[%s]handlemsg1 req args error", shortId(tx.RequestHash().String()))
@@ -599,6 +785,22 @@ func (p *Processor) checkTxAddrValid(tx *modules.Transaction) bool {
 				shortId(reqId.String()), contract.Creator, reqAddr.Bytes())
 			return false
 		}
+	case modules.APP_CONTRACT_UPGRADE_REQUEST:
+		_, msg, err := getContractTxContractInfo(tx, cType)
+		if err != nil {
+			return false
+		}
+		reqPay := msg.Payload.(*modules.ContractUpgradeRequestPayload)
+		contract, err := p.dag.GetContract(reqPay.ContractId)
+		if err != nil {
+			log.Debugf("[%s]checkTxAddrValid, GetContract fail, contractId[%v]", shortId(reqId.String()),
+				reqPay.ContractId)
+			return false
+		}
+		if err = contract.CheckUpgradeAuth(reqAddr, reqPay); err != nil {
+			log.Debugf("[%s]checkTxAddrValid, %s", shortId(reqId.String()), err.Error())
+			return false
+		}
 	}
 	return true
 }
@@ -689,7 +891,7 @@ func getContractTxType(tx *modules.Transaction) (modules.MessageType, error) {
 		return modules.APP_UNKNOW, errors.New("getContractTxType get param is nil")
 	}
 	for _, msg := range tx.Messages() {
-		if msg.App >= modules.APP_CONTRACT_TPL_REQUEST && msg.App <= modules.APP_CONTRACT_STOP_REQUEST {
+		if msg.App >= modules.APP_CONTRACT_TPL_REQUEST && msg.App <= modules.APP_CONTRACT_UPGRADE_REQUEST {
 			return msg.App, nil
 		}
 	}
@@ -743,33 +945,8 @@ func conversionElectionSeedData(in []byte) []byte {
 	return in
 }
 
-/*
-Preliminary test conclusion
-expectNum:4
-use:
-total    weight    num
-20         4        5
-50         7        7
-100        8        5
-200        15       5
-500        17       6
-1000       18       5
-*/
 func electionWeightValue(total uint64) (val uint64) {
-	if total <= 20 {
-		return 4
-	} else if total > 20 && total <= 50 {
-		return 7
-	} else if total > 50 && total <= 100 {
-		return 8
-	} else if total > 100 && total <= 200 {
-		return 15
-	} else if total > 200 && total <= 500 {
-		return 17
-	} else if total > 500 {
-		return 20
-	}
-	return 4
+	return alg.ElectionWeight(total)
 }
 
 func shortId(id string) string {
@@ -825,6 +1002,9 @@ func getContractFeeLevel(dag iDag, msg modules.MessageType, feeType int) (level
 		opFee = cp.ContractTxInvokeFeeLevel
 	case modules.APP_CONTRACT_STOP_REQUEST:
 		opFee = cp.ContractTxStopFeeLevel
+	case modules.APP_CONTRACT_UPGRADE_REQUEST:
+		//升级需要启动新的合约容器，与部署的费用相同
+		opFee = cp.ContractTxDeployFeeLevel
 	}
 	if feeType == ContractFeeTypeTimeOut {
 		level = opFee * timeFee
@@ -906,6 +1086,79 @@ func calculateContractDeployDuringTime(dag iDag, tx *modules.Transaction) (uint6
 	return uint64(duringTime), nil
 }
 
+//contractFeeLevelPrecision 费用等级按千分之一向上取整后参与整数运算
+const contractFeeLevelPrecision = 1000
+
+//getContractInvokeBaseFee 调用请求按超时时间和交易大小需要支付的费用，全部用整数计算并向上取整，
+//不同平台的陪审员得到相同的结果
+func getContractInvokeBaseFee(dag iDag, timeout uint32, txSize uint64) uint64 {
+	cp := dag.GetChainParameters()
+	level := math.Ceil(cp.ContractTxInvokeFeeLevel * contractFeeLevelPrecision)
+	if level <= 0 {
+		return 0
+	}
+	timeFee := new(big.Int).Mul(new(big.Int).SetUint64(cp.ContractTxTimeoutUnitFee),
+		new(big.Int).SetUint64(uint64(timeout)))
+	sizeFee := new(big.Int).Mul(new(big.Int).SetUint64(cp.ContractTxSizeUnitFee), new(big.Int).SetUint64(txSize))
+	fee := new(big.Int).Add(timeFee, sizeFee)
+	fee.Mul(fee, new(big.Int).SetUint64(uint64(level)))
+	fee.Add(fee, big.NewInt(contractFeeLevelPrecision-1))
+	fee.Div(fee, big.NewInt(contractFeeLevelPrecision))
+	if !fee.IsUint64() {
+		return math.MaxUint64
+	}
+	return fee.Uint64()
+}
+
+//getContractInvokeMinFee 调用请求的基本费用，以及再加上最小Gas上限费用后的最低手续费
+func getContractInvokeMinFee(dag iDag, reqTx *modules.Transaction,
+	reqPay *modules.ContractInvokeRequestPayload) (baseFee uint64, minFee uint64) {
+	baseFee = getContractInvokeBaseFee(dag, reqPay.Timeout,
+		uint64(reqTx.Size())+ContractDefaultSignatureSize+ContractDefaultRWSize)
+	minFee = baseFee + modules.ContractGasFee(modules.MinContractGasLimit)
+	if minFee < baseFee {
+		minFee = math.MaxUint64
+	}
+	return baseFee, minFee
+}
+
+//checkContractInvokeReqFee 提交调用请求前检查手续费，不够支付最小Gas上限时直接拒绝，
+//而不是让陪审员在没有Gas的情况下执行合约
+func checkContractInvokeReqFee(dag iDag, tx *modules.Transaction, fee uint64) error {
+	reqMsg := tx.Messages()[tx.GetRequestMsgIndex()]
+	if reqMsg.App != modules.APP_CONTRACT_INVOKE_REQUEST {
+		return nil
+	}
+	reqPay := reqMsg.Payload.(*modules.ContractInvokeRequestPayload)
+	if _, minFee := getContractInvokeMinFee(dag, tx.GetRequestTx(), reqPay); fee < minFee {
+		return fmt.Errorf("checkContractInvokeReqFee, fee[%d] is not enough, need at least %d to pay "+
+			"the minimum gas limit %d", fee, minFee, modules.MinContractGasLimit)
+	}
+	return nil
+}
+
+//calculateContractInvokeGasLimit 扣除交易大小和超时时间的费用后，剩余的手续费是可以使用的Gas费用，
+//实际的Gas上限不超过请求中声明的上限。所有陪审员根据同一个请求交易用整数计算，结果相同
+func calculateContractInvokeGasLimit(dag iDag, tx *modules.Transaction,
+	reqPay *modules.ContractInvokeRequestPayload) (uint64, error) {
+	reqTx := tx.GetRequestTx()
+	fees, err := dag.GetTxFee(reqTx)
+	if err != nil {
+		return 0, fmt.Errorf("calculateContractInvokeGasLimit, GetTxFee err:%s", err.Error())
+	}
+	baseFee, minFee := getContractInvokeMinFee(dag, reqTx, reqPay)
+	if fees.Amount < minFee {
+		return 0, fmt.Errorf("calculateContractInvokeGasLimit, fee[%d] is not enough, need at least %d to pay "+
+			"the minimum gas limit %d", fees.Amount, minFee, modules.MinContractGasLimit)
+	}
+	gasLimit := modules.ContractGasOfFee(fees.Amount - baseFee)
+	if declared := reqPay.GetGasLimit(); gasLimit > declared {
+		gasLimit = declared
+	}
+	log.Debug("calculateContractInvokeGasLimit", "fee", fees.Amount, "baseFee", baseFee, "gasLimit", gasLimit)
+	return gasLimit, nil
+}
+
 func addContractDeployDuringTime(dag iDag, tx *modules.Transaction) error {
 	if tx == nil {
 		return errors.New("calculateContractDeployDuringTime, param is nil")
@@ -978,6 +1231,14 @@ func getSysCfgContractElectionNum(dag iDag) int {
 	return contractEleNum
 }
 
+func getSysCfgVrfAlgorithm(dag iDag) string {
+	alg := dag.GetChainParameters().VrfAlgorithm
+	if alg == "" {
+		alg = core.DefaultVrfAlgorithm
+	}
+	return alg
+}
+
 func randSelectEle(ele []modules.ElectionInf) []modules.ElectionInf {
 	out := make([]modules.ElectionInf, 0)
 	out = append(out, ele...)
@@ -1,11 +1,18 @@
 package jury
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/dag"
 	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSortSigs(t *testing.T) {
@@ -77,4 +84,96 @@ func TestEleRand(t *testing.T) {
 
 	fmt.Println(out)
 	fmt.Println(ele)
-}
\ No newline at end of file
+}
+func TestCreateContractErrorPayloadMsg(t *testing.T) {
+	reqMsg := modules.NewMessage(modules.APP_CONTRACT_INVOKE_REQUEST,
+		&modules.ContractInvokeRequestPayload{ContractId: []byte("contract")})
+	tx := modules.NewTransaction([]*modules.Message{reqMsg})
+
+	msg := createContractErrorPayloadMsg(tx, &modules.ContractOutOfGasError{Limit: 5000})
+	payload := msg.Payload.(*modules.ContractInvokePayload)
+	assert.Equal(t, modules.ContractErrCodeOutOfGas, payload.ErrMsg.Code)
+	assert.Equal(t, uint64(5000), payload.GasUsed)
+
+	//合约返回相同的错误信息不会被当作Gas耗尽
+	msg = createContractErrorPayloadMsg(tx, errors.New(modules.ContractOutOfGasMsg+", gas limit:5000"))
+	payload = msg.Payload.(*modules.ContractInvokePayload)
+	assert.Equal(t, modules.ContractErrCodeDefault, payload.ErrMsg.Code)
+	assert.Equal(t, uint64(0), payload.GasUsed)
+}
+
+func TestCalculateContractInvokeGasLimit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cp := core.NewChainParams()
+	mdag := dag.NewMockIDag(mockCtrl)
+	mdag.EXPECT().GetChainParameters().Return(&cp).AnyTimes()
+
+	reqPay := &modules.ContractInvokeRequestPayload{ContractId: []byte("contract"), Timeout: 10}
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_CONTRACT_INVOKE_REQUEST, reqPay)})
+	txSize := uint64(tx.Size()) + ContractDefaultSignatureSize + ContractDefaultRWSize
+	baseFee := cp.ContractTxTimeoutUnitFee*uint64(reqPay.Timeout) + cp.ContractTxSizeUnitFee*txSize
+	assert.Equal(t, baseFee, getContractInvokeBaseFee(mdag, reqPay.Timeout, txSize))
+	minFee := baseFee + modules.ContractGasFee(modules.MinContractGasLimit)
+
+	//手续费不够支付最小Gas上限时拒绝执行
+	mdag.EXPECT().GetTxFee(gomock.Any()).Return(&modules.AmountAsset{Amount: minFee - 1}, nil)
+	_, err := calculateContractInvokeGasLimit(mdag, tx, reqPay)
+	assert.NotNil(t, err)
+	assert.NotNil(t, checkContractInvokeReqFee(mdag, tx, minFee-1))
+	assert.Nil(t, checkContractInvokeReqFee(mdag, tx, minFee))
+
+	mdag.EXPECT().GetTxFee(gomock.Any()).Return(&modules.AmountAsset{Amount: minFee + 3000}, nil)
+	gasLimit, err := calculateContractInvokeGasLimit(mdag, tx, reqPay)
+	assert.Nil(t, err)
+	assert.Equal(t, modules.MinContractGasLimit+3000, gasLimit)
+
+	//不超过请求中声明的Gas上限
+	reqPay.GasLimit = 2000
+	mdag.EXPECT().GetTxFee(gomock.Any()).Return(&modules.AmountAsset{Amount: minFee + 3000}, nil)
+	gasLimit, err = calculateContractInvokeGasLimit(mdag, tx, reqPay)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2000), gasLimit)
+
+	//费用等级不是整数时按千分之一向上取整
+	cp.ContractTxInvokeFeeLevel = 1.5
+	assert.Equal(t, (baseFee*1500+999)/1000, getContractInvokeBaseFee(mdag, reqPay.Timeout, txSize))
+}
+
+func TestContractSigEnough(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cp := core.NewChainParams()
+	mdag := dag.NewMockIDag(mockCtrl)
+	mdag.EXPECT().GetChainParameters().Return(&cp).AnyTimes()
+	p := &Processor{dag: mdag}
+
+	newJury := func(pubKeys ...[]byte) *modules.ElectionNode {
+		ele := &modules.ElectionNode{}
+		for _, pubKey := range pubKeys {
+			ele.EleList = append(ele.EleList, modules.ElectionInf{PublicKey: pubKey,
+				AddrHash: util.RlpHash(crypto.PubkeyBytesToAddress(pubKey))})
+		}
+		return ele
+	}
+	keys := [][]byte{{2, 1}, {2, 2}, {2, 3}, {2, 4}}
+	callerJury := newJury(keys[0], keys[1], keys[2])
+	calleeEle := newJury(keys[3])
+
+	sigPayload := &modules.SignaturePayload{}
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_SIGNATURE, sigPayload)})
+	for _, key := range keys[:3] {
+		sigPayload.Signatures = append(sigPayload.Signatures, modules.SignatureSet{PubKey: key, Signature: key})
+	}
+	ctx := &contractTx{eleNode: callerJury, sigTx: tx}
+	assert.True(t, p.contractSigEnough(ctx))
+
+	//跨陪审团调用时被调用合约的陪审团也要有足够的签名
+	ctx.callees = []*calleeJury{{contractAddr: common.NewAddress([]byte("callee"), common.ContractHash),
+		ele: calleeEle}}
+	assert.False(t, p.contractSigEnough(ctx))
+	sigPayload.Signatures = append(sigPayload.Signatures, modules.SignatureSet{PubKey: keys[3], Signature: keys[3]})
+	assert.True(t, p.contractSigEnough(ctx))
+}
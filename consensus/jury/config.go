@@ -58,6 +58,10 @@ type Config struct {
 	//ContractSigNum int   //user contract jury sig number  //todo  no used
 	//ElectionNum    int   //vrf election jury number       //todo  no used
 	Accounts []*AccountConf // the set of the mediator info
+	//合约私有状态被替换、删除或合约停止后在本地保留的天数，0表示不清理
+	PrivateStateRetention uint32
+	//陪审员执行合约后等待其他陪审员签名的秒数，超时后重新选举陪审团，0表示不重新选举
+	JuryFailoverTimeout uint32
 }
 
 func (aConf *AccountConf) configToAccount() *JuryAccount {
@@ -76,6 +80,8 @@ var DefaultConfig = Config{
 	Accounts: []*AccountConf{
 		&AccountConf{},
 	},
+	PrivateStateRetention: 30,
+	JuryFailoverTimeout:   120,
 }
 
 func MakeConfig() Config {
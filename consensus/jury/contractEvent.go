@@ -24,6 +24,7 @@ import (
 
 	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
 	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/validator"
@@ -66,7 +67,7 @@ func (p *Processor) ProcessContractEvent(event *ContractEvent) (bool, error) {
 	case CONTRACT_EVENT_EXEC:
 		brd, err = p.contractExecEvent(event.Tx, event.Ele)
 	case CONTRACT_EVENT_SIG:
-		brd, err = p.contractSigEvent(event.Tx, event.Ele)
+		brd, err = p.contractSigEvent(event.Tx, event.Ele, event.PrivateStates)
 	case CONTRACT_EVENT_COMMIT:
 		brd, err = p.contractCommitEvent(event.Tx)
 	case CONTRACT_EVENT_ELE:
@@ -140,10 +141,18 @@ func (p *Processor) contractExecEvent(tx *modules.Transaction, ele *modules.Elec
 			adaInf: make(map[uint32]*AdapterInf),
 		}
 	} else {
-		if p.mtx[reqId].reqRcvEd {
+		ctx := p.mtx[reqId]
+		//陪审团重新选举后，请求会改由新陪审团重新执行
+		rerouted := ctx.eleNode != nil && !isSameJury(ctx.eleNode, ele) && p.isContractJury(tx.GetContractId(), ele)
+		if ctx.reqRcvEd && !rerouted {
 			p.locker.Unlock()
 			return false, nil
 		}
+		if rerouted {
+			ctx.sigTx = nil
+			ctx.rcvTx = nil
+			ctx.seals = nil
+		}
 	}
 	p.mtx[reqId].reqTx = tx.GetRequestTx()
 	p.mtx[reqId].eleNode = ele
@@ -161,7 +170,8 @@ func (p *Processor) contractExecEvent(tx *modules.Transaction, ele *modules.Elec
 	return true, nil
 }
 
-func (p *Processor) contractSigEvent(tx *modules.Transaction, ele *modules.ElectionNode) (broadcast bool, err error) {
+func (p *Processor) contractSigEvent(tx *modules.Transaction, ele *modules.ElectionNode,
+	states []*modules.PrivateState) (broadcast bool, err error) {
 	if tx == nil {
 		return false, errors.New("contractSigEvent, tx is nil")
 	}
@@ -174,6 +184,7 @@ func (p *Processor) contractSigEvent(tx *modules.Transaction, ele *modules.Elect
 		}
 	}
 	log.Debugf("[%s]contractSigEvent, receive sig tx[%s]", shortId(reqId.String()), tx.Hash().String())
+	seal := p.savePeerPrivateStates(tx, states)
 	if _, ok := p.mtx[reqId]; !ok {
 		log.Debugf("[%s]contractSigEvent, local not find reqId,create it", shortId(reqId.String()))
 		p.mtx[reqId] = &contractTx{
@@ -184,18 +195,33 @@ func (p *Processor) contractSigEvent(tx *modules.Transaction, ele *modules.Elect
 			adaInf:  make(map[uint32]*AdapterInf),
 		}
 		p.mtx[reqId].rcvTx = append(p.mtx[reqId].rcvTx, tx)
+		if seal != nil {
+			p.mtx[reqId].seals = append(p.mtx[reqId].seals, seal)
+		}
+		p.joinCrossJuryExec(p.mtx[reqId], tx, ele)
 		return true, nil
 	}
 	ctx := p.mtx[reqId]
 	ctx.rcvTx = append(ctx.rcvTx, tx)
+	if seal != nil {
+		ctx.seals = append(ctx.seals, seal)
+	}
+	p.joinCrossJuryExec(ctx, tx, ele)
+
+	//对方私有状态的明文与本地相同但使用的盐Id更小，本地改用对方的盐后重新签名
+	if ctx.sigTx != nil && adoptPrivateSeal(ctx) {
+		log.Debugf("[%s]contractSigEvent, adopt private state salts of tx[%s]", shortId(reqId.String()),
+			tx.Hash().String())
+		return true, p.signContractResult(reqId, ctx, ctx.reqTx.Clone(), ctx.eleNode)
+	}
 
 	//如果是jury，将接收到tx与本地执行后的tx进行对比，相同则添加签名到sigTx，如果满足签名数量且签名值最小则广播tx，否则函数返回
 	if ok, err := checkAndAddTxSigMsgData(ctx.sigTx, tx); err == nil && ok {
-		if getTxSigNum(ctx.sigTx) >= getSysCfgContractSignatureNum(p.dag) {
+		if p.contractSigEnough(ctx) {
 			if localIsMinSignature(ctx.sigTx) { //todo
 				//签名数量足够，而且当前节点是签名最新的节点，那么合并签名并广播完整交易
 				log.Infof("[%s]runContractReq, localIsMinSignature Ok!", shortId(reqId.String()))
-				p.processContractPayout(ctx.sigTx, ele)
+				p.processContractPayout(ctx.sigTx, ele, ctx.callees)
 				go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_COMMIT, Ele: ele, Tx: ctx.sigTx}, true)
 			}
 		}
@@ -205,6 +231,36 @@ func (p *Processor) contractSigEvent(tx *modules.Transaction, ele *modules.Elect
 	return true, nil
 }
 
+//joinCrossJuryExec 跨陪审团调用时，被调用合约的陪审员收到调用合约陪审团的签名交易后也执行合约并签名，
+//执行结果相同的签名才会被合并
+func (p *Processor) joinCrossJuryExec(ctx *contractTx, tx *modules.Transaction, ele *modules.ElectionNode) {
+	if ctx.reqRcvEd || tx.IsSystemContract() {
+		return
+	}
+	account := p.getLocalJuryAccount()
+	if account == nil {
+		return
+	}
+	reqId := tx.RequestHash()
+	callees, err := getContractCalleeJuries(p.dag, tx)
+	if err != nil {
+		log.Debugf("[%s]joinCrossJuryExec, getContractCalleeJuries error:%s", shortId(reqId.String()), err.Error())
+		return
+	}
+	addrHash := util.RlpHash(account.Address)
+	for _, callee := range callees {
+		if callee.ele.HasAddrHash(addrHash) {
+			log.Debugf("[%s]joinCrossJuryExec, local is juror of callee[%s]", shortId(reqId.String()),
+				callee.contractAddr.String())
+			ctx.reqTx = tx.GetRequestTx()
+			ctx.eleNode = ele
+			ctx.reqRcvEd = true
+			go p.runContractReq(reqId, ele)
+			return
+		}
+	}
+}
+
 func (p *Processor) contractCommitEvent(tx *modules.Transaction) (broadcast bool, err error) {
 	if tx == nil {
 		return false, errors.New("contractCommitEvent, tx is nil")
@@ -71,10 +71,11 @@ type ContractInvokeReq struct {
 	txid     string //common.Hash
 	args     [][]byte
 	timeout  time.Duration
+	gasLimit uint64
 }
 
 func (req ContractInvokeReq) do(rwM rwset.TxManager, v contracts.ContractInf) (interface{}, error) {
-	return v.Invoke(rwM, req.chainID, req.deployId, req.txid, req.args, req.timeout)
+	return v.Invoke(rwM, req.chainID, req.deployId, req.txid, req.args, req.timeout, req.gasLimit)
 }
 
 type ContractStopReq struct {
@@ -88,6 +89,19 @@ func (req ContractStopReq) do(rwM rwset.TxManager, v contracts.ContractInf) (int
 	return v.Stop(rwM, req.chainID, req.deployId, req.txid, req.deleteImage)
 }
 
+type ContractUpgradeReq struct {
+	chainID    string
+	deployId   []byte
+	templateId []byte
+	txid       string
+	args       [][]byte
+	timeout    time.Duration
+}
+
+func (req ContractUpgradeReq) do(rwM rwset.TxManager, v contracts.ContractInf) (interface{}, error) {
+	return v.Upgrade(rwM, req.chainID, req.deployId, req.templateId, req.txid, req.args, req.timeout)
+}
+
 func ContractProcess(rwM rwset.TxManager, contract *contracts.Contract, req ContractReqInf) (interface{}, error) {
 	if contract == nil || req == nil {
 		log.Error("ContractProcess", "param is nil,", "err")
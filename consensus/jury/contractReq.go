@@ -126,8 +126,10 @@ func (p *Processor) ContractInstallReq(from, to common.Address, daoAmount, daoFe
 	return reqId, nil, nil
 }
 
+//policy 多签升级策略，为空时只有创建者可以升级合约
 func (p *Processor) ContractDeployReq(from, to common.Address, daoAmount, daoFee uint64, templateId []byte,
-	args [][]byte, extData []byte, timeout time.Duration) (common.Hash, common.Address, error) {
+	args [][]byte, extData []byte, timeout time.Duration, policy *modules.ContractUpgradePolicy) (common.Hash,
+	common.Address, error) {
 	if from == (common.Address{}) || to == (common.Address{}) || templateId == nil {
 		log.Error("ContractDeployReq, param is error")
 		return common.Hash{}, common.Address{}, errors.New("ContractDeployReq request param is error")
@@ -143,6 +145,11 @@ func (p *Processor) ContractDeployReq(from, to common.Address, daoAmount, daoFee
 			return common.Hash{}, common.Address{}, errors.New("ContractDeployReq request param len overflow")
 		}
 	}
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return common.Hash{}, common.Address{}, fmt.Errorf("ContractDeployReq, %s", err.Error())
+		}
+	}
 	if daoFee == 0 { //dynamic calculation fee
 		fee, _, _, err := p.ContractDeployReqFee(from, to, daoAmount, daoFee, templateId, args, extData, timeout)
 		if err != nil {
@@ -154,10 +161,11 @@ func (p *Processor) ContractDeployReq(from, to common.Address, daoAmount, daoFee
 	msgReq := &modules.Message{
 		App: modules.APP_CONTRACT_DEPLOY_REQUEST,
 		Payload: &modules.ContractDeployRequestPayload{
-			TemplateId: templateId,
-			Args:       args,
-			ExtData:    extData,
-			Timeout:    uint32(timeout),
+			TemplateId:    templateId,
+			Args:          args,
+			ExtData:       extData,
+			Timeout:       uint32(timeout),
+			UpgradePolicy: policy,
 		},
 	}
 	reqId, tx, err := p.createContractTxReq(common.Address{}, from, to, daoAmount, daoFee, nil, msgReq)
@@ -302,9 +310,69 @@ func (p *Processor) ContractStopReq(from, to common.Address, daoAmount, daoFee u
 	return reqId, nil
 }
 
+//ContractUpgradeHash 返回多签升级策略的管理员需要签名的Hash
+func (p *Processor) ContractUpgradeHash(contractId common.Address, templateId []byte, args [][]byte,
+	timeout uint32) (common.Hash, error) {
+	contract, err := p.dag.GetContract(contractId.Bytes())
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("ContractUpgradeHash, GetContract err:%s", err.Error())
+	}
+	req := &modules.ContractUpgradeRequestPayload{ContractId: contractId.Bytes(), TemplateId: templateId,
+		Args: args, Timeout: timeout}
+	return req.UpgradeHash(contract.UpgradeCount()), nil
+}
+
+//ContractUpgradeReq 把合约升级到新的模板，args为迁移函数的参数，signs为多签升级策略的管理员签名
+func (p *Processor) ContractUpgradeReq(from, to common.Address, daoAmount, daoFee uint64, contractId common.Address,
+	templateId []byte, args [][]byte, timeout uint32, signs []modules.SignatureSet) (common.Hash, error) {
+	if from == (common.Address{}) || to == (common.Address{}) || contractId == (common.Address{}) ||
+		templateId == nil {
+		log.Error("ContractUpgradeReq, param is error")
+		return common.Hash{}, errors.New("ContractUpgradeReq request param is error")
+	}
+	if len(templateId) > MaxLengthTplId || len(args) > MaxNumberArgs {
+		log.Error("ContractUpgradeReq", "request param len overflow, len(templateId)",
+			len(templateId), "len(args)", len(args))
+		return common.Hash{}, errors.New("ContractUpgradeReq request param len overflow")
+	}
+	for _, arg := range args {
+		if len(arg) > MaxLengthArgs {
+			log.Error("ContractUpgradeReq", "request param len overflow,len(arg)", len(arg))
+			return common.Hash{}, errors.New("ContractUpgradeReq request param len overflow")
+		}
+	}
+	if daoFee == 0 { //dynamic calculation fee
+		fee, _, _, err := p.ContractUpgradeReqFee(from, to, daoAmount, daoFee, contractId, templateId, args,
+			timeout, signs)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("ContractUpgradeReq, ContractUpgradeReqFee err:%s", err.Error())
+		}
+		daoFee = uint64(fee) + 1
+		log.Debug("ContractUpgradeReq", "dynamic calculation fee:", daoFee)
+	}
+	msgReq := &modules.Message{
+		App: modules.APP_CONTRACT_UPGRADE_REQUEST,
+		Payload: &modules.ContractUpgradeRequestPayload{
+			ContractId: contractId.Bytes(),
+			TemplateId: templateId,
+			Args:       args,
+			Timeout:    timeout,
+			Signatures: signs,
+		},
+	}
+	reqId, tx, err := p.createContractTxReq(contractId, from, to, daoAmount, daoFee, nil, msgReq)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	log.Infof("[%s]ContractUpgradeReq ok, reqId[%s], contractId[%s], templateId[%x]",
+		shortId(reqId.String()), reqId.String(), contractId.String(), templateId)
+	//broadcast
+	go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_EXEC, Ele: p.mtx[reqId].eleNode, Tx: tx}, true)
+	return reqId, nil
+}
+
 //deploy -->invoke
 func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duration) (rsp []byte, err error) {
-	exist := false
 	chainId := rwset.ChainId
 
 	var lock sync.Mutex
@@ -323,14 +391,34 @@ func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duratio
 
 	log.Debugf("ContractQuery enter, addr[%s][%v]", addr.String(), id)
 
+	if err = p.startUserContract(addr, depTxId.String(), timeout); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("ContractQuery, begin to invoke contract:%s", addr.String())
+	rst, err := p.contract.Invoke(rwset.RwM, chainId, addr.Bytes(), invTxId.String(), args, timeout, modules.DefaultContractGasLimit)
+	rwset.RwM.CloseTxSimulator(chainId, invTxId.String())
+	rwset.RwM.Close()
+	if err != nil {
+		log.Errorf("ContractQuery, id[%s], Invoke err:%s", addr.String(), err.Error())
+		return nil, err
+	}
+	log.Debugf("ContractQuery, id[%s], query result:%s", addr.String(), hex.EncodeToString(rst.Payload))
+	return rst.Payload, nil
+}
+
+//startUserContract 用户合约的容器没有运行时，在本地启动合约容器
+func (p *Processor) startUserContract(addr common.Address, depTxId string, timeout time.Duration) error {
+	exist := false
+	chainId := rwset.ChainId
 	if addr.IsSystemContractAddress() {
-		log.Debugf("ContractQuery, is system contract, addr[%s]", addr.String())
+		log.Debugf("startUserContract, is system contract, addr[%s]", addr.String())
 	} else {
 		//cons, err := utils.GetAllContainers(client)
 		cons, err := p.pDocker.GetAllContainers()
 		if err != nil {
-			log.Errorf("ContractQuery, id[%s], GetAllContainers err:%s", addr.String(), err.Error())
-			return nil, err
+			log.Errorf("startUserContract, id[%s], GetAllContainers err:%s", addr.String(), err.Error())
+			return err
 		}
 		//cas, _ := utils.GetAllContainerAddr(cons, "Up")
 		cas, _ := p.pDocker.GetAllContainersAddrsWithStatus(cons, "Up")
@@ -338,7 +426,7 @@ func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duratio
 			name := ca[:35]
 			contractAddr, _ := common.StringToAddress(name)
 			if contractAddr.Equal(addr) { //use first
-				log.Debugf("ContractQuery, contractId[%s],find container(Up)", addr.String())
+				log.Debugf("startUserContract, contractId[%s],find container(Up)", addr.String())
 				exist = true
 				break
 			}
@@ -346,13 +434,13 @@ func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duratio
 		if !exist {
 			cc, err := p.dag.GetContract(addr.Bytes())
 			if err != nil {
-				log.Errorf("ContractQuery, GetContract err:%s ", err.Error())
-				return nil, err
+				log.Errorf("startUserContract, GetContract err:%s ", err.Error())
+				return err
 			}
 			ct, err := p.dag.GetContractTpl(cc.TemplateId)
 			if err != nil {
-				log.Errorf("ContractQuery, GetContractTpl err:%s ", err.Error())
-				return nil, err
+				log.Errorf("startUserContract, GetContractTpl err:%s ", err.Error())
+				return err
 			}
 			cv := ct.Version + ":" + contractcfg.GetConfig().ContractAddress
 			spec := &pb.ChaincodeSpec{
@@ -373,17 +461,17 @@ func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duratio
 			dag, err := comm.GetCcDagHand()
 			if err != nil {
 				log.Error("getCcDagHand err:", "error", err)
-				return nil, err
+				return err
 			}
 			_, chaincodeData, err := ucc.RecoverChainCodeFromDb(dag, cc.TemplateId)
 			if err != nil {
-				log.Error("ContractQuery", "chainid:", chainId, "templateId:", cc.TemplateId, "RecoverChainCodeFromDb err", err)
-				return nil, err
+				log.Error("startUserContract", "chainid:", chainId, "templateId:", cc.TemplateId, "RecoverChainCodeFromDb err", err)
+				return err
 			}
-			err = ucc.DeployUserCC(addr.Bytes(), chaincodeData, spec, chainId, depTxId.String(), nil, timeout)
+			err = ucc.DeployUserCC(addr.Bytes(), chaincodeData, spec, chainId, depTxId, nil, timeout)
 			if err != nil {
-				log.Error("ContractQuery ", "DeployUserCC error", err)
-				return nil, nil
+				log.Error("startUserContract ", "DeployUserCC error", err)
+				return err
 			}
 			//juryAddrs := p.GetLocalJuryAddrs()
 			//juryAddr := ""
@@ -404,22 +492,13 @@ func (p *Processor) ContractQuery(id []byte, args [][]byte, timeout time.Duratio
 			//if err != nil {
 			//	err = p.dag.SaveChaincode(addr, cInf)
 			//	if err != nil {
-			//		log.Debugf("ContractQuery, SaveChaincode err:%s", err.Error())
+			//		log.Debugf("startUserContract, SaveChaincode err:%s", err.Error())
 			//	}
 			//}
 		}
 	}
 
-	log.Debugf("ContractQuery, begin to invoke contract:%s", addr.String())
-	rst, err := p.contract.Invoke(rwset.RwM, chainId, addr.Bytes(), invTxId.String(), args, timeout)
-	rwset.RwM.CloseTxSimulator(chainId, invTxId.String())
-	rwset.RwM.Close()
-	if err != nil {
-		log.Errorf("ContractQuery, id[%s], Invoke err:%s", addr.String(), err.Error())
-		return nil, err
-	}
-	log.Debugf("ContractQuery, id[%s], query result:%s", addr.String(), hex.EncodeToString(rst.Payload))
-	return rst.Payload, nil
+	return nil
 }
 
 func (p *Processor) ElectionVrfReq(id uint32) ([]byte, error) {
@@ -119,6 +119,32 @@ func (p *Processor) ContractInvokeReqFee(from, to common.Address, daoAmount, dao
 		log.Error("ContractInvokeReqFee", "CreateGenericTransaction err:", err)
 		return 0, 0, 0, err
 	}
+	fee, size, tm, err = p.getTxContractFee(tx, ContractDefaultSignatureSize+ContractDefaultRWSize, timeout)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	//按默认的Gas上限估算合约执行的费用
+	return fee + float64(modules.ContractGasFee(modules.DefaultContractGasLimit)), size, tm, nil
+}
+
+func (p *Processor) ContractUpgradeReqFee(from, to common.Address, daoAmount, daoFee uint64,
+	contractId common.Address, templateId []byte, args [][]byte, timeout uint32,
+	signs []modules.SignatureSet) (fee float64, size float64, tm uint32, err error) {
+	msgReq := &modules.Message{
+		App: modules.APP_CONTRACT_UPGRADE_REQUEST,
+		Payload: &modules.ContractUpgradeRequestPayload{
+			ContractId: contractId.Bytes(),
+			TemplateId: templateId,
+			Args:       args,
+			Timeout:    timeout,
+			Signatures: signs,
+		},
+	}
+	tx, _, err := p.dag.CreateGenericTransaction(from, to, daoAmount, daoFee, nil, msgReq, p.ptn.TxPool())
+	if err != nil {
+		log.Error("ContractUpgradeReqFee", "CreateGenericTransaction err:", err)
+		return 0, 0, 0, err
+	}
 	return p.getTxContractFee(tx, ContractDefaultSignatureSize+ContractDefaultRWSize, timeout)
 }
 
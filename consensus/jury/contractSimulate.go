@@ -0,0 +1,116 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/core/gen"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/dag/rwset"
+)
+
+// ContractSimulateInvoke 在本地模拟执行一次合约调用，返回读写集、付款、事件和估算的手续费，不会广播请求，也不会改变任何状态。
+// stable为true时只读取稳定单元的状态，trace为true时返回合约发出的每一次shim调用
+func (p *Processor) ContractSimulateInvoke(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
+	contractId common.Address, args [][]byte, timeout uint32, gasLimit uint64,
+	stable, trace bool) (*modules.ContractSimulateResult, error) {
+	if from == (common.Address{}) || to == (common.Address{}) || contractId == (common.Address{}) || args == nil {
+		log.Error("ContractSimulateInvoke, param is error")
+		return nil, errors.New("ContractSimulateInvoke request param is error")
+	}
+	if len(args) > MaxNumberArgs {
+		return nil, errors.New("ContractSimulateInvoke request param len overflow")
+	}
+	for _, arg := range args {
+		if len(arg) > MaxLengthArgs {
+			return nil, errors.New("ContractSimulateInvoke request param args len overflow")
+		}
+	}
+	if contractId.IsSystemContractAddress() {
+		return nil, fmt.Errorf("ContractSimulateInvoke, system contract[%s] not support", contractId.String())
+	}
+	reqPay := &modules.ContractInvokeRequestPayload{
+		ContractId: contractId.Bytes(),
+		Args:       args,
+		Timeout:    timeout,
+		GasLimit:   gasLimit,
+	}
+	msgReq := modules.NewMessage(modules.APP_CONTRACT_INVOKE_REQUEST, reqPay)
+	reqTx, _, err := p.dag.CreateGenericTransaction(from, to, daoAmount, daoFee, certID, msgReq, p.ptn.TxPool())
+	if err != nil {
+		log.Errorf("ContractSimulateInvoke, CreateGenericTransaction err:%s", err.Error())
+		return nil, err
+	}
+	//合约参数与陪审员执行时相同
+	fullArgs, err := handleMsg0(reqTx, p.dag, args)
+	if err != nil {
+		return nil, err
+	}
+	fullArgs, err = handleArg1(reqTx, fullArgs)
+	if err != nil {
+		return nil, err
+	}
+	tmout := time.Duration(timeout) * time.Second
+	rd, _ := crypto.GetRandomBytes(32)
+	if err = p.startUserContract(contractId, util.RlpHash(rd).String(), tmout); err != nil {
+		return nil, err
+	}
+
+	//使用独立的读写集管理，模拟执行的结果不会进入正常的合约执行流程
+	rwM, _ := rwset.NewRwSetMgr("simulate")
+	rd, _ = crypto.GetRandomBytes(32)
+	txid := util.RlpHash(rd).String()
+	result, steps, err := p.contract.Simulate(rwM, rwset.ChainId, contractId.Bytes(), txid, fullArgs, tmout,
+		reqPay.GetGasLimit(), stable, trace)
+	rwM.CloseTxSimulator(rwset.ChainId, txid)
+	rwM.Close()
+
+	simulate := &modules.ContractSimulateResult{Trace: steps}
+	var msgs []*modules.Message
+	if err == nil {
+		msgs, err = invokeResultToMsgs(p.dag, reqTx, result)
+	}
+	if err != nil {
+		log.Debugf("ContractSimulateInvoke, contract[%s] execute err:%s", contractId.String(), err.Error())
+		errMsg := createContractErrorPayloadMsg(reqTx, err)
+		simulate.ErrMsg = errMsg.Payload.(*modules.ContractInvokePayload).ErrMsg
+		msgs = append([]*modules.Message{errMsg}, contractPayBack(reqTx, contractId.Bytes())...)
+	} else {
+		simulate.Result = result
+	}
+	tx, err := gen.GenContractTransction(reqTx, msgs)
+	if err != nil {
+		log.Errorf("ContractSimulateInvoke, GenContractTransaction err:%s", err.Error())
+		return nil, err
+	}
+	simulate.Fee, simulate.Size, _, err = p.getTxContractFee(tx, ContractDefaultSignatureSize, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return simulate, nil
+}
@@ -44,9 +44,12 @@ type elector struct {
 	addr     common.Address
 	password string
 	ks       *keystore.KeyStore
+	vrfAlg   string
+	score    func(juror common.Address) uint32 //陪审员的信誉，为空时不调整权重
 }
 
-func newElector(num uint, total uint64, addr common.Address, password string, ks *keystore.KeyStore) *elector {
+func newElector(num uint, total uint64, addr common.Address, password string, ks *keystore.KeyStore,
+	vrfAlg string, score func(juror common.Address) uint32) *elector {
 	e := &elector{
 		num:      num,
 		weight:   electionWeightValue(total),
@@ -54,10 +57,20 @@ func newElector(num uint, total uint64, addr common.Address, password string, ks
 		addr:     addr,
 		password: password,
 		ks:       ks,
+		vrfAlg:   vrfAlg,
+		score:    score,
 	}
 	return e
 }
 
+//jurorWeight 按陪审员的信誉调整后的选举权重
+func (e *elector) jurorWeight(juror common.Address) uint64 {
+	if e.score == nil {
+		return e.weight
+	}
+	return alg.ReputationWeight(e.weight, e.score(juror))
+}
+
 func (e *elector) checkElected(data []byte) (proof []byte, err error) {
 	if e.weight < 1 || data == nil {
 		errs := fmt.Sprintf("checkElected param error, num[%d], weight[%d]", e.num, e.weight)
@@ -71,12 +84,16 @@ func (e *elector) checkElected(data []byte) (proof []byte, err error) {
 		return nil, err
 	}
 
-	proof, sel, err := vrf.VrfProve(privateKey.(*ecdsa.PrivateKey), data)
+	v, err := vrf.GetVrf(e.vrfAlg)
+	if err != nil {
+		return nil, err
+	}
+	proof, sel, err := v.VrfProve(privateKey.(*ecdsa.PrivateKey), data)
 	if err != nil {
 		return nil, err
 	}
 	if len(sel) > 0 {
-		if alg.Selected(e.num, e.weight, e.total, sel) > 0 {
+		if alg.Selected(e.num, e.jurorWeight(e.addr), e.total, sel) > 0 {
 			return proof, nil
 		}
 	}
@@ -84,7 +101,11 @@ func (e *elector) checkElected(data []byte) (proof []byte, err error) {
 }
 
 func (e *elector) verifyVrf(proof, data []byte, pubKey []byte) (bool, error) {
-	ok, pro, err := vrf.VrfVerify(pubKey, data, proof)
+	v, err := vrf.GetVrf(e.vrfAlg)
+	if err != nil {
+		return false, err
+	}
+	ok, pro, err := v.VrfVerify(pubKey, data, proof)
 	if err != nil {
 		log.Error("verifyVrf fail", "ok?", ok)
 		return false, err
@@ -92,7 +113,7 @@ func (e *elector) verifyVrf(proof, data []byte, pubKey []byte) (bool, error) {
 	if ok {
 		vrfValue := pro
 		if len(vrfValue) > 0 {
-			if alg.Selected(e.num, e.weight, e.total, vrfValue) > 0 {
+			if alg.Selected(e.num, e.jurorWeight(crypto.PubkeyBytesToAddress(pubKey)), e.total, vrfValue) > 0 {
 				return true, nil
 			}
 		}
@@ -181,6 +202,23 @@ func (p *Processor) electionEventIsProcess(event *ElectionEvent) (common.Hash, b
 		if haveM {
 			return reqId, true
 		}
+	case ELECTION_EVENT_FAILOVER_REQUEST:
+		evt := event.Event.(*JuryFailoverRequestEvent)
+		reqId = evt.ReqId
+		if haveJ {
+			return reqId, true
+		}
+	case ELECTION_EVENT_FAILOVER_SIG:
+		evt := event.Event.(*JuryFailoverSigEvent)
+		reqId = evt.ElectionId
+		if haveJ {
+			return reqId, true
+		}
+	case ELECTION_EVENT_PRIVATE_STATE, ELECTION_EVENT_PRIVATE_STATE_REQUEST:
+		reqId = util.RlpHash(event.Event)
+		if haveJ {
+			return reqId, true
+		}
 	}
 	return reqId, false
 }
@@ -231,6 +269,10 @@ func (p *Processor) electionEventBroadcast(event *ElectionEvent) (recved bool, i
 				}
 			}
 		}
+	case ELECTION_EVENT_FAILOVER_REQUEST, ELECTION_EVENT_FAILOVER_SIG,
+		ELECTION_EVENT_PRIVATE_STATE, ELECTION_EVENT_PRIVATE_STATE_REQUEST:
+		//重新选举的确认和私有状态同步不使用mel缓存
+		return false, false, nil
 	}
 	if _, ok := p.mel[reqId]; !ok {
 		p.mel[reqId] = &electionVrf{
@@ -255,7 +297,8 @@ func (p *Processor) checkElectionSigRequestEventValid(evt *ElectionSigRequestEve
 		log.Debugf("[%s]checkElectionSigRequestEventValid, len(%d)", shortId(reqId.String()), len(evt.Ele))
 		return false
 	}
-	etor := newElector(uint(cfgEleNum), evt.JuryCount, common.Address{}, "", nil)
+	etor := newElector(uint(cfgEleNum), evt.JuryCount, common.Address{}, "", nil, getSysCfgVrfAlgorithm(p.dag),
+		p.jurorScore)
 	for i, e := range evt.Ele {
 		if e.EType == 1 { //todo
 			continue
@@ -316,7 +359,8 @@ func (p *Processor) processElectionRequestEvent(reqEvt *ElectionRequestEvent) (e
 	if account == nil {
 		return errors.New("processElectionRequestEvent, getLocalJuryAccount fail")
 	}
-	elr := newElector(uint(getSysCfgContractElectionNum(p.dag)), reqEvt.JuryCount, account.Address, account.Password, p.ptn.GetKeyStore())
+	elr := newElector(uint(getSysCfgContractElectionNum(p.dag)), reqEvt.JuryCount, account.Address, account.Password,
+		p.ptn.GetKeyStore(), getSysCfgVrfAlgorithm(p.dag), p.electionScore(reqEvt.SeedUnit))
 
 	addrHash := util.RlpHash(account.Address)
 	proof, err := elr.checkElected(getElectionSeedData(reqEvt.ReqId))
@@ -374,8 +418,13 @@ func (p *Processor) processElectionResultEvent(rstEvt *ElectionResultEvent) erro
 			return nil
 		}
 	}
-	//验证vrf
-	elr := newElector(uint(cfgEleNum), rstEvt.JuryCount, common.Address{}, "", p.ptn.GetKeyStore())
+	//验证vrf，重新选举时按种子单元上生效的信誉计算
+	score := p.jurorScore
+	if re, ok := p.reElections[reqId]; ok {
+		score = p.electionScore(re.payload.SeedUnit)
+	}
+	elr := newElector(uint(cfgEleNum), rstEvt.JuryCount, common.Address{}, "", p.ptn.GetKeyStore(),
+		getSysCfgVrfAlgorithm(p.dag), score)
 	ok, err := elr.verifyVrf(rstEvt.Ele.Proof, getElectionSeedData(reqId), rstEvt.Ele.PublicKey) //rstEvt.ReqId[:]
 	if err != nil {
 		log.Errorf("[%s]processElectionResultEvent, verify VRF fail", shortId(reqId.String()))
@@ -556,6 +605,14 @@ func (p *Processor) ProcessElectionEvent(event *ElectionEvent) (err error) {
 		err = p.processElectionSigRequestEvent(event.Event.(*ElectionSigRequestEvent))
 	} else if event.EType == ELECTION_EVENT_SIG_RESULT {
 		err = p.processElectionSigResultEvent(event.Event.(*ElectionSigResultEvent))
+	} else if event.EType == ELECTION_EVENT_FAILOVER_REQUEST {
+		err = p.processJuryFailoverRequestEvent(event.Event.(*JuryFailoverRequestEvent))
+	} else if event.EType == ELECTION_EVENT_FAILOVER_SIG {
+		err = p.processJuryFailoverSigEvent(event.Event.(*JuryFailoverSigEvent))
+	} else if event.EType == ELECTION_EVENT_PRIVATE_STATE {
+		err = p.processPrivateStateEvent(event.Event.(*PrivateStateEvent))
+	} else if event.EType == ELECTION_EVENT_PRIVATE_STATE_REQUEST {
+		err = p.processPrivateStateRequestEvent(event.Event.(*PrivateStateRequestEvent))
 	}
 
 	return err
@@ -40,6 +40,12 @@ const (
 	ELECTION_EVENT_VRF_RESULT  ElectionEventType = 2
 	ELECTION_EVENT_SIG_REQUEST ElectionEventType = 3
 	ELECTION_EVENT_SIG_RESULT  ElectionEventType = 4
+
+	ELECTION_EVENT_FAILOVER_REQUEST ElectionEventType = 5 //请求原陪审团中的其他陪审员确认合约请求超时
+	ELECTION_EVENT_FAILOVER_SIG     ElectionEventType = 6 //原陪审员确认超时后对重新选举的签名
+
+	ELECTION_EVENT_PRIVATE_STATE         ElectionEventType = 7 //用合约当前陪审团的公钥重新加密的私有状态
+	ELECTION_EVENT_PRIVATE_STATE_REQUEST ElectionEventType = 8 //陪审员缺少私有状态时向陪审团请求
 )
 
 type AdapterInf struct {
@@ -62,6 +68,8 @@ type ContractEvent struct {
 	CType ContractEventType
 	Ele   *modules.ElectionNode `rlp:"nil"`
 	Tx    *modules.Transaction
+	//签名事件附带合约执行产生的私有状态，用陪审员的公钥加密
+	PrivateStates []*modules.PrivateState `rlp:"tail"`
 }
 
 func (ce *ContractEvent) Hash() common.Hash {
@@ -72,6 +80,7 @@ func (ce *ContractEvent) Hash() common.Hash {
 type ElectionRequestEvent struct {
 	ReqId     common.Hash
 	JuryCount uint64
+	SeedUnit  common.Hash //重新选举绑定的单元，选举权重按该单元上生效的信誉计算
 }
 type ElectionResultEvent struct {
 	ReqId     common.Hash
@@ -91,6 +100,28 @@ type ElectionSigResultEvent struct {
 	Sig       modules.SignatureSet
 }
 
+//failover
+type JuryFailoverRequestEvent struct {
+	ReqId      common.Hash //超时的合约请求
+	ContractId []byte
+	PrevJury   common.Hash
+	SeedUnit   common.Hash
+}
+type JuryFailoverSigEvent struct {
+	ElectionId common.Hash
+	Sig        modules.SignatureSet
+}
+
+//private state
+type PrivateStateEvent struct {
+	ContractId []byte
+	States     []*modules.PrivateState
+}
+type PrivateStateRequestEvent struct {
+	ContractId []byte
+	Keys       []string //缺少的私有状态在合约状态中的Key
+}
+
 type ElectionEvent struct {
 	EType ElectionEventType `json:"etype"`
 	Event interface{}       `json:"event"`
@@ -136,6 +167,34 @@ func (es *ElectionEventBytes) ToElectionEvent() (*ElectionEvent, error) {
 			return nil, err
 		}
 		event.Event = &evt
+	} else if es.EType == ELECTION_EVENT_FAILOVER_REQUEST {
+		var evt JuryFailoverRequestEvent
+		err = json.Unmarshal(es.Event, &evt)
+		if err != nil {
+			return nil, err
+		}
+		event.Event = &evt
+	} else if es.EType == ELECTION_EVENT_FAILOVER_SIG {
+		var evt JuryFailoverSigEvent
+		err = json.Unmarshal(es.Event, &evt)
+		if err != nil {
+			return nil, err
+		}
+		event.Event = &evt
+	} else if es.EType == ELECTION_EVENT_PRIVATE_STATE {
+		var evt PrivateStateEvent
+		err = json.Unmarshal(es.Event, &evt)
+		if err != nil {
+			return nil, err
+		}
+		event.Event = &evt
+	} else if es.EType == ELECTION_EVENT_PRIVATE_STATE_REQUEST {
+		var evt PrivateStateRequestEvent
+		err = json.Unmarshal(es.Event, &evt)
+		if err != nil {
+			return nil, err
+		}
+		event.Event = &evt
 	}
 	return &event, nil
 }
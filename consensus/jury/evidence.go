@@ -0,0 +1,51 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"encoding/json"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//SubmitEquivocationEvidence 向保证金合约提交Mediator作恶证据，由合约验证签名后自动没收保证金
+func (p *Processor) SubmitEquivocationEvidence(from common.Address,
+	evidence *modules.MediatorEquivocationEvidence) (common.Hash, error) {
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	depositAddr := syscontract.DepositContractAddress
+	args := [][]byte{[]byte(modules.SubmitEquivocationEvidence), data}
+	fee, _, _, err := p.ContractInvokeReqFee(from, depositAddr, 0, 0, nil, depositAddr, args, 0)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	reqId, err := p.ContractInvokeReq(from, depositAddr, 0, uint64(fee)+1, nil, depositAddr, args, 0)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	log.Infof("[%s]SubmitEquivocationEvidence, mediator[%s], evidence[%s]", shortId(reqId.String()),
+		evidence.Mediator().String(), evidence.Hash().String())
+	return reqId, nil
+}
@@ -0,0 +1,423 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"bytes"
+	"math"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/core/accounts"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/dag/errors"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//重新选举在该时间内没有完成则放弃，等待下一次超时重新发起。
+//选举种子绑定的单元很快会超过JuryUpdateSeedMaxAge，不能等待太久
+const juryReElectionExpiry = time.Second * 60
+
+//juryReElection 合约陪审员掉线后的重新选举
+type juryReElection struct {
+	payload   *modules.ContractJuryUpdatePayload //EleNode为空表示还在收集VRF选举结果
+	jury      *modules.ElectionNode              //被替换的陪审团
+	sigs      []modules.SignatureSet             //原陪审员确认超时后对ElectionId的签名
+	pending   map[common.Hash]bool               //等待改由新陪审团执行的合约请求
+	submitted bool                               //已经提交陪审团更新交易
+	tm        time.Time
+}
+
+//juryFailoverLoop 检查本地已签名但长时间收集不到足够签名的用户合约请求，
+//如果是因为陪审员掉线，则请求原陪审团中的其他陪审员确认，同时在陪审员候选人中重新选举，
+//收集到足够的确认签名后把新陪审团记录到链上，然后重新发起请求
+func (p *Processor) juryFailoverLoop() {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			p.checkJuryFailover()
+			p.submitJuryUpdates()
+			p.rerouteContractReqs()
+		}
+	}
+}
+
+//txSigners 返回交易中签名的陪审员地址hash
+func txSigners(tx *modules.Transaction) map[common.Hash]bool {
+	signers := make(map[common.Hash]bool)
+	if tx == nil {
+		return signers
+	}
+	for _, msg := range tx.Messages() {
+		if msg.App == modules.APP_SIGNATURE {
+			for _, sig := range msg.Payload.(*modules.SignaturePayload).Signatures {
+				signers[util.RlpHash(crypto.PubkeyBytesToAddress(sig.PubKey))] = true
+			}
+		}
+	}
+	return signers
+}
+
+//absentJurors 返回没有返回任何签名的陪审员，执行结果不一致的陪审员仍然在线，不算掉线
+func absentJurors(jury *modules.ElectionNode, ctx *contractTx) []common.Hash {
+	responded := txSigners(ctx.sigTx)
+	for _, tx := range ctx.rcvTx {
+		for signer := range txSigners(tx) {
+			responded[signer] = true
+		}
+	}
+	absent := make([]common.Hash, 0)
+	for _, e := range jury.EleList {
+		if !responded[e.AddrHash] {
+			absent = append(absent, e.AddrHash)
+		}
+	}
+	return absent
+}
+
+//stalledJury 检查合约请求是否在本地签名后超时，且本地收到的签名显示在线的陪审员已经凑不够签名，
+//返回合约当前的陪审团
+func (p *Processor) stalledJury(reqId common.Hash, ctx *contractTx, cfgSigNum int) (*modules.ElectionNode, bool) {
+	if ctx == nil || !ctx.valid || ctx.sigTx == nil || ctx.rstTx != nil || ctx.reqTx == nil ||
+		ctx.reqTx.IsSystemContract() {
+		return nil, false
+	}
+	if time.Since(ctx.tm) < p.failoverTimeout || getTxSigNum(ctx.sigTx) >= cfgSigNum {
+		return nil, false
+	}
+	if cType, err := getContractTxType(ctx.reqTx); err != nil || cType != modules.APP_CONTRACT_INVOKE_REQUEST {
+		return nil, false
+	}
+	if p.checkTxReqIdIsExist(reqId) {
+		return nil, false
+	}
+	jury, err := p.dag.GetContractJury(ctx.reqTx.GetContractId())
+	if err != nil {
+		return nil, false
+	}
+	if len(jury.EleList)-len(absentJurors(jury, ctx)) >= cfgSigNum {
+		return nil, false
+	}
+	return jury, true
+}
+
+//isRecentUnit 单元在本地存在，且与最新单元的高度相差不超过JuryUpdateSeedMaxAge
+func (p *Processor) isRecentUnit(hash common.Hash) bool {
+	header, err := p.dag.GetHeaderByHash(hash)
+	if err != nil || header == nil {
+		return false
+	}
+	_, index, err := p.dag.GetNewestUnit(header.GetAssetId())
+	if err != nil || index == nil {
+		return false
+	}
+	height := header.NumberU64()
+	return height <= index.Index && index.Index-height <= modules.JuryUpdateSeedMaxAge
+}
+
+//signJuryUpdate 本地陪审员属于被替换的陪审团时，对重新选举的ElectionId签名
+func (p *Processor) signJuryUpdate(payload *modules.ContractJuryUpdatePayload,
+	jury *modules.ElectionNode) (*modules.SignatureSet, error) {
+	account := p.getLocalJuryAccount()
+	if account == nil {
+		return nil, errors.New("signJuryUpdate, not find local jury account")
+	}
+	if !jury.HasAddrHash(util.RlpHash(account.Address)) {
+		return nil, errors.New("signJuryUpdate, local jury account is not in the jury")
+	}
+	ks := p.ptn.GetKeyStore()
+	sig, err := ks.SignMessageWithPassphrase(accounts.Account{Address: account.Address}, account.Password,
+		payload.ElectionId().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := ks.GetPublicKey(account.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &modules.SignatureSet{PubKey: pubKey, Signature: sig}, nil
+}
+
+func (p *Processor) getReElection(prevJury common.Hash) *juryReElection {
+	for _, re := range p.reElections {
+		if re.payload.PrevJury == prevJury {
+			return re
+		}
+	}
+	return nil
+}
+
+func (p *Processor) checkJuryFailover() {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	cfgSigNum := getSysCfgContractSignatureNum(p.dag)
+	for reqId, ctx := range p.mtx {
+		jury, stalled := p.stalledJury(reqId, ctx, cfgSigNum)
+		if !stalled {
+			continue
+		}
+		if re := p.getReElection(jury.Hash()); re != nil {
+			re.pending[reqId] = true
+			continue
+		}
+		contractId := ctx.reqTx.GetContractId()
+		seedUnit, _, err := p.dag.GetNewestUnit(modules.PTNCOIN)
+		if err != nil {
+			continue
+		}
+		payload := &modules.ContractJuryUpdatePayload{ContractId: contractId, PrevJury: jury.Hash(), SeedUnit: seedUnit}
+		sig, err := p.signJuryUpdate(payload, jury)
+		if err != nil {
+			log.Debugf("[%s]checkJuryFailover, %s", shortId(reqId.String()), err.Error())
+			continue
+		}
+		eleId := payload.ElectionId()
+		log.Infof("[%s]checkJuryFailover, contract[%x] signature timeout, absent jurors:%v",
+			shortId(reqId.String()), contractId, absentJurors(jury, ctx))
+
+		p.reElections[eleId] = &juryReElection{
+			payload: payload,
+			jury:    jury,
+			sigs:    []modules.SignatureSet{*sig},
+			pending: map[common.Hash]bool{reqId: true},
+			tm:      time.Now(),
+		}
+		p.mel[eleId] = &electionVrf{
+			rcvEle: make([]modules.ElectionInf, 0),
+			sigs:   make([]modules.SignatureSet, 0),
+			tm:     time.Now(),
+			nType:  1,
+			brded:  true,
+		}
+		reqEvent := &ElectionRequestEvent{
+			ReqId:     eleId,
+			JuryCount: uint64(p.dag.JuryCount()),
+			SeedUnit:  seedUnit,
+		}
+		failoverEvent := &JuryFailoverRequestEvent{
+			ReqId:      reqId,
+			ContractId: contractId,
+			PrevJury:   payload.PrevJury,
+			SeedUnit:   seedUnit,
+		}
+		go p.ptn.ElectionBroadcast(ElectionEvent{EType: ELECTION_EVENT_VRF_REQUEST, Event: reqEvent}, true)
+		go p.ptn.ElectionBroadcast(ElectionEvent{EType: ELECTION_EVENT_FAILOVER_REQUEST, Event: failoverEvent}, false)
+	}
+}
+
+//processJuryFailoverRequestEvent 原陪审团中的其他陪审员只有在本地也观察到同一个请求超时，
+//且在线的陪审员凑不够签名时，才对重新选举签名
+func (p *Processor) processJuryFailoverRequestEvent(evt *JuryFailoverRequestEvent) error {
+	if evt == nil {
+		return errors.New("processJuryFailoverRequestEvent, param is nil")
+	}
+	if p.failoverTimeout <= 0 {
+		return nil
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	reqId := evt.ReqId
+	ctx := p.mtx[reqId]
+	if ctx == nil || ctx.reqTx == nil || !bytes.Equal(ctx.reqTx.GetContractId(), evt.ContractId) {
+		return nil
+	}
+	jury, stalled := p.stalledJury(reqId, ctx, getSysCfgContractSignatureNum(p.dag))
+	if !stalled || jury.Hash() != evt.PrevJury {
+		log.Debugf("[%s]processJuryFailoverRequestEvent, request is not stalled locally", shortId(reqId.String()))
+		return nil
+	}
+	if !p.isRecentUnit(evt.SeedUnit) {
+		log.Debugf("[%s]processJuryFailoverRequestEvent, seed unit[%s] is not a recent unit",
+			shortId(reqId.String()), evt.SeedUnit.String())
+		return nil
+	}
+	payload := &modules.ContractJuryUpdatePayload{ContractId: evt.ContractId, PrevJury: evt.PrevJury,
+		SeedUnit: evt.SeedUnit}
+	sig, err := p.signJuryUpdate(payload, jury)
+	if err != nil {
+		return err
+	}
+	sigEvent := &JuryFailoverSigEvent{ElectionId: payload.ElectionId(), Sig: *sig}
+	go p.ptn.ElectionBroadcast(ElectionEvent{EType: ELECTION_EVENT_FAILOVER_SIG, Event: sigEvent}, false)
+	return nil
+}
+
+//processJuryFailoverSigEvent 发起重新选举的陪审员收集原陪审员的签名
+func (p *Processor) processJuryFailoverSigEvent(evt *JuryFailoverSigEvent) error {
+	if evt == nil {
+		return errors.New("processJuryFailoverSigEvent, param is nil")
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	re, ok := p.reElections[evt.ElectionId]
+	if !ok || re.submitted {
+		return nil
+	}
+	if !re.jury.HasAddrHash(util.RlpHash(crypto.PubkeyBytesToAddress(evt.Sig.PubKey))) {
+		return nil
+	}
+	for _, sig := range re.sigs {
+		if bytes.Equal(sig.PubKey, evt.Sig.PubKey) {
+			return nil
+		}
+	}
+	pass, err := crypto.MyCryptoLib.Verify(evt.Sig.PubKey, evt.Sig.Signature, evt.ElectionId.Bytes())
+	if err != nil || !pass {
+		return errors.New("processJuryFailoverSigEvent, verify signature fail")
+	}
+	re.sigs = append(re.sigs, evt.Sig)
+	return nil
+}
+
+//submitJuryUpdates 收集到足够的VRF选举结果后，提交陪审团更新交易
+func (p *Processor) submitJuryUpdates() {
+	p.locker.Lock()
+	payloads := make([]*modules.ContractJuryUpdatePayload, 0)
+	cfgEleNum := getSysCfgContractElectionNum(p.dag)
+	cfgSigNum := getSysCfgContractSignatureNum(p.dag)
+	for eleId, re := range p.reElections {
+		if time.Since(re.tm) > juryReElectionExpiry {
+			log.Infof("[%s]submitJuryUpdates, re-election of contract[%x] expired",
+				shortId(eleId.String()), re.payload.ContractId)
+			delete(p.reElections, eleId)
+			continue
+		}
+		mel := p.mel[eleId]
+		if re.submitted || mel == nil {
+			continue
+		}
+		//原陪审团中确认超时的陪审员数量不足
+		if len(re.sigs) < modules.JuryUpdateSignThreshold(len(re.jury.EleList), cfgSigNum) {
+			continue
+		}
+		//只能从陪审员候选人中选举
+		candidates := make([]modules.ElectionInf, 0)
+		for _, e := range mel.rcvEle {
+			if p.dag.IsActiveJury(crypto.PubkeyBytesToAddress(e.PublicKey)) {
+				candidates = append(candidates, e)
+			}
+		}
+		se, valid := p.selectElectionInf(nil, randSelectEle(candidates), cfgEleNum)
+		if !valid {
+			continue
+		}
+		re.payload.EleNode = modules.ElectionNode{JuryCount: mel.juryCnt, EleList: se}
+		re.payload.Signatures = re.sigs
+		re.submitted = true
+		payloads = append(payloads, re.payload)
+	}
+	p.locker.Unlock()
+
+	for _, payload := range payloads {
+		txHash, err := p.submitJuryUpdate(payload)
+		if err != nil {
+			log.Errorf("[%s]submitJuryUpdates, contract[%x] err:%s",
+				shortId(payload.ElectionId().String()), payload.ContractId, err.Error())
+			p.locker.Lock()
+			if re, ok := p.reElections[payload.ElectionId()]; ok {
+				re.submitted = false
+			}
+			p.locker.Unlock()
+			continue
+		}
+		log.Infof("[%s]submitJuryUpdates, contract[%x] jury update tx[%s]",
+			shortId(payload.ElectionId().String()), payload.ContractId, txHash.String())
+	}
+}
+
+func (p *Processor) submitJuryUpdate(payload *modules.ContractJuryUpdatePayload) (common.Hash, error) {
+	account := p.getLocalJuryAccount()
+	if account == nil {
+		return common.Hash{}, errors.New("submitJuryUpdate, not find local jury account")
+	}
+	msg := modules.NewMessage(modules.APP_CONTRACT_JURY_UPDATE, payload)
+	tx, _, err := p.dag.CreateGenericTransaction(account.Address, account.Address, 0, 0, nil, msg, p.ptn.TxPool())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	//与APP_DATA相同，按交易大小收取手续费
+	size := tx.Size().Float64() + ContractDefaultSignatureSize
+	fee := uint64(math.Ceil(float64(p.dag.GetChainParameters().TransferPtnPricePerKByte) * size / 1024))
+	tx, _, err = p.dag.CreateGenericTransaction(account.Address, account.Address, 0, fee, nil, msg, p.ptn.TxPool())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx, err = p.ptn.SignGenericTransaction(account.Address, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err = p.ptn.TxPool().AddLocal(tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+//rerouteContractReqs 新陪审团记录到链上后，把等待的合约请求发给新陪审团执行
+func (p *Processor) rerouteContractReqs() {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for eleId, re := range p.reElections {
+		if !re.submitted {
+			continue
+		}
+		jury, err := p.dag.GetContractJury(re.payload.ContractId)
+		if err != nil || jury.Hash() == re.payload.PrevJury {
+			continue
+		}
+		//陪审团已经被替换，可能是其他陪审员发起的重新选举
+		delete(p.reElections, eleId)
+		for reqId := range re.pending {
+			ctx := p.mtx[reqId]
+			if ctx == nil || ctx.reqTx == nil || ctx.rstTx != nil || p.checkTxReqIdIsExist(reqId) {
+				continue
+			}
+			log.Infof("[%s]rerouteContractReqs, contract[%x] reroute to jury[%s]",
+				shortId(reqId.String()), re.payload.ContractId, jury.Hash().String())
+			ctx.eleNode = jury
+			ctx.sigTx = nil
+			ctx.rcvTx = nil
+			ctx.reqRcvEd = false
+			ctx.tm = time.Now()
+			go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_EXEC, Ele: jury, Tx: ctx.reqTx}, true)
+		}
+	}
+}
+
+//isContractJury 检查ele是否为合约当前记录在链上的陪审团
+func (p *Processor) isContractJury(contractId []byte, ele *modules.ElectionNode) bool {
+	if ele == nil || !common.IsUserContractId(contractId) {
+		return false
+	}
+	jury, err := p.dag.GetContractJury(contractId)
+	if err != nil {
+		return false
+	}
+	return jury.Hash() == ele.Hash()
+}
@@ -0,0 +1,116 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJurorSigTx(t *testing.T, pubKeys ...[]byte) *modules.Transaction {
+	tx := modules.NewTransaction([]*modules.Message{})
+	for _, pubKey := range pubKeys {
+		assert.Nil(t, addContractSignatureSet(tx, &modules.SignatureSet{PubKey: pubKey}))
+	}
+	return tx
+}
+
+func TestAbsentJurors(t *testing.T) {
+	pubKeys := make([][]byte, 4)
+	jury := &modules.ElectionNode{JuryCount: 20}
+	for i := range pubKeys {
+		key, _ := crypto.MyCryptoLib.KeyGen()
+		privKey, _ := crypto.ToECDSA(key)
+		pubKeys[i] = crypto.CompressPubkey(&privKey.PublicKey)
+		addrHash := util.RlpHash(crypto.PubkeyBytesToAddress(pubKeys[i]))
+		jury.EleList = append(jury.EleList, modules.ElectionInf{AddrHash: addrHash, PublicKey: pubKeys[i]})
+	}
+
+	//只有本地签名
+	ctx := &contractTx{sigTx: newJurorSigTx(t, pubKeys[0])}
+	assert.Equal(t, 3, len(absentJurors(jury, ctx)))
+
+	//收到的签名已经合并到本地
+	ctx.sigTx = newJurorSigTx(t, pubKeys[0], pubKeys[1])
+	absent := absentJurors(jury, ctx)
+	assert.Equal(t, []common.Hash{jury.EleList[2].AddrHash, jury.EleList[3].AddrHash}, absent)
+
+	//执行结果不一致的陪审员仍然在线
+	ctx.rcvTx = []*modules.Transaction{newJurorSigTx(t, pubKeys[2])}
+	absent = absentJurors(jury, ctx)
+	assert.Equal(t, []common.Hash{jury.EleList[3].AddrHash}, absent)
+}
+
+func TestJuryUpdateElectionSeed(t *testing.T) {
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	jury := &modules.ElectionNode{JuryCount: 20}
+	payload := &modules.ContractJuryUpdatePayload{ContractId: contractId, PrevJury: jury.Hash()}
+	//重新选举复用合约部署时的VRF选举流程，以ElectionId作为选举请求Id
+	assert.Equal(t, payload.ElectionSeed(), getElectionSeedData(payload.ElectionId()))
+
+	//替换后的陪审团再次掉线时使用新的种子
+	jury.EleList = append(jury.EleList, modules.ElectionInf{AddrHash: common.HexToHash("0x02")})
+	next := &modules.ContractJuryUpdatePayload{ContractId: contractId, PrevJury: jury.Hash()}
+	assert.NotEqual(t, payload.ElectionSeed(), next.ElectionSeed())
+
+	//种子与发起时的最新单元绑定
+	bound := &modules.ContractJuryUpdatePayload{ContractId: contractId, PrevJury: jury.Hash(),
+		SeedUnit: common.HexToHash("0x03")}
+	assert.NotEqual(t, next.ElectionSeed(), bound.ElectionSeed())
+}
+
+func TestProcessJuryFailoverSigEvent(t *testing.T) {
+	keys := make([][]byte, 3)
+	pubKeys := make([][]byte, 3)
+	for i := range keys {
+		keys[i], _ = crypto.MyCryptoLib.KeyGen()
+		pubKeys[i], _ = crypto.MyCryptoLib.PrivateKeyToPubKey(keys[i])
+	}
+	//第3个不是原陪审员
+	jury := &modules.ElectionNode{JuryCount: 20}
+	for _, pubKey := range pubKeys[:2] {
+		addrHash := util.RlpHash(crypto.PubkeyBytesToAddress(pubKey))
+		jury.EleList = append(jury.EleList, modules.ElectionInf{AddrHash: addrHash, PublicKey: pubKey})
+	}
+	payload := &modules.ContractJuryUpdatePayload{PrevJury: jury.Hash(), SeedUnit: common.HexToHash("0x03")}
+	eleId := payload.ElectionId()
+	p := &Processor{locker: new(sync.Mutex), reElections: map[common.Hash]*juryReElection{
+		eleId: {payload: payload, jury: jury}}}
+	sigEvent := func(i int, data []byte) *JuryFailoverSigEvent {
+		sig, err := crypto.MyCryptoLib.Sign(keys[i], data)
+		assert.Nil(t, err)
+		return &JuryFailoverSigEvent{ElectionId: eleId, Sig: modules.SignatureSet{PubKey: pubKeys[i], Signature: sig}}
+	}
+
+	assert.Nil(t, p.processJuryFailoverSigEvent(sigEvent(0, eleId.Bytes())))
+	assert.Nil(t, p.processJuryFailoverSigEvent(sigEvent(0, eleId.Bytes())))
+	assert.Nil(t, p.processJuryFailoverSigEvent(sigEvent(2, eleId.Bytes())))
+	assert.NotNil(t, p.processJuryFailoverSigEvent(sigEvent(1, common.HexToHash("0x04").Bytes())))
+	assert.Equal(t, 1, len(p.reElections[eleId].sigs))
+
+	assert.Nil(t, p.processJuryFailoverSigEvent(sigEvent(1, eleId.Bytes())))
+	assert.Equal(t, 2, len(p.reElections[eleId].sigs))
+}
@@ -79,51 +79,13 @@ func resultToContractPayments(dag iDag, requestTx *modules.Transaction, result *
 		paytoContractUtxo = requestTx.GetNewUtxos()
 	}
 	if result.TokenPayOut != nil && len(result.TokenPayOut) > 0 {
-		payouts := tokenPayOutGroupByAsset(result.TokenPayOut)
-		for ast, aa := range payouts {
-			ast1 := ast
-			asset := &ast1
-			utxos, err := dag.GetAddr1TokenUtxos(addr, asset)
+		//被调用合约的付款从被调用合约地址付出
+		for _, src := range tokenPayOutGroupBySource(addr, result.TokenPayOut) {
+			srcPayments, err := payOutToPayments(dag, src.Address, src.payouts, paytoContractUtxo)
 			if err != nil {
 				return nil, err
 			}
-			//本次Request付款到合约的Utxo，可以在Result中马上Payout
-			for out, utxo := range paytoContractUtxo {
-				toAddr, _ := tokenengine.Instance.GetAddressFromScript(utxo.PkScript)
-				if utxo.Asset.Equal(asset) && toAddr == addr {
-					out.TxHash = common.NewSelfHash()
-					utxos[out] = utxo
-				}
-			}
-			utxo2 := convertMapUtxo(utxos)
-			us := core.Utxos{}
-			for _, u := range utxo2 {
-				us = append(us, u)
-			}
-			totalPayAmt := uint64(0)
-			for _, a := range aa {
-				totalPayAmt += a.Amount
-			}
-			selected, change, err := core.Select_utxo_Greedy(us, totalPayAmt)
-			if err != nil {
-				return nil, err
-			}
-			payment := &modules.PaymentPayload{}
-			for _, s := range selected {
-				sutxo := s.(*modules.UtxoWithOutPoint)
-				in := modules.NewTxIn(&sutxo.OutPoint, nil)
-				payment.AddTxIn(in)
-			}
-			for _, a := range aa {
-				out := modules.NewTxOut(a.Amount, tokenengine.Instance.GenerateLockScript(a.Address), asset)
-				payment.AddTxOut(out)
-			}
-			//Change
-			if change > 0 {
-				out2 := modules.NewTxOut(change, tokenengine.Instance.GenerateLockScript(addr), asset)
-				payment.AddTxOut(out2)
-			}
-			payments = append(payments, payment)
+			payments = append(payments, srcPayments...)
 		}
 	} else {
 		utxos, err := dag.GetAddr1TokenUtxos(addr, nil)
@@ -139,6 +101,124 @@ func resultToContractPayments(dag iDag, requestTx *modules.Transaction, result *
 	return payments, nil
 }
 
+func payOutToPayments(dag iDag, addr common.Address, tokenPayOut []*modules.TokenPayOut,
+	paytoContractUtxo map[modules.OutPoint]*modules.Utxo) ([]*modules.PaymentPayload, error) {
+	payments := []*modules.PaymentPayload{}
+	payouts := tokenPayOutGroupByAsset(tokenPayOut)
+	for ast, aa := range payouts {
+		ast1 := ast
+		asset := &ast1
+		utxos, err := dag.GetAddr1TokenUtxos(addr, asset)
+		if err != nil {
+			return nil, err
+		}
+		//本次Request付款到合约的Utxo，可以在Result中马上Payout
+		for out, utxo := range paytoContractUtxo {
+			toAddr, _ := tokenengine.Instance.GetAddressFromScript(utxo.PkScript)
+			if utxo.Asset.Equal(asset) && toAddr == addr {
+				out.TxHash = common.NewSelfHash()
+				utxos[out] = utxo
+			}
+		}
+		utxo2 := convertMapUtxo(utxos)
+		us := core.Utxos{}
+		for _, u := range utxo2 {
+			us = append(us, u)
+		}
+		totalPayAmt := uint64(0)
+		for _, a := range aa {
+			totalPayAmt += a.Amount
+		}
+		selected, change, err := core.Select_utxo_Greedy(us, totalPayAmt)
+		if err != nil {
+			return nil, err
+		}
+		payment := &modules.PaymentPayload{}
+		for _, s := range selected {
+			sutxo := s.(*modules.UtxoWithOutPoint)
+			in := modules.NewTxIn(&sutxo.OutPoint, nil)
+			payment.AddTxIn(in)
+		}
+		for _, a := range aa {
+			out := modules.NewTxOut(a.Amount, tokenengine.Instance.GenerateLockScript(a.Address), asset)
+			payment.AddTxOut(out)
+		}
+		//Change
+		if change > 0 {
+			out2 := modules.NewTxOut(change, tokenengine.Instance.GenerateLockScript(addr), asset)
+			payment.AddTxOut(out2)
+		}
+		payments = append(payments, payment)
+	}
+	return payments, nil
+}
+
+type sourcePayOut struct {
+	Address common.Address
+	payouts []*modules.TokenPayOut
+}
+
+//按付款的合约地址分组，保持付款出现的顺序
+func tokenPayOutGroupBySource(contractAddr common.Address, payouts []*modules.TokenPayOut) []*sourcePayOut {
+	result := []*sourcePayOut{}
+	for _, payout := range payouts {
+		from := payout.PayFrom
+		if from == (common.Address{}) {
+			from = contractAddr
+		}
+		var src *sourcePayOut
+		for _, s := range result {
+			if s.Address == from {
+				src = s
+				break
+			}
+		}
+		if src == nil {
+			src = &sourcePayOut{Address: from}
+			result = append(result, src)
+		}
+		src.payouts = append(src.payouts, payout)
+	}
+	return result
+}
+
+//calleeJury 跨陪审团调用中与调用合约陪审团不同的被调用合约陪审团
+type calleeJury struct {
+	contractAddr common.Address
+	ele          *modules.ElectionNode
+}
+
+//getContractCalleeJuries 合约调用结果中读写了状态或者付款的被调用合约，其陪审团与调用合约不同时为跨陪审团调用，
+//被调用合约的陪审团也要执行合约并签名，其合约地址的付款使用被调用合约陪审团的赎回脚本解锁
+func getContractCalleeJuries(dag iDag, tx *modules.Transaction) ([]*calleeJury, error) {
+	if reqType, _ := getContractTxType(tx); reqType != modules.APP_CONTRACT_INVOKE_REQUEST {
+		return nil, nil
+	}
+	callees, err := tx.GetContractCalleeAddrs(dag.GetUtxoEntry, tokenengine.Instance.GetAddressFromScript)
+	if err != nil || len(callees) == 0 {
+		return nil, err
+	}
+	callerJury, err := dag.GetContractJury(tx.GetContractId())
+	if err != nil {
+		return nil, fmt.Errorf("get jury of caller fail:%s", err.Error())
+	}
+	result := []*calleeJury{}
+	for _, callee := range callees {
+		ele, err := dag.GetContractJury(callee.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("get jury of callee %s fail:%s", callee.String(), err.Error())
+		}
+		if !isSameJury(callerJury, ele) {
+			result = append(result, &calleeJury{contractAddr: callee, ele: ele})
+		}
+	}
+	return result, nil
+}
+
+func isSameJury(a, b *modules.ElectionNode) bool {
+	return a != nil && a.SameJurors(b)
+}
+
 type addrAmount struct {
 	Address common.Address
 	Amount  uint64
@@ -138,3 +138,36 @@ func TestMergeUtxoPayments(t *testing.T) {
 		fmt.Println("TestMergeUtxoPayments", "ok, payment", pay)
 	}
 }
+
+func TestTokenPayOutGroupBySource(t *testing.T) {
+	ptn := modules.NewPTNAsset()
+	contractAddr, _ := common.StringToAddress("PCGTta3M4t3yXu8uRgkKvaWd2d8DR32W9vM")
+	calleeAddr, _ := common.StringToAddress("PCGTta3M4t3yXu8uRgkKvaWd2d8DRijspoq")
+	payouts := []*modules.TokenPayOut{
+		{PayTo: addr1, Amount: 1, Asset: ptn},
+		{PayTo: addr2, Amount: 2, Asset: ptn, PayFrom: calleeAddr},
+		{PayTo: addr2, Amount: 3, Asset: ptn},
+	}
+	result := tokenPayOutGroupBySource(contractAddr, payouts)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, contractAddr, result[0].Address)
+	assert.Equal(t, 2, len(result[0].payouts))
+	assert.Equal(t, calleeAddr, result[1].Address)
+	assert.Equal(t, 1, len(result[1].payouts))
+}
+
+func TestIsSameJury(t *testing.T) {
+	a := &modules.ElectionNode{EleList: []modules.ElectionInf{{AddrHash: common.HexToHash("01")},
+		{AddrHash: common.HexToHash("02")}}}
+	b := &modules.ElectionNode{EleList: []modules.ElectionInf{{AddrHash: common.HexToHash("02")},
+		{AddrHash: common.HexToHash("01")}}}
+	c := &modules.ElectionNode{EleList: []modules.ElectionInf{{AddrHash: common.HexToHash("01")},
+		{AddrHash: common.HexToHash("03")}}}
+	//陪审员相同但顺序不同，赎回脚本不同
+	assert.False(t, isSameJury(a, b))
+	d := &modules.ElectionNode{EleList: []modules.ElectionInf{{AddrHash: common.HexToHash("01")},
+		{AddrHash: common.HexToHash("02")}}}
+	assert.True(t, isSameJury(a, d))
+	assert.False(t, isSameJury(a, c))
+	assert.False(t, isSameJury(a, nil))
+}
@@ -0,0 +1,114 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//jurorScore 陪审员在链上记录的信誉，用于调整VRF选举权重
+func (p *Processor) jurorScore(juror common.Address) uint32 {
+	return p.dag.GetJurorReputation(juror).Score()
+}
+
+//electionScore 重新选举时按种子单元上生效的信誉计算选举权重，与验证陪审团更新交易时的计算一致
+func (p *Processor) electionScore(seedUnit common.Hash) func(juror common.Address) uint32 {
+	if seedUnit == (common.Hash{}) {
+		return p.jurorScore
+	}
+	header, err := p.dag.GetHeaderByHash(seedUnit)
+	if err != nil || header == nil {
+		return p.jurorScore
+	}
+	return func(juror common.Address) uint32 {
+		return p.dag.GetJurorReputation(juror).ScoreAt(uint64(header.Timestamp()))
+	}
+}
+
+//isContractResultErr 合约执行结果是否为执行错误
+func isContractResultErr(tx *modules.Transaction) bool {
+	for _, msg := range tx.TxMessages() {
+		switch payload := msg.Payload.(type) {
+		case *modules.ContractInvokePayload:
+			return payload.ErrMsg.Code != 0
+		case *modules.ContractDeployPayload:
+			return payload.ErrMsg.Code != 0
+		case *modules.ContractStopPayload:
+			return payload.ErrMsg.Code != 0
+		}
+	}
+	return false
+}
+
+//recordJurorOutcomes 合约请求过期时，统计陪审团中每个陪审员的执行结果，
+//只统计本地执行过的用户合约请求，调用者需要持有p.locker
+func (p *Processor) recordJurorOutcomes(ctx *contractTx) {
+	if !ctx.valid || ctx.sigTx == nil || ctx.eleNode == nil || ctx.reqTx == nil || ctx.reqTx.IsSystemContract() {
+		return
+	}
+	matched := txSigners(ctx.sigTx)
+	localErr := isContractResultErr(ctx.sigTx)
+	mismatched := make(map[common.Hash]bool)
+	refused := make(map[common.Hash]bool)
+	for _, tx := range ctx.rcvTx {
+		rcvErr := isContractResultErr(tx)
+		for signer := range txSigners(tx) {
+			if matched[signer] {
+				continue
+			}
+			if rcvErr && !localErr {
+				refused[signer] = true
+			} else {
+				mismatched[signer] = true
+			}
+		}
+	}
+	for _, e := range ctx.eleNode.EleList {
+		outcome, ok := p.outcomes[e.AddrHash]
+		if !ok {
+			outcome = &modules.JurorOutcome{}
+			p.outcomes[e.AddrHash] = outcome
+		}
+		switch {
+		case matched[e.AddrHash]:
+			outcome.Matched++
+		case refused[e.AddrHash]:
+			outcome.Refused++
+		case mismatched[e.AddrHash]:
+			outcome.Mismatched++
+		default:
+			outcome.TimedOut++
+		}
+	}
+}
+
+//GetJurorOutcome 返回本节点观察到的陪审员执行结果统计
+func (p *Processor) GetJurorOutcome(juror common.Address) *modules.JurorOutcome {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	outcome := &modules.JurorOutcome{}
+	if o, ok := p.outcomes[util.RlpHash(juror)]; ok {
+		*outcome = *o
+	}
+	return outcome
+}
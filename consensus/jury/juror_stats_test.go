@@ -0,0 +1,90 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func newInvokeResultTx(t *testing.T, errCode uint32, pubKeys ...[]byte) *modules.Transaction {
+	invoke := &modules.ContractInvokePayload{ErrMsg: modules.ContractError{Code: errCode}}
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_CONTRACT_INVOKE, invoke)})
+	for _, pubKey := range pubKeys {
+		assert.Nil(t, addContractSignatureSet(tx, &modules.SignatureSet{PubKey: pubKey}))
+	}
+	return tx
+}
+
+func TestRecordJurorOutcomes(t *testing.T) {
+	pubKeys := make([][]byte, 5)
+	addrs := make([]common.Address, 5)
+	jury := &modules.ElectionNode{JuryCount: 20}
+	for i := range pubKeys {
+		key, _ := crypto.MyCryptoLib.KeyGen()
+		privKey, _ := crypto.ToECDSA(key)
+		pubKeys[i] = crypto.CompressPubkey(&privKey.PublicKey)
+		addrs[i] = crypto.PubkeyBytesToAddress(pubKeys[i])
+		jury.EleList = append(jury.EleList, modules.ElectionInf{AddrHash: util.RlpHash(addrs[i]), PublicKey: pubKeys[i]})
+	}
+	p := &Processor{locker: new(sync.Mutex), outcomes: make(map[common.Hash]*modules.JurorOutcome)}
+
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	reqMsg := modules.NewMessage(modules.APP_CONTRACT_INVOKE_REQUEST,
+		&modules.ContractInvokeRequestPayload{ContractId: contractId})
+
+	//0,1签名一致，2返回执行错误，3返回的读写集不同，4没有返回
+	ctx := &contractTx{
+		reqTx:   modules.NewTransaction([]*modules.Message{reqMsg}),
+		eleNode: jury,
+		valid:   true,
+		sigTx:   newInvokeResultTx(t, 0, pubKeys[0], pubKeys[1]),
+		rcvTx: []*modules.Transaction{
+			newInvokeResultTx(t, 0, pubKeys[1]),
+			newInvokeResultTx(t, modules.ContractErrCodeDefault, pubKeys[2]),
+			newInvokeResultTx(t, 0, pubKeys[3]),
+		},
+	}
+	p.recordJurorOutcomes(ctx)
+	p.recordJurorOutcomes(ctx)
+
+	assert.Equal(t, &modules.JurorOutcome{Matched: 2}, p.GetJurorOutcome(addrs[0]))
+	assert.Equal(t, &modules.JurorOutcome{Matched: 2}, p.GetJurorOutcome(addrs[1]))
+	assert.Equal(t, &modules.JurorOutcome{Refused: 2}, p.GetJurorOutcome(addrs[2]))
+	assert.Equal(t, &modules.JurorOutcome{Mismatched: 2}, p.GetJurorOutcome(addrs[3]))
+	assert.Equal(t, &modules.JurorOutcome{TimedOut: 2}, p.GetJurorOutcome(addrs[4]))
+
+	//本地执行出错时，其他陪审员返回的错误结果只算不一致
+	ctx.sigTx = newInvokeResultTx(t, modules.ContractErrCodeDefault, pubKeys[0])
+	ctx.rcvTx = []*modules.Transaction{newInvokeResultTx(t, modules.ContractErrCodeDefault+1, pubKeys[2])}
+	p.recordJurorOutcomes(ctx)
+	assert.Equal(t, &modules.JurorOutcome{Refused: 2, Mismatched: 1}, p.GetJurorOutcome(addrs[2]))
+
+	//没有本地执行结果的请求不统计
+	ctx.sigTx = nil
+	p.recordJurorOutcomes(ctx)
+	assert.Equal(t, &modules.JurorOutcome{Matched: 3}, p.GetJurorOutcome(addrs[0]))
+}
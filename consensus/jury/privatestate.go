@@ -0,0 +1,537 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/dag/errors"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+const (
+	privateStateJuryCheckInterval = time.Second * 10 //检查合约陪审团是否变更的间隔
+	privateStateSyncTimeout       = time.Second * 5  //执行合约前等待陪审团发来缺少的私有状态
+	privateStateResendInterval    = time.Second * 30 //同一合约的私有状态在该时间内只重新发送一次
+)
+
+// DecryptPrivateState 用本地陪审员账户的私钥解密合约私有状态
+func (p *Processor) DecryptPrivateState(ps *modules.PrivateState) ([]byte, error) {
+	ks := p.ptn.GetKeyStore()
+	for addr := range p.local {
+		pubKey, err := ks.GetPublicKey(addr)
+		if err != nil {
+			continue
+		}
+		cipher, err := ps.GetCipher(pubKey)
+		if err != nil {
+			continue
+		}
+		return ks.DecryptMessage(addr, cipher)
+	}
+	return nil, modules.ErrPrivateStateNotFound
+}
+
+//openPrivateState 用本地陪审员的私钥解密私有状态，返回明文和盐，并检查与Hash一致
+func (p *Processor) openPrivateState(ps *modules.PrivateState) ([]byte, []byte, error) {
+	plain, err := p.DecryptPrivateState(ps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ps.Open(plain)
+}
+
+//encryptPrivateStates 将合约执行产生的私有状态用陪审员的公钥加密后保存在本地，
+//返回的密文随签名交易发给其他陪审员，保存失败不影响合约的执行结果。
+//跨陪审团调用时参与执行的陪审员都要解密其他陪审员使用的盐，所以用所有相关陪审团的公钥加密
+func (p *Processor) encryptPrivateStates(writes []*modules.PrivateWrite,
+	ele *modules.ElectionNode) []*modules.PrivateState {
+	now := uint64(time.Now().Unix())
+	pubKeys := make([][]byte, 0)
+	contracts := make(map[string]bool)
+	for _, pw := range writes {
+		if contracts[string(pw.ContractId)] {
+			continue
+		}
+		contracts[string(pw.ContractId)] = true
+		keys, err := getPrivateStatePubKeys(p.dag, pw.ContractId, ele)
+		if err != nil {
+			log.Warnf("encryptPrivateStates, contract[%x] %s", pw.ContractId, err.Error())
+		}
+		for _, key := range keys {
+			if !containsPubKey(pubKeys, key) {
+				pubKeys = append(pubKeys, key)
+			}
+		}
+	}
+	states := make([]*modules.PrivateState, 0, len(writes))
+	if len(pubKeys) == 0 {
+		return states
+	}
+	for _, pw := range writes {
+		if pw.IsDelete {
+			continue
+		}
+		ps, err := modules.NewPrivateState(pw.ContractId, pw.Key, pw.Value, pw.Salt, pubKeys, now)
+		if err != nil {
+			log.Warnf("encryptPrivateStates, encrypt private state[%s] err:%s", pw.Key, err.Error())
+			continue
+		}
+		if err = p.dag.SavePrivateState(ps); err != nil {
+			log.Warnf("encryptPrivateStates, save private state[%s] err:%s", pw.Key, err.Error())
+		}
+		states = append(states, ps)
+	}
+	return states
+}
+
+//getPrivateStatePubKeys 合约陪审团和执行陪审团成员的公钥，跨陪审团调用时执行陪审团与被调用合约的陪审团不同
+func getPrivateStatePubKeys(dag iDag, contractId []byte, ele *modules.ElectionNode) ([][]byte, error) {
+	keys, err := getContractJuryPubKeys(dag, contractId)
+	if err != nil {
+		return nil, err
+	}
+	if ele == nil {
+		return keys, nil
+	}
+	for _, e := range ele.EleList {
+		if len(e.PublicKey) == 0 || containsPubKey(keys, e.PublicKey) {
+			continue
+		}
+		keys = append(keys, e.PublicKey)
+	}
+	return keys, nil
+}
+
+func containsPubKey(keys [][]byte, pubKey []byte) bool {
+	for _, key := range keys {
+		if bytes.Equal(key, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func getContractJuryPubKeys(dag iDag, contractId []byte) ([][]byte, error) {
+	jury, err := dag.GetContractJury(contractId)
+	if err != nil {
+		return nil, err
+	}
+	if jury == nil || len(jury.EleList) == 0 {
+		return nil, fmt.Errorf("contract[%s] has no jury", common.NewAddress(contractId, common.ContractHash).String())
+	}
+	keys := make([][]byte, 0, len(jury.EleList))
+	for _, ele := range jury.EleList {
+		keys = append(keys, ele.PublicKey)
+	}
+	return keys, nil
+}
+
+//trackPrivateStates 记录本地私有状态加密时合约的陪审团，陪审团变更后重新加密，调用者持有p.locker
+func (p *Processor) trackPrivateStates(states []*modules.PrivateState) {
+	for _, ps := range states {
+		if _, ok := p.privateJuries[string(ps.ContractId)]; ok {
+			continue
+		}
+		jury, err := p.dag.GetContractJury(ps.ContractId)
+		if err != nil || jury == nil {
+			continue
+		}
+		p.privateJuries[string(ps.ContractId)] = jury.Hash()
+	}
+}
+
+//txPrivateStateHashes 交易写集中私有状态的Hash
+func txPrivateStateHashes(tx *modules.Transaction) map[string]bool {
+	hashes := make(map[string]bool)
+	for _, msg := range tx.Messages() {
+		if msg.App != modules.APP_CONTRACT_INVOKE {
+			continue
+		}
+		for _, ws := range msg.Payload.(*modules.ContractInvokePayload).WriteSet {
+			if ws.IsPrivate && !ws.IsDelete {
+				hashes[privateStateId(ws.ContractId, ws.Key, ws.Value)] = true
+			}
+		}
+	}
+	return hashes
+}
+
+func privateStateId(contractId []byte, key string, hash []byte) string {
+	return string(contractId) + key + string(hash)
+}
+
+//privateSeal 一个陪审员计算私有状态Hash时使用的一组盐，每个陪审员执行合约时生成的盐不同，
+//陪审员之间采用明文相同且Id最小的一组盐，使签名的执行结果一致
+type privateSeal struct {
+	id     common.Hash
+	writes map[string]*modules.PrivateWrite //contractId+key -> 明文和盐
+}
+
+//privateSealId 一组私有状态Hash的摘要，与写集中的顺序无关
+func privateSealId(hashes [][]byte) common.Hash {
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i], hashes[j]) < 0 })
+	return util.RlpHash(hashes)
+}
+
+//localPrivateSeal 本地执行结果当前使用的盐
+func localPrivateSeal(writes []*modules.PrivateWrite) *privateSeal {
+	seal := &privateSeal{writes: make(map[string]*modules.PrivateWrite)}
+	hashes := make([][]byte, 0, len(writes))
+	for _, pw := range writes {
+		if pw.IsDelete {
+			continue
+		}
+		seal.writes[privateStateId(pw.ContractId, pw.Key, nil)] = pw
+		hashes = append(hashes, pw.Hash())
+	}
+	seal.id = privateSealId(hashes)
+	return seal
+}
+
+//match 两组盐对应的私有状态明文是否相同
+func (s *privateSeal) match(other *privateSeal) bool {
+	if len(s.writes) != len(other.writes) {
+		return false
+	}
+	for id, pw := range s.writes {
+		o, ok := other.writes[id]
+		if !ok || !bytes.Equal(pw.Value, o.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+//adoptPrivateSeal 其他陪审员发来的盐对应的明文与本地相同且Id更小时，本地改用该组盐，
+//返回本地的盐是否改变，调用者持有p.locker
+func adoptPrivateSeal(ctx *contractTx) bool {
+	if len(ctx.privates) == 0 || len(ctx.seals) == 0 {
+		return false
+	}
+	local := localPrivateSeal(ctx.privates)
+	if len(local.writes) == 0 {
+		return false
+	}
+	best := local
+	for _, seal := range ctx.seals {
+		if bytes.Compare(seal.id.Bytes(), best.id.Bytes()) < 0 && local.match(seal) {
+			best = seal
+		}
+	}
+	if best == local {
+		return false
+	}
+	for id, pw := range local.writes {
+		pw.Salt = common.CopyBytes(best.writes[id].Salt)
+	}
+	return true
+}
+
+//sealPrivateWrites 用私有状态当前的盐重新计算合约执行结果中私有状态的Hash，不修改原消息
+func sealPrivateWrites(msgs []*modules.Message, writes []*modules.PrivateWrite) []*modules.Message {
+	if len(writes) == 0 {
+		return msgs
+	}
+	local := localPrivateSeal(writes)
+	sealed := make([]*modules.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.App != modules.APP_CONTRACT_INVOKE {
+			sealed = append(sealed, msg)
+			continue
+		}
+		payload := *msg.Payload.(*modules.ContractInvokePayload)
+		payload.WriteSet = make([]modules.ContractWriteSet, len(payload.WriteSet))
+		copy(payload.WriteSet, msg.Payload.(*modules.ContractInvokePayload).WriteSet)
+		for i, ws := range payload.WriteSet {
+			if !ws.IsPrivate || ws.IsDelete {
+				continue
+			}
+			if pw, ok := local.writes[privateStateId(ws.ContractId, ws.Key, nil)]; ok {
+				payload.WriteSet[i].Value = pw.Hash()
+			}
+		}
+		sealed = append(sealed, modules.NewMessage(msg.App, &payload))
+	}
+	return sealed
+}
+
+//savePeerPrivateStates 保存其他陪审员随签名交易发来的私有状态，只保存与交易写集中的Hash一致且本地陪审员能解密的，
+//交易中的私有状态都能解密时返回该陪审员使用的盐，调用者持有p.locker
+func (p *Processor) savePeerPrivateStates(tx *modules.Transaction, states []*modules.PrivateState) *privateSeal {
+	if len(states) == 0 {
+		return nil
+	}
+	hashes := txPrivateStateHashes(tx)
+	seal := &privateSeal{writes: make(map[string]*modules.PrivateWrite)}
+	sealHashes := make([][]byte, 0, len(states))
+	saved := make([]*modules.PrivateState, 0, len(states))
+	for _, ps := range states {
+		if ps == nil || !hashes[privateStateId(ps.ContractId, ps.Key, ps.Hash)] {
+			continue
+		}
+		value, salt, err := p.openPrivateState(ps)
+		if err != nil {
+			continue
+		}
+		id := privateStateId(ps.ContractId, ps.Key, nil)
+		if _, ok := seal.writes[id]; !ok {
+			seal.writes[id] = &modules.PrivateWrite{ContractId: ps.ContractId, Key: ps.Key, Value: value, Salt: salt}
+			sealHashes = append(sealHashes, ps.Hash)
+		}
+		ps.Timestamp = uint64(time.Now().Unix())
+		if err := p.dag.SavePrivateState(ps); err != nil {
+			log.Warnf("savePeerPrivateStates, save private state[%s] err:%s", ps.Key, err.Error())
+			continue
+		}
+		saved = append(saved, ps)
+	}
+	p.trackPrivateStates(saved)
+	if len(seal.writes) != len(hashes) {
+		return nil
+	}
+	seal.id = privateSealId(sealHashes)
+	return seal
+}
+
+//missingPrivateStates 链上当前的私有状态中本地没有或者不能解密的Key
+func (p *Processor) missingPrivateStates(contractId []byte) []string {
+	states, err := p.dag.GetContractStatesByPrefix(contractId, modules.PrivateStateKeyPrefix)
+	if err != nil {
+		return nil
+	}
+	missing := make([]string, 0)
+	for key, state := range states {
+		if len(state.Value) == 0 {
+			continue
+		}
+		ps, err := p.dag.GetPrivateState(contractId, key, state.Value)
+		if err == nil {
+			if _, err = p.DecryptPrivateState(ps); err == nil {
+				continue
+			}
+		}
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+//syncPrivateStates 执行合约前检查本地是否缺少链上当前的私有状态，新选出或者掉线后恢复的陪审员
+//向陪审团请求缺少的私有状态，并等待一段时间
+func (p *Processor) syncPrivateStates(contractId []byte) {
+	missing := p.missingPrivateStates(contractId)
+	if len(missing) == 0 {
+		return
+	}
+	log.Infof("syncPrivateStates, contract[%x] missing %d private states", contractId, len(missing))
+	reqEvent := &PrivateStateRequestEvent{ContractId: contractId, Keys: missing}
+	go p.ptn.ElectionBroadcast(ElectionEvent{EType: ELECTION_EVENT_PRIVATE_STATE_REQUEST, Event: reqEvent}, false)
+
+	deadline := time.Now().Add(privateStateSyncTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-p.quit:
+			return
+		case <-time.After(time.Millisecond * 200):
+		}
+		if len(p.missingPrivateStates(contractId)) == 0 {
+			return
+		}
+	}
+	log.Warnf("syncPrivateStates, contract[%x] private states not received in %s", contractId,
+		privateStateSyncTimeout.String())
+}
+
+//sendPrivateStates 用合约当前陪审团的公钥重新加密本地能解密的私有状态，发给陪审团
+func (p *Processor) sendPrivateStates(contractId []byte, keys []string) error {
+	pubKeys, err := getContractJuryPubKeys(p.dag, contractId)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().Unix())
+	states := make([]*modules.PrivateState, 0, len(keys))
+	for _, key := range keys {
+		if !modules.IsPrivateStateKey(key) {
+			continue
+		}
+		hash, _, err := p.dag.GetContractState(contractId, key)
+		if err != nil || len(hash) == 0 {
+			continue
+		}
+		ps, err := p.dag.GetPrivateState(contractId, key, hash)
+		if err != nil {
+			continue
+		}
+		//使用原来的盐，重新加密后链上的Hash不变
+		value, salt, err := p.openPrivateState(ps)
+		if err != nil {
+			continue
+		}
+		newPs, err := modules.NewPrivateState(contractId, key, value, salt, pubKeys, now)
+		if err != nil {
+			log.Warnf("sendPrivateStates, encrypt private state[%s] err:%s", key, err.Error())
+			continue
+		}
+		states = append(states, newPs)
+	}
+	if len(states) == 0 {
+		return nil
+	}
+	log.Infof("sendPrivateStates, contract[%x] send %d private states to jury", contractId, len(states))
+	event := &PrivateStateEvent{ContractId: contractId, States: states}
+	go p.ptn.ElectionBroadcast(ElectionEvent{EType: ELECTION_EVENT_PRIVATE_STATE, Event: event}, true)
+	return nil
+}
+
+//processPrivateStateEvent 保存陪审团成员重新加密发来的私有状态，只保存链上当前的且本地能解密的
+func (p *Processor) processPrivateStateEvent(evt *PrivateStateEvent) error {
+	if evt == nil {
+		return errors.New("processPrivateStateEvent, param is nil")
+	}
+	saved := make([]*modules.PrivateState, 0, len(evt.States))
+	for _, ps := range evt.States {
+		if ps == nil || !bytes.Equal(ps.ContractId, evt.ContractId) || !modules.IsPrivateStateKey(ps.Key) {
+			continue
+		}
+		hash, _, err := p.dag.GetContractState(ps.ContractId, ps.Key)
+		if err != nil || !bytes.Equal(hash, ps.Hash) {
+			continue
+		}
+		if _, _, err = p.openPrivateState(ps); err != nil {
+			continue
+		}
+		ps.Timestamp = uint64(time.Now().Unix())
+		if err = p.dag.SavePrivateState(ps); err != nil {
+			log.Warnf("processPrivateStateEvent, save private state[%s] err:%s", ps.Key, err.Error())
+			continue
+		}
+		saved = append(saved, ps)
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.trackPrivateStates(saved)
+	return nil
+}
+
+//processPrivateStateRequestEvent 本地能解密请求的私有状态时，重新加密后发给合约当前的陪审团，
+//密文只有链上记录的陪审员能解密，同一合约在一段时间内只响应一次
+func (p *Processor) processPrivateStateRequestEvent(evt *PrivateStateRequestEvent) error {
+	if evt == nil {
+		return errors.New("processPrivateStateRequestEvent, param is nil")
+	}
+	if !p.canSendPrivateStates(evt.ContractId) {
+		return nil
+	}
+	return p.sendPrivateStates(evt.ContractId, evt.Keys)
+}
+
+func (p *Processor) canSendPrivateStates(contractId []byte) bool {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	if tm, ok := p.privateSent[string(contractId)]; ok && time.Since(tm) < privateStateResendInterval {
+		return false
+	}
+	p.privateSent[string(contractId)] = time.Now()
+	return true
+}
+
+//privateStateJuryLoop 合约陪审团变更后，原陪审员把本地能解密的私有状态用新陪审团的公钥重新加密并发送
+func (p *Processor) privateStateJuryLoop() {
+	ticker := time.NewTicker(privateStateJuryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			p.checkPrivateStateJuries()
+		}
+	}
+}
+
+func (p *Processor) checkPrivateStateJuries() {
+	p.locker.Lock()
+	for contractId, tm := range p.privateSent {
+		if time.Since(tm) >= privateStateResendInterval {
+			delete(p.privateSent, contractId)
+		}
+	}
+	changed := make(map[string]common.Hash)
+	for contractId, juryHash := range p.privateJuries {
+		jury, err := p.dag.GetContractJury([]byte(contractId))
+		if err != nil || jury == nil || jury.Hash() == juryHash {
+			continue
+		}
+		changed[contractId] = jury.Hash()
+	}
+	p.locker.Unlock()
+
+	for contractId, juryHash := range changed {
+		cid := []byte(contractId)
+		//最近已经发送过的，下次检查时再发给新陪审团
+		if !p.canSendPrivateStates(cid) {
+			continue
+		}
+		states, err := p.dag.GetContractStatesByPrefix(cid, modules.PrivateStateKeyPrefix)
+		if err == nil {
+			keys := make([]string, 0, len(states))
+			for key := range states {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if err = p.sendPrivateStates(cid, keys); err != nil {
+				log.Warnf("checkPrivateStateJuries, contract[%x] err:%s", cid, err.Error())
+				continue
+			}
+		}
+		p.locker.Lock()
+		p.privateJuries[contractId] = juryHash
+		p.locker.Unlock()
+	}
+}
+
+//privateStatePurgeLoop 定时清理超过保留期且已经失效的私有状态，链上仍在使用的私有状态不会被清理
+func (p *Processor) privateStatePurgeLoop() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			expire := uint64(time.Now().Add(-p.privateRetention).Unix())
+			count, err := p.dag.PurgePrivateStates(expire)
+			if err != nil {
+				log.Warnf("privateStatePurgeLoop, purge private states err:%s", err.Error())
+				continue
+			}
+			if count > 0 {
+				log.Infof("privateStatePurgeLoop, purge %d private states", count)
+			}
+		}
+	}
+}
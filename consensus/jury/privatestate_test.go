@@ -0,0 +1,131 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxPrivateStateHashes(t *testing.T) {
+	contractId := []byte("contract")
+	key := modules.PrivateStateKey("bid")
+	hash := modules.PrivateStateHash(contractId, key, []byte("100"), []byte("salt"))
+	writeSet := []modules.ContractWriteSet{
+		{Key: "public", Value: []byte("1"), ContractId: contractId},
+		{Key: key, Value: hash, ContractId: contractId, IsPrivate: true},
+		{Key: modules.PrivateStateKey("old"), IsDelete: true, ContractId: contractId, IsPrivate: true},
+	}
+	payload := modules.NewContractInvokePayload(contractId, nil, writeSet, nil, modules.ContractError{})
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_CONTRACT_INVOKE, payload)})
+
+	hashes := txPrivateStateHashes(tx)
+	assert.Equal(t, 1, len(hashes))
+	assert.True(t, hashes[privateStateId(contractId, key, hash)])
+	//Hash与写集不一致的私有状态不保存
+	assert.False(t, hashes[privateStateId(contractId, key, []byte("other"))])
+}
+
+func TestAdoptPrivateSeal(t *testing.T) {
+	contractId := []byte("contract")
+	key := modules.PrivateStateKey("bid")
+	newWrite := func(value string, salt string) *modules.PrivateWrite {
+		return &modules.PrivateWrite{ContractId: contractId, Key: key, Value: []byte(value), Salt: []byte(salt)}
+	}
+	newSeal := func(pw *modules.PrivateWrite) *privateSeal {
+		return &privateSeal{id: privateSealId([][]byte{pw.Hash()}),
+			writes: map[string]*modules.PrivateWrite{privateStateId(contractId, key, nil): pw}}
+	}
+	local := newWrite("100", "a")
+	payload := modules.NewContractInvokePayload(contractId, nil,
+		[]modules.ContractWriteSet{{Key: key, Value: local.Hash(), ContractId: contractId, IsPrivate: true}},
+		nil, modules.ContractError{})
+	msgs := []*modules.Message{modules.NewMessage(modules.APP_CONTRACT_INVOKE, payload)}
+
+	//明文不同的盐不采用
+	other := newWrite("200", "b")
+	ctx := &contractTx{msgs: msgs, privates: []*modules.PrivateWrite{local}, seals: []*privateSeal{newSeal(other)}}
+	assert.False(t, adoptPrivateSeal(ctx))
+
+	//明文相同时采用Id最小的一组盐
+	seals := []*privateSeal{newSeal(newWrite("100", "b")), newSeal(newWrite("100", "c")),
+		newSeal(newWrite("100", "d"))}
+	localSeal := localPrivateSeal(ctx.privates)
+	min := localSeal
+	for _, seal := range seals {
+		if bytes.Compare(seal.id.Bytes(), min.id.Bytes()) < 0 {
+			min = seal
+		}
+	}
+	ctx.seals = seals
+	assert.Equal(t, min != localSeal, adoptPrivateSeal(ctx))
+	assert.Equal(t, min.id, localPrivateSeal(ctx.privates).id)
+	assert.False(t, adoptPrivateSeal(ctx))
+
+	//重新计算执行结果中的Hash，原消息不变
+	sealed := sealPrivateWrites(msgs, ctx.privates)
+	ws := sealed[0].Payload.(*modules.ContractInvokePayload).WriteSet[0]
+	assert.Equal(t, ctx.privates[0].Hash(), ws.Value)
+	assert.Equal(t, modules.PrivateStateHash(contractId, key, []byte("100"), []byte("a")),
+		payload.WriteSet[0].Value)
+}
+
+func TestContractEvent_PrivateStates(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	pubKey := crypto.CompressPubkey(&prv.PublicKey)
+	ps, err := modules.NewPrivateState([]byte("contract"), modules.PrivateStateKey("bid"), []byte("100"),
+		[]byte("salt"), [][]byte{pubKey}, 1)
+	assert.Nil(t, err)
+	tx := modules.NewTransaction([]*modules.Message{})
+
+	//不带私有状态的事件与之前的编码相同
+	type oldContractEvent struct {
+		CType ContractEventType
+		Ele   *modules.ElectionNode `rlp:"nil"`
+		Tx    *modules.Transaction
+	}
+	oldData, err := rlp.EncodeToBytes(&oldContractEvent{CType: CONTRACT_EVENT_SIG, Tx: tx})
+	assert.Nil(t, err)
+	data, err := rlp.EncodeToBytes(&ContractEvent{CType: CONTRACT_EVENT_SIG, Tx: tx})
+	assert.Nil(t, err)
+	assert.Equal(t, oldData, data)
+
+	data, err = rlp.EncodeToBytes(&ContractEvent{CType: CONTRACT_EVENT_SIG, Tx: tx,
+		PrivateStates: []*modules.PrivateState{ps}})
+	assert.Nil(t, err)
+	event := &ContractEvent{}
+	assert.Nil(t, rlp.DecodeBytes(data, event))
+	assert.Equal(t, 1, len(event.PrivateStates))
+	assert.Equal(t, ps.Hash, event.PrivateStates[0].Hash)
+
+	//重新加密的私有状态通过选举事件发送
+	ev := &ElectionEvent{EType: ELECTION_EVENT_PRIVATE_STATE,
+		Event: &PrivateStateEvent{ContractId: ps.ContractId, States: []*modules.PrivateState{ps}}}
+	evBytes, err := ev.ToElectionEventBytes()
+	assert.Nil(t, err)
+	ev2, err := evBytes.ToElectionEvent()
+	assert.Nil(t, err)
+	assert.Equal(t, ps.Ciphers[0].Cipher, ev2.Event.(*PrivateStateEvent).States[0].Ciphers[0].Cipher)
+}
@@ -0,0 +1,331 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package jury
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/palletone/go-palletone/dag/dagconfig"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//本地发起触发请求后，在该时间内任务状态没有变化则重新触发
+const scheduleRetryInterval = 5 * time.Minute
+
+//检查触发请求是否已经打包的间隔
+const scheduleCheckInterval = 3 * time.Second
+
+type scheduleTrigger struct {
+	runs uint32
+	tm   time.Time
+}
+
+//scheduledInvoke 本节点触发的一次运行，触发成功后发起合约调用请求，调用结果稳定后发起结算请求
+type scheduledInvoke struct {
+	id      string
+	from    common.Address
+	fee     uint64 //合约调用请求的手续费
+	invoked bool   //已经发起调用请求，等待调用结果稳定
+	tm      time.Time
+}
+
+//checkScheduleRegister 定时调用注册请求只能由目标合约的创建者发起，与验证交易时的检查一致
+func checkScheduleRegister(dag iDag, tx *modules.Transaction, reqPay *modules.ContractInvokeRequestPayload) error {
+	if !bytes.Equal(reqPay.ContractId, syscontract.SchedulerContractAddress.Bytes()) {
+		return nil
+	}
+	target, ok := modules.ScheduleRegisterTarget(reqPay.Args)
+	if !ok {
+		return nil
+	}
+	contract, err := dag.GetContract(target.Bytes())
+	if err != nil {
+		return fmt.Errorf("schedule target contract[%s] not found", target.String())
+	}
+	reqAddr, err := dag.GetTxRequesterAddress(tx)
+	if err != nil {
+		return err
+	}
+	return modules.CheckScheduleRegisterAuth(contract, reqAddr)
+}
+
+//checkScheduledInvoke 带有定时任务标记的调用请求必须由触发本次运行的Mediator发起，合约和参数与链上的任务一致。
+//返回去掉标记后的合约参数，以及是否为定时任务的调用
+func checkScheduledInvoke(dag iDag, tx *modules.Transaction, reqPay *modules.ContractInvokeRequestPayload) (
+	[][]byte, bool, error) {
+	id, ok := modules.ParseScheduledInvoke(reqPay.Args)
+	if !ok {
+		return reqPay.Args, false, nil
+	}
+	schedule, err := getContractSchedule(dag, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !schedule.MatchScheduledInvoke(reqPay.ContractId, reqPay.Args) {
+		return nil, false, fmt.Errorf("invoke not match the running schedule[%s]", id)
+	}
+	reqAddr, err := dag.GetTxRequesterAddress(tx)
+	if err != nil {
+		return nil, false, err
+	}
+	if reqAddr.String() != schedule.Pending {
+		return nil, false, fmt.Errorf("schedule[%s] is triggered by %s, requester:%s", id, schedule.Pending,
+			reqAddr.String())
+	}
+	return reqPay.Args[:len(reqPay.Args)-1], true, nil
+}
+
+//getContractSchedule 从定时调用系统合约的状态中读取任务
+func getContractSchedule(dag iDag, id string) (*modules.ContractSchedule, error) {
+	data, _, err := dag.GetContractState(syscontract.SchedulerContractAddress.Bytes(),
+		modules.ContractSchedulePrefix+id)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("schedule[%s] not found", id)
+	}
+	schedule := &modules.ContractSchedule{}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+//ScheduleTrigger Mediator产块后通知定时调度线程为到期的任务发起触发请求，不阻塞产块
+func (p *Processor) ScheduleTrigger(addr common.Address) {
+	select {
+	case p.schedCh <- addr:
+	default: //上一次通知还没有处理完
+	}
+}
+
+//scheduleLoop 在产块流程之外发起触发请求，触发结果打包后发起对用户合约的调用请求，调用结果稳定后结算
+func (p *Processor) scheduleLoop() {
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case addr := <-p.schedCh:
+			p.triggerScheduledInvokes(addr)
+		case <-ticker.C:
+			p.invokeScheduled()
+		}
+	}
+}
+
+//triggerScheduledInvokes 为到期的定时任务发起触发请求，
+//触发请求由定时调用系统合约执行，记录本次运行由该Mediator负责
+func (p *Processor) triggerScheduledInvokes(addr common.Address) {
+	states, err := p.dag.GetContractStatesByPrefix(syscontract.SchedulerContractAddress.Bytes(),
+		modules.ContractSchedulePrefix)
+	if err != nil || len(states) == 0 {
+		return
+	}
+	now, err := p.dag.GetNewestUnitTimestamp(dagconfig.DagConfig.GetGasToken())
+	if err != nil {
+		log.Debugf("TriggerScheduledInvokes, GetNewestUnitTimestamp err:%s", err.Error())
+		return
+	}
+	triggered := make(map[string]*scheduleTrigger)
+	for _, state := range states {
+		schedule := &modules.ContractSchedule{}
+		if err := json.Unmarshal(state.Value, schedule); err != nil {
+			continue
+		}
+		if t, ok := p.schedTriggered[schedule.Id]; ok && t.runs == schedule.Runs &&
+			time.Since(t.tm) < scheduleRetryInterval {
+			triggered[schedule.Id] = t //已经发起过触发请求，等待结果上链
+			continue
+		}
+		if !p.isScheduleDue(schedule, now) {
+			continue
+		}
+		if err := p.triggerSchedule(addr, schedule); err != nil {
+			log.Warnf("TriggerScheduledInvokes, schedule[%s] err:%s", schedule.Id, err.Error())
+			continue
+		}
+		triggered[schedule.Id] = &scheduleTrigger{runs: schedule.Runs, tm: time.Now()}
+	}
+	p.schedTriggered = triggered
+}
+
+//isScheduleDue 与系统合约中的判断保持一致，按高度触发时要求该高度的单元已经稳定
+func (p *Processor) isScheduleDue(schedule *modules.ContractSchedule, now int64) bool {
+	if schedule.Balance < schedule.FeePerRun || schedule.IsPending(now) {
+		return false
+	}
+	if schedule.IsTimeDue(now) {
+		return true
+	}
+	if schedule.TriggerHeight > 0 {
+		index := &modules.ChainIndex{AssetID: dagconfig.DagConfig.GetGasToken(), Index: schedule.TriggerHeight}
+		unit, err := p.dag.GetStableUnitByNumber(index)
+		return err == nil && unit != nil
+	}
+	return false
+}
+
+//triggerSchedule Mediator垫付触发、调用和结算请求的手续费，结算时由系统合约从预付手续费中支付本次运行的手续费。
+//预付手续费扣除触发和结算请求的手续费后，剩余部分作为合约调用请求的手续费
+func (p *Processor) triggerSchedule(addr common.Address, schedule *modules.ContractSchedule) error {
+	contractAddr, err := common.StringToAddress(schedule.ContractAddr)
+	if err != nil {
+		return err
+	}
+	schedulerAddr := syscontract.SchedulerContractAddress
+	args := [][]byte{[]byte("trigger"), []byte(schedule.Id)}
+	fee, _, _, err := p.ContractInvokeReqFee(addr, schedulerAddr, 0, 0, nil, schedulerAddr, args, 0)
+	if err != nil {
+		return err
+	}
+	triggerFee := uint64(fee) + 1
+	if 2*triggerFee >= schedule.FeePerRun {
+		return fmt.Errorf("fee per run %d not enough, trigger fee %d", schedule.FeePerRun, triggerFee)
+	}
+	reqId, err := p.ContractInvokeReq(addr, schedulerAddr, 0, triggerFee, nil, schedulerAddr, args, 0)
+	if err != nil {
+		return err
+	}
+	log.Infof("[%s]triggerSchedule, schedule[%s], contract[%s]", shortId(reqId.String()), schedule.Id,
+		contractAddr.String())
+	p.locker.Lock()
+	p.schedPending[reqId] = &scheduledInvoke{id: schedule.Id, from: addr, fee: schedule.FeePerRun - 2*triggerFee,
+		tm: time.Now()}
+	p.locker.Unlock()
+	return nil
+}
+
+//invokeScheduled 触发请求打包后发起对用户合约的调用请求，由陪审员像普通请求一样执行，
+//调用结果稳定后发起结算请求。请求可能由其他Mediator执行，所以根据reqId查询链上的结果，
+//请求超时删除或者超过任务的运行超时后不再等待
+func (p *Processor) invokeScheduled() {
+	p.locker.Lock()
+	ready := make(map[common.Hash]*scheduledInvoke)
+	for reqId, si := range p.schedPending {
+		if time.Since(si.tm) > time.Duration(modules.ScheduleRunTimeout)*time.Second {
+			delete(p.schedPending, reqId)
+			continue
+		}
+		txHash, err := p.dag.GetTxHashByReqId(reqId)
+		if err != nil || txHash.IsZero() {
+			if _, ok := p.mtx[reqId]; !ok {
+				delete(p.schedPending, reqId)
+			}
+			continue
+		}
+		if si.invoked {
+			if _, err := p.dag.GetStableTransactionOnly(txHash); err != nil {
+				continue //调用结果稳定后才能结算
+			}
+		}
+		ready[reqId] = si
+		delete(p.schedPending, reqId)
+	}
+	p.locker.Unlock()
+
+	for reqId, si := range ready {
+		txHash, _ := p.dag.GetTxHashByReqId(reqId)
+		var err error
+		if si.invoked {
+			err = p.settleScheduled(si, txHash)
+		} else {
+			err = p.invokeScheduledRun(reqId, si, txHash)
+		}
+		if err != nil {
+			log.Warnf("[%s]invokeScheduled, schedule[%s] err:%s", shortId(reqId.String()), si.id, err.Error())
+		}
+	}
+}
+
+//invokeScheduledRun 链上记录的本次运行由本节点触发时，发起带有任务标记的合约调用请求
+func (p *Processor) invokeScheduledRun(reqId common.Hash, si *scheduledInvoke, txHash common.Hash) error {
+	tx, err := p.dag.GetTransactionOnly(txHash)
+	if err != nil {
+		return err
+	}
+	if !isScheduleTriggerOk(tx) {
+		return errors.New("trigger failed")
+	}
+	schedule, err := getContractSchedule(p.dag, si.id)
+	if err != nil {
+		return err
+	}
+	if schedule.Pending != si.from.String() || schedule.PendingReq != reqId.String() {
+		return fmt.Errorf("schedule is triggered by %s", schedule.Pending)
+	}
+	contractAddr, err := common.StringToAddress(schedule.ContractAddr)
+	if err != nil {
+		return err
+	}
+	invokeReqId, err := p.ContractInvokeReq(si.from, contractAddr, 0, si.fee, nil, contractAddr,
+		schedule.ScheduledInvokeArgs(), 0)
+	if err != nil {
+		return err
+	}
+	log.Infof("[%s]invokeScheduledRun, schedule[%s], invoke reqId[%s]", shortId(reqId.String()), si.id,
+		invokeReqId.String())
+	p.locker.Lock()
+	p.schedPending[invokeReqId] = &scheduledInvoke{id: si.id, from: si.from, invoked: true, tm: si.tm}
+	p.locker.Unlock()
+	return nil
+}
+
+//settleScheduled 调用执行成功并稳定后发起结算请求，执行失败的运行超时后由Mediator重新触发
+func (p *Processor) settleScheduled(si *scheduledInvoke, txHash common.Hash) error {
+	tx, err := p.dag.GetStableTransactionOnly(txHash)
+	if err != nil {
+		return err
+	}
+	schedule, err := getContractSchedule(p.dag, si.id)
+	if err != nil {
+		return err
+	}
+	if !schedule.IsScheduledInvokeOk(tx) {
+		return fmt.Errorf("invoke tx[%s] failed", txHash.String())
+	}
+	schedulerAddr := syscontract.SchedulerContractAddress
+	args := [][]byte{[]byte("settle"), []byte(si.id), []byte(txHash.String())}
+	reqId, err := p.ContractInvokeReq(si.from, schedulerAddr, 0, 0, nil, schedulerAddr, args, 0)
+	if err != nil {
+		return err
+	}
+	log.Infof("[%s]settleScheduled, schedule[%s], invoke tx[%s]", shortId(reqId.String()), si.id,
+		txHash.String())
+	return nil
+}
+
+//isScheduleTriggerOk 触发请求的执行结果是否成功
+func isScheduleTriggerOk(tx *modules.Transaction) bool {
+	for _, msg := range tx.TxMessages() {
+		if msg.App != modules.APP_CONTRACT_INVOKE {
+			continue
+		}
+		invoke, ok := msg.Payload.(*modules.ContractInvokePayload)
+		return ok && invoke.ErrMsg.Code == 0
+	}
+	return false
+}
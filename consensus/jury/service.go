@@ -83,10 +83,12 @@ type iDag interface {
 		msg *modules.Message, txPool txspool.ITxPool) (*modules.Transaction, uint64, error)
 	GetTransaction(hash common.Hash) (*modules.TransactionWithUnitInfo, error)
 	GetTransactionOnly(hash common.Hash) (*modules.Transaction, error)
+	GetStableTransactionOnly(hash common.Hash) (*modules.Transaction, error)
 	GetHeaderByHash(common.Hash) (*modules.Header, error)
 	GetTxRequesterAddress(tx *modules.Transaction) (common.Address, error)
 	//GetConfig(name string) ([]byte, *modules.StateVersion, error)
 	IsTransactionExist(hash common.Hash) (bool, error)
+	GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error)
 	GetContractJury(contractId []byte) (*modules.ElectionNode, error)
 	GetContractTpl(tplId []byte) (*modules.ContractTemplate, error)
 	GetContract(contractId []byte) (*modules.Contract, error)
@@ -101,11 +103,19 @@ type iDag interface {
 
 	//nouse
 	GetNewestUnitTimestamp(token modules.AssetId) (int64, error)
+	GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error)
 	GetScheduledMediator(slotNum uint32) common.Address
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 	GetSlotAtTime(when time.Time) uint32
 	GetJurorReward(jurorAdd common.Address) common.Address
+	GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation
 
+	GetStableUnitByNumber(number *modules.ChainIndex) (*modules.Unit, error)
 	CheckReadSetValid(contractId []byte, readSet []modules.ContractReadSet) bool
+
+	SavePrivateState(ps *modules.PrivateState) error
+	GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error)
+	PurgePrivateStates(expireTime uint64) (int, error)
 }
 
 type electionVrf struct {
@@ -121,15 +131,19 @@ type electionVrf struct {
 }
 
 type contractTx struct {
-	eleNode  *modules.ElectionNode  //dynamic
-	reqTx    *modules.Transaction   //request contract
-	rstTx    *modules.Transaction   //contract run result---system
-	sigTx    *modules.Transaction   //contract sig result---user, 0:local, 1,2 other,signature is the same as local value
-	rcvTx    []*modules.Transaction //the local has not received the request contract, the cache has signed the contract
-	tm       time.Time              //create time
-	valid    bool                   //contract request valid identification
-	reqRcvEd bool                   //contract request received
-	adaInf   map[uint32]*AdapterInf //adapter event data information
+	eleNode  *modules.ElectionNode   //dynamic
+	reqTx    *modules.Transaction    //request contract
+	rstTx    *modules.Transaction    //contract run result---system
+	sigTx    *modules.Transaction    //contract sig result---user, 0:local, 1,2 other,signature is the same as local value
+	rcvTx    []*modules.Transaction  //the local has not received the request contract, the cache has signed the contract
+	tm       time.Time               //create time
+	valid    bool                    //contract request valid identification
+	reqRcvEd bool                    //contract request received
+	adaInf   map[uint32]*AdapterInf  //adapter event data information
+	callees  []*calleeJury           //cross-jury call, the callee juries that must sign too
+	msgs     []*modules.Message      //local execution result, private state hashes are recomputed when salts change
+	privates []*modules.PrivateWrite //private state plaintexts and salts of local execution result
+	seals    []*privateSeal          //private state salts received from other jurors
 }
 
 type Processor struct {
@@ -151,6 +165,19 @@ type Processor struct {
 	contractExecFeed  event.Feed
 	contractExecScope event.SubscriptionScope
 	pDocker           *utils.PalletOneDocker
+
+	privateRetention time.Duration          //私有状态的保留期
+	privateJuries    map[string]common.Hash //合约Id--本地私有状态加密时的陪审团
+	privateSent      map[string]time.Time   //合约Id--最近一次向陪审团重新发送私有状态的时间
+
+	schedTriggered map[string]*scheduleTrigger       //定时任务Id--本地已发起的触发请求
+	schedPending   map[common.Hash]*scheduledInvoke //触发或调用请求reqId--本节点触发的运行
+	schedCh        chan common.Address              //产块后通知定时调度线程，由该Mediator发起触发请求
+
+	failoverTimeout time.Duration                    //本地签名后等待其他陪审员签名的时间，超时后重新选举
+	reElections     map[common.Hash]*juryReElection //重新选举Id--陪审员掉线后的重新选举
+
+	outcomes map[common.Hash]*modules.JurorOutcome //陪审员地址hash--本地观察到的执行结果
 }
 
 var instanceProcessor *Processor
@@ -189,6 +216,16 @@ func NewContractProcessor(ptn PalletOne, dag iDag, contract *contracts.Contract,
 		lockVrf:      make(map[common.Address][]modules.ElectionInf),
 		validator:    val,
 		errMsgEnable: true,
+
+		privateRetention: time.Duration(cfg.PrivateStateRetention) * 24 * time.Hour,
+		privateJuries:    make(map[string]common.Hash),
+		privateSent:      make(map[string]time.Time),
+		schedTriggered:   make(map[string]*scheduleTrigger),
+		schedPending:     make(map[common.Hash]*scheduledInvoke),
+		schedCh:          make(chan common.Address, 1),
+		failoverTimeout:  time.Duration(cfg.JuryFailoverTimeout) * time.Second,
+		reElections:      make(map[common.Hash]*juryReElection),
+		outcomes:         make(map[common.Hash]*modules.JurorOutcome),
 	}
 
 	val.SetContractTxCheckFun(CheckTxContract)
@@ -210,6 +247,14 @@ func (p *Processor) Start(server *p2p.Server) error {
 	//合约执行节点更新线程
 	//合约定时清理线程
 	go p.ContractTxDeleteLoop()
+	if p.privateRetention > 0 {
+		go p.privateStatePurgeLoop()
+	}
+	go p.privateStateJuryLoop()
+	go p.scheduleLoop()
+	if p.failoverTimeout > 0 {
+		go p.juryFailoverLoop()
+	}
 	return nil
 }
 
@@ -275,19 +320,40 @@ func (p *Processor) runContractReq(reqId common.Hash, ele *modules.ElectionNode)
 	reqTx := ctx.reqTx.Clone()
 	p.locker.Unlock()
 
-	msgs, err := runContractCmd(rwset.RwM, p.dag, p.contract, reqTx, ele, p.errMsgEnable) //contract exec long time...
+	if !reqTx.IsSystemContract() {
+		p.syncPrivateStates(reqTx.GetContractId())
+	}
+	msgs, privates, err := runContractReqCmd(rwset.RwM, p.dag, p.contract, reqTx, ele, p.errMsgEnable) //contract exec long time...
 	if err != nil {
 		log.Errorf("[%s]runContractReq, runContractCmd reqTx, err：%s", shortId(reqId.String()), err.Error())
 		return err
 	}
 	p.locker.Lock()
 	defer p.locker.Unlock()
+	ctx.msgs = msgs
+	ctx.privates = privates
+	//执行前已经收到其他陪审员的盐时，采用明文相同且Id最小的一组
+	adoptPrivateSeal(ctx)
+	return p.signContractResult(reqId, ctx, reqTx, ele)
+}
 
-	tx, err := p.GenContractTransaction(reqTx, msgs)
+//signContractResult 用私有状态当前的盐生成执行结果交易，签名后与收到的签名交易合并，调用者持有p.locker
+func (p *Processor) signContractResult(reqId common.Hash, ctx *contractTx, reqTx *modules.Transaction,
+	ele *modules.ElectionNode) error {
+	states := p.encryptPrivateStates(ctx.privates, ele)
+	p.trackPrivateStates(states)
+
+	tx, err := p.GenContractTransaction(reqTx, sealPrivateWrites(ctx.msgs, ctx.privates))
 	if err != nil {
 		log.Error("[%s]runContractReq, GenContractSigTransactions error:%s", shortId(reqId.String()), err.Error())
 		return err
 	}
+	callees, err := getContractCalleeJuries(p.dag, tx)
+	if err != nil {
+		log.Errorf("[%s]runContractReq, getContractCalleeJuries error:%s", shortId(reqId.String()), err.Error())
+		return err
+	}
+	ctx.callees = callees
 
 	//如果系统合约，直接添加到缓存池
 	//如果用户合约，需要签名，添加到缓存池并广播
@@ -323,18 +389,20 @@ func (p *Processor) runContractReq(reqId common.Hash, ele *modules.ElectionNode)
 
 		sigNum := getTxSigNum(ctx.sigTx)
 		cfgSigNum := getSysCfgContractSignatureNum(p.dag)
-		log.Debugf("[%s]runContractReq sigNum %d, p.contractSigNum %d", shortId(reqId.String()), sigNum, cfgSigNum)
-		if sigNum >= cfgSigNum {
+		log.Debugf("[%s]runContractReq sigNum %d, p.contractSigNum %d, callee juries %d",
+			shortId(reqId.String()), sigNum, cfgSigNum, len(ctx.callees))
+		if p.contractSigEnough(ctx) {
 			if localIsMinSignature(ctx.sigTx) {
 				//签名数量足够，而且当前节点是签名最新的节点，那么合并签名并广播完整交易
 				log.Infof("[%s]runContractReq, localIsMinSignature Ok!", shortId(reqId.String()))
-				p.processContractPayout(ctx.sigTx, ele)
+				p.processContractPayout(ctx.sigTx, ele, ctx.callees)
 				go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_COMMIT, Ele: ele, Tx: ctx.sigTx}, true)
 				return nil
 			}
 		}
-		//广播
-		go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_SIG, Ele: ele, Tx: sigTx}, false)
+		//广播，私有状态的密文随签名交易发给其他陪审员
+		go p.ptn.ContractBroadcast(ContractEvent{CType: CONTRACT_EVENT_SIG, Ele: ele, Tx: sigTx,
+			PrivateStates: states}, false)
 	}
 	return nil
 }
@@ -722,7 +790,10 @@ func (p *Processor) isValidateElection(tx *modules.Transaction, ele *modules.Ele
 	}
 	jjhAd := p.dag.GetChainParameters().FoundationAddress
 	isExit := false
-	elr := newElector(uint(cfgEleNum), ele.JuryCount, common.Address{}, "", p.ptn.GetKeyStore())
+	//链上记录的陪审团在部署或者重新选举时已经验证过VRF
+	isContractJury := cType == modules.APP_CONTRACT_INVOKE_REQUEST && p.isContractJury(contractId, ele)
+	elr := newElector(uint(cfgEleNum), ele.JuryCount, common.Address{}, "", p.ptn.GetKeyStore(),
+		getSysCfgVrfAlgorithm(p.dag), p.jurorScore)
 	for i, e := range ele.EleList {
 		isVerify := false
 		//检查地址hash是否在本地
@@ -759,6 +830,9 @@ func (p *Processor) isValidateElection(tx *modules.Transaction, ele *modules.Ele
 			log.Errorf("[%s]isValidateElection, not active Jury, addrHash[%v]", shortId(reqId.String()), e.AddrHash)
 			return false
 		}
+		if isContractJury {
+			continue
+		}
 		isVerify, err := elr.verifyVrf(e.Proof, conversionElectionSeedData(contractId), e.PublicKey)
 		if err != nil || !isVerify {
 			log.Infof("[%s]isValidateElection, index[%d],verifyVrf fail, contractId[%s]",
@@ -840,6 +914,9 @@ func (p *Processor) createContractTxReqToken(contractId, from, to common.Address
 	if err != nil {
 		return common.Hash{}, nil, err
 	}
+	if err = checkContractInvokeReqFee(p.dag, tx, daoFee); err != nil {
+		return common.Hash{}, nil, err
+	}
 	log.Debugf("[%s]createContractTxReqToken,contractId[%s],tx[%v]",
 		shortId(tx.RequestHash().String()), contractId.String(), tx)
 	return p.signGenericTx(contractId, from, tx)
@@ -851,6 +928,9 @@ func (p *Processor) createContractTxReq(contractId, from, to common.Address, dao
 	if err != nil {
 		return common.Hash{}, nil, err
 	}
+	if err = checkContractInvokeReqFee(p.dag, tx, daoFee); err != nil {
+		return common.Hash{}, nil, err
+	}
 	return p.signGenericTx(contractId, from, tx)
 }
 func (p *Processor) SignAndExecuteAndSendRequest(from common.Address,
@@ -923,6 +1003,7 @@ func (p *Processor) ContractTxDeleteLoop() {
 			} else {
 				if time.Since(v.tm) > time.Second*600 {
 					log.Infof("[%s]ContractTxDeleteLoop, contract is valid, delete tx id", shortId(k.String()))
+					p.recordJurorOutcomes(v)
 					delete(p.mtx, k)
 				}
 			}
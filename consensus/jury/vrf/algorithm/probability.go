@@ -126,3 +126,45 @@ func Selected(expectedNum uint, weight, total uint64, vrf []byte) int {
 //		return 0
 //	}
 //}
+
+/*
+Preliminary test conclusion
+expectNum:4
+use:
+total    weight    num
+20         4        5
+50         7        7
+100        8        5
+200        15       5
+500        17       6
+1000       18       5
+*/
+//ElectionWeight 根据陪审员候选人总数确定选举时使用的权重
+func ElectionWeight(total uint64) (val uint64) {
+	if total <= 20 {
+		return 4
+	} else if total > 20 && total <= 50 {
+		return 7
+	} else if total > 50 && total <= 100 {
+		return 8
+	} else if total > 100 && total <= 200 {
+		return 15
+	} else if total > 200 && total <= 500 {
+		return 17
+	} else if total > 500 {
+		return 20
+	}
+	return 4
+}
+
+//ReputationWeight 按陪审员的信誉(0-100)降低其选举权重，信誉越低当选概率越小，权重最小为1
+func ReputationWeight(weight uint64, score uint32) uint64 {
+	if score > 100 {
+		score = 100
+	}
+	val := weight * uint64(score) / 100
+	if val < 1 {
+		return 1
+	}
+	return val
+}
@@ -19,9 +19,11 @@
 package vrf
 
 import (
-	es "github.com/palletone/go-palletone/consensus/jury/vrf/vrfEs"
-	//ec "github.com/palletone/go-palletone/consensus/jury/vrf/vrfEss"
-	//ess "github.com/palletone/go-palletone/consensus/jury/vrf/vrfEss"
+	"fmt"
+
+	"github.com/palletone/go-palletone/consensus/jury/vrf/vrfEc"
+	"github.com/palletone/go-palletone/consensus/jury/vrf/vrfEs"
+	"github.com/palletone/go-palletone/consensus/jury/vrf/vrfEss"
 )
 
 type Vrf interface {
@@ -29,12 +31,28 @@ type Vrf interface {
 	VrfVerify(pubKey, msg, proof []byte) (verify bool, selData []byte, err error)
 }
 
-var vTpye = new(es.Es) //VRF type
+//VRF算法名称，通过系统参数VrfAlgorithm选择
+//vrfEd基于ed25519密钥，与账户的secp256k1密钥不兼容，不能用于陪审员选举
+const (
+	AlgorithmEs  = "es"
+	AlgorithmEss = "ess"
+	AlgorithmEc  = "ec"
+)
 
-func VrfProve(priKey interface{}, msg []byte) (proof, selData []byte, err error) {
-	return vTpye.VrfProve(priKey, msg)
+var algorithms = map[string]Vrf{
+	AlgorithmEs:  new(vrfEs.Es),
+	AlgorithmEss: new(vrfEss.Ess),
+	AlgorithmEc:  new(vrfEc.Ec),
 }
 
-func VrfVerify(pubKey, msg, proof []byte) (verify bool, selData []byte, err error) {
-	return vTpye.VrfVerify(pubKey, msg, proof)
+//GetVrf 根据算法名称获取VRF实现，名称为空时使用默认的es算法
+func GetVrf(name string) (Vrf, error) {
+	if name == "" {
+		name = AlgorithmEs
+	}
+	v, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported vrf algorithm: %s", name)
+	}
+	return v, nil
 }
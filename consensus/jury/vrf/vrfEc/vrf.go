@@ -53,7 +53,13 @@ func (e *Ec) VrfProve(priKey interface{}, msg []byte) (proof ,selData []byte, er
 //func VrfVerify(pub *ecdsa.PublicKey, msg, vrfValue, vrfProof []byte) (bool, error) {
 //func VrfVerify(pub *ecdsa.PublicKey, msg, proof []byte) (bool, error) {
 func (e *Ec) VrfVerify(pubKey, msg, proof []byte) (bool, []byte, error) {
-	pk:= crypto.P256ToECDSAPub(pubKey)
+	var pk *ecdsa.PublicKey
+	if len(pubKey) == 33 {
+		//账户使用的压缩格式的secp256k1公钥
+		pk, _ = crypto.DecompressPubkey(pubKey)
+	} else {
+		pk = crypto.P256ToECDSAPub(pubKey)
+	}
 	if pk == nil {
 		log.Error("VrfVerify, P256ToECDSAPub fail")
 		return false, nil, errors.New("VrfVerify, P256ToECDSAPub fail")
@@ -0,0 +1,58 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2018
+ */
+package vrf
+
+import (
+	"testing"
+
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVrf(t *testing.T) {
+	for _, alg := range core.SupportedVrfAlgorithms {
+		v, err := GetVrf(alg)
+		assert.Nil(t, err, alg)
+		assert.NotNil(t, v, alg)
+	}
+	def, err := GetVrf("")
+	assert.Nil(t, err)
+	assert.Equal(t, algorithms[core.DefaultVrfAlgorithm], def)
+
+	_, err = GetVrf("ed")
+	assert.NotNil(t, err)
+}
+
+func TestVrfEss(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pubKey := crypto.CompressPubkey(&key.PublicKey)
+	v, _ := GetVrf(AlgorithmEss)
+	msg := []byte("election seed")
+
+	proof, sel, err := v.VrfProve(key, msg)
+	assert.Nil(t, err)
+	ok, sel2, err := v.VrfVerify(pubKey, msg, proof)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, sel, sel2)
+
+	ok, _, _ = v.VrfVerify(pubKey, []byte("another seed"), proof)
+	assert.False(t, ok)
+}
@@ -0,0 +1,71 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package mediatorplugin
+
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+// 接收memdag发现的mediator作恶事件，由本地的活跃mediator向保证金合约提交证据
+func (mp *MediatorPlugin) equivocationEvidenceLoop() {
+	ch := make(chan modules.MediatorEquivocationEvent)
+	sub := mp.dag.SubscribeMediatorEquivocationEvent(ch)
+	defer sub.Unsubscribe()
+
+	// 已经提交过的证据，避免重复提交
+	submitted := make(map[common.Hash]bool)
+	for {
+		select {
+		case ev := <-ch:
+			hash := ev.Evidence.Hash()
+			if submitted[hash] {
+				continue
+			}
+			if mp.submitEquivocationEvidence(ev.Evidence) {
+				submitted[hash] = true
+			}
+		case <-sub.Err():
+			return
+		case <-mp.quit:
+			return
+		}
+	}
+}
+
+func (mp *MediatorPlugin) submitEquivocationEvidence(evidence *modules.MediatorEquivocationEvidence) bool {
+	offender := evidence.Mediator()
+	for _, med := range mp.GetLocalActiveMediators() {
+		if med == offender {
+			continue
+		}
+		reqId, err := mp.ptn.ContractProcessor().SubmitEquivocationEvidence(med, evidence)
+		if err != nil {
+			log.Warnf("mediator(%v) submit equivocation evidence of mediator(%v) err: %v", med.Str(),
+				offender.Str(), err.Error())
+			continue
+		}
+		log.Infof("mediator(%v) submitted equivocation evidence of mediator(%v), reqId: %v", med.Str(),
+			offender.Str(), reqId.String())
+		return true
+	}
+	return false
+}
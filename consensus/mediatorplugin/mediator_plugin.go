@@ -28,6 +28,7 @@ import (
 	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/dag/rwset"
 )
 
@@ -237,14 +238,17 @@ func (mp *MediatorPlugin) maybeProduceUnit() (ProductionCondition, map[string]st
 
 	// 2. 生产单元
 	var groupPubKey []byte = nil
+	var attest *modules.SigShareAttestation = nil
 	if mp.groupSigningEnabled {
 		groupPubKey = mp.localMediatorPubKey(scheduledMediator)
 		if len(groupPubKey) == 0 {
 			log.Debugf("the groupPubKey is nil")
 		}
+		// 记录上一个unit收到的签名分片情况
+		attest = mp.sigShareStat.attestation(scheduledMediator, dag.GetActiveMediators())
 	}
 
-	newUnit, err := dag.GenerateUnit(scheduledTime, scheduledMediator, groupPubKey, ks, mp.ptn.TxPool())
+	newUnit, err := dag.GenerateUnit(scheduledTime, scheduledMediator, groupPubKey, ks, mp.ptn.TxPool(), attest)
 	if err != nil {
 		detail["Msg"] = fmt.Sprintf("GenerateUnit err: %v", err.Error())
 		return ExceptionProducing, detail
@@ -259,11 +263,14 @@ func (mp *MediatorPlugin) maybeProduceUnit() (ProductionCondition, map[string]st
 	detail["ParentHash"] = newUnit.ParentHash()[0].TerminalString()
 
 	// 3. 对 unit 进行群签名和广播
-	go mp.groupSignUnit(scheduledMediator, unitHash)
+	go mp.groupSignUnit(scheduledMediator, unitHash, newUnit.NumberU64())
 
 	// 4. 异步向区块链网络广播新unit
 	go mp.newProducedUnitFeed.Send(NewProducedUnitEvent{Unit: newUnit})
 	log.Debugf("send NewProducedUnitEvent")
 
+	// 5. 通知合约处理模块为到期的定时调用任务发起触发请求
+	mp.ptn.ContractProcessor().ScheduleTrigger(scheduledMediator)
+
 	return Produced, detail
 }
@@ -67,7 +67,7 @@ type iDag interface {
 	IsActiveMediator(add common.Address) bool
 
 	GenerateUnit(when time.Time, producer common.Address, groupPubKey []byte, ks *keystore.KeyStore,
-		txpool txspool.ITxPool) (*modules.Unit, error)
+		txpool txspool.ITxPool, attest *modules.SigShareAttestation) (*modules.Unit, error)
 
 	IsPrecedingMediator(add common.Address) bool
 	IsIrreversibleUnit(hash common.Hash) (bool, error)
@@ -80,6 +80,7 @@ type iDag interface {
 
 	IsConsecutiveMediator(nextMediator common.Address) bool
 	MediatorParticipationRate() uint32
+	SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription
 }
 
 type MediatorPlugin struct {
@@ -139,6 +140,9 @@ type MediatorPlugin struct {
 	toTBLSRecoverBuf map[common.Address]map[common.Hash]*sigShareSet
 	toTBLSBufLock    *sync.RWMutex
 
+	// 签名分片贡献统计
+	sigShareStat *sigShareStat
+
 	// unit 签名分片的事件订阅
 	sigShareFeed  event.Feed
 	sigShareScope event.SubscriptionScope
@@ -181,6 +185,11 @@ func (mp *MediatorPlugin) Start(server *p2p.Server) error {
 		go mp.launchProduction()
 	}
 
+	// 监听mediator作恶事件并提交证据
+	if len(mp.mediators) > 0 {
+		go mp.equivocationEvidenceLoop()
+	}
+
 	log.Debugf("mediator plugin startup end")
 	return nil
 }
@@ -336,6 +345,7 @@ func (mp *MediatorPlugin) initGroupSignBuf() {
 	mp.toTBLSSignBuf = make(map[common.Address]map[common.Hash]bool, lamc)
 	mp.toTBLSRecoverBuf = make(map[common.Address]map[common.Hash]*sigShareSet, lamc)
 	mp.toTBLSBufLock = new(sync.RWMutex)
+	mp.sigShareStat = newSigShareStat()
 }
 
 func (mp *MediatorPlugin) UpdateMediatorsDKG(isRenew bool) {
@@ -352,6 +362,7 @@ func (mp *MediatorPlugin) UpdateMediatorsDKG(isRenew bool) {
 	mp.lastMaintenanceTime = mp.dag.LastMaintenanceTime()
 	//log.Debugf("dkgLock.Unlock()")
 	mp.dkgLock.Unlock()
+	mp.sigShareStat.closeRound()
 
 	// 判断是否重新 初始化DKG 和 VSS 协议
 	// todo albert 待优化
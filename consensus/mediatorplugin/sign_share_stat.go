@@ -0,0 +1,207 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package mediatorplugin
+
+import (
+	"sync"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/statistics/metrics"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+)
+
+// sigShareStat 统计本地mediator生产的unit收到的各个活跃mediator的签名分片，
+// 签名分片只有unit的生产者才能收到，所以生产者在其下一个unit的Header中记录上一个unit的统计结果，
+// 由各节点在应用unit时计入mediator的活跃度
+type sigShareStat struct {
+	lock sync.Mutex
+
+	// 本届需要群签名的unit数
+	requested uint32
+	// 每个unit已经收到签名分片的mediator，用于去重
+	units map[common.Hash]map[common.Address]bool
+	// 本届每个mediator贡献签名分片的unit数
+	current map[common.Address]uint32
+	// 上一届的统计结果
+	preceding map[common.Address]modules.LivenessRound
+	// 本届每个本地mediator最近生产的unit
+	latest map[common.Address]*producedUnit
+}
+
+type producedUnit struct {
+	hash  common.Hash
+	index uint64
+}
+
+func newSigShareStat() *sigShareStat {
+	return &sigShareStat{
+		units:     make(map[common.Hash]map[common.Address]bool),
+		current:   make(map[common.Address]uint32),
+		preceding: make(map[common.Address]modules.LivenessRound),
+		latest:    make(map[common.Address]*producedUnit),
+	}
+}
+
+func (s *sigShareStat) addUnit(producer common.Address, unitHash common.Hash, index uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.units[unitHash]; ok {
+		return
+	}
+	s.units[unitHash] = make(map[common.Address]bool)
+	s.requested++
+	s.latest[producer] = &producedUnit{hash: unitHash, index: index}
+}
+
+// attestation 返回producer本届最近生产的unit收到签名分片的情况，activeMeds是按地址排序的活跃mediator列表
+func (s *sigShareStat) attestation(producer common.Address,
+	activeMeds []common.Address) *modules.SigShareAttestation {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	latest, ok := s.latest[producer]
+	if !ok {
+		return nil
+	}
+	contributors := s.units[latest.hash]
+	indexes := make([]int, 0, len(contributors))
+	for i, med := range activeMeds {
+		if contributors[med] {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return modules.NewSigShareAttestation(latest.index, indexes)
+}
+
+// 返回false表示该unit不在本届统计范围内，或者已经收到过该mediator的签名分片
+func (s *sigShareStat) addSigShare(unitHash common.Hash, med common.Address) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	contributors, ok := s.units[unitHash]
+	if !ok || contributors[med] {
+		return false
+	}
+	contributors[med] = true
+	s.current[med]++
+
+	return true
+}
+
+// 换届时结束本届的统计
+func (s *sigShareStat) closeRound() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.preceding = s.roundsLocked()
+	s.requested = 0
+	s.units = make(map[common.Hash]map[common.Address]bool)
+	s.current = make(map[common.Address]uint32)
+	s.latest = make(map[common.Address]*producedUnit)
+}
+
+func (s *sigShareStat) roundsLocked() map[common.Address]modules.LivenessRound {
+	rounds := make(map[common.Address]modules.LivenessRound, len(s.current))
+	for med, count := range s.current {
+		rounds[med] = modules.LivenessRound{Scheduled: s.requested, Produced: count}
+	}
+	return rounds
+}
+
+// 返回本届需要群签名的unit数，以及本届和上一届的统计结果
+func (s *sigShareStat) rounds() (uint32, map[common.Address]modules.LivenessRound,
+	map[common.Address]modules.LivenessRound) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.requested, s.roundsLocked(), s.preceding
+}
+
+// 签名分片贡献率，以百分比表示
+func sigShareScore(r modules.LivenessRound) uint32 {
+	if r.Scheduled == 0 {
+		return 100
+	}
+	return uint32(uint64(r.Produced) * 100 / uint64(r.Scheduled))
+}
+
+func (mp *MediatorPlugin) recordSigShare(unitHash common.Hash, med common.Address) {
+	if !mp.sigShareStat.addSigShare(unitHash, med) {
+		return
+	}
+
+	_, current, _ := mp.sigShareStat.rounds()
+	metrics.GetOrRegisterGauge("mediator/sigshare/"+med.Str(), nil).Update(int64(sigShareScore(current[med])))
+}
+
+// SigShareStat 签名分片贡献情况
+type SigShareStat struct {
+	Mediator  string                `json:"mediator"`
+	Score     uint32                `json:"score"`
+	Current   modules.LivenessRound `json:"current"`
+	Preceding modules.LivenessRound `json:"preceding"`
+}
+
+// GetSigShareStats 返回本地mediator生产的unit中，各个活跃mediator本届和上一届的签名分片贡献情况
+func (a *PublicMediatorAPI) GetSigShareStats() []*SigShareStat {
+	stats := make([]*SigShareStat, 0)
+	if a.sigShareStat == nil {
+		return stats
+	}
+
+	requested, current, preceding := a.sigShareStat.rounds()
+	for _, med := range a.dag.GetActiveMediators() {
+		cur, ok := current[med]
+		if !ok {
+			cur = modules.LivenessRound{Scheduled: requested}
+		}
+
+		stats = append(stats, &SigShareStat{
+			Mediator:  med.Str(),
+			Score:     sigShareScore(cur),
+			Current:   cur,
+			Preceding: preceding[med],
+		})
+	}
+
+	return stats
+}
+
+// 根据签名分片的索引找到对应的活跃mediator，换届前生产的unit不再统计
+func (mp *MediatorPlugin) countSigShare(header *modules.Header, sigShare []byte) {
+	mp.dkgLock.RLock()
+	isCurrent := header.Timestamp() > mp.lastMaintenanceTime
+	mp.dkgLock.RUnlock()
+	if !isCurrent {
+		return
+	}
+
+	index, err := tbls.SigShare(sigShare).Index()
+	if err != nil || index < 0 || index >= mp.dag.ActiveMediatorsCount() {
+		log.Debugf("invalid sign share of the unit(%v)", header.Hash().TerminalString())
+		return
+	}
+
+	mp.recordSigShare(header.Hash(), mp.dag.GetActiveMediatorAddr(index))
+}
@@ -246,6 +246,10 @@ func (mp *MediatorPlugin) AddToTBLSRecoverBuf(event *SigShareEvent) {
 	}
 
 	localMed := header.Author()
+	if mp.IsLocalMediator(localMed) {
+		mp.countSigShare(header, event.SigShare)
+	}
+
 	mp.toTBLSBufLock.Lock()
 	//log.Debugf("toTBLSBufLock.Lock()")
 	//defer log.Debugf("toTBLSBufLock.Unlock()")
@@ -378,7 +382,7 @@ func (mp *MediatorPlugin) recoverUnitTBLS(localMed common.Address, unitHash comm
 	go mp.groupSigFeed.Send(event)
 }
 
-func (mp *MediatorPlugin) groupSignUnit(localMed common.Address, unitHash common.Hash) {
+func (mp *MediatorPlugin) groupSignUnit(localMed common.Address, unitHash common.Hash, index uint64) {
 	if !mp.groupSigningEnabled {
 		return
 	}
@@ -393,6 +397,7 @@ func (mp *MediatorPlugin) groupSignUnit(localMed common.Address, unitHash common
 	mp.toTBLSRecoverBuf[localMed][unitHash] = newSigShareSet(aSize)
 	//log.Debugf("toTBLSBufLock.Unlock()")
 	mp.toTBLSBufLock.Unlock()
+	mp.sigShareStat.addUnit(localMed, unitHash, index)
 
 	// 2. 过了 unit 确认时间后，及时删除群签名分片的相关数据，防止内存溢出
 	go func() {
@@ -48,8 +48,9 @@ type ContractInf interface {
 	Close() error
 	Install(chainID string, ccName string, ccPath string, ccVersion string, ccDescription, ccAbi, ccLanguage string) (payload *md.ContractTplPayload, err error)
 	Deploy(rwM rwset.TxManager, chainID string, templateId []byte, txId string, args [][]byte, timeout time.Duration) (deployId []byte, deployPayload *md.ContractDeployPayload, e error)
-	Invoke(rwM rwset.TxManager, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration) (*md.ContractInvokeResult, error)
+	Invoke(rwM rwset.TxManager, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration, gasLimit uint64) (*md.ContractInvokeResult, error)
 	Stop(rwM rwset.TxManager, chainID string, deployId []byte, txid string, deleteImage bool) (*md.ContractStopPayload, error)
+	Upgrade(rwM rwset.TxManager, chainID string, deployId []byte, templateId []byte, txid string, args [][]byte, timeout time.Duration) (*md.ContractUpgradePayload, error)
 }
 
 // Initialize 初始化合约管理模块以及加载系统合约，
@@ -137,7 +138,7 @@ func (c *Contract) Deploy(rwM rwset.TxManager, chainID string, templateId []byte
 // Invoke 合约invoke调用，根据指定合约调用参数执行已经部署的合约，函数返回合约调用单元。
 // The contract invoke call, execute the deployed contract according to the specified contract call parameters,
 // and the function returns the contract call unit.
-func (c *Contract) Invoke(rwM rwset.TxManager, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration) (*md.ContractInvokeResult, error) {
+func (c *Contract) Invoke(rwM rwset.TxManager, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration, gasLimit uint64) (*md.ContractInvokeResult, error) {
 	log.Info("Enter Contract Invoke====", "chainID", chainID, "deployId", deployId, "txid", txid, "timeout", timeout)
 	defer log.Info("Exit Contract Invoke====", "chainID", chainID, "deployId", deployId, "txid", txid, "timeout", timeout)
 	atomic.LoadInt32(&initFlag)
@@ -149,7 +150,31 @@ func (c *Contract) Invoke(rwM rwset.TxManager, chainID string, deployId []byte,
 		log.Info("contract test invoke")
 		return test.Invoke(rwM, c.dag, chainID, deployId, txid, args)
 	}
-	return cc.Invoke(rwM, c.dag, chainID, deployId, txid, args, timeout)
+	return cc.Invoke(rwM, c.dag, chainID, deployId, txid, args, timeout, gasLimit)
+}
+
+// Simulate 合约的模拟执行，与Invoke相同但不改变任何状态。stable为true时只读取稳定单元的状态，trace为true时返回合约的每一次shim调用
+//Simulate the contract invoke without changing any state. If stable is true only the state of the stable units is read,
+//and if trace is true the shim calls of the contract are also returned.
+func (c *Contract) Simulate(rwM rwset.TxManager, chainID string, deployId []byte, txid string, args [][]byte,
+	timeout time.Duration, gasLimit uint64, stable, trace bool) (*md.ContractInvokeResult, []*md.ShimCallTrace, error) {
+	log.Info("Enter Contract Simulate====", "chainID", chainID, "deployId", deployId, "txid", txid, "stable", stable)
+	defer log.Info("Exit Contract Simulate====", "chainID", chainID, "deployId", deployId, "txid", txid, "stable", stable)
+	atomic.LoadInt32(&initFlag)
+	if initFlag == 0 {
+		log.Error("Contract module not initialized")
+		return nil, nil, errors.New("contract not initialized")
+	}
+	idag := c.dag
+	if stable {
+		idag = c.dag.StableStateDag()
+	}
+	if contractcfg.DebugTest {
+		log.Info("contract test simulate")
+		result, err := test.Invoke(rwM, idag, chainID, deployId, txid, args)
+		return result, nil, err
+	}
+	return cc.Simulate(rwM, idag, chainID, deployId, txid, args, timeout, gasLimit, trace)
 }
 
 // Stop 停止指定合约。根据需求可以对镜像文件进行删除操作
@@ -168,3 +193,19 @@ func (c *Contract) Stop(rwM rwset.TxManager, chainID string, deployId []byte, tx
 	}
 	return cc.Stop(rwM, c.dag, deployId, chainID, txid, deleteImage, false)
 }
+
+// Upgrade 把已部署的合约升级到新的模板，并在新模板上执行状态迁移
+//Upgrade the deployed contract to the new template and migrate the state on the new template.
+func (c *Contract) Upgrade(rwM rwset.TxManager, chainID string, deployId []byte, templateId []byte, txid string, args [][]byte, timeout time.Duration) (*md.ContractUpgradePayload, error) {
+	log.Info("Enter Contract Upgrade====", "chainID", chainID, "deployId", deployId, "templateId", templateId, "txid", txid)
+	defer log.Info("Exit Contract Upgrade====", "chainID", chainID, "deployId", deployId, "txid", txid)
+	atomic.LoadInt32(&initFlag)
+	if initFlag == 0 {
+		log.Error("Contract module not initialized")
+		return nil, errors.New("contract not initialized")
+	}
+	if contractcfg.DebugTest {
+		return nil, errors.New("contract upgrade is not supported in debug test mode")
+	}
+	return cc.Upgrade(rwM, c.dag, chainID, deployId, templateId, txid, args, timeout)
+}
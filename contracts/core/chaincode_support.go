@@ -38,6 +38,7 @@ import (
 	"github.com/palletone/go-palletone/contracts/accesscontrol"
 	cfg "github.com/palletone/go-palletone/contracts/contractcfg"
 	"github.com/palletone/go-palletone/contracts/platforms"
+	"github.com/palletone/go-palletone/contracts/platforms/wasm"
 	"github.com/palletone/go-palletone/contracts/shim"
 	"github.com/palletone/go-palletone/core/vmContractPub/ccprovider"
 	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
@@ -45,6 +46,7 @@ import (
 	"github.com/palletone/go-palletone/vm/api"
 	"github.com/palletone/go-palletone/vm/ccintf"
 	"github.com/palletone/go-palletone/vm/controller"
+	"github.com/palletone/go-palletone/vm/inproccontroller"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"golang.org/x/net/context"
@@ -431,6 +433,9 @@ func (chaincodeSupport *ChaincodeSupport) getLaunchConfigs(cccid *ccprovider.CCC
 		args = []string{"java", "-jar", "chaincode.jar", "--peerAddress", chaincodeSupport.peerAddress}
 	case pb.ChaincodeSpec_NODE:
 		args = []string{"/bin/sh", "-c", fmt.Sprintf("cd /usr/local/src; npm start -- --peer.address %s", chaincodeSupport.peerAddress)}
+	case pb.ChaincodeSpec_WASM:
+		//在节点进程内运行，不需要启动命令
+		args = []string{"wasm"}
 
 	default:
 		return nil, nil, nil, errors.Errorf("unknown chaincodeType: %s", cLang)
@@ -619,6 +624,9 @@ func (chaincodeSupport *ChaincodeSupport) Stop(context context.Context, cccid *c
 	//sir := container.StopImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, Version: cccid.Version}, Timeout: 0}
 	// The line below is left for debugging. It replaces the line above to keep
 	// the chaincode container around to give you a chance to get data
+	if isWasmChaincode(cds) {
+		cds.ChaincodeSpec.ChaincodeId.Path = wasmChaincodePath(cds)
+	}
 	sir := controller.StopImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, ChainID: "" /*cccid.ChainID*/, Version: cccid.Version}, Timeout: 0, Dontremove: dontRmCon}
 	vmtype := chaincodeSupport.getVMType(cds)
 
@@ -627,6 +635,9 @@ func (chaincodeSupport *ChaincodeSupport) Stop(context context.Context, cccid *c
 		err = errors.WithMessage(err, "error stopping container")
 		//but proceed to cleanup
 	}
+	if isWasmChaincode(cds) {
+		inproccontroller.Deregister(cds.ChaincodeSpec.ChaincodeId.Path)
+	}
 
 	chaincodeSupport.runningChaincodes.Lock()
 	if _, ok := chaincodeSupport.chaincodeHasBeenLaunched(canName); !ok {
@@ -798,6 +809,9 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context,
 		//	}
 		//}
 
+		if err = registerWasmChaincode(cds); err != nil {
+			return cID, cMsg, err
+		}
 		builder := func() (io.Reader, error) { return platforms.GenerateDockerBuild(cds) }
 		err = chaincodeSupport.launchAndWaitForRegister(context, cccid, cds, &ccLauncherImpl{context, chaincodeSupport, cccid, cds, builder})
 		if err != nil {
@@ -827,12 +841,36 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context,
 //getVMType - just returns a string for now. Another possibility is to use a factory method to
 //return a VM executor
 func (chaincodeSupport *ChaincodeSupport) getVMType(cds *pb.ChaincodeDeploymentSpec) string {
-	if cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM {
+	if cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM || isWasmChaincode(cds) {
 		return controller.SYSTEM
 	}
 	return controller.DOCKER
 }
 
+func isWasmChaincode(cds *pb.ChaincodeDeploymentSpec) bool {
+	return cds.ChaincodeSpec != nil && cds.ChaincodeSpec.Type == pb.ChaincodeSpec_WASM
+}
+
+//wasm合约按合约名注册到进程内容器，每个合约一个独立的路径
+func wasmChaincodePath(cds *pb.ChaincodeDeploymentSpec) string {
+	return "wasm/" + cds.ChaincodeSpec.ChaincodeId.Name
+}
+
+//registerWasmChaincode 用合约字节码创建进程内的wasm合约，替换已注册的同名合约
+func registerWasmChaincode(cds *pb.ChaincodeDeploymentSpec) error {
+	if !isWasmChaincode(cds) {
+		return nil
+	}
+	cc, err := wasm.NewChaincode(cds.CodePackage)
+	if err != nil {
+		return errors.WithMessage(err, "invalid wasm chaincode")
+	}
+	path := wasmChaincodePath(cds)
+	cds.ChaincodeSpec.ChaincodeId.Path = path
+	inproccontroller.Deregister(path)
+	return inproccontroller.Register(path, cc)
+}
+
 // HandleChaincodeStream implements ccintf.HandleChaincodeStream for all vms to call with appropriate stream
 func (chaincodeSupport *ChaincodeSupport) HandleChaincodeStream(ctxt context.Context, stream ccintf.ChaincodeStream) error {
 	return HandleChaincodeStream(chaincodeSupport, ctxt, stream, chaincodeSupport.jury)
@@ -0,0 +1,221 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/palletone/go-palletone/core/vmContractPub/ccprovider"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+//ContractCallKey is used to attach the call stack of user contract invocation
+const ContractCallKey key = "contractcallkey"
+
+//合约调用合约的最大深度，包括用户直接调用的合约
+const MaxContractCallDepth = 8
+
+//ContractResolver returns the chaincode id of a deployed user contract
+type ContractResolver func(contractAddr common.Address) (*pb.ChaincodeID, error)
+
+//Callee is a user contract called by another contract successfully
+type Callee struct {
+	Address common.Address
+	Name    string             //读写集的命名空间
+	Event   *pb.ChaincodeEvent //被调用合约设置的事件
+}
+
+// ContractCall keeps the call stack of one user contract invocation. All the
+// called contracts share the TxSimulator and the gas meter of the invocation.
+type ContractCall struct {
+	lock    sync.Mutex
+	resolve ContractResolver
+	timeout time.Duration
+	stack   []common.Address
+	callees []*Callee
+}
+
+func NewContractCall(contractAddr common.Address, timeout time.Duration, resolve ContractResolver) *ContractCall {
+	return &ContractCall{resolve: resolve, timeout: timeout, stack: []common.Address{contractAddr}}
+}
+
+//GetContractCall returns the call stack attached to the context, nil if not exist
+func GetContractCall(ctxt context.Context) *ContractCall {
+	if call, ok := ctxt.Value(ContractCallKey).(*ContractCall); ok {
+		return call
+	}
+	return nil
+}
+
+//Callees returns the called contracts in the order of completion
+func (c *ContractCall) Callees() []*Callee {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*Callee{}, c.callees...)
+}
+
+//enter 检查调用深度和重入，返回调用合约、被调用合约的ChaincodeID和当前已完成调用的数量
+func (c *ContractCall) enter(callee common.Address) (common.Address, *pb.ChaincodeID, int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	caller := c.stack[len(c.stack)-1]
+	if len(c.stack) >= MaxContractCallDepth {
+		return caller, nil, 0, fmt.Errorf("contract call depth exceeds %d", MaxContractCallDepth)
+	}
+	for _, addr := range c.stack {
+		if addr == callee {
+			return caller, nil, 0, fmt.Errorf("reentrant call to contract %s", callee.String())
+		}
+	}
+	cid, err := c.resolve(callee)
+	if err != nil {
+		return caller, nil, 0, err
+	}
+	c.stack = append(c.stack, callee)
+	return caller, cid, len(c.callees), nil
+}
+
+//exit 被调用合约执行结束，成功时记录被调用合约，失败时丢弃其内部完成的调用
+func (c *ContractCall) exit(checkpoint int, callee *Callee) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stack = c.stack[:len(c.stack)-1]
+	if callee == nil {
+		c.callees = c.callees[:checkpoint]
+		return
+	}
+	for _, cl := range c.callees {
+		if cl.Address == callee.Address {
+			return
+		}
+	}
+	c.callees = append(c.callees, callee)
+}
+
+//getUserContractCallee 被调用的是否为用户合约，用户合约使用合约地址调用
+func getUserContractCallee(payload []byte) (*pb.ChaincodeSpec, common.Address, bool) {
+	spec := &pb.ChaincodeSpec{}
+	if err := proto.Unmarshal(payload, spec); err != nil || spec.ChaincodeId == nil {
+		return nil, common.Address{}, false
+	}
+	addr, err := common.StringToAddress(spec.ChaincodeId.Name)
+	if err != nil || addr.GetType() != common.ContractHash || addr.IsSystemContractAddress() {
+		return nil, common.Address{}, false
+	}
+	return spec, addr, true
+}
+
+//callUserContract 同步调用其他用户合约，被调用合约失败时回滚其所有读写集和Token操作
+func (handler *Handler) callUserContract(txContext *transactionContext, msg *pb.ChaincodeMessage,
+	spec *pb.ChaincodeSpec, callee common.Address) ([]byte, error) {
+	call := txContext.contractCall
+	if call == nil {
+		return nil, errors.New("contract call is not supported in this context")
+	}
+	caller, cid, checkpoint, err := call.enter(callee)
+	if err != nil {
+		return nil, err
+	}
+	txsim := txContext.txsimulator
+	snapshot := txsim.Snapshot()
+	response, err := handler.executeCallee(txContext, msg, spec, caller, callee, cid)
+	if err == nil {
+		err = calleeError(response)
+	}
+	if err != nil {
+		log.Debugf("[%s]C-call-C %s -> %s failed, rollback:%s", shorttxid(msg.Txid), caller.String(),
+			callee.String(), err.Error())
+		call.exit(checkpoint, nil)
+		if errRevert := txsim.RevertToSnapshot(snapshot); errRevert != nil {
+			return nil, errRevert
+		}
+		return nil, err
+	}
+	call.exit(checkpoint, &Callee{Address: callee, Name: cid.Name, Event: response.ChaincodeEvent})
+	return proto.Marshal(response)
+}
+
+func (handler *Handler) executeCallee(txContext *transactionContext, msg *pb.ChaincodeMessage,
+	spec *pb.ChaincodeSpec, caller, callee common.Address, cid *pb.ChaincodeID) (*pb.ChaincodeMessage, error) {
+	//被调用合约的请求地址为调用合约，前两个参数与用户调用时相同
+	invokeInfo, err := json.Marshal(modules.InvokeInfo{InvokeAddress: caller})
+	if err != nil {
+		return nil, err
+	}
+	var args [][]byte
+	if spec.Input != nil {
+		args = spec.Input.Args
+	}
+	if modules.IsContractMigrateCall(args) {
+		return nil, fmt.Errorf("can't call %s of contract %s", modules.ContractMigrateFunc, callee.String())
+	}
+	args = append([][]byte{invokeInfo, {}}, args...)
+
+	ctxt := context.WithValue(context.Background(), TXSimulatorKey, txContext.txsimulator)
+	ctxt = context.WithValue(ctxt, ContractCallKey, txContext.contractCall)
+	if txContext.gasMeter != nil {
+		ctxt = context.WithValue(ctxt, GasMeterKey, txContext.gasMeter)
+	}
+	if txContext.tracer != nil {
+		ctxt = context.WithValue(ctxt, CallTracerKey, txContext.tracer)
+	}
+	cccid := ccprovider.NewCCContext(callee.Bytes(), txContext.chainID, cid.Name, cid.Version, msg.Txid, false,
+		txContext.signedProp, txContext.proposal)
+	cciSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: spec.Type, ChaincodeId: cid,
+		Input: &pb.ChaincodeInput{Args: args}}}
+	log.Debugf("[%s]C-call-C %s -> %s", shorttxid(msg.Txid), caller.String(), callee.String())
+	_, input, err := handler.chaincodeSupport.Launch(ctxt, cccid, cciSpec)
+	if err != nil {
+		return nil, err
+	}
+	ccMsg, err := createCCMessage(callee.Bytes(), pb.ChaincodeMessage_TRANSACTION, txContext.chainID, msg.Txid, input)
+	if err != nil {
+		return nil, err
+	}
+	return handler.chaincodeSupport.Execute(ctxt, cccid, ccMsg, txContext.contractCall.timeout)
+}
+
+//calleeError 被调用合约返回错误或者执行失败
+func calleeError(response *pb.ChaincodeMessage) error {
+	if response == nil {
+		return errors.New("contract call response is nil")
+	}
+	if response.Type != pb.ChaincodeMessage_COMPLETED {
+		return fmt.Errorf("contract call failed:%s", string(response.Payload))
+	}
+	res := &pb.Response{}
+	if err := proto.Unmarshal(response.Payload, res); err != nil {
+		return err
+	}
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return fmt.Errorf("contract call failed:%s", res.Message)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContractAddr(name string) common.Address {
+	return crypto.RequestIdToContractAddress(common.BytesToHash([]byte(name)))
+}
+
+func TestContractCall_EnterExit(t *testing.T) {
+	a, b, c := newTestContractAddr("a"), newTestContractAddr("b"), newTestContractAddr("c")
+	call := NewContractCall(a, 0, func(addr common.Address) (*pb.ChaincodeID, error) {
+		return &pb.ChaincodeID{Name: addr.String()}, nil
+	})
+	caller, cid, cp, err := call.enter(b)
+	assert.Nil(t, err)
+	assert.Equal(t, a, caller)
+	assert.Equal(t, b.String(), cid.Name)
+	assert.Equal(t, 0, cp)
+
+	//重入
+	_, _, _, err = call.enter(a)
+	assert.NotNil(t, err)
+
+	caller, _, cp2, err := call.enter(c)
+	assert.Nil(t, err)
+	assert.Equal(t, b, caller)
+	call.exit(cp2, &Callee{Address: c, Name: c.String()})
+	assert.Equal(t, 1, len(call.Callees()))
+
+	//b失败，c的结果一起丢弃
+	call.exit(cp, nil)
+	assert.Equal(t, 0, len(call.Callees()))
+	assert.Equal(t, 1, len(call.stack))
+}
+
+func TestContractCall_MaxDepth(t *testing.T) {
+	call := NewContractCall(newTestContractAddr("0"), 0, func(addr common.Address) (*pb.ChaincodeID, error) {
+		return &pb.ChaincodeID{Name: addr.String()}, nil
+	})
+	for i := 1; i < MaxContractCallDepth; i++ {
+		_, _, _, err := call.enter(newTestContractAddr(string(rune('0' + i))))
+		assert.Nil(t, err)
+	}
+	_, _, _, err := call.enter(newTestContractAddr("x"))
+	assert.NotNil(t, err)
+}
+
+func TestGetUserContractCallee(t *testing.T) {
+	addr := newTestContractAddr("user")
+	payload, _ := proto.Marshal(&pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: addr.String()}})
+	_, callee, ok := getUserContractCallee(payload)
+	assert.True(t, ok)
+	assert.Equal(t, addr, callee)
+
+	payload, _ = proto.Marshal(&pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: "mycc"}})
+	_, _, ok = getUserContractCallee(payload)
+	assert.False(t, ok)
+}
@@ -0,0 +1,165 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"sync"
+
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"golang.org/x/net/context"
+)
+
+// GasMeterKey is used to attach the gas meter of user contract invocation
+const GasMeterKey key = "gasmeterkey"
+
+// Gas的计算只依赖合约发出的shim调用和返回的数据，与执行时间无关，所有陪审员的结果相同
+const (
+	GasShimCall       uint64 = 100  //每次shim调用
+	GasReadByte       uint64 = 1    //返回给合约的每字节数据
+	GasWriteByte      uint64 = 10   //写入状态的每字节数据
+	GasTokenOp        uint64 = 5000 //支付、定义和增发Token
+	GasInvokeContract uint64 = 1000 //调用其他合约
+)
+
+// GasMeter counts the resources used by a contract invocation. Once the limit
+// is exceeded every following shim call is rejected and the invocation fails.
+type GasMeter struct {
+	lock      sync.Mutex
+	limit     uint64
+	used      uint64
+	exhausted bool
+
+	Calls      uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	TokenOps   uint64
+}
+
+func NewGasMeter(limit uint64) *GasMeter {
+	return &GasMeter{limit: limit}
+}
+
+func getGasMeter(ctxt context.Context) *GasMeter {
+	if meter, ok := ctxt.Value(GasMeterKey).(*GasMeter); ok {
+		return meter
+	}
+	//系统合约不计算Gas
+	return nil
+}
+
+func (m *GasMeter) consume(gas uint64) error {
+	if m.exhausted {
+		return m.outOfGas()
+	}
+	if gas > m.limit-m.used {
+		m.used = m.limit
+		m.exhausted = true
+		return m.outOfGas()
+	}
+	m.used += gas
+	return nil
+}
+
+func (m *GasMeter) outOfGas() error {
+	return &modules.ContractOutOfGasError{Limit: m.limit}
+}
+
+// ChargeMessage charges the shim call sent by the contract
+func (m *GasMeter) ChargeMessage(msg *pb.ChaincodeMessage) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	gas := GasShimCall
+	m.Calls++
+	switch msg.Type {
+	case pb.ChaincodeMessage_PUT_STATE:
+		m.WriteBytes += uint64(len(msg.Payload))
+		gas += uint64(len(msg.Payload)) * GasWriteByte
+	case pb.ChaincodeMessage_PAY_OUT_TOKEN, pb.ChaincodeMessage_DEFINE_TOKEN, pb.ChaincodeMessage_SUPPLY_TOKEN:
+		m.TokenOps++
+		gas += GasTokenOp
+	case pb.ChaincodeMessage_INVOKE_CHAINCODE:
+		gas += GasInvokeContract
+	}
+	return m.consume(gas)
+}
+
+// ChargeResponse charges the data returned to the contract
+func (m *GasMeter) ChargeResponse(msg *pb.ChaincodeMessage) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.ReadBytes += uint64(len(msg.Payload))
+	return m.consume(uint64(len(msg.Payload)) * GasReadByte)
+}
+
+// Check returns the out of gas error if the limit is exceeded
+func (m *GasMeter) Check() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.exhausted {
+		return m.outOfGas()
+	}
+	return nil
+}
+
+func (m *GasMeter) Used() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.used
+}
+
+func (m *GasMeter) Limit() uint64 {
+	return m.limit
+}
+
+// 合约请求的数据，不包括合约执行结束的消息
+func isMeteredMessage(msg *pb.ChaincodeMessage) bool {
+	switch msg.Type {
+	case pb.ChaincodeMessage_REGISTER, pb.ChaincodeMessage_COMPLETED, pb.ChaincodeMessage_ERROR,
+		pb.ChaincodeMessage_KEEPALIVE:
+		return false
+	}
+	return true
+}
+
+// chargeGas 合约发出shim调用时计算Gas，Gas耗尽时直接返回错误
+func (handler *Handler) chargeGas(msg *pb.ChaincodeMessage) error {
+	if !isMeteredMessage(msg) {
+		return nil
+	}
+	txContext := handler.getTxContext(msg.ChannelId, msg.Txid)
+	if txContext == nil || txContext.gasMeter == nil {
+		return nil
+	}
+	return txContext.gasMeter.ChargeMessage(msg)
+}
+
+// chargeResponseGas 返回给合约的数据按字节计算Gas
+func (handler *Handler) chargeResponseGas(msg *pb.ChaincodeMessage) {
+	if msg.Type != pb.ChaincodeMessage_RESPONSE {
+		return
+	}
+	txContext := handler.getTxContext(msg.ChannelId, msg.Txid)
+	if txContext == nil || txContext.gasMeter == nil {
+		return
+	}
+	//超出的部分在合约执行结束后统一判定失败
+	txContext.gasMeter.ChargeResponse(msg)
+}
@@ -0,0 +1,63 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasMeter_Charge(t *testing.T) {
+	meter := NewGasMeter(10000)
+	put := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_PUT_STATE, Payload: make([]byte, 20)}
+	assert.Nil(t, meter.ChargeMessage(put))
+	assert.Equal(t, GasShimCall+20*GasWriteByte, meter.Used())
+
+	rsp := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: make([]byte, 50)}
+	assert.Nil(t, meter.ChargeResponse(rsp))
+	assert.Equal(t, GasShimCall+20*GasWriteByte+50*GasReadByte, meter.Used())
+	assert.Nil(t, meter.Check())
+
+	//Token操作超出上限
+	pay := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_PAY_OUT_TOKEN}
+	assert.Nil(t, meter.ChargeMessage(pay))
+	err := meter.ChargeMessage(pay)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.Contains(err.Error(), modules.ContractOutOfGasMsg))
+		assert.Equal(t, &modules.ContractOutOfGasError{Limit: 10000}, err)
+	}
+	assert.Equal(t, uint64(10000), meter.Used())
+	assert.Equal(t, uint64(2), meter.TokenOps)
+	assert.NotNil(t, meter.Check())
+
+	//耗尽后所有调用都失败
+	get := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_STATE}
+	assert.NotNil(t, meter.ChargeMessage(get))
+}
+
+func TestIsMeteredMessage(t *testing.T) {
+	assert.False(t, isMeteredMessage(&pb.ChaincodeMessage{Type: pb.ChaincodeMessage_COMPLETED}))
+	assert.False(t, isMeteredMessage(&pb.ChaincodeMessage{Type: pb.ChaincodeMessage_KEEPALIVE}))
+	assert.True(t, isMeteredMessage(&pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_STATE}))
+}
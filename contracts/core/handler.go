@@ -63,6 +63,12 @@ type transactionContext struct {
 	signedProp       *pb.SignedProposal
 	proposal         *pb.Proposal
 	responseNotifier chan *pb.ChaincodeMessage
+	//用户合约的Gas计量，系统合约为nil
+	gasMeter *GasMeter
+	//用户合约调用其他用户合约的调用栈，系统合约为nil
+	contractCall *ContractCall
+	//模拟执行时记录shim调用，正常执行时为nil
+	tracer *CallTracer
 
 	// tracks open iterators used for range queries
 	//queryIteratorMap    map[string]commonledger.ResultsIterator
@@ -90,6 +96,8 @@ type IAdapterJury interface {
 	AdapterFunRequest(reqId common.Hash, contractId common.Address, msgType uint32, consultContent []byte, myAnswer []byte) ([]byte, error)
 	AdapterFunResult(reqId common.Hash, contractId common.Address, msgType uint32, consultContent []byte, timeOut time.Duration) ([]byte, error)
 	GetLocalJuryAddrs() []common.Address
+	//用本地陪审员的私钥解密合约私有状态
+	DecryptPrivateState(ps *modules.PrivateState) ([]byte, error)
 }
 
 // Handler responsible for management of Peer's side of chaincode stream
@@ -150,6 +158,27 @@ func filterError(errFromFSMEvent error) error {
 	return nil
 }
 
+//getPrivateState 读取私有状态，本地保存的密文由陪审员解密，并检查与链上的Hash一致
+func (handler *Handler) getPrivateState(txContext *transactionContext, contractId []byte, ns string,
+	key string) ([]byte, error) {
+	value, ps, err := txContext.txsimulator.GetPrivateState(contractId, ns, key)
+	if err != nil || ps == nil {
+		return value, err
+	}
+	if handler.aJury == nil {
+		return nil, modules.ErrPrivateStateNotFound
+	}
+	plain, err := handler.aJury.DecryptPrivateState(ps)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err = ps.Open(plain)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 func isCollectionSet(collection string) bool {
 	//if collection == "" {
 	//	return false
@@ -215,6 +244,7 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{readystate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_GET_TIMESTAMP.String(), Src: []string{readystate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_GET_STATE_BY_PREFIX.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_GET_STATE_BY_RANGE.String(), Src: []string{readystate}, Dst: readystate},
 			//{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{readystate}, Dst: readystate},
 			//{Name: pb.ChaincodeMessage_QUERY_STATE_NEXT.String(), Src: []string{readystate}, Dst: readystate},
 			//{Name: pb.ChaincodeMessage_QUERY_STATE_CLOSE.String(), Src: []string{readystate}, Dst: readystate},
@@ -240,6 +270,7 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			"after_" + pb.ChaincodeMessage_GET_STATE.String():           func(e *fsm.Event) { v.afterGetState(e) },
 			"after_" + pb.ChaincodeMessage_GET_TIMESTAMP.String():       func(e *fsm.Event) { v.afterGetTimestamp(e) },
 			"after_" + pb.ChaincodeMessage_GET_STATE_BY_PREFIX.String(): func(e *fsm.Event) { v.afterGetStateByPrefix(e) },
+			"after_" + pb.ChaincodeMessage_GET_STATE_BY_RANGE.String():  func(e *fsm.Event) { v.afterGetStateByRange(e) },
 			//"after_" + pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String():       func(e *fsm.Event) { v.afterGetHistoryForKey(e, v.FSM.Current()) },
 			//"after_" + pb.ChaincodeMessage_QUERY_STATE_NEXT.String():          func(e *fsm.Event) { v.afterQueryStateNext(e, v.FSM.Current()) },
 			//"after_" + pb.ChaincodeMessage_QUERY_STATE_CLOSE.String():         func(e *fsm.Event) { v.afterQueryStateClose(e, v.FSM.Current()) },
@@ -424,6 +455,7 @@ func (handler *Handler) serialSend(msg *pb.ChaincodeMessage) error {
 	handler.serialLock.Lock()
 	defer handler.serialLock.Unlock()
 
+	handler.chargeResponseGas(msg)
 	var err error
 	if err = handler.ChatStream.Send(msg); err != nil {
 		err = errors.WithMessage(err, fmt.Sprintf("[%s]Error sending %s", shorttxid(msg.Txid), msg.Type.String()))
@@ -473,6 +505,9 @@ func (handler *Handler) createTxContext(ctxt context.Context, chainID string, tx
 	log.Debugf("createTxContext, create txCtxID[%s]", txCtxID)
 	//glh
 	txctx.txsimulator = getTxSimulator(ctxt)
+	txctx.gasMeter = getGasMeter(ctxt)
+	txctx.contractCall = GetContractCall(ctxt)
+	txctx.tracer = getCallTracer(ctxt)
 	//txctx.historyQueryExecutor = getHistoryQueryExecutor(ctxt)
 
 	return txctx, nil
@@ -584,6 +619,15 @@ func (handler *Handler) processStream() error {
 				// and it does not touch the state machine
 				continue
 			}
+			//Gas耗尽后拒绝合约的后续调用，合约以错误结束
+			errGas := handler.chargeGas(in)
+			handler.traceMessage(in, errGas)
+			if errGas != nil {
+				log.Debugf("[%s]Reject message %s, %s", shorttxid(in.Txid), in.Type.String(), errGas.Error())
+				handler.serialSendAsync(&pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR,
+					Payload: []byte(errGas.Error()), Txid: in.Txid, ChannelId: in.ChannelId}, nil)
+				continue
+			}
 		case nsInfo = <-handler.nextState:
 			in = nsInfo.msg
 			if in == nil {
@@ -834,6 +878,96 @@ func (handler *Handler) handleGetStateByPrefix(msg *pb.ChaincodeMessage) {
 	}()
 }
 
+// afterGetStateByRange handles a GET_STATE_BY_RANGE request from the chaincode.
+func (handler *Handler) afterGetStateByRange(e *fsm.Event) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(errors.New("received unexpected message type"))
+		return
+	}
+	log.Debugf("[%s]Received %s, invoking get state from ledger", shorttxid(msg.Txid),
+		pb.ChaincodeMessage_GET_STATE_BY_RANGE)
+
+	// Query ledger for state
+	handler.handleGetStateByRange(msg)
+}
+
+//每次范围查询最多返回的记录数，超过的部分由链码通过书签继续查询
+const maxStateByRangePageSize = 1000
+
+func (handler *Handler) handleGetStateByRange(msg *pb.ChaincodeMessage) {
+	go func() {
+		// Check if this is the unique state request from this chaincode txid
+		uniqueReq := handler.createTXIDEntry(msg.ChannelId, msg.Txid)
+		if !uniqueReq {
+			// Drop this request
+			log.Error("Another state request pending for this Txid. Cannot process.")
+			return
+		}
+
+		var serialSendMsg *pb.ChaincodeMessage
+		var txContext *transactionContext
+		txContext, serialSendMsg = handler.isValidTxSim(msg.ChannelId, msg.Txid,
+			"[%s]No ledger context for GetStateByRange. Sending %s", shorttxid(msg.Txid), pb.ChaincodeMessage_ERROR)
+
+		defer func() {
+			handler.deleteTXIDEntry(msg.ChannelId, msg.Txid)
+			log.Debugf("[%s]handleGetStateByRange serial send %s",
+				shorttxid(serialSendMsg.Txid), serialSendMsg.Type)
+			handler.serialSendAsync(serialSendMsg, nil)
+		}()
+
+		if txContext == nil {
+			return
+		}
+		getState := &pb.GetStateByRange{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, getState)
+		if unmarshalErr != nil {
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(unmarshalErr.Error()),
+				Txid: msg.Txid, ChannelId: msg.ChannelId}
+			return
+		}
+		pageSize := int(getState.PageSize)
+		if pageSize <= 0 || pageSize > maxStateByRangePageSize {
+			pageSize = maxStateByRangePageSize
+		}
+		chaincodeID := handler.getCCRootName()
+		log.Debugf("[%s] getting state for chaincode %s, range [%s,%s), page size %d, channel %s",
+			shorttxid(msg.Txid), chaincodeID, getState.StartKey, getState.EndKey, pageSize, txContext.chainID)
+
+		rows, bookmark, err := txContext.txsimulator.GetStatesByRange(msg.ContractId, chaincodeID,
+			getState.StartKey, getState.EndKey, pageSize)
+		if err != nil {
+			log.Errorf("[%s]Failed to get chaincode state by range(%s). Sending %s",
+				shorttxid(msg.Txid), err, pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(err.Error()),
+				Txid: msg.Txid, ChannelId: msg.ChannelId}
+			return
+		}
+		response := &pb.QueryResponse{Results: make([]*pb.QueryResultBytes, 0, len(rows)),
+			HasMore: bookmark != "", Id: bookmark}
+		for _, row := range rows {
+			data, err := json.Marshal(row)
+			if err != nil {
+				serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(err.Error()),
+					Txid: msg.Txid, ChannelId: msg.ChannelId}
+				return
+			}
+			response.Results = append(response.Results, &pb.QueryResultBytes{ResultBytes: data})
+		}
+		res, err := proto.Marshal(response)
+		if err != nil {
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(err.Error()),
+				Txid: msg.Txid, ChannelId: msg.ChannelId}
+			return
+		}
+		log.Debugf("[%s]Got %d states, has more:%t. Sending %s", shorttxid(msg.Txid), len(rows),
+			response.HasMore, pb.ChaincodeMessage_RESPONSE)
+		serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: res, Txid: msg.Txid,
+			ChannelId: msg.ChannelId}
+	}()
+}
+
 // afterGetState handles a GET_STATE request from the chaincode.
 func (handler *Handler) afterGetState(e *fsm.Event) {
 	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
@@ -1420,7 +1554,9 @@ func (handler *Handler) handleGetState(msg *pb.ChaincodeMessage) {
 
 		var res []byte
 		var err error
-		if isCollectionSet(getState.Collection) {
+		if getState.Collection == modules.PrivateStateCollection {
+			res, err = handler.getPrivateState(txContext, msg.ContractId, chaincodeID, getState.Key)
+		} else if isCollectionSet(getState.Collection) {
 			//glh
 			//res, err = txContext.txsimulator.GetPrivateData(chaincodeID, getState.Collection, getState.Key)
 		} else {
@@ -1634,7 +1770,9 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 					err = txContext.txsimulator.SetState(chaincodeID, putState.Key, putState.Value)
 				}
 			*/
-			if isCollectionSet(putState.Collection) {
+			if putState.Collection == modules.PrivateStateCollection {
+				err = txContext.txsimulator.SetPrivateState(putState.ContractId, chaincodeID, putState.Key, putState.Value)
+			} else if isCollectionSet(putState.Collection) {
 				//err = txContext.txsimulator.SetPrivateData(chaincodeID, putState.Collection, putState.Key, putState.Value)
 			} else {
 				err = txContext.txsimulator.SetState(putState.ContractId, chaincodeID, putState.Key, putState.Value)
@@ -1656,11 +1794,16 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 					err = txContext.txsimulator.DeleteState(chaincodeID, delState.Key)
 				}
 			*/
-			if isCollectionSet(delState.Collection) {
+			if delState.Collection == modules.PrivateStateCollection {
+				err = txContext.txsimulator.DeletePrivateState(delState.ContractId, chaincodeID, delState.Key)
+			} else if isCollectionSet(delState.Collection) {
 				//err = txContext.txsimulator.DeletePrivateData(chaincodeID, delState.Collection, delState.Key)
 			} else {
 				err = txContext.txsimulator.DeleteState(delState.ContractId, chaincodeID, delState.Key)
 			}
+		} else if spec, callee, ok := getUserContractCallee(msg.Payload); ok &&
+			msg.Type == pb.ChaincodeMessage_INVOKE_CHAINCODE {
+			res, err = handler.callUserContract(txContext, msg, spec, callee)
 		} else if msg.Type.String() == pb.ChaincodeMessage_INVOKE_CHAINCODE.String() {
 			log.Debugf("[%s] C-call-C", shorttxid(msg.Txid))
 			chaincodeSpec := &pb.ChaincodeSpec{}
@@ -0,0 +1,112 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"golang.org/x/net/context"
+)
+
+// CallTracerKey is used to attach the shim call tracer of a simulated invocation
+const CallTracerKey key = "calltracerkey"
+
+// CallTracer records the shim calls sent by the contracts of an invocation,
+// including the contracts called by it.
+type CallTracer struct {
+	lock  sync.Mutex
+	steps []*modules.ShimCallTrace
+}
+
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+func getCallTracer(ctxt context.Context) *CallTracer {
+	if tracer, ok := ctxt.Value(CallTracerKey).(*CallTracer); ok {
+		return tracer
+	}
+	return nil
+}
+
+func (t *CallTracer) record(contract string, msg *pb.ChaincodeMessage, gasUsed uint64, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	step := &modules.ShimCallTrace{Step: len(t.steps) + 1, Contract: contract, Type: msg.Type.String(),
+		Key: shimCallKey(msg), Size: len(msg.Payload), GasUsed: gasUsed}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.steps = append(t.steps, step)
+}
+
+// Steps returns the shim calls in the order they were sent
+func (t *CallTracer) Steps() []*modules.ShimCallTrace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	steps := make([]*modules.ShimCallTrace, len(t.steps))
+	copy(steps, t.steps)
+	return steps
+}
+
+// shimCallKey 读写状态的调用返回操作的Key
+func shimCallKey(msg *pb.ChaincodeMessage) string {
+	switch msg.Type {
+	case pb.ChaincodeMessage_GET_STATE:
+		req := &pb.GetState{}
+		if proto.Unmarshal(msg.Payload, req) == nil {
+			return req.Key
+		}
+	case pb.ChaincodeMessage_PUT_STATE:
+		req := &pb.PutState{}
+		if proto.Unmarshal(msg.Payload, req) == nil {
+			return req.Key
+		}
+	case pb.ChaincodeMessage_DEL_STATE:
+		req := &pb.DelState{}
+		if proto.Unmarshal(msg.Payload, req) == nil {
+			return req.Key
+		}
+	}
+	return ""
+}
+
+// traceMessage 模拟执行时记录合约发出的shim调用
+func (handler *Handler) traceMessage(msg *pb.ChaincodeMessage, err error) {
+	if !isMeteredMessage(msg) {
+		return
+	}
+	txContext := handler.getTxContext(msg.ChannelId, msg.Txid)
+	if txContext == nil || txContext.tracer == nil {
+		return
+	}
+	var gasUsed uint64
+	if txContext.gasMeter != nil {
+		gasUsed = txContext.gasMeter.Used()
+	}
+	contract := ""
+	if handler.ccInstance != nil {
+		contract = handler.ccInstance.ChaincodeName
+	}
+	txContext.tracer.record(contract, msg, gasUsed, err)
+}
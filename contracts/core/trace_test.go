@@ -0,0 +1,53 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallTracer_Record(t *testing.T) {
+	tracer := NewCallTracer()
+	put, _ := proto.Marshal(&pb.PutState{Key: "name", Value: []byte("value")})
+	tracer.record("contract1", &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_PUT_STATE, Payload: put}, 100, nil)
+	get, _ := proto.Marshal(&pb.GetState{Key: "balance"})
+	tracer.record("contract2", &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_STATE, Payload: get}, 300,
+		errors.New("out of gas"))
+	tracer.record("contract1", &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_PAY_OUT_TOKEN}, 300, nil)
+
+	steps := tracer.Steps()
+	assert.Equal(t, 3, len(steps))
+	assert.Equal(t, 1, steps[0].Step)
+	assert.Equal(t, "contract1", steps[0].Contract)
+	assert.Equal(t, pb.ChaincodeMessage_PUT_STATE.String(), steps[0].Type)
+	assert.Equal(t, "name", steps[0].Key)
+	assert.Equal(t, len(put), steps[0].Size)
+	assert.Equal(t, uint64(100), steps[0].GasUsed)
+	assert.Equal(t, "balance", steps[1].Key)
+	assert.Equal(t, "out of gas", steps[1].Error)
+	//没有Key的调用
+	assert.Equal(t, "", steps[2].Key)
+	assert.Equal(t, 3, steps[2].Step)
+}
@@ -203,7 +203,27 @@ func Deploy(rwM rwset.TxManager, idag dag.IDag, chainID string, templateId []byt
 //timeout:ms
 // ccName can be contract Id
 //func Invoke(chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration) (*peer.ContractInvokePayload, error) {
-func Invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration) (*md.ContractInvokeResult, error) {
+//gasLimit 用户合约可消耗的Gas上限，系统合约不计算Gas
+func Invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte, txid string, args [][]byte, timeout time.Duration, gasLimit uint64) (*md.ContractInvokeResult, error) {
+	return invoke(rwM, idag, chainID, deployId, txid, args, timeout, gasLimit, nil)
+}
+
+//Simulate 与Invoke的执行过程相同，trace为true时同时返回合约发出的每一次shim调用，执行出错时也返回已经记录的调用
+func Simulate(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte, txid string, args [][]byte,
+	timeout time.Duration, gasLimit uint64, trace bool) (*md.ContractInvokeResult, []*md.ShimCallTrace, error) {
+	var tracer *core.CallTracer
+	if trace {
+		tracer = core.NewCallTracer()
+	}
+	result, err := invoke(rwM, idag, chainID, deployId, txid, args, timeout, gasLimit, tracer)
+	if tracer == nil {
+		return result, nil, err
+	}
+	return result, tracer.Steps(), err
+}
+
+func invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte, txid string, args [][]byte,
+	timeout time.Duration, gasLimit uint64, tracer *core.CallTracer) (*md.ContractInvokeResult, error) {
 	log.Debugf("Invoke enter")
 	log.Info("Invoke enter", "chainID", chainID, "deployId", deployId, "txid", txid, "timeout", timeout)
 	defer log.Info("Invoke exit", "chainID", chainID, "deployId", deployId, "txid", txid, "timeout", timeout)
@@ -223,13 +243,13 @@ func Invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte,
 		contractName = cc.Name
 		contractVersion = cc.Version
 	} else {
-		contract, err := idag.GetContract(address.Bytes())
+		cid, err := getUserContractChaincodeId(idag, address)
 		if err != nil {
 			log.Debugf("Invoke, get chain code err:%s", err.Error())
 			return nil, err
 		}
-		contractName = contract.Name
-		contractVersion = contract.Version + ":" + contractcfg.GetConfig().ContractAddress
+		contractName = cid.Name
+		contractVersion = cid.Version
 	}
 	startTm := time.Now()
 	es := NewEndorserServer(mksupt)
@@ -248,11 +268,37 @@ func Invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte,
 		log.Errorf("signedEndorserProposa error[%v]", err)
 		return nil, err
 	}
-	rsp, unit, err := es.ProcessProposal(rwM, idag, deployId, context.Background(), sprop, prop, chainID, cid, timeout)
+	ctxt := context.Background()
+	if tracer != nil {
+		ctxt = context.WithValue(ctxt, core.CallTracerKey, tracer)
+	}
+	var meter *core.GasMeter
+	if !address.IsSystemContractAddress() {
+		meter = core.NewGasMeter(gasLimit)
+		ctxt = context.WithValue(ctxt, core.GasMeterKey, meter)
+		//用户合约可以调用其他用户合约
+		call := core.NewContractCall(address, timeout, func(addr common.Address) (*pb.ChaincodeID, error) {
+			return getUserContractChaincodeId(idag, addr)
+		})
+		ctxt = context.WithValue(ctxt, core.ContractCallKey, call)
+	}
+	rsp, unit, err := es.ProcessProposal(rwM, idag, deployId, ctxt, sprop, prop, chainID, cid, timeout)
+	if meter != nil {
+		//Gas耗尽时不论合约返回什么结果都按失败处理，保证所有陪审员的执行结果一致
+		if errGas := meter.Check(); errGas != nil {
+			log.Infof("Invoke, contract[%s] %s", contractName, errGas.Error())
+			return nil, errGas
+		}
+	}
 	if err != nil {
 		log.Infof("ProcessProposal error[%v]", err)
 		return nil, err
 	}
+	if meter != nil {
+		unit.GasUsed = meter.Used()
+		log.Debugf("Invoke, contract[%s] gas used:%d, shim calls:%d, read bytes:%d, write bytes:%d, token ops:%d",
+			contractName, unit.GasUsed, meter.Calls, meter.ReadBytes, meter.WriteBytes, meter.TokenOps)
+	}
 	if !address.IsSystemContractAddress() {
 		sizeRW, disk, isOver := removeConWhenOverDisk(contractName+":"+contractVersion, idag)
 		if isOver {
@@ -269,6 +315,16 @@ func Invoke(rwM rwset.TxManager, idag dag.IDag, chainID string, deployId []byte,
 	return unit, nil
 }
 
+//getUserContractChaincodeId 已部署用户合约的ChaincodeID
+func getUserContractChaincodeId(idag dag.IDag, address common.Address) (*pb.ChaincodeID, error) {
+	contract, err := idag.GetContract(address.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ChaincodeID{Name: contract.Name,
+		Version: contract.Version + ":" + contractcfg.GetConfig().ContractAddress}, nil
+}
+
 func Stop(rwM rwset.TxManager, idag dag.IDag, contractid []byte, chainID string, txid string, deleteImage bool, dontRmCon bool) (*md.ContractStopPayload, error) {
 	log.Info("Stop enter", "contractid", contractid, "chainID", chainID, "deployId", contractid, "txid", txid)
 	defer log.Info("Stop enter", "contractid", contractid, "chainID", chainID, "deployId", contractid, "txid", txid)
@@ -287,20 +343,25 @@ func Stop(rwM rwset.TxManager, idag dag.IDag, contractid []byte, chainID string,
 		return nil, err
 	}
 	contract.Version += ":" + contractcfg.GetConfig().ContractAddress
-	stopResult, err := StopByName(contractid, setChainId, txid, contract, deleteImage, dontRmCon)
+	//wasm合约在进程内运行，停止时需要合约的语言
+	language := ""
+	if tpl, err := idag.GetContractTpl(contract.TemplateId); err == nil {
+		language = tpl.Language
+	}
+	stopResult, err := StopByName(contractid, setChainId, txid, contract, language, deleteImage, dontRmCon)
 	if err != nil {
 		return nil, err
 	}
 	return stopResult, err
 }
 
-func StopByName(contractid []byte, chainID string, txid string, usercc *md.Contract, deleteImage bool, dontRmCon bool) (*md.ContractStopPayload, error) {
+func StopByName(contractid []byte, chainID string, txid string, usercc *md.Contract, language string, deleteImage bool, dontRmCon bool) (*md.ContractStopPayload, error) {
 	usrcc := &ucc.UserChaincode{
 		Name: usercc.Name,
 		//Path:     usercc.Path,
-		Version: usercc.Version,
-		Enabled: true,
-		//Language: usercc.Language,
+		Version:  usercc.Version,
+		Enabled:  true,
+		Language: language,
 	}
 	err := ucc.StopUserCC(contractid, chainID, usrcc, txid, deleteImage, dontRmCon)
 	if err != nil {
@@ -359,6 +420,117 @@ func RestartContainer(idag dag.IDag, chainID string, addr common.Address, txId s
 	return contract.ContractId, err
 }
 
+//Upgrade 用新模板启动合约容器，在新模板上执行Migrate迁移已有状态，成功后停止旧模板的容器
+//合约的Name（容器名）不变，新旧容器通过模板的版本区分，所以新模板的版本必须与当前版本不同
+func Upgrade(rwM rwset.TxManager, idag dag.IDag, chainID string, contractId []byte, templateId []byte, txid string,
+	args [][]byte, timeout time.Duration) (*md.ContractUpgradePayload, error) {
+	log.Info("Upgrade enter", "chainID", chainID, "contractId", contractId, "templateId", templateId, "txid", txid)
+	defer log.Info("Upgrade exit", "chainID", chainID, "contractId", contractId, "txid", txid)
+	setTimeOut := time.Duration(30) * time.Second
+	if timeout > 0 {
+		setTimeOut = timeout
+	}
+	address := common.NewAddress(contractId, common.ContractHash)
+	if address.IsSystemContractAddress() {
+		return nil, errors.New("system contract can not be upgraded")
+	}
+	contract, err := idag.GetContract(address.Bytes())
+	if err != nil {
+		log.Debugf("Upgrade, get contract err:%s", err.Error())
+		return nil, err
+	}
+	if contract.Status != 1 {
+		return nil, fmt.Errorf("contract[%s] is stopped", address.String())
+	}
+	if bytes.Equal(contract.TemplateId, templateId) {
+		return nil, errors.New("contract already use the template")
+	}
+	templateCC, chaincodeData, err := ucc.RecoverChainCodeFromDb(idag, templateId)
+	if err != nil {
+		log.Error("Upgrade", "chainid:", chainID, "templateId:", templateId, "RecoverChainCodeFromDb err", err)
+		return nil, err
+	}
+	if templateCC.Version == contract.Version {
+		return nil, fmt.Errorf("the version of new template is the same as contract version %s", contract.Version)
+	}
+	oldLanguage := ""
+	if tpl, err := idag.GetContractTpl(contract.TemplateId); err == nil {
+		oldLanguage = tpl.Language
+	}
+
+	mksupt := &SupportImpl{}
+	txsim, err := mksupt.GetTxSimulator(rwM, idag, chainID, txid)
+	if err != nil {
+		log.Error("getTxSimulator err:", "error", err)
+		return nil, errors.WithMessage(err, "GetTxSimulator error")
+	}
+	newVersion := templateCC.Version + ":" + contractcfg.GetConfig().ContractAddress
+	//参数为空时容器只启动不调用Init
+	spec := &pb.ChaincodeSpec{
+		Type: pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value[templateCC.Language]),
+		Input: &pb.ChaincodeInput{
+			Args: [][]byte{},
+		},
+		ChaincodeId: &pb.ChaincodeID{
+			Name:    contract.Name,
+			Path:    templateCC.Path,
+			Version: newVersion,
+		},
+	}
+	cp := idag.GetChainParameters()
+	spec.CpuQuota = cp.UccCpuQuota
+	spec.CpuShare = cp.UccCpuShares
+	spec.Memory = cp.UccMemory
+	err = ucc.DeployUserCC(contractId, chaincodeData, spec, chainID, txid, txsim, setTimeOut)
+	if err != nil {
+		log.Error("Upgrade, deployUserCC err:", "error", err)
+		return nil, errors.WithMessage(err, "Upgrade fail")
+	}
+	newCC := &md.Contract{Name: contract.Name, Version: newVersion}
+	payload, err := migrate(rwM, idag, chainID, contractId, newCC.Name, newVersion, txid, args, setTimeOut)
+	if err != nil {
+		if _, errStop := StopByName(contractId, chainID, txid, newCC, templateCC.Language, false, false); errStop != nil {
+			log.Warnf("Upgrade, stop the container of new template err:%s", errStop.Error())
+		}
+		return nil, err
+	}
+	payload.TemplateId = templateId
+	contract.Version += ":" + contractcfg.GetConfig().ContractAddress
+	if _, err := StopByName(contractId, chainID, txid, contract, oldLanguage, false, false); err != nil {
+		log.Warnf("Upgrade, stop the container of old template err:%s", err.Error())
+	}
+	return payload, nil
+}
+
+//migrate 在新模板上调用迁移函数，迁移不能转出Token
+func migrate(rwM rwset.TxManager, idag dag.IDag, chainID string, contractId []byte, name, version string,
+	txid string, args [][]byte, timeout time.Duration) (*md.ContractUpgradePayload, error) {
+	cid := &pb.ChaincodeID{Name: name, Version: version}
+	spec := &pb.ChaincodeSpec{ChaincodeId: cid, Input: &pb.ChaincodeInput{Args: args}}
+	sprop, prop, err := SignedEndorserProposa(chainID, txid, spec, []byte("palletone"), []byte("msg1"))
+	if err != nil {
+		log.Errorf("signedEndorserProposa error[%v]", err)
+		return nil, err
+	}
+	meter := core.NewGasMeter(md.DefaultContractGasLimit)
+	ctxt := context.WithValue(context.Background(), core.GasMeterKey, meter)
+	es := NewEndorserServer(&SupportImpl{})
+	_, unit, err := es.ProcessProposal(rwM, idag, contractId, ctxt, sprop, prop, chainID, cid, timeout)
+	if errGas := meter.Check(); errGas != nil {
+		return nil, errGas
+	}
+	if err != nil {
+		log.Infof("Upgrade, migrate error[%v]", err)
+		return nil, err
+	}
+	if len(unit.TokenPayOut) > 0 || len(unit.TokenSupply) > 0 || unit.TokenDefine != nil {
+		return nil, errors.New("contract migrate can not pay out, supply or define token")
+	}
+	payload := md.NewContractUpgradePayload(contractId, nil, unit.ReadSet, unit.WriteSet, md.ContractError{})
+	payload.RangeReadSet = unit.RangeReadSet
+	return payload, nil
+}
+
 //  调用的时候，若调用完发现磁盘使用超过系统上限，则kill掉并移除
 func removeConWhenOverDisk(containerName string, dag dag.IDag) (sizeRW int64, disk int64, isOver bool) {
 	log.Debugf("start KillAndRmWhenOver")
@@ -24,6 +24,7 @@ import (
 	"golang.org/x/net/context"
 	"time"
 
+	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/contracts/core"
 	"github.com/palletone/go-palletone/contracts/shim"
@@ -231,7 +232,7 @@ func (e *Endorser) ProcessProposal(rwM rwset.TxManager, idag dag.IDag, deployId
 	}
 
 	//1 -- simulate
-	res, _, _, err := e.simulateProposal(deployId, ctx, chainID, txid, signedProp, prop, cid, txsim, tmout)
+	res, _, ccevent, err := e.simulateProposal(deployId, ctx, chainID, txid, signedProp, prop, cid, txsim, tmout)
 	log.Debugf("simulate proposal")
 	if err != nil {
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, nil, err
@@ -263,6 +264,16 @@ func (e *Endorser) ProcessProposal(rwM rwset.TxManager, idag dag.IDag, deployId
 		log.Errorf("chainID[%s] converRwTxResult2DagUnit failed", chainID)
 		return nil, nil, errors.New("Conver RwSet to dag unit fail")
 	}
+	if ccevent != nil && ccevent.EventName != "" {
+		unit.Events = append(unit.Events, &modules.ContractEvent{ContractId: common.CopyBytes(deployId),
+			Name: ccevent.EventName, Payload: ccevent.Payload})
+	}
+	if call := core.GetContractCall(ctx); call != nil {
+		if err = mergeCalleeResults(txsim, unit, call.Callees()); err != nil {
+			log.Errorf("chainID[%s] mergeCalleeResults failed:%s", chainID, err.Error())
+			return nil, nil, err
+		}
+	}
 
 	pResp.Response.Payload = res.Payload
 	unit.Payload = res.Payload
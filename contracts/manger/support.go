@@ -20,6 +20,7 @@
 package manger
 
 import (
+	"fmt"
 	"time"
 
 	"golang.org/x/net/context"
@@ -110,15 +111,20 @@ func RwTxResult2DagInvokeUnit(tx rwset.TxSimulator, txid string, nm string, depl
 	}
 	tokenDefine, _ := tx.GetTokenDefineData(nm)
 	tokenSupply, _ := tx.GetTokenSupplyData(nm)
+	rangeRd, err := tx.GetRangeRwData(nm)
+	if err != nil {
+		return nil, err
+	}
 	log.Debugf("txid=%s, nm=%s, rd=%#v, wt=%v", txid, nm, rd, wt)
 	invoke := &md.ContractInvokeResult{
-		ContractId:  deployId,
-		Args:        args,
-		ReadSet:     make([]md.ContractReadSet, 0),
-		WriteSet:    make([]md.ContractWriteSet, 0),
-		TokenPayOut: tokenPay,
-		TokenDefine: tokenDefine,
-		TokenSupply: tokenSupply,
+		ContractId:   deployId,
+		Args:         args,
+		ReadSet:      make([]md.ContractReadSet, 0),
+		WriteSet:     make([]md.ContractWriteSet, 0),
+		RangeReadSet: rangeRd,
+		TokenPayOut:  tokenPay,
+		TokenDefine:  tokenDefine,
+		TokenSupply:  tokenSupply,
 	}
 
 	for idx, val := range rd {
@@ -131,19 +137,96 @@ func RwTxResult2DagInvokeUnit(tx rwset.TxSimulator, txid string, nm string, depl
 		log.Debugf("ReadSet: idx[%v], fun[%s], key[%s], val[%v]", idx, args[2], val.GetKey(), val.GetVersion())
 	}
 	for idx, val := range wt {
-		ws := md.ContractWriteSet{
-			Key:        val.GetKey(),
-			Value:      val.GetValue(),
-			IsDelete:   val.GetIsDelete(),
-			ContractId: val.ContractId,
+		ws, private, err := convertWriteSet(val)
+		if err != nil {
+			return nil, err
 		}
 		invoke.WriteSet = append(invoke.WriteSet, ws)
-		log.Debugf("WriteSet: idx[%d], fun[%s], key[%s], val[%v], delete[%t]", idx, args[2], val.GetKey(), val.GetValue(), val.GetIsDelete())
+		if private != nil {
+			invoke.PrivateWriteSet = append(invoke.PrivateWriteSet, private)
+		}
+		log.Debugf("WriteSet: idx[%d], fun[%s], key[%s], val[%v], delete[%t]", idx, args[2], ws.Key, ws.Value, ws.IsDelete)
 	}
 
 	return invoke, nil
 }
 
+//convertWriteSet 私有状态上链时只保留明文加随机盐的Hash，明文和盐单独返回
+func convertWriteSet(val *rwset.KVWrite) (md.ContractWriteSet, *md.PrivateWrite, error) {
+	ws := md.ContractWriteSet{
+		Key:        val.GetKey(),
+		Value:      val.GetValue(),
+		IsDelete:   val.GetIsDelete(),
+		ContractId: val.ContractId,
+	}
+	if !val.GetIsPrivate() {
+		return ws, nil, nil
+	}
+	private := &md.PrivateWrite{ContractId: ws.ContractId, Key: ws.Key, Value: ws.Value, IsDelete: ws.IsDelete}
+	ws.IsPrivate = true
+	if !ws.IsDelete {
+		salt, err := md.NewPrivateStateSalt()
+		if err != nil {
+			return ws, nil, err
+		}
+		private.Salt = salt
+		ws.Value = private.Hash()
+	}
+	return ws, private, nil
+}
+
+//mergeCalleeResults 将被调用合约的读写集和Token操作合并到调用结果中，被调用合约的付款从其合约地址付出
+func mergeCalleeResults(tx rwset.TxSimulator, invoke *md.ContractInvokeResult, callees []*chaincode.Callee) error {
+	for _, callee := range callees {
+		rd, wt, err := tx.GetRwData(callee.Name)
+		if err != nil {
+			return err
+		}
+		for _, val := range rd {
+			invoke.ReadSet = append(invoke.ReadSet, md.ContractReadSet{Key: val.GetKey(), Version: val.GetVersion(),
+				ContractId: val.ContractId})
+		}
+		for _, val := range wt {
+			ws, private, err := convertWriteSet(val)
+			if err != nil {
+				return err
+			}
+			invoke.WriteSet = append(invoke.WriteSet, ws)
+			if private != nil {
+				invoke.PrivateWriteSet = append(invoke.PrivateWriteSet, private)
+			}
+		}
+		rangeRd, err := tx.GetRangeRwData(callee.Name)
+		if err != nil {
+			return err
+		}
+		invoke.RangeReadSet = append(invoke.RangeReadSet, rangeRd...)
+		tokenPay, err := tx.GetPayOutData(callee.Name)
+		if err != nil {
+			return err
+		}
+		for _, pay := range tokenPay {
+			payout := *pay
+			payout.PayFrom = callee.Address
+			invoke.TokenPayOut = append(invoke.TokenPayOut, &payout)
+		}
+		tokenSupply, _ := tx.GetTokenSupplyData(callee.Name)
+		invoke.TokenSupply = append(invoke.TokenSupply, tokenSupply...)
+		if tokenDefine, _ := tx.GetTokenDefineData(callee.Name); tokenDefine != nil {
+			if invoke.TokenDefine != nil {
+				return fmt.Errorf("contract %s define token more than once in one invocation", callee.Address.String())
+			}
+			invoke.TokenDefine = tokenDefine
+		}
+		if callee.Event != nil && callee.Event.EventName != "" {
+			invoke.Events = append(invoke.Events, &md.ContractEvent{ContractId: callee.Address.Bytes(),
+				Name: callee.Event.EventName, Payload: callee.Event.Payload})
+		}
+		invoke.Callees = append(invoke.Callees, callee.Address)
+	}
+	return nil
+}
+
 //func RwTxResult2DagDeployUnit(tx rwset.TxSimulator, txid string, nm string, fun []byte) (*pb.ContractDeployPayload, error) {
 func RwTxResult2DagDeployUnit(tx rwset.TxSimulator, templateId []byte, nm string, contractId []byte, args [][]byte, timeout time.Duration) (*md.ContractDeployPayload, error) {
 	log.Debug("RwTxResult2DagDeployUnit enter")
@@ -170,6 +253,9 @@ func RwTxResult2DagDeployUnit(tx rwset.TxSimulator, templateId []byte, nm string
 		log.Debug("RwTxResult2DagDeployUnit", "ReadSet: idx", idx, "args", args, "key", val.GetKey(), "val", *val.GetVersion())
 	}
 	for idx, val := range wt {
+		if val.GetIsPrivate() {
+			return nil, fmt.Errorf("private state[%s] can not be written when deploy", val.GetKey())
+		}
 		ws := md.ContractWriteSet{
 			Key:        val.GetKey(),
 			Value:      val.GetValue(),
@@ -30,6 +30,7 @@ import (
 	"github.com/palletone/go-palletone/contracts/platforms/golang"
 	"github.com/palletone/go-palletone/contracts/platforms/java"
 	"github.com/palletone/go-palletone/contracts/platforms/node"
+	"github.com/palletone/go-palletone/contracts/platforms/wasm"
 	"github.com/palletone/go-palletone/core/vmContractPub/metadata"
 	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
 	cutil "github.com/palletone/go-palletone/vm/common"
@@ -70,6 +71,8 @@ func Find(chaincodeType pb.ChaincodeSpec_Type) (Platform, error) {
 		return &java.Platform{}, nil
 	case pb.ChaincodeSpec_NODE:
 		return &node.Platform{}, nil
+	case pb.ChaincodeSpec_WASM:
+		return &wasm.Platform{}, nil
 	default:
 		return nil, fmt.Errorf("Unknown chaincodeType: %s", chaincodeType)
 	}
@@ -0,0 +1,261 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/contracts/shim"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/vm/wasm"
+)
+
+const (
+	//每次Init/Invoke最多执行的步数
+	DefaultStepLimit = 10000000
+
+	//宿主函数的基础步数和每字节步数
+	hostCallSteps = 100
+	hostByteSteps = 1
+	stateOpSteps  = 1000
+)
+
+// Chaincode runs a wasm module as shim.Chaincode. The module exports
+// "invoke" and optionally "init" and "migrate", all with the type
+// [] -> [i32], zero means success. The host functions are imported from
+// module "env".
+type Chaincode struct {
+	module    *wasm.Module
+	stepLimit uint64
+}
+
+func NewChaincode(code []byte) (*Chaincode, error) {
+	m, err := wasm.DecodeModule(code)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"init", "invoke", "migrate"} {
+		ft, ok := m.ExportedFunc(name)
+		if !ok {
+			if name != "invoke" {
+				continue
+			}
+			return nil, fmt.Errorf("wasm chaincode must export function %s", name)
+		}
+		if len(ft.Params) != 0 || len(ft.Results) != 1 || ft.Results[0] != wasm.ValueTypeI32 {
+			return nil, fmt.Errorf("wasm chaincode function %s must be []->[i32]", name)
+		}
+	}
+	cc := &Chaincode{module: m, stepLimit: DefaultStepLimit}
+	//检查导入的宿主函数都存在
+	if _, err = wasm.Instantiate(m, cc.imports(&callContext{}), cc.stepLimit); err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+func (cc *Chaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	if _, ok := cc.module.ExportedFunc("init"); !ok {
+		return shim.Success(nil)
+	}
+	return cc.call(stub, "init")
+}
+
+func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return cc.call(stub, "invoke")
+}
+
+//合约升级时调用模板导出的migrate函数，没有导出时保持原有状态
+func (cc *Chaincode) Migrate(stub shim.ChaincodeStubInterface) pb.Response {
+	if _, ok := cc.module.ExportedFunc("migrate"); !ok {
+		return shim.Success(nil)
+	}
+	return cc.call(stub, "migrate")
+}
+
+// 每次调用都创建新的实例，内存和全局变量不会在交易之间共享
+func (cc *Chaincode) call(stub shim.ChaincodeStubInterface, name string) pb.Response {
+	ctx := &callContext{stub: stub}
+	vm, err := wasm.Instantiate(cc.module, cc.imports(ctx), cc.stepLimit)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	code, err := vm.Invoke(name)
+	log.Debugf("wasm chaincode[%s] tx[%s] steps:%d", name, stub.GetTxID(), vm.Steps())
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if code != 0 {
+		if ctx.errMsg == "" {
+			ctx.errMsg = fmt.Sprintf("wasm chaincode %s returned %d", name, int32(code))
+		}
+		return shim.Error(ctx.errMsg)
+	}
+	return shim.Success(ctx.result)
+}
+
+type callContext struct {
+	stub   shim.ChaincodeStubInterface
+	result []byte
+	errMsg string
+}
+
+func fn(params int, hasResult bool) wasm.FuncType {
+	ft := wasm.FuncType{Params: make([]wasm.ValueType, params)}
+	for i := range ft.Params {
+		ft.Params[i] = wasm.ValueTypeI32
+	}
+	if hasResult {
+		ft.Results = []wasm.ValueType{wasm.ValueTypeI32}
+	}
+	return ft
+}
+
+// 读取内存并按字节计费
+func readBytes(vm *wasm.VM, ptr, size uint64) ([]byte, error) {
+	if err := vm.UseSteps(uint64(uint32(size)) * hostByteSteps); err != nil {
+		return nil, err
+	}
+	return vm.ReadMemory(uint32(ptr), uint32(size))
+}
+
+// 写入不超过cap的数据，返回实际长度，数据超过cap时返回-1
+func writeBytes(vm *wasm.VM, ptr, capacity uint64, data []byte) (uint64, error) {
+	if uint64(len(data)) > uint64(uint32(capacity)) {
+		return uint64(uint32(0xffffffff)), nil
+	}
+	if err := vm.UseSteps(uint64(len(data)) * hostByteSteps); err != nil {
+		return 0, err
+	}
+	if err := vm.WriteMemory(uint32(ptr), data); err != nil {
+		return 0, err
+	}
+	return uint64(len(data)), nil
+}
+
+func (cc *Chaincode) imports(ctx *callContext) map[string]*wasm.HostFunction {
+	host := func(params int, hasResult bool, steps uint64,
+		f func(vm *wasm.VM, args []uint64) (uint64, error)) *wasm.HostFunction {
+		return &wasm.HostFunction{
+			Type: fn(params, hasResult),
+			Fn: func(vm *wasm.VM, args []uint64) (uint64, error) {
+				if err := vm.UseSteps(steps); err != nil {
+					return 0, err
+				}
+				return f(vm, args)
+			},
+		}
+	}
+	return map[string]*wasm.HostFunction{
+		//参数个数，第一个参数为函数名
+		"env.args_count": host(0, true, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			return uint64(len(ctx.stub.GetArgs())), nil
+		}),
+		//arg(idx, ptr, cap) i32
+		"env.arg": host(3, true, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			all := ctx.stub.GetArgs()
+			if args[0] >= uint64(len(all)) {
+				return uint64(uint32(0xffffffff)), nil
+			}
+			return writeBytes(vm, args[1], args[2], all[args[0]])
+		}),
+		//get_state(kptr, klen, vptr, vcap) i32，状态不存在时返回-1
+		"env.get_state": host(4, true, stateOpSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			key, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			value, err := ctx.stub.GetState(string(key))
+			if err != nil || value == nil {
+				return uint64(uint32(0xffffffff)), nil
+			}
+			return writeBytes(vm, args[2], args[3], value)
+		}),
+		//put_state(kptr, klen, vptr, vlen) i32
+		"env.put_state": host(4, true, stateOpSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			key, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			value, err := readBytes(vm, args[2], args[3])
+			if err != nil {
+				return 0, err
+			}
+			if err = ctx.stub.PutState(string(key), value); err != nil {
+				ctx.errMsg = err.Error()
+				return uint64(uint32(0xffffffff)), nil
+			}
+			return 0, nil
+		}),
+		//del_state(kptr, klen) i32
+		"env.del_state": host(2, true, stateOpSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			key, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			if err = ctx.stub.DelState(string(key)); err != nil {
+				ctx.errMsg = err.Error()
+				return uint64(uint32(0xffffffff)), nil
+			}
+			return 0, nil
+		}),
+		//tx_id(ptr, cap) i32
+		"env.tx_id": host(2, true, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			return writeBytes(vm, args[0], args[1], []byte(ctx.stub.GetTxID()))
+		}),
+		//invoke_address(ptr, cap) i32
+		"env.invoke_address": host(2, true, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			addr, err := ctx.stub.GetInvokeAddress()
+			if err != nil {
+				ctx.errMsg = err.Error()
+				return uint64(uint32(0xffffffff)), nil
+			}
+			return writeBytes(vm, args[0], args[1], []byte(addr.String()))
+		}),
+		//set_result(ptr, len)
+		"env.set_result": host(2, false, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			data, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			ctx.result = data
+			return 0, nil
+		}),
+		//set_error(ptr, len)
+		"env.set_error": host(2, false, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			data, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			ctx.errMsg = string(data)
+			return 0, nil
+		}),
+		//log(ptr, len)
+		"env.log": host(2, false, hostCallSteps, func(vm *wasm.VM, args []uint64) (uint64, error) {
+			data, err := readBytes(vm, args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			log.Debugf("wasm chaincode log: %s", string(data))
+			return 0, nil
+		}),
+	}
+}
@@ -0,0 +1,104 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/stretchr/testify/assert"
+)
+
+func section(id byte, content ...byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+// 计数器合约，每次调用将状态counter加1并返回新值
+// (import "env" "get_state" (func (param i32 i32 i32 i32) (result i32)))
+// (import "env" "put_state" (func (param i32 i32 i32 i32) (result i32)))
+// (import "env" "set_result" (func (param i32 i32)))
+// (memory 1) (data (i32.const 0) "counter")
+// (func (export "invoke") (result i32) ...)
+var counterContract = func() []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, section(1, 3,
+		0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7f,
+		0x60, 2, 0x7f, 0x7f, 0,
+		0x60, 0, 1, 0x7f)...)
+	code = append(code, section(2, 3,
+		3, 'e', 'n', 'v', 9, 'g', 'e', 't', '_', 's', 't', 'a', 't', 'e', 0, 0,
+		3, 'e', 'n', 'v', 9, 'p', 'u', 't', '_', 's', 't', 'a', 't', 'e', 0, 0,
+		3, 'e', 'n', 'v', 10, 's', 'e', 't', '_', 'r', 'e', 's', 'u', 'l', 't', 0, 1)...)
+	code = append(code, section(3, 1, 2)...)
+	code = append(code, section(5, 1, 0, 1)...)
+	code = append(code, section(7, 1, 6, 'i', 'n', 'v', 'o', 'k', 'e', 0, 3)...)
+	body := []byte{0,
+		0x41, 0, 0x41, 7, 0x41, 16, 0x41, 8, 0x10, 0, 0x1a, //get_state("counter", 16, 8)
+		0x41, 16, 0x41, 16, 0x29, 3, 0, 0x42, 1, 0x7c, 0x37, 3, 0, //mem[16] += 1
+		0x41, 0, 0x41, 7, 0x41, 16, 0x41, 8, 0x10, 1, 0x1a, //put_state("counter", mem[16:24])
+		0x41, 16, 0x41, 8, 0x10, 2, //set_result(mem[16:24])
+		0x41, 0, 0x0b}
+	code = append(code, section(10, append([]byte{1, byte(len(body))}, body...)...)...)
+	code = append(code, section(11, 1, 0, 0x41, 0, 0x0b, 7, 'c', 'o', 'u', 'n', 't', 'e', 'r')...)
+	return code
+}()
+
+func TestChaincode_Counter(t *testing.T) {
+	cc, err := NewChaincode(counterContract)
+	if !assert.Nil(t, err) {
+		return
+	}
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stub := shim.NewMockChaincodeStubInterface(mockCtrl)
+	stub.EXPECT().GetTxID().Return("tx").AnyTimes()
+
+	state := map[string][]byte{}
+	stub.EXPECT().GetState(gomock.Any()).DoAndReturn(func(key string) ([]byte, error) {
+		return state[key], nil
+	}).AnyTimes()
+	stub.EXPECT().PutState(gomock.Any(), gomock.Any()).DoAndReturn(func(key string, value []byte) error {
+		state[key] = value
+		return nil
+	}).AnyTimes()
+
+	//没有导出init
+	assert.Equal(t, int32(shim.OK), cc.Init(stub).Status)
+	for i := uint64(1); i <= 3; i++ {
+		rsp := cc.Invoke(stub)
+		assert.Equal(t, int32(shim.OK), rsp.Status)
+		assert.Equal(t, i, binary.LittleEndian.Uint64(rsp.Payload))
+	}
+	assert.Equal(t, uint64(3), binary.LittleEndian.Uint64(state["counter"]))
+
+	//步数不足时调用失败
+	cc.stepLimit = 10
+	assert.Equal(t, int32(shim.ERROR), cc.Invoke(stub).Status)
+}
+
+func TestNewChaincode_Invalid(t *testing.T) {
+	_, err := NewChaincode([]byte("not wasm"))
+	assert.NotNil(t, err)
+	//没有导出invoke
+	_, err = NewChaincode([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,81 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/vm/wasm"
+)
+
+// wasm合约不需要容器，在节点进程内由解释器执行
+var errNoContainer = errors.New("wasm chaincode runs in process, no container is needed")
+
+// Platform for WebAssembly chaincodes, the code package is the wasm bytecode
+type Platform struct {
+}
+
+// ValidateSpec validates the wasm chaincode specs
+func (wasmPlatform *Platform) ValidateSpec(spec *pb.ChaincodeSpec) error {
+	if spec.ChaincodeId == nil || spec.ChaincodeId.Path == "" {
+		return errors.New("wasm chaincode path is empty")
+	}
+	return nil
+}
+
+func (wasmPlatform *Platform) ValidateDeploymentSpec(cds *pb.ChaincodeDeploymentSpec) error {
+	_, err := wasm.DecodeModule(cds.CodePackage)
+	return err
+}
+
+// GetChainCodePayload reads the wasm file at the chaincode path and checks it
+func (wasmPlatform *Platform) GetChainCodePayload(spec *pb.ChaincodeSpec) ([]byte, error) {
+	if err := wasmPlatform.ValidateSpec(spec); err != nil {
+		return nil, err
+	}
+	code, err := ioutil.ReadFile(spec.ChaincodeId.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read wasm file error: %s", err.Error())
+	}
+	if _, err = NewChaincode(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (wasmPlatform *Platform) GetDeploymentPayload(spec *pb.ChaincodeSpec) ([]byte, error) {
+	return wasmPlatform.GetChainCodePayload(spec)
+}
+
+func (wasmPlatform *Platform) GenerateDockerfile(cds *pb.ChaincodeDeploymentSpec) (string, error) {
+	return "", errNoContainer
+}
+
+func (wasmPlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec, tw *tar.Writer) error {
+	return errNoContainer
+}
+
+func (wasmPlatform *Platform) GetPlatformEnvPath(spec *pb.ChaincodeSpec) (string, error) {
+	return "", nil
+}
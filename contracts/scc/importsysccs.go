@@ -25,6 +25,7 @@ import (
 	"github.com/palletone/go-palletone/contracts/syscontract/digitalidcc"
 	"github.com/palletone/go-palletone/contracts/syscontract/exchangecc"
 	"github.com/palletone/go-palletone/contracts/syscontract/partitioncc"
+	"github.com/palletone/go-palletone/contracts/syscontract/schedulercc"
 	prc20v1 "github.com/palletone/go-palletone/contracts/syscontract/prc20/v1"
 	prc20v2 "github.com/palletone/go-palletone/contracts/syscontract/prc20/v2"
 
@@ -202,6 +203,15 @@ var systemChaincodes = []*SystemChaincode{
 		InitArgs:  [][]byte{},
 		Chaincode: &exchangecc.ExchangeMgr{},
 	},
+	{
+		Id:        syscontract.SchedulerContractAddress.Bytes(),
+		Enabled:   true,
+		Name:      "scheduler_sycc",
+		Path:      "./SchedulerContractAddress",
+		Version:   "ptn001",
+		InitArgs:  [][]byte{},
+		Chaincode: &schedulercc.SchedulerMgr{},
+	},
 	//TODO add other system chaincodes ...
 }
 
@@ -45,6 +45,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Logger for the shim package.
@@ -56,13 +57,13 @@ var cert string
 var GlobalStateContractId = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 var ERROR_ONLY_SYS_CONTRACT = errors.New("Only system contract can call this function.")
 
-//const (
-//
-//	minUnicodeRuneValue   = 0            //U+0000
-//	maxUnicodeRuneValue   = utf8.MaxRune //U+10FFFF - maximum (and unallocated) code point
-//	compositeKeyNamespace = "\x00"
-//	emptyKeySubstitute    = "\x01"
-//)
+const (
+	minUnicodeRuneValue   = '\x00'       //U+0000
+	maxUnicodeRuneValue   = utf8.MaxRune //U+10FFFF - maximum (and unallocated) code point
+	compositeKeyNamespace = "\x00"
+	//不分页的范围查询每次从节点获取的记录数
+	defaultRangeQueryPageSize = 100
+)
 
 // ChaincodeStub is an object passed to chaincode for shim side handling of
 // APIs.
@@ -437,6 +438,25 @@ func (stub *ChaincodeStub) PutGlobalState(key string, value []byte) error {
 
 }
 
+// GetPrivateState documentation can be found in interfaces.go
+func (stub *ChaincodeStub) GetPrivateState(key string) ([]byte, error) {
+	return stub.handler.handleGetState(modules.PrivateStateCollection, key, stub.ContractId, stub.ChannelId,
+		stub.TxID)
+}
+
+// PutPrivateState documentation can be found in interfaces.go
+func (stub *ChaincodeStub) PutPrivateState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	return stub.handler.handlePutState(modules.PrivateStateCollection, nil, key, value, stub.ChannelId, stub.TxID)
+}
+
+// DelPrivateState documentation can be found in interfaces.go
+func (stub *ChaincodeStub) DelPrivateState(key string) error {
+	return stub.handler.handleDelState(modules.PrivateStateCollection, nil, key, stub.ChannelId, stub.TxID)
+}
+
 // DelState documentation can be found in interfaces.go
 func (stub *ChaincodeStub) DelState(key string) error {
 	// Access public data by setting the collection to empty string
@@ -619,6 +639,23 @@ func (stub *ChaincodeStub) GetStableUnit(unitHash string, unitNumber uint64) (*m
 	return stub.handler.handleGetStableUnit(unitHash, unitNumber, stub.ContractId, stub.ChannelId, stub.TxID)
 }
 
+func (stub *ChaincodeStub) GetRandomness(height uint64) (common.Hash, error) {
+	return getRandomness(stub, height)
+}
+
+//单元的随机数由该稳定单元记录的父单元群签名生成，所以创世单元没有随机数。
+//不使用本地保存的群签名，各陪审员是否已经收到群签名并不一致，而单元中记录的群签名已经写入链上
+func getRandomness(stub ChaincodeStubInterface, height uint64) (common.Hash, error) {
+	if height == 0 {
+		return common.Hash{}, errors.New("the genesis unit has no randomness")
+	}
+	unit, err := stub.GetStableUnit("", height)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return modules.RandomnessBeacon(unit.UnitHeader)
+}
+
 func (stub *ChaincodeStub) DefineToken(tokenType byte, define []byte, creator string) error {
 	if !common.IsSystemContractId(stub.ContractId) {
 		return ERROR_ONLY_SYS_CONTRACT
@@ -869,214 +906,201 @@ func (c *ChaincodeLogger) Criticalf(format string, args ...interface{}) {
 // nil
 //}
 
-// CommonIterator documentation can be found in interfaces.go
-//type CommonIterator struct {
-// handler    *Handler
-// channelId  string
-// txid       string
-// response   *pb.QueryResponse
-// currentLoc int
-//}
-
 // StateQueryIterator documentation can be found in interfaces.go
-//type StateQueryIterator struct {
-// *CommonIterator
-//}
+type StateQueryIterator struct {
+	handler    *Handler
+	contractId []byte
+	channelId  string
+	txid       string
+	endKey     string
+	pageSize   int32
+	//分页查询只返回当前页，不分页时读完当前页后按书签自动获取下一页
+	paged      bool
+	response   *pb.QueryResponse
+	currentLoc int
+}
 
-// HistoryQueryIterator documentation can be found in interfaces.go
-//type HistoryQueryIterator struct {
-// *CommonIterator
-//}
+// HasNext documentation can be found in interfaces.go
+func (iter *StateQueryIterator) HasNext() bool {
+	if iter.response == nil {
+		return false
+	}
+	if iter.currentLoc < len(iter.response.Results) {
+		return true
+	}
+	return !iter.paged && iter.response.HasMore
+}
 
-//type resultType uint8
+// Next documentation can be found in interfaces.go
+func (iter *StateQueryIterator) Next() (*modules.KeyValue, error) {
+	if iter.response == nil {
+		return nil, errors.New("iterator is closed")
+	}
+	if iter.currentLoc == len(iter.response.Results) && !iter.paged && iter.response.HasMore {
+		response, err := iter.handler.handleGetStateByRange(iter.response.Id, iter.endKey, iter.pageSize,
+			iter.contractId, iter.channelId, iter.txid)
+		if err != nil {
+			return nil, err
+		}
+		iter.response = response
+		iter.currentLoc = 0
+	}
+	if iter.currentLoc >= len(iter.response.Results) {
+		return nil, errors.New("no such key")
+	}
+	kv := &modules.KeyValue{}
+	if err := json.Unmarshal(iter.response.Results[iter.currentLoc].ResultBytes, kv); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling result from bytes")
+	}
+	iter.currentLoc++
+	return kv, nil
+}
 
-//const (
-// STATE_QUERY_RESULT resultType = iota + 1
-// HISTORY_QUERY_RESULT
-//)
+// Close documentation can be found in interfaces.go
+func (iter *StateQueryIterator) Close() error {
+	iter.response = nil
+	return nil
+}
 
-//func (stub *ChaincodeStub) handleGetStateByRange(collection, startKey, endKey string) (StateQueryIteratorInterface, error) {
-// response, err := stub.handler.handleGetStateByRange(collection, startKey, endKey, stub.ChannelId, stub.TxID)
-// if err != nil {
-//    return nil, err
-// }
-// return &StateQueryIterator{CommonIterator: &CommonIterator{stub.handler, stub.ChannelId, stub.TxID, response, 0}},
-// nil
-//}
+func (stub *ChaincodeStub) handleGetStateByRange(startKey, endKey string, pageSize int32,
+	paged bool) (*StateQueryIterator, error) {
+	response, err := stub.handler.handleGetStateByRange(startKey, endKey, pageSize, stub.ContractId,
+		stub.ChannelId, stub.TxID)
+	if err != nil {
+		return nil, err
+	}
+	return &StateQueryIterator{handler: stub.handler, contractId: stub.ContractId, channelId: stub.ChannelId,
+		txid: stub.TxID, endKey: endKey, pageSize: pageSize, paged: paged, response: response}, nil
+}
+
+func (stub *ChaincodeStub) handleGetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, errors.Errorf("invalid page size %d", pageSize)
+	}
+	if bookmark != "" {
+		if bookmark < startKey || (endKey != "" && bookmark >= endKey) {
+			return nil, nil, errors.Errorf("bookmark [%s] is out of range", bookmark)
+		}
+		startKey = bookmark
+	}
+	iter, err := stub.handleGetStateByRange(startKey, endKey, pageSize, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata := &QueryResponseMetadata{FetchedRecordsCount: int32(len(iter.response.Results)),
+		Bookmark: iter.response.Id}
+	return iter, metadata, nil
+}
 
 // GetStateByRange documentation can be found in interfaces.go
-//func (stub *ChaincodeStub) GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error) {
-// if startKey == "" {
-//    startKey = emptyKeySubstitute
-// }
-// if err := validateSimpleKeys(startKey, endKey); err != nil {
-//    return nil, err
-// }
-// collection := ""
-// return stub.handleGetStateByRange(collection, startKey, endKey)
-//}
+func (stub *ChaincodeStub) GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return stub.handleGetStateByRange(startKey, endKey, defaultRangeQueryPageSize, false)
+}
+
+// GetStateByRangeWithPagination documentation can be found in interfaces.go
+func (stub *ChaincodeStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return stub.handleGetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+}
+
+//GetStateByPartialCompositeKey function can be invoked by a chaincode to query the
+//state based on a given partial composite key. This function returns an
+//iterator which can be used to iterate over all composite keys whose prefix
+//matches the given partial composite key.
+func (stub *ChaincodeStub) GetStateByPartialCompositeKey(objectType string,
+	attributes []string) (StateQueryIteratorInterface, error) {
+	partialCompositeKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return stub.handleGetStateByRange(partialCompositeKey, partialCompositeKey+string(maxUnicodeRuneValue),
+		defaultRangeQueryPageSize, false)
+}
+
+// GetStateByPartialCompositeKeyWithPagination documentation can be found in interfaces.go
+func (stub *ChaincodeStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string,
+	pageSize int32, bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	partialCompositeKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stub.handleGetStateByRangeWithPagination(partialCompositeKey,
+		partialCompositeKey+string(maxUnicodeRuneValue), pageSize, bookmark)
+}
 
 //CreateCompositeKey documentation can be found in interfaces.go
-//func (stub *ChaincodeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
-// return createCompositeKey(objectType, attributes)
-//}
+func (stub *ChaincodeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
 
 //SplitCompositeKey documentation can be found in interfaces.go
-//func (stub *ChaincodeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
-// return splitCompositeKey(compositeKey)
-//}
+func (stub *ChaincodeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	return splitCompositeKey(compositeKey)
+}
 
-//func createCompositeKey(objectType string, attributes []string) (string, error) {
-// if err := validateCompositeKeyAttribute(objectType); err != nil {
-//    return "", err
-// }
-// ck := compositeKeyNamespace + objectType + string(minUnicodeRuneValue)
-// for _, att := range attributes {
-//    if err := validateCompositeKeyAttribute(att); err != nil {
-//       return "", err
-//    }
-//    ck += att + string(minUnicodeRuneValue)
-// }
-// return ck, nil
-//}
+func createCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	ck := compositeKeyNamespace + objectType + string(minUnicodeRuneValue)
+	for _, att := range attributes {
+		if err := validateCompositeKeyAttribute(att); err != nil {
+			return "", err
+		}
+		ck += att + string(minUnicodeRuneValue)
+	}
+	return ck, nil
+}
 
-//func splitCompositeKey(compositeKey string) (string, []string, error) {
-// componentIndex := 1
-// components := []string{}
-// for i := 1; i < len(compositeKey); i++ {
-//    if compositeKey[i] == minUnicodeRuneValue {
-//       components = append(components, compositeKey[componentIndex:i])
-//       componentIndex = i + 1
-//    }
-// }
-// return components[0], components[1:], nil
-//}
+func splitCompositeKey(compositeKey string) (string, []string, error) {
+	if len(compositeKey) < 2 || compositeKey[0] != compositeKeyNamespace[0] ||
+		compositeKey[len(compositeKey)-1] != minUnicodeRuneValue {
+		return "", nil, errors.Errorf("invalid composite key [%x]", compositeKey)
+	}
+	componentIndex := 1
+	components := []string{}
+	for i := 1; i < len(compositeKey); i++ {
+		if compositeKey[i] == minUnicodeRuneValue {
+			components = append(components, compositeKey[componentIndex:i])
+			componentIndex = i + 1
+		}
+	}
+	return components[0], components[1:], nil
+}
 
-//func validateCompositeKeyAttribute(str string) error {
-// if !utf8.ValidString(str) {
-//    return errors.Errorf("not a valid utf8 string: [%x]", str)
-// }
-// for index, runeValue := range str {
-//    if runeValue == minUnicodeRuneValue || runeValue == maxUnicodeRuneValue {
-//       return errors.Errorf(`input contain unicode %#U starting at position [%d]. %#U and %#U are not allowed
-// in the input attribute of a composite key`,
-//          runeValue, index, minUnicodeRuneValue, maxUnicodeRuneValue)
-//    }
-// }
-// return nil
-//}
+func validateCompositeKeyAttribute(str string) error {
+	if !utf8.ValidString(str) {
+		return errors.Errorf("not a valid utf8 string: [%x]", str)
+	}
+	for index, runeValue := range str {
+		if runeValue == minUnicodeRuneValue || runeValue == maxUnicodeRuneValue {
+			return errors.Errorf(`input contain unicode %#U starting at position [%d]. %#U and %#U are not allowed
+in the input attribute of a composite key`,
+				runeValue, index, minUnicodeRuneValue, maxUnicodeRuneValue)
+		}
+	}
+	return nil
+}
 
 //To ensure that simple keys do not go into composite key namespace,
 //we validate simplekey to check whether the key starts with 0x00 (which
 //is the namespace for compositeKey). This helps in avoding simple/composite
 //key collisions.
-//func validateSimpleKeys(simpleKeys ...string) error {
-// for _, key := range simpleKeys {
-//    if len(key) > 0 && key[0] == compositeKeyNamespace[0] {
-//       return errors.Errorf(`first character of the key [%s] contains a null character which is not allowed`, key)
-//    }
-// }
-// return nil
-//}
-
-//GetStateByPartialCompositeKey function can be invoked by a chaincode to query the
-//state based on a given partial composite key. This function returns an
-//iterator which can be used to iterate over all composite keys whose prefix
-//matches the given partial composite key. This function should be used only for
-//a partial composite key. For a full composite key, an iter with empty response
-//would be returned.
-//func (stub *ChaincodeStub) GetStateByPartialCompositeKey(objectType string,
-// attributes []string) (StateQueryIteratorInterface, error) {
-// collection := ""
-// if partialCompositeKey, err := stub.CreateCompositeKey(objectType, attributes); err == nil {
-//    return stub.handleGetStateByRange(collection, partialCompositeKey, partialCompositeKey+string(maxUnicodeRuneValue))
-// } else {
-//    return nil, err
-// }
-//}
-
-// HasNext documentation can be found in interfaces.go
-//func (iter *CommonIterator) HasNext() bool {
-// if iter.currentLoc < len(iter.response.Results) || iter.response.HasMore {
-//    return true
-// }
-// return false
-//}
-
-// getResultsFromBytes deserializes QueryResult and return either a KV struct
-// or KeyModification depending on the result type (i.e., state (range/execute)
-// query, history query). Note that commonledger.QueryResult is an empty golang
-// interface that can hold values of any type.
-//func (iter *CommonIterator) getResultFromBytes(queryResultBytes *pb.QueryResultBytes,
-//rType resultType) (commonledger.QueryResult, error) {
-//glh
-/*
-   if rType == STATE_QUERY_RESULT {
-      stateQueryResult := &queryresult.KV{}
-      if err := proto.Unmarshal(queryResultBytes.ResultBytes, stateQueryResult); err != nil {
-         return nil, errors.Wrap(err, "error unmarshaling result from bytes")
-      }
-      return stateQueryResult, nil
-
-   } else if rType == HISTORY_QUERY_RESULT {
-      historyQueryResult := &queryresult.KeyModification{}
-      if err := proto.Unmarshal(queryResultBytes.ResultBytes, historyQueryResult); err != nil {
-         return nil, err
-      }
-      return historyQueryResult, nil
-   }
-   return nil, errors.New("wrong result type")
-*/
-//return nil, errors.New("glh unfinished")
-//}
-//
-//func (iter *CommonIterator) fetchNextQueryResult() error {
-// if response, err := iter.handler.handleQueryStateNext(iter.response.Id, iter.channelId, iter.txid); err == nil {
-//    iter.currentLoc = 0
-//    iter.response = response
-//    return nil
-// } else {
-//    return err
-// }
-//}
-
-// nextResult returns the next QueryResult (i.e., either a KV struct or KeyModification)
-// from the state or history query iterator. Note that commonledger.QueryResult is an
-// empty golang interface that can hold values of any type.
-//func (iter *CommonIterator) nextResult(rType resultType) (commonledger.QueryResult, error) {
-// if iter.currentLoc < len(iter.response.Results) {
-//    // On valid access of an element from cached results
-//    queryResult, err := iter.getResultFromBytes(iter.response.Results[iter.currentLoc], rType)
-//    if err != nil {
-//       log.Errorf("Failed to decode query results: %+v", err)
-//       return nil, err
-//    }
-//    iter.currentLoc++
-//
-//    if iter.currentLoc == len(iter.response.Results) && iter.response.HasMore {
-//       // On access of last item, pre-fetch to update HasMore flag
-//       if err = iter.fetchNextQueryResult(); err != nil {
-//          log.Errorf("Failed to fetch next results: %+v", err)
-//          return nil, err
-//       }
-//    }
-//
-//    return queryResult, err
-// } else if !iter.response.HasMore {
-//    // On call to Next() without check of HasMore
-//    return nil, errors.New("no such key")
-// }
-//
-// // should not fall through here
-// // case: no cached results but HasMore is true.
-// return nil, errors.New("invalid iterator state")
-//}
-
-// Close documentation can be found in interfaces.go
-//func (iter *CommonIterator) Close() error {
-// _, err := iter.handler.handleQueryStateClose(iter.response.Id, iter.channelId, iter.txid)
-// return err
-//}
+func validateSimpleKeys(simpleKeys ...string) error {
+	for _, key := range simpleKeys {
+		if len(key) > 0 && key[0] == compositeKeyNamespace[0] {
+			return errors.Errorf(`first character of the key [%s] contains a null character which is not allowed`, key)
+		}
+	}
+	return nil
+}
 
 // GetCreator documentation can be found in interfaces.go
 //func (stub *ChaincodeStub) GetCreator() ([]byte, error) {
@@ -0,0 +1,48 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package shim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeKey(t *testing.T) {
+	key, err := createCompositeKey("order", []string{"alice", "001"})
+	assert.Nil(t, err)
+	assert.Equal(t, "\x00order\x00alice\x00001\x00", key)
+	objectType, attributes, err := splitCompositeKey(key)
+	assert.Nil(t, err)
+	assert.Equal(t, "order", objectType)
+	assert.Equal(t, []string{"alice", "001"}, attributes)
+
+	//部分组合键是完整组合键的前缀
+	partial, err := createCompositeKey("order", []string{"alice"})
+	assert.Nil(t, err)
+	assert.True(t, partial < key && key < partial+string(maxUnicodeRuneValue))
+
+	_, err = createCompositeKey("order", []string{"a\x00b"})
+	assert.NotNil(t, err)
+	_, _, err = splitCompositeKey("order")
+	assert.NotNil(t, err)
+	assert.NotNil(t, validateSimpleKeys("a", key))
+	assert.Nil(t, validateSimpleKeys("a", ""))
+}
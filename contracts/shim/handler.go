@@ -318,7 +318,16 @@ func (handler *Handler) handleTransaction(msg *pb.ChaincodeMessage) {
 			return
 		}
 
-		res := handler.cc.Invoke(stub)
+		var res pb.Response
+		if fn, _ := stub.GetFunctionAndParameters(); fn == modules.ContractMigrateFunc {
+			//合约升级时的状态迁移，模板没有实现Migrator时保持原有状态
+			res = Success(nil)
+			if migrator, ok := handler.cc.(Migrator); ok {
+				res = migrator.Migrate(stub)
+			}
+		} else {
+			res = handler.cc.Invoke(stub)
+		}
 
 		// Endorser will handle error contained in Response.
 		resBytes, err := proto.Marshal(&res)
@@ -470,6 +479,42 @@ func (handler *Handler) handelGetStateByPrefix(prefix string, contractId []byte,
 	return nil, errors.Errorf("[%s]incorrect chaincode message %s received. Expecting %s or %s",
 		shorttxid(responseMsg.Txid), responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR)
 }
+// handleGetStateByRange communicates with the peer to fetch one page of the states in [startKey,endKey).
+func (handler *Handler) handleGetStateByRange(startKey, endKey string, pageSize int32, contractId []byte,
+	channelId string, txid string) (*pb.QueryResponse, error) {
+	payloadBytes, _ := proto.Marshal(&pb.GetStateByRange{StartKey: startKey, EndKey: endKey, PageSize: pageSize})
+
+	msg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_STATE_BY_RANGE, Payload: payloadBytes, Txid: txid,
+		ChannelId: channelId, ContractId: contractId}
+	log.Debugf("[%s]Sending %s", shorttxid(msg.Txid), pb.ChaincodeMessage_GET_STATE_BY_RANGE)
+
+	responseMsg, err := handler.callPeerWithChaincodeMsg(msg, channelId, txid)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("[%s]error sending GET_STATE_BY_RANGE",
+			shorttxid(txid)))
+	}
+
+	if responseMsg.Type.String() == pb.ChaincodeMessage_RESPONSE.String() {
+		// Success response
+		log.Debugf("[%s]GetStateByRange received payload %s", shorttxid(responseMsg.Txid),
+			pb.ChaincodeMessage_RESPONSE)
+		response := &pb.QueryResponse{}
+		if err = proto.Unmarshal(responseMsg.Payload, response); err != nil {
+			return nil, errors.Errorf("[%s]GetStateByRange unmarshall error", shorttxid(responseMsg.Txid))
+		}
+		return response, nil
+	}
+	if responseMsg.Type.String() == pb.ChaincodeMessage_ERROR.String() {
+		// Error response
+		log.Errorf("[%s]GetStateByRange received error %s", shorttxid(responseMsg.Txid),
+			pb.ChaincodeMessage_ERROR)
+		return nil, errors.New(string(responseMsg.Payload[:]))
+	}
+
+	// Incorrect chaincode message received
+	return nil, errors.Errorf("[%s]incorrect chaincode message %s received. Expecting %s or %s",
+		shorttxid(responseMsg.Txid), responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR)
+}
 func (handler *Handler) handleGetTimestamp(collection string, rangeNumber uint32, contractid []byte,
 	channelId string, txid string) ([]byte, error) {
 	// Construct payload for GET_STATE
@@ -40,6 +40,15 @@ type Chaincode interface {
 	Invoke(stub ChaincodeStubInterface) pb.Response
 }
 
+// Migrator is an optional interface a chaincode template can implement to
+// migrate the existing state when a deployed contract is upgraded to it.
+// If the template doesn't implement it, the upgrade keeps the state as is.
+type Migrator interface {
+	// Migrate is called once on the new template during the upgrade
+	// transaction, the state changes are committed with the upgrade.
+	Migrate(stub ChaincodeStubInterface) pb.Response
+}
+
 // ChaincodeStubInterface is used by deployable chaincode apps to access and
 // modify their ledgers
 type ChaincodeStubInterface interface {
@@ -87,6 +96,9 @@ type ChaincodeStubInterface interface {
 	// the called chaincode on a different channel is a `Query`, which does not
 	// participate in state validation checks in subsequent commit phase.
 	// If `channel` is empty, the caller's channel is assumed.
+	// A user contract is called with its contract address as `chaincodeName`;
+	// if the called contract fails, all its state changes and payouts are
+	// rolled back and an error Response is returned.
 	InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response
 
 	// GetState returns the value of the specified `key` from the
@@ -130,6 +142,16 @@ type ChaincodeStubInterface interface {
 	DelState(key string) error
 	DelGlobalState(key string) error
 
+	// PutPrivateState puts the specified `key` and `value` as private data.
+	// Only the hash of the value is written to the ledger, the value is
+	// encrypted to the jury of the contract and kept by the jurors.
+	PutPrivateState(key string, value []byte) error
+	// GetPrivateState returns the private value of the specified `key`.
+	// It can only be read on the jurors of the contract.
+	GetPrivateState(key string) ([]byte, error)
+	// DelPrivateState records the specified private `key` to be deleted.
+	DelPrivateState(key string) error
+
 	// GetTxTimestamp returns the timestamp when the transaction was created. This
 	// is taken from the transaction ChannelHeader, therefore it will indicate the
 	// client's timestamp and will have the same value across all endorsers.
@@ -162,6 +184,8 @@ type ChaincodeStubInterface interface {
 	GetStableTransactionByHash(txHash string) (*modules.Transaction, error)
 	//根据单元哈希或单元高度获得一个稳定的单元
 	GetStableUnit(unitHash string, unitNumber uint64) (*modules.Unit, error)
+	//获得某个高度稳定单元的随机数，由该单元记录的父单元群签名生成，所有陪审员得到相同的结果，可用于抽样等
+	GetRandomness(height uint64) (common.Hash, error)
 	//将合约上锁定的某种Token支付出去
 	PayOutToken(addr string, invokeTokens *modules.AmountAsset, lockTime uint32) error
 	//获取invoke参数，包括invokeAddr,tokens,fee,funcName,params
@@ -186,8 +210,14 @@ type ChaincodeStubInterface interface {
 	// Call Close() on the returned StateQueryIteratorInterface object when done.
 	// The query is re-executed during validation phase to ensure result set
 	// has not changed since transaction endorsement (phantom reads detected).
-	//TODO
-	//GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error)
+	GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error)
+
+	// GetStateByRangeWithPagination returns a range iterator over at most
+	// pageSize keys in [startKey, endKey). The bookmark returned in the
+	// metadata is used as the bookmark of the next call to fetch the next
+	// page, an empty bookmark means there are no more keys in the range.
+	GetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+		bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error)
 
 	// GetStateByPartialCompositeKey queries the state in the ledger based on
 	// a given partial composite key. This function returns an iterator
@@ -199,23 +229,25 @@ type ChaincodeStubInterface interface {
 	// Call Close() on the returned StateQueryIteratorInterface object when done.
 	// The query is re-executed during validation phase to ensure result set
 	// has not changed since transaction endorsement (phantom reads detected).
-	//TODO
-	//GetStateByPartialCompositeKey(objectType string, keys []string) (StateQueryIteratorInterface, error)
+	GetStateByPartialCompositeKey(objectType string, keys []string) (StateQueryIteratorInterface, error)
+
+	// GetStateByPartialCompositeKeyWithPagination is the paged version of
+	// GetStateByPartialCompositeKey, see GetStateByRangeWithPagination.
+	GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32,
+		bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error)
 
 	// CreateCompositeKey combines the given `attributes` to form a composite
 	// key. The objectType and attributes are expected to have only valid utf8
 	// strings and should not contain U+0000 (nil byte) and U+10FFFF
 	// (biggest and unallocated code point).
 	// The resulting composite key can be used as the key in PutState().
-	//TODO
-	//CreateCompositeKey(objectType string, attributes []string) (string, error)
+	CreateCompositeKey(objectType string, attributes []string) (string, error)
 
 	// SplitCompositeKey splits the specified key into attributes on which the
 	// composite key was formed. Composite keys found during range queries
 	// or partial composite key queries can therefore be split into their
 	// composite parts.
-	//TODO
-	//SplitCompositeKey(compositeKey string) (string, []string, error)
+	SplitCompositeKey(compositeKey string) (string, []string, error)
 
 	// GetQueryResult performs a "rich" query against a state database. It is
 	// only supported for state databases that support rich query,
@@ -281,25 +313,33 @@ type ChaincodeStubInterface interface {
 
 // CommonIteratorInterface allows a chaincode to check whether any more result
 // to be fetched from an iterator and close it when done.
-//type CommonIteratorInterface interface {
-// HasNext returns true if the range query iterator contains additional keys
-// and values.
-//HasNext() bool
-
-// Close closes the iterator. This should be called when done
-// reading from the iterator to free up resources.
-//Close() error
-//}
+type CommonIteratorInterface interface {
+	// HasNext returns true if the range query iterator contains additional keys
+	// and values.
+	HasNext() bool
+
+	// Close closes the iterator. This should be called when done
+	// reading from the iterator to free up resources.
+	Close() error
+}
 
 // StateQueryIteratorInterface allows a chaincode to iterate over a set of
-// key/value pairs returned by range and execute query.
-//type StateQueryIteratorInterface interface {
-// Inherit HasNext() and Close()
-//CommonIteratorInterface
+// key/value pairs returned by range query.
+type StateQueryIteratorInterface interface {
+	// Inherit HasNext() and Close()
+	CommonIteratorInterface
 
-// Next returns the next key and value in the range and execute query iterator.
-//Next() (*queryresult.KV, error)
-//}
+	// Next returns the next key and value in the range query iterator.
+	Next() (*modules.KeyValue, error)
+}
+
+// QueryResponseMetadata is returned by the paged range queries.
+type QueryResponseMetadata struct {
+	// FetchedRecordsCount is the number of records in this page
+	FetchedRecordsCount int32
+	// Bookmark is used to fetch the next page, empty if there are no more records
+	Bookmark string
+}
 
 // HistoryQueryIteratorInterface allows a chaincode to iterate over a set of
 // key/value pairs returned by a history query.
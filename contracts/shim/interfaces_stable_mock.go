@@ -320,6 +320,49 @@ func (mr *MockChaincodeStubInterfaceMockRecorder) RecvJury(msgType, consultConte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvJury", reflect.TypeOf((*MockChaincodeStubInterface)(nil).RecvJury), msgType, consultContent, timeout)
 }
 
+// PutPrivateState mocks base method
+func (m *MockChaincodeStubInterface) PutPrivateState(key string, value []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutPrivateState", key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutPrivateState indicates an expected call of PutPrivateState
+func (mr *MockChaincodeStubInterfaceMockRecorder) PutPrivateState(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutPrivateState", reflect.TypeOf((*MockChaincodeStubInterface)(nil).PutPrivateState), key, value)
+}
+
+// GetPrivateState mocks base method
+func (m *MockChaincodeStubInterface) GetPrivateState(key string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateState", key)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivateState indicates an expected call of GetPrivateState
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetPrivateState(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateState", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetPrivateState), key)
+}
+
+// DelPrivateState mocks base method
+func (m *MockChaincodeStubInterface) DelPrivateState(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelPrivateState", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DelPrivateState indicates an expected call of DelPrivateState
+func (mr *MockChaincodeStubInterfaceMockRecorder) DelPrivateState(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelPrivateState", reflect.TypeOf((*MockChaincodeStubInterface)(nil).DelPrivateState), key)
+}
+
 // DelState mocks base method
 func (m *MockChaincodeStubInterface) DelState(key string) error {
 	m.ctrl.T.Helper()
@@ -512,6 +555,21 @@ func (mr *MockChaincodeStubInterfaceMockRecorder) GetStableUnit(unitHash, unitNu
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStableUnit", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetStableUnit), unitHash, unitNumber)
 }
 
+// GetRandomness mocks base method
+func (m *MockChaincodeStubInterface) GetRandomness(height uint64) (common.Hash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRandomness", height)
+	ret0, _ := ret[0].(common.Hash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRandomness indicates an expected call of GetRandomness
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetRandomness(height interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRandomness", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetRandomness), height)
+}
+
 // PayOutToken mocks base method
 func (m *MockChaincodeStubInterface) PayOutToken(addr string, invokeTokens *modules.AmountAsset, lockTime uint32) error {
 	m.ctrl.T.Helper()
@@ -602,3 +660,96 @@ func (mr *MockChaincodeStubInterfaceMockRecorder) IsRequesterCertValid() *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRequesterCertValid", reflect.TypeOf((*MockChaincodeStubInterface)(nil).IsRequesterCertValid))
 }
+
+// GetStateByRange mocks base method
+func (m *MockChaincodeStubInterface) GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateByRange", startKey, endKey)
+	ret0, _ := ret[0].(StateQueryIteratorInterface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStateByRange indicates an expected call of GetStateByRange
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetStateByRange(startKey, endKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByRange", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetStateByRange), startKey, endKey)
+}
+
+// GetStateByRangeWithPagination mocks base method
+func (m *MockChaincodeStubInterface) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateByRangeWithPagination", startKey, endKey, pageSize, bookmark)
+	ret0, _ := ret[0].(StateQueryIteratorInterface)
+	ret1, _ := ret[1].(*QueryResponseMetadata)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetStateByRangeWithPagination indicates an expected call of GetStateByRangeWithPagination
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByRangeWithPagination", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetStateByRangeWithPagination), startKey, endKey, pageSize, bookmark)
+}
+
+// GetStateByPartialCompositeKey mocks base method
+func (m *MockChaincodeStubInterface) GetStateByPartialCompositeKey(objectType string, keys []string) (StateQueryIteratorInterface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateByPartialCompositeKey", objectType, keys)
+	ret0, _ := ret[0].(StateQueryIteratorInterface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStateByPartialCompositeKey indicates an expected call of GetStateByPartialCompositeKey
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetStateByPartialCompositeKey(objectType, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByPartialCompositeKey", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetStateByPartialCompositeKey), objectType, keys)
+}
+
+// GetStateByPartialCompositeKeyWithPagination mocks base method
+func (m *MockChaincodeStubInterface) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateByPartialCompositeKeyWithPagination", objectType, keys, pageSize, bookmark)
+	ret0, _ := ret[0].(StateQueryIteratorInterface)
+	ret1, _ := ret[1].(*QueryResponseMetadata)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetStateByPartialCompositeKeyWithPagination indicates an expected call of GetStateByPartialCompositeKeyWithPagination
+func (mr *MockChaincodeStubInterfaceMockRecorder) GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByPartialCompositeKeyWithPagination", reflect.TypeOf((*MockChaincodeStubInterface)(nil).GetStateByPartialCompositeKeyWithPagination), objectType, keys, pageSize, bookmark)
+}
+
+// CreateCompositeKey mocks base method
+func (m *MockChaincodeStubInterface) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCompositeKey", objectType, attributes)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCompositeKey indicates an expected call of CreateCompositeKey
+func (mr *MockChaincodeStubInterfaceMockRecorder) CreateCompositeKey(objectType, attributes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCompositeKey", reflect.TypeOf((*MockChaincodeStubInterface)(nil).CreateCompositeKey), objectType, attributes)
+}
+
+// SplitCompositeKey mocks base method
+func (m *MockChaincodeStubInterface) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SplitCompositeKey", compositeKey)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SplitCompositeKey indicates an expected call of SplitCompositeKey
+func (mr *MockChaincodeStubInterfaceMockRecorder) SplitCompositeKey(compositeKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SplitCompositeKey", reflect.TypeOf((*MockChaincodeStubInterface)(nil).SplitCompositeKey), compositeKey)
+}
@@ -0,0 +1,990 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package shim
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/palletone/go-palletone/common"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/pkg/errors"
+)
+
+// MockStub is an implementation of ChaincodeStubInterface for unit testing
+// chaincode. The contract states, the UTXO set and the unit chain are all kept
+// in memory, so contracts can be tested without a running node or Docker.
+// Every MockInit/MockInvoke runs in its own transaction: writes, payouts and
+// token definitions are buffered in a rwset and only committed when the
+// chaincode returns a successful response.
+type MockStub struct {
+	// Name of the mock contract, it's the contract address
+	Name string
+	// ContractId of the mock contract
+	ContractId []byte
+	ChannelId  string
+	// TxID of the running transaction
+	TxID string
+	// OutChainHandler is called by OutChainCall if set
+	OutChainHandler func(outChainName string, method string, params []byte) ([]byte, error)
+
+	cc   Chaincode
+	args [][]byte
+
+	invokeAddr   common.Address
+	invokeTokens []*modules.InvokeTokens
+	invokeFees   *modules.AmountAsset
+	cert         []byte
+
+	states  map[string]map[string][]byte //contractId--key--value, committed states
+	private map[string][]byte
+	utxos   map[modules.OutPoint]*mockUtxo
+	txs     map[common.Hash]*modules.Transaction
+	peers   map[string]*MockStub
+	gp      *modules.GlobalProperty
+	times   []int64 //单元的时间，下标为单元高度
+	txCount uint64
+
+	tx      *mockTx
+	payouts []*modules.TokenPayOut
+	events  []*modules.ContractEvent
+}
+
+type mockUtxo struct {
+	address string
+	asset   *modules.Asset
+	amount  uint64
+}
+
+type mockWrite struct {
+	value    []byte
+	isDelete bool
+}
+
+// mockTx is the in-memory rwset of the running transaction
+type mockTx struct {
+	writes      map[string]map[string]*mockWrite
+	private     map[string]*mockWrite
+	payouts     []*modules.TokenPayOut
+	events      []*modules.ContractEvent
+	tokenDefine *modules.TokenDefine
+	tokenSupply []*modules.TokenSupply
+	callees     []*mockSnapshot //被调用合约在调用前的状态，本交易失败时恢复
+}
+
+// mockSnapshot is the committed states of a MockStub before it's called by another contract
+type mockSnapshot struct {
+	stub    *MockStub
+	states  map[string]map[string][]byte
+	private map[string][]byte
+	utxos   map[modules.OutPoint]*mockUtxo
+	payouts []*modules.TokenPayOut
+	events  []*modules.ContractEvent
+}
+
+func newMockTx() *mockTx {
+	return &mockTx{writes: make(map[string]map[string]*mockWrite), private: make(map[string]*mockWrite)}
+}
+
+// NewMockStub creates a MockStub for the chaincode deployed at contractAddr
+func NewMockStub(contractAddr common.Address, cc Chaincode) *MockStub {
+	return &MockStub{
+		Name:       contractAddr.String(),
+		ContractId: contractAddr.Bytes(),
+		ChannelId:  "palletone",
+		cc:         cc,
+		states:     make(map[string]map[string][]byte),
+		private:    make(map[string][]byte),
+		utxos:      make(map[modules.OutPoint]*mockUtxo),
+		txs:        make(map[common.Hash]*modules.Transaction),
+		peers:      make(map[string]*MockStub),
+		gp:         modules.NewGlobalProp(),
+		times:      []int64{time.Now().Unix()},
+	}
+}
+
+// ---------- test helpers ----------
+
+// SetInvoker sets the requester address of the following transactions
+func (stub *MockStub) SetInvoker(addr common.Address) {
+	stub.invokeAddr = addr
+}
+
+// SetInvokeTokens sets the tokens paid by the requester in the next transaction,
+// they are cleared after the transaction
+func (stub *MockStub) SetInvokeTokens(tokens ...*modules.InvokeTokens) {
+	stub.invokeTokens = tokens
+}
+
+// SetInvokeFees sets the fee of the following transactions
+func (stub *MockStub) SetInvokeFees(fees *modules.AmountAsset) {
+	stub.invokeFees = fees
+}
+
+// SetRequesterCert sets the certificate bytes of the requester
+func (stub *MockStub) SetRequesterCert(cert []byte) {
+	stub.cert = cert
+}
+
+// SetGlobalProperty sets the system config returned by GetSystemConfig
+func (stub *MockStub) SetGlobalProperty(gp *modules.GlobalProperty) {
+	stub.gp = gp
+}
+
+// SetContractState sets a committed state of any contract, it can be used to
+// prepare the state read by GetContractState and GetGlobalState
+func (stub *MockStub) SetContractState(contractAddr common.Address, key string, value []byte) {
+	stub.putCommitted(contractAddr.Bytes(), key, value)
+}
+
+// MockPeerChaincode registers another MockStub to be called by InvokeChaincode
+func (stub *MockStub) MockPeerChaincode(contractAddr common.Address, peer *MockStub) {
+	stub.peers[contractAddr.String()] = peer
+}
+
+// AddStableTransaction adds a transaction returned by GetStableTransactionByHash
+func (stub *MockStub) AddStableTransaction(tx *modules.Transaction) {
+	stub.txs[tx.Hash()] = tx
+}
+
+// FundAddress adds a UTXO of the asset to the address
+func (stub *MockStub) FundAddress(addr common.Address, asset *modules.Asset, amount uint64) {
+	stub.addUtxo(stub.utxos, stub.newMockTxHash(), addr.String(), asset, amount)
+}
+
+// GetBalance returns the balance of the asset owned by the address
+func (stub *MockStub) GetBalance(addr common.Address, asset *modules.Asset) uint64 {
+	return balanceOf(stub.utxos, addr.String())[*asset]
+}
+
+// AdvanceHeight produces n units, the time of each unit is MediatorInterval
+// seconds after the previous one
+func (stub *MockStub) AdvanceHeight(n uint64) {
+	interval := int64(stub.gp.ChainParameters.MediatorInterval)
+	for i := uint64(0); i < n; i++ {
+		stub.times = append(stub.times, stub.Timestamp()+interval)
+	}
+}
+
+// AdvanceTime produces a unit whose time is d after the newest unit
+func (stub *MockStub) AdvanceTime(d time.Duration) {
+	stub.times = append(stub.times, stub.Timestamp()+int64(d/time.Second))
+}
+
+// Height returns the height of the newest unit
+func (stub *MockStub) Height() uint64 {
+	return uint64(len(stub.times) - 1)
+}
+
+// Timestamp returns the time of the newest unit
+func (stub *MockStub) Timestamp() int64 {
+	return stub.times[len(stub.times)-1]
+}
+
+// Payouts returns the payouts of the last successful transaction
+func (stub *MockStub) Payouts() []*modules.TokenPayOut {
+	return stub.payouts
+}
+
+// Events returns the events of the last successful transaction
+func (stub *MockStub) Events() []*modules.ContractEvent {
+	return stub.events
+}
+
+// AssertPayout checks the last successful transaction paid the amount of the asset to the address
+func (stub *MockStub) AssertPayout(addr common.Address, asset *modules.Asset, amount uint64) error {
+	var paid uint64
+	for _, payout := range stub.payouts {
+		if payout.PayTo == addr && payout.Asset.Equal(asset) {
+			paid += payout.Amount
+		}
+	}
+	if paid != amount {
+		return errors.Errorf("expect payout %d %s to %s, but got %d", amount, asset.String(), addr.String(),
+			paid)
+	}
+	return nil
+}
+
+// MockInit calls the Init function of the chaincode in a new transaction,
+// args are the function name and parameters
+func (stub *MockStub) MockInit(txid string, args [][]byte) pb.Response {
+	res, _ := stub.mockTransaction(txid, args, stub.nextInvokeInfo(), stub.cc.Init)
+	return res
+}
+
+// MockInvoke calls the Invoke function of the chaincode in a new transaction,
+// args are the function name and parameters
+func (stub *MockStub) MockInvoke(txid string, args [][]byte) pb.Response {
+	res, _ := stub.mockTransaction(txid, args, stub.nextInvokeInfo(), stub.cc.Invoke)
+	return res
+}
+
+//nextInvokeInfo 测试设置的请求参数，SetInvokeTokens设置的Token只用于一个交易
+func (stub *MockStub) nextInvokeInfo() *modules.InvokeInfo {
+	info := &modules.InvokeInfo{InvokeAddress: stub.invokeAddr, InvokeTokens: stub.invokeTokens,
+		InvokeFees: stub.invokeFees}
+	stub.invokeTokens = nil
+	return info
+}
+
+//mockTransaction 执行一个交易，失败时丢弃本交易的写入并恢复被调用的合约，
+//成功时返回被调用合约的快照，用于调用者失败时恢复
+func (stub *MockStub) mockTransaction(txid string, args [][]byte, info *modules.InvokeInfo,
+	fn func(stub ChaincodeStubInterface) pb.Response) (pb.Response, []*mockSnapshot) {
+	if txid == "" {
+		txid = stub.newMockTxHash().String()
+	}
+	invokeInfo, _ := json.Marshal(info)
+	//与节点上的调用参数格式一致：[invokeInfo, certId, function, params...]
+	stub.args = append([][]byte{invokeInfo, nil}, args...)
+	stub.TxID = txid
+	stub.tx = newMockTx()
+	defer func() {
+		stub.tx = nil
+	}()
+
+	res := fn(stub)
+	if res.Status == OK {
+		if err := stub.commit(info.InvokeAddress, info.InvokeTokens); err != nil {
+			res = Error(err.Error())
+		}
+	}
+	if res.Status != OK {
+		for i := len(stub.tx.callees) - 1; i >= 0; i-- {
+			stub.tx.callees[i].restore()
+		}
+		return res, nil
+	}
+	return res, stub.tx.callees
+}
+
+func (stub *MockStub) snapshot() *mockSnapshot {
+	s := &mockSnapshot{stub: stub, states: make(map[string]map[string][]byte, len(stub.states)),
+		private: make(map[string][]byte, len(stub.private)), utxos: make(map[modules.OutPoint]*mockUtxo,
+			len(stub.utxos)), payouts: stub.payouts, events: stub.events}
+	for contractId, states := range stub.states {
+		s.states[contractId] = make(map[string][]byte, len(states))
+		for k, v := range states {
+			s.states[contractId][k] = v
+		}
+	}
+	for k, v := range stub.private {
+		s.private[k] = v
+	}
+	for k, v := range stub.utxos {
+		s.utxos[k] = v
+	}
+	return s
+}
+
+func (s *mockSnapshot) restore() {
+	s.stub.states, s.stub.private, s.stub.utxos = s.states, s.private, s.utxos
+	s.stub.payouts, s.stub.events = s.payouts, s.events
+}
+
+//commit 交易执行成功后，先在UTXO集合的副本上完成转账，全部成功后再一起提交
+func (stub *MockStub) commit(invoker common.Address, invokeTokens []*modules.InvokeTokens) error {
+	txHash := common.HexToHash(stub.TxID)
+	utxos := make(map[modules.OutPoint]*mockUtxo, len(stub.utxos))
+	for k, v := range stub.utxos {
+		utxos[k] = v
+	}
+	for _, token := range invokeTokens {
+		err := stub.transfer(utxos, txHash, invoker.String(), token.Address, token.Asset, token.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	contractAddr := common.NewAddress(stub.ContractId, common.ContractHash)
+	for _, payout := range stub.tx.payouts {
+		from := contractAddr
+		if payout.PayFrom != (common.Address{}) {
+			from = payout.PayFrom
+		}
+		err := stub.transfer(utxos, txHash, from.String(), payout.PayTo.String(), payout.Asset, payout.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	if stub.tx.tokenDefine != nil {
+		if err := stub.defineToken(utxos, txHash, stub.tx.tokenDefine); err != nil {
+			return err
+		}
+	}
+	for _, supply := range stub.tx.tokenSupply {
+		asset := &modules.Asset{}
+		asset.AssetId.SetBytes(supply.AssetId)
+		asset.UniqueId.SetBytes(supply.UniqueId)
+		stub.addUtxo(utxos, txHash, supply.Creator.String(), asset, supply.Amount)
+	}
+	stub.utxos = utxos
+
+	for contractId, writes := range stub.tx.writes {
+		for key, w := range writes {
+			if w.isDelete {
+				delete(stub.states[contractId], key)
+			} else {
+				stub.putCommitted([]byte(contractId), key, w.value)
+			}
+		}
+	}
+	for key, w := range stub.tx.private {
+		if w.isDelete {
+			delete(stub.private, key)
+		} else {
+			stub.private[key] = w.value
+		}
+	}
+	stub.payouts = stub.tx.payouts
+	stub.events = stub.tx.events
+	return nil
+}
+
+//defineToken 与节点上的处理一致，新定义的Token全部发给创建者
+func (stub *MockStub) defineToken(utxos map[modules.OutPoint]*mockUtxo, txHash common.Hash,
+	define *modules.TokenDefine) error {
+	creator := define.Creator.String()
+	switch define.TokenType {
+	case 0: //ERC20
+		token := modules.FungibleToken{}
+		if err := json.Unmarshal(define.TokenDefineJson, &token); err != nil {
+			return err
+		}
+		asset := &modules.Asset{}
+		asset.AssetId, _ = modules.NewAssetId(token.Symbol, modules.AssetType_FungibleToken, token.Decimals,
+			txHash.Bytes(), modules.UniqueIdType_Null)
+		stub.addUtxo(utxos, txHash, creator, asset, token.TotalSupply)
+	case 1: //ERC721
+		token := modules.NonFungibleToken{}
+		if err := json.Unmarshal(define.TokenDefineJson, &token); err != nil {
+			return err
+		}
+		if uint64(len(token.NonFungibleData)) < token.TotalSupply {
+			return errors.New("NonFungibleData's len less than TotalSupply")
+		}
+		for i := uint64(0); i < token.TotalSupply; i++ {
+			if len(token.NonFungibleData[i].UniqueBytes) < 16 {
+				return errors.New("UniqueBytes's len must bigger than 16")
+			}
+			asset := &modules.Asset{}
+			asset.AssetId, _ = modules.NewAssetId(token.Symbol, modules.AssetType_NonFungibleToken, 0,
+				txHash.Bytes(), modules.UniqueIdType(token.Type))
+			asset.UniqueId.SetBytes(token.NonFungibleData[i].UniqueBytes)
+			stub.addUtxo(utxos, txHash, creator, asset, 1)
+		}
+	case 2: //VoteToken
+		token := modules.VoteToken{}
+		if err := json.Unmarshal(define.TokenDefineJson, &token); err != nil {
+			return err
+		}
+		asset := &modules.Asset{}
+		asset.AssetId, _ = modules.NewAssetId(token.Symbol, modules.AssetType_VoteToken, 0, txHash.Bytes(),
+			modules.UniqueIdType_Null)
+		stub.addUtxo(utxos, txHash, creator, asset, token.TotalSupply)
+	default:
+		return errors.Errorf("unknown token type %d", define.TokenType)
+	}
+	return nil
+}
+
+//transfer 花费from地址的UTXO，生成给to地址的输出和找零
+func (stub *MockStub) transfer(utxos map[modules.OutPoint]*mockUtxo, txHash common.Hash, from, to string,
+	asset *modules.Asset, amount uint64) error {
+	if amount == 0 {
+		return nil
+	}
+	outPoints := make([]modules.OutPoint, 0)
+	for outPoint, utxo := range utxos {
+		if utxo.address == from && utxo.asset.Equal(asset) {
+			outPoints = append(outPoints, outPoint)
+		}
+	}
+	//按OutPoint排序，保证选择的UTXO是确定的
+	sort.Slice(outPoints, func(i, j int) bool {
+		return outPoints[i].String() < outPoints[j].String()
+	})
+	var total uint64
+	for _, outPoint := range outPoints {
+		if total >= amount {
+			break
+		}
+		total += utxos[outPoint].amount
+		delete(utxos, outPoint)
+	}
+	if total < amount {
+		return errors.Errorf("address %s balance of %s is %d, not enough to pay %d", from, asset.String(),
+			total, amount)
+	}
+	stub.addUtxo(utxos, txHash, to, asset, amount)
+	if total > amount {
+		stub.addUtxo(utxos, txHash, from, asset, total-amount)
+	}
+	return nil
+}
+
+func (stub *MockStub) addUtxo(utxos map[modules.OutPoint]*mockUtxo, txHash common.Hash, addr string,
+	asset *modules.Asset, amount uint64) {
+	outPoint := modules.OutPoint{TxHash: txHash}
+	for {
+		if _, ok := utxos[outPoint]; !ok {
+			break
+		}
+		outPoint.OutIndex++
+	}
+	a := *asset
+	utxos[outPoint] = &mockUtxo{address: addr, asset: &a, amount: amount}
+}
+
+func balanceOf(utxos map[modules.OutPoint]*mockUtxo, addr string) map[modules.Asset]uint64 {
+	result := make(map[modules.Asset]uint64)
+	for _, utxo := range utxos {
+		if utxo.address == addr {
+			result[*utxo.asset] += utxo.amount
+		}
+	}
+	return result
+}
+
+func (stub *MockStub) newMockTxHash() common.Hash {
+	stub.txCount++
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, stub.txCount)
+	return common.BytesToHash(append([]byte(stub.Name), b...))
+}
+
+func (stub *MockStub) putCommitted(contractId []byte, key string, value []byte) {
+	states, ok := stub.states[string(contractId)]
+	if !ok {
+		states = make(map[string][]byte)
+		stub.states[string(contractId)] = states
+	}
+	states[key] = value
+}
+
+func (stub *MockStub) checkTx() error {
+	if stub.tx == nil {
+		return errors.New("no transaction is running, use MockInit or MockInvoke")
+	}
+	return nil
+}
+
+func (stub *MockStub) getState(contractId []byte, key string) ([]byte, error) {
+	if stub.tx != nil {
+		if w, ok := stub.tx.writes[string(contractId)][key]; ok {
+			if w.isDelete {
+				return nil, nil
+			}
+			return w.value, nil
+		}
+	}
+	return stub.states[string(contractId)][key], nil
+}
+
+func (stub *MockStub) putState(contractId []byte, key string, value []byte, isDelete bool) error {
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	writes, ok := stub.tx.writes[string(contractId)]
+	if !ok {
+		writes = make(map[string]*mockWrite)
+		stub.tx.writes[string(contractId)] = writes
+	}
+	writes[key] = &mockWrite{value: value, isDelete: isDelete}
+	return nil
+}
+
+//getSortedStates 返回合约当前的所有状态（包含本交易的写入），按Key排序
+func (stub *MockStub) getSortedStates(contractId []byte) []*modules.KeyValue {
+	merged := make(map[string][]byte)
+	for k, v := range stub.states[string(contractId)] {
+		merged[k] = v
+	}
+	if stub.tx != nil {
+		for k, w := range stub.tx.writes[string(contractId)] {
+			if w.isDelete {
+				delete(merged, k)
+			} else {
+				merged[k] = w.value
+			}
+		}
+	}
+	result := make([]*modules.KeyValue, 0, len(merged))
+	for k, v := range merged {
+		result = append(result, &modules.KeyValue{Key: k, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func (stub *MockStub) mockHeader(number uint64) *modules.Header {
+	header := modules.NewHeader(nil, common.Hash{}, nil, nil, nil, nil, nil, modules.PTNCOIN, number,
+		stub.times[number])
+	if number > 0 {
+		//模拟生产者记录的父单元群签名
+		header.SetPrevGroupSign([]byte(fmt.Sprintf("group sign of unit %d at %d", number-1,
+			stub.times[number-1])))
+	}
+	return header
+}
+
+// ---------- ChaincodeStubInterface ----------
+
+func (stub *MockStub) GetArgs() [][]byte {
+	if len(stub.args) <= 2 {
+		return nil
+	}
+	return stub.args[2:]
+}
+
+func (stub *MockStub) GetStringArgs() []string {
+	args := stub.GetArgs()
+	strargs := make([]string, 0, len(args))
+	for _, barg := range args {
+		strargs = append(strargs, string(barg))
+	}
+	return strargs
+}
+
+func (stub *MockStub) GetFunctionAndParameters() (function string, params []string) {
+	allargs := stub.GetStringArgs()
+	params = []string{}
+	if len(allargs) >= 1 {
+		function = allargs[0]
+		params = allargs[1:]
+	}
+	return
+}
+
+func (stub *MockStub) GetArgsSlice() ([]byte, error) {
+	res := []byte{}
+	for _, barg := range stub.GetArgs() {
+		res = append(res, barg...)
+	}
+	return res, nil
+}
+
+func (stub *MockStub) GetTxID() string {
+	return stub.TxID
+}
+
+func (stub *MockStub) GetChannelID() string {
+	return stub.ChannelId
+}
+
+// InvokeChaincode calls the MockStub registered by MockPeerChaincode, the requester of the
+// called contract is this contract. The writes of the called contract are discarded if this
+// transaction fails, the same as the contract call on a node.
+func (stub *MockStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	if err := stub.checkTx(); err != nil {
+		return Error(err.Error())
+	}
+	peer, ok := stub.peers[chaincodeName]
+	if !ok {
+		return Error("chaincode " + chaincodeName + " is not registered by MockPeerChaincode")
+	}
+	if peer.tx != nil {
+		return Error("reentrant call to chaincode " + chaincodeName)
+	}
+	snapshot := peer.snapshot()
+	//与节点上一致，被调用合约的请求地址为调用合约，不带Token和手续费
+	info := &modules.InvokeInfo{InvokeAddress: common.NewAddress(stub.ContractId, common.ContractHash)}
+	res, callees := peer.mockTransaction(stub.TxID, args, info, peer.cc.Invoke)
+	if res.Status != OK {
+		return res
+	}
+	stub.tx.callees = append(append(stub.tx.callees, snapshot), callees...)
+	return res
+}
+
+func (stub *MockStub) GetState(key string) ([]byte, error) {
+	return stub.getState(stub.ContractId, key)
+}
+
+func (stub *MockStub) GetGlobalState(key string) ([]byte, error) {
+	return stub.getState(GlobalStateContractId, key)
+}
+
+func (stub *MockStub) GetContractState(contractAddr common.Address, key string) ([]byte, error) {
+	return stub.getState(contractAddr.Bytes(), key)
+}
+
+func (stub *MockStub) GetStateByPrefix(prefix string) ([]*modules.KeyValue, error) {
+	result := make([]*modules.KeyValue, 0)
+	for _, kv := range stub.getSortedStates(stub.ContractId) {
+		if strings.HasPrefix(kv.Key, prefix) {
+			result = append(result, kv)
+		}
+	}
+	return result, nil
+}
+
+func (stub *MockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	return stub.putState(stub.ContractId, key, value, false)
+}
+
+func (stub *MockStub) PutGlobalState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if !common.IsSystemContractId(stub.ContractId) {
+		return ERROR_ONLY_SYS_CONTRACT
+	}
+	return stub.putState(GlobalStateContractId, key, value, false)
+}
+
+func (stub *MockStub) OutChainCall(outChainName string, method string, params []byte) ([]byte, error) {
+	if stub.OutChainHandler == nil {
+		return nil, errors.New("OutChainCall is not supported by MockStub without OutChainHandler")
+	}
+	return stub.OutChainHandler(outChainName, method, params)
+}
+
+func (stub *MockStub) SendJury(msgType uint32, consultContent []byte, myAnswer []byte) ([]byte, error) {
+	return nil, errors.New("SendJury is not supported by MockStub")
+}
+
+func (stub *MockStub) RecvJury(msgType uint32, consultContent []byte, timeout uint32) ([]byte, error) {
+	return nil, errors.New("RecvJury is not supported by MockStub")
+}
+
+func (stub *MockStub) DelState(key string) error {
+	return stub.putState(stub.ContractId, key, nil, true)
+}
+
+func (stub *MockStub) DelGlobalState(key string) error {
+	if !common.IsSystemContractId(stub.ContractId) {
+		return ERROR_ONLY_SYS_CONTRACT
+	}
+	return stub.putState(GlobalStateContractId, key, nil, true)
+}
+
+// PutPrivateState keeps the plaintext in memory, there is no jury to encrypt it for
+func (stub *MockStub) PutPrivateState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	stub.tx.private[key] = &mockWrite{value: value}
+	return nil
+}
+
+func (stub *MockStub) GetPrivateState(key string) ([]byte, error) {
+	if stub.tx != nil {
+		if w, ok := stub.tx.private[key]; ok {
+			if w.isDelete {
+				return nil, nil
+			}
+			return w.value, nil
+		}
+	}
+	return stub.private[key], nil
+}
+
+func (stub *MockStub) DelPrivateState(key string) error {
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	stub.tx.private[key] = &mockWrite{isDelete: true}
+	return nil
+}
+
+// GetTxTimestamp returns the time of the unit at height/rangeNumber*rangeNumber like the node does
+func (stub *MockStub) GetTxTimestamp(rangeNumber uint32) (*timestamp.Timestamp, error) {
+	if rangeNumber == 0 {
+		rangeNumber = 1
+	}
+	index := stub.Height() / uint64(rangeNumber) * uint64(rangeNumber)
+	return &timestamp.Timestamp{Seconds: stub.times[index], Nanos: 0}, nil
+}
+
+func (stub *MockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be nil string")
+	}
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	stub.tx.events = append(stub.tx.events, &modules.ContractEvent{ContractId: common.CopyBytes(stub.ContractId),
+		Name: name, Payload: common.CopyBytes(payload)})
+	return nil
+}
+
+func (stub *MockStub) GetSystemConfig() (*modules.GlobalProperty, error) {
+	return stub.gp, nil
+}
+
+func (stub *MockStub) GetInvokeAddress() (common.Address, error) {
+	invokeAddr, _, _, _, _, err := stub.GetInvokeParameters()
+	return invokeAddr, err
+}
+
+func (stub *MockStub) GetInvokeTokens() ([]*modules.InvokeTokens, error) {
+	_, invokeTokens, _, _, _, err := stub.GetInvokeParameters()
+	return invokeTokens, err
+}
+
+func (stub *MockStub) GetContractAllState() (map[string]*modules.ContractStateValue, error) {
+	result := make(map[string]*modules.ContractStateValue)
+	for k, v := range stub.states[string(stub.ContractId)] {
+		result[k] = &modules.ContractStateValue{Value: v}
+	}
+	return result, nil
+}
+
+func (stub *MockStub) GetInvokeFees() (*modules.AmountAsset, error) {
+	_, _, invokeFees, _, _, err := stub.GetInvokeParameters()
+	return invokeFees, err
+}
+
+func (stub *MockStub) GetContractID() ([]byte, string) {
+	addr := common.NewAddress(stub.ContractId, common.ContractHash)
+	return stub.ContractId, addr.Str()
+}
+
+// GetTokenBalance returns the committed balance, address is the contract itself if it's empty
+func (stub *MockStub) GetTokenBalance(address string, token *modules.Asset) ([]*modules.InvokeTokens, error) {
+	if address == "" {
+		address = common.NewAddress(stub.ContractId, common.ContractHash).String()
+	}
+	result := []*modules.InvokeTokens{}
+	for asset, amount := range balanceOf(stub.utxos, address) {
+		asset := asset
+		if token != nil && !token.Equal(&asset) {
+			continue
+		}
+		result = append(result, &modules.InvokeTokens{Amount: amount, Asset: &asset, Address: address})
+	}
+	return result, nil
+}
+
+func (stub *MockStub) GetStableTransactionByHash(txHash string) (*modules.Transaction, error) {
+	tx, ok := stub.txs[common.HexToHash(txHash)]
+	if !ok {
+		return nil, errors.New("transaction not found:" + txHash)
+	}
+	return tx, nil
+}
+
+// GetStableUnit returns a unit with an empty body, all produced units are treated as stable
+func (stub *MockStub) GetStableUnit(unitHash string, unitNumber uint64) (*modules.Unit, error) {
+	if unitHash != "" {
+		hash := common.HexToHash(unitHash)
+		for i := range stub.times {
+			header := stub.mockHeader(uint64(i))
+			if header.Hash() == hash {
+				return modules.NewUnit(header, nil), nil
+			}
+		}
+		return nil, errors.New("unit not found:" + unitHash)
+	}
+	if unitNumber > stub.Height() {
+		return nil, fmt.Errorf("unit %d not found, height is %d", unitNumber, stub.Height())
+	}
+	return modules.NewUnit(stub.mockHeader(unitNumber), nil), nil
+}
+
+func (stub *MockStub) GetRandomness(height uint64) (common.Hash, error) {
+	return getRandomness(stub, height)
+}
+
+func (stub *MockStub) PayOutToken(addr string, invokeTokens *modules.AmountAsset, lockTime uint32) error {
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	payTo, err := common.StringToAddress(addr)
+	if err != nil {
+		return err
+	}
+	stub.tx.payouts = append(stub.tx.payouts, &modules.TokenPayOut{Asset: invokeTokens.Asset,
+		Amount: invokeTokens.Amount, PayTo: payTo, LockTime: lockTime})
+	return nil
+}
+
+func (stub *MockStub) GetInvokeParameters() (invokeAddr common.Address, invokeTokens []*modules.InvokeTokens,
+	invokeFees *modules.AmountAsset, funcName string, params []string, err error) {
+	if len(stub.args) == 0 {
+		return
+	}
+	invokeInfo := &modules.InvokeInfo{}
+	err = json.Unmarshal(stub.args[0], invokeInfo)
+	if err != nil {
+		return common.Address{}, nil, nil, "", nil, err
+	}
+	funcName, params = stub.GetFunctionAndParameters()
+	return invokeInfo.InvokeAddress, invokeInfo.InvokeTokens, invokeInfo.InvokeFees, funcName, params, nil
+}
+
+func (stub *MockStub) DefineToken(tokenType byte, define []byte, creator string) error {
+	if !common.IsSystemContractId(stub.ContractId) {
+		return ERROR_ONLY_SYS_CONTRACT
+	}
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	createAddr, err := common.StringToAddress(creator)
+	if err != nil {
+		return err
+	}
+	stub.tx.tokenDefine = &modules.TokenDefine{TokenType: int(tokenType), TokenDefineJson: define,
+		Creator: createAddr}
+	return nil
+}
+
+func (stub *MockStub) SupplyToken(assetId []byte, uniqueId []byte, amt uint64, creator string) error {
+	if !common.IsSystemContractId(stub.ContractId) {
+		return ERROR_ONLY_SYS_CONTRACT
+	}
+	if err := stub.checkTx(); err != nil {
+		return err
+	}
+	createAddr, err := common.StringToAddress(creator)
+	if err != nil {
+		return err
+	}
+	stub.tx.tokenSupply = append(stub.tx.tokenSupply, &modules.TokenSupply{AssetId: assetId,
+		UniqueId: uniqueId, Amount: amt, Creator: createAddr})
+	return nil
+}
+
+func (stub *MockStub) GetRequesterCert() (certBytes []byte, err error) {
+	if len(stub.cert) == 0 {
+		return nil, errors.New("requester has no certificate")
+	}
+	return stub.cert, nil
+}
+
+func (stub *MockStub) IsRequesterCertValid() (bool, error) {
+	return len(stub.cert) > 0, nil
+}
+
+func (stub *MockStub) GetStateByRange(startKey, endKey string) (StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return stub.rangeQuery(startKey, endKey, 0), nil
+}
+
+func (stub *MockStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return stub.rangeQueryWithPagination(startKey, endKey, pageSize, bookmark)
+}
+
+func (stub *MockStub) GetStateByPartialCompositeKey(objectType string,
+	keys []string) (StateQueryIteratorInterface, error) {
+	partialCompositeKey, err := createCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return stub.rangeQuery(partialCompositeKey, partialCompositeKey+string(maxUnicodeRuneValue), 0), nil
+}
+
+func (stub *MockStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string,
+	pageSize int32, bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	partialCompositeKey, err := createCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stub.rangeQueryWithPagination(partialCompositeKey, partialCompositeKey+string(maxUnicodeRuneValue),
+		pageSize, bookmark)
+}
+
+func (stub *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
+
+func (stub *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	return splitCompositeKey(compositeKey)
+}
+
+//rangeQuery 返回[startKey, endKey)范围内的状态，endKey为空表示不限制，pageSize为0表示不分页
+func (stub *MockStub) rangeQuery(startKey, endKey string, pageSize int32) *MockStateRangeQueryIterator {
+	iter := &MockStateRangeQueryIterator{}
+	for _, kv := range stub.getSortedStates(stub.ContractId) {
+		if kv.Key < startKey || (endKey != "" && kv.Key >= endKey) {
+			continue
+		}
+		if pageSize > 0 && int32(len(iter.kvs)) == pageSize {
+			iter.next = kv.Key
+			break
+		}
+		iter.kvs = append(iter.kvs, kv)
+	}
+	return iter
+}
+
+func (stub *MockStub) rangeQueryWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (StateQueryIteratorInterface, *QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, errors.Errorf("invalid page size %d", pageSize)
+	}
+	if bookmark != "" {
+		if bookmark < startKey || (endKey != "" && bookmark >= endKey) {
+			return nil, nil, errors.Errorf("bookmark [%s] is out of range", bookmark)
+		}
+		startKey = bookmark
+	}
+	iter := stub.rangeQuery(startKey, endKey, pageSize)
+	return iter, &QueryResponseMetadata{FetchedRecordsCount: int32(len(iter.kvs)), Bookmark: iter.next}, nil
+}
+
+// MockStateRangeQueryIterator is the StateQueryIteratorInterface returned by MockStub
+type MockStateRangeQueryIterator struct {
+	kvs     []*modules.KeyValue
+	next    string
+	current int
+	closed  bool
+}
+
+func (iter *MockStateRangeQueryIterator) HasNext() bool {
+	return !iter.closed && iter.current < len(iter.kvs)
+}
+
+func (iter *MockStateRangeQueryIterator) Next() (*modules.KeyValue, error) {
+	if iter.closed {
+		return nil, errors.New("iterator is closed")
+	}
+	if iter.current >= len(iter.kvs) {
+		return nil, errors.New("no such key")
+	}
+	kv := iter.kvs[iter.current]
+	iter.current++
+	return kv, nil
+}
+
+func (iter *MockStateRangeQueryIterator) Close() error {
+	iter.closed = true
+	return nil
+}
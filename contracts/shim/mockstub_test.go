@@ -0,0 +1,255 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package shim
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ ChaincodeStubInterface = (*MockStub)(nil)
+
+//escrowCC 测试用的用户合约：存入PTN，按记录的金额取回
+type escrowCC struct{}
+
+func (cc *escrowCC) Init(stub ChaincodeStubInterface) pb.Response {
+	return Success(nil)
+}
+
+func (cc *escrowCC) Invoke(stub ChaincodeStubInterface) pb.Response {
+	f, args := stub.GetFunctionAndParameters()
+	addr, _ := stub.GetInvokeAddress()
+	switch f {
+	case "deposit":
+		tokens, _ := stub.GetInvokeTokens()
+		var amount uint64
+		for _, token := range tokens {
+			amount += token.Amount
+		}
+		if err := stub.PutState("deposit-"+addr.String(), []byte(strconv.FormatUint(amount, 10))); err != nil {
+			return Error(err.Error())
+		}
+		if err := stub.SetEvent("deposit", []byte(addr.String())); err != nil {
+			return Error(err.Error())
+		}
+		return Success(nil)
+	case "withdraw":
+		data, _ := stub.GetState("deposit-" + addr.String())
+		amount, _ := strconv.ParseUint(string(data), 10, 64)
+		if len(args) > 0 { //取回指定的金额，用于测试余额不足
+			amount, _ = strconv.ParseUint(args[0], 10, 64)
+		}
+		if err := stub.PayOutToken(addr.String(), modules.NewAmountAsset(amount, modules.NewPTNAsset()),
+			0); err != nil {
+			return Error(err.Error())
+		}
+		if err := stub.DelState("deposit-" + addr.String()); err != nil {
+			return Error(err.Error())
+		}
+		return Success(nil)
+	case "putAndFail":
+		_ = stub.PutState("fail", []byte("1"))
+		return Error("fail")
+	}
+	return Error("unknown function " + f)
+}
+
+//callerCC 测试用的调用其他合约的用户合约：call [合约地址] [函数] [是否在调用后失败]
+type callerCC struct{}
+
+func (cc *callerCC) Init(stub ChaincodeStubInterface) pb.Response {
+	return Success(nil)
+}
+
+func (cc *callerCC) Invoke(stub ChaincodeStubInterface) pb.Response {
+	_, args := stub.GetFunctionAndParameters()
+	res := stub.InvokeChaincode(args[0], [][]byte{[]byte(args[1])}, "")
+	if res.Status != OK {
+		return res
+	}
+	if err := stub.PutState("called", []byte(args[1])); err != nil {
+		return Error(err.Error())
+	}
+	if len(args) > 2 && args[2] == "fail" {
+		return Error("caller failed after call")
+	}
+	return Success(nil)
+}
+
+func TestMockStub_InvokeChaincode(t *testing.T) {
+	calleeAddr := common.NewAddress([]byte("callee contract"), common.ContractHash)
+	callerAddr := common.NewAddress([]byte("caller contract"), common.ContractHash)
+	user := common.NewAddress([]byte("user"), common.PublicKeyHash)
+	callee := NewMockStub(calleeAddr, &escrowCC{})
+	callee.SetInvoker(user)
+	caller := NewMockStub(callerAddr, &callerCC{})
+	caller.SetInvoker(user)
+	caller.MockPeerChaincode(calleeAddr, callee)
+
+	//调用者失败时，被调用合约的写入被丢弃
+	res := caller.MockInvoke("", [][]byte{[]byte("call"), []byte(calleeAddr.String()), []byte("deposit"),
+		[]byte("fail")})
+	assert.NotEqual(t, int32(OK), res.Status)
+	v, _ := callee.GetState("deposit-" + callerAddr.String())
+	assert.Nil(t, v)
+	assert.Equal(t, 0, len(callee.Events()))
+
+	//被调用合约的请求地址为调用合约，测试设置的请求地址不变
+	res = caller.MockInvoke("", [][]byte{[]byte("call"), []byte(calleeAddr.String()), []byte("deposit")})
+	assert.Equal(t, int32(OK), res.Status, res.Message)
+	v, _ = callee.GetState("deposit-" + callerAddr.String())
+	assert.Equal(t, []byte("0"), v)
+	v, _ = callee.GetState("deposit-" + user.String())
+	assert.Nil(t, v)
+	assert.Equal(t, user, callee.invokeAddr)
+
+	//被调用合约失败时，调用者也失败
+	res = caller.MockInvoke("", [][]byte{[]byte("call"), []byte(calleeAddr.String()), []byte("putAndFail")})
+	assert.NotEqual(t, int32(OK), res.Status)
+	v, _ = caller.GetState("called")
+	assert.Equal(t, []byte("deposit"), v)
+	v, _ = callee.GetState("fail")
+	assert.Nil(t, v)
+}
+
+func TestMockStub_PayoutAndRollback(t *testing.T) {
+	contractAddr := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash)
+	alice := common.NewAddress(common.Hex2Bytes("00000000000000000000000000000000000a11ce"),
+		common.PublicKeyHash)
+	ptn := modules.NewPTNAsset()
+	stub := NewMockStub(contractAddr, &escrowCC{})
+	stub.FundAddress(alice, ptn, 1000)
+	stub.SetInvoker(alice)
+
+	//余额不足时调用失败，不扣款也不写状态
+	stub.SetInvokeTokens(&modules.InvokeTokens{Amount: 2000, Asset: ptn, Address: contractAddr.String()})
+	res := stub.MockInvoke("", [][]byte{[]byte("deposit")})
+	assert.Equal(t, int32(ERROR), res.Status)
+	assert.Equal(t, uint64(1000), stub.GetBalance(alice, ptn))
+
+	stub.SetInvokeTokens(&modules.InvokeTokens{Amount: 300, Asset: ptn, Address: contractAddr.String()})
+	res = stub.MockInvoke("", [][]byte{[]byte("deposit")})
+	assert.Equal(t, int32(OK), res.Status, res.Message)
+	assert.Equal(t, uint64(700), stub.GetBalance(alice, ptn))
+	assert.Equal(t, uint64(300), stub.GetBalance(contractAddr, ptn))
+	assert.Equal(t, 1, len(stub.Events()))
+	balance, _ := stub.GetTokenBalance("", ptn)
+	assert.Equal(t, uint64(300), balance[0].Amount)
+
+	//合约余额不足，整个交易回滚
+	res = stub.MockInvoke("", [][]byte{[]byte("withdraw"), []byte("500")})
+	assert.Equal(t, int32(ERROR), res.Status)
+	state, _ := stub.GetState("deposit-" + alice.String())
+	assert.Equal(t, "300", string(state))
+
+	res = stub.MockInvoke("", [][]byte{[]byte("withdraw")})
+	assert.Equal(t, int32(OK), res.Status, res.Message)
+	assert.Nil(t, stub.AssertPayout(alice, ptn, 300))
+	assert.NotNil(t, stub.AssertPayout(alice, ptn, 200))
+	assert.Equal(t, uint64(1000), stub.GetBalance(alice, ptn))
+	state, _ = stub.GetState("deposit-" + alice.String())
+	assert.Nil(t, state)
+
+	//执行失败时写入的状态被丢弃
+	res = stub.MockInvoke("", [][]byte{[]byte("putAndFail")})
+	assert.Equal(t, int32(ERROR), res.Status)
+	state, _ = stub.GetState("fail")
+	assert.Nil(t, state)
+}
+
+func TestMockStub_RangeQuery(t *testing.T) {
+	contractAddr := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash)
+	stub := NewMockStub(contractAddr, &escrowCC{})
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		stub.SetContractState(contractAddr, k, []byte(k))
+	}
+	iter, err := stub.GetStateByRange("b", "e")
+	assert.Nil(t, err)
+	keys := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		assert.Nil(t, err)
+		keys = append(keys, kv.Key)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, keys)
+
+	iter, meta, err := stub.GetStateByRangeWithPagination("", "", 2, "")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), meta.FetchedRecordsCount)
+	assert.Equal(t, "c", meta.Bookmark)
+	_, meta, err = stub.GetStateByRangeWithPagination("", "", 2, meta.Bookmark)
+	assert.Nil(t, err)
+	assert.Equal(t, "e", meta.Bookmark)
+	_ = iter.Close()
+	assert.False(t, iter.HasNext())
+}
+
+func TestMockStub_AdvanceHeightAndTime(t *testing.T) {
+	contractAddr := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash)
+	stub := NewMockStub(contractAddr, &escrowCC{})
+	start := stub.Timestamp()
+	interval := int64(stub.gp.ChainParameters.MediatorInterval)
+
+	_, err := stub.GetStableUnit("", 10)
+	assert.NotNil(t, err)
+	stub.AdvanceHeight(10)
+	assert.Equal(t, uint64(10), stub.Height())
+	unit, err := stub.GetStableUnit("", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, start+10*interval, unit.Timestamp())
+	byHash, err := stub.GetStableUnit(unit.Hash().String(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), byHash.NumberU64())
+
+	stub.AdvanceTime(time.Hour)
+	ts, _ := stub.GetTxTimestamp(1)
+	assert.Equal(t, start+10*interval+3600, ts.Seconds)
+	ts, _ = stub.GetTxTimestamp(10)
+	assert.Equal(t, start+10*interval, ts.Seconds)
+}
+
+func TestMockStub_GetRandomness(t *testing.T) {
+	contractAddr := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash)
+	stub := NewMockStub(contractAddr, &escrowCC{})
+	stub.AdvanceHeight(3)
+
+	_, err := stub.GetRandomness(0)
+	assert.NotNil(t, err)
+	r1, err := stub.GetRandomness(1)
+	assert.Nil(t, err)
+	r2, err := stub.GetRandomness(2)
+	assert.Nil(t, err)
+	assert.NotEqual(t, r1, r2)
+	again, _ := stub.GetRandomness(2)
+	assert.Equal(t, r2, again)
+	//单元还不存在
+	_, err = stub.GetRandomness(5)
+	assert.NotNil(t, err)
+}
@@ -61,6 +61,10 @@ var (
     //10Token互换合约
 	//PCGTta3M4t3yXu8uRgkKvaWd2d8DS36t3ba
 	ExchangeContractAddress = common.HexToAddress("0x000000000000000000000000000000000000000A1C")
+
+	//11定时调用合约
+	//PCGTta3M4t3yXu8uRgkKvaWd2d8DSDC6K99
+	SchedulerContractAddress = common.HexToAddress("0x000000000000000000000000000000000000000B1C")
 	
 	//15测试调试用
 	//PCGTta3M4t3yXu8uRgkKvaWd2d8DSfQdUHf
@@ -182,6 +182,15 @@ func (d *DepositChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response
 		}
 		return d.ApplyForForfeitureDeposit(stub, args[0], args[1], args[2])
 	//
+	//  提交Mediator作恶证据
+	case modules.SubmitEquivocationEvidence:
+		log.Info("Enter DepositChaincode Contract " + modules.SubmitEquivocationEvidence + " Invoke")
+		if len(args) != 1 {
+			log.Error("args need one parameter")
+			return shim.Error("args need one parameter")
+		}
+		return d.SubmitEquivocationEvidence(stub, args[0])
+	//
 	//  获取Mediator申请加入列表
 	case modules.GetBecomeMediatorApplyList:
 		log.Info("Enter DepositChaincode Contract " + modules.GetBecomeMediatorApplyList + " Query")
@@ -753,6 +762,11 @@ func (d *DepositChaincode) HandleForForfeitureApplication(stub shim.ChaincodeStu
 	return handleForForfeitureApplication(stub, address, okOrNo)
 }
 
+//  提交Mediator作恶证据，没收保证金
+func (d *DepositChaincode) SubmitEquivocationEvidence(stub shim.ChaincodeStubInterface, evidence string) pb.Response {
+	return submitEquivocationEvidence(stub, evidence)
+}
+
 //  移除超级节点同意列表
 func (d DepositChaincode) HandleNodeRemoveFromAgreeList(stub shim.ChaincodeStubInterface, address string) pb.Response {
 	return hanldeNodeRemoveFromAgreeList(stub, address)
@@ -0,0 +1,102 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package deposit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/contracts/shim"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/dagconfig"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//  提交Mediator作恶证据，证据自身可验证，任何人都可以提交，不需要基金会投票
+//  验证通过后没收该Mediator的全部保证金给基金会，并从候选列表中移除，下一个维护周期不再当选
+func submitEquivocationEvidence(stub shim.ChaincodeStubInterface, evidenceJson string) pb.Response {
+	log.Info("SubmitEquivocationEvidence")
+	evidence := &modules.MediatorEquivocationEvidence{}
+	err := json.Unmarshal([]byte(evidenceJson), evidence)
+	if err != nil {
+		return shim.Error("invalid evidence: " + err.Error())
+	}
+	err = evidence.Validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	//  同一份证据只处理一次
+	handled, err := stub.GetState(evidence.Key())
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(handled) != 0 {
+		return shim.Error("evidence has been handled: " + evidence.Hash().String())
+	}
+	mediator := evidence.Mediator().String()
+	err = slashMediatorDeposit(stub, mediator)
+	if err != nil {
+		log.Errorf("slash mediator[%s] deposit err: %s", mediator, err.Error())
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(evidence.Key(), []byte(mediator))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+//  没收mediator保证金
+func slashMediatorDeposit(stub shim.ChaincodeStubInterface, mediator string) error {
+	md, err := getMediatorDeposit(stub, mediator)
+	if err != nil {
+		return err
+	}
+	if md == nil {
+		return fmt.Errorf("mediator %s has no deposit", mediator)
+	}
+	if md.Balance == 0 {
+		return fmt.Errorf("mediator %s deposit has been slashed or withdrawn", mediator)
+	}
+	gp, err := stub.GetSystemConfig()
+	if err != nil {
+		return err
+	}
+	gasToken := dagconfig.DagConfig.GetGasToken().ToAsset()
+	err = stub.PayOutToken(gp.ChainParameters.FoundationAddress, modules.NewAmountAsset(md.Balance, gasToken), 0)
+	if err != nil {
+		return err
+	}
+
+	//  移除列表，mediator同时也是jury，不在列表中时忽略
+	for _, candidate := range []string{modules.MediatorList, modules.JuryList} {
+		list, err := getList(stub, candidate)
+		if err != nil {
+			return err
+		}
+		if _, ok := list[mediator]; ok {
+			delete(list, mediator)
+			err = saveList(stub, candidate, list)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	md.Status = modules.Quited
+	md.Balance = 0
+	return saveMediatorDeposit(stub, mediator, md)
+}
@@ -0,0 +1,84 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package deposit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/dag/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func signedHeader(key []byte, parent common.Hash, index uint64, t int64) *modules.Header {
+	pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(key)
+	h := modules.NewHeader([]common.Hash{parent}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{},
+		[]uint16{}, modules.PTNCOIN, index, t)
+	sig, _ := crypto.MyCryptoLib.Sign(key, h.HashWithoutAuthor().Bytes())
+	h.SetAuthor(modules.Authentifier{PubKey: pubKey, Signature: sig})
+	return h
+}
+
+func TestSubmitEquivocationEvidence(t *testing.T) {
+	key, _ := crypto.MyCryptoLib.KeyGen()
+	a := signedHeader(key, common.HexToHash("0x01"), 10, 1574390000)
+	b := signedHeader(key, common.HexToHash("0x02"), 10, 1574390000)
+	mediator := a.Author()
+	foundation := common.NewAddress(common.Hex2Bytes("00000000000000000000000000000000000f0dd0"),
+		common.PublicKeyHash)
+	reporter := common.NewAddress(common.Hex2Bytes("00000000000000000000000000000000000a11ce"),
+		common.PublicKeyHash)
+	ptn := modules.NewPTNAsset()
+
+	stub := shim.NewMockStub(syscontract.DepositContractAddress, &DepositChaincode{})
+	gp := modules.NewGlobalProp()
+	gp.ChainParameters.FoundationAddress = foundation.String()
+	stub.SetGlobalProperty(gp)
+	stub.SetInvoker(reporter)
+	stub.FundAddress(syscontract.DepositContractAddress, ptn, 10000)
+	md := modules.NewMediatorDeposit()
+	md.Status = modules.Agree
+	md.Balance = 10000
+	data, _ := json.Marshal(md)
+	stub.SetContractState(syscontract.DepositContractAddress, storage.MediatorDepositKey(mediator.String()), data)
+	list, _ := json.Marshal(map[string]bool{mediator.String(): true, reporter.String(): true})
+	stub.SetContractState(syscontract.DepositContractAddress, modules.MediatorList, list)
+
+	//无效的证据
+	invalid, _ := json.Marshal(modules.NewMediatorEquivocationEvidence(a, a))
+	res := stub.MockInvoke("", [][]byte{[]byte(modules.SubmitEquivocationEvidence), invalid})
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+
+	evidence, _ := json.Marshal(modules.NewMediatorEquivocationEvidence(a, b))
+	res = stub.MockInvoke("", [][]byte{[]byte(modules.SubmitEquivocationEvidence), evidence})
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	assert.Nil(t, stub.AssertPayout(foundation, ptn, 10000))
+	slashed, err := getMediatorDeposit(stub, mediator.String())
+	assert.Nil(t, err)
+	assert.Equal(t, modules.Quited, slashed.Status)
+	assert.Equal(t, uint64(0), slashed.Balance)
+	candidates, _ := getList(stub, modules.MediatorList)
+	assert.False(t, candidates[mediator.String()])
+	assert.True(t, candidates[reporter.String()])
+
+	//同一份证据不能重复提交
+	res = stub.MockInvoke("", [][]byte{[]byte(modules.SubmitEquivocationEvidence), evidence})
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+}
@@ -0,0 +1,65 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package v2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	dm "github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPRC20_MockStub(t *testing.T) {
+	creator := common.NewAddress(common.Hex2Bytes("00000000000000000000000000000000000a11ce"),
+		common.PublicKeyHash)
+	stub := shim.NewMockStub(syscontract.CreateTokenContractAddress, &PRC20{})
+	stub.SetInvoker(creator)
+
+	res := stub.MockInvoke("", [][]byte{[]byte("createToken"), []byte("Test Token"), []byte("TST"), []byte("2"),
+		[]byte("1000"), []byte(creator.String())})
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	//重复的Symbol创建失败
+	res = stub.MockInvoke("", [][]byte{[]byte("createToken"), []byte("Test Token"), []byte("TST"), []byte("2"),
+		[]byte("1000")})
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+
+	res = stub.MockInvoke("", [][]byte{[]byte("getTokenInfo"), []byte("TST")})
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	info := &tokenIDInfo{}
+	assert.Nil(t, json.Unmarshal(res.Payload, info))
+	asset, err := dm.StringToAsset(info.AssetID)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100000), stub.GetBalance(creator, asset))
+
+	res = stub.MockInvoke("", [][]byte{[]byte("supplyToken"), []byte("TST"), []byte("5")})
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	assert.Equal(t, uint64(100500), stub.GetBalance(creator, asset))
+
+	//非增发地址不能增发
+	stub.SetInvoker(common.NewAddress(common.Hex2Bytes("0000000000000000000000000000000000000b0b"),
+		common.PublicKeyHash))
+	res = stub.MockInvoke("", [][]byte{[]byte("supplyToken"), []byte("TST"), []byte("5")})
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	assert.Equal(t, uint64(100500), stub.GetBalance(creator, asset))
+}
@@ -0,0 +1,50 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPRC721_MockStub(t *testing.T) {
+	creator := common.NewAddress(common.Hex2Bytes("00000000000000000000000000000000000a11ce"),
+		common.PublicKeyHash)
+	stub := shim.NewMockStub(syscontract.CreateToken721ContractAddress, &PRC721{})
+	stub.SetInvoker(creator)
+
+	metas := `[{"TokenID":"","MetaData":"first"},{"TokenID":"","MetaData":"second"}]`
+	res := stub.MockInvoke("", [][]byte{[]byte("createToken"), []byte("Test NFT"), []byte("NFT"), []byte("1"),
+		[]byte("2"), []byte(metas)})
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+
+	//每个TokenID是一个独立的资产，全部发给创建者
+	balances, err := stub.GetTokenBalance(creator.String(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(balances))
+	for _, b := range balances {
+		assert.Equal(t, uint64(1), b.Amount)
+		assert.Equal(t, "NFT", b.Asset.AssetId.GetSymbol())
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+//Package schedulercc 定时调用系统合约，用户预付手续费注册定时任务，
+//Mediator为到期的任务触发一次运行并发起合约调用请求，由陪审员像普通请求一样执行，
+//调用执行成功并稳定后Mediator再结算，本次运行的手续费在结算时才支付给Mediator
+package schedulercc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/contracts/shim"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	pb "github.com/palletone/go-palletone/core/vmContractPub/protos/peer"
+	"github.com/palletone/go-palletone/dag/dagconfig"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+//PCGTta3M4t3yXu8uRgkKvaWd2d8DSDC6K99
+type SchedulerMgr struct {
+}
+
+func (p *SchedulerMgr) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (p *SchedulerMgr) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	f, args := stub.GetFunctionAndParameters()
+
+	switch f {
+	case "register": //注册定时任务
+		if len(args) < 7 {
+			return shim.Error("must input at least 7 args: [ContractAddress][TriggerHeight][TriggerTime]" +
+				"[IntervalRounds][Times][FeePerRun][Function][Args...]")
+		}
+		schedule, err := parseSchedule(args)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		id, err := p.Register(stub, schedule)
+		if err != nil {
+			return shim.Error("Register error:" + err.Error())
+		}
+		return shim.Success([]byte(id))
+	case "cancel": //撤销定时任务，退回剩余的预付手续费
+		if len(args) != 1 {
+			return shim.Error("must input 1 args: [ScheduleId]")
+		}
+		err := p.Cancel(stub, args[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "trigger": //Mediator触发到期的任务
+		if len(args) != 1 {
+			return shim.Error("must input 1 args: [ScheduleId]")
+		}
+		err := p.Trigger(stub, args[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "settle": //调用执行成功后Mediator结算本次运行
+		if len(args) != 2 {
+			return shim.Error("must input 2 args: [ScheduleId][InvokeTxHash]")
+		}
+		err := p.Settle(stub, args[0], args[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "getSchedule":
+		if len(args) != 1 {
+			return shim.Error("must input 1 args: [ScheduleId]")
+		}
+		result, err := getSchedule(stub, args[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		data, _ := json.Marshal(result)
+		return shim.Success(data)
+	case "getSchedules":
+		result, err := getAllSchedules(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		data, _ := json.Marshal(result)
+		return shim.Success(data)
+	default:
+		jsonResp := "{\"Error\":\"Unknown function " + f + "\"}"
+		return shim.Error(jsonResp)
+	}
+}
+
+func parseSchedule(args []string) (*modules.ContractSchedule, error) {
+	contractAddr, err := common.StringToAddress(args[0])
+	if err != nil {
+		return nil, errors.New("Invalid contract address:" + args[0])
+	}
+	if !common.IsUserContractId(contractAddr.Bytes()) {
+		return nil, errors.New("only user contract can be scheduled:" + args[0])
+	}
+	height, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("Invalid trigger height:" + args[1])
+	}
+	tm, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return nil, errors.New("Invalid trigger time:" + args[2])
+	}
+	if height == 0 && tm <= 0 {
+		return nil, errors.New("trigger height or trigger time must be set")
+	}
+	interval, err := strconv.ParseUint(args[3], 10, 32)
+	if err != nil {
+		return nil, errors.New("Invalid interval rounds:" + args[3])
+	}
+	times, err := strconv.ParseUint(args[4], 10, 32)
+	if err != nil || times == 0 {
+		return nil, errors.New("Invalid times:" + args[4])
+	}
+	if interval == 0 && times != 1 {
+		return nil, errors.New("interval rounds must be set when times is greater than 1")
+	}
+	fee, err := strconv.ParseUint(args[5], 10, 64)
+	if err != nil || fee == 0 {
+		return nil, errors.New("Invalid fee per run:" + args[5])
+	}
+	if args[6] == "" {
+		return nil, errors.New("function name is empty")
+	}
+	return &modules.ContractSchedule{
+		ContractAddr:   contractAddr.String(),
+		TriggerHeight:  height,
+		TriggerTime:    tm,
+		IntervalRounds: uint32(interval),
+		RemainTimes:    uint32(times),
+		FeePerRun:      fee,
+		Function:       args[6],
+		Args:           args[7:],
+	}, nil
+}
+
+//Register 注册定时任务，调用时支付给本合约的PTN作为预付手续费，至少要够支付所有次数的调用。
+//只有目标合约的创建者可以注册，由验证交易时检查请求者，避免任何人都能让Mediator调用别人的合约
+func (p *SchedulerMgr) Register(stub shim.ChaincodeStubInterface, schedule *modules.ContractSchedule) (string,
+	error) {
+	addr, err := stub.GetInvokeAddress()
+	if err != nil {
+		return "", errors.New("Invalid address string:" + err.Error())
+	}
+	amount, err := getPayToContract(stub)
+	if err != nil {
+		return "", err
+	}
+	if amount/uint64(schedule.RemainTimes) < schedule.FeePerRun {
+		return "", fmt.Errorf("prepaid fee %d not enough for %d runs", amount, schedule.RemainTimes)
+	}
+	schedule.Id = stub.GetTxID()
+	schedule.Creator = addr.String()
+	schedule.Balance = amount
+	return schedule.Id, saveSchedule(stub, schedule)
+}
+
+//Cancel 创建者撤销定时任务，正在运行的任务要等结算或者超时后才能撤销
+func (p *SchedulerMgr) Cancel(stub shim.ChaincodeStubInterface, id string) error {
+	addr, err := stub.GetInvokeAddress()
+	if err != nil {
+		return errors.New("Invalid address string:" + err.Error())
+	}
+	schedule, err := getSchedule(stub, id)
+	if err != nil {
+		return err
+	}
+	if schedule.Creator != addr.String() {
+		return errors.New("only creator can cancel the schedule")
+	}
+	ts, err := stub.GetTxTimestamp(1)
+	if err != nil {
+		return err
+	}
+	if schedule.IsPending(ts.Seconds) {
+		return fmt.Errorf("schedule[%s] is running", id)
+	}
+	return closeSchedule(stub, schedule)
+}
+
+//Trigger 只有活跃Mediator可以触发到期的任务，记录本次运行的触发者，不支付手续费。
+//触发后超时没有结算的任务可以被重新触发，之前的调用请求不能再用于结算
+func (p *SchedulerMgr) Trigger(stub shim.ChaincodeStubInterface, id string) error {
+	addr, err := stub.GetInvokeAddress()
+	if err != nil {
+		return errors.New("Invalid address string:" + err.Error())
+	}
+	gp, err := stub.GetSystemConfig()
+	if err != nil {
+		return errors.New("fail to get system config err")
+	}
+	if !gp.ActiveMediators[addr] {
+		return errors.New("only active mediator can trigger the schedule")
+	}
+	schedule, err := getSchedule(stub, id)
+	if err != nil {
+		return err
+	}
+	ts, err := stub.GetTxTimestamp(1)
+	if err != nil {
+		return err
+	}
+	if !isDue(stub, schedule, ts.Seconds) {
+		return fmt.Errorf("schedule[%s] is not due", id)
+	}
+	if schedule.Balance < schedule.FeePerRun {
+		return fmt.Errorf("schedule[%s] balance not enough", id)
+	}
+	if schedule.IsPending(ts.Seconds) {
+		return fmt.Errorf("schedule[%s] is running", id)
+	}
+	schedule.Pending = addr.String()
+	schedule.PendingReq = stub.GetTxID()
+	schedule.PendingTime = ts.Seconds
+	return saveSchedule(stub, schedule)
+}
+
+//Settle 本次运行的调用请求执行成功并稳定后，触发的Mediator凭调用交易结算，手续费此时才支付给Mediator
+func (p *SchedulerMgr) Settle(stub shim.ChaincodeStubInterface, id, invokeTxHash string) error {
+	addr, err := stub.GetInvokeAddress()
+	if err != nil {
+		return errors.New("Invalid address string:" + err.Error())
+	}
+	schedule, err := getSchedule(stub, id)
+	if err != nil {
+		return err
+	}
+	if schedule.Pending != addr.String() {
+		return errors.New("only the triggering mediator can settle the schedule")
+	}
+	tx, err := stub.GetStableTransactionByHash(invokeTxHash)
+	if err != nil {
+		return fmt.Errorf("invoke tx[%s] not stable:%s", invokeTxHash, err.Error())
+	}
+	if !schedule.IsScheduledInvokeOk(tx) {
+		return fmt.Errorf("tx[%s] is not a successful invoke of schedule[%s]", invokeTxHash, id)
+	}
+	gp, err := stub.GetSystemConfig()
+	if err != nil {
+		return errors.New("fail to get system config err")
+	}
+	ts, err := stub.GetTxTimestamp(1)
+	if err != nil {
+		return err
+	}
+	gasToken := dagconfig.DagConfig.GetGasToken().ToAsset()
+	err = stub.PayOutToken(addr.String(), modules.NewAmountAsset(schedule.FeePerRun, gasToken), 0)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(ts.Seconds, gp.ChainParameters.MaintenanceInterval) {
+		return closeSchedule(stub, schedule)
+	}
+	return saveSchedule(stub, schedule)
+}
+
+//isDue 按高度触发的任务要求该高度的单元已经稳定，保证所有节点的判断一致
+func isDue(stub shim.ChaincodeStubInterface, schedule *modules.ContractSchedule, now int64) bool {
+	if schedule.IsTimeDue(now) {
+		return true
+	}
+	if schedule.TriggerHeight > 0 {
+		unit, err := stub.GetStableUnit("", schedule.TriggerHeight)
+		return err == nil && unit != nil
+	}
+	return false
+}
+
+func getPayToContract(stub shim.ChaincodeStubInterface) (uint64, error) {
+	invokeTokens, err := stub.GetInvokeTokens()
+	if err != nil {
+		return 0, err
+	}
+	gasToken := dagconfig.DagConfig.GetGasToken().ToAsset()
+	for _, invokeTo := range invokeTokens {
+		if invokeTo.Address == syscontract.SchedulerContractAddress.String() {
+			if !invokeTo.Asset.Equal(gasToken) {
+				return 0, errors.New("prepaid fee must be " + gasToken.String())
+			}
+			return invokeTo.Amount, nil
+		}
+	}
+	return 0, errors.New("no pay to contract token")
+}
+
+//closeSchedule 删除任务并把剩余的预付手续费退回创建者
+func closeSchedule(stub shim.ChaincodeStubInterface, schedule *modules.ContractSchedule) error {
+	if schedule.Balance > 0 {
+		gasToken := dagconfig.DagConfig.GetGasToken().ToAsset()
+		err := stub.PayOutToken(schedule.Creator, modules.NewAmountAsset(schedule.Balance, gasToken), 0)
+		if err != nil {
+			return err
+		}
+	}
+	return stub.DelState(schedule.Key())
+}
+
+func saveSchedule(stub shim.ChaincodeStubInterface, schedule *modules.ContractSchedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(schedule.Key(), data)
+}
+
+func getSchedule(stub shim.ChaincodeStubInterface, id string) (*modules.ContractSchedule, error) {
+	data, err := stub.GetState(modules.ContractSchedulePrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("schedule not found:" + id)
+	}
+	schedule := &modules.ContractSchedule{}
+	err = json.Unmarshal(data, schedule)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func getAllSchedules(stub shim.ChaincodeStubInterface) ([]*modules.ContractSchedule, error) {
+	kvs, err := stub.GetStateByPrefix(modules.ContractSchedulePrefix)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*modules.ContractSchedule, 0, len(kvs))
+	for _, kv := range kvs {
+		schedule := &modules.ContractSchedule{}
+		err = json.Unmarshal(kv.Value, schedule)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, schedule)
+	}
+	return result, nil
+}
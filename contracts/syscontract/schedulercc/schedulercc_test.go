@@ -0,0 +1,53 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package schedulercc
+
+import (
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	userContract := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash).String()
+	s, err := parseSchedule([]string{userContract, "0", "1570000000", "2", "3", "100", "put", "a", "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, userContract, s.ContractAddr)
+	assert.Equal(t, int64(1570000000), s.TriggerTime)
+	assert.Equal(t, uint32(2), s.IntervalRounds)
+	assert.Equal(t, uint32(3), s.RemainTimes)
+	assert.Equal(t, uint64(100), s.FeePerRun)
+	assert.Equal(t, []string{"a", "1"}, s.Args)
+
+	//系统合约不能被定时调用
+	_, err = parseSchedule([]string{syscontract.SchedulerContractAddress.String(), "10", "0", "0", "1", "100",
+		"trigger"})
+	assert.NotNil(t, err)
+	//没有设置触发高度或时间
+	_, err = parseSchedule([]string{userContract, "0", "0", "0", "1", "100", "put"})
+	assert.NotNil(t, err)
+	//多次调用必须设置间隔
+	_, err = parseSchedule([]string{userContract, "10", "0", "0", "2", "100", "put"})
+	assert.NotNil(t, err)
+}
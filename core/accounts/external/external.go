@@ -0,0 +1,232 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+// Package external implements an accounts backend which keeps the private keys
+// in a separate local signer process, reached over IPC/JSON-RPC.
+package external
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	ethereum "github.com/palletone/go-palletone"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/event"
+	"github.com/palletone/go-palletone/common/hexutil"
+	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/core/accounts"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+// ExternalScheme is the protocol scheme prefixing account and wallet URLs.
+const ExternalScheme = "extsigner"
+
+// Timeout of one request to the signer process
+const requestTimeout = 30 * time.Second
+
+// ExternalSigner is both an accounts.Backend and the single accounts.Wallet of
+// this backend. Every signing request is forwarded to the signer process, which
+// owns the keys and decides by its policy whether the request is allowed.
+type ExternalSigner struct {
+	endpoint string
+	client   *rpc.Client
+
+	mu      sync.RWMutex
+	pubKeys map[common.Address][]byte //公钥缓存，签名者的账户不会变化
+
+	updateFeed  event.Feed
+	updateScope event.SubscriptionScope
+}
+
+// NewExternalSigner connects to the signer process listening on endpoint, which
+// can be an IPC path or a http/ws url.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newExternalSigner(endpoint, client), nil
+}
+
+func newExternalSigner(endpoint string, client *rpc.Client) *ExternalSigner {
+	return &ExternalSigner{
+		endpoint: endpoint,
+		client:   client,
+		pubKeys:  make(map[common.Address][]byte),
+	}
+}
+
+func (s *ExternalSigner) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return s.client.CallContext(ctx, result, method, args...)
+}
+
+// Wallets implements accounts.Backend, the signer process is presented as a
+// single wallet containing all of its accounts.
+func (s *ExternalSigner) Wallets() []accounts.Wallet {
+	return []accounts.Wallet{s}
+}
+
+// Subscribe implements accounts.Backend. The wallet of the signer never arrives
+// or drops after it is created, so no event will be sent.
+func (s *ExternalSigner) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return s.updateScope.Track(s.updateFeed.Subscribe(sink))
+}
+
+// URL implements accounts.Wallet.
+func (s *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: ExternalScheme, Path: s.endpoint}
+}
+
+// Status implements accounts.Wallet, returning whether the signer process can
+// be reached.
+func (s *ExternalSigner) Status() (string, error) {
+	var addrs []common.Address
+	if err := s.call(&addrs, "signer_accounts"); err != nil {
+		return "Disconnected", err
+	}
+	return "Connected", nil
+}
+
+// Open implements accounts.Wallet, but is a noop since the connection is
+// established when the signer is created.
+func (s *ExternalSigner) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, closing the connection to the signer process.
+func (s *ExternalSigner) Close() error {
+	s.updateScope.Close()
+	s.client.Close()
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts the signer process
+// is able to sign for.
+func (s *ExternalSigner) Accounts() []accounts.Account {
+	var addrs []common.Address
+	if err := s.call(&addrs, "signer_accounts"); err != nil {
+		return nil
+	}
+	result := make([]accounts.Account, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, accounts.Account{Address: addr, URL: s.URL()})
+	}
+	return result
+}
+
+// Contains implements accounts.Wallet.
+func (s *ExternalSigner) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != s.URL() {
+		return false
+	}
+	return s.HasAddress(account.Address)
+}
+
+// Derive implements accounts.Wallet, but is not supported by the signer.
+func (s *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for the signer.
+func (s *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// SignMessage implements accounts.Wallet, requesting the signer process to sign
+// the message with the given account.
+func (s *ExternalSigner) SignMessage(account accounts.Account, msg []byte) ([]byte, error) {
+	if account.URL != (accounts.URL{}) && account.URL != s.URL() {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return s.SignData(account.Address, msg)
+}
+
+// SignTx implements accounts.Wallet, but is not supported, transactions are
+// signed input by input through SignMessage.
+func (s *ExternalSigner) SignTx(account accounts.Account, tx *modules.Transaction,
+	chainID *big.Int) (*modules.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignMessageWithPassphrase implements accounts.Wallet, but is not supported,
+// the signer process does not accept passphrases from the node.
+func (s *ExternalSigner) SignMessageWithPassphrase(account accounts.Account, passphrase string,
+	msg []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported.
+func (s *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string,
+	tx *modules.Transaction, chainID *big.Int) (*modules.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// HasAddress reports whether the signer process holds the key of the address.
+func (s *ExternalSigner) HasAddress(addr common.Address) bool {
+	_, err := s.GetPublicKey(addr)
+	return err == nil
+}
+
+// GetPublicKey returns the public key of the address from the signer process.
+func (s *ExternalSigner) GetPublicKey(addr common.Address) ([]byte, error) {
+	s.mu.RLock()
+	pubKey, ok := s.pubKeys[addr]
+	s.mu.RUnlock()
+	if ok {
+		return pubKey, nil
+	}
+	var result hexutil.Bytes
+	if err := s.call(&result, "signer_publicKey", addr); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.pubKeys[addr] = result
+	s.mu.Unlock()
+	return result, nil
+}
+
+// SignTxInput requests the signer process to sign an input of the transaction.
+// The whole transaction is sent, so the signer checks its policy against all
+// the outputs and the hash type instead of the signature data.
+func (s *ExternalSigner) SignTxInput(addr common.Address, tx *modules.Transaction, hashType uint32, msgIdx,
+	inputIdx int, subScript []byte) ([]byte, error) {
+	rawTx, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Bytes
+	err = s.call(&result, "signer_signTxInput", addr, hexutil.Bytes(rawTx), hashType, msgIdx, inputIdx,
+		hexutil.Bytes(subScript))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SignData requests the signer process to sign msg with the key of the address,
+// the signer may reject it by its policy.
+func (s *ExternalSigner) SignData(addr common.Address, msg []byte) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := s.call(&result, "signer_signMessage", addr, hexutil.Bytes(msg)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package external
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/core/accounts"
+	"github.com/palletone/go-palletone/core/accounts/keystore"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+	"github.com/stretchr/testify/assert"
+)
+
+// 启动一个进程内的签名者，返回节点使用的keystore（不包含私钥）和签名者的账户
+func newTestSigner(t *testing.T, policy *Policy) (*keystore.KeyStore, common.Address, func()) {
+	signerDir, _ := ioutil.TempDir("", "signer-keystore")
+	nodeDir, _ := ioutil.TempDir("", "node-keystore")
+	signerKs := keystore.NewKeyStore(signerDir, keystore.LightScryptN, keystore.LightScryptP)
+	acc, err := signerKs.NewAccount("1")
+	assert.Nil(t, err)
+	assert.Nil(t, signerKs.Unlock(acc, "1"))
+
+	server := rpc.NewServer()
+	assert.Nil(t, server.RegisterName("signer", NewSignerAPI(signerKs, policy)))
+	signer := newExternalSigner("inproc", rpc.DialInProc(server))
+
+	nodeKs := keystore.NewKeyStore(nodeDir, keystore.LightScryptN, keystore.LightScryptP)
+	nodeKs.SetExternalSigner(signer)
+	tokenengine.SetTxInputSigner(nodeKs)
+	return nodeKs, acc.Address, func() {
+		tokenengine.SetTxInputSigner(nil)
+		signer.Close()
+		server.Stop()
+		os.RemoveAll(signerDir)
+		os.RemoveAll(nodeDir)
+	}
+}
+
+func buildTestTx(from, to common.Address, amount, change uint64) (*modules.Transaction, map[modules.OutPoint][]byte) {
+	fromLock := tokenengine.Instance.GenerateLockScript(from)
+	outPoint := modules.NewOutPoint(common.HexToHash("0x1111870aa8c894376dbd960a22171d0ad7be057a730e14d7103ed4a6dbb34873"), 0, 0)
+	pay := &modules.PaymentPayload{}
+	pay.AddTxIn(modules.NewTxIn(outPoint, nil))
+	pay.AddTxOut(modules.NewTxOut(amount, tokenengine.Instance.GenerateLockScript(to), modules.NewPTNAsset()))
+	pay.AddTxOut(modules.NewTxOut(change, fromLock, modules.NewPTNAsset()))
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, pay)})
+	return tx, map[modules.OutPoint][]byte{*outPoint: fromLock}
+}
+
+func signTestTx(ks *keystore.KeyStore, tx *modules.Transaction, lockScripts map[modules.OutPoint][]byte) error {
+	return signTestTxWithHashType(ks, tx, lockScripts, tokenengine.SigHashAll)
+}
+
+func signTestTxWithHashType(ks *keystore.KeyStore, tx *modules.Transaction,
+	lockScripts map[modules.OutPoint][]byte, hashType uint32) error {
+	_, err := tokenengine.Instance.SignTxAllPaymentInput(tx, hashType, lockScripts, nil,
+		ks.GetPublicKey, ks.SignMessage)
+	return err
+}
+
+func TestExternalSigner(t *testing.T) {
+	ks, addr, stop := newTestSigner(t, nil)
+	defer stop()
+
+	//节点的keystore透明地使用签名者的账户
+	assert.True(t, ks.IsUnlock(addr))
+	assert.Nil(t, ks.TimedUnlock(accounts.Account{Address: addr}, "wrong", 0))
+	pubKey, err := ks.GetPublicKey(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, addr, crypto.PubkeyBytesToAddress(pubKey))
+	other := common.NewAddress(crypto.Hash160([]byte("other")), common.PublicKeyHash)
+	assert.False(t, ks.IsUnlock(other))
+
+	tx, lockScripts := buildTestTx(addr, other, 100, 50)
+	assert.Nil(t, signTestTx(ks, tx, lockScripts))
+	for _, lockScript := range lockScripts {
+		assert.Nil(t, tokenengine.Instance.ScriptValidate(lockScript, nil, tx, 0, 0))
+	}
+
+	//没有策略时不允许为Unit签名
+	header := modules.NewHeader(nil, common.Hash{}, nil, nil, nil, nil, nil, modules.NewPTNIdType(), 1, 0)
+	_, err = ks.SigUnit(header, addr)
+	assert.NotNil(t, err)
+}
+
+func TestExternalSignerPolicy(t *testing.T) {
+	allowed := common.NewAddress(crypto.Hash160([]byte("allowed")), common.PublicKeyHash)
+	denied := common.NewAddress(crypto.Hash160([]byte("denied")), common.PublicKeyHash)
+	policy := &Policy{
+		AllowedRecipients: []string{allowed.String()},
+		DailyLimits:       map[string]uint64{modules.NewPTNAsset().String(): 1000},
+		AllowUnitSign:     true,
+	}
+	assert.Nil(t, policy.init())
+	ks, addr, stop := newTestSigner(t, policy)
+	defer stop()
+
+	tx, lockScripts := buildTestTx(addr, denied, 100, 50)
+	assert.NotNil(t, signTestTx(ks, tx, lockScripts), "recipient is not allowed")
+
+	tx, lockScripts = buildTestTx(addr, allowed, 600, 10000)
+	assert.Nil(t, signTestTx(ks, tx, lockScripts), "change is not counted")
+	//同一个交易重新签名不重复计算限额
+	tx2, _ := buildTestTx(addr, allowed, 600, 10000)
+	assert.Nil(t, signTestTx(ks, tx2, lockScripts))
+	tx3, _ := buildTestTx(addr, allowed, 500, 10000)
+	assert.NotNil(t, signTestTx(ks, tx3, lockScripts), "daily limit exceeded")
+	tx4, _ := buildTestTx(addr, allowed, 400, 10000)
+	assert.Nil(t, signTestTx(ks, tx4, lockScripts))
+
+	//SigHashNone和SigHashSingle的签名不约束所有输出，有策略时不允许
+	tx5, _ := buildTestTx(addr, denied, 1, 10000)
+	assert.NotNil(t, signTestTxWithHashType(ks, tx5, lockScripts, tokenengine.SigHashNone))
+	assert.NotNil(t, signTestTxWithHashType(ks, tx5, lockScripts, tokenengine.SigHashSingle))
+	//签名数据不一定包含所有输出，有策略时不能直接对签名数据签名
+	for _, lockScript := range lockScripts {
+		data, err := tokenengine.CalcSignatureData(tx5, tokenengine.SigHashNone, 0, 0, lockScript)
+		assert.Nil(t, err)
+		_, err = ks.SignMessage(addr, data)
+		assert.NotNil(t, err)
+	}
+
+	header := modules.NewHeader(nil, common.Hash{}, nil, nil, nil, nil, nil, modules.NewPTNIdType(), 1, 0)
+	sign, err := ks.SigUnit(header, addr)
+	assert.Nil(t, err)
+	pubKey, _ := ks.GetPublicKey(addr)
+	assert.True(t, keystore.VerifyUnitWithPK(append(sign, 0), header, pubKey))
+}
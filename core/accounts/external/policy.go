@@ -0,0 +1,188 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+)
+
+// Policy is the signing policy of the signer process, loaded from a json file:
+//
+//	{
+//	  "allowed_recipients": ["P1..."],
+//	  "daily_limits": {"PTN": 100000000000},
+//	  "allow_unit_sign": true
+//	}
+type Policy struct {
+	AllowedRecipients []string          `json:"allowed_recipients"` //允许的收款地址，为空表示不限制
+	DailyLimits       map[string]uint64 `json:"daily_limits"`       //每种资产每个签名账户每天最多转出的金额，未配置的资产不限制
+	AllowUnitSign     bool              `json:"allow_unit_sign"`    //是否允许为Mediator的Unit签名
+
+	recipients map[common.Address]bool
+	mu         sync.Mutex
+	day        string
+	spent      map[common.Address]map[string]uint64 //签名账户当天已经转出的金额
+	signedTxs  map[common.Hash]bool                 //当天已经计入限额的交易
+}
+
+// LoadPolicy reads the policy from a json file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %s", file, err.Error())
+	}
+	if err := policy.init(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *Policy) init() error {
+	p.recipients = make(map[common.Address]bool)
+	for _, str := range p.AllowedRecipients {
+		addr, err := common.StringToAddress(str)
+		if err != nil {
+			return fmt.Errorf("invalid allowed recipient %s: %s", str, err.Error())
+		}
+		p.recipients[addr] = true
+	}
+	return nil
+}
+
+// active reports whether the transactions are restricted by the policy.
+func (p *Policy) active() bool {
+	return len(p.recipients) > 0 || len(p.DailyLimits) > 0
+}
+
+// Check decides whether the signer allows the account to sign the message.
+// The signature data of a transaction input may not contain all the outputs,
+// so when the policy is active transactions must be signed by CheckTxInput.
+func (p *Policy) Check(signer common.Address, msg []byte) error {
+	tx := &modules.Transaction{}
+	if err := rlp.DecodeBytes(msg, tx); err == nil && len(tx.TxMessages()) > 0 {
+		if p.active() {
+			return fmt.Errorf("policy: transaction must be signed with the whole transaction")
+		}
+		return nil
+	}
+	header := &modules.Header{}
+	if err := rlp.DecodeBytes(msg, header); err == nil {
+		if !p.AllowUnitSign {
+			return fmt.Errorf("policy: unit sign is not allowed")
+		}
+		return nil
+	}
+	return fmt.Errorf("policy: unknown message to sign")
+}
+
+// CheckTxInput decides whether the signer allows the account to sign an input
+// of the whole transaction. Only SigHashAll commits to all the outputs checked
+// by the policy, the other hash types are rejected when the policy is active.
+func (p *Policy) CheckTxInput(signer common.Address, tx *modules.Transaction, hashType uint32) error {
+	if !p.active() {
+		return nil
+	}
+	if hashType != tokenengine.SigHashAll {
+		return fmt.Errorf("policy: hash type %d is not allowed", hashType)
+	}
+	return p.checkTx(signer, tx)
+}
+
+func (p *Policy) checkTx(signer common.Address, tx *modules.Transaction) error {
+	amounts := make(map[string]uint64)
+	for _, msg := range tx.TxMessages() {
+		pay, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, out := range pay.Outputs {
+			to, err := tokenengine.Instance.GetAddressFromScript(out.PkScript)
+			if err != nil {
+				return fmt.Errorf("policy: unknown recipient script %x", out.PkScript)
+			}
+			if to == signer { //找零
+				continue
+			}
+			if len(p.recipients) > 0 && !p.recipients[to] {
+				return fmt.Errorf("policy: recipient %s is not allowed", to.String())
+			}
+			if out.Asset != nil {
+				amounts[out.Asset.String()] += out.Value
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	today := time.Now().UTC().Format("2006-01-02")
+	if p.day != today {
+		p.day = today
+		p.spent = make(map[common.Address]map[string]uint64)
+		p.signedTxs = make(map[common.Hash]bool)
+	}
+	//同一个交易的每个Input都需要签名，只计算一次
+	txKey := unsignedTxHash(tx)
+	if p.signedTxs[txKey] {
+		return nil
+	}
+	spent, ok := p.spent[signer]
+	if !ok {
+		spent = make(map[string]uint64)
+		p.spent[signer] = spent
+	}
+	for asset, amount := range amounts {
+		limit, ok := p.DailyLimits[asset]
+		if ok && spent[asset]+amount > limit {
+			return fmt.Errorf("policy: daily limit of %s exceeded, spent %d, limit %d, request %d",
+				asset, spent[asset], limit, amount)
+		}
+	}
+	for asset, amount := range amounts {
+		spent[asset] += amount
+	}
+	p.signedTxs[txKey] = true
+	return nil
+}
+
+func unsignedTxHash(tx *modules.Transaction) common.Hash {
+	txCopy := tx.Clone()
+	for _, msg := range txCopy.Messages() {
+		if pay, ok := msg.Payload.(*modules.PaymentPayload); ok {
+			for _, in := range pay.Inputs {
+				in.SignatureScript = nil
+			}
+		}
+	}
+	return txCopy.Hash()
+}
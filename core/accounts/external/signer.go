@@ -0,0 +1,114 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package external
+
+import (
+	"errors"
+	"net"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/hexutil"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/core/accounts/keystore"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+)
+
+// SignerAPI is the json-rpc service of the signer process, registered in the
+// "signer" namespace. Only the unlocked accounts of the keystore can sign.
+type SignerAPI struct {
+	ks     *keystore.KeyStore
+	policy *Policy
+}
+
+func NewSignerAPI(ks *keystore.KeyStore, policy *Policy) *SignerAPI {
+	if policy == nil {
+		policy = &Policy{}
+	}
+	if policy.recipients == nil {
+		policy.init()
+	}
+	return &SignerAPI{ks: ks, policy: policy}
+}
+
+// Accounts returns the unlocked accounts which can be used to sign.
+func (api *SignerAPI) Accounts() []common.Address {
+	addrs := []common.Address{}
+	for _, acc := range api.ks.Accounts() {
+		if api.ks.IsUnlock(acc.Address) {
+			addrs = append(addrs, acc.Address)
+		}
+	}
+	return addrs
+}
+
+func (api *SignerAPI) PublicKey(addr common.Address) (hexutil.Bytes, error) {
+	if !api.ks.HasAddress(addr) || !api.ks.IsUnlock(addr) {
+		return nil, errors.New("unknown account")
+	}
+	return api.ks.GetPublicKey(addr)
+}
+
+func (api *SignerAPI) SignMessage(addr common.Address, msg hexutil.Bytes) (hexutil.Bytes, error) {
+	if !api.ks.HasAddress(addr) || !api.ks.IsUnlock(addr) {
+		return nil, errors.New("unknown account")
+	}
+	if err := api.policy.Check(addr, msg); err != nil {
+		log.Warnf("Reject sign request of account[%s]: %s", addr.String(), err.Error())
+		return nil, err
+	}
+	return api.ks.SignMessage(addr, msg)
+}
+
+// SignTxInput signs an input of the transaction after the policy checked the
+// whole transaction. The signature data is calculated by the signer itself.
+func (api *SignerAPI) SignTxInput(addr common.Address, rawTx hexutil.Bytes, hashType uint32, msgIdx,
+	inputIdx int, subScript hexutil.Bytes) (hexutil.Bytes, error) {
+	if !api.ks.HasAddress(addr) || !api.ks.IsUnlock(addr) {
+		return nil, errors.New("unknown account")
+	}
+	tx := &modules.Transaction{}
+	if err := rlp.DecodeBytes(rawTx, tx); err != nil {
+		return nil, err
+	}
+	if err := api.policy.CheckTxInput(addr, tx, hashType); err != nil {
+		log.Warnf("Reject sign request of account[%s]: %s", addr.String(), err.Error())
+		return nil, err
+	}
+	data, err := tokenengine.CalcSignatureData(tx, hashType, msgIdx, inputIdx, subScript)
+	if err != nil {
+		return nil, err
+	}
+	return api.ks.SignMessage(addr, data)
+}
+
+// StartSigner serves the signer api on the IPC endpoint.
+func StartSigner(endpoint string, ks *keystore.KeyStore, policy *Policy) (net.Listener, *rpc.Server, error) {
+	apis := []rpc.API{{
+		Namespace: "signer",
+		Version:   "1.0",
+		Service:   NewSignerAPI(ks, policy),
+		Public:    true,
+	}}
+	return rpc.StartIPCEndpoint(endpoint, apis)
+}
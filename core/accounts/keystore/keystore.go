@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/crypto/ecies"
 	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/core/accounts"
@@ -69,9 +70,21 @@ type KeyStore struct {
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
 
+	external ExternalSigner // Signer process holding the keys not stored in this keystore
+
 	mu sync.RWMutex
 }
 
+// ExternalSigner signs for the accounts whose keys are kept out of the node
+// process, see core/accounts/external.
+type ExternalSigner interface {
+	HasAddress(addr common.Address) bool
+	GetPublicKey(addr common.Address) ([]byte, error)
+	SignData(addr common.Address, msg []byte) ([]byte, error)
+	SignTxInput(addr common.Address, tx *modules.Transaction, hashType uint32, msgIdx, inputIdx int,
+		subScript []byte) ([]byte, error)
+}
+
 type unlocked struct {
 	*Key
 	abort chan struct{}
@@ -220,6 +233,45 @@ func (ks *KeyStore) updater() {
 	}
 }
 
+// SetExternalSigner forwards the signing of the accounts which are not in this
+// keystore to the external signer, so callers of the keystore can use them
+// transparently.
+func (ks *KeyStore) SetExternalSigner(signer ExternalSigner) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.external = signer
+}
+
+// externalSigner returns the external signer if the address is held by it
+// instead of this keystore.
+func (ks *KeyStore) externalSigner(addr common.Address) ExternalSigner {
+	ks.mu.RLock()
+	signer := ks.external
+	ks.mu.RUnlock()
+	if signer == nil || ks.cache.hasAddress(addr) || !signer.HasAddress(addr) {
+		return nil
+	}
+	return signer
+}
+
+// IsTxInputSigner reports whether the transaction inputs of the address are
+// signed by the external signer, which checks the whole transaction by its
+// policy instead of the signature data.
+func (ks *KeyStore) IsTxInputSigner(addr common.Address) bool {
+	return ks.externalSigner(addr) != nil
+}
+
+// SignTxInput forwards the whole transaction to the external signer to sign
+// the input at msgIdx/inputIdx with the given hash type.
+func (ks *KeyStore) SignTxInput(addr common.Address, tx *modules.Transaction, hashType uint32, msgIdx,
+	inputIdx int, subScript []byte) ([]byte, error) {
+	signer := ks.externalSigner(addr)
+	if signer == nil {
+		return nil, ErrNoMatch
+	}
+	return signer.SignTxInput(addr, tx, hashType, msgIdx, inputIdx, subScript)
+}
+
 // HasAddress reports whether a key with the given address is present.
 func (ks *KeyStore) HasAddress(addr common.Address) bool {
 	return ks.cache.hasAddress(addr)
@@ -257,6 +309,9 @@ func (ks *KeyStore) Delete(a accounts.Account, passphrase string) error {
 // SignHash calculates a ECDSA signature for the given hash. The produced
 // signature is in the [R || S ] format .
 func (ks *KeyStore) SignMessage(addr common.Address, msg []byte) ([]byte, error) {
+	if signer := ks.externalSigner(addr); signer != nil {
+		return signer.SignData(addr, msg)
+	}
 	// Look up the key to sign with and abort if it cannot be found
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
@@ -270,6 +325,22 @@ func (ks *KeyStore) SignMessage(addr common.Address, msg []byte) ([]byte, error)
 	//return crypto.Sign(hash, unlockedKey.PrivateKey)
 }
 
+// DecryptMessage decrypts the ECIES ciphertext with the unlocked key of the account.
+func (ks *KeyStore) DecryptMessage(addr common.Address, ciphertext []byte) ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[addr]
+	if !found {
+		return nil, ErrLocked
+	}
+	prvKey, err := crypto.ToECDSA(unlockedKey.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ecies.ImportECDSA(prvKey).Decrypt(ciphertext, nil, nil)
+}
+
 // SignTx signs the given transaction with the requested account.
 func (ks *KeyStore) SignTx(a accounts.Account, tx *modules.Transaction,
 	chainID *big.Int) (*modules.Transaction, error) {
@@ -371,6 +442,10 @@ func (ks *KeyStore) Lock(addr common.Address) error {
 // shortens the active unlock timeout. If the address was previously unlocked
 // indefinitely the timeout is not altered.
 func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout time.Duration) error {
+	//外部签名者的账户由签名进程自己解锁
+	if ks.externalSigner(a.Address) != nil {
+		return nil
+	}
 	a, key, err := ks.getDecryptedKey(a, passphrase)
 	if err != nil {
 		return err
@@ -400,6 +475,9 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 }
 
 func (ks *KeyStore) IsUnlock(addr common.Address) bool {
+	if ks.externalSigner(addr) != nil {
+		return true
+	}
 	ks.mu.Lock()
 	_, found := ks.unlocked[addr]
 	ks.mu.Unlock()
@@ -568,6 +646,9 @@ func (ks *KeyStore) getPrivateKey(address common.Address) ([]byte, error) {
 }
 
 func (ks *KeyStore) GetPublicKey(address common.Address) ([]byte, error) {
+	if signer := ks.externalSigner(address); signer != nil {
+		return signer.GetPublicKey(address)
+	}
 	// Look up the key to sign with and abort if it cannot be found
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
@@ -587,6 +668,13 @@ func (ks *KeyStore) SigUnit(unitHeader *modules.Header, address common.Address)
 }
 
 func (ks *KeyStore) SigData(data interface{}, address common.Address) ([]byte, error) {
+	if signer := ks.externalSigner(address); signer != nil {
+		msg, err := rlp.EncodeToBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.SignData(address, msg)
+	}
 	privateKey, err := ks.getPrivateKey(address)
 	if err != nil {
 		return nil, err
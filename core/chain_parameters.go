@@ -148,6 +148,14 @@ type ChainParametersExtra struct {
 
 	PledgeAllocateThreshold int `json:"pledge_allocate_threshold"`
 	PledgeRecordsThreshold  int `json:"pledge_records_threshold"`
+
+	// mediator在最近若干个维护周期内的出块率(百分比)低于该值时，换届时不再当选活跃mediator，0表示不启用
+	MediatorLivenessThreshold uint32 `json:"mediator_liveness_threshold"`
+	// 计算mediator出块率的维护周期数
+	MediatorLivenessRounds uint32 `json:"mediator_liveness_rounds"`
+
+	// 陪审员选举使用的VRF算法
+	VrfAlgorithm string `json:"vrf_algorithm"`
 }
 
 func NewChainParametersExtra() ChainParametersExtra {
@@ -156,6 +164,11 @@ func NewChainParametersExtra() ChainParametersExtra {
 
 		PledgeAllocateThreshold: DefaultPledgeAllocateThreshold,
 		PledgeRecordsThreshold:  DefaultPledgeRecordsThreshold,
+
+		MediatorLivenessThreshold: DefaultMediatorLivenessThreshold,
+		MediatorLivenessRounds:    DefaultMediatorLivenessRounds,
+
+		VrfAlgorithm: DefaultVrfAlgorithm,
 	}
 }
 
@@ -221,6 +234,18 @@ func CheckSysConfigArgType(field, value string) error {
 	return err
 }
 
+// 陪审员选举可以选用的VRF算法，与 consensus/jury/vrf 中注册的算法名称一致
+var SupportedVrfAlgorithms = []string{"es", "ess", "ec"}
+
+func IsSupportedVrfAlgorithm(alg string) bool {
+	for _, a := range SupportedVrfAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
 type GetMediatorCountFn func() int
 
 func CheckChainParameterValue(field, value string, icp *ImmutableChainParameters, cp *ChainParameters,
@@ -269,6 +294,21 @@ func CheckChainParameterValue(field, value string, icp *ImmutableChainParameters
 			err = fmt.Errorf("new MaintenanceInterval(%v) must be divisible by mediator interval(%v)",
 				newMaintenanceInterval, cp.MediatorInterval)
 		}
+	case "MediatorLivenessThreshold":
+		newThreshold, _ := strconv.ParseUint(value, 10, 64)
+		if newThreshold > 100 {
+			err = fmt.Errorf("new MediatorLivenessThreshold(%v) cannot more than 100", newThreshold)
+		}
+	case "MediatorLivenessRounds":
+		newRounds, _ := strconv.ParseUint(value, 10, 64)
+		if newRounds == 0 {
+			err = fmt.Errorf("new MediatorLivenessRounds(%v) must be larger than 0", newRounds)
+		}
+	case "VrfAlgorithm":
+		if !IsSupportedVrfAlgorithm(value) {
+			err = fmt.Errorf("new VrfAlgorithm(%v) is not supported, must be one of %v", value,
+				SupportedVrfAlgorithms)
+		}
 
 	default:
 		err = nil
@@ -39,6 +39,10 @@ type ChainParametersExtraTemp struct {
 
 	PledgeAllocateThreshold string
 	PledgeRecordsThreshold  string
+
+	// 后续增加的参数，兼容旧版本的编码，按顺序依次为:
+	// MediatorLivenessThreshold, MediatorLivenessRounds, VrfAlgorithm
+	ExtraParams []string `rlp:"tail"`
 }
 
 type ChainParametersExtraTemp104alpha struct {
@@ -112,6 +116,12 @@ func (cp *ChainParametersExtra) GetCPT() *ChainParametersExtraTemp {
 
 		PledgeAllocateThreshold: strconv.FormatInt(int64(cp.PledgeAllocateThreshold), 10),
 		PledgeRecordsThreshold:  strconv.FormatInt(int64(cp.PledgeRecordsThreshold), 10),
+
+		ExtraParams: []string{
+			strconv.FormatUint(uint64(cp.MediatorLivenessThreshold), 10),
+			strconv.FormatUint(uint64(cp.MediatorLivenessRounds), 10),
+			cp.VrfAlgorithm,
+		},
 	}
 }
 
@@ -261,6 +271,28 @@ func (cpt *ChainParametersExtraTemp) GetCP(cp *ChainParametersExtra) error {
 	}
 	cp.PledgeRecordsThreshold = int(PledgeRecordsThreshold)
 
+	// 旧版本的编码没有该参数，使用默认值
+	cp.MediatorLivenessThreshold = DefaultMediatorLivenessThreshold
+	cp.MediatorLivenessRounds = DefaultMediatorLivenessRounds
+	if len(cpt.ExtraParams) >= 2 {
+		MediatorLivenessThreshold, err := strconv.ParseUint(cpt.ExtraParams[0], 10, 32)
+		if err != nil {
+			return err
+		}
+		cp.MediatorLivenessThreshold = uint32(MediatorLivenessThreshold)
+
+		MediatorLivenessRounds, err := strconv.ParseUint(cpt.ExtraParams[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		cp.MediatorLivenessRounds = uint32(MediatorLivenessRounds)
+	}
+
+	cp.VrfAlgorithm = DefaultVrfAlgorithm
+	if len(cpt.ExtraParams) >= 3 {
+		cp.VrfAlgorithm = cpt.ExtraParams[2]
+	}
+
 	return nil
 }
 
@@ -39,3 +39,44 @@ func Test_ChainParameters_Rlp(t *testing.T) {
 	assert.Equal(t, cp.ContractElectionNum, cp2.ContractElectionNum)
 	assert.Equal(t, cp.UccCpuShares, cp2.UccCpuShares)
 }
+
+//旧版本的编码中没有mediator出块率和VRF算法的参数，解码后使用默认值
+func Test_ChainParameters_RlpCompatible(t *testing.T) {
+	cp := NewChainParams()
+	cp.MediatorLivenessThreshold = 60
+	cp.MediatorLivenessRounds = 5
+	cp.VrfAlgorithm = "ess"
+	data, err := rlp.EncodeToBytes(&cp)
+	assert.Nil(t, err)
+	cp2 := &ChainParameters{}
+	assert.Nil(t, rlp.DecodeBytes(data, cp2))
+	assert.Equal(t, uint32(60), cp2.MediatorLivenessThreshold)
+	assert.Equal(t, uint32(5), cp2.MediatorLivenessRounds)
+	assert.Equal(t, "ess", cp2.VrfAlgorithm)
+
+	cpt := cp.GetCPT()
+	cpt.ExtraParams = cpt.ExtraParams[:2]
+	data, err = rlp.EncodeToBytes(cpt)
+	assert.Nil(t, err)
+	cp4 := &ChainParameters{}
+	assert.Nil(t, rlp.DecodeBytes(data, cp4))
+	assert.Equal(t, uint32(60), cp4.MediatorLivenessThreshold)
+	assert.Equal(t, DefaultVrfAlgorithm, cp4.VrfAlgorithm)
+
+	cpt.ExtraParams = nil
+	data, err = rlp.EncodeToBytes(cpt)
+	assert.Nil(t, err)
+	cp3 := &ChainParameters{}
+	assert.Nil(t, rlp.DecodeBytes(data, cp3))
+	assert.Equal(t, uint32(DefaultMediatorLivenessThreshold), cp3.MediatorLivenessThreshold)
+	assert.Equal(t, uint32(DefaultMediatorLivenessRounds), cp3.MediatorLivenessRounds)
+	assert.Equal(t, cp.PledgeRecordsThreshold, cp3.PledgeRecordsThreshold)
+	assert.Equal(t, DefaultVrfAlgorithm, cp3.VrfAlgorithm)
+}
+
+func Test_CheckChainParameterValue_VrfAlgorithm(t *testing.T) {
+	icp := NewImmutChainParams()
+	cp := NewChainParams()
+	assert.Nil(t, CheckChainParameterValue("VrfAlgorithm", "ess", &icp, &cp, nil))
+	assert.NotNil(t, CheckChainParameterValue("VrfAlgorithm", "ed", &icp, &cp, nil))
+}
@@ -32,6 +32,11 @@ const (
 	//  当添加新质押地址 t = 2 时，tx_size = 1267 b,当前单元大小为 5 m = 5120 kb =>3,236.345679012346
 	//  即 t = 1 时， tx_size = 633.5 b,当前单元大小为 5 m = 5120 kb =>8,088.467614533965
 	DefaultPledgeRecordsThreshold = 2000
+	//  mediator出块率阈值，按最近3个维护周期计算，签名分片贡献率无法验证，不参与判定
+	DefaultMediatorLivenessThreshold = 50
+	DefaultMediatorLivenessRounds    = 3
+	//  陪审员选举默认使用的VRF算法
+	DefaultVrfAlgorithm = "es"
 	// DefaultTxCoinYearRate            = 0
 	DefaultRewardHeight              = 100
 	DefaultGenerateUnitReward        = 15000
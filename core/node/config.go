@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/palletone/go-palletone/core/accounts"
+	"github.com/palletone/go-palletone/core/accounts/external"
 	"github.com/palletone/go-palletone/core/accounts/keystore"
 	//"github.com/palletone/go-palletone/core/accounts/usbwallet"
 	"github.com/palletone/go-palletone/common"
@@ -33,6 +34,7 @@ import (
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/common/p2p"
 	"github.com/palletone/go-palletone/common/p2p/discover"
+	"github.com/palletone/go-palletone/tokenengine"
 )
 
 const (
@@ -88,6 +90,10 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the IPC path or url of the external signer process. The
+	// accounts held by the signer can be used as if they were in the keystore.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -416,8 +422,16 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		return nil, "", err
 	}
 	// Assemble the account manager and supported backends
-	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+	ks := keystore.NewKeyStore(keydir, scryptN, scryptP)
+	backends := []accounts.Backend{ks}
+	if conf.ExternalSigner != "" {
+		signer, err := external.NewExternalSigner(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("connect to external signer %s error: %v", conf.ExternalSigner, err)
+		}
+		ks.SetExternalSigner(signer)
+		tokenengine.SetTxInputSigner(ks)
+		backends = append(backends, signer)
 	}
 	/*
 		if !conf.NoUSB {
@@ -16,6 +16,10 @@
 
 package types
 
+import (
+	"github.com/palletone/go-palletone/common/crypto"
+)
+
 const (
 	// BloomByteLength represents the number of bytes used in a header log bloom.
 	BloomByteLength = 256
@@ -26,3 +30,45 @@ const (
 
 //// Bloom represents a 2048 bit bloom filter.
 type Bloom [BloomByteLength]byte
+
+// BytesToBloom converts a byte slice to a bloom filter.
+func BytesToBloom(b []byte) Bloom {
+	var bloom Bloom
+	if len(b) > BloomByteLength {
+		b = b[len(b)-BloomByteLength:]
+	}
+	copy(bloom[BloomByteLength-len(b):], b)
+	return bloom
+}
+
+// Add adds d to the filter. The bits are the same as the ones used by
+// bloombits.Matcher, so the blooms can be indexed by bloombits.Generator.
+func (b *Bloom) Add(d []byte) {
+	for _, bit := range bloom9(d) {
+		b[BloomByteLength-1-bit/8] |= byte(1) << (bit % 8)
+	}
+}
+
+// Test checks whether d may be in the filter.
+func (b Bloom) Test(d []byte) bool {
+	for _, bit := range bloom9(d) {
+		if b[BloomByteLength-1-bit/8]&(byte(1)<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Bytes returns the byte representation of the bloom filter.
+func (b Bloom) Bytes() []byte {
+	return b[:]
+}
+
+func bloom9(d []byte) [3]uint {
+	h := crypto.Keccak256(d)
+	var bits [3]uint
+	for i := 0; i < len(bits); i++ {
+		bits[i] = (uint(h[2*i])<<8)&2047 + uint(h[2*i+1])
+	}
+	return bits
+}
@@ -54,6 +54,7 @@ const (
 	ChaincodeSpec_NODE      ChaincodeSpec_Type = 2
 	ChaincodeSpec_CAR       ChaincodeSpec_Type = 3
 	ChaincodeSpec_JAVA      ChaincodeSpec_Type = 4
+	ChaincodeSpec_WASM      ChaincodeSpec_Type = 5
 )
 
 var ChaincodeSpec_Type_name = map[int32]string{
@@ -62,6 +63,7 @@ var ChaincodeSpec_Type_name = map[int32]string{
 	2: "NODE",
 	3: "CAR",
 	4: "JAVA",
+	5: "WASM",
 }
 
 var ChaincodeSpec_Type_value = map[string]int32{
@@ -70,6 +72,7 @@ var ChaincodeSpec_Type_value = map[string]int32{
 	"NODE":      2,
 	"CAR":       3,
 	"JAVA":      4,
+	"WASM":      5,
 }
 
 func (x ChaincodeSpec_Type) String() string {
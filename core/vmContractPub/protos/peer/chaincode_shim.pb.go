@@ -58,6 +58,7 @@ const (
 	ChaincodeMessage_OUTCHAIN_CALL             ChaincodeMessage_Type = 33
 	ChaincodeMessage_GET_STABLE_TRANSACTION    ChaincodeMessage_Type = 34
 	ChaincodeMessage_GET_STABLE_UNIT           ChaincodeMessage_Type = 35
+	ChaincodeMessage_GET_STATE_BY_RANGE        ChaincodeMessage_Type = 36
 )
 
 var ChaincodeMessage_Type_name = map[int32]string{
@@ -89,6 +90,7 @@ var ChaincodeMessage_Type_name = map[int32]string{
 	33: "OUTCHAIN_CALL",
 	34: "GET_STABLE_TRANSACTION",
 	35: "GET_STABLE_UNIT",
+	36: "GET_STATE_BY_RANGE",
 }
 
 var ChaincodeMessage_Type_value = map[string]int32{
@@ -120,6 +122,7 @@ var ChaincodeMessage_Type_value = map[string]int32{
 	"OUTCHAIN_CALL":             33,
 	"GET_STABLE_TRANSACTION":    34,
 	"GET_STABLE_UNIT":           35,
+	"GET_STATE_BY_RANGE":        36,
 }
 
 func (x ChaincodeMessage_Type) String() string {
@@ -501,6 +504,7 @@ type GetStateByRange struct {
 	StartKey             string   `protobuf:"bytes,1,opt,name=startKey,proto3" json:"startKey,omitempty"`
 	EndKey               string   `protobuf:"bytes,2,opt,name=endKey,proto3" json:"endKey,omitempty"`
 	Collection           string   `protobuf:"bytes,3,opt,name=collection,proto3" json:"collection,omitempty"`
+	PageSize             int32    `protobuf:"varint,4,opt,name=pageSize,proto3" json:"pageSize,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -552,6 +556,13 @@ func (m *GetStateByRange) GetCollection() string {
 	return ""
 }
 
+func (m *GetStateByRange) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
 type GetQueryResult struct {
 	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
 	Collection           string   `protobuf:"bytes,2,opt,name=collection,proto3" json:"collection,omitempty"`
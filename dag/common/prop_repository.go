@@ -59,6 +59,9 @@ type IPropRepository interface {
 
 	GetChainThreshold() (int, error)
 	GetChainParameters() *core.ChainParameters
+
+	StoreSnapshotCommitment(c *modules.SnapshotCommitment) error
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 }
 
 func (pRep *PropRepository) GetChainParameters() *core.ChainParameters {
@@ -132,6 +135,14 @@ func (pRep *PropRepository) GetHeadUnitProperty(asset modules.AssetId) (*modules
 	return pRep.db.GetNewestUnit(asset)
 }
 
+func (pRep *PropRepository) StoreSnapshotCommitment(c *modules.SnapshotCommitment) error {
+	return pRep.db.StoreSnapshotCommitment(c)
+}
+
+func (pRep *PropRepository) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	return pRep.db.GetSnapshotCommitment(index)
+}
+
 // 洗牌算法，更新mediator的调度顺序
 func (pRep *PropRepository) UpdateMediatorSchedule() bool {
 	gp, err := pRep.RetrieveGlobalProp()
@@ -43,6 +43,7 @@ type IStateRepository interface {
 	SaveContractState(id []byte, w *modules.ContractWriteSet, version *modules.StateVersion) error
 	GetContractStatesById(id []byte) (map[string]*modules.ContractStateValue, error)
 	GetContractStatesByPrefix(id []byte, prefix string) (map[string]*modules.ContractStateValue, error)
+	GetContractStatesByRange(id []byte, startKey, endKey string, limit int) ([]*modules.ContractStateKV, error)
 
 	GetContract(id []byte) (*modules.Contract, error)
 	GetAllContracts() ([]*modules.Contract, error)
@@ -69,6 +70,8 @@ type IStateRepository interface {
 	IsMediator(address common.Address) bool
 	RetrieveMediatorInfo(address common.Address) (*modules.MediatorInfo, error)
 	StoreMediatorInfo(add common.Address, mi *modules.MediatorInfo) error
+	StoreMediatorLiveness(add common.Address, ml *modules.MediatorLiveness) error
+	RetrieveMediatorLiveness(add common.Address) (*modules.MediatorLiveness, error)
 
 	//GetCurrentChainIndex(assetId modules.AssetId) (*modules.ChainIndex, error)
 
@@ -78,6 +81,8 @@ type IStateRepository interface {
 	//GetJurorByAddr(addr string) (*modules.JurorDeposit, error)
 	GetJurorReward(jurorAdd common.Address) common.Address
 	GetJurorByAddrHash(addrHash common.Hash) (*modules.JurorDeposit, error)
+	GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation
+	SettleJurorReputations(unitTime uint64) error
 	GetContractDeveloperList() ([]common.Address, error)
 	IsContractDeveloper(address common.Address) bool
 
@@ -180,6 +185,11 @@ func (rep *StateRepository) GetContractStatesByPrefix(id []byte,
 	return rep.statedb.GetContractStatesByPrefix(id, prefix)
 }
 
+func (rep *StateRepository) GetContractStatesByRange(id []byte, startKey, endKey string,
+	limit int) ([]*modules.ContractStateKV, error) {
+	return rep.statedb.GetContractStatesByRange(id, startKey, endKey, limit)
+}
+
 func (rep *StateRepository) GetContract(id []byte) (*modules.Contract, error) {
 	return rep.statedb.GetContract(id)
 }
@@ -238,6 +248,14 @@ func (rep *StateRepository) UpdateMediatorInfoExpand(med *core.Mediator) error {
 	return rep.statedb.UpdateMediatorInfoExpand(med)
 }
 
+func (rep *StateRepository) StoreMediatorLiveness(add common.Address, ml *modules.MediatorLiveness) error {
+	return rep.statedb.StoreMediatorLiveness(add, ml)
+}
+
+func (rep *StateRepository) RetrieveMediatorLiveness(add common.Address) (*modules.MediatorLiveness, error) {
+	return rep.statedb.RetrieveMediatorLiveness(add)
+}
+
 func (rep *StateRepository) GetMediators() map[common.Address]bool {
 	return rep.statedb.GetMediators()
 }
@@ -442,6 +460,19 @@ func (rep *StateRepository) GetJurorReward(jurorAdd common.Address) common.Addre
 	return jd.GetRewardAdd()
 }
 
+func (rep *StateRepository) GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation {
+	jr, err := rep.statedb.RetrieveJurorReputation(jurorAdd)
+	if err != nil {
+		log.Warnf("RetrieveJurorReputation(%s) error:%s", jurorAdd.String(), err.Error())
+		return modules.NewJurorReputation()
+	}
+	return jr
+}
+
+func (rep *StateRepository) SettleJurorReputations(unitTime uint64) error {
+	return rep.statedb.SettleJurorReputations(unitTime)
+}
+
 func (rep *StateRepository) GetJurorByAddrHash(hash common.Hash) (*modules.JurorDeposit, error) {
 	if addr, exist := rep.mapHash2Address[hash]; exist {
 		log.Debugf("GetJurorByAddrHash(hash:%s) in cache map,addr:%s",
@@ -36,6 +36,7 @@ import (
 	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/dag/parameter"
 	"github.com/palletone/go-palletone/dag/storage"
+	"github.com/palletone/go-palletone/statistics/metrics"
 	"github.com/palletone/go-palletone/tokenengine"
 )
 
@@ -61,6 +62,9 @@ type UnitProduceRepository struct {
 
 	// append by albert·gou 用于account 各种投票数据统计
 	mediatorVoteTally voteTallys
+
+	// 本次维护周期中出块率低于阈值的mediator
+	inactiveMediators map[common.Address]bool
 }
 
 type AfterChainMaintenanceEventFunc func(event *modules.ChainMaintenanceEvent)
@@ -240,6 +244,8 @@ func (rep *UnitProduceRepository) updateMediatorMissedUnits(unit *modules.Unit)
 			med := rep.GetMediator(mediatorMissed)
 			med.TotalMissed++
 			rep.stateRep.UpdateMediatorInfoExpand(med)
+
+			rep.updateMediatorLivenessRecord(mediatorMissed, false)
 		}
 	}
 
@@ -292,10 +298,56 @@ func (rep *UnitProduceRepository) updateSigningMediator(newUnit *modules.Unit) {
 	med.TotalProduct++
 	med.LastConfirmedUnitNum = lastConfirmedUnitNum
 	rep.stateRep.UpdateMediatorInfoExpand(med)
+	rep.updateMediatorLivenessRecord(signingMediator, true)
+	rep.updateSigShareLiveness(newUnit)
 
 	log.Debugf("the LastConfirmedUnitNum of mediator(%v) is: %v", med.Address.Str(), lastConfirmedUnitNum)
 }
 
+// 根据生产者在unit中记录的其上一个unit的签名分片贡献情况，更新各个活跃mediator的签名分片统计，
+// 被记录的unit必须是该生产者在本届生产的，且每个unit只记录一次。
+// 贡献情况由生产者自行记录，无法验证，只用于展示，不影响mediator是否当选
+func (rep *UnitProduceRepository) updateSigShareLiveness(newUnit *modules.Unit) {
+	attest, ok := newUnit.UnitHeader.GetSigShareAttestation()
+	if !ok {
+		return
+	}
+
+	producer := newUnit.Author()
+	pl, err := rep.stateRep.RetrieveMediatorLiveness(producer)
+	if err != nil {
+		log.Errorf("retrieve liveness of mediator(%v) err: %v", producer.Str(), err.Error())
+		return
+	}
+	if attest.UnitIndex <= pl.LastAttested || attest.UnitIndex >= newUnit.NumberU64() {
+		log.Debugf("the sign share attestation of unit(%v) is out of date", newUnit.Hash().TerminalString())
+		return
+	}
+
+	attested, err := rep.unitRep.GetHeaderByNumber(&modules.ChainIndex{AssetID: newUnit.Number().AssetID,
+		Index: attest.UnitIndex})
+	if err != nil || attested.Author() != producer ||
+		attested.Timestamp() <= int64(rep.GetDynGlobalProp().LastMaintenanceTime) {
+		log.Debugf("the unit attested by unit(%v) is not produced by %v in this maintenance interval",
+			newUnit.Hash().TerminalString(), producer.Str())
+		return
+	}
+
+	pl.LastAttested = attest.UnitIndex
+	rep.stateRep.StoreMediatorLiveness(producer, pl)
+
+	for i, add := range rep.GetGlobalProp().GetActiveMediators() {
+		ml, err := rep.stateRep.RetrieveMediatorLiveness(add)
+		if err != nil {
+			log.Errorf("retrieve liveness of mediator(%v) err: %v", add.Str(), err.Error())
+			continue
+		}
+
+		ml.AddSigShare(attest.Contributed(i))
+		rep.stateRep.StoreMediatorLiveness(add, ml)
+	}
+}
+
 func (rep *UnitProduceRepository) GetGlobalProp() *modules.GlobalProperty {
 	gp, _ := rep.propRep.RetrieveGlobalProp()
 	return gp
@@ -326,6 +378,14 @@ func (dag *UnitProduceRepository) performChainMaintenance(nextUnit *modules.Unit
 	// 对每个账户的各种投票信息进行初步统计
 	dag.performAccountMaintenance()
 
+	// 结束当前周期的活跃度统计，找出出块率过低的mediator
+	dag.updateMediatorLiveness()
+
+	// 固定陪审员在本周期的执行记录，作为下一个周期分配合约执行费和选举的依据
+	if err := dag.stateRep.SettleJurorReputations(uint64(nextUnit.Timestamp())); err != nil {
+		log.Errorf("settle juror reputations err: %v", err.Error())
+	}
+
 	// 统计投票并更新活跃 mediator 列表
 	isChanged := dag.updateActiveMediators()
 
@@ -337,6 +397,7 @@ func (dag *UnitProduceRepository) performChainMaintenance(nextUnit *modules.Unit
 
 	// 清理中间处理缓存数据
 	dag.mediatorVoteTally = nil
+	dag.inactiveMediators = nil
 
 	// 发送更新活跃 mediator 事件，以方便其他模块做相应处理
 	log.Debugf("send ActiveMediatorsUpdated event")
@@ -537,7 +598,7 @@ func (dag *UnitProduceRepository) updateActiveMediators() bool {
 	// 2. 根据每个mediator的得票数，排序出前n个 active mediator
 	log.Debugf("In this round, The active mediator's count is %v", mediatorCount)
 	if dag.mediatorVoteTally.Len() > 0 {
-		sort.PartialSort(dag.mediatorVoteTally, mediatorCount)
+		dag.sortVoteTallyByLiveness(mediatorCount)
 	}
 
 	// 3. 更新每个mediator的得票数
@@ -672,3 +733,76 @@ func (dag *UnitProduceRepository) updateMaintenanceFlag(newMaintenanceFlag bool)
 //
 //	return false
 //}
+
+// 记录mediator在当前维护周期内的生产情况
+func (rep *UnitProduceRepository) updateMediatorLivenessRecord(add common.Address, produced bool) {
+	ml, err := rep.stateRep.RetrieveMediatorLiveness(add)
+	if err != nil {
+		log.Errorf("retrieve liveness of mediator(%v) err: %v", add.Str(), err.Error())
+		return
+	}
+
+	if produced {
+		ml.AddProduced()
+	} else {
+		ml.AddMissed()
+	}
+	rep.stateRep.StoreMediatorLiveness(add, ml)
+}
+
+// 维护周期结束时，结束每个mediator的活跃度统计周期，并找出最近K个周期内出块率低于阈值的mediator
+func (dag *UnitProduceRepository) updateMediatorLiveness() {
+	cp := dag.propRep.GetChainParameters()
+	threshold := cp.MediatorLivenessThreshold
+	rounds := cp.MediatorLivenessRounds
+
+	dag.inactiveMediators = make(map[common.Address]bool)
+	for add := range dag.stateRep.GetMediators() {
+		ml, err := dag.stateRep.RetrieveMediatorLiveness(add)
+		if err != nil {
+			log.Errorf("retrieve liveness of mediator(%v) err: %v", add.Str(), err.Error())
+			continue
+		}
+
+		ml.CloseRound(rounds)
+		dag.stateRep.StoreMediatorLiveness(add, ml)
+		metrics.GetOrRegisterGauge("mediator/liveness/"+add.Str(), nil).Update(int64(ml.Score()))
+
+		if ml.IsInactive(threshold, rounds) {
+			log.Infof("the liveness score of mediator(%v) is %v, lower than %v, in last %v maintenance intervals",
+				add.Str(), ml.Score(), threshold, rounds)
+			dag.inactiveMediators[add] = true
+		}
+	}
+}
+
+// 按得票数排序，出块率过低的mediator排在其他mediator之后，
+// 只有在其他mediator数量不足时才会当选，保证活跃mediator的数量不变
+func (dag *UnitProduceRepository) sortVoteTallyByLiveness(mediatorCount int) {
+	if len(dag.inactiveMediators) == 0 {
+		sort.PartialSort(dag.mediatorVoteTally, mediatorCount)
+		return
+	}
+
+	eligible := make(voteTallys, 0, dag.mediatorVoteTally.Len())
+	inactive := make(voteTallys, 0, len(dag.inactiveMediators))
+	for _, voteTally := range dag.mediatorVoteTally {
+		if dag.inactiveMediators[voteTally.candidate] {
+			inactive = append(inactive, voteTally)
+		} else {
+			eligible = append(eligible, voteTally)
+		}
+	}
+
+	if eligible.Len() >= mediatorCount {
+		sort.PartialSort(eligible, mediatorCount)
+	} else {
+		sort.PartialSort(eligible, eligible.Len())
+		lack := mediatorCount - eligible.Len()
+		if lack > inactive.Len() {
+			lack = inactive.Len()
+		}
+		sort.PartialSort(inactive, lack)
+	}
+	dag.mediatorVoteTally = append(eligible, inactive...)
+}
@@ -26,9 +26,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/ptndb"
 	"github.com/palletone/go-palletone/common/uint128"
 	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_UnitProduceRepository_UpdateSysParams(t *testing.T) {
@@ -163,3 +165,26 @@ func Test_LowAndHightDisplayOfRecentSlotsFilled(t *testing.T) {
 	dgp.RecentSlotsFilled = dgp.RecentSlotsFilled.Lsh(10).Add64(1)
 	t.Log(dgp.RecentSlotsFilled.BinaryStr())
 }
+
+func Test_UnitProduceRepository_SortVoteTallyByLiveness(t *testing.T) {
+	addrs := make([]common.Address, 4)
+	rep := &UnitProduceRepository{}
+	for i := range addrs {
+		addrs[i] = common.NewAddress([]byte{byte(i + 1)}, common.PublicKeyHash)
+		rep.mediatorVoteTally = append(rep.mediatorVoteTally,
+			&voteTally{candidate: addrs[i], votedCount: uint64(100 - i)})
+	}
+
+	// 得票最多的mediator出块率过低，不再当选
+	rep.inactiveMediators = map[common.Address]bool{addrs[0]: true}
+	rep.sortVoteTallyByLiveness(2)
+	assert.Equal(t, addrs[1], rep.mediatorVoteTally[0].candidate)
+	assert.Equal(t, addrs[2], rep.mediatorVoteTally[1].candidate)
+
+	// 其他mediator数量不足时，出块率过低的mediator按得票数补足
+	rep.inactiveMediators = map[common.Address]bool{addrs[0]: true, addrs[1]: true, addrs[2]: true}
+	rep.sortVoteTallyByLiveness(3)
+	assert.Equal(t, addrs[3], rep.mediatorVoteTally[0].candidate)
+	assert.Equal(t, addrs[0], rep.mediatorVoteTally[1].candidate)
+	assert.Equal(t, addrs[1], rep.mediatorVoteTally[2].candidate)
+}
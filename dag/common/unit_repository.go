@@ -20,6 +20,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -28,11 +29,14 @@ import (
 	"time"
 
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/configure"
 	"github.com/palletone/go-palletone/core"
 
 	"github.com/palletone/go-palletone/core/accounts/keystore"
+	"github.com/palletone/go-palletone/core/types"
 	"github.com/palletone/go-palletone/dag/dagconfig"
 	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
@@ -52,7 +56,8 @@ type IUnitRepository interface {
 	//GenesisHeight() modules.ChainIndex
 	SaveUnit(unit *modules.Unit, isGenesis bool) error
 	CreateUnit(mediatorReward common.Address, txpool txspool.ITxPool, when time.Time,
-		propdb IPropRepository, getJurorRewardFunc modules.GetJurorRewardAddFunc) (*modules.Unit, error)
+		propdb IPropRepository, getJurorRewardFunc modules.GetJurorRewardAddFunc,
+		attest *modules.SigShareAttestation, prevGroupSign PrevGroupSignFunc) (*modules.Unit, error)
 	IsGenesis(hash common.Hash) bool
 	GetAddrTransactions(addr common.Address) ([]*modules.TransactionWithUnitInfo, error)
 	GetHeaderByHash(hash common.Hash) (*modules.Header, error)
@@ -105,8 +110,14 @@ type IUnitRepository interface {
 	SubscribeSysContractStateChangeEvent(ob AfterSysContractStateChangeEventFunc)
 	SaveCommon(key, val []byte) error
 	RebuildAddrTxIndex() error
+	GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error)
+	GetContractEventBloom(unitIndex uint64) (types.Bloom, error)
+	SavePrivateState(ps *modules.PrivateState) error
+	GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error)
+	PurgePrivateStates(expireTime uint64) (int, error)
 
 	CheckReadSetValid(contractId []byte, readSet []modules.ContractReadSet) bool
+	CheckRangeReadSetValid(contractId []byte, rangeReadSet []modules.ContractRangeReadSet) bool
 }
 type UnitRepository struct {
 	dagdb          storage.IDagDb
@@ -346,6 +357,41 @@ func (rep *UnitRepository) GetTransactionOnly(hash common.Hash) (*modules.Transa
 	return rep.dagdb.GetTransactionOnly(hash)
 }
 
+func (rep *UnitRepository) GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error) {
+	return rep.idxdb.GetContractEvents(unitIndex)
+}
+
+func (rep *UnitRepository) GetContractEventBloom(unitIndex uint64) (types.Bloom, error) {
+	return rep.idxdb.GetContractEventBloom(unitIndex)
+}
+
+func (rep *UnitRepository) SavePrivateState(ps *modules.PrivateState) error {
+	return rep.idxdb.SavePrivateState(ps)
+}
+
+func (rep *UnitRepository) GetPrivateState(contractId []byte, key string,
+	hash []byte) (*modules.PrivateState, error) {
+	return rep.idxdb.GetPrivateState(contractId, key, hash)
+}
+
+func (rep *UnitRepository) PurgePrivateStates(expireTime uint64) (int, error) {
+	return rep.idxdb.PurgePrivateStates(expireTime, rep.isPrivateStateObsolete)
+}
+
+//isPrivateStateObsolete 链上的Hash已经被替换或删除，或者合约已经停止，本地保存的私有状态才可以清理
+func (rep *UnitRepository) isPrivateStateObsolete(ps *modules.PrivateState) bool {
+	contract, err := rep.statedb.GetContract(ps.ContractId)
+	if err == nil && contract.Status == 0 {
+		return true
+	}
+	hash, _, err := rep.statedb.GetContractState(ps.ContractId, ps.Key)
+	if err != nil {
+		//读取失败时保留，只有确认Key已经被删除时才清理
+		return errors.IsNotFoundError(err)
+	}
+	return !bytes.Equal(hash, ps.Hash)
+}
+
 func (rep *UnitRepository) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
 	return rep.dagdb.GetTxLookupEntry(hash)
 }
@@ -509,6 +555,32 @@ func GetUnitWithSig(unit *modules.Unit, ks *keystore.KeyStore, signer common.Add
 //	return nil
 //}
 
+// headerExtra 需要提交快照Hash的单元，在Header的Extra中写入本地计算的快照Hash，
+// 其他单元写入生产者上一个单元的签名分片贡献情况
+func (rep *UnitRepository) headerExtra(index uint64, propdb IPropRepository,
+	attest *modules.SigShareAttestation) []byte {
+	if !modules.IsSnapshotCommitUnit(index) {
+		if attest == nil {
+			return []byte{}
+		}
+		data, err := rlp.EncodeToBytes(attest)
+		if err != nil || uint64(len(data)) > configure.MaximumExtraDataSize {
+			log.Warnf("Sign share attestation of unit[%d] is too long, skip to record it", attest.UnitIndex)
+			return []byte{}
+		}
+		return data
+	}
+	c, err := propdb.GetSnapshotCommitment(index - modules.SnapshotCommitDelay)
+	if err != nil {
+		log.Warnf("Snapshot hash of unit[%d] not found, skip to commit it", index-modules.SnapshotCommitDelay)
+		return []byte{}
+	}
+	return c.Hash.Bytes()
+}
+
+// PrevGroupSignFunc 返回父单元的群签名，还没有收到时返回空
+type PrevGroupSignFunc func(parent common.Hash) []byte
+
 /**
 创建单元,但是未签名
 create common unit
@@ -516,7 +588,8 @@ create common unit
 return: correct if error is nil, and otherwise is incorrect
 */
 func (rep *UnitRepository) CreateUnit(mediatorReward common.Address, txpool txspool.ITxPool,
-	when time.Time, propdb IPropRepository, getJurorRewardFunc modules.GetJurorRewardAddFunc) (*modules.Unit, error) {
+	when time.Time, propdb IPropRepository, getJurorRewardFunc modules.GetJurorRewardAddFunc,
+	attest *modules.SigShareAttestation, prevGroupSign PrevGroupSignFunc) (*modules.Unit, error) {
 	log.Debug("create unit lock unitRepository.")
 	rep.lock.RLock()
 	defer rep.lock.RUnlock()
@@ -538,8 +611,12 @@ func (rep *UnitRepository) CreateUnit(mediatorReward common.Address, txpool txsp
 
 	// step3. generate genesis unit header
 	b := []byte{}
-	header := modules.NewHeader([]common.Hash{phash}, common.Hash{}, b, b, b, b, []uint16{},
-		chainIndex.AssetID, chainIndex.Index, when.Unix())
+	header := modules.NewHeader([]common.Hash{phash}, common.Hash{}, b, b, rep.headerExtra(chainIndex.Index, propdb, attest),
+		b, []uint16{}, chainIndex.AssetID, chainIndex.Index, when.Unix())
+	//记录父单元的群签名，用于生成该单元的随机数
+	if prevGroupSign != nil {
+		header.SetPrevGroupSign(prevGroupSign(phash))
+	}
 	//if err := sigHeader(header, ks, mediatorReward); err != nil {
 	//	errStr := fmt.Sprintf("GetUnitWithSig error: %v", err.Error())
 	//	log.Debug(errStr)
@@ -631,6 +708,11 @@ func (rep *UnitRepository) CheckReadSetValid(contractId []byte, readSet []module
 	return checkReadSetIsValid(rep.statedb, contractId, readSet)
 }
 
+func (rep *UnitRepository) CheckRangeReadSetValid(contractId []byte,
+	rangeReadSet []modules.ContractRangeReadSet) bool {
+	return checkRangeReadSetIsValid(rep.statedb, contractId, rangeReadSet)
+}
+
 func checkReadSetIsValid(dag storage.IStateDb, contractId []byte, readSet []modules.ContractReadSet) bool {
 	for _, rd := range readSet {
 		_, v, err := dag.GetContractState(contractId, rd.Key)
@@ -646,6 +728,38 @@ func checkReadSetIsValid(dag storage.IStateDb, contractId []byte, readSet []modu
 	return true
 }
 
+//重新执行范围查询，范围内的Key有增删或版本变化都视为冲突(幻读)
+func checkRangeReadSetIsValid(dag storage.IStateDb, contractId []byte,
+	rangeReadSet []modules.ContractRangeReadSet) bool {
+	for _, rr := range rangeReadSet {
+		id := contractId
+		if len(rr.ContractId) > 0 {
+			id = rr.ContractId
+		}
+		//多取一条，范围内新增的Key也能发现
+		rows, err := dag.GetContractStatesByRange(id, rr.StartKey, rr.EndKey, len(rr.Reads)+1)
+		if err != nil {
+			log.Debugf("checkRangeReadSetIsValid, GetContractStatesByRange fail, contractId[%x], error:%s",
+				id, err.Error())
+			return false
+		}
+		if len(rows) != len(rr.Reads) {
+			log.Debugf("checkRangeReadSetIsValid, phantom read, contractId[%x], range[%s,%s), read %d, local %d",
+				id, rr.StartKey, rr.EndKey, len(rr.Reads), len(rows))
+			return false
+		}
+		for i, row := range rows {
+			rd := rr.Reads[i]
+			if row.Key != rd.Key || !row.Version.Equal(rd.Version) {
+				log.Debugf("checkRangeReadSetIsValid, not equal, contractId[%x], key[%s], local ver1[%v], ver2[%v]",
+					id, rd.Key, row.Version, rd.Version)
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func markTxIllegal(dag storage.IStateDb, tx *modules.Transaction) {
 	if tx == nil {
 		return
@@ -657,6 +771,7 @@ func markTxIllegal(dag storage.IStateDb, tx *modules.Transaction) {
 		return
 	}
 	var readSet []modules.ContractReadSet
+	var rangeReadSet []modules.ContractRangeReadSet
 	var contractId []byte
 
 	for _, msg := range tx.Messages() {
@@ -668,14 +783,21 @@ func markTxIllegal(dag storage.IStateDb, tx *modules.Transaction) {
 		case modules.APP_CONTRACT_INVOKE:
 			payload := msg.Payload.(*modules.ContractInvokePayload)
 			readSet = payload.ReadSet
+			rangeReadSet = payload.RangeReadSet
 			contractId = common.CopyBytes(payload.ContractId)
 		case modules.APP_CONTRACT_STOP:
 			payload := msg.Payload.(*modules.ContractStopPayload)
 			readSet = payload.ReadSet
 			contractId = common.CopyBytes(payload.ContractId)
+		case modules.APP_CONTRACT_UPGRADE:
+			payload := msg.Payload.(*modules.ContractUpgradePayload)
+			readSet = payload.ReadSet
+			rangeReadSet = payload.RangeReadSet
+			contractId = common.CopyBytes(payload.ContractId)
 		}
 	}
-	valid := checkReadSetIsValid(dag, contractId, readSet)
+	valid := checkReadSetIsValid(dag, contractId, readSet) &&
+		checkRangeReadSetIsValid(dag, contractId, rangeReadSet)
 	tx.SetIllegal(!valid)
 }
 
@@ -702,7 +824,7 @@ func (rep *UnitRepository) ComputeTxFeesAllocate(mediatorReward common.Address,
 			tempTxs.allUtxo[o] = u
 		}
 		allowcate, err := tx.GetTxFeeAllocate(tempTxs.getUtxoEntryFromTxs,
-			rep.tokenEngine.GetScriptSigners, mediatorReward, getJurorRewardFunc)
+			rep.tokenEngine.GetScriptSigners, mediatorReward, getJurorRewardFunc, rep.getJurorScore)
 		if err != nil {
 			return nil, err
 		}
@@ -712,6 +834,15 @@ func (rep *UnitRepository) ComputeTxFeesAllocate(mediatorReward common.Address,
 	return ads, nil
 }
 
+//陪审员的信誉，用于按信誉分配合约执行费
+func (rep *UnitRepository) getJurorScore(jurorAdd common.Address) uint32 {
+	jr, err := rep.statedb.RetrieveJurorReputation(jurorAdd)
+	if err != nil {
+		log.Warnf("RetrieveJurorReputation(%s) error:%s", jurorAdd.String(), err.Error())
+	}
+	return jr.Score()
+}
+
 //,Mediator奖励
 func (rep *UnitRepository) ComputeGenerateUnitReward(mediatorReward common.Address, asset *modules.Asset) *modules.Addition {
 	a := &modules.Addition{
@@ -957,6 +1088,10 @@ func (rep *UnitRepository) SaveUnit(unit *modules.Unit, isGenesis bool) error {
 		log.Info("SaveBody", "error", err.Error())
 		return err
 	}
+	// save contract events and the bloom of the unit
+	if err := rep.idxdb.SaveContractEvents(unit.NumberU64(), modules.GetContractEventLogs(unit)); err != nil {
+		log.Errorf("save contract events of unit[%s] failed,error: %s", uHash.String(), err.Error())
+	}
 	//step4  Special process genesis unit
 	if isGenesis {
 		if err := rep.propdb.SetNewestUnit(unit.Header()); err != nil {
@@ -987,6 +1122,7 @@ func (rep *UnitRepository) saveTx4Unit(unit *modules.Unit, txIndex int, tx *modu
 	templateId := make([]byte, 0)
 	// traverse messages
 	var installReq *modules.ContractInstallRequestPayload
+	var upgradePolicy *modules.ContractUpgradePolicy
 	reqIndex := tx.GetRequestMsgIndex()
 	for msgIndex, msg := range tx.TxMessages() {
 		// handle different messages
@@ -1006,7 +1142,8 @@ func (rep *UnitRepository) saveTx4Unit(unit *modules.Unit, txIndex int, tx *modu
 			}
 		case modules.APP_CONTRACT_DEPLOY:
 			deploy := msg.Payload.(*modules.ContractDeployPayload)
-			if ok := rep.saveContractInitPayload(unit.Number(), uint32(txIndex), templateId, deploy, requester, unitTime); !ok {
+			if ok := rep.saveContractInitPayload(unit.Number(), uint32(txIndex), templateId, deploy, requester, unitTime,
+				upgradePolicy); !ok {
 				return fmt.Errorf("Save contract init payload error.")
 			}
 		case modules.APP_CONTRACT_INVOKE:
@@ -1017,6 +1154,14 @@ func (rep *UnitRepository) saveTx4Unit(unit *modules.Unit, txIndex int, tx *modu
 			if ok := rep.saveContractStop(reqId, msg); !ok {
 				return fmt.Errorf("save contract stop payload failed.")
 			}
+		case modules.APP_CONTRACT_UPGRADE:
+			if ok := rep.saveContractUpgrade(unit.Number(), uint32(txIndex), reqId, msg, unitTime); !ok {
+				return fmt.Errorf("save contract upgrade payload failed.")
+			}
+		case modules.APP_CONTRACT_JURY_UPDATE:
+			if ok := rep.saveContractJuryUpdate(unit.Number(), uint32(txIndex), msg); !ok {
+				return fmt.Errorf("save contract jury update payload failed.")
+			}
 		case modules.APP_ACCOUNT_UPDATE:
 			if err := rep.updateAccountInfo(msg, requester, unit.Number(), uint32(txIndex)); err != nil {
 				return fmt.Errorf("apply Account Updating Operation error")
@@ -1029,6 +1174,9 @@ func (rep *UnitRepository) saveTx4Unit(unit *modules.Unit, txIndex int, tx *modu
 			}
 			deployReq := msg.Payload.(*modules.ContractDeployRequestPayload)
 			templateId = deployReq.TemplateId
+			upgradePolicy = deployReq.UpgradePolicy
+		case modules.APP_CONTRACT_UPGRADE_REQUEST:
+			//升级请求本身不需要保存，授权在验证时已经检查
 		case modules.APP_CONTRACT_STOP_REQUEST:
 			if ok := rep.saveContractStopReq(reqId, msg); !ok {
 				return fmt.Errorf("save contract of stop request failed.")
@@ -1041,6 +1189,9 @@ func (rep *UnitRepository) saveTx4Unit(unit *modules.Unit, txIndex int, tx *modu
 			if ok := rep.saveSignature(reqId, msg); !ok {
 				return fmt.Errorf("save contract of signature failed.")
 			}
+			if ok := rep.saveJurorReputation(tx, msg); !ok {
+				return fmt.Errorf("save juror reputation failed.")
+			}
 		case modules.APP_DATA:
 			if ok := rep.saveDataPayload(requester, unitHash, unitHeight, unitTime, txHash,
 				msg.Payload.(*modules.DataPayload)); !ok {
@@ -1312,7 +1463,8 @@ func (rep *UnitRepository) saveContractInvokePayload(tx *modules.Transaction, he
 To save contract init state
 */
 func (rep *UnitRepository) saveContractInitPayload(height *modules.ChainIndex, txIndex uint32, templateId []byte,
-	payload *modules.ContractDeployPayload, requester common.Address, unitTime int64) bool {
+	payload *modules.ContractDeployPayload, requester common.Address, unitTime int64,
+	upgradePolicy *modules.ContractUpgradePolicy) bool {
 	//编译源码时，发生错误信息，但是此时因为还没有构建chaincode容器，所以导致contractId为空
 	if payload.ContractId == nil {
 		log.Infof("source codes go build error")
@@ -1340,6 +1492,7 @@ func (rep *UnitRepository) saveContractInitPayload(height *modules.ChainIndex, t
 		v = temC.Version
 	}
 	contract := modules.NewContract(templateId, payload, requester, uint64(unitTime), v)
+	contract.UpgradePolicy = upgradePolicy
 	err := rep.statedb.SaveContract(contract)
 	if err != nil {
 		log.Errorf("Save contract[%x] error:%s", payload.ContractId, err.Error())
@@ -1449,6 +1602,90 @@ func (rep *UnitRepository) saveContractStop(reqid []byte, msg *modules.Message)
 	return true
 }
 
+//saveContractUpgrade 保存迁移的写集，把合约指向新的模板并记录版本
+func (rep *UnitRepository) saveContractUpgrade(height *modules.ChainIndex, txIndex uint32, reqid []byte,
+	msg *modules.Message, unitTime int64) bool {
+	upgrade, ok := msg.Payload.(*modules.ContractUpgradePayload)
+	if !ok {
+		log.Error("saveContractUpgrade", "error", "payload is not the ContractUpgrade type.")
+		return false
+	}
+	//升级失败时合约保持原来的模板
+	if upgrade.ErrMsg.Code != 0 {
+		return true
+	}
+	version := &modules.StateVersion{
+		Height:  height,
+		TxIndex: txIndex,
+	}
+	if len(upgrade.WriteSet) > 0 {
+		err := rep.statedb.SaveContractStates(upgrade.ContractId, upgrade.WriteSet, version)
+		if err != nil {
+			log.Errorf("save contract[%x] migrate writeset error:%s", upgrade.ContractId, err.Error())
+			return false
+		}
+	}
+	contract, err := rep.statedb.GetContract(upgrade.ContractId)
+	if err != nil {
+		log.Info("get contract with id failed,", "error", err)
+		return false
+	}
+	tpl, err := rep.statedb.GetContractTpl(upgrade.TemplateId)
+	if err != nil {
+		log.Errorf("get contract template with id = %x, error:%s", upgrade.TemplateId, err.Error())
+		return false
+	}
+	contract.Upgrade(upgrade.TemplateId, tpl.Version, common.BytesToHash(reqid), uint64(unitTime))
+	if err = rep.statedb.SaveContract(contract); err != nil {
+		log.Errorf("Save contract[%x] error:%s", upgrade.ContractId, err.Error())
+		return false
+	}
+	//更新按陪审员地址保存的合约信息
+	if jury, err := rep.statedb.GetContractJury(upgrade.ContractId); err == nil {
+		for _, node := range jury.EleList {
+			if err := rep.statedb.SaveContractWithJuryAddr(node.AddrHash, contract); err != nil {
+				log.Errorf("SaveContractWithJuryAddr error: %s", err.Error())
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//saveContractJuryUpdate 用重新选举出的陪审团替换合约原来的陪审团
+func (rep *UnitRepository) saveContractJuryUpdate(height *modules.ChainIndex, txIndex uint32,
+	msg *modules.Message) bool {
+	update, ok := msg.Payload.(*modules.ContractJuryUpdatePayload)
+	if !ok {
+		log.Error("saveContractJuryUpdate", "error", "payload is not the ContractJuryUpdate type.")
+		return false
+	}
+	if ok := rep.saveJuryTimeoutReputation(update); !ok {
+		return false
+	}
+	version := &modules.StateVersion{
+		Height:  height,
+		TxIndex: txIndex,
+	}
+	err := rep.statedb.SaveContractJury(update.ContractId, update.EleNode, version)
+	if err != nil {
+		log.Errorf("Save jury for contract[%x] error:%s", update.ContractId, err.Error())
+		return false
+	}
+	contract, err := rep.statedb.GetContract(update.ContractId)
+	if err != nil {
+		log.Info("get contract with id failed,", "error", err)
+		return false
+	}
+	for _, node := range update.EleNode.EleList {
+		if err := rep.statedb.SaveContractWithJuryAddr(node.AddrHash, contract); err != nil {
+			log.Errorf("SaveContractWithJuryAddr error: %s", err.Error())
+			return false
+		}
+	}
+	return true
+}
+
 // saveContractStopReq
 func (rep *UnitRepository) saveContractStopReq(reqid []byte, msg *modules.Message) bool {
 	stop, ok := msg.Payload.(*modules.ContractStopRequestPayload)
@@ -1479,6 +1716,74 @@ func (rep *UnitRepository) saveSignature(reqid []byte, msg *modules.Message) boo
 	return true
 }
 
+//saveJurorReputation 根据用户合约结果交易中的签名，增加签名陪审员的执行次数。
+//结果交易只需要部分陪审员的签名，打包哪些签名由组装交易的陪审员决定，没有签名不能证明陪审员没有执行，不计为Missed
+func (rep *UnitRepository) saveJurorReputation(tx *modules.Transaction, msg *modules.Message) bool {
+	sig, ok := msg.Payload.(*modules.SignaturePayload)
+	if !ok {
+		log.Error("saveJurorReputation", "error", "payload is not the signature type.")
+		return false
+	}
+	contractId := tx.GetContractId()
+	if !common.IsUserContractId(contractId) {
+		return true
+	}
+	jury, err := rep.statedb.GetContractJury(contractId)
+	if err != nil {
+		//合约部署失败时没有陪审团记录
+		log.Debugf("saveJurorReputation, contract[%x] jury not found", contractId)
+		return true
+	}
+	signed := make(map[common.Address]bool)
+	for _, s := range sig.Signatures {
+		signed[crypto.PubkeyBytesToAddress(s.PubKey)] = true
+	}
+	return rep.updateJurorReputations(jury, func(juror common.Address) bool { return signed[juror] },
+		(*modules.JurorReputation).AddExecuted)
+}
+
+//saveJuryTimeoutReputation 陪审团因合约请求超时被替换时，原陪审团中没有对超时确认签名的陪审员计为Missed。
+//替换陪审团需要超过juryNum-sigNum个原陪审员各自在本地确认请求超时且在线的陪审员凑不够签名
+func (rep *UnitRepository) saveJuryTimeoutReputation(update *modules.ContractJuryUpdatePayload) bool {
+	jury, err := rep.statedb.GetContractJury(update.ContractId)
+	if err != nil || jury.Hash() != update.PrevJury {
+		log.Debugf("saveJuryTimeoutReputation, previous jury of contract[%x] not found", update.ContractId)
+		return true
+	}
+	confirmed := make(map[common.Address]bool)
+	for _, s := range update.Signatures {
+		confirmed[crypto.PubkeyBytesToAddress(s.PubKey)] = true
+	}
+	return rep.updateJurorReputations(jury, func(juror common.Address) bool { return !confirmed[juror] },
+		(*modules.JurorReputation).AddMissed)
+}
+
+//updateJurorReputations 更新陪审团中满足条件的陪审员的执行记录
+func (rep *UnitRepository) updateJurorReputations(jury *modules.ElectionNode, match func(common.Address) bool,
+	update func(*modules.JurorReputation)) bool {
+	for _, e := range jury.EleList {
+		//指定地址的陪审员没有公钥，无法确定地址
+		if len(e.PublicKey) == 0 {
+			continue
+		}
+		juror := crypto.PubkeyBytesToAddress(e.PublicKey)
+		if !match(juror) {
+			continue
+		}
+		jr, err := rep.statedb.RetrieveJurorReputation(juror)
+		if err != nil {
+			log.Errorf("RetrieveJurorReputation(%s) error:%s", juror.String(), err.Error())
+			return false
+		}
+		update(jr)
+		if err := rep.statedb.StoreJurorReputation(juror, jr); err != nil {
+			log.Errorf("StoreJurorReputation(%s) error:%s", juror.String(), err.Error())
+			return false
+		}
+	}
+	return true
+}
+
 /**
 从levedb中根据ChainIndex获得Unit信息
 To get unit information by its ChainIndex
@@ -654,6 +654,30 @@ func TestContractTxsIllegal(t *testing.T) {
 	//mark
 	markTxsIllegal(statedb, txs)
 }
+
+func TestCheckRangeReadSetIsValid(t *testing.T) {
+	db, _ := ptndb.NewMemDatabase()
+	statedb := storage.NewStateDb(db)
+	contractId := []byte("RangeContract")
+	version := &modules.StateVersion{Height: &modules.ChainIndex{Index: 10}, TxIndex: 0}
+	ws := []modules.ContractWriteSet{*modules.NewWriteSet("a", []byte("1")), *modules.NewWriteSet("c", []byte("3"))}
+	assert.Nil(t, statedb.SaveContractStates(contractId, ws, version))
+	rangeRead := modules.ContractRangeReadSet{StartKey: "a", EndKey: "d", Reads: []modules.ContractReadSet{
+		{Key: "a", Version: version}, {Key: "c", Version: version}}}
+	assert.True(t, checkRangeReadSetIsValid(statedb, contractId, []modules.ContractRangeReadSet{rangeRead}))
+
+	//范围内新增了Key，幻读
+	version2 := &modules.StateVersion{Height: &modules.ChainIndex{Index: 11}, TxIndex: 0}
+	assert.Nil(t, statedb.SaveContractState(contractId, modules.NewWriteSet("b", []byte("2")), version2))
+	assert.False(t, checkRangeReadSetIsValid(statedb, contractId, []modules.ContractRangeReadSet{rangeRead}))
+	//范围外的变化不影响
+	rangeRead2 := modules.ContractRangeReadSet{StartKey: "c", EndKey: "", Reads: []modules.ContractReadSet{
+		{Key: "c", Version: version}}}
+	assert.True(t, checkRangeReadSetIsValid(statedb, contractId, []modules.ContractRangeReadSet{rangeRead2}))
+	//版本变化
+	assert.Nil(t, statedb.SaveContractState(contractId, modules.NewWriteSet("c", []byte("4")), version2))
+	assert.False(t, checkRangeReadSetIsValid(statedb, contractId, []modules.ContractRangeReadSet{rangeRead2}))
+}
 func markTxsIllegal(dag storage.IStateDb, txs []*modules.Transaction) {
 	for _, tx := range txs {
 		if !tx.IsContractTx() {
@@ -726,3 +750,41 @@ func markTxsIllegal(dag storage.IStateDb, txs []*modules.Transaction) {
 // 	}
 // 	return txs
 // }
+
+func TestSaveJurorReputation_Evidence(t *testing.T) {
+	rep := mockUnitRepository()
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	jury := modules.ElectionNode{JuryCount: 4}
+	addrs := make([]common.Address, 4)
+	pubKeys := make([][]byte, 4)
+	for i := range addrs {
+		prv, _ := crypto.GenerateKey()
+		pubKeys[i] = crypto.CompressPubkey(&prv.PublicKey)
+		addrs[i] = crypto.PubkeyBytesToAddress(pubKeys[i])
+		jury.EleList = append(jury.EleList, modules.ElectionInf{AddrHash: util.RlpHash(addrs[i]), PublicKey: pubKeys[i]})
+	}
+	version := &modules.StateVersion{Height: &modules.ChainIndex{Index: 1}}
+	assert.Nil(t, rep.statedb.SaveContractJury(contractId, jury, version))
+
+	//结果交易中没有签名的陪审员不计为Missed
+	sigs := &modules.SignaturePayload{Signatures: []modules.SignatureSet{
+		{PubKey: pubKeys[0]}, {PubKey: pubKeys[1]}, {PubKey: pubKeys[2]}}}
+	req := &modules.ContractInvokeRequestPayload{ContractId: contractId}
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_CONTRACT_INVOKE_REQUEST, req)})
+	assert.True(t, rep.saveJurorReputation(tx, modules.NewMessage(modules.APP_SIGNATURE, sigs)))
+	for i, addr := range addrs {
+		jr, err := rep.statedb.RetrieveJurorReputation(addr)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), jr.Current.Missed)
+		assert.Equal(t, i < 3, jr.Current.Executed == 1)
+	}
+
+	//陪审团超时被替换时，没有确认超时的陪审员计为Missed
+	update := &modules.ContractJuryUpdatePayload{ContractId: contractId, PrevJury: jury.Hash(),
+		Signatures: []modules.SignatureSet{{PubKey: pubKeys[0]}, {PubKey: pubKeys[1]}}}
+	assert.True(t, rep.saveJuryTimeoutReputation(update))
+	for i, addr := range addrs {
+		jr, _ := rep.statedb.RetrieveJurorReputation(addr)
+		assert.Equal(t, i >= 2, jr.Current.Missed == 1)
+	}
+}
@@ -45,6 +45,8 @@ var (
 	CONTRACT_JURY_PREFIX        = []byte("cj")
 	REQID_TXID_PREFIX           = []byte("rq")
 	MEDIATOR_INFO_PREFIX        = []byte("mi")
+	MEDIATOR_LIVENESS_PREFIX    = []byte("ml") // prefix + address, mediator的出块率记录
+	JUROR_REPUTATION_PREFIX     = []byte("jr") // prefix + address, 陪审员执行合约的记录
 	DEPOSIT_BALANCE_PREFIX      = []byte("db")
 	DEPOSIT_JURY_BALANCE_PREFIX = []byte("djbp")
 	//DEPOSIT_MEDIATOR_VOTE_PREFIX = []byte("dn")
@@ -52,11 +54,15 @@ var (
 	PLEDGE_WITHDRAW_PREFIX = []byte("pw")
 
 	GLOBAL_PROPERTY_HISTORY_PREFIX = []byte("gh")
+	SNAPSHOT_PREFIX                = []byte("sn") // prefix + index, UTXO集和状态快照的Hash
 
 	ACCOUNT_INFO_PREFIX        = []byte("ai")
 	ACCOUNT_PTN_BALANCE_PREFIX = []byte("ab")
 	TOKEN_TXID_PREFIX          = []byte("tt") //IndexDB中存储一个Token关联的TxId
 	TOKEN_EX_PREFIX            = []byte("te") //IndexDB中存储一个Token关联的ProofOfExistence
+	CONTRACT_EVENT_PREFIX      = []byte("ev") //IndexDB中存储一个单元的合约事件，prefix + index
+	CONTRACT_EVENT_BLOOM       = []byte("bl") //IndexDB中存储一个单元合约事件的Bloom，prefix + index
+	PRIVATE_STATE_PREFIX       = []byte("pv") //IndexDB中存储加密的合约私有状态，prefix + contractId + key
 	// lookup
 	LOOKUP_PREFIX              = []byte("lu")
 	UTXO_PREFIX                = []byte("uo")
@@ -69,6 +75,7 @@ var (
 	DYNAMIC_GLOBALPROPERTY_KEY = []byte("dpDynamicGlobalProperty")
 	MEDIATOR_SCHEDULE_KEY      = []byte("msMediatorSchedule")
 	DATA_VERSION_KEY           = []byte("gptnversion")
+	FEE_ESTIMATOR_KEY          = []byte("feFeeEstimator")
 
 	//filehash
 	IDX_MAIN_DATA_TXID              = []byte("md") //Old value: mda
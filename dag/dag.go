@@ -502,6 +502,35 @@ func (d *Dag) GetTxSearchEntry(hash common.Hash) (*modules.TxLookupEntry, error)
 	return txlookup, err
 }
 
+// return the txLookEntry by transaction hash, used by validator
+func (d *Dag) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
+	return d.unstableUnitRep.GetTxLookupEntry(hash)
+}
+
+//GetContractEvents 返回某个高度的单元中的合约事件
+func (d *Dag) GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error) {
+	return d.unstableUnitRep.GetContractEvents(unitIndex)
+}
+
+func (d *Dag) GetContractEventBloom(unitIndex uint64) (types.Bloom, error) {
+	return d.unstableUnitRep.GetContractEventBloom(unitIndex)
+}
+
+//SavePrivateState 在本地保存加密的合约私有状态，私有状态不参与共识
+func (d *Dag) SavePrivateState(ps *modules.PrivateState) error {
+	return d.unstableUnitRep.SavePrivateState(ps)
+}
+
+//GetPrivateState 按链上保存的Hash查询加密的私有状态
+func (d *Dag) GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error) {
+	return d.unstableUnitRep.GetPrivateState(contractId, key, hash)
+}
+
+//PurgePrivateStates 清理超过保留期且已经被替换、删除或合约已停止的私有状态
+func (d *Dag) PurgePrivateStates(expireTime uint64) (int, error) {
+	return d.unstableUnitRep.PurgePrivateStates(expireTime)
+}
+
 // InsertHeaderDag attempts to insert the given header chain in to the local
 // chain, possibly creating a reorg. If an error is returned, it will return the
 // index number of the failing header as well an error describing what went wrong.
@@ -1018,6 +1047,11 @@ func (d *Dag) GetContractStatesByPrefix(id []byte, prefix string) (map[string]*m
 	return d.unstableStateRep.GetContractStatesByPrefix(id, prefix)
 }
 
+func (d *Dag) GetContractStatesByRange(id []byte, startKey, endKey string,
+	limit int) ([]*modules.ContractStateKV, error) {
+	return d.unstableStateRep.GetContractStatesByRange(id, startKey, endKey, limit)
+}
+
 // return electionInfo by contractId
 func (d *Dag) GetContractJury(contractId []byte) (*modules.ElectionNode, error) {
 	return d.unstableStateRep.GetContractJury(contractId)
@@ -1369,6 +1403,10 @@ func (d *Dag) CheckReadSetValid(contractId []byte, readSet []modules.ContractRea
 	return d.unstableUnitRep.CheckReadSetValid(contractId, readSet)
 }
 
+func (d *Dag) CheckRangeReadSetValid(contractId []byte, rangeReadSet []modules.ContractRangeReadSet) bool {
+	return d.unstableUnitRep.CheckRangeReadSetValid(contractId, rangeReadSet)
+}
+
 func (d *Dag) CheckHeaderCorrect(number int) error {
 	ptn := modules.PTNCOIN
 	if number == 0 {
@@ -10,6 +10,7 @@ import (
 	event "github.com/palletone/go-palletone/common/event"
 	discover "github.com/palletone/go-palletone/common/p2p/discover"
 	core "github.com/palletone/go-palletone/core"
+	types "github.com/palletone/go-palletone/core/types"
 	modules "github.com/palletone/go-palletone/dag/modules"
 	txspool "github.com/palletone/go-palletone/txspool"
 	big "math/big"
@@ -461,6 +462,95 @@ func (mr *MockIDagMockRecorder) GetTxSearchEntry(hash interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxSearchEntry", reflect.TypeOf((*MockIDag)(nil).GetTxSearchEntry), hash)
 }
 
+// GetContractEvents mocks base method
+func (m *MockIDag) GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContractEvents", unitIndex)
+	ret0, _ := ret[0].([]*modules.ContractEventLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContractEvents indicates an expected call of GetContractEvents
+func (mr *MockIDagMockRecorder) GetContractEvents(unitIndex interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContractEvents", reflect.TypeOf((*MockIDag)(nil).GetContractEvents), unitIndex)
+}
+
+// GetContractEventBloom mocks base method
+func (m *MockIDag) GetContractEventBloom(unitIndex uint64) (types.Bloom, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContractEventBloom", unitIndex)
+	ret0, _ := ret[0].(types.Bloom)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContractEventBloom indicates an expected call of GetContractEventBloom
+func (mr *MockIDagMockRecorder) GetContractEventBloom(unitIndex interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContractEventBloom", reflect.TypeOf((*MockIDag)(nil).GetContractEventBloom), unitIndex)
+}
+
+// SavePrivateState mocks base method
+func (m *MockIDag) SavePrivateState(ps *modules.PrivateState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePrivateState", ps)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePrivateState indicates an expected call of SavePrivateState
+func (mr *MockIDagMockRecorder) SavePrivateState(ps interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePrivateState", reflect.TypeOf((*MockIDag)(nil).SavePrivateState), ps)
+}
+
+// GetPrivateState mocks base method
+func (m *MockIDag) GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateState", contractId, key, hash)
+	ret0, _ := ret[0].(*modules.PrivateState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivateState indicates an expected call of GetPrivateState
+func (mr *MockIDagMockRecorder) GetPrivateState(contractId, key, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateState", reflect.TypeOf((*MockIDag)(nil).GetPrivateState), contractId, key, hash)
+}
+
+// PurgePrivateStates mocks base method
+func (m *MockIDag) PurgePrivateStates(expireTime uint64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgePrivateStates", expireTime)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgePrivateStates indicates an expected call of PurgePrivateStates
+func (mr *MockIDagMockRecorder) PurgePrivateStates(expireTime interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgePrivateStates", reflect.TypeOf((*MockIDag)(nil).PurgePrivateStates), expireTime)
+}
+
+// GetTxLookupEntry mocks base method
+func (m *MockIDag) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTxLookupEntry", hash)
+	ret0, _ := ret[0].(*modules.TxLookupEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTxLookupEntry indicates an expected call of GetTxLookupEntry
+func (mr *MockIDagMockRecorder) GetTxLookupEntry(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxLookupEntry", reflect.TypeOf((*MockIDag)(nil).GetTxLookupEntry), hash)
+}
+
 // GetTxRequesterAddress mocks base method
 func (m *MockIDag) GetTxRequesterAddress(tx *modules.Transaction) (common.Address, error) {
 	m.ctrl.T.Helper()
@@ -637,6 +727,35 @@ func (mr *MockIDagMockRecorder) GetContractStatesByPrefix(id, prefix interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContractStatesByPrefix", reflect.TypeOf((*MockIDag)(nil).GetContractStatesByPrefix), id, prefix)
 }
 
+// StableStateDag mocks base method
+func (m *MockIDag) StableStateDag() IDag {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StableStateDag")
+	ret0, _ := ret[0].(IDag)
+	return ret0
+}
+
+// StableStateDag indicates an expected call of StableStateDag
+func (mr *MockIDagMockRecorder) StableStateDag() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StableStateDag", reflect.TypeOf((*MockIDag)(nil).StableStateDag))
+}
+
+// GetContractStatesByRange mocks base method
+func (m *MockIDag) GetContractStatesByRange(id []byte, startKey, endKey string, limit int) ([]*modules.ContractStateKV, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContractStatesByRange", id, startKey, endKey, limit)
+	ret0, _ := ret[0].([]*modules.ContractStateKV)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContractStatesByRange indicates an expected call of GetContractStatesByRange
+func (mr *MockIDagMockRecorder) GetContractStatesByRange(id, startKey, endKey, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContractStatesByRange", reflect.TypeOf((*MockIDag)(nil).GetContractStatesByRange), id, startKey, endKey, limit)
+}
+
 // GetContractJury mocks base method
 func (m *MockIDag) GetContractJury(contractId []byte) (*modules.ElectionNode, error) {
 	m.ctrl.T.Helper()
@@ -1150,6 +1269,46 @@ func (mr *MockIDagMockRecorder) SubscribeToGroupSignEvent(ch interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeToGroupSignEvent", reflect.TypeOf((*MockIDag)(nil).SubscribeToGroupSignEvent), ch)
 }
 
+// PostMediatorEquivocationEvent mocks base method
+func (m *MockIDag) PostMediatorEquivocationEvent(ev modules.MediatorEquivocationEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PostMediatorEquivocationEvent", ev)
+}
+
+// PostMediatorEquivocationEvent indicates an expected call of PostMediatorEquivocationEvent
+func (mr *MockIDagMockRecorder) PostMediatorEquivocationEvent(ev interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostMediatorEquivocationEvent", reflect.TypeOf((*MockIDag)(nil).PostMediatorEquivocationEvent), ev)
+}
+
+// SubscribeMediatorEquivocationEvent mocks base method
+func (m *MockIDag) SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeMediatorEquivocationEvent", ch)
+	ret0, _ := ret[0].(event.Subscription)
+	return ret0
+}
+
+// SubscribeMediatorEquivocationEvent indicates an expected call of SubscribeMediatorEquivocationEvent
+func (mr *MockIDagMockRecorder) SubscribeMediatorEquivocationEvent(ch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeMediatorEquivocationEvent", reflect.TypeOf((*MockIDag)(nil).SubscribeMediatorEquivocationEvent), ch)
+}
+
+// SubscribeStableUnitEvent mocks base method
+func (m *MockIDag) SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeStableUnitEvent", ch)
+	ret0, _ := ret[0].(event.Subscription)
+	return ret0
+}
+
+// SubscribeStableUnitEvent indicates an expected call of SubscribeStableUnitEvent
+func (mr *MockIDagMockRecorder) SubscribeStableUnitEvent(ch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeStableUnitEvent", reflect.TypeOf((*MockIDag)(nil).SubscribeStableUnitEvent), ch)
+}
+
 // IsSynced mocks base method
 func (m *MockIDag) IsSynced(toStrictly bool) bool {
 	m.ctrl.T.Helper()
@@ -1685,6 +1844,20 @@ func (mr *MockIDagMockRecorder) GetMediatorInfo(address interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMediatorInfo", reflect.TypeOf((*MockIDag)(nil).GetMediatorInfo), address)
 }
 
+// GetMediatorLiveness mocks base method
+func (m *MockIDag) GetMediatorLiveness(address common.Address) *modules.MediatorLiveness {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMediatorLiveness", address)
+	ret0, _ := ret[0].(*modules.MediatorLiveness)
+	return ret0
+}
+
+// GetMediatorLiveness indicates an expected call of GetMediatorLiveness
+func (mr *MockIDagMockRecorder) GetMediatorLiveness(address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMediatorLiveness", reflect.TypeOf((*MockIDag)(nil).GetMediatorLiveness), address)
+}
+
 // GetVotingForMediator mocks base method
 func (m *MockIDag) GetVotingForMediator(addStr string) (map[string]uint64, error) {
 	m.ctrl.T.Helper()
@@ -1772,6 +1945,22 @@ func (mr *MockIDagMockRecorder) GetNewestUnitTimestamp(token interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNewestUnitTimestamp", reflect.TypeOf((*MockIDag)(nil).GetNewestUnitTimestamp), token)
 }
 
+// GetNewestUnit mocks base method
+func (m *MockIDag) GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNewestUnit", token)
+	ret0, _ := ret[0].(common.Hash)
+	ret1, _ := ret[1].(*modules.ChainIndex)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetNewestUnit indicates an expected call of GetNewestUnit
+func (mr *MockIDagMockRecorder) GetNewestUnit(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNewestUnit", reflect.TypeOf((*MockIDag)(nil).GetNewestUnit), token)
+}
+
 // GetScheduledMediator mocks base method
 func (m *MockIDag) GetScheduledMediator(slotNum uint32) common.Address {
 	m.ctrl.T.Helper()
@@ -1786,6 +1975,21 @@ func (mr *MockIDagMockRecorder) GetScheduledMediator(slotNum interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledMediator", reflect.TypeOf((*MockIDag)(nil).GetScheduledMediator), slotNum)
 }
 
+// GetSnapshotCommitment mocks base method
+func (m *MockIDag) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshotCommitment", index)
+	ret0, _ := ret[0].(*modules.SnapshotCommitment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshotCommitment indicates an expected call of GetSnapshotCommitment
+func (mr *MockIDagMockRecorder) GetSnapshotCommitment(index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotCommitment", reflect.TypeOf((*MockIDag)(nil).GetSnapshotCommitment), index)
+}
+
 // GetSlotAtTime mocks base method
 func (m *MockIDag) GetSlotAtTime(when time.Time) uint32 {
 	m.ctrl.T.Helper()
@@ -1901,6 +2105,20 @@ func (mr *MockIDagMockRecorder) CheckReadSetValid(contractId, readSet interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReadSetValid", reflect.TypeOf((*MockIDag)(nil).CheckReadSetValid), contractId, readSet)
 }
 
+// CheckRangeReadSetValid mocks base method
+func (m *MockIDag) CheckRangeReadSetValid(contractId []byte, rangeReadSet []modules.ContractRangeReadSet) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckRangeReadSetValid", contractId, rangeReadSet)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CheckRangeReadSetValid indicates an expected call of CheckRangeReadSetValid
+func (mr *MockIDagMockRecorder) CheckRangeReadSetValid(contractId, rangeReadSet interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRangeReadSetValid", reflect.TypeOf((*MockIDag)(nil).CheckRangeReadSetValid), contractId, rangeReadSet)
+}
+
 // IsActiveJury mocks base method
 func (m *MockIDag) IsActiveJury(addr common.Address) bool {
 	m.ctrl.T.Helper()
@@ -2077,6 +2295,20 @@ func (mr *MockIDagMockRecorder) GetJurorByAddrHash(hash interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJurorByAddrHash", reflect.TypeOf((*MockIDag)(nil).GetJurorByAddrHash), hash)
 }
 
+// GetJurorReputation mocks base method
+func (m *MockIDag) GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJurorReputation", jurorAdd)
+	ret0, _ := ret[0].(*modules.JurorReputation)
+	return ret0
+}
+
+// GetJurorReputation indicates an expected call of GetJurorReputation
+func (mr *MockIDagMockRecorder) GetJurorReputation(jurorAdd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJurorReputation", reflect.TypeOf((*MockIDag)(nil).GetJurorReputation), jurorAdd)
+}
+
 // GetJurorReward mocks base method
 func (m *MockIDag) GetJurorReward(jurorAdd common.Address) common.Address {
 	m.ctrl.T.Helper()
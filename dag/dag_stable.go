@@ -0,0 +1,100 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package dag
+
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+// stableStateDag 只读取稳定单元状态的Dag视图，用于合约的模拟执行，其他方法与Dag相同
+type stableStateDag struct {
+	*Dag
+}
+
+// StableStateDag returns a view of the dag whose contract states, contracts,
+// utxos and current header are read from the stable units only.
+func (d *Dag) StableStateDag() IDag {
+	return &stableStateDag{Dag: d}
+}
+
+func (d *stableStateDag) StableStateDag() IDag {
+	return d
+}
+
+func (d *stableStateDag) CurrentHeader(token modules.AssetId) *modules.Header {
+	index := d.GetStableChainIndex(token)
+	if index == nil {
+		return nil
+	}
+	header, err := d.stableUnitRep.GetHeaderByNumber(index)
+	if err != nil {
+		log.Errorf("Stable CurrentHeader by token[%s] error:%s", token.String(), err.Error())
+		return nil
+	}
+	return header
+}
+
+func (d *stableStateDag) GetContract(id []byte) (*modules.Contract, error) {
+	return d.stableStateRep.GetContract(id)
+}
+
+func (d *stableStateDag) GetContractTpl(tplId []byte) (*modules.ContractTemplate, error) {
+	return d.stableStateRep.GetContractTpl(tplId)
+}
+
+func (d *stableStateDag) GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error) {
+	return d.stableStateRep.GetContractState(id, field)
+}
+
+func (d *stableStateDag) GetContractStateByVersion(id []byte, field string,
+	version *modules.StateVersion) ([]byte, error) {
+	return d.stableStateRep.GetContractStateByVersion(id, field, version)
+}
+
+func (d *stableStateDag) GetContractStatesById(id []byte) (map[string]*modules.ContractStateValue, error) {
+	return d.stableStateRep.GetContractStatesById(id)
+}
+
+func (d *stableStateDag) GetContractStatesByPrefix(id []byte,
+	prefix string) (map[string]*modules.ContractStateValue, error) {
+	return d.stableStateRep.GetContractStatesByPrefix(id, prefix)
+}
+
+func (d *stableStateDag) GetContractStatesByRange(id []byte, startKey, endKey string,
+	limit int) ([]*modules.ContractStateKV, error) {
+	return d.stableStateRep.GetContractStatesByRange(id, startKey, endKey, limit)
+}
+
+func (d *stableStateDag) GetAddrUtxos(addr common.Address) (map[modules.OutPoint]*modules.Utxo, error) {
+	return d.stableUtxoRep.GetAddrUtxos(addr, nil)
+}
+
+func (d *stableStateDag) GetAddr1TokenUtxos(addr common.Address, asset *modules.Asset) (
+	map[modules.OutPoint]*modules.Utxo, error) {
+	return d.stableUtxoRep.GetAddrUtxos(addr, asset)
+}
+
+func (d *stableStateDag) GetGlobalProp() *modules.GlobalProperty {
+	gp, _ := d.stablePropRep.RetrieveGlobalProp()
+	return gp
+}
@@ -37,7 +37,7 @@ import (
 // GenerateUnit, generate unit
 // @author Albert·Gou
 func (dag *Dag) GenerateUnit(when time.Time, producer common.Address, groupPubKey []byte, ks *keystore.KeyStore,
-	txpool txspool.ITxPool) (*modules.Unit, error) {
+	txpool txspool.ITxPool, attest *modules.SigShareAttestation) (*modules.Unit, error) {
 	t0 := time.Now()
 	defer func(start time.Time) {
 		log.Debugf("GenerateUnit cost time: %v", time.Since(start))
@@ -47,7 +47,7 @@ func (dag *Dag) GenerateUnit(when time.Time, producer common.Address, groupPubKe
 	//log.Debugf("generate unit ...")
 
 	// 2. 生产unit，添加交易集、时间戳、签名
-	unsign_unit, err := dag.createUnit(producer, txpool, when)
+	unsign_unit, err := dag.createUnit(producer, txpool, when, attest)
 	if err != nil {
 		errStr := fmt.Sprintf("createUnit error: %v", err.Error())
 		log.Debug(errStr)
@@ -111,7 +111,7 @@ func (dag *Dag) GenerateUnit(when time.Time, producer common.Address, groupPubKe
 // createUnit, create a unit when mediator being produced
 //创建未签名的Unit
 func (d *Dag) createUnit(mAddr common.Address, txpool txspool.ITxPool,
-	when time.Time) (*modules.Unit, error) {
+	when time.Time, attest *modules.SigShareAttestation) (*modules.Unit, error) {
 
 	med, err := d.unstableStateRep.RetrieveMediator(mAddr)
 	if err != nil {
@@ -120,5 +120,14 @@ func (d *Dag) createUnit(mAddr common.Address, txpool txspool.ITxPool,
 
 	//return d.unstableUnitRep.CreateUnit(med.GetRewardAdd(), txpool, rep, state.GetJurorReward)
 	return d.unstableUnitRep.CreateUnit(med.GetRewardAdd(), txpool, when,
-		d.unstablePropRep, d.unstableStateRep.GetJurorReward)
+		d.unstablePropRep, d.unstableStateRep.GetJurorReward, attest, d.prevGroupSign)
+}
+
+// prevGroupSign 父单元收到群签名后即稳定，群签名保存在稳定数据库的Header中
+func (d *Dag) prevGroupSign(parent common.Hash) []byte {
+	header, err := d.stableUnitRep.GetHeaderByHash(parent)
+	if err != nil {
+		return nil
+	}
+	return header.GetGroupSign()
 }
@@ -120,6 +120,10 @@ func (dag *Dag) GetNewestUnitTimestamp(token modules.AssetId) (int64, error) {
 	return dag.unstablePropRep.GetNewestUnitTimestamp(token)
 }
 
+func (dag *Dag) GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error) {
+	return dag.unstablePropRep.GetNewestUnit(token)
+}
+
 func (dag *Dag) GetSlotTime(slotNum uint32) time.Time {
 	return dag.unstablePropRep.GetSlotTime(slotNum)
 }
@@ -128,6 +132,10 @@ func (dag *Dag) GetScheduledMediator(slotNum uint32) common.Address {
 	return dag.unstablePropRep.GetScheduledMediator(slotNum)
 }
 
+func (dag *Dag) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	return dag.stablePropRep.GetSnapshotCommitment(index)
+}
+
 func (dag *Dag) HeadUnitTime() int64 {
 	gasToken := dagconfig.DagConfig.GetGasToken()
 	t, _ := dag.unstablePropRep.GetNewestUnitTimestamp(gasToken)
@@ -207,6 +215,11 @@ func (d *Dag) GetMediatorInfo(address common.Address) *modules.MediatorInfo {
 	return mi
 }
 
+func (d *Dag) GetMediatorLiveness(address common.Address) *modules.MediatorLiveness {
+	ml, _ := d.unstableStateRep.RetrieveMediatorLiveness(address)
+	return ml
+}
+
 func (d *Dag) JuryCount() uint {
 	juryList, err := d.unstableStateRep.GetJuryCandidateList()
 	if err != nil {
@@ -250,6 +263,10 @@ func (d *Dag) GetJurorReward(jurorAdd common.Address) common.Address {
 	return d.unstableStateRep.GetJurorReward(jurorAdd)
 }
 
+func (d *Dag) GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation {
+	return d.unstableStateRep.GetJurorReputation(jurorAdd)
+}
+
 func (d *Dag) UnstableHeadUnitProperty(asset modules.AssetId) (*modules.UnitProperty, error) {
 	return d.unstablePropRep.GetHeadUnitProperty(asset)
 }
@@ -39,6 +39,18 @@ func (d *Dag) SubscribeToGroupSignEvent(ch chan<- modules.ToGroupSignEvent) even
 	return d.Memdag.SubscribeToGroupSignEvent(ch)
 }
 
+func (d *Dag) SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription {
+	return d.Memdag.SubscribeMediatorEquivocationEvent(ch)
+}
+
+func (d *Dag) SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription {
+	return d.Memdag.SubscribeStableUnitEvent(ch)
+}
+
+func (d *Dag) PostMediatorEquivocationEvent(ev modules.MediatorEquivocationEvent) {
+	d.Memdag.PostMediatorEquivocationEvent(ev)
+}
+
 func (d *Dag) IsActiveMediator(add common.Address) bool {
 	return d.GetGlobalProp().IsActiveMediator(add)
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/p2p/discover"
 	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/core/types"
 	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/txspool"
 )
@@ -67,6 +68,12 @@ type IDag interface {
 	GetStableUnitByNumber(number *modules.ChainIndex) (*modules.Unit, error)
 	IsTransactionExist(hash common.Hash) (bool, error)
 	GetTxSearchEntry(hash common.Hash) (*modules.TxLookupEntry, error)
+	GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error)
+	GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error)
+	GetContractEventBloom(unitIndex uint64) (types.Bloom, error)
+	SavePrivateState(ps *modules.PrivateState) error
+	GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error)
+	PurgePrivateStates(expireTime uint64) (int, error)
 	GetTxRequesterAddress(tx *modules.Transaction) (common.Address, error)
 
 	// InsertHeaderDag inserts a batch of headers into the local chain.
@@ -86,6 +93,9 @@ type IDag interface {
 
 	GetContractStatesById(id []byte) (map[string]*modules.ContractStateValue, error)
 	GetContractStatesByPrefix(id []byte, prefix string) (map[string]*modules.ContractStateValue, error)
+	GetContractStatesByRange(id []byte, startKey, endKey string, limit int) ([]*modules.ContractStateKV, error)
+	//只读取稳定单元状态的视图
+	StableStateDag() IDag
 	GetContractJury(contractId []byte) (*modules.ElectionNode, error)
 	GetUnitNumber(hash common.Hash) (*modules.ChainIndex, error)
 
@@ -129,6 +139,9 @@ type IDag interface {
 	GetTxFee(pay *modules.Transaction) (*modules.AmountAsset, error)
 	SetUnitGroupSign(unitHash common.Hash, groupSign []byte, txpool txspool.ITxPool) error
 	SubscribeToGroupSignEvent(ch chan<- modules.ToGroupSignEvent) event.Subscription
+	SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription
+	SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription
+	PostMediatorEquivocationEvent(ev modules.MediatorEquivocationEvent)
 
 	IsSynced(toStrictly bool) bool
 	SubscribeActiveMediatorsUpdatedEvent(ch chan<- modules.ActiveMediatorsUpdatedEvent) event.Subscription
@@ -180,6 +193,7 @@ type IDag interface {
 	GetActiveMediators() []common.Address
 	GetAccountVotedMediators(addr common.Address) map[string]bool
 	GetMediatorInfo(address common.Address) *modules.MediatorInfo
+	GetMediatorLiveness(address common.Address) *modules.MediatorLiveness
 
 	GetVotingForMediator(addStr string) (map[string]uint64, error)
 	MediatorVotedResults() (map[string]uint64, error)
@@ -188,16 +202,19 @@ type IDag interface {
 	GetMediator(add common.Address) *core.Mediator
 
 	GetNewestUnitTimestamp(token modules.AssetId) (int64, error)
+	GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error)
 	GetScheduledMediator(slotNum uint32) common.Address
 	GetSlotAtTime(when time.Time) uint32
 	GetChainParameters() *core.ChainParameters
 	GetImmutableChainParameters() *core.ImmutableChainParameters
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 
 	GetDataVersion() (*modules.DataVersion, error)
 	StoreDataVersion(dv *modules.DataVersion) error
 	QueryProofOfExistenceByReference(ref []byte) ([]*modules.ProofOfExistence, error)
 	GetAssetReference(asset []byte) ([]*modules.ProofOfExistence, error)
 	CheckReadSetValid(contractId []byte, readSet []modules.ContractReadSet) bool
+	CheckRangeReadSetValid(contractId []byte, rangeReadSet []modules.ContractRangeReadSet) bool
 
 	IsActiveJury(addr common.Address) bool
 	JuryCount() uint
@@ -216,6 +233,7 @@ type IDag interface {
 	RebuildAddrTxIndex() error
 	GetJurorByAddrHash(hash common.Hash) (*modules.JurorDeposit, error)
 	GetJurorReward(jurorAdd common.Address) common.Address
+	GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation
 
 	SubscribeUnstableRepositoryUpdatedEvent(ch chan<- modules.UnstableRepositoryUpdatedEvent) event.Subscription
 	GetContractsWithJuryAddr(addr common.Hash) []*modules.Contract
@@ -61,6 +61,11 @@ type IMemDag interface {
 	//订阅切换主链事件
 	SubscribeSwitchMainChainEvent(ob SwitchMainChainEventFunc)
 	SubscribeToGroupSignEvent(ch chan<- modules.ToGroupSignEvent) event.Subscription
+	//订阅mediator作恶事件
+	SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription
+	PostMediatorEquivocationEvent(ev modules.MediatorEquivocationEvent)
+	//订阅单元稳定事件
+	SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription
 	//关闭
 	Close()
 }
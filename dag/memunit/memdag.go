@@ -35,9 +35,11 @@ import (
 	csort "github.com/palletone/go-palletone/common/sort"
 	"github.com/palletone/go-palletone/core"
 	common2 "github.com/palletone/go-palletone/dag/common"
+	"github.com/palletone/go-palletone/dag/dagconfig"
 	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/dag/palletcache"
+	"github.com/palletone/go-palletone/dag/storage"
 	"github.com/palletone/go-palletone/tokenengine"
 	"github.com/palletone/go-palletone/txspool"
 	"github.com/palletone/go-palletone/validator"
@@ -65,12 +67,22 @@ type MemDag struct {
 	// append by albert·gou 用于通知群签名
 	toGroupSignFeed  event.Feed
 	toGroupSignScope event.SubscriptionScope
-	db               ptndb.Database
-	tokenEngine      tokenengine.ITokenEngine
-	quit             chan struct{} // used for exit
-	observers        []SwitchMainChainEventFunc
+	// 发现mediator对同一高度签名了多个单元时通知
+	equivocationFeed  event.Feed
+	equivocationScope event.SubscriptionScope
+	// 单元稳定后通知，由单独的协程按高度顺序发送，不占用memdag的锁
+	stableUnitFeed  event.Feed
+	stableUnitScope event.SubscriptionScope
+	stableUnitCh    chan *modules.Unit
+	db              ptndb.Database
+	tokenEngine     tokenengine.ITokenEngine
+	quit            chan struct{} // used for exit
+	observers       []SwitchMainChainEventFunc
 }
 
+// 等待发送的稳定单元事件的缓存大小
+const stableUnitChanSize = 1024
+
 func (pmg *MemDag) SubscribeSwitchMainChainEvent(ob SwitchMainChainEventFunc) {
 	if pmg.observers == nil {
 		pmg.observers = []SwitchMainChainEventFunc{}
@@ -80,12 +92,22 @@ func (pmg *MemDag) SubscribeSwitchMainChainEvent(ob SwitchMainChainEventFunc) {
 
 func (pmg *MemDag) Close() {
 	pmg.toGroupSignScope.Close()
+	pmg.equivocationScope.Close()
+	pmg.stableUnitScope.Close()
 }
 
 func (pmg *MemDag) SubscribeToGroupSignEvent(ch chan<- modules.ToGroupSignEvent) event.Subscription {
 	return pmg.toGroupSignScope.Track(pmg.toGroupSignFeed.Subscribe(ch))
 }
 
+func (pmg *MemDag) SubscribeMediatorEquivocationEvent(ch chan<- modules.MediatorEquivocationEvent) event.Subscription {
+	return pmg.equivocationScope.Track(pmg.equivocationFeed.Subscribe(ch))
+}
+
+func (pmg *MemDag) SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription {
+	return pmg.stableUnitScope.Track(pmg.stableUnitFeed.Subscribe(ch))
+}
+
 func (pmg *MemDag) SetStableThreshold(count int) {
 	pmg.lock.Lock()
 	defer pmg.lock.Unlock()
@@ -135,6 +157,7 @@ func NewMemDag(token modules.AssetId, threshold int, saveHeaderOnly bool, db ptn
 		db:                 db,
 		tokenEngine:        tokenEngine,
 		observers:          []SwitchMainChainEventFunc{},
+		stableUnitCh:       make(chan *modules.Unit, stableUnitChanSize),
 	}
 	memdag.stableUnitHash.Store(stablehash)
 	memdag.stableUnitHeight.Store(index)
@@ -150,8 +173,29 @@ func NewMemDag(token modules.AssetId, threshold int, saveHeaderOnly bool, db ptn
 	tpropRep := common2.NewPropRepository4Db(db)
 	memdag.ldbValidator = validator.NewValidate(trep, tutxoRep, tstateRep, tpropRep, cache, saveHeaderOnly)
 	go memdag.loopRebuildTmpDb()
+	go memdag.loopStableUnitEvent()
 	return memdag
 }
+
+func (chain *MemDag) loopStableUnitEvent() {
+	for {
+		select {
+		case unit := <-chain.stableUnitCh:
+			chain.stableUnitFeed.Send(modules.StableUnitEvent{Unit: unit})
+		case <-chain.quit:
+			return
+		}
+	}
+}
+
+// postStableUnitEvent 不阻塞设置稳定单元的流程，订阅者处理不过来时丢弃事件
+func (chain *MemDag) postStableUnitEvent(unit *modules.Unit) {
+	select {
+	case chain.stableUnitCh <- unit:
+	default:
+		log.Warnf("stable unit event queue is full, drop the event of unit[%d]", unit.NumberU64())
+	}
+}
 func (chain *MemDag) loopRebuildTmpDb() {
 	rebuild := time.NewTicker(10 * time.Minute)
 	defer rebuild.Stop()
@@ -337,6 +381,10 @@ func (chain *MemDag) setNextStableUnit(chain_units map[common.Hash]*modules.Unit
 	if !chain.saveHeaderOnly && len(unit.Txs) > 1 {
 		go txpool.SendStoredTxs(unit.Txs.GetTxIds())
 	}
+	if !chain.saveHeaderOnly {
+		chain.processSnapshot(unit)
+	}
+	chain.postStableUnitEvent(unit)
 
 	log.Debugf("Remove unit index[%d],hash[%s] from chainUnits", height, hash.String())
 
@@ -349,6 +397,51 @@ func (chain *MemDag) setNextStableUnit(chain_units map[common.Hash]*modules.Unit
 	return true
 }
 
+// processSnapshot 快照点的单元稳定时在后台保存UTXO集和状态的快照文件并计算快照Hash，
+// 单元Header中提交的快照Hash只用于提示，与本地计算的不一致时记录日志，不影响共识
+func (chain *MemDag) processSnapshot(unit *modules.Unit) {
+	height := unit.NumberU64()
+	if modules.IsSnapshotPoint(height) {
+		//在持有锁时只创建数据库的只读视图，遍历和写文件在后台进行
+		view, err := storage.NewSnapshotView(chain.db)
+		if err != nil {
+			log.Errorf("Create snapshot view of unit[%d] error:%s", height, err.Error())
+		} else {
+			go chain.saveSnapshot(view, unit)
+		}
+	}
+	committed, index, ok := unit.UnitHeader.GetSnapshotHash()
+	if !ok {
+		return
+	}
+	c, err := chain.ldbPropRep.GetSnapshotCommitment(index)
+	if err != nil {
+		log.Warnf("Unit[%d] commits snapshot hash of unit[%d], but local snapshot hash not found",
+			height, index)
+		return
+	}
+	if c.Hash != committed {
+		log.Errorf("Unit[%s] commits snapshot hash %s of unit[%d], but the local is %s",
+			unit.Hash().String(), committed.String(), index, c.Hash.String())
+	}
+}
+
+func (chain *MemDag) saveSnapshot(view *storage.SnapshotView, unit *modules.Unit) {
+	defer view.Release()
+	start := time.Now()
+	height := unit.NumberU64()
+	hash, err := storage.SaveSnapshotFile(view, unit, storage.SnapshotDir(dagconfig.DagConfig.DbPath))
+	if err != nil {
+		log.Errorf("Compute snapshot hash of unit[%d] error:%s", height, err.Error())
+		return
+	}
+	c := &modules.SnapshotCommitment{UnitIndex: height, UnitHash: unit.Hash(), Hash: hash}
+	if err := chain.ldbPropRep.StoreSnapshotCommitment(c); err != nil {
+		log.Errorf("Save snapshot hash of unit[%d] error:%s", height, err.Error())
+	}
+	log.Infof("Snapshot hash of unit[%d] is %s, spent:%s", height, hash.String(), time.Since(start).String())
+}
+
 func (chain *MemDag) checkUnitIrreversibleWithGroupSign(unit *modules.Unit) bool {
 	//if unit.GetGroupPubKeyByte() == nil || unit.GetGroupSign() == nil {
 	//	return false
@@ -779,6 +872,7 @@ func (chain *MemDag) addUnit(unit *modules.Unit, txpool txspool.ITxPool, isGener
 		chain.orphanUnitsParants.Store(unit.ParentHash()[0], uHash)
 	}
 
+	chain.checkEquivocation(unit)
 	chain.addUnitHeight(unit)
 	inter_tmp, has := chain.chainUnits.Load(chain.GetLastMainChainUnit().Hash())
 	if !has {
@@ -803,6 +897,45 @@ func (chain *MemDag) addUnitHeight(unit *modules.Unit) {
 	chain.height_hashs.Store(height, hs)
 }
 
+// 检查是否已经有同一个mediator在同一时间槽或同一父单元上签名的其他单元，有则生成作恶证据并通知。
+// 同一时间槽的单元可能在不同高度，所以检查memdag中的所有单元
+func (chain *MemDag) checkEquivocation(unit *modules.Unit) {
+	author := unit.Author()
+	others := make([]*modules.Unit, 0)
+	collect := func(other *modules.Unit) {
+		if other == nil || other.Hash() == unit.Hash() || other.Author() != author {
+			return
+		}
+		if modules.IsSameSlot(unit.UnitHeader, other.UnitHeader) ||
+			modules.IsSameParent(unit.UnitHeader, other.UnitHeader) {
+			others = append(others, other)
+		}
+	}
+	chain.chainUnits.Range(func(k, v interface{}) bool {
+		collect(v.(*ChainTempDb).Unit)
+		return true
+	})
+	chain.orphanUnits.Range(func(k, v interface{}) bool {
+		collect(v.(*modules.Unit))
+		return true
+	})
+	for _, other := range others {
+		evidence := modules.NewMediatorEquivocationEvidence(unit.UnitHeader, other.UnitHeader)
+		if err := evidence.Validate(); err != nil {
+			log.Debugf("invalid equivocation evidence of mediator[%s]: %s", author.Str(), err.Error())
+			continue
+		}
+		log.Warnf("mediator[%s] signed two conflicting units[%s, %s]", author.Str(), other.Hash().String(),
+			unit.Hash().String())
+		chain.PostMediatorEquivocationEvent(modules.MediatorEquivocationEvent{Evidence: evidence})
+	}
+}
+
+// PostMediatorEquivocationEvent 通知发现的mediator作恶证据，fetcher收到冲突的单元时也通过这里通知
+func (chain *MemDag) PostMediatorEquivocationEvent(ev modules.MediatorEquivocationEvent) {
+	go chain.equivocationFeed.Send(ev)
+}
+
 // 单元稳定后，清空该高度的所有缓存
 func (chain *MemDag) delHeightUnitsAndTemp(height uint64) {
 	to_del_h := make([]uint64, 0)
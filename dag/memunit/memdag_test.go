@@ -289,3 +289,58 @@ func (v mockValidate) CheckTxIsExist(tx *modules.Transaction) bool {
 func (v mockValidate) ValidateTxFeeEnough(tx *modules.Transaction, extSize float64, extTime float64) bool {
 	return true
 }
+
+//同一个mediator在同一高度签名了两个不同的单元，发出作恶事件
+func TestMemDag_MediatorEquivocation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	txpool := txspool.NewMockITxPool(mockCtrl)
+	txpool.EXPECT().SetPendingTxs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	txpool.EXPECT().ResetPendingTxs(gomock.Any()).Return(nil).AnyTimes()
+	u0 := newTestUnit(common.Hash{}, 1, key1)
+
+	db, _ := ptndb.NewMemDatabase()
+	dagDb := storage.NewDagDb(db)
+	utxoDb := storage.NewUtxoDb(db, tokenengine.Instance)
+	stateDb := storage.NewStateDb(db)
+	idxDb := storage.NewIndexDb(db)
+	propDb := storage.NewPropertyDb(db)
+	propDb.SetNewestUnit(u0.UnitHeader)
+	mockMediatorInit(stateDb, propDb)
+	unitRep := dagcommon.NewUnitRepository(dagDb, idxDb, utxoDb, stateDb, propDb, tokenengine.Instance)
+	unitRep.SaveUnit(u0, false)
+	propRep := dagcommon.NewPropRepository(propDb)
+	stateRep := dagcommon.NewStateRepository(stateDb, dagDb)
+	memdag := NewMemDag(modules.PTNCOIN, 2,
+		false, db, unitRep, propRep, stateRep, cache(), tokenengine.Instance)
+	ch := make(chan modules.MediatorEquivocationEvent, 1)
+	sub := memdag.SubscribeMediatorEquivocationEvent(ch)
+	defer sub.Unsubscribe()
+
+	resign := func(unit *modules.Unit, key []byte) *modules.Unit {
+		au := unit.UnitHeader.GetAuthors()
+		au.Signature, _ = crypto.MyCryptoLib.Sign(key, unit.UnitHeader.HashWithoutAuthor().Bytes())
+		unit.UnitHeader.SetAuthor(au)
+		return unit
+	}
+	u1 := resign(newTestUnit(u0.Hash(), 2, key2), key2)
+	_, _, _, _, _, err := memdag.AddUnit(u1, txpool, true)
+	assert.Nil(t, err)
+	//其他mediator在同一高度的单元不是作恶
+	u2 := resign(newTestUnit(common.HexToHash("0x02"), 2, key1), key1)
+	_, _, _, _, _, err = memdag.AddUnit(u2, txpool, true)
+	assert.Nil(t, err)
+	u3 := resign(newTestUnit(common.HexToHash("0x03"), 2, key2), key2)
+	_, _, _, _, _, err = memdag.AddUnit(u3, txpool, true)
+	assert.Nil(t, err)
+
+	select {
+	case ev := <-ch:
+		assert.Nil(t, ev.Evidence.Validate())
+		assert.Equal(t, addr2, ev.Evidence.Mediator())
+		assert.Equal(t, modules.NewMediatorEquivocationEvidence(u1.UnitHeader, u3.UnitHeader).Hash(),
+			ev.Evidence.Hash())
+	case <-time.After(time.Second):
+		t.Fatal("no equivocation event")
+	}
+}
@@ -21,10 +21,11 @@
 package memunit
 
 import (
+	"bytes"
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/ptndb"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
-	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -67,14 +68,14 @@ func (i *TempdbIterator) Next() bool {
 }
 
 func (i *TempdbIterator) Key() []byte {
-	if i.idx == -1 {
+	if i.idx < 0 || i.idx >= len(i.result) {
 		return nil
 	}
 	return i.result[i.idx].Key
 }
 
 func (i *TempdbIterator) Value() []byte {
-	if i.idx == -1 {
+	if i.idx < 0 || i.idx >= len(i.result) {
 		return nil
 	}
 	return i.result[i.idx].Value
@@ -87,17 +88,11 @@ func (i *TempdbIterator) First() bool {
 func (i *TempdbIterator) Last() bool {
 	return i.idx != -1
 }
+
+// Seek 与leveldb一致，移动到第一个不小于key的位置，按前缀创建的迭代器是按key排序的
 func (i *TempdbIterator) Seek(key []byte) bool {
-	if i.idx == -1 {
-		return false
-	}
-	for j := 0; j <= i.idx; j++ {
-		val := i.result[j]
-		if reflect.DeepEqual(key, val.Key) {
-			return true
-		}
-	}
-	return false
+	i.idx = sort.Search(len(i.result), func(j int) bool { return bytes.Compare(i.result[j].Key, key) >= 0 })
+	return i.idx < len(i.result)
 }
 func (i *TempdbIterator) Prev() bool {
 	return i.idx != -1
@@ -141,6 +136,8 @@ func (db *Tempdb) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
 	for k, v := range result {
 		kv = append(kv, KeyValue{[]byte(k), v})
 	}
+	// 与leveldb一致，按key的顺序遍历
+	sort.Slice(kv, func(i, j int) bool { return string(kv[i].Key) < string(kv[j].Key) })
 	return &TempdbIterator{result: kv, idx: -1}
 }
 
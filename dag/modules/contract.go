@@ -20,9 +20,24 @@
 package modules
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
 )
 
+//升级合约时在新模板上调用的迁移函数名，模板可以选择实现
+const ContractMigrateFunc = "__migrate__"
+
+//IsContractMigrateCall 调用的函数是否为迁移函数，迁移函数只能由合约升级请求触发，普通调用不能使用
+func IsContractMigrateCall(args [][]byte) bool {
+	return len(args) > 0 && string(args[0]) == ContractMigrateFunc
+}
+
 type Contract struct {
 	// 根据用户创建合约实例申请的RequestId截取其后20字节生成
 	ContractId   []byte
@@ -33,6 +48,118 @@ type Contract struct {
 	CreationTime uint64 // creation  date
 	DuringTime   uint64 //合约部署持续时间，单位秒
 	Version      string
+	//部署时声明的多签升级策略，为空时只有Creator可以升级
+	UpgradePolicy *ContractUpgradePolicy
+	//合约使用过的模板版本，最后一个为当前版本
+	Versions []*ContractVersion
+}
+
+//ContractUpgradePolicy 合约的多签升级策略，需要至少Threshold个管理员签名才能升级
+type ContractUpgradePolicy struct {
+	Admins    []common.Address `json:"admins"`
+	Threshold uint32           `json:"threshold"`
+}
+
+func (p *ContractUpgradePolicy) Validate() error {
+	if p.Threshold == 0 {
+		return errors.New("upgrade policy threshold must be greater than 0")
+	}
+	if int(p.Threshold) > len(p.Admins) {
+		return fmt.Errorf("upgrade policy threshold %d is greater than admin count %d", p.Threshold, len(p.Admins))
+	}
+	admins := make(map[common.Address]bool)
+	for _, addr := range p.Admins {
+		if admins[addr] {
+			return fmt.Errorf("duplicate upgrade policy admin %s", addr.String())
+		}
+		admins[addr] = true
+	}
+	return nil
+}
+
+func (p *ContractUpgradePolicy) Copy() *ContractUpgradePolicy {
+	admins := make([]common.Address, len(p.Admins))
+	copy(admins, p.Admins)
+	return &ContractUpgradePolicy{Admins: admins, Threshold: p.Threshold}
+}
+
+func (p *ContractUpgradePolicy) isAdmin(addr common.Address) bool {
+	for _, admin := range p.Admins {
+		if admin == addr {
+			return true
+		}
+	}
+	return false
+}
+
+//ContractVersion 合约的一个模板版本，RequestId为部署或者升级请求的Id
+type ContractVersion struct {
+	TemplateId []byte      `json:"template_id"`
+	Version    string      `json:"version"`
+	Time       uint64      `json:"time"`
+	RequestId  common.Hash `json:"request_id"`
+}
+
+//升级策略和版本历史编码在尾部，旧版本保存的合约可以正常解码
+type contractTemp struct {
+	ContractId   []byte
+	TemplateId   []byte
+	Name         string
+	Status       byte
+	Creator      []byte
+	CreationTime uint64
+	DuringTime   uint64
+	Version      string
+	Tail         []contractUpgradeTemp `rlp:"tail"`
+}
+
+type contractUpgradeTemp struct {
+	UpgradePolicy []*ContractUpgradePolicy
+	Versions      []*ContractVersion
+}
+
+//使用值接收者，保证Contract值和指针的RLP编码一致
+func (c Contract) EncodeRLP(w io.Writer) error {
+	temp := &contractTemp{ContractId: c.ContractId, TemplateId: c.TemplateId, Name: c.Name, Status: c.Status,
+		Creator: c.Creator, CreationTime: c.CreationTime, DuringTime: c.DuringTime, Version: c.Version}
+	if c.UpgradePolicy != nil || len(c.Versions) > 0 {
+		upgrade := contractUpgradeTemp{Versions: c.Versions}
+		if c.UpgradePolicy != nil {
+			upgrade.UpgradePolicy = []*ContractUpgradePolicy{c.UpgradePolicy}
+		}
+		temp.Tail = []contractUpgradeTemp{upgrade}
+	}
+	return rlp.Encode(w, temp)
+}
+
+func (c *Contract) DecodeRLP(s *rlp.Stream) error {
+	temp := &contractTemp{}
+	if err := s.Decode(temp); err != nil {
+		return err
+	}
+	if len(temp.Tail) > 1 {
+		return fmt.Errorf("invalid contract, upgrade info count:%d", len(temp.Tail))
+	}
+	c.ContractId = temp.ContractId
+	c.TemplateId = temp.TemplateId
+	c.Name = temp.Name
+	c.Status = temp.Status
+	c.Creator = temp.Creator
+	c.CreationTime = temp.CreationTime
+	c.DuringTime = temp.DuringTime
+	c.Version = temp.Version
+	c.UpgradePolicy = nil
+	c.Versions = nil
+	if len(temp.Tail) == 1 {
+		if len(temp.Tail[0].UpgradePolicy) > 1 {
+			return fmt.Errorf("invalid contract, upgrade policy count:%d", len(temp.Tail[0].UpgradePolicy))
+		}
+		if len(temp.Tail[0].UpgradePolicy) == 1 {
+			c.UpgradePolicy = temp.Tail[0].UpgradePolicy[0]
+		}
+		c.Versions = temp.Tail[0].Versions
+	}
+	return nil
 }
 
 func NewContract(templateId []byte, deploy *ContractDeployPayload, creator common.Address, unitTime uint64, version string) *Contract {
@@ -46,5 +173,56 @@ func NewContract(templateId []byte, deploy *ContractDeployPayload, creator commo
 		Version:      version,
 		DuringTime:   deploy.DuringTime,
 	}
+	c.Versions = []*ContractVersion{{TemplateId: templateId, Version: version, Time: unitTime}}
 	return c
 }
+
+//GetVersions 返回合约的版本历史，旧版本部署的合约没有记录历史，只返回当前版本
+func (c *Contract) GetVersions() []*ContractVersion {
+	if len(c.Versions) > 0 {
+		return c.Versions
+	}
+	return []*ContractVersion{{TemplateId: c.TemplateId, Version: c.Version, Time: c.CreationTime}}
+}
+
+//UpgradeCount 合约已经升级的次数
+func (c *Contract) UpgradeCount() int {
+	return len(c.GetVersions()) - 1
+}
+
+//Upgrade 把合约指向新的模板并记录版本历史
+func (c *Contract) Upgrade(templateId []byte, version string, reqId common.Hash, unitTime uint64) {
+	c.Versions = append(c.GetVersions(), &ContractVersion{TemplateId: templateId, Version: version,
+		Time: unitTime, RequestId: reqId})
+	c.TemplateId = templateId
+	c.Version = version
+}
+
+//CheckUpgradeAuth 检查升级请求的授权，声明了多签升级策略时需要足够的管理员签名，否则只能由创建者升级
+func (c *Contract) CheckUpgradeAuth(requester common.Address, req *ContractUpgradeRequestPayload) error {
+	if c.UpgradePolicy == nil {
+		if !bytes.Equal(c.Creator, requester.Bytes()) {
+			return fmt.Errorf("only the creator %s can upgrade the contract",
+				common.NewAddress(c.Creator, common.PublicKeyHash).String())
+		}
+		return nil
+	}
+	hash := req.UpgradeHash(c.UpgradeCount())
+	signed := make(map[common.Address]bool)
+	for _, sig := range req.Signatures {
+		addr := crypto.PubkeyBytesToAddress(sig.PubKey)
+		if signed[addr] || !c.UpgradePolicy.isAdmin(addr) {
+			continue
+		}
+		valid, err := crypto.MyCryptoLib.Verify(sig.PubKey, sig.Signature, hash.Bytes())
+		if err != nil || !valid {
+			continue
+		}
+		signed[addr] = true
+	}
+	if uint32(len(signed)) < c.UpgradePolicy.Threshold {
+		return fmt.Errorf("upgrade the contract need %d admin signatures, but only %d valid",
+			c.UpgradePolicy.Threshold, len(signed))
+	}
+	return nil
+}
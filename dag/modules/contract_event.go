@@ -0,0 +1,91 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/core/types"
+)
+
+// ContractEvent 合约通过SetEvent设置的事件，随合约执行结果一起写入交易
+type ContractEvent struct {
+	ContractId []byte `json:"contract_id"`
+	Name       string `json:"event_name"`
+	Payload    []byte `json:"payload"`
+}
+
+func (e *ContractEvent) Copy() *ContractEvent {
+	return &ContractEvent{ContractId: common.CopyBytes(e.ContractId), Name: e.Name,
+		Payload: common.CopyBytes(e.Payload)}
+}
+
+// ContractEventLog 保存在IndexDB中的合约事件，记录事件所在的单元和交易
+type ContractEventLog struct {
+	ContractId []byte      `json:"contract_id"`
+	Name       string      `json:"event_name"`
+	Payload    []byte      `json:"payload"`
+	TxHash     common.Hash `json:"tx_hash"`
+	TxIndex    uint32      `json:"tx_index"`
+	UnitHash   common.Hash `json:"unit_hash"`
+	UnitIndex  uint64      `json:"unit_index"`
+	Timestamp  uint64      `json:"timestamp"`
+}
+
+// GetContractEventLogs 返回单元中所有执行成功的合约事件，非法交易和执行出错的合约不产生事件
+func GetContractEventLogs(unit *Unit) []*ContractEventLog {
+	logs := []*ContractEventLog{}
+	for txIndex, tx := range unit.Transactions() {
+		if tx.Illegal() {
+			continue
+		}
+		for _, msg := range tx.TxMessages() {
+			if msg.App != APP_CONTRACT_INVOKE {
+				continue
+			}
+			invoke, ok := msg.Payload.(*ContractInvokePayload)
+			if !ok || invoke.ErrMsg.Code != 0 {
+				continue
+			}
+			for _, ev := range invoke.Events {
+				logs = append(logs, &ContractEventLog{
+					ContractId: common.CopyBytes(ev.ContractId),
+					Name:       ev.Name,
+					Payload:    common.CopyBytes(ev.Payload),
+					TxHash:     tx.Hash(),
+					TxIndex:    uint32(txIndex),
+					UnitHash:   unit.Hash(),
+					UnitIndex:  unit.NumberU64(),
+					Timestamp:  uint64(unit.Timestamp()),
+				})
+			}
+		}
+	}
+	return logs
+}
+
+// CreateContractEventBloom 把合约地址和事件名加入Bloom，用于按合约和事件名过滤单元
+func CreateContractEventBloom(logs []*ContractEventLog) types.Bloom {
+	var bloom types.Bloom
+	for _, l := range logs {
+		bloom.Add(l.ContractId)
+		bloom.Add([]byte(l.Name))
+	}
+	return bloom
+}
@@ -0,0 +1,181 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/palletone/go-palletone/common"
+)
+
+// ContractSchedulePrefix 定时调用合约中保存调度任务的State Key前缀
+const ContractSchedulePrefix = "Schedule-"
+
+// ScheduleRunTimeout 触发后超过该时间(秒)仍没有结算，其他Mediator可以重新触发该任务
+const ScheduleRunTimeout int64 = 600
+
+// scheduledInvokeMarker 定时任务调用请求的最后一个参数，由陪审员检查后去掉，合约看不到该参数
+const scheduledInvokeMarker = "@schedule:"
+
+// ContractSchedule 定时调用任务：在指定的单元高度或时间调用合约的某个方法，
+// 也可以每隔若干个维护周期重复调用，每次调用的手续费从预付的余额中扣除
+type ContractSchedule struct {
+	Id             string   `json:"id"`
+	Creator        string   `json:"creator"`
+	ContractAddr   string   `json:"contract_addr"`
+	Function       string   `json:"function"`
+	Args           []string `json:"args"`
+	TriggerHeight  uint64   `json:"trigger_height"`  //达到该稳定单元高度时触发，为0表示不按高度触发
+	TriggerTime    int64    `json:"trigger_time"`    //达到该单元时间时触发，为0表示不按时间触发
+	IntervalRounds uint32   `json:"interval_rounds"` //重复调用的间隔维护周期数，为0表示只调用一次
+	RemainTimes    uint32   `json:"remain_times"`    //剩余调用次数
+	Runs           uint32   `json:"runs"`            //已经触发的次数
+	FeePerRun      uint64   `json:"fee_per_run"`     //每次调用支付给Mediator的手续费
+	Balance        uint64   `json:"balance"`         //预付手续费的余额
+	Pending        string   `json:"pending"`         //本次运行的触发Mediator，调用执行成功后由其结算
+	PendingReq     string   `json:"pending_req"`     //本次运行的触发请求reqId，调用请求必须带上
+	PendingTime    int64    `json:"pending_time"`    //本次运行的触发时间
+}
+
+// ScheduleRegisterFunc 定时调用合约中注册任务的函数名
+const ScheduleRegisterFunc = "register"
+
+// ScheduleRegisterTarget 定时调用合约注册请求的目标合约，注册请求只能由目标合约的创建者发起
+func ScheduleRegisterTarget(args [][]byte) (common.Address, bool) {
+	if len(args) < 2 || string(args[0]) != ScheduleRegisterFunc {
+		return common.Address{}, false
+	}
+	addr, err := common.StringToAddress(string(args[1]))
+	if err != nil {
+		return common.Address{}, true
+	}
+	return addr, true
+}
+
+// CheckScheduleRegisterAuth 检查注册请求的发起者是否为目标合约的创建者
+func CheckScheduleRegisterAuth(contract *Contract, reqAddr common.Address) error {
+	if contract == nil || !common.IsUserContractId(contract.ContractId) {
+		return errors.New("schedule target is not a user contract")
+	}
+	if !bytes.Equal(contract.Creator, reqAddr.Bytes()) {
+		return fmt.Errorf("only contract creator can register schedule, requester:%s", reqAddr.String())
+	}
+	return nil
+}
+
+// Key 调度任务在合约State中的Key
+func (s *ContractSchedule) Key() string {
+	return ContractSchedulePrefix + s.Id
+}
+
+// InvokeArgs 触发时调用合约的参数
+func (s *ContractSchedule) InvokeArgs() [][]byte {
+	args := make([][]byte, 0, len(s.Args)+1)
+	args = append(args, []byte(s.Function))
+	for _, arg := range s.Args {
+		args = append(args, []byte(arg))
+	}
+	return args
+}
+
+// ScheduledInvokeArgs 触发后Mediator发起调用请求的参数，最后带上任务Id和触发请求的reqId
+func (s *ContractSchedule) ScheduledInvokeArgs() [][]byte {
+	return append(s.InvokeArgs(), []byte(scheduledInvokeMarker+s.Id+":"+s.PendingReq))
+}
+
+// ParseScheduledInvoke 解析调用请求中的定时任务标记，返回任务Id
+func ParseScheduledInvoke(args [][]byte) (string, bool) {
+	if len(args) == 0 {
+		return "", false
+	}
+	last := string(args[len(args)-1])
+	if !strings.HasPrefix(last, scheduledInvokeMarker) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(last, scheduledInvokeMarker), ":", 2)
+	return parts[0], true
+}
+
+// MatchScheduledInvoke 调用请求的合约和参数是否与本次运行一致
+func (s *ContractSchedule) MatchScheduledInvoke(contractId []byte, args [][]byte) bool {
+	if s.Pending == "" {
+		return false
+	}
+	addr, err := common.StringToAddress(s.ContractAddr)
+	if err != nil || !bytes.Equal(addr.Bytes(), contractId) {
+		return false
+	}
+	expect := s.ScheduledInvokeArgs()
+	if len(args) != len(expect) {
+		return false
+	}
+	for i := range args {
+		if !bytes.Equal(args[i], expect[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsScheduledInvokeOk 交易是否为本次运行的调用请求，并且合约执行成功
+func (s *ContractSchedule) IsScheduledInvokeOk(tx *Transaction) bool {
+	matched, executed := false, false
+	for _, msg := range tx.TxMessages() {
+		switch msg.App {
+		case APP_CONTRACT_INVOKE_REQUEST:
+			req, ok := msg.Payload.(*ContractInvokeRequestPayload)
+			matched = ok && s.MatchScheduledInvoke(req.ContractId, req.Args)
+		case APP_CONTRACT_INVOKE:
+			invoke, ok := msg.Payload.(*ContractInvokePayload)
+			executed = ok && invoke.ErrMsg.Code == 0
+		}
+	}
+	return matched && executed
+}
+
+// IsPending 本次运行是否已经触发且还没有超时，now为当前单元的时间
+func (s *ContractSchedule) IsPending(now int64) bool {
+	return s.Pending != "" && now < s.PendingTime+ScheduleRunTimeout
+}
+
+// IsTimeDue 按时间触发的任务是否到期，now为当前最新单元的时间
+func (s *ContractSchedule) IsTimeDue(now int64) bool {
+	return s.TriggerTime > 0 && now >= s.TriggerTime
+}
+
+// Next 调用结算后更新任务，返回任务是否已经结束
+func (s *ContractSchedule) Next(now int64, maintenanceInterval uint32) bool {
+	s.Pending, s.PendingReq, s.PendingTime = "", "", 0
+	s.Runs++
+	s.Balance -= s.FeePerRun
+	if s.RemainTimes > 0 {
+		s.RemainTimes--
+	}
+	if s.RemainTimes == 0 || s.IntervalRounds == 0 || s.Balance < s.FeePerRun {
+		return true
+	}
+	//重复调用统一按时间计算下一次触发
+	s.TriggerHeight = 0
+	s.TriggerTime = now + int64(s.IntervalRounds)*int64(maintenanceInterval)
+	return false
+}
@@ -0,0 +1,95 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractSchedule_Next(t *testing.T) {
+	s := &ContractSchedule{Id: "abc", Function: "put", Args: []string{"a", "1"}, TriggerHeight: 100,
+		IntervalRounds: 2, RemainTimes: 3, FeePerRun: 10, Balance: 30}
+	assert.Equal(t, "Schedule-abc", s.Key())
+	assert.Equal(t, [][]byte{[]byte("put"), []byte("a"), []byte("1")}, s.InvokeArgs())
+	assert.False(t, s.IsTimeDue(1000))
+
+	assert.False(t, s.Next(1000, 600))
+	assert.Equal(t, uint64(0), s.TriggerHeight)
+	assert.Equal(t, int64(2200), s.TriggerTime)
+	assert.Equal(t, uint32(2), s.RemainTimes)
+	assert.Equal(t, uint64(20), s.Balance)
+	assert.False(t, s.IsTimeDue(2199))
+	assert.True(t, s.IsTimeDue(2200))
+
+	assert.False(t, s.Next(2200, 600))
+	assert.True(t, s.Next(3400, 600))
+	assert.Equal(t, uint32(3), s.Runs)
+	assert.Equal(t, uint64(0), s.Balance)
+
+	once := &ContractSchedule{TriggerTime: 100, RemainTimes: 1, FeePerRun: 10, Balance: 15}
+	assert.True(t, once.Next(100, 600))
+	assert.Equal(t, uint64(5), once.Balance)
+}
+
+func TestContractSchedule_ScheduledInvoke(t *testing.T) {
+	contractAddr := common.NewAddress(common.Hex2Bytes("5a3f0e9a0c93b3f83b28f1c5ab9e3c1e7c4b0a11"),
+		common.ContractHash)
+	s := &ContractSchedule{Id: "abc", ContractAddr: contractAddr.String(), Function: "put", Args: []string{"a"},
+		TriggerTime: 100, IntervalRounds: 1, RemainTimes: 2, FeePerRun: 10, Balance: 20}
+	assert.False(t, s.IsPending(100))
+	//没有触发时不能调用
+	assert.False(t, s.MatchScheduledInvoke(contractAddr.Bytes(), s.ScheduledInvokeArgs()))
+
+	s.Pending, s.PendingReq, s.PendingTime = "P1abc", "0x01", 100
+	assert.True(t, s.IsPending(100+ScheduleRunTimeout-1))
+	assert.False(t, s.IsPending(100+ScheduleRunTimeout))
+
+	args := s.ScheduledInvokeArgs()
+	id, ok := ParseScheduledInvoke(args)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", id)
+	_, ok = ParseScheduledInvoke(s.InvokeArgs())
+	assert.False(t, ok)
+	assert.True(t, s.MatchScheduledInvoke(contractAddr.Bytes(), args))
+	assert.False(t, s.MatchScheduledInvoke(contractAddr.Bytes(), s.InvokeArgs()))
+
+	//重新触发后，之前的调用请求不能再用于结算
+	retriggered := *s
+	retriggered.PendingReq = "0x02"
+	assert.False(t, retriggered.MatchScheduledInvoke(contractAddr.Bytes(), args))
+
+	req := NewMessage(APP_CONTRACT_INVOKE_REQUEST, &ContractInvokeRequestPayload{ContractId: contractAddr.Bytes(),
+		Args: args})
+	okResult := NewMessage(APP_CONTRACT_INVOKE, NewContractInvokePayload(contractAddr.Bytes(), nil, nil, nil,
+		ContractError{}))
+	failResult := NewMessage(APP_CONTRACT_INVOKE, NewContractInvokePayload(contractAddr.Bytes(), nil, nil, nil,
+		ContractError{Code: 500, Message: "fail"}))
+	assert.True(t, s.IsScheduledInvokeOk(NewTransaction([]*Message{req, okResult})))
+	assert.False(t, s.IsScheduledInvokeOk(NewTransaction([]*Message{req, failResult})))
+	assert.False(t, s.IsScheduledInvokeOk(NewTransaction([]*Message{req})))
+
+	assert.False(t, s.Next(200, 600))
+	assert.Equal(t, "", s.Pending)
+	assert.Equal(t, "", s.PendingReq)
+	assert.False(t, s.IsPending(200))
+}
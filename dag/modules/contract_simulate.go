@@ -0,0 +1,41 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+// ShimCallTrace 模拟执行时记录的合约发出的一次shim调用
+type ShimCallTrace struct {
+	Step     int    `json:"step"`
+	Contract string `json:"contract"`
+	Type     string `json:"type"`
+	Key      string `json:"key,omitempty"`
+	Size     int    `json:"size"`
+	GasUsed  uint64 `json:"gas_used"` //本次调用完成后累计使用的Gas
+	Error    string `json:"error,omitempty"`
+}
+
+// ContractSimulateResult 合约调用的模拟执行结果，模拟执行不会产生交易，也不会改变任何状态
+type ContractSimulateResult struct {
+	Result *ContractInvokeResult //执行出错时为nil
+	ErrMsg ContractError
+	Trace  []*ShimCallTrace
+	//按执行结果生成合约交易后估算的手续费和交易大小
+	Fee  float64
+	Size float64
+}
@@ -0,0 +1,124 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type testContractV1 struct {
+	ContractId   []byte
+	TemplateId   []byte
+	Name         string
+	Status       byte
+	Creator      []byte
+	CreationTime uint64
+	DuringTime   uint64
+	Version      string
+}
+
+func newTestContract() *Contract {
+	deploy := &ContractDeployPayload{ContractId: []byte("contract"), Name: "test", DuringTime: 100}
+	creator := common.Address{1}
+	return NewContract([]byte("tpl1"), deploy, creator, 1000, "v1")
+}
+
+func TestContract_Rlp(t *testing.T) {
+	//旧版本保存的合约可以解码，并且只有当前一个版本
+	old := &testContractV1{ContractId: []byte("contract"), TemplateId: []byte("tpl1"), Name: "test", Status: 1,
+		Creator: common.Address{1}.Bytes(), CreationTime: 1000, DuringTime: 100, Version: "v1"}
+	data, _ := rlp.EncodeToBytes(old)
+	c := &Contract{}
+	assert.Nil(t, rlp.DecodeBytes(data, c))
+	assert.Equal(t, "v1", c.Version)
+	assert.Nil(t, c.UpgradePolicy)
+	assert.Equal(t, 1, len(c.GetVersions()))
+	assert.Equal(t, 0, c.UpgradeCount())
+
+	c = newTestContract()
+	c.UpgradePolicy = &ContractUpgradePolicy{Admins: []common.Address{{2}, {3}}, Threshold: 1}
+	c.Upgrade([]byte("tpl2"), "v2", common.HexToHash("0x01"), 2000)
+	data, err := rlp.EncodeToBytes(c)
+	assert.Nil(t, err)
+	c2 := &Contract{}
+	assert.Nil(t, rlp.DecodeBytes(data, c2))
+	assert.Equal(t, []byte("tpl2"), c2.TemplateId)
+	assert.Equal(t, "v2", c2.Version)
+	assert.Equal(t, uint32(1), c2.UpgradePolicy.Threshold)
+	assert.Equal(t, 1, c2.UpgradeCount())
+	assert.Equal(t, "v1", c2.GetVersions()[0].Version)
+	assert.Equal(t, common.HexToHash("0x01"), c2.GetVersions()[1].RequestId)
+}
+
+func TestContractUpgradePolicy_Validate(t *testing.T) {
+	assert.NotNil(t, (&ContractUpgradePolicy{Admins: []common.Address{{1}}, Threshold: 0}).Validate())
+	assert.NotNil(t, (&ContractUpgradePolicy{Admins: []common.Address{{1}}, Threshold: 2}).Validate())
+	assert.NotNil(t, (&ContractUpgradePolicy{Admins: []common.Address{{1}, {1}}, Threshold: 1}).Validate())
+	assert.Nil(t, (&ContractUpgradePolicy{Admins: []common.Address{{1}, {2}}, Threshold: 2}).Validate())
+}
+
+func TestContract_CheckUpgradeAuth(t *testing.T) {
+	c := newTestContract()
+	req := &ContractUpgradeRequestPayload{ContractId: c.ContractId, TemplateId: []byte("tpl2"),
+		Args: [][]byte{[]byte("a")}, Timeout: 10}
+	//没有升级策略时只有创建者可以升级
+	assert.Nil(t, c.CheckUpgradeAuth(common.Address{1}, req))
+	assert.NotNil(t, c.CheckUpgradeAuth(common.Address{2}, req))
+
+	keys := make([][]byte, 3)
+	admins := make([]common.Address, 3)
+	for i := range keys {
+		keys[i], _ = crypto.MyCryptoLib.KeyGen()
+		pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(keys[i])
+		admins[i] = crypto.PubkeyBytesToAddress(pubKey)
+	}
+	c.UpgradePolicy = &ContractUpgradePolicy{Admins: admins, Threshold: 2}
+	sign := func(key []byte, count int) SignatureSet {
+		pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(key)
+		sig, _ := crypto.MyCryptoLib.Sign(key, req.UpgradeHash(count).Bytes())
+		return SignatureSet{PubKey: pubKey, Signature: sig}
+	}
+	//声明了升级策略后，创建者也需要足够的管理员签名
+	assert.NotNil(t, c.CheckUpgradeAuth(common.Address{1}, req))
+	req.Signatures = []SignatureSet{sign(keys[0], 0)}
+	assert.NotNil(t, c.CheckUpgradeAuth(common.Address{1}, req))
+	//同一个管理员的重复签名只计算一次
+	req.Signatures = []SignatureSet{sign(keys[0], 0), sign(keys[0], 0)}
+	assert.NotNil(t, c.CheckUpgradeAuth(common.Address{1}, req))
+	req.Signatures = []SignatureSet{sign(keys[0], 0), sign(keys[2], 0)}
+	assert.Nil(t, c.CheckUpgradeAuth(common.Address{2}, req))
+
+	//升级以后之前的签名不能再次使用
+	c.Upgrade([]byte("tpl2"), "v2", common.Hash{}, 2000)
+	assert.NotNil(t, c.CheckUpgradeAuth(common.Address{2}, req))
+	req.Signatures = []SignatureSet{sign(keys[1], 1), sign(keys[2], 1)}
+	assert.Nil(t, c.CheckUpgradeAuth(common.Address{2}, req))
+}
+
+func TestIsContractMigrateCall(t *testing.T) {
+	assert.True(t, IsContractMigrateCall([][]byte{[]byte(ContractMigrateFunc), []byte("a")}))
+	assert.False(t, IsContractMigrateCall([][]byte{[]byte("put"), []byte(ContractMigrateFunc)}))
+	assert.False(t, IsContractMigrateCall(nil))
+}
@@ -58,6 +58,8 @@ const (
 	ApplyForForfeitureDeposit     = "ApplyForForfeitureDeposit"
 	DeveloperPayToDepositContract = "DeveloperPayToDepositContract"
 	JuryPayToDepositContract      = "JuryPayToDepositContract"
+	//提交Mediator作恶证据，自动没收保证金
+	SubmitEquivocationEvidence = "SubmitEquivocationEvidence"
 	//基金会处理
 	HandleForForfeitureApplication = "HandleForForfeitureApplication"
 	HandleForApplyQuitMediator     = "HandleForApplyQuitMediator"
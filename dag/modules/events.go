@@ -5,7 +5,11 @@ import (
 )
 
 // TxPreEvent is posted when a transaction enters the transaction pool.
-type TxPreEvent struct{ Tx *Transaction }
+// Replaced lists the hashes of pool transactions evicted by it (replace-by-fee).
+type TxPreEvent struct {
+	Tx       *Transaction
+	Replaced []common.Hash
+}
 
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
@@ -48,3 +52,8 @@ type ToGroupSignEvent struct {
 
 type UnstableRepositoryUpdatedEvent struct {
 }
+
+// StableUnitEvent 单元稳定并写入数据库后发出，稳定单元不会再被回滚
+type StableUnitEvent struct {
+	Unit *Unit
+}
@@ -31,6 +31,8 @@ type headerJsonTemp struct {
 	Extra       []byte        `json:"extra"`
 	Time        uint32        `json:"creation_time"` // unit create time
 	CryptoLib   []byte        `json:"crypto_lib"`    //该区块使用的加解密算法和哈希算法，0位表示非对称加密算法，1位表示Hash算法
+	//父单元的群签名
+	PrevGroupSign []byte `json:"prev_group_sign,omitempty"`
 }
 
 func (input *Header) MarshalJSON() ([]byte, error) {
@@ -47,6 +49,7 @@ func (input *Header) MarshalJSON() ([]byte, error) {
 	temp.Extra = input.header.Extra
 	temp.Time = uint32(input.header.Time)
 	temp.CryptoLib = input.header.CryptoLib
+	temp.PrevGroupSign = input.header.PrevGroupSign
 	return json.Marshal(temp)
 }
 func (input *Header) UnmarshalJSON(b []byte) error {
@@ -70,5 +73,6 @@ func (input *Header) UnmarshalJSON(b []byte) error {
 	input.header.Extra = temp.Extra
 	input.header.Time = int64(temp.Time)
 	input.header.CryptoLib = temp.CryptoLib
+	input.header.PrevGroupSign = temp.PrevGroupSign
 	return nil
 }
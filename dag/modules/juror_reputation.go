@@ -0,0 +1,116 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+// JurorReputationWindow 执行记录总数超过该值时，Executed和Missed都减半，使较早的记录逐渐失去影响
+const JurorReputationWindow = 1000
+
+// JurorRecord 陪审员执行用户合约的次数，只根据链上可以验证的记录统计。
+// Executed 为签名被打包进合约结果交易的次数，
+// Missed 为合约请求超时导致陪审团被替换时，没有确认超时的次数(掉线或者没有响应)
+type JurorRecord struct {
+	Executed uint64 `json:"executed"`
+	Missed   uint64 `json:"missed"`
+}
+
+// JurorReputation 陪审员的链上执行记录。Current 随合约结果交易逐笔更新，
+// Settled 是上一次维护周期结束时 Current 的快照，分配合约执行费和陪审员选举只使用 Settled，
+// 保证同一个单元在生产和验证时得到相同的结果。
+// Previous 是再上一次维护周期结束时的快照，SettledTime 是上一次固定记录的维护单元的时间，
+// 绑定了单元的选举按该单元时生效的记录计算，不受选举和验证之间发生的维护影响
+type JurorReputation struct {
+	Current     JurorRecord `json:"current"`
+	Settled     JurorRecord `json:"settled"`
+	Previous    JurorRecord `json:"previous"`
+	SettledTime uint64      `json:"settled_time"`
+}
+
+// JurorOutcome 本节点观察到的某个陪审员的执行结果，只在本地统计，不上链
+type JurorOutcome struct {
+	Matched    uint64 `json:"matched"`    //返回的执行结果与本地一致
+	Mismatched uint64 `json:"mismatched"` //返回的读写集与本地不一致
+	Refused    uint64 `json:"refused"`    //本地执行成功，但该陪审员返回合约执行错误
+	TimedOut   uint64 `json:"timed_out"`  //在请求过期前没有返回任何结果
+}
+
+// JurorStatsJson 用于api展示陪审员的信誉
+type JurorStatsJson struct {
+	Address   string        `json:"address"`
+	Candidate bool          `json:"candidate"`
+	Score     uint32        `json:"score"`
+	Current   JurorRecord   `json:"current"`
+	Settled   JurorRecord   `json:"settled"`
+	Local     *JurorOutcome `json:"local_outcome"`
+}
+
+// Score 签名率，以百分比表示，没有任何记录时为100
+func (r JurorRecord) Score() uint32 {
+	if r.Executed+r.Missed == 0 {
+		return 100
+	}
+	return uint32(r.Executed * 100 / (r.Executed + r.Missed))
+}
+
+func NewJurorReputation() *JurorReputation {
+	return &JurorReputation{}
+}
+
+func (jr *JurorReputation) AddExecuted() {
+	jr.Current.Executed++
+	jr.decay()
+}
+
+func (jr *JurorReputation) AddMissed() {
+	jr.Current.Missed++
+	jr.decay()
+}
+
+func (jr *JurorReputation) decay() {
+	if jr.Current.Executed+jr.Current.Missed > JurorReputationWindow {
+		jr.Current.Executed /= 2
+		jr.Current.Missed /= 2
+	}
+}
+
+// Settle 维护周期结束时，固定当前的记录，作为下一个维护周期使用的信誉，unitTime 为维护单元的时间
+func (jr *JurorReputation) Settle(unitTime uint64) {
+	jr.Previous = jr.Settled
+	jr.Settled = jr.Current
+	jr.SettledTime = unitTime
+}
+
+// Score 上一次维护周期结束时的签名率，以百分比表示，没有任何记录时为100
+func (jr *JurorReputation) Score() uint32 {
+	if jr == nil {
+		return 100
+	}
+	return jr.Settled.Score()
+}
+
+// ScoreAt 指定时间的单元上生效的签名率，该单元在上一次维护之前时使用再上一次固定的记录
+func (jr *JurorReputation) ScoreAt(unitTime uint64) uint32 {
+	if jr == nil {
+		return 100
+	}
+	if unitTime < jr.SettledTime {
+		return jr.Previous.Score()
+	}
+	return jr.Settled.Score()
+}
@@ -0,0 +1,101 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJurorReputation(t *testing.T) {
+	var empty *JurorReputation
+	assert.Equal(t, uint32(100), empty.Score())
+	jr := NewJurorReputation()
+	assert.Equal(t, uint32(100), jr.Score())
+
+	for i := 0; i < 3; i++ {
+		jr.AddExecuted()
+	}
+	jr.AddMissed()
+	//维护周期结束前，新的记录不影响信誉
+	assert.Equal(t, uint32(100), jr.Score())
+	assert.Equal(t, uint32(75), jr.Current.Score())
+	jr.Settle(1000)
+	assert.Equal(t, uint32(75), jr.Score())
+	jr.AddMissed()
+	assert.Equal(t, uint32(75), jr.Score())
+	//维护单元之前的单元上生效的仍是原来的记录
+	assert.Equal(t, uint32(100), jr.ScoreAt(999))
+	assert.Equal(t, uint32(75), jr.ScoreAt(1000))
+	jr.Settle(2000)
+	assert.Equal(t, uint32(75), jr.ScoreAt(1999))
+	assert.Equal(t, uint32(60), jr.ScoreAt(2000))
+
+	data, err := rlp.EncodeToBytes(jr)
+	assert.Nil(t, err)
+	decoded := NewJurorReputation()
+	assert.Nil(t, rlp.DecodeBytes(data, decoded))
+	assert.Equal(t, jr, decoded)
+
+	//超过窗口后记录减半，较早的缺席逐渐失去影响
+	jr = &JurorReputation{Current: JurorRecord{Executed: 600, Missed: JurorReputationWindow - 600}}
+	jr.AddExecuted()
+	assert.Equal(t, JurorRecord{Executed: 300, Missed: 200}, jr.Current)
+	jr.Settle(1000)
+	assert.Equal(t, uint32(60), jr.Score())
+}
+
+func TestJurorRewardScores(t *testing.T) {
+	a1, _ := common.StringToAddress("P1NzevLMVCFJKWr4KAcHxyyh9xXaVU8yv3N")
+	a2, _ := common.StringToAddress("P1MdMxNVaKZYdyxxwLZnTYQcJpxAYa6nEvz")
+	jury := []common.Address{a1, a2}
+
+	scores, total := jurorRewardScores(jury, func(juror common.Address) uint32 {
+		if juror == a1 {
+			return 100
+		}
+		return 50
+	})
+	assert.Equal(t, []uint64{100, 50}, scores)
+	assert.Equal(t, uint64(150), total)
+
+	//信誉都为0时平均分配
+	scores, total = jurorRewardScores(jury, func(juror common.Address) uint32 { return 0 })
+	assert.Equal(t, []uint64{1, 1}, scores)
+	assert.Equal(t, uint64(2), total)
+
+	scores, total = jurorRewardScores(jury, nil)
+	assert.Equal(t, []uint64{1, 1}, scores)
+	assert.Equal(t, uint64(2), total)
+}
+
+func TestJurorRewardShare(t *testing.T) {
+	assert.Equal(t, uint64(66), jurorRewardShare(100, 100, 150))
+	assert.Equal(t, uint64(33), jurorRewardShare(100, 50, 150))
+	assert.Equal(t, uint64(50), jurorRewardShare(100, 1, 2))
+
+	//金额很大时不会溢出
+	amount := uint64(1) << 62
+	assert.Equal(t, amount/3*2+amount%3*2/3, jurorRewardShare(amount, 200, 300))
+	assert.Equal(t, amount, jurorRewardShare(amount, 100, 100))
+}
@@ -0,0 +1,117 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/util"
+)
+
+// EquivocationEvidencePrefix 保证金合约中记录已经处理过的作恶证据的State Key前缀
+const EquivocationEvidencePrefix = "Evidence-"
+
+// MediatorEquivocationEvidence Mediator作恶证据：同一个Mediator在同一个时间槽签名了两个不同单元，
+// 或者在同一个父单元上签名了两个不同单元。同一高度但时间槽不同的单元可能是正常的分叉，不算作恶。
+// 证据只包含两个带签名的单元头，任何节点都可以独立验证
+type MediatorEquivocationEvidence struct {
+	HeaderA *Header `json:"header_a"`
+	HeaderB *Header `json:"header_b"`
+}
+
+// MediatorEquivocationEvent memdag发现Mediator作恶时发出的事件
+type MediatorEquivocationEvent struct {
+	Evidence *MediatorEquivocationEvidence
+}
+
+func NewMediatorEquivocationEvidence(a, b *Header) *MediatorEquivocationEvidence {
+	//按单元Hash排序，保证同一对单元生成的证据是一样的
+	if bytes.Compare(a.Hash().Bytes(), b.Hash().Bytes()) > 0 {
+		a, b = b, a
+	}
+	return &MediatorEquivocationEvidence{HeaderA: a, HeaderB: b}
+}
+
+// Mediator 作恶的Mediator地址
+func (e *MediatorEquivocationEvidence) Mediator() common.Address {
+	return e.HeaderA.Author()
+}
+
+// Hash 证据的唯一标识，与两个单元头的先后顺序无关
+func (e *MediatorEquivocationEvidence) Hash() common.Hash {
+	hashA, hashB := e.HeaderA.Hash(), e.HeaderB.Hash()
+	if bytes.Compare(hashA.Bytes(), hashB.Bytes()) > 0 {
+		hashA, hashB = hashB, hashA
+	}
+	return util.RlpHash([]common.Hash{hashA, hashB})
+}
+
+// Key 证据在保证金合约State中的Key
+func (e *MediatorEquivocationEvidence) Key() string {
+	return EquivocationEvidencePrefix + e.Hash().String()
+}
+
+// Validate 验证证据：两个不同的单元由同一个Mediator签名，且签名都有效，
+// 两个单元在同一条链上使用了同一个时间槽，或者有相同的父单元
+func (e *MediatorEquivocationEvidence) Validate() error {
+	if e.HeaderA == nil || e.HeaderB == nil || e.HeaderA.ChainIndex() == nil || e.HeaderB.ChainIndex() == nil {
+		return errors.New("evidence header is empty")
+	}
+	if e.HeaderA.Hash() == e.HeaderB.Hash() {
+		return errors.New("evidence headers are the same unit")
+	}
+	authorA, authorB := e.HeaderA.GetAuthors(), e.HeaderB.GetAuthors()
+	if !bytes.Equal(authorA.PubKey, authorB.PubKey) {
+		return errors.New("evidence headers are signed by different mediators")
+	}
+	if e.HeaderA.GetAssetId() != e.HeaderB.GetAssetId() {
+		return errors.New("evidence headers are in different chains")
+	}
+	if !IsSameSlot(e.HeaderA, e.HeaderB) && !IsSameParent(e.HeaderA, e.HeaderB) {
+		return errors.New("evidence headers are neither in the same slot nor built on the same parent")
+	}
+	for _, h := range []*Header{e.HeaderA, e.HeaderB} {
+		if !VerifyHeaderAuthor(h) {
+			return errors.New("invalid signature of evidence header " + h.Hash().String())
+		}
+	}
+	return nil
+}
+
+// VerifyHeaderAuthor 验证单元头中Mediator的签名
+func VerifyHeaderAuthor(h *Header) bool {
+	author := h.GetAuthors()
+	valid, err := crypto.MyCryptoLib.Verify(author.PubKey, author.Signature, h.HashWithoutAuthor().Bytes())
+	return err == nil && valid
+}
+
+// IsSameSlot Mediator只在自己的时间槽生产单元，单元时间就是时间槽的开始时间
+func IsSameSlot(a, b *Header) bool {
+	return a.Timestamp() == b.Timestamp()
+}
+
+// IsSameParent 两个单元建立在同一个父单元上，说明Mediator在已经生产过单元的链上又分叉出另一个单元
+func IsSameParent(a, b *Header) bool {
+	parentsA, parentsB := a.ParentHash(), b.ParentHash()
+	return len(parentsA) > 0 && len(parentsB) > 0 && parentsA[0] == parentsB[0]
+}
@@ -0,0 +1,77 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignedHeader(key []byte, parent common.Hash, index uint64, t int64) *Header {
+	pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(key)
+	h := NewHeader([]common.Hash{parent}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{}, []uint16{},
+		PTNCOIN, index, t)
+	sig, _ := crypto.MyCryptoLib.Sign(key, h.HashWithoutAuthor().Bytes())
+	h.SetAuthor(Authentifier{PubKey: pubKey, Signature: sig})
+	return h
+}
+
+func TestMediatorEquivocationEvidence_Validate(t *testing.T) {
+	key, _ := crypto.MyCryptoLib.KeyGen()
+	other, _ := crypto.MyCryptoLib.KeyGen()
+	p1 := common.HexToHash("0x01")
+	p2 := common.HexToHash("0x02")
+
+	//同一父单元上的两个单元
+	a := newSignedHeader(key, p1, 10, tt)
+	b := newSignedHeader(key, p1, 10, tt+3)
+	evidence := NewMediatorEquivocationEvidence(a, b)
+	assert.Nil(t, evidence.Validate())
+	assert.Equal(t, a.Author(), evidence.Mediator())
+	assert.Equal(t, evidence.Hash(), NewMediatorEquivocationEvidence(b, a).Hash())
+
+	//JSON序列化后仍然可以验证
+	data, err := json.Marshal(evidence)
+	assert.Nil(t, err)
+	decoded := &MediatorEquivocationEvidence{}
+	assert.Nil(t, json.Unmarshal(data, decoded))
+	assert.Nil(t, decoded.Validate())
+	assert.Equal(t, evidence.Key(), decoded.Key())
+
+	//同一时间槽的两个单元
+	assert.Nil(t, NewMediatorEquivocationEvidence(a, newSignedHeader(key, p2, 11, tt)).Validate())
+	//同一高度但父单元和时间槽都不同，可能是正常的分叉
+	assert.NotNil(t, NewMediatorEquivocationEvidence(a, newSignedHeader(key, p2, 10, tt+3)).Validate())
+	//不同高度不同时间槽
+	assert.NotNil(t, NewMediatorEquivocationEvidence(a, newSignedHeader(key, p2, 11, tt+3)).Validate())
+	//同一个单元
+	assert.NotNil(t, NewMediatorEquivocationEvidence(a, a).Validate())
+	//不同的Mediator
+	assert.NotNil(t, NewMediatorEquivocationEvidence(a, newSignedHeader(other, p2, 10, tt)).Validate())
+
+	//篡改签名
+	forged := newSignedHeader(key, p2, 10, tt)
+	forged.SetAuthor(Authentifier{PubKey: a.GetAuthors().PubKey, Signature: a.GetAuthors().Signature})
+	assert.NotNil(t, NewMediatorEquivocationEvidence(a, forged).Validate())
+}
@@ -0,0 +1,178 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import "github.com/ethereum/go-ethereum/rlp"
+
+// LivenessRound 一个维护周期内mediator被调度的生产槽数和实际生产的单元数，
+// 以及需要其签名分片的单元数和生产者记录的其实际贡献了签名分片的单元数
+type LivenessRound struct {
+	Scheduled           uint32 `json:"scheduled"`
+	Produced            uint32 `json:"produced"`
+	SigShareRequested   uint32 `json:"sig_share_requested"`
+	SigShareContributed uint32 `json:"sig_share_contributed"`
+}
+
+// MediatorLiveness mediator的活跃度记录，Current是当前维护周期，
+// History保存最近K个已经结束的维护周期，按时间先后排列，
+// LastAttested是该mediator作为生产者最近一次记录签名分片贡献情况的单元高度，防止重复记录
+type MediatorLiveness struct {
+	Current      LivenessRound   `json:"current"`
+	History      []LivenessRound `json:"history"`
+	LastAttested uint64          `json:"last_attested"`
+}
+
+// SigShareAttestation 生产者在单元Header的Extra中记录其上一个单元收到了哪些活跃mediator的签名分片，
+// UnitIndex是上一个单元的高度，Contributors是按签名分片的索引(即按地址排序的活跃mediator列表中的位置)组成的位图。
+// 签名分片只有生产者能收到，生产者少记只影响其自己的单元，对每个mediator的贡献率影响有限
+type SigShareAttestation struct {
+	UnitIndex    uint64 `json:"unit_index"`
+	Contributors []byte `json:"contributors"`
+}
+
+func NewSigShareAttestation(unitIndex uint64, contributors []int) *SigShareAttestation {
+	attest := &SigShareAttestation{UnitIndex: unitIndex, Contributors: []byte{}}
+	for _, i := range contributors {
+		if i < 0 {
+			continue
+		}
+		for len(attest.Contributors) <= i/8 {
+			attest.Contributors = append(attest.Contributors, 0)
+		}
+		attest.Contributors[i/8] |= 1 << uint(i%8)
+	}
+	return attest
+}
+
+// Contributed 返回索引为 i 的活跃mediator是否贡献了签名分片
+func (a *SigShareAttestation) Contributed(i int) bool {
+	if i < 0 || i/8 >= len(a.Contributors) {
+		return false
+	}
+	return a.Contributors[i/8]&(1<<uint(i%8)) != 0
+}
+
+// GetSigShareAttestation 返回Header的Extra中记录的签名分片贡献情况，提交快照Hash的单元不记录
+func (h *Header) GetSigShareAttestation() (*SigShareAttestation, bool) {
+	extra := h.Extra()
+	if len(extra) == 0 || IsSnapshotCommitUnit(h.NumberU64()) {
+		return nil, false
+	}
+	attest := &SigShareAttestation{}
+	if err := rlp.DecodeBytes(extra, attest); err != nil {
+		return nil, false
+	}
+	return attest, true
+}
+
+// MediatorLivenessJson 用于api展示mediator的活跃度
+type MediatorLivenessJson struct {
+	Address             string          `json:"address"`
+	Score               uint32          `json:"score"`
+	ProduceScore        uint32          `json:"produce_score"`
+	SigShareScore       uint32          `json:"sig_share_score"`
+	Scheduled           uint64          `json:"scheduled"`
+	Produced            uint64          `json:"produced"`
+	SigShareRequested   uint64          `json:"sig_share_requested"`
+	SigShareContributed uint64          `json:"sig_share_contributed"`
+	Inactive            bool            `json:"inactive"`
+	Current             LivenessRound   `json:"current"`
+	History             []LivenessRound `json:"history"`
+}
+
+func NewMediatorLiveness() *MediatorLiveness {
+	return &MediatorLiveness{History: make([]LivenessRound, 0)}
+}
+
+func (ml *MediatorLiveness) AddProduced() {
+	ml.Current.Scheduled++
+	ml.Current.Produced++
+}
+
+func (ml *MediatorLiveness) AddMissed() {
+	ml.Current.Scheduled++
+}
+
+func (ml *MediatorLiveness) AddSigShare(contributed bool) {
+	ml.Current.SigShareRequested++
+	if contributed {
+		ml.Current.SigShareContributed++
+	}
+}
+
+// CloseRound 维护周期结束时，将当前周期的记录移入历史，只保留最近 rounds 个周期
+func (ml *MediatorLiveness) CloseRound(rounds uint32) {
+	ml.History = append(ml.History, ml.Current)
+	ml.Current = LivenessRound{}
+	if rounds > 0 && uint32(len(ml.History)) > rounds {
+		ml.History = ml.History[uint32(len(ml.History))-rounds:]
+	}
+}
+
+// Totals 历史窗口内总的调度数和生产数
+func (ml *MediatorLiveness) Totals() (scheduled, produced uint64) {
+	for _, r := range ml.History {
+		scheduled += uint64(r.Scheduled)
+		produced += uint64(r.Produced)
+	}
+	return
+}
+
+// SigShareTotals 历史窗口内总的需要签名分片的单元数和贡献了签名分片的单元数
+func (ml *MediatorLiveness) SigShareTotals() (requested, contributed uint64) {
+	for _, r := range ml.History {
+		requested += uint64(r.SigShareRequested)
+		contributed += uint64(r.SigShareContributed)
+	}
+	return
+}
+
+// ProduceScore 历史窗口内的出块率，以百分比表示，窗口内没有被调度时为100
+func (ml *MediatorLiveness) ProduceScore() uint32 {
+	scheduled, produced := ml.Totals()
+	if scheduled == 0 {
+		return 100
+	}
+	return uint32(produced * 100 / scheduled)
+}
+
+// SigShareScore 历史窗口内的签名分片贡献率，以百分比表示，窗口内没有需要签名分片的单元时为100，
+// 贡献情况由生产者自行记录，无法验证，只用于展示，不参与活跃度的判定
+func (ml *MediatorLiveness) SigShareScore() uint32 {
+	requested, contributed := ml.SigShareTotals()
+	if requested == 0 {
+		return 100
+	}
+	return uint32(contributed * 100 / requested)
+}
+
+// Score 历史窗口内用于判定活跃度的参与率，只按链上可以验证的出块率计算
+func (ml *MediatorLiveness) Score() uint32 {
+	return ml.ProduceScore()
+}
+
+// IsInactive 窗口内已经记录满 rounds 个维护周期，且参与率低于阈值，则在换届时不再当选活跃mediator，
+// threshold 为0表示不启用该功能
+func (ml *MediatorLiveness) IsInactive(threshold, rounds uint32) bool {
+	if threshold == 0 || rounds == 0 || uint32(len(ml.History)) < rounds {
+		return false
+	}
+	return ml.Score() < threshold
+}
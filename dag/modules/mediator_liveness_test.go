@@ -0,0 +1,111 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediatorLiveness(t *testing.T) {
+	ml := NewMediatorLiveness()
+	assert.Equal(t, uint32(100), ml.Score())
+	assert.False(t, ml.IsInactive(50, 2))
+
+	//第一个周期 1/4
+	ml.AddProduced()
+	ml.AddMissed()
+	ml.AddMissed()
+	ml.AddMissed()
+	ml.CloseRound(2)
+	assert.Equal(t, uint32(25), ml.Score())
+	//周期数不足时不判定
+	assert.False(t, ml.IsInactive(50, 2))
+
+	//第二个周期 2/4
+	ml.AddProduced()
+	ml.AddProduced()
+	ml.AddMissed()
+	ml.AddMissed()
+	ml.CloseRound(2)
+	assert.Equal(t, uint32(37), ml.Score())
+	assert.True(t, ml.IsInactive(50, 2))
+	assert.False(t, ml.IsInactive(0, 2))
+
+	//第三个周期 4/4，第一个周期移出窗口
+	for i := 0; i < 4; i++ {
+		ml.AddProduced()
+	}
+	ml.CloseRound(2)
+	assert.Equal(t, 2, len(ml.History))
+	scheduled, produced := ml.Totals()
+	assert.Equal(t, uint64(8), scheduled)
+	assert.Equal(t, uint64(6), produced)
+	assert.Equal(t, uint32(75), ml.Score())
+	assert.False(t, ml.IsInactive(50, 2))
+
+	data, err := rlp.EncodeToBytes(ml)
+	assert.Nil(t, err)
+	decoded := NewMediatorLiveness()
+	assert.Nil(t, rlp.DecodeBytes(data, decoded))
+	assert.Equal(t, ml, decoded)
+}
+
+func TestMediatorLiveness_SigShare(t *testing.T) {
+	ml := NewMediatorLiveness()
+	//出块率 2/2，签名分片贡献率 1/4
+	ml.AddProduced()
+	ml.AddProduced()
+	ml.AddSigShare(true)
+	ml.AddSigShare(false)
+	ml.AddSigShare(false)
+	ml.AddSigShare(false)
+	ml.CloseRound(1)
+	assert.Equal(t, uint32(100), ml.ProduceScore())
+	assert.Equal(t, uint32(25), ml.SigShareScore())
+	//签名分片贡献情况由生产者记录，不参与活跃度的判定
+	assert.Equal(t, uint32(100), ml.Score())
+	assert.False(t, ml.IsInactive(70, 1))
+}
+
+func TestSigShareAttestation(t *testing.T) {
+	attest := NewSigShareAttestation(123, []int{0, 2, 9})
+	assert.True(t, attest.Contributed(0))
+	assert.False(t, attest.Contributed(1))
+	assert.True(t, attest.Contributed(2))
+	assert.True(t, attest.Contributed(9))
+	assert.False(t, attest.Contributed(20))
+
+	extra, err := rlp.EncodeToBytes(attest)
+	assert.Nil(t, err)
+	h := NewHeader(nil, common.Hash{}, nil, nil, extra, nil, nil, AssetId{}, 124, 0)
+	decoded, ok := h.GetSigShareAttestation()
+	assert.True(t, ok)
+	assert.Equal(t, attest, decoded)
+
+	//提交快照Hash的单元不记录签名分片贡献情况
+	h = NewHeader(nil, common.Hash{}, nil, nil, extra, nil, nil, AssetId{},
+		SnapshotCommitIndex(SnapshotInterval*2), 0)
+	_, ok = h.GetSigShareAttestation()
+	assert.False(t, ok)
+}
@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
 	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/util"
 	"strings"
 )
 
@@ -40,6 +42,8 @@ const (
 
 	APP_DATA
 	APP_ACCOUNT_UPDATE
+	APP_CONTRACT_UPGRADE
+	APP_CONTRACT_JURY_UPDATE
 
 	APP_UNKNOW = 99
 
@@ -47,8 +51,10 @@ const (
 	APP_CONTRACT_DEPLOY_REQUEST = 101
 	APP_CONTRACT_INVOKE_REQUEST = 102
 	APP_CONTRACT_STOP_REQUEST   = 103
+	//合约升级请求
+	APP_CONTRACT_UPGRADE_REQUEST = 104
 	// 为了兼容起见:
-	// 添加別的request需要添加在 APP_CONTRACT_TPL_REQUEST 与 APP_CONTRACT_STOP_REQUEST 之间
+	// 添加別的request需要添加在 APP_CONTRACT_TPL_REQUEST 与 APP_CONTRACT_UPGRADE_REQUEST 之间
 	// 添加别的msg类型，需要添加到 APP_ACCOUNT_UPDATE 与 APP_UNKNOW之间
 )
 
@@ -160,7 +166,7 @@ func CopyMessage(cpyMsg *Message) *Message {
 		writeSet := []ContractWriteSet{}
 		for _, ws := range payload.WriteSet {
 			cs := ContractWriteSet{Key: ws.Key, Value: common.CopyBytes(ws.Value), IsDelete: ws.IsDelete,
-				ContractId: common.CopyBytes(ws.ContractId)}
+				ContractId: common.CopyBytes(ws.ContractId), IsPrivate: ws.IsPrivate}
 			writeSet = append(writeSet, cs)
 		}
 		newPayload.ReadSet = readSet
@@ -195,11 +201,20 @@ func CopyMessage(cpyMsg *Message) *Message {
 		writeSet := []ContractWriteSet{}
 		for _, ws := range payload.WriteSet {
 			cs := ContractWriteSet{Key: ws.Key, Value: common.CopyBytes(ws.Value), IsDelete: ws.IsDelete,
-				ContractId: common.CopyBytes(ws.ContractId)}
+				ContractId: common.CopyBytes(ws.ContractId), IsPrivate: ws.IsPrivate}
 			writeSet = append(writeSet, cs)
 		}
 		newPayload.ReadSet = readSet
 		newPayload.WriteSet = writeSet
+		if len(payload.RangeReadSet) > 0 {
+			newPayload.RangeReadSet = make([]ContractRangeReadSet, 0, len(payload.RangeReadSet))
+			for _, rr := range payload.RangeReadSet {
+				newPayload.RangeReadSet = append(newPayload.RangeReadSet, rr.Copy())
+			}
+		}
+		for _, ev := range payload.Events {
+			newPayload.Events = append(newPayload.Events, ev.Copy())
+		}
 		msg.Payload = &newPayload
 
 	case APP_CONTRACT_STOP:
@@ -221,7 +236,7 @@ func CopyMessage(cpyMsg *Message) *Message {
 		writeSet := []ContractWriteSet{}
 		for _, ws := range payload.WriteSet {
 			cs := ContractWriteSet{Key: ws.Key, Value: common.CopyBytes(ws.Value), IsDelete: ws.IsDelete,
-				ContractId: common.CopyBytes(ws.ContractId)}
+				ContractId: common.CopyBytes(ws.ContractId), IsPrivate: ws.IsPrivate}
 			writeSet = append(writeSet, cs)
 		}
 		temp_load.ReadSet = readSet
@@ -264,6 +279,9 @@ func CopyMessage(cpyMsg *Message) *Message {
 			temp_load.Args = make([][]byte, 0)
 			temp_load.Args = append(temp_load.Args, payload.Args...)
 		}
+		if payload.UpgradePolicy != nil {
+			temp_load.UpgradePolicy = payload.UpgradePolicy.Copy()
+		}
 		msg.Payload = &temp_load
 	case APP_CONTRACT_INVOKE_REQUEST:
 		payload, _ := cpyMsg.Payload.(*ContractInvokeRequestPayload)
@@ -271,6 +289,7 @@ func CopyMessage(cpyMsg *Message) *Message {
 			ContractId: common.CopyBytes(payload.ContractId),
 			Args:       payload.Args,
 			Timeout:    payload.Timeout,
+			GasLimit:   payload.GasLimit,
 		}
 		if len(payload.Args) > 0 {
 			newPayload.Args = make([][]byte, 0)
@@ -283,6 +302,41 @@ func CopyMessage(cpyMsg *Message) *Message {
 		temp_load := *payload
 		temp_load.ContractId = common.CopyBytes(payload.ContractId)
 		msg.Payload = &temp_load
+	case APP_CONTRACT_UPGRADE_REQUEST:
+		payload, _ := cpyMsg.Payload.(*ContractUpgradeRequestPayload)
+		temp_load := *payload
+		temp_load.ContractId = common.CopyBytes(payload.ContractId)
+		temp_load.TemplateId = common.CopyBytes(payload.TemplateId)
+		if len(payload.Args) > 0 {
+			temp_load.Args = make([][]byte, 0)
+			temp_load.Args = append(temp_load.Args, payload.Args...)
+		}
+		if len(payload.Signatures) > 0 {
+			temp_load.Signatures = make([]SignatureSet, len(payload.Signatures))
+			copy(temp_load.Signatures, payload.Signatures)
+		}
+		msg.Payload = &temp_load
+	case APP_CONTRACT_UPGRADE:
+		payload, _ := cpyMsg.Payload.(*ContractUpgradePayload)
+		temp_load := *payload
+		temp_load.ContractId = common.CopyBytes(payload.ContractId)
+		temp_load.TemplateId = common.CopyBytes(payload.TemplateId)
+		temp_load.ReadSet = make([]ContractReadSet, len(payload.ReadSet))
+		copy(temp_load.ReadSet, payload.ReadSet)
+		temp_load.WriteSet = make([]ContractWriteSet, len(payload.WriteSet))
+		copy(temp_load.WriteSet, payload.WriteSet)
+		temp_load.RangeReadSet = make([]ContractRangeReadSet, len(payload.RangeReadSet))
+		copy(temp_load.RangeReadSet, payload.RangeReadSet)
+		msg.Payload = &temp_load
+	case APP_CONTRACT_JURY_UPDATE:
+		payload, _ := cpyMsg.Payload.(*ContractJuryUpdatePayload)
+		temp_load := *payload
+		temp_load.ContractId = common.CopyBytes(payload.ContractId)
+		temp_load.EleNode.EleList = make([]ElectionInf, len(payload.EleNode.EleList))
+		copy(temp_load.EleNode.EleList, payload.EleNode.EleList)
+		temp_load.Signatures = make([]SignatureSet, len(payload.Signatures))
+		copy(temp_load.Signatures, payload.Signatures)
+		msg.Payload = &temp_load
 	}
 
 	return &msg
@@ -331,6 +385,18 @@ func (msg *Message) CompareMessages(inMsg *Message) bool {
 		payA, _ := msg.Payload.(*ContractStopRequestPayload)
 		payB, _ := inMsg.Payload.(*ContractStopRequestPayload)
 		return payA.Equal(payB)
+	case APP_CONTRACT_UPGRADE_REQUEST:
+		payA, _ := msg.Payload.(*ContractUpgradeRequestPayload)
+		payB, _ := inMsg.Payload.(*ContractUpgradeRequestPayload)
+		return payA.Equal(payB)
+	case APP_CONTRACT_UPGRADE:
+		payA, _ := msg.Payload.(*ContractUpgradePayload)
+		payB, _ := inMsg.Payload.(*ContractUpgradePayload)
+		return payA.Equal(payB)
+	case APP_CONTRACT_JURY_UPDATE:
+		payA, _ := msg.Payload.(*ContractJuryUpdatePayload)
+		payB, _ := inMsg.Payload.(*ContractJuryUpdatePayload)
+		return payA.Equal(payB)
 	}
 	return true
 }
@@ -340,6 +406,8 @@ type ContractWriteSet struct {
 	Key        string `json:"key"`
 	Value      []byte `json:"value"`
 	ContractId []byte `json:"contract_id"`
+	//私有状态，Value只是明文的Hash，明文加密保存在陪审员本地
+	IsPrivate bool `json:"is_private"`
 }
 
 func NewWriteSet(key string, value []byte) *ContractWriteSet {
@@ -362,6 +430,13 @@ type ContractStateValue struct {
 	Version *StateVersion `json:"version"`
 }
 
+// ContractStateKV 范围查询返回的合约状态，按Key排序
+type ContractStateKV struct {
+	Key     string        `json:"key"`
+	Value   []byte        `json:"value"`
+	Version *StateVersion `json:"version"`
+}
+
 func (version *StateVersion) String() string {
 	if version == nil {
 		return `null`
@@ -432,6 +507,45 @@ type ContractError struct {
 	Message string `json:"error_message"` // error data
 }
 
+//合约执行的错误码
+const (
+	ContractErrCodeDefault  uint32 = 500
+	ContractErrCodeOutOfGas uint32 = 501
+)
+
+//合约调用的Gas，计算shim调用次数、读写的状态字节数和Token操作
+const (
+	DefaultContractGasLimit uint64 = 10000000
+	MaxContractGasLimit     uint64 = 1000000000
+	//MinContractGasLimit 手续费至少要能支付的Gas，不足时请求在提交和执行时都被拒绝
+	MinContractGasLimit uint64 = 100000
+)
+
+//ContractOutOfGasMsg Gas耗尽时的错误信息，所有陪审员得到相同的错误
+const ContractOutOfGasMsg = "contract out of gas"
+
+//ContractGasPrice 每单位Gas的价格(dao)，合约调用按实际使用的Gas从请求的手续费中扣除
+const ContractGasPrice uint64 = 1
+
+//ContractGasFee 使用gas个单位的Gas需要支付的手续费
+func ContractGasFee(gas uint64) uint64 {
+	return gas * ContractGasPrice
+}
+
+//ContractGasOfFee 手续费最多可以支付的Gas
+func ContractGasOfFee(fee uint64) uint64 {
+	return fee / ContractGasPrice
+}
+
+//ContractOutOfGasError Gas耗尽的错误，只由节点的GasMeter产生，合约自己返回的错误信息不会被当作Gas耗尽
+type ContractOutOfGasError struct {
+	Limit uint64
+}
+
+func (e *ContractOutOfGasError) Error() string {
+	return fmt.Sprintf("%s, gas limit:%d", ContractOutOfGasMsg, e.Limit)
+}
+
 //node election
 type ElectionInf struct {
 	EType     byte        `json:"election_type"` //vrf type, if set to 1, it is the assignation node
@@ -445,12 +559,66 @@ type ElectionNode struct {
 	EleList   []ElectionInf `json:"ele_list"`   //
 }
 
+func (e *ElectionNode) Hash() common.Hash {
+	return util.RlpHash(e)
+}
+
+//SameJurors 陪审员及其顺序都相同，赎回脚本按陪审员顺序生成，顺序不同时赎回脚本也不同
+func (e *ElectionNode) SameJurors(other *ElectionNode) bool {
+	if other == nil || len(e.EleList) != len(other.EleList) {
+		return false
+	}
+	for i, ele := range e.EleList {
+		if ele.AddrHash != other.EleList[i].AddrHash {
+			return false
+		}
+	}
+	return true
+}
+
+//HasAddrHash 陪审团中是否包含该地址hash的陪审员
+func (e *ElectionNode) HasAddrHash(addrHash common.Hash) bool {
+	for _, ele := range e.EleList {
+		if ele.AddrHash == addrHash {
+			return true
+		}
+	}
+	return false
+}
+
 type ContractReadSet struct {
 	Key        string        `json:"key"`
 	Version    *StateVersion `json:"version" rlp:"nil"`
 	ContractId []byte        `json:"contract_id"`
 }
 
+// ContractRangeReadSet 合约范围查询的读集，记录[StartKey,EndKey)范围内读到的所有Key及其版本。
+// 验证时重新执行范围查询，范围内的Key有增删(幻读)或者版本变化都视为冲突
+type ContractRangeReadSet struct {
+	StartKey   string            `json:"start_key"`
+	EndKey     string            `json:"end_key"` // 为空表示没有上界
+	Reads      []ContractReadSet `json:"reads"`
+	ContractId []byte            `json:"contract_id"`
+}
+
+// Copy 深拷贝范围读集
+func (r *ContractRangeReadSet) Copy() ContractRangeReadSet {
+	cpy := ContractRangeReadSet{StartKey: r.StartKey, EndKey: r.EndKey,
+		ContractId: common.CopyBytes(r.ContractId), Reads: make([]ContractReadSet, 0, len(r.Reads))}
+	for _, rd := range r.Reads {
+		cs := ContractReadSet{Key: rd.Key, ContractId: common.CopyBytes(rd.ContractId)}
+		if rd.Version != nil {
+			version := *rd.Version
+			if rd.Version.Height != nil {
+				version.Height = &ChainIndex{AssetID: rd.Version.Height.AssetID, Index: rd.Version.Height.Index}
+			}
+			cs.Version = &version
+		}
+		cpy.Reads = append(cpy.Reads, cs)
+	}
+	return cpy
+}
+
 //请求合约信息
 type InvokeInfo struct {
 	InvokeAddress common.Address  `json:"invoke_address"` //请求地址
@@ -475,6 +643,8 @@ type TokenPayOut struct {
 	Amount   uint64
 	PayTo    common.Address
 	LockTime uint32
+	//被调用合约付出Token时为被调用合约的地址，为空表示从本合约付出
+	PayFrom common.Address
 }
 
 //用户钱包发起的合约调用申请
@@ -504,6 +674,8 @@ type ContractDeployRequestPayload struct {
 	Args       [][]byte `json:"args"`
 	ExtData    []byte   `json:"extend_data"`
 	Timeout    uint32   `json:"timeout"`
+	//多签升级策略，为空时只有合约的创建者可以升级合约，没有设置时编码结果与旧版本一致
+	UpgradePolicy *ContractUpgradePolicy `json:"upgrade_policy,omitempty"`
 }
 
 type ContractDeployPayload struct {
@@ -524,6 +696,19 @@ type ContractInvokeRequestPayload struct {
 	ContractId []byte   `json:"contract_id"` // contract id
 	Args       [][]byte `json:"args"`        // contract arguments list
 	Timeout    uint32   `json:"timeout"`
+	//Gas上限，0表示使用默认值DefaultContractGasLimit
+	GasLimit uint64 `json:"gas_limit,omitempty"`
+}
+
+// GetGasLimit returns the declared gas limit, or the default one if it is not declared
+func (p *ContractInvokeRequestPayload) GetGasLimit() uint64 {
+	if p.GasLimit == 0 {
+		return DefaultContractGasLimit
+	}
+	if p.GasLimit > MaxContractGasLimit {
+		return MaxContractGasLimit
+	}
+	return p.GasLimit
 }
 
 //如果是用户想修改自己的State信息，那么ContractId可以为空或�?0字节
@@ -534,6 +719,12 @@ type ContractInvokePayload struct {
 	WriteSet   []ContractWriteSet `json:"write_set"`      // the set data of write, and value could be any type
 	Payload    []byte             `json:"payload"`        // the contract execution result
 	ErrMsg     ContractError      `json:"contract_error"` // contract error message
+	//范围查询的读集，没有范围查询时编码结果与旧版本一致
+	RangeReadSet []ContractRangeReadSet `json:"range_read_set,omitempty"`
+	//合约执行中通过SetEvent设置的事件，没有事件时编码结果与旧版本一致
+	Events []*ContractEvent `json:"events,omitempty"`
+	//合约执行使用的Gas，按ContractGasPrice从手续费中扣除，为0时编码结果与旧版本一致
+	GasUsed uint64 `json:"gas_used,omitempty"`
 }
 
 // App: contract_stop
@@ -550,6 +741,50 @@ type ContractStopPayload struct {
 	ErrMsg     ContractError      `json:"contract_error"` // contract error message
 }
 
+// App: contract_upgrade
+//把已部署的合约指向新的模板，合约地址、状态和锁定在合约地址上的Token保持不变，Args作为Migrate的参数
+type ContractUpgradeRequestPayload struct {
+	ContractId []byte   `json:"contract_id"`
+	TemplateId []byte   `json:"template_id"` // new contract template id
+	Args       [][]byte `json:"args"`        // migrate arguments list
+	Timeout    uint32   `json:"timeout"`
+	//合约部署时声明了多签升级策略时，管理员对UpgradeHash的签名
+	Signatures []SignatureSet `json:"signature_set"`
+}
+
+type ContractUpgradePayload struct {
+	ContractId   []byte                 `json:"contract_id"`    // contract id
+	TemplateId   []byte                 `json:"template_id"`    // new contract template id
+	ReadSet      []ContractReadSet      `json:"read_set"`       // the read set of Migrate
+	WriteSet     []ContractWriteSet     `json:"write_set"`      // the write set of Migrate
+	RangeReadSet []ContractRangeReadSet `json:"range_read_set"` // the range read set of Migrate
+	ErrMsg       ContractError          `json:"contract_error"` // contract error message
+}
+
+// App: contract_jury_update
+//合约的陪审员掉线导致无法收集足够签名时，陪审员重新选举并用该消息把新的陪审团记录到链上。
+//原陪审团中至少JuryUpdateSignThreshold个陪审员各自确认请求超时后，对ElectionId签名，证明陪审团已无法完成签名
+type ContractJuryUpdatePayload struct {
+	ContractId []byte         `json:"contract_id"`   // user contract id
+	PrevJury   common.Hash    `json:"prev_jury"`     // hash of the jury being replaced
+	SeedUnit   common.Hash    `json:"seed_unit"`     // a recent unit, the election seed is bound to it
+	EleNode    ElectionNode   `json:"election_node"` // the new jury
+	Signatures []SignatureSet `json:"signatures"`    // signatures of the previous jurors on ElectionId
+}
+
+//JuryUpdateSeedMaxAge 陪审团更新交易引用的单元与最新单元之间最多相差的高度
+const JuryUpdateSeedMaxAge = 30
+
+//JuryUpdateSignThreshold 替换陪审团需要的原陪审员签名数。
+//超过juryNum-sigNum个陪审员确认超时，说明剩余的陪审员无法凑够sigNum个签名
+func JuryUpdateSignThreshold(juryNum, sigNum int) int {
+	threshold := juryNum - sigNum + 1
+	if threshold < 1 {
+		threshold = 1
+	}
+	return threshold
+}
+
 //contract invoke result
 type ContractInvokeResult struct {
 	ContractId  []byte             `json:"contract_id"` // contract id
@@ -562,6 +797,14 @@ type ContractInvokeResult struct {
 	TokenSupply []*TokenSupply     `json:"token_supply"`   //增发Token请求产生的结果
 	TokenDefine *TokenDefine       `json:"token_define"`   //定义新Token
 	ErrMsg      ContractError      `json:"contract_error"` // contract error message
+	//范围查询的读集
+	RangeReadSet []ContractRangeReadSet `json:"range_read_set"`
+	GasUsed      uint64                 `json:"gas_used"`
+	//本次调用中执行成功的被调用合约，读写集和付款已经合并到本结果中
+	Callees []common.Address `json:"callees"`
+	Events  []*ContractEvent `json:"events"`
+	//私有状态的明文和盐，不写入交易，由陪审员加密后保存在本地
+	PrivateWriteSet []*PrivateWrite `json:"-"`
 }
 
 type SignaturePayload struct {
@@ -652,6 +895,17 @@ func NewContractStopPayload(contractid []byte, readset []ContractReadSet, writes
 	}
 }
 
+func NewContractUpgradePayload(contractid []byte, templateId []byte, readset []ContractReadSet,
+	writeset []ContractWriteSet, err ContractError) *ContractUpgradePayload {
+	return &ContractUpgradePayload{
+		ContractId: contractid,
+		TemplateId: templateId,
+		ReadSet:    readset,
+		WriteSet:   writeset,
+		ErrMsg:     err,
+	}
+}
+
 func (a *ElectionInf) Equal(b *ElectionInf) bool {
 	if b == nil {
 		return false
@@ -697,7 +951,7 @@ func (a *ContractWriteSet) Equal(b *ContractWriteSet) bool {
 	if !(a.IsDelete == b.IsDelete) || !strings.EqualFold(a.Key, b.Key) || !bytes.Equal(a.Value, b.Value) {
 		return false
 	}
-	return true
+	return a.IsPrivate == b.IsPrivate
 }
 
 func (a *ContractTplPayload) Equal(b *ContractTplPayload) bool {
@@ -803,6 +1057,57 @@ func (a *ContractStopRequestPayload) Equal(b *ContractStopRequestPayload) bool {
 
 }
 
+func (a *ContractUpgradeRequestPayload) Equal(b *ContractUpgradeRequestPayload) bool {
+	rlpA, err := rlp.EncodeToBytes(a)
+	if err != nil {
+		return false
+	}
+	rlpB, err := rlp.EncodeToBytes(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(rlpA, rlpB)
+}
+
+//UpgradeHash 管理员需要签名的内容，包含合约已经升级的次数，防止签名被重放到以后的升级中
+func (a *ContractUpgradeRequestPayload) UpgradeHash(upgradeCount int) common.Hash {
+	return util.RlpHash([]interface{}{a.ContractId, a.TemplateId, a.Args, a.Timeout, uint64(upgradeCount)})
+}
+
+func (a *ContractUpgradePayload) Equal(b *ContractUpgradePayload) bool {
+	rlpA, err := rlp.EncodeToBytes(a)
+	if err != nil {
+		return false
+	}
+	rlpB, err := rlp.EncodeToBytes(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(rlpA, rlpB)
+}
+
+func (a *ContractJuryUpdatePayload) Equal(b *ContractJuryUpdatePayload) bool {
+	rlpA, err := rlp.EncodeToBytes(a)
+	if err != nil {
+		return false
+	}
+	rlpB, err := rlp.EncodeToBytes(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(rlpA, rlpB)
+}
+
+//ElectionId 重新选举的标识，由合约、被替换的陪审团和发起时的最新单元决定，同一个陪审团只能被替换一次
+func (a *ContractJuryUpdatePayload) ElectionId() common.Hash {
+	return util.RlpHash([]interface{}{a.ContractId, a.PrevJury, a.SeedUnit})
+}
+
+//ElectionSeed 重新选举时VRF使用的种子，与合约部署选举的种子计算方式相同
+func (a *ContractJuryUpdatePayload) ElectionSeed() []byte {
+	return crypto.RequestIdToContractAddress(a.ElectionId()).Bytes()
+}
+
 type SysTokenIDInfo struct {
 	CreateAddr     string
 	TotalSupply    uint64
@@ -22,6 +22,34 @@ package modules
 
 const defaultTxInOutAlloc = 0
 
+const (
+	// SequenceLockTimeDisabled is a flag that if set on a transaction
+	// input's sequence number, the sequence number will not be interpreted
+	// as a relative locktime.
+	SequenceLockTimeDisabled = 1 << 31
+
+	// SequenceLockTimeIsSeconds is a flag that if set on a transaction
+	// input's sequence number, the relative locktime has units of 512
+	// seconds.
+	SequenceLockTimeIsSeconds = 1 << 22
+
+	// SequenceReplaceable is a flag that if set on a transaction input's
+	// sequence number, the transaction opts in to be replaced in the
+	// transaction pool by a conflicting one which pays a higher fee.
+	SequenceReplaceable = 1 << 30
+
+	// SequenceLockTimeMask is a mask that extracts the relative locktime
+	// when masked against the transaction input sequence number.
+	SequenceLockTimeMask = 0x0000ffff
+
+	// SequenceLockTimeGranularity is the defined time based granularity
+	// for seconds-based relative time locks. When converting from seconds
+	// to a sequence number, the value is right shifted by this amount,
+	// therefore the granularity of relative time locks in 512 or 2^9
+	// seconds. Enforced relative lock times are multiples of 512 seconds.
+	SequenceLockTimeGranularity = 9
+)
+
 // Token exchange message and verify message
 // App: payment
 type PaymentPayload struct {
@@ -40,11 +68,13 @@ type Input struct {
 	// if user creating a new asset, this field should be it's config data. Otherwise it is null.
 	Extra            []byte    `json:"extra" rlp:"nil"`
 	PreviousOutPoint *OutPoint `json:"pre_outpoint"`
+	// relative lock time of this input, see SequenceLockTimeMask. 0 means no relative lock.
+	Sequence uint32 `json:"sequence"`
 }
 
 // NewTxIn returns a new ptn transaction input with the provided
-// previous outpoint point and signature script with a default sequence of
-// MaxTxInSequenceNum.
+// previous outpoint point and signature script with a default sequence of 0,
+// which means no relative lock time.
 func NewTxIn(prevOut *OutPoint, signatureScript []byte) *Input {
 	return &Input{
 		PreviousOutPoint: prevOut,
@@ -80,6 +110,45 @@ func (pay *PaymentPayload) IsCoinbase() bool {
 	return false
 }
 
+// HasRelativeLockTime returns true if the input's sequence number should be
+// interpreted as a relative lock time.
+func (input *Input) HasRelativeLockTime() bool {
+	return input.Sequence&SequenceLockTimeDisabled == 0 && input.Sequence&SequenceLockTimeMask != 0
+}
+
+// IsReplaceable returns true if the input signals opt-in replace-by-fee.
+func (input *Input) IsReplaceable() bool {
+	return input.Sequence&SequenceReplaceable == SequenceReplaceable
+}
+
+// RelativeLockTime returns the relative lock encoded in the sequence number.
+// If isSeconds is true, lock is the number of seconds the spent output must be
+// confirmed, otherwise it is the number of units.
+func (input *Input) RelativeLockTime() (lock int64, isSeconds bool) {
+	if !input.HasRelativeLockTime() {
+		return 0, false
+	}
+	lock = int64(input.Sequence & SequenceLockTimeMask)
+	if input.Sequence&SequenceLockTimeIsSeconds == SequenceLockTimeIsSeconds {
+		return lock << SequenceLockTimeGranularity, true
+	}
+	return lock, false
+}
+
+// NewSequenceLockByUnits returns the sequence number of a relative lock time
+// which lock the spent output for the given number of units.
+func NewSequenceLockByUnits(units uint32) uint32 {
+	return units & SequenceLockTimeMask
+}
+
+// NewSequenceLockBySeconds returns the sequence number of a relative lock time
+// which lock the spent output for at least the given seconds.
+func NewSequenceLockBySeconds(seconds uint32) uint32 {
+	// round up to the granularity, so the lock is never shorter than required
+	lock := (seconds + (1 << SequenceLockTimeGranularity) - 1) >> SequenceLockTimeGranularity
+	return SequenceLockTimeIsSeconds | (lock & SequenceLockTimeMask)
+}
+
 // AddTxIn adds a transaction input to the message.
 func (pld *PaymentPayload) AddTxIn(ti *Input) {
 	pld.Inputs = append(pld.Inputs, ti)
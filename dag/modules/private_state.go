@@ -0,0 +1,153 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/crypto/ecies"
+	"github.com/palletone/go-palletone/common/util"
+)
+
+// PrivateStateCollection 合约读写私有状态时使用的Collection名称
+const PrivateStateCollection = "private"
+
+// PrivateStateKeyPrefix 私有状态在合约状态中的Key前缀，私有状态与公开状态的Key互不冲突
+const PrivateStateKeyPrefix = "\x01private\x01"
+
+var ErrPrivateStateNotFound = errors.New("private state not found or not encrypted to local jury")
+
+// PrivateStateKey 合约私有状态Key对应的合约状态Key
+func PrivateStateKey(key string) string {
+	return PrivateStateKeyPrefix + key
+}
+
+// IsPrivateStateKey 合约状态Key是否属于私有状态
+func IsPrivateStateKey(key string) bool {
+	return strings.HasPrefix(key, PrivateStateKeyPrefix)
+}
+
+// PrivateStateSaltLength 私有状态Hash使用的随机盐的长度
+const PrivateStateSaltLength = 32
+
+// NewPrivateStateSalt 生成计算私有状态Hash的随机盐
+func NewPrivateStateSalt() ([]byte, error) {
+	salt := make([]byte, PrivateStateSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// PrivateStateHash 私有状态上链的Hash，加入随机盐防止对取值范围较小的明文穷举，
+// 盐与明文一起加密保存在本地
+func PrivateStateHash(contractId []byte, key string, value []byte, salt []byte) []byte {
+	h := util.RlpHash([]interface{}{contractId, key, value, salt})
+	return h.Bytes()
+}
+
+// PrivateWrite 合约执行产生的私有状态明文和计算Hash使用的盐，不写入交易
+type PrivateWrite struct {
+	ContractId []byte
+	Key        string
+	Value      []byte
+	IsDelete   bool
+	Salt       []byte
+}
+
+// Hash 私有状态写入链上的Hash
+func (pw *PrivateWrite) Hash() []byte {
+	return PrivateStateHash(pw.ContractId, pw.Key, pw.Value, pw.Salt)
+}
+
+// privateStatePlain 私有状态加密前的明文，包括计算Hash的盐
+type privateStatePlain struct {
+	Value []byte
+	Salt  []byte
+}
+
+// PrivateStateCipher 用一个陪审员的公钥加密的私有状态
+type PrivateStateCipher struct {
+	PubKey []byte `json:"pub_key"`
+	Cipher []byte `json:"cipher"`
+}
+
+// PrivateState 保存在本地的私有状态，链上只保存Hash，明文用合约陪审团每个成员的公钥加密
+type PrivateState struct {
+	ContractId []byte                `json:"contract_id"`
+	Key        string                `json:"key"`
+	Hash       []byte                `json:"hash"`
+	Ciphers    []*PrivateStateCipher `json:"ciphers"`
+	Timestamp  uint64                `json:"timestamp"` //保存时间，用于按保留期清理
+}
+
+// NewPrivateState 用陪审员的压缩公钥加密私有状态的明文和盐
+func NewPrivateState(contractId []byte, key string, value []byte, salt []byte, pubKeys [][]byte,
+	timestamp uint64) (*PrivateState, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no jury public key to encrypt private state")
+	}
+	plain, err := rlp.EncodeToBytes(&privateStatePlain{Value: value, Salt: salt})
+	if err != nil {
+		return nil, err
+	}
+	ps := &PrivateState{ContractId: common.CopyBytes(contractId), Key: key,
+		Hash: PrivateStateHash(contractId, key, value, salt), Timestamp: timestamp}
+	for _, pubKey := range pubKeys {
+		pub, err := crypto.DecompressPubkey(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		ct, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pub), plain, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		ps.Ciphers = append(ps.Ciphers, &PrivateStateCipher{PubKey: common.CopyBytes(pubKey), Cipher: ct})
+	}
+	return ps, nil
+}
+
+// GetCipher 返回用指定公钥加密的密文
+func (ps *PrivateState) GetCipher(pubKey []byte) ([]byte, error) {
+	for _, c := range ps.Ciphers {
+		if bytes.Equal(c.PubKey, pubKey) {
+			return c.Cipher, nil
+		}
+	}
+	return nil, ErrPrivateStateNotFound
+}
+
+// Open 解析解密后的明文，返回私有状态的值和盐，并检查与Hash一致
+func (ps *PrivateState) Open(plain []byte) ([]byte, []byte, error) {
+	p := &privateStatePlain{}
+	if err := rlp.DecodeBytes(plain, p); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(ps.Hash, PrivateStateHash(ps.ContractId, ps.Key, p.Value, p.Salt)) {
+		return nil, nil, errors.New("private state value not match the hash")
+	}
+	return p.Value, p.Salt, nil
+}
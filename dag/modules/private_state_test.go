@@ -0,0 +1,64 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/crypto/ecies"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPrivateState(t *testing.T) {
+	prv1, _ := crypto.GenerateKey()
+	prv2, _ := crypto.GenerateKey()
+	pub1 := crypto.CompressPubkey(&prv1.PublicKey)
+	pub2 := crypto.CompressPubkey(&prv2.PublicKey)
+	contractId := []byte("contract")
+	value := []byte("bid:100")
+
+	salt, err := NewPrivateStateSalt()
+	assert.Nil(t, err)
+	ps, err := NewPrivateState(contractId, "bid_1", value, salt, [][]byte{pub1, pub2}, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, PrivateStateHash(contractId, "bid_1", value, salt), ps.Hash)
+	//没有盐不能从明文算出链上的Hash
+	assert.NotEqual(t, PrivateStateHash(contractId, "bid_1", value, nil), ps.Hash)
+	assert.Equal(t, 2, len(ps.Ciphers))
+
+	cipher, err := ps.GetCipher(pub2)
+	assert.Nil(t, err)
+	plain, err := ecies.ImportECDSA(prv2).Decrypt(cipher, nil, nil)
+	assert.Nil(t, err)
+	v, s, err := ps.Open(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, value, v)
+	assert.Equal(t, salt, s)
+	other, _ := rlp.EncodeToBytes(&privateStatePlain{Value: []byte("bid:1"), Salt: salt})
+	_, _, err = ps.Open(other)
+	assert.NotNil(t, err)
+
+	//不是陪审团成员
+	prv3, _ := crypto.GenerateKey()
+	_, err = ps.GetCipher(crypto.CompressPubkey(&prv3.PublicKey))
+	assert.Equal(t, ErrPrivateStateNotFound, err)
+}
@@ -0,0 +1,41 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"fmt"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+)
+
+const randomnessDomain = "PalletOne randomness beacon"
+
+// RandomnessBeacon 单元的随机数由该单元记录的父单元群签名生成。
+// 群签名需要门限数量的mediator签名分片才能恢复，且对同一个单元Hash是唯一的，
+// 生产者无法通过选择打包的交易预先计算并挑选随机数；群签名写入了单元Hash，所有陪审员根据同一个稳定单元得到相同的结果。
+// 生产者没有收到父单元的群签名时不记录，该高度没有随机数
+func RandomnessBeacon(h *Header) (common.Hash, error) {
+	sign := h.GetPrevGroupSign()
+	if len(sign) == 0 {
+		return common.Hash{}, fmt.Errorf("unit %d has no group sign of its parent", h.NumberU64())
+	}
+	return crypto.Keccak256Hash([]byte(randomnessDomain), sign), nil
+}
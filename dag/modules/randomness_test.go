@@ -0,0 +1,78 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomnessBeacon(t *testing.T) {
+	h := NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{}, []uint16{},
+		PTNCOIN, 10, 1574390000)
+	_, err := RandomnessBeacon(h)
+	assert.NotNil(t, err)
+
+	h.SetPrevGroupSign([]byte("group sign of unit 9"))
+	r, err := RandomnessBeacon(h)
+	assert.Nil(t, err)
+	assert.NotEqual(t, common.Hash{}, r)
+
+	//只由父单元群签名决定，与生产者可以选择的内容无关
+	h2 := NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{}, []uint16{},
+		PTNCOIN, 10, 1574390003)
+	h2.SetPrevGroupSign([]byte("group sign of unit 9"))
+	r2, err := RandomnessBeacon(h2)
+	assert.Nil(t, err)
+	assert.Equal(t, r, r2)
+
+	h2.SetPrevGroupSign([]byte("another group sign"))
+	r2, _ = RandomnessBeacon(h2)
+	assert.NotEqual(t, r, r2)
+}
+
+func TestHeader_PrevGroupSignRLP(t *testing.T) {
+	h := NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{}, []uint16{},
+		PTNCOIN, 10, 1574390000)
+	old, err := rlp.EncodeToBytes(h)
+	assert.Nil(t, err)
+	hash := h.Hash()
+
+	h.SetPrevGroupSign([]byte("group sign of unit 9"))
+	assert.NotEqual(t, hash, h.Hash())
+	data, err := rlp.EncodeToBytes(h)
+	assert.Nil(t, err)
+	decoded := &Header{}
+	assert.Nil(t, rlp.DecodeBytes(data, decoded))
+	assert.Equal(t, h.GetPrevGroupSign(), decoded.GetPrevGroupSign())
+	assert.Equal(t, h.Hash(), decoded.Hash())
+
+	//没有记录父单元群签名的单元编码保持不变
+	h.SetPrevGroupSign(nil)
+	data, err = rlp.EncodeToBytes(h)
+	assert.Nil(t, err)
+	assert.Equal(t, old, data)
+	decoded = &Header{}
+	assert.Nil(t, rlp.DecodeBytes(data, decoded))
+	assert.Equal(t, hash, decoded.Hash())
+}
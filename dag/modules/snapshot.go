@@ -0,0 +1,65 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package modules
+
+import (
+	"github.com/palletone/go-palletone/common"
+)
+
+const (
+	// SnapshotInterval 每SnapshotInterval个稳定单元，全节点计算一次UTXO集和状态的快照Hash
+	SnapshotInterval = 10000
+	// SnapshotCommitDelay 快照点之后的第SnapshotCommitDelay个单元在Header的Extra中提交快照Hash，
+	// 延迟提交保证生产单元的mediator已经将快照点设为稳定单元
+	SnapshotCommitDelay = 100
+)
+
+// SnapshotCommitment 稳定单元处的UTXO集和状态的快照Hash
+type SnapshotCommitment struct {
+	UnitIndex uint64      `json:"unit_index"` // 快照对应的稳定单元高度
+	UnitHash  common.Hash `json:"unit_hash"`  // 快照对应的稳定单元Hash
+	Hash      common.Hash `json:"hash"`       // 快照Hash
+}
+
+// IsSnapshotPoint 该高度的单元稳定时是否需要计算快照
+func IsSnapshotPoint(index uint64) bool {
+	return index > 0 && index%SnapshotInterval == 0
+}
+
+// IsSnapshotCommitUnit 该高度的单元是否需要提交快照Hash
+func IsSnapshotCommitUnit(index uint64) bool {
+	return index > SnapshotInterval && index%SnapshotInterval == SnapshotCommitDelay
+}
+
+// SnapshotCommitIndex 提交快照Hash的单元的高度
+func SnapshotCommitIndex(snapshotIndex uint64) uint64 {
+	return snapshotIndex + SnapshotCommitDelay
+}
+
+// GetSnapshotHash 返回Header中提交的快照Hash以及快照对应的单元高度。
+// 快照Hash已经包含了快照单元的高度和Hash，所以Header中只保存32字节的快照Hash
+func (h *Header) GetSnapshotHash() (common.Hash, uint64, bool) {
+	index := h.NumberU64()
+	extra := h.Extra()
+	if !IsSnapshotCommitUnit(index) || len(extra) != common.HashLength {
+		return common.Hash{}, 0, false
+	}
+	return common.BytesToHash(extra), index - SnapshotCommitDelay, true
+}
@@ -135,6 +135,9 @@ func (tx *Transaction) GetContractId() []byte {
 		case APP_CONTRACT_STOP_REQUEST:
 			payload := msg.Payload.(*ContractStopRequestPayload)
 			return common.CopyBytes(payload.ContractId)
+		case APP_CONTRACT_UPGRADE_REQUEST:
+			payload := msg.Payload.(*ContractUpgradeRequestPayload)
+			return common.CopyBytes(payload.ContractId)
 		}
 	}
 	return nil
@@ -240,6 +243,7 @@ type GetAddressFromScriptFunc func(lockScript []byte) (common.Address, error)
 type GetScriptSignersFunc func(tx *Transaction, msgIdx, inputIndex int) ([]common.Address, error)
 type QueryStateByVersionFunc func(id []byte, field string, version *StateVersion) ([]byte, error)
 type GetJurorRewardAddFunc func(jurorAdd common.Address) common.Address
+type GetJurorScoreFunc func(jurorAdd common.Address) uint32
 
 //计算该交易的手续费，基于UTXO，所以传入查询UTXO的函数指针
 func (tx *Transaction) GetTxFee(queryUtxoFunc QueryUtxoFunc) (*AmountAsset, error) {
@@ -496,6 +500,22 @@ func (tx *Transaction) GetSpendOutpoints() []*OutPoint {
 	return result
 }
 
+//交易的任意一个Input设置了SequenceReplaceable，则该交易在交易池中可以被手续费更高的冲突交易替换
+func (tx *Transaction) IsReplaceable() bool {
+	for _, msg := range tx.txdata.TxMessages {
+		if msg.App != APP_PAYMENT {
+			continue
+		}
+		pay := msg.Payload.(*PaymentPayload)
+		for _, input := range pay.Inputs {
+			if input.IsReplaceable() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //获得合约交易的签名对应的陪审员地址
 func (tx *Transaction) GetContractTxSignatureAddress() []common.Address {
 	if !tx.IsContractTx() {
@@ -596,6 +616,88 @@ func (tx *Transaction) HasContractPayoutMsg() (bool, int, *Message) {
 	return false, 0, nil
 }
 
+//查询Input花费的Utxo，本交易中前面的Payment产生的Utxo直接从交易中获取
+func (tx *Transaction) GetSpendUtxo(outpoint *OutPoint, queryUtxoFunc QueryUtxoFunc) (*Utxo, error) {
+	if !outpoint.TxHash.IsSelfHash() {
+		return queryUtxoFunc(outpoint)
+	}
+	msgs := tx.txdata.TxMessages
+	if int(outpoint.MessageIndex) >= len(msgs) || msgs[outpoint.MessageIndex].App != APP_PAYMENT {
+		return nil, errors.New("invalid self outpoint " + outpoint.String())
+	}
+	pay := msgs[outpoint.MessageIndex].Payload.(*PaymentPayload)
+	if int(outpoint.OutIndex) >= len(pay.Outputs) {
+		return nil, errors.New("invalid self outpoint " + outpoint.String())
+	}
+	out := pay.Outputs[outpoint.OutIndex]
+	return &Utxo{Amount: out.Value, Asset: out.Asset, PkScript: out.PkScript, LockTime: pay.LockTime}, nil
+}
+
+//获取合约调用结果涉及的被调用用户合约地址，包括读写了其状态、发出了其事件以及从其合约地址付款的合约
+func (tx *Transaction) GetContractCalleeAddrs(queryUtxoFunc QueryUtxoFunc,
+	getAddrFunc GetAddressFromScriptFunc) ([]common.Address, error) {
+	caller := common.NewAddress(tx.GetContractId(), common.ContractHash)
+	callees := []common.Address{}
+	addCallee := func(contractId []byte) {
+		if !common.IsUserContractId(contractId) {
+			return
+		}
+		addr := common.NewAddress(contractId, common.ContractHash)
+		if addr == caller {
+			return
+		}
+		for _, callee := range callees {
+			if callee == addr {
+				return
+			}
+		}
+		callees = append(callees, addr)
+	}
+	isResult := false
+	for _, msg := range tx.txdata.TxMessages {
+		if msg.App.IsRequest() {
+			isResult = true
+			continue
+		}
+		if !isResult {
+			continue
+		}
+		switch msg.App {
+		case APP_CONTRACT_INVOKE:
+			payload := msg.Payload.(*ContractInvokePayload)
+			for _, rs := range payload.ReadSet {
+				addCallee(rs.ContractId)
+			}
+			for _, ws := range payload.WriteSet {
+				addCallee(ws.ContractId)
+			}
+			for _, rr := range payload.RangeReadSet {
+				addCallee(rr.ContractId)
+			}
+			for _, event := range payload.Events {
+				addCallee(event.ContractId)
+			}
+		case APP_PAYMENT:
+			pay := msg.Payload.(*PaymentPayload)
+			for _, input := range pay.Inputs {
+				if input.PreviousOutPoint == nil {
+					continue
+				}
+				utxo, err := tx.GetSpendUtxo(input.PreviousOutPoint, queryUtxoFunc)
+				if err != nil {
+					return nil, errors.New("Get utxo by " + input.PreviousOutPoint.String() + " error:" + err.Error())
+				}
+				addr, err := getAddrFunc(utxo.PkScript)
+				if err != nil {
+					return nil, err
+				}
+				addCallee(addr.Bytes())
+			}
+		}
+	}
+	return callees, nil
+}
+
 //获取该交易的所有From地址
 func (tx *Transaction) GetFromAddrs(queryUtxoFunc QueryUtxoFunc, getAddrFunc GetAddressFromScriptFunc) (
 	[]common.Address, error) {
@@ -739,7 +841,7 @@ func (msg *Transaction) baseSize() int {
 }
 func (tx *Transaction) IsContractTx() bool {
 	for _, m := range tx.txdata.TxMessages {
-		if m.App >= APP_CONTRACT_TPL && m.App <= APP_SIGNATURE {
+		if (m.App >= APP_CONTRACT_TPL && m.App <= APP_SIGNATURE) || m.App == APP_CONTRACT_UPGRADE {
 			return true
 		}
 	}
@@ -855,8 +957,10 @@ func (tx *Transaction) GetTxFeeAllocateLegacyV1(queryUtxoFunc QueryUtxoFunc, get
 }
 
 //获得一笔交易的手续费分配情况,包括Mediator的打包费，Juror的合约执行费
+//Juror之间按各自的信誉分配合约执行费
 func (tx *Transaction) GetTxFeeAllocate(queryUtxoFunc QueryUtxoFunc, getSignerFunc GetScriptSignersFunc,
-	mediatorReward common.Address, getJurorRewardFunc GetJurorRewardAddFunc) ([]*Addition, error) {
+	mediatorReward common.Address, getJurorRewardFunc GetJurorRewardAddFunc,
+	getJurorScoreFunc GetJurorScoreFunc) ([]*Addition, error) {
 	fee, err := tx.GetTxFee(queryUtxoFunc)
 	result := make([]*Addition, 0)
 	if err != nil {
@@ -871,7 +975,8 @@ func (tx *Transaction) GetTxFeeAllocate(queryUtxoFunc QueryUtxoFunc, getSignerFu
 	for msgIdx, msg := range tx.TxMessages() {
 		if msg.App == APP_CONTRACT_INVOKE_REQUEST ||
 			msg.App == APP_CONTRACT_DEPLOY_REQUEST ||
-			msg.App == APP_CONTRACT_STOP_REQUEST {
+			msg.App == APP_CONTRACT_STOP_REQUEST ||
+			msg.App == APP_CONTRACT_UPGRADE_REQUEST {
 			isJuryInside = true
 			//只有合约部署和调用的时候会涉及到Jury，才会分手续费给Jury
 			continue
@@ -895,12 +1000,12 @@ func (tx *Transaction) GetTxFeeAllocate(queryUtxoFunc QueryUtxoFunc, getSignerFu
 
 	juryAllocatedAmt := uint64(0)
 	if isJuryInside { //合约执行，Fee需要分配给Jury
-		juryAmount := float64(fee.Amount) * parameter.CurrentSysParameters.ContractFeeJuryPercent
-		juryCount := float64(len(jury))
-		for _, juror := range jury {
+		juryAmount := uint64(float64(fee.Amount) * parameter.CurrentSysParameters.ContractFeeJuryPercent)
+		scores, totalScore := jurorRewardScores(jury, getJurorScoreFunc)
+		for i, juror := range jury {
 			jIncome := &Addition{
 				Addr:   getJurorRewardFunc(juror),
-				Amount: uint64(juryAmount / juryCount),
+				Amount: jurorRewardShare(juryAmount, scores[i], totalScore),
 				Asset:  fee.Asset,
 			}
 			juryAllocatedAmt += jIncome.Amount
@@ -931,6 +1036,31 @@ func (tx *Transaction) GetTxFeeAllocate(queryUtxoFunc QueryUtxoFunc, getSignerFu
 	return result, nil
 }
 
+//jurorRewardShare 按权重计算Juror分得的合约执行费，全部使用整数运算，
+//先拆分为商和余数，避免amount*score溢出，除不尽的部分留给Mediator
+func jurorRewardShare(amount, score, total uint64) uint64 {
+	return amount/total*score + amount%total*score/total
+}
+
+//jurorRewardScores 返回每个Juror分配合约执行费的权重及权重之和，所有Juror的信誉都为0时平均分配
+func jurorRewardScores(jury []common.Address, getJurorScoreFunc GetJurorScoreFunc) ([]uint64, uint64) {
+	scores := make([]uint64, len(jury))
+	total := uint64(0)
+	if getJurorScoreFunc != nil {
+		for i, juror := range jury {
+			scores[i] = uint64(getJurorScoreFunc(juror))
+			total += scores[i]
+		}
+	}
+	if total == 0 {
+		for i := range scores {
+			scores[i] = 1
+		}
+		total = uint64(len(scores))
+	}
+	return scores, total
+}
+
 // SerializeSizeStripped returns the number of bytes it would take to serialize
 // the transaction, excluding any included witness data.
 func (tx *Transaction) SerializeSizeStripped() int {
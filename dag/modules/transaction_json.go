@@ -103,6 +103,23 @@ type idxContractStopPayload struct {
 	*ContractStopPayload
 }
 
+//upgrade
+type idxContractUpgradeRequestPayload struct {
+	Index int
+	*ContractUpgradeRequestPayload
+}
+
+type idxContractUpgradePayload struct {
+	Index int
+	*ContractUpgradePayload
+}
+
+//jury update
+type idxContractJuryUpdatePayload struct {
+	Index int
+	*ContractJuryUpdatePayload
+}
+
 type txJsonTemp struct {
 	MsgCount int
 	CertId   string
@@ -118,11 +135,15 @@ type txJsonTemp struct {
 	ContractDeployRequest  []*idxContractDeployRequestPayload
 	ContractInvokeRequest  []*idxContractInvokeRequestPayload
 	ContractStopRequest    []*idxContractStopRequestPayload
+	ContractUpgradeRequest []*idxContractUpgradeRequestPayload
 
-	ContractTpl    []*idxContractTplPayload
-	ContractDeploy []*idxContractDeployPayload
-	ContractInvoke []*idxContractInvokePayload
-	ContractStop   []*idxContractStopPayload
+	ContractTpl     []*idxContractTplPayload
+	ContractDeploy  []*idxContractDeployPayload
+	ContractInvoke  []*idxContractInvokePayload
+	ContractStop    []*idxContractStopPayload
+	ContractUpgrade []*idxContractUpgradePayload
+
+	ContractJuryUpdate []*idxContractJuryUpdatePayload
 }
 
 func tx2JsonTemp(tx *Transaction) (*txJsonTemp, error) {
@@ -169,6 +190,18 @@ func tx2JsonTemp(tx *Transaction) (*txJsonTemp, error) {
 					Index:                      idx,
 					ContractStopRequestPayload: msg.Payload.(*ContractStopRequestPayload),
 				})
+		} else if msg.App == APP_CONTRACT_UPGRADE_REQUEST {
+			temp.ContractUpgradeRequest = append(temp.ContractUpgradeRequest,
+				&idxContractUpgradeRequestPayload{
+					Index:                         idx,
+					ContractUpgradeRequestPayload: msg.Payload.(*ContractUpgradeRequestPayload),
+				})
+		} else if msg.App == APP_CONTRACT_UPGRADE {
+			temp.ContractUpgrade = append(temp.ContractUpgrade, &idxContractUpgradePayload{
+				Index: idx, ContractUpgradePayload: msg.Payload.(*ContractUpgradePayload)})
+		} else if msg.App == APP_CONTRACT_JURY_UPDATE {
+			temp.ContractJuryUpdate = append(temp.ContractJuryUpdate, &idxContractJuryUpdatePayload{
+				Index: idx, ContractJuryUpdatePayload: msg.Payload.(*ContractJuryUpdatePayload)})
 		} else if msg.App == APP_DATA {
 			temp.Text = append(temp.Text, &idxTextPayload{Index: idx, DataPayload: msg.Payload.(*DataPayload)})
 		} else if msg.App == APP_SIGNATURE {
@@ -218,6 +251,10 @@ func jsonTemp2tx(tx *Transaction, temp *txJsonTemp) error {
 		sdw.TxMessages[p.Index] = NewMessage(APP_CONTRACT_STOP_REQUEST, p.ContractStopRequestPayload)
 		processed++
 	}
+	for _, p := range temp.ContractUpgradeRequest {
+		sdw.TxMessages[p.Index] = NewMessage(APP_CONTRACT_UPGRADE_REQUEST, p.ContractUpgradeRequestPayload)
+		processed++
+	}
 
 	//content
 	for _, p := range temp.ContractTpl {
@@ -236,6 +273,14 @@ func jsonTemp2tx(tx *Transaction, temp *txJsonTemp) error {
 		sdw.TxMessages[p.Index] = NewMessage(APP_CONTRACT_STOP, p.ContractStopPayload)
 		processed++
 	}
+	for _, p := range temp.ContractUpgrade {
+		sdw.TxMessages[p.Index] = NewMessage(APP_CONTRACT_UPGRADE, p.ContractUpgradePayload)
+		processed++
+	}
+	for _, p := range temp.ContractJuryUpdate {
+		sdw.TxMessages[p.Index] = NewMessage(APP_CONTRACT_JURY_UPDATE, p.ContractJuryUpdatePayload)
+		processed++
+	}
 
 	for _, p := range temp.Text {
 		sdw.TxMessages[p.Index] = NewMessage(APP_DATA, p.DataPayload)
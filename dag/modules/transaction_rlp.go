@@ -167,6 +167,27 @@ func temp2Tx(temp *transactionTemp, tx *Transaction) error {
 				return err
 			}
 			m1.Payload = &payload
+		} else if m.App == APP_CONTRACT_UPGRADE_REQUEST {
+			var payload ContractUpgradeRequestPayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
+		} else if m.App == APP_CONTRACT_UPGRADE {
+			var payload ContractUpgradePayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
+		} else if m.App == APP_CONTRACT_JURY_UPDATE {
+			var payload ContractJuryUpdatePayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
 			//} else if m.App == APP_CONFIG {
 			//	var conf ConfigPayload
 			//	rlp.DecodeBytes(m.Data, &conf)
@@ -209,17 +230,38 @@ type inputTemp struct {
 	OutIndex        uint32
 }
 
+//带相对锁定时间的Input，为了兼容历史数据，只有Sequence不为0时才使用该格式
+type inputTempV2 struct {
+	SignatureScript []byte
+	Extra           []byte
+	TxHash          common.Hash // reference Utxo struct key field
+	MessageIndex    uint32      // message index in transaction
+	OutIndex        uint32
+	Sequence        uint32
+}
+
 func (input *Input) DecodeRLP(s *rlp.Stream) error {
 	raw, err := s.Raw()
 	if err != nil {
 		return err
 	}
-	temp := &inputTemp{TxHash: common.Hash{}}
-	err = rlp.DecodeBytes(raw, temp)
-	if err != nil {
-		return err
+	temp := &inputTempV2{TxHash: common.Hash{}}
+	v1 := &inputTemp{TxHash: common.Hash{}}
+	err = rlp.DecodeBytes(raw, v1)
+	if err == nil {
+		temp.SignatureScript = v1.SignatureScript
+		temp.Extra = v1.Extra
+		temp.TxHash = v1.TxHash
+		temp.MessageIndex = v1.MessageIndex
+		temp.OutIndex = v1.OutIndex
+	} else {
+		err = rlp.DecodeBytes(raw, temp)
+		if err != nil {
+			return err
+		}
 	}
 
+	input.Sequence = temp.Sequence
 	input.SignatureScript = temp.SignatureScript
 	input.Extra = temp.Extra
 	if !common.EmptyHash(temp.TxHash) {
@@ -228,6 +270,15 @@ func (input *Input) DecodeRLP(s *rlp.Stream) error {
 	return nil
 }
 func (input *Input) EncodeRLP(w io.Writer) error {
+	if input.Sequence != 0 {
+		temp := inputTempV2{SignatureScript: input.SignatureScript, Extra: input.Extra, Sequence: input.Sequence}
+		if input.PreviousOutPoint != nil {
+			temp.TxHash = input.PreviousOutPoint.TxHash
+			temp.MessageIndex = input.PreviousOutPoint.MessageIndex
+			temp.OutIndex = input.PreviousOutPoint.OutIndex
+		}
+		return rlp.Encode(w, temp)
+	}
 	temp := inputTemp{SignatureScript: input.SignatureScript, Extra: input.Extra}
 	if input.PreviousOutPoint != nil {
 		temp.TxHash = input.PreviousOutPoint.TxHash
@@ -236,3 +287,214 @@ func (input *Input) EncodeRLP(w io.Writer) error {
 	}
 	return rlp.Encode(w, temp)
 }
+
+//GasLimit为0时不编码，与旧版本的编码保持一致
+type contractInvokeRequestPayloadTemp struct {
+	ContractId []byte
+	Args       [][]byte
+	Timeout    uint32
+	GasLimit   []uint64 `rlp:"tail"`
+}
+
+func (p *ContractInvokeRequestPayload) EncodeRLP(w io.Writer) error {
+	temp := &contractInvokeRequestPayloadTemp{ContractId: p.ContractId, Args: p.Args, Timeout: p.Timeout}
+	if p.GasLimit > 0 {
+		temp.GasLimit = []uint64{p.GasLimit}
+	}
+	return rlp.Encode(w, temp)
+}
+
+func (p *ContractInvokeRequestPayload) DecodeRLP(s *rlp.Stream) error {
+	temp := &contractInvokeRequestPayloadTemp{}
+	if err := s.Decode(temp); err != nil {
+		return err
+	}
+	if len(temp.GasLimit) > 1 {
+		return fmt.Errorf("invalid contract invoke request, gas limit count:%d", len(temp.GasLimit))
+	}
+	p.ContractId = temp.ContractId
+	p.Args = temp.Args
+	p.Timeout = temp.Timeout
+	p.GasLimit = 0
+	if len(temp.GasLimit) == 1 {
+		p.GasLimit = temp.GasLimit[0]
+	}
+	return nil
+}
+
+//UpgradePolicy为空时不编码，与旧版本的编码保持一致
+type contractDeployRequestPayloadTemp struct {
+	TemplateId    []byte
+	Args          [][]byte
+	ExtData       []byte
+	Timeout       uint32
+	UpgradePolicy []*ContractUpgradePolicy `rlp:"tail"`
+}
+
+func (p *ContractDeployRequestPayload) EncodeRLP(w io.Writer) error {
+	temp := &contractDeployRequestPayloadTemp{TemplateId: p.TemplateId, Args: p.Args, ExtData: p.ExtData,
+		Timeout: p.Timeout}
+	if p.UpgradePolicy != nil {
+		temp.UpgradePolicy = []*ContractUpgradePolicy{p.UpgradePolicy}
+	}
+	return rlp.Encode(w, temp)
+}
+
+func (p *ContractDeployRequestPayload) DecodeRLP(s *rlp.Stream) error {
+	temp := &contractDeployRequestPayloadTemp{}
+	if err := s.Decode(temp); err != nil {
+		return err
+	}
+	if len(temp.UpgradePolicy) > 1 {
+		return fmt.Errorf("invalid contract deploy request, upgrade policy count:%d", len(temp.UpgradePolicy))
+	}
+	p.TemplateId = temp.TemplateId
+	p.Args = temp.Args
+	p.ExtData = temp.ExtData
+	p.Timeout = temp.Timeout
+	p.UpgradePolicy = nil
+	if len(temp.UpgradePolicy) == 1 {
+		p.UpgradePolicy = temp.UpgradePolicy[0]
+	}
+	return nil
+}
+
+//使用的Gas、范围读集和事件依次编码在尾部，每一项都带有类型标记，没有这些数据时编码结果与旧版本一致
+type contractInvokePayloadTemp struct {
+	ContractId []byte
+	Args       [][]byte
+	ReadSet    []ContractReadSet
+	WriteSet   []ContractWriteSet
+	Payload    []byte
+	ErrMsg     ContractError
+	Tail       []invokeTailItem `rlp:"tail"`
+}
+
+//尾部数据的类型
+const (
+	invokeTailGasUsed uint8 = iota + 1
+	invokeTailRangeReadSet
+	invokeTailEvents
+)
+
+type invokeTailItem struct {
+	Kind uint8
+	Data rlp.RawValue
+}
+
+func newInvokeTailItem(kind uint8, val interface{}) (invokeTailItem, error) {
+	data, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return invokeTailItem{}, err
+	}
+	return invokeTailItem{Kind: kind, Data: data}, nil
+}
+
+func (p *ContractInvokePayload) EncodeRLP(w io.Writer) error {
+	temp := &contractInvokePayloadTemp{ContractId: p.ContractId, Args: p.Args, ReadSet: p.ReadSet,
+		WriteSet: p.WriteSet, Payload: p.Payload, ErrMsg: p.ErrMsg}
+	if p.GasUsed > 0 {
+		item, err := newInvokeTailItem(invokeTailGasUsed, p.GasUsed)
+		if err != nil {
+			return err
+		}
+		temp.Tail = append(temp.Tail, item)
+	}
+	for i := range p.RangeReadSet {
+		item, err := newInvokeTailItem(invokeTailRangeReadSet, &p.RangeReadSet[i])
+		if err != nil {
+			return err
+		}
+		temp.Tail = append(temp.Tail, item)
+	}
+	if len(p.Events) > 0 {
+		item, err := newInvokeTailItem(invokeTailEvents, p.Events)
+		if err != nil {
+			return err
+		}
+		temp.Tail = append(temp.Tail, item)
+	}
+	return rlp.Encode(w, temp)
+}
+
+func (p *ContractInvokePayload) DecodeRLP(s *rlp.Stream) error {
+	temp := &contractInvokePayloadTemp{}
+	if err := s.Decode(temp); err != nil {
+		return err
+	}
+	p.ContractId = temp.ContractId
+	p.Args = temp.Args
+	p.ReadSet = temp.ReadSet
+	p.WriteSet = temp.WriteSet
+	p.Payload = temp.Payload
+	p.ErrMsg = temp.ErrMsg
+	p.RangeReadSet = nil
+	p.Events = nil
+	p.GasUsed = 0
+	//各类数据只能按编码时的顺序出现，保证解码后重新编码的结果不变
+	last := uint8(0)
+	for _, item := range temp.Tail {
+		if item.Kind < last || (item.Kind == last && item.Kind != invokeTailRangeReadSet) {
+			return fmt.Errorf("invalid contract invoke payload, unexpected tail item kind %d", item.Kind)
+		}
+		last = item.Kind
+		switch item.Kind {
+		case invokeTailGasUsed:
+			if err := rlp.DecodeBytes(item.Data, &p.GasUsed); err != nil {
+				return err
+			}
+			if p.GasUsed == 0 {
+				return fmt.Errorf("invalid contract invoke payload, gas used is 0")
+			}
+		case invokeTailRangeReadSet:
+			rr := ContractRangeReadSet{}
+			if err := rlp.DecodeBytes(item.Data, &rr); err != nil {
+				return err
+			}
+			p.RangeReadSet = append(p.RangeReadSet, rr)
+		case invokeTailEvents:
+			if err := rlp.DecodeBytes(item.Data, &p.Events); err != nil {
+				return err
+			}
+			if len(p.Events) == 0 {
+				return fmt.Errorf("invalid contract invoke payload, events is empty")
+			}
+		default:
+			return fmt.Errorf("invalid contract invoke payload, unknown tail item kind %d", item.Kind)
+		}
+	}
+	return nil
+}
+
+//私有标记编码在尾部，公开状态的写集编码与之前相同
+type contractWriteSetTemp struct {
+	IsDelete   bool
+	Key        string
+	Value      []byte
+	ContractId []byte
+	Private    []bool `rlp:"tail"`
+}
+
+func (w ContractWriteSet) EncodeRLP(out io.Writer) error {
+	temp := &contractWriteSetTemp{IsDelete: w.IsDelete, Key: w.Key, Value: w.Value, ContractId: w.ContractId}
+	if w.IsPrivate {
+		temp.Private = []bool{true}
+	}
+	return rlp.Encode(out, temp)
+}
+
+func (w *ContractWriteSet) DecodeRLP(s *rlp.Stream) error {
+	temp := &contractWriteSetTemp{}
+	if err := s.Decode(temp); err != nil {
+		return err
+	}
+	if len(temp.Private) > 1 {
+		return fmt.Errorf("invalid contract write set, private flag count:%d", len(temp.Private))
+	}
+	w.IsDelete = temp.IsDelete
+	w.Key = temp.Key
+	w.Value = temp.Value
+	w.ContractId = temp.ContractId
+	w.IsPrivate = len(temp.Private) == 1 && temp.Private[0]
+	return nil
+}
@@ -80,6 +80,36 @@ func TestInput_RLP(t *testing.T) {
 	assertEqualRlp(t, input, input3)
 }
 
+func TestInputSequence_RLP(t *testing.T) {
+	outpoint := NewOutPoint(hash, 1, 2)
+	input := NewTxIn(outpoint, []byte{1, 2, 3})
+	bytes, err := rlp.EncodeToBytes(input)
+	assert.Nil(t, err)
+	//no sequence, keep the legacy format
+	legacy, _ := rlp.EncodeToBytes(newTestInput(hash, 1, 2, []byte{1, 2, 3}, nil))
+	assert.Equal(t, legacy, bytes)
+
+	input.Sequence = NewSequenceLockByUnits(10)
+	bytes, err = rlp.EncodeToBytes(input)
+	assert.Nil(t, err)
+	assert.NotEqual(t, legacy, bytes)
+	input2 := &Input{}
+	err = rlp.DecodeBytes(bytes, input2)
+	assert.Nil(t, err)
+	assert.Equal(t, input.Sequence, input2.Sequence)
+	assert.Equal(t, outpoint.String(), input2.PreviousOutPoint.String())
+	lock, isSeconds := input2.RelativeLockTime()
+	assert.Equal(t, int64(10), lock)
+	assert.False(t, isSeconds)
+
+	input2.Sequence = NewSequenceLockBySeconds(1000)
+	lock, isSeconds = input2.RelativeLockTime()
+	assert.Equal(t, int64(1024), lock)
+	assert.True(t, isSeconds)
+	input2.Sequence |= SequenceLockTimeDisabled
+	assert.False(t, input2.HasRelativeLockTime())
+}
+
 func TestCoinbaseInput_RLP(t *testing.T) {
 	input := newCoinbaseInput([]byte("unlock"), []byte("extra"))
 	input.Extra = []byte{1, 2, 3}
@@ -225,6 +255,59 @@ func TestContractInvokeReqPayload_Rlp(t *testing.T) {
 	assertEqualRlp(t, pay, pay2)
 }
 
+func TestContractInvokeReqPayloadGasLimit_Rlp(t *testing.T) {
+	//没有设置GasLimit时，编码与旧版本的Payload一致
+	old := newTestContractInvokeReq()
+	pay := &ContractInvokeRequestPayload{ContractId: old.ContractId, Args: old.Args, Timeout: old.Timeout}
+	oldBytes, _ := rlp.EncodeToBytes(old)
+	newBytes, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	assert.Equal(t, oldBytes, newBytes)
+	pay2 := &ContractInvokeRequestPayload{}
+	assert.Nil(t, rlp.DecodeBytes(oldBytes, pay2))
+	assert.Equal(t, uint64(0), pay2.GasLimit)
+	assert.Equal(t, DefaultContractGasLimit, pay2.GetGasLimit())
+
+	pay.GasLimit = 50000
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	pay3 := &ContractInvokeRequestPayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay3))
+	assertEqualRlp(t, pay, pay3)
+	assert.Equal(t, uint64(50000), pay3.GetGasLimit())
+}
+
+type TestContractDeployRequestPayload struct {
+	TemplateId []byte
+	Args       [][]byte
+	ExtData    []byte
+	Timeout    uint32
+}
+
+func TestContractDeployReqPayloadUpgradePolicy_Rlp(t *testing.T) {
+	//没有设置升级策略时，编码与旧版本的Payload一致
+	old := &TestContractDeployRequestPayload{TemplateId: []byte("tpl"), Args: [][]byte{[]byte("a")},
+		ExtData: []byte("ext"), Timeout: 10}
+	pay := &ContractDeployRequestPayload{TemplateId: old.TemplateId, Args: old.Args, ExtData: old.ExtData,
+		Timeout: old.Timeout}
+	oldBytes, _ := rlp.EncodeToBytes(old)
+	newBytes, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	assert.Equal(t, oldBytes, newBytes)
+	pay2 := &ContractDeployRequestPayload{}
+	assert.Nil(t, rlp.DecodeBytes(oldBytes, pay2))
+	assert.Nil(t, pay2.UpgradePolicy)
+
+	pay.UpgradePolicy = &ContractUpgradePolicy{Admins: []common.Address{{1}, {2}}, Threshold: 2}
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	pay3 := &ContractDeployRequestPayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay3))
+	assertEqualRlp(t, pay, pay3)
+	assert.Equal(t, uint32(2), pay3.UpgradePolicy.Threshold)
+	assert.Equal(t, 2, len(pay3.UpgradePolicy.Admins))
+}
+
 func newTestContractInvokeReq() *TestContractInvokeRequestPayload {
 	a := []byte("AAAA")
 	b := []byte("BBBBBBBBBBB")
@@ -247,6 +330,35 @@ func TestContractInvokeResultPayload_Rlp(t *testing.T) {
 	assertEqualRlp(t, pay, pay2)
 }
 
+func TestContractInvokePayloadRangeReadSet_Rlp(t *testing.T) {
+	//没有范围读集时，编码与旧版本的Payload一致
+	type oldInvokePayload struct {
+		ContractId []byte
+		Args       [][]byte
+		ReadSet    []ContractReadSet
+		WriteSet   []ContractWriteSet
+		Payload    []byte
+		ErrMsg     ContractError
+	}
+	pay := newTestContractInvokeResult()
+	old := &oldInvokePayload{ContractId: pay.ContractId, ReadSet: pay.ReadSet, WriteSet: pay.WriteSet}
+	oldBytes, _ := rlp.EncodeToBytes(old)
+	newBytes, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	assert.Equal(t, oldBytes, newBytes)
+
+	version := &StateVersion{&ChainIndex{PTNCOIN, 101}, 1}
+	pay.RangeReadSet = []ContractRangeReadSet{{StartKey: "order_", EndKey: "order`",
+		Reads: []ContractReadSet{{Key: "order_1", Version: version}}}}
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	pay2 := &ContractInvokePayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay2))
+	assert.Equal(t, 1, len(pay2.RangeReadSet))
+	assert.Equal(t, "order_1", pay2.RangeReadSet[0].Reads[0].Key)
+	assertEqualRlp(t, pay, pay2)
+}
+
 func newTestContractInvokeResult() *ContractInvokePayload {
 	version := &StateVersion{&ChainIndex{PTNCOIN, 100}, 2}
 	read1 := ContractReadSet{"A", version, []byte("This is value")}
@@ -275,3 +387,93 @@ func TestTx2PaymentsEncode(t *testing.T) {
 	assert.Nil(t, err)
 	t.Logf("%#v", tx2)
 }
+
+func TestContractInvokePayloadEvents_Rlp(t *testing.T) {
+	pay := newTestContractInvokeResult()
+	version := &StateVersion{&ChainIndex{PTNCOIN, 101}, 1}
+	pay.RangeReadSet = []ContractRangeReadSet{{StartKey: "order_", EndKey: "order`",
+		Reads: []ContractReadSet{{Key: "order_1", Version: version}}}}
+	pay.Events = []*ContractEvent{{ContractId: pay.ContractId, Name: "transfer", Payload: []byte("100")}}
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	pay2 := &ContractInvokePayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay2))
+	assert.Equal(t, 1, len(pay2.RangeReadSet))
+	assert.Equal(t, 1, len(pay2.Events))
+	assert.Equal(t, "transfer", pay2.Events[0].Name)
+	assert.Equal(t, []byte("100"), pay2.Events[0].Payload)
+	assertEqualRlp(t, pay, pay2)
+}
+
+//尾部数据按类型标记解码，与元素个数无关
+func TestContractInvokePayloadTail_Rlp(t *testing.T) {
+	pay := newTestContractInvokeResult()
+	version := &StateVersion{&ChainIndex{PTNCOIN, 101}, 1}
+	pay.RangeReadSet = []ContractRangeReadSet{{StartKey: "a"}, {StartKey: "order_", EndKey: "order`",
+		Reads: []ContractReadSet{{Key: "order_1", Version: version}}}}
+	pay.Events = []*ContractEvent{{ContractId: pay.ContractId, Name: "transfer"},
+		{ContractId: pay.ContractId, Name: "approve", Payload: []byte("1")}}
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	pay2 := &ContractInvokePayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay2))
+	assert.Equal(t, 2, len(pay2.RangeReadSet))
+	assert.Equal(t, 2, len(pay2.Events))
+	assertEqualRlp(t, pay, pay2)
+
+	//事件不能出现在范围读集之前，不能出现未知的类型
+	temp := &contractInvokePayloadTemp{ContractId: pay.ContractId}
+	events, _ := newInvokeTailItem(invokeTailEvents, pay.Events)
+	rr, _ := newInvokeTailItem(invokeTailRangeReadSet, &pay.RangeReadSet[0])
+	temp.Tail = []invokeTailItem{events, rr}
+	data, err = rlp.EncodeToBytes(temp)
+	assert.Nil(t, err)
+	assert.NotNil(t, rlp.DecodeBytes(data, &ContractInvokePayload{}))
+	temp.Tail = []invokeTailItem{{Kind: 9, Data: rr.Data}}
+	data, err = rlp.EncodeToBytes(temp)
+	assert.Nil(t, err)
+	assert.NotNil(t, rlp.DecodeBytes(data, &ContractInvokePayload{}))
+}
+
+func TestContractInvokePayloadGasUsed_Rlp(t *testing.T) {
+	pay := newTestContractInvokeResult()
+	data, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+
+	pay.GasUsed = 12345
+	version := &StateVersion{&ChainIndex{PTNCOIN, 101}, 1}
+	pay.RangeReadSet = []ContractRangeReadSet{{StartKey: "order_", EndKey: "order`",
+		Reads: []ContractReadSet{{Key: "order_1", Version: version}}}}
+	pay.Events = []*ContractEvent{{ContractId: pay.ContractId, Name: "transfer", Payload: []byte("100")}}
+	data2, err := rlp.EncodeToBytes(pay)
+	assert.Nil(t, err)
+	assert.NotEqual(t, data, data2)
+	pay2 := &ContractInvokePayload{}
+	assert.Nil(t, rlp.DecodeBytes(data2, pay2))
+	assert.Equal(t, uint64(12345), pay2.GasUsed)
+	assert.Equal(t, 1, len(pay2.RangeReadSet))
+	assert.Equal(t, 1, len(pay2.Events))
+	assertEqualRlp(t, pay, pay2)
+
+	//没有使用Gas时与旧版本的编码一致
+	pay3 := &ContractInvokePayload{}
+	assert.Nil(t, rlp.DecodeBytes(data, pay3))
+	assert.Equal(t, uint64(0), pay3.GasUsed)
+}
+
+func TestContractWriteSetPrivate_Rlp(t *testing.T) {
+	ws := ContractWriteSet{Key: "kyc_1", Value: []byte("value"), ContractId: []byte("contract")}
+	data, err := rlp.EncodeToBytes(ws)
+	assert.Nil(t, err)
+	//公开状态的编码与增加私有标记之前相同
+	old, _ := rlp.EncodeToBytes([]interface{}{false, ws.Key, ws.Value, ws.ContractId})
+	assert.Equal(t, old, data)
+
+	ws.IsPrivate = true
+	data, err = rlp.EncodeToBytes(ws)
+	assert.Nil(t, err)
+	ws2 := ContractWriteSet{}
+	assert.Nil(t, rlp.DecodeBytes(data, &ws2))
+	assert.True(t, ws2.IsPrivate)
+	assert.True(t, ws.Equal(&ws2))
+}
@@ -340,3 +340,43 @@ func TestAdditionJson(t *testing.T) {
 	data, _ := json.Marshal(income)
 	t.Log(string(data))
 }
+
+func TestTransaction_GetContractCalleeAddrs(t *testing.T) {
+	callerId := []byte(strings.Repeat("\x11", 20))
+	calleeA := common.NewAddress([]byte(strings.Repeat("\x22", 20)), common.ContractHash)
+	calleeB := common.NewAddress([]byte(strings.Repeat("\x33", 20)), common.ContractHash)
+	caller := common.NewAddress(callerId, common.ContractHash)
+	//请求中付款到调用合约
+	reqPay := &PaymentPayload{}
+	reqPay.AddTxOut(NewTxOut(10, caller.Bytes21(), NewPTNAsset()))
+	tx := NewTransaction([]*Message{NewMessage(APP_PAYMENT, reqPay),
+		NewMessage(APP_CONTRACT_INVOKE_REQUEST, &ContractInvokeRequestPayload{ContractId: callerId})})
+	invoke := &ContractInvokePayload{ContractId: callerId,
+		WriteSet: []ContractWriteSet{{Key: "a", ContractId: callerId}, {Key: "b", ContractId: calleeA.Bytes()}}}
+	tx.AddMessage(NewMessage(APP_CONTRACT_INVOKE, invoke))
+	utxoOutPoint := NewOutPoint(common.HexToHash("0x01"), 0, 0)
+	pay := &PaymentPayload{}
+	pay.AddTxIn(NewTxIn(NewOutPoint(common.NewSelfHash(), 0, 0), nil))
+	pay.AddTxIn(NewTxIn(utxoOutPoint, nil))
+	tx.AddMessage(NewMessage(APP_PAYMENT, pay))
+
+	queryUtxo := func(outpoint *OutPoint) (*Utxo, error) {
+		if *outpoint == *utxoOutPoint {
+			return &Utxo{PkScript: calleeB.Bytes21()}, nil
+		}
+		return nil, fmt.Errorf("utxo %s not found", outpoint.String())
+	}
+	getAddr := func(lockScript []byte) (common.Address, error) {
+		return common.BytesToAddress(lockScript), nil
+	}
+	callees, err := tx.GetContractCalleeAddrs(queryUtxo, getAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{calleeA, calleeB}, callees)
+
+	//找不到花费的Utxo
+	badPay := &PaymentPayload{}
+	badPay.AddTxIn(NewTxIn(NewOutPoint(common.HexToHash("0x02"), 0, 0), nil))
+	tx.AddMessage(NewMessage(APP_PAYMENT, badPay))
+	_, err = tx.GetContractCalleeAddrs(queryUtxo, getAddr)
+	assert.NotNil(t, err)
+}
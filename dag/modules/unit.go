@@ -58,6 +58,8 @@ type header_sdw struct {
 	Extra       []byte        `json:"extra"`
 	Time        int64         `json:"creation_time"` // unit create time
 	CryptoLib   []byte        `json:"crypto_lib"`    //该区块使用的加解密算法和哈希算法，0位表示非对称加密算法，1位表示Hash算法
+	//父单元的群签名，由生产者在产块时写入，参与单元Hash的计算，用于生成随机数
+	PrevGroupSign []byte `json:"prev_group_sign"`
 }
 
 func initHeaderSdw(parents []common.Hash, tx_root common.Hash, pubkey, sig, extra, crypto_lib []byte,
@@ -186,6 +188,17 @@ func (h *Header) GetGroupSign() []byte {
 	return common.CopyBytes(h.group_sign)
 }
 
+// SetPrevGroupSign 生产者写入父单元的群签名，需要在签名前设置
+func (h *Header) SetPrevGroupSign(sign []byte) {
+	h.header.PrevGroupSign = common.CopyBytes(sign)
+	h.ResetHash()
+}
+
+// GetPrevGroupSign 单元中记录的父单元的群签名，生产者没有收到时为空
+func (h *Header) GetPrevGroupSign() []byte {
+	return common.CopyBytes(h.header.PrevGroupSign)
+}
+
 func (h *Header) GetGroupPubKey() (kyber.Point, error) {
 	pubKeyB := h.group_pubKey
 	if len(pubKeyB) == 0 {
@@ -286,6 +299,7 @@ func (cpy *Header) CopyHeader(h *Header) {
 	author := h.GetAuthors()
 	sdw := initHeaderSdw(h.ParentHash(), h.TxRoot(), author.PubKey, author.Signature, h.Extra(), h.Cryptolib(),
 		h.GetTxsIllegal(), h.ChainIndex().AssetID, h.ChainIndex().Index, h.Timestamp())
+	sdw.PrevGroupSign = h.GetPrevGroupSign()
 	cpy.header = sdw
 	cpy.group_sign = make([]byte, len(h.group_sign))
 	if len(h.group_sign) > 0 {
@@ -304,6 +318,7 @@ func CopyHeader(h *Header) *Header {
 	author := h.GetAuthors()
 	sdw := initHeaderSdw(h.ParentHash(), h.TxRoot(), author.PubKey, author.Signature, h.Extra(), h.Cryptolib(),
 		h.GetTxsIllegal(), h.ChainIndex().AssetID, h.ChainIndex().Index, h.Timestamp())
+	sdw.PrevGroupSign = h.GetPrevGroupSign()
 	cpy := Header{header: sdw}
 
 	if len(h.group_sign) > 0 {
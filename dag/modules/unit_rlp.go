@@ -34,6 +34,8 @@ type headerTemp struct {
 	Extra       []byte        `json:"extra"`
 	Time        uint32        `json:"creation_time"` // unit create time
 	CryptoLib   []byte        `json:"crypto_lib"`    //该区块使用的加解密算法和哈希算法，0位表示非对称加密算法，1位表示Hash算法
+	//父单元的群签名，没有时编码结果与旧版本一致
+	PrevGroupSign [][]byte `json:"prev_group_sign" rlp:"tail"`
 }
 
 func (input *Header) DecodeRLP(s *rlp.Stream) error {
@@ -59,6 +61,10 @@ func (input *Header) DecodeRLP(s *rlp.Stream) error {
 	input.header.Extra = temp.Extra
 	input.header.Time = int64(temp.Time)
 	input.header.CryptoLib = temp.CryptoLib
+	input.header.PrevGroupSign = nil
+	if len(temp.PrevGroupSign) > 0 {
+		input.header.PrevGroupSign = temp.PrevGroupSign[0]
+	}
 	return nil
 }
 func (input *Header) EncodeRLP(w io.Writer) error {
@@ -73,6 +79,9 @@ func (input *Header) EncodeRLP(w io.Writer) error {
 	temp.Extra = input.header.Extra
 	temp.Time = uint32(input.header.Time)
 	temp.CryptoLib = input.header.CryptoLib
+	if len(input.header.PrevGroupSign) > 0 {
+		temp.PrevGroupSign = [][]byte{input.header.PrevGroupSign}
+	}
 	return rlp.Encode(w, temp)
 }
 
@@ -96,6 +96,7 @@ type KVWrite struct {
 	isDelete   bool   `protobuf:"varint,2,opt,name=is_delete,json=isDelete"`
 	value      []byte `protobuf:"bytes,3,opt,name=value,proto3"`
 	ContractId []byte `protobuf:"bytes,4,opt,name=contract_id,proto3" json:"contract_id,omitempty"`
+	isPrivate  bool
 }
 
 func (m *KVWrite) Reset() {
@@ -104,6 +105,7 @@ func (m *KVWrite) Reset() {
 	m.isDelete = n.isDelete
 	m.value = n.value
 	m.ContractId = n.ContractId
+	m.isPrivate = n.isPrivate
 }
 func (m *KVWrite) String() string            { return proto.CompactTextString(m) }
 func (*KVWrite) ProtoMessage()               {}
@@ -130,6 +132,14 @@ func (m *KVWrite) GetValue() []byte {
 	return nil
 }
 
+//私有状态的写，value是明文
+func (m *KVWrite) GetIsPrivate() bool {
+	if m != nil {
+		return m.isPrivate
+	}
+	return false
+}
+
 type Version struct {
 	//chainId uint64 `protobuf:"varint,1,opt,name=block_num,json=blockNum"`
 	//txNum   uint64 `protobuf:"varint,2,opt,name=tx_num,json=txNum"`
@@ -41,6 +41,13 @@ type RwSetTxSimulator struct {
 	//writePerformed          bool   // 没用到，注释掉
 	pvtdataQueriesPerformed bool
 	doneInvoked             bool
+	//合约调用合约时保存的快照，被调用合约失败时回滚
+	snapshots []*rwSnapshot
+}
+
+type rwSnapshot struct {
+	rwsetBuilder *RWSetBuilder
+	write_cache  map[string][]byte
 }
 
 type VersionedValue struct {
@@ -87,7 +94,10 @@ func (s *RwSetTxSimulator) GetState(contractid []byte, ns string, key string) ([
 	if err := s.CheckDone(); err != nil {
 		return nil, err
 	}
-	if value, has := s.write_cache[key]; has {
+	if modules.IsPrivateStateKey(key) {
+		return nil, fmt.Errorf("key[%s] is reserved for private state", key)
+	}
+	if value, has := s.write_cache[cacheKey(contractid, key)]; has {
 		if s.rwsetBuilder != nil {
 			s.rwsetBuilder.AddToReadSet(contractid, ns, key, nil)
 		}
@@ -137,6 +147,49 @@ func (s *RwSetTxSimulator) GetStatesByPrefix(contractid []byte, ns string, prefi
 	return result, nil
 }
 
+
+// GetStatesByRange 按Key的顺序返回[startKey,endKey)范围内的状态，endKey为空表示不设上限。
+// limit>0时最多返回limit条，还有剩余数据时返回下一条的Key作为书签。
+// 读到的范围和Key的版本都记录到读集中，验证时范围内新增或删除的Key都会被发现
+func (s *RwSetTxSimulator) GetStatesByRange(contractid []byte, ns string, startKey, endKey string,
+	limit int) ([]*modules.KeyValue, string, error) {
+	if err := s.CheckDone(); err != nil {
+		return nil, "", err
+	}
+	if endKey != "" && startKey >= endKey {
+		return nil, "", fmt.Errorf("invalid range [%s,%s)", startKey, endKey)
+	}
+	query := limit
+	if limit > 0 {
+		query = limit + 1
+	}
+	rows, err := s.dag.GetContractStatesByRange(contractid, startKey, endKey, query)
+	if err != nil {
+		log.Debugf("get range from db[%s] failed,range:[%s,%s),error:[%s]", ns, startKey, endKey, err.Error())
+		return nil, "", err
+	}
+	bookmark := ""
+	if limit > 0 && len(rows) > limit {
+		bookmark = rows[limit].Key
+		rows = rows[:limit]
+	}
+	//分页时只记录实际读到的范围
+	rangeRead := &modules.ContractRangeReadSet{StartKey: startKey, EndKey: endKey, ContractId: contractid,
+		Reads: make([]modules.ContractReadSet, 0, len(rows))}
+	if bookmark != "" {
+		rangeRead.EndKey = bookmark
+	}
+	result := make([]*modules.KeyValue, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, &modules.KeyValue{Key: row.Key, Value: row.Value})
+		rangeRead.Reads = append(rangeRead.Reads, modules.ContractReadSet{Key: row.Key, Version: row.Version})
+	}
+	if s.rwsetBuilder != nil {
+		s.rwsetBuilder.AddToRangeReadSet(ns, rangeRead)
+	}
+	return result, bookmark, nil
+}
+
 // GetState implements method in interface `ledger.TxSimulator`
 func (s *RwSetTxSimulator) GetTimestamp(ns string, rangeNumber uint32) ([]byte, error) {
 	//testValue := []byte("abc")
@@ -160,9 +213,39 @@ func (s *RwSetTxSimulator) SetState(contractId []byte, ns string, key string, va
 	if s.pvtdataQueriesPerformed {
 		return errors.New("pvtdata Queries Performed")
 	}
+	if modules.IsPrivateStateKey(key) {
+		return fmt.Errorf("key[%s] is reserved for private state", key)
+	}
 	//todo ValidateKeyValue
 	s.rwsetBuilder.AddToWriteSet(contractId, ns, key, value)
-	s.write_cache[key] = value
+	s.write_cache[cacheKey(contractId, key)] = value
+	return nil
+}
+
+//多个合约共用一个TxSimulator，缓存的Key需要区分合约
+func cacheKey(contractId []byte, key string) string {
+	return string(contractId) + key
+}
+
+// Snapshot saves the current read/write sets and returns an id for RevertToSnapshot
+func (s *RwSetTxSimulator) Snapshot() int {
+	cache := make(map[string][]byte, len(s.write_cache))
+	for k, v := range s.write_cache {
+		cache[k] = v
+	}
+	s.snapshots = append(s.snapshots, &rwSnapshot{rwsetBuilder: s.rwsetBuilder.Copy(), write_cache: cache})
+	return len(s.snapshots) - 1
+}
+
+// RevertToSnapshot discards all reads, writes and token operations after the snapshot
+func (s *RwSetTxSimulator) RevertToSnapshot(id int) error {
+	if id < 0 || id >= len(s.snapshots) {
+		return fmt.Errorf("snapshot %d not found", id)
+	}
+	snapshot := s.snapshots[id]
+	s.rwsetBuilder = snapshot.rwsetBuilder
+	s.write_cache = snapshot.write_cache
+	s.snapshots = s.snapshots[:id]
 	return nil
 }
 
@@ -171,6 +254,50 @@ func (s *RwSetTxSimulator) DeleteState(contractId []byte, ns string, key string)
 	return s.SetState(contractId, ns, key, nil)
 }
 
+// SetPrivateState 写入私有状态，写集中保存明文，生成合约结果时替换为明文的Hash
+func (s *RwSetTxSimulator) SetPrivateState(contractId []byte, ns string, key string, value []byte) error {
+	if err := s.CheckDone(); err != nil {
+		return err
+	}
+	s.rwsetBuilder.AddToPrivateWriteSet(contractId, ns, key, value)
+	s.write_cache[cacheKey(contractId, modules.PrivateStateKey(key))] = value
+	return nil
+}
+
+// GetPrivateState 读取私有状态，本交易写过的直接返回明文，否则返回本地保存的密文，由陪审员解密
+func (s *RwSetTxSimulator) GetPrivateState(contractId []byte, ns string, key string) ([]byte,
+	*modules.PrivateState, error) {
+	if err := s.CheckDone(); err != nil {
+		return nil, nil, err
+	}
+	stateKey := modules.PrivateStateKey(key)
+	if value, has := s.write_cache[cacheKey(contractId, stateKey)]; has {
+		if s.rwsetBuilder != nil {
+			s.rwsetBuilder.AddToReadSet(contractId, ns, stateKey, nil)
+		}
+		return value, nil, nil
+	}
+	hash, ver, err := s.dag.GetContractState(contractId, stateKey)
+	if err != nil || len(hash) == 0 {
+		log.Debugf("get private state hash from db[%s] failed,key:%s", ns, key)
+		return nil, nil, nil
+	}
+	if s.rwsetBuilder != nil {
+		s.rwsetBuilder.AddToReadSet(contractId, ns, stateKey, ver)
+	}
+	ps, err := s.dag.GetPrivateState(contractId, stateKey, hash)
+	if err != nil {
+		log.Debugf("private state[%s] of hash[%x] not found", key, hash)
+		return nil, nil, modules.ErrPrivateStateNotFound
+	}
+	return nil, ps, nil
+}
+
+// DeletePrivateState 删除私有状态
+func (s *RwSetTxSimulator) DeletePrivateState(contractId []byte, ns string, key string) error {
+	return s.SetPrivateState(contractId, ns, key, nil)
+}
+
 func (s *RwSetTxSimulator) GetRwData(ns string) ([]*KVRead, []*KVWrite, error) {
 	rd := make(map[string]*KVRead)
 	wt := make(map[string]*KVWrite)
@@ -195,6 +322,12 @@ func (s *RwSetTxSimulator) GetRwData(ns string) ([]*KVRead, []*KVWrite, error) {
 	//sort keys and convert map to slice
 	return convertReadMap2Slice(rd), convertWriteMap2Slice(wt), nil
 }
+func (s *RwSetTxSimulator) GetRangeRwData(ns string) ([]modules.ContractRangeReadSet, error) {
+	if s.rwsetBuilder == nil {
+		return nil, nil
+	}
+	return s.rwsetBuilder.GetRangeReadSet(ns), nil
+}
 func convertReadMap2Slice(rd map[string]*KVRead) []*KVRead {
 	keys := make([]string, 0)
 	for k := range rd {
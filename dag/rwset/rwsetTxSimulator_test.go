@@ -215,3 +215,51 @@ func TestConvertReadMap2Slice(t *testing.T) {
 		t.Logf("%d,%#v", i, r)
 	}
 }
+
+func TestRwSetTxSimulator_RevertToSnapshot(t *testing.T) {
+	simulator := &RwSetTxSimulator{rwsetBuilder: NewRWSetBuilder(), write_cache: make(map[string][]byte)}
+	caller := []byte("caller")
+	callee := []byte("callee")
+	assert.Nil(t, simulator.SetState(caller, "caller", "balance", []byte("100")))
+
+	id := simulator.Snapshot()
+	assert.Nil(t, simulator.SetState(callee, "callee", "balance", []byte("1")))
+	simulator.PayOutToken("callee", "P1NzevLMVCFJKWr4KAcHxyyh9xXaVU8yv3N", modules.NewPTNAsset(), 1, 0)
+	//不同合约的相同Key互不影响
+	value, err := simulator.GetState(caller, "caller", "balance")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("100"), value)
+
+	assert.Nil(t, simulator.RevertToSnapshot(id))
+	_, wt, _ := simulator.GetRwData("callee")
+	assert.Equal(t, 0, len(wt))
+	payouts, _ := simulator.GetPayOutData("callee")
+	assert.Equal(t, 0, len(payouts))
+	_, wt, _ = simulator.GetRwData("caller")
+	assert.Equal(t, 1, len(wt))
+	assert.NotNil(t, simulator.RevertToSnapshot(id))
+}
+
+func TestRwSetTxSimulator_PrivateStateNamespace(t *testing.T) {
+	simulator := &RwSetTxSimulator{rwsetBuilder: NewRWSetBuilder(), write_cache: make(map[string][]byte)}
+	contractId := []byte("contract")
+	assert.Nil(t, simulator.SetState(contractId, "ns", "bid", []byte("public")))
+	assert.Nil(t, simulator.SetPrivateState(contractId, "ns", "bid", []byte("private")))
+	//公开状态不能读写私有状态的Key
+	assert.NotNil(t, simulator.SetState(contractId, "ns", modules.PrivateStateKey("bid"), []byte("1")))
+	_, err := simulator.GetState(contractId, "ns", modules.PrivateStateKey("bid"))
+	assert.NotNil(t, err)
+
+	value, err := simulator.GetState(contractId, "ns", "bid")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("public"), value)
+	value, _, err = simulator.GetPrivateState(contractId, "ns", "bid")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("private"), value)
+
+	_, wt, _ := simulator.GetRwData("ns")
+	assert.Equal(t, 2, len(wt))
+	for _, w := range wt {
+		assert.Equal(t, w.GetIsPrivate(), modules.IsPrivateStateKey(w.GetKey()))
+	}
+}
@@ -15,6 +15,8 @@ type TxManager interface {
 type TxSimulator interface {
 	GetState(contractid []byte, ns string, key string) ([]byte, error)
 	GetStatesByPrefix(contractid []byte, ns string, prefix string) ([]*modules.KeyValue, error)
+	GetStatesByRange(contractid []byte, ns string, startKey, endKey string, limit int) ([]*modules.KeyValue,
+		string, error)
 	GetTimestamp(ns string, rangeNumber uint32) ([]byte, error)
 	SetState(contractid []byte, ns string, key string, value []byte) error
 	GetTokenBalance(ns string, addr common.Address, asset *modules.Asset) (map[modules.Asset]uint64, error)
@@ -24,12 +26,18 @@ type TxSimulator interface {
 	DefineToken(ns string, tokenType int32, define []byte, creator string) error
 	SupplyToken(ns string, assetId, uniqueId []byte, amt uint64, creator string) error
 	DeleteState(contractid []byte, ns string, key string) error
+	SetPrivateState(contractid []byte, ns string, key string, value []byte) error
+	GetPrivateState(contractid []byte, ns string, key string) ([]byte, *modules.PrivateState, error)
+	DeletePrivateState(contractid []byte, ns string, key string) error
 	GetContractStatesById(contractid []byte) (map[string]*modules.ContractStateValue, error)
 	GetRwData(ns string) ([]*KVRead, []*KVWrite, error)
+	GetRangeRwData(ns string) ([]modules.ContractRangeReadSet, error)
 	GetPayOutData(ns string) ([]*modules.TokenPayOut, error)
 	GetTokenDefineData(ns string) (*modules.TokenDefine, error)
 	GetTokenSupplyData(ns string) ([]*modules.TokenSupply, error)
 	GetTxSimulationResults() ([]byte, error)
+	Snapshot() int
+	RevertToSnapshot(id int) error
 	CheckDone() error
 	Done()
 	String() string
@@ -37,6 +37,7 @@ type nsPubRwBuilder struct {
 	tokenPayOut []*modules.TokenPayOut
 	tokenSupply []*modules.TokenSupply
 	tokenDefine *modules.TokenDefine
+	rangeReads  []*modules.ContractRangeReadSet
 }
 
 func NewRWSetBuilder() *RWSetBuilder {
@@ -69,6 +70,20 @@ func (b *RWSetBuilder) AddToReadSet(contractId []byte, ns string, key string, ve
 	// ReadSet
 	nsPubRwBuilder.readMap[key] = NewKVRead(contractId, key, version)
 }
+
+//记录一次范围查询读到的所有Key及版本，用于验证时检测幻读
+func (b *RWSetBuilder) AddToRangeReadSet(ns string, rangeRead *modules.ContractRangeReadSet) {
+	nsPubRwBuilder := b.getOrCreateNsPubRwBuilder(ns)
+	nsPubRwBuilder.rangeReads = append(nsPubRwBuilder.rangeReads, rangeRead)
+}
+func (b *RWSetBuilder) GetRangeReadSet(ns string) []modules.ContractRangeReadSet {
+	nsPubRwBuilder := b.getOrCreateNsPubRwBuilder(ns)
+	result := make([]modules.ContractRangeReadSet, 0, len(nsPubRwBuilder.rangeReads))
+	for _, rr := range nsPubRwBuilder.rangeReads {
+		result = append(result, rr.Copy())
+	}
+	return result
+}
 func (b *RWSetBuilder) AddTokenPayOut(ns string, addr string, asset *modules.Asset, amount uint64, lockTime uint32) {
 	nsPubRwBuilder := b.getOrCreateNsPubRwBuilder(ns)
 	if nsPubRwBuilder.tokenPayOut == nil {
@@ -86,6 +101,17 @@ func (b *RWSetBuilder) AddToWriteSet(contractId []byte, ns string, key string, v
 	}
 	nsPubRwBuilder.writeMap[key] = newKVWrite(contractId, key, value)
 }
+//私有状态使用单独的Key前缀，与同名的公开状态互不覆盖
+func (b *RWSetBuilder) AddToPrivateWriteSet(contractId []byte, ns string, key string, value []byte) {
+	nsPubRwBuilder := b.getOrCreateNsPubRwBuilder(ns)
+	if nsPubRwBuilder.writeMap == nil {
+		nsPubRwBuilder.writeMap = make(map[string]*KVWrite)
+	}
+	stateKey := modules.PrivateStateKey(key)
+	write := newKVWrite(contractId, stateKey, value)
+	write.isPrivate = true
+	nsPubRwBuilder.writeMap[stateKey] = write
+}
 func (b *RWSetBuilder) GetTokenPayOut(ns string) []*modules.TokenPayOut {
 	nsPubRwBuilder := b.getOrCreateNsPubRwBuilder(ns)
 
@@ -122,6 +148,28 @@ func (b *RWSetBuilder) AddSupplyToken(ns string, assetId, uniqueId []byte, amt u
 	return nil
 }
 
+// Copy returns a deep copy of the builder, used for the snapshot of simulator
+func (b *RWSetBuilder) Copy() *RWSetBuilder {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	cp := NewRWSetBuilder()
+	for ns, nsBuilder := range b.pubRwBuilderMap {
+		nb := newNsPubRwBuilder(ns)
+		for k, v := range nsBuilder.readMap {
+			nb.readMap[k] = v
+		}
+		for k, v := range nsBuilder.writeMap {
+			nb.writeMap[k] = v
+		}
+		nb.tokenPayOut = append(nb.tokenPayOut, nsBuilder.tokenPayOut...)
+		nb.tokenSupply = append(nb.tokenSupply, nsBuilder.tokenSupply...)
+		nb.tokenDefine = nsBuilder.tokenDefine
+		nb.rangeReads = append(nb.rangeReads, nsBuilder.rangeReads...)
+		cp.pubRwBuilderMap[ns] = nb
+	}
+	return cp
+}
+
 func (b *RWSetBuilder) getOrCreateNsPubRwBuilder(ns string) *nsPubRwBuilder {
 	b.locker.Lock()
 	defer b.locker.Unlock()
@@ -142,5 +190,6 @@ func newNsPubRwBuilder(namespace string) *nsPubRwBuilder {
 		[]*modules.TokenPayOut{},
 		[]*modules.TokenSupply{},
 		nil,
+		[]*modules.ContractRangeReadSet{},
 	}
 }
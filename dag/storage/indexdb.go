@@ -21,10 +21,14 @@
 package storage
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/core/types"
 	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
 )
 
@@ -48,6 +52,14 @@ type IIndexDb interface {
 	GetMainDataTxIds(maindata []byte) ([]common.Hash, error)
 	SaveProofOfExistence(poe *modules.ProofOfExistence) error
 	QueryProofOfExistenceByReference(ref []byte) ([]*modules.ProofOfExistence, error)
+
+	SaveContractEvents(unitIndex uint64, logs []*modules.ContractEventLog) error
+	GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error)
+	GetContractEventBloom(unitIndex uint64) (types.Bloom, error)
+
+	SavePrivateState(ps *modules.PrivateState) error
+	GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error)
+	PurgePrivateStates(expireTime uint64, obsolete func(ps *modules.PrivateState) bool) (int, error)
 }
 
 func (db *IndexDb) SaveAddressTxId(address common.Address, txid common.Hash) error {
@@ -141,3 +153,85 @@ func (db *IndexDb) QueryProofOfExistenceByReference(ref []byte) ([]*modules.Proo
 	}
 	return result, nil
 }
+
+func getContractEventKey(prefix []byte, unitIndex uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, unitIndex)
+	return append(common.CopyBytes(prefix), b...)
+}
+
+//SaveContractEvents 保存一个单元的合约事件和对应的Bloom
+func (db *IndexDb) SaveContractEvents(unitIndex uint64, logs []*modules.ContractEventLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := StoreToRlpBytes(db.db, getContractEventKey(constants.CONTRACT_EVENT_PREFIX, unitIndex), logs); err != nil {
+		return err
+	}
+	bloom := modules.CreateContractEventBloom(logs)
+	return db.db.Put(getContractEventKey(constants.CONTRACT_EVENT_BLOOM, unitIndex), bloom.Bytes())
+}
+
+func (db *IndexDb) GetContractEvents(unitIndex uint64) ([]*modules.ContractEventLog, error) {
+	logs := []*modules.ContractEventLog{}
+	err := RetrieveFromRlpBytes(db.db, getContractEventKey(constants.CONTRACT_EVENT_PREFIX, unitIndex), &logs)
+	if err != nil && !errors.IsNotFoundError(err) {
+		return nil, err
+	}
+	return logs, nil
+}
+
+//GetContractEventBloom 单元没有合约事件时返回空的Bloom
+func (db *IndexDb) GetContractEventBloom(unitIndex uint64) (types.Bloom, error) {
+	data, err := db.db.Get(getContractEventKey(constants.CONTRACT_EVENT_BLOOM, unitIndex))
+	if err != nil {
+		if errors.IsNotFoundError(err) {
+			return types.Bloom{}, nil
+		}
+		return types.Bloom{}, err
+	}
+	return types.BytesToBloom(data), nil
+}
+
+//私有状态按明文的Hash保存，没有上链的执行结果不会覆盖链上值对应的密文
+func getPrivateStateKey(contractId []byte, key string, hash []byte) []byte {
+	k := append(common.CopyBytes(constants.PRIVATE_STATE_PREFIX), contractId...)
+	k = append(k, key...)
+	return append(k, hash...)
+}
+
+//SavePrivateState 保存加密的私有状态
+func (db *IndexDb) SavePrivateState(ps *modules.PrivateState) error {
+	return StoreToRlpBytes(db.db, getPrivateStateKey(ps.ContractId, ps.Key, ps.Hash), ps)
+}
+
+func (db *IndexDb) GetPrivateState(contractId []byte, key string, hash []byte) (*modules.PrivateState, error) {
+	ps := &modules.PrivateState{}
+	err := RetrieveFromRlpBytes(db.db, getPrivateStateKey(contractId, key, hash), ps)
+	if err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+//PurgePrivateStates 删除保存时间早于expireTime且已经失效的私有状态，返回删除的数量
+func (db *IndexDb) PurgePrivateStates(expireTime uint64, obsolete func(ps *modules.PrivateState) bool) (int, error) {
+	iter := db.db.NewIteratorWithPrefix(constants.PRIVATE_STATE_PREFIX)
+	defer iter.Release()
+	keys := make([][]byte, 0)
+	for iter.Next() {
+		ps := &modules.PrivateState{}
+		if err := rlp.DecodeBytes(iter.Value(), ps); err != nil {
+			return 0, err
+		}
+		if ps.Timestamp < expireTime && obsolete(ps) {
+			keys = append(keys, common.CopyBytes(iter.Key()))
+		}
+	}
+	for _, key := range keys {
+		if err := db.db.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
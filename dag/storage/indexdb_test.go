@@ -22,6 +22,7 @@
 package storage
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/palletone/go-palletone/common"
@@ -50,3 +51,61 @@ func TestIndexDb_QueryProofOfExistenceByReference(t *testing.T) {
 }
 
 
+
+func TestIndexDb_SaveContractEvents(t *testing.T) {
+	db, _ := ptndb.NewMemDatabase()
+	idxdb := NewIndexDb(db)
+	contractId := common.BytesToAddress([]byte("contract1")).Bytes()
+	logs := []*modules.ContractEventLog{{ContractId: contractId, Name: "transfer", Payload: []byte("100"),
+		TxHash: common.BytesToHash([]byte("txid")), UnitIndex: 10}}
+	assert.Nil(t, idxdb.SaveContractEvents(10, logs))
+
+	result, err := idxdb.GetContractEvents(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "transfer", result[0].Name)
+	bloom, err := idxdb.GetContractEventBloom(10)
+	assert.Nil(t, err)
+	assert.True(t, bloom.Test(contractId))
+	assert.True(t, bloom.Test([]byte("transfer")))
+	assert.False(t, bloom.Test([]byte("approve")))
+
+	//没有事件的单元
+	result, err = idxdb.GetContractEvents(11)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result))
+	bloom, err = idxdb.GetContractEventBloom(11)
+	assert.Nil(t, err)
+	assert.False(t, bloom.Test([]byte("transfer")))
+}
+
+func TestIndexDb_PrivateState(t *testing.T) {
+	db, _ := ptndb.NewMemDatabase()
+	idxdb := NewIndexDb(db)
+	contractId := common.BytesToAddress([]byte("contract1")).Bytes()
+	old := &modules.PrivateState{ContractId: contractId, Key: "kyc", Hash: []byte("hash1"), Timestamp: 100}
+	latest := &modules.PrivateState{ContractId: contractId, Key: "kyc", Hash: []byte("hash2"), Timestamp: 200}
+	assert.Nil(t, idxdb.SavePrivateState(old))
+	assert.Nil(t, idxdb.SavePrivateState(latest))
+
+	ps, err := idxdb.GetPrivateState(contractId, "kyc", []byte("hash1"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100), ps.Timestamp)
+
+	//链上的Hash仍然是hash1时不清理
+	current := []byte("hash1")
+	obsolete := func(ps *modules.PrivateState) bool { return !bytes.Equal(ps.Hash, current) }
+	count, err := idxdb.PurgePrivateStates(150, obsolete)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+	//被hash2替换后，只清理超过保留期的hash1
+	current = []byte("hash2")
+	count, err = idxdb.PurgePrivateStates(300, obsolete)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	_, err = idxdb.GetPrivateState(contractId, "kyc", []byte("hash1"))
+	assert.NotNil(t, err)
+	ps, err = idxdb.GetPrivateState(contractId, "kyc", []byte("hash2"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(200), ps.Timestamp)
+}
@@ -21,6 +21,7 @@
 package storage
 
 import (
+	"encoding/binary"
 	"reflect"
 
 	"github.com/palletone/go-palletone/common/log"
@@ -49,6 +50,9 @@ type IPropertyDb interface {
 	GetNewestUnit(token modules.AssetId) (*modules.UnitProperty, error)
 
 	GetChainParameters() *core.ChainParameters
+
+	StoreSnapshotCommitment(c *modules.SnapshotCommitment) error
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 }
 
 func (propdb *PropertyDb) GetChainParameters() *core.ChainParameters {
@@ -188,3 +192,25 @@ func (db *PropertyDb) GetNewestUnit(asset modules.AssetId) (*modules.UnitPropert
 	//return data.Hash, data.Index, int64(data.Timestamp), nil
 	return data, nil
 }
+
+func getSnapshotKey(index uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, index)
+	return append(constants.SNAPSHOT_PREFIX, b...)
+}
+
+// StoreSnapshotCommitment 保存稳定单元处UTXO集和状态的快照Hash
+func (db *PropertyDb) StoreSnapshotCommitment(c *modules.SnapshotCommitment) error {
+	log.Debugf("DB[%s] Save snapshot hash %s of unit #%d", reflect.TypeOf(db.db).String(),
+		c.Hash.String(), c.UnitIndex)
+	return StoreToRlpBytes(db.db, getSnapshotKey(c.UnitIndex), c)
+}
+
+func (db *PropertyDb) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	c := &modules.SnapshotCommitment{}
+	err := RetrieveFromRlpBytes(db.db, getSnapshotKey(index), c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,542 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2019
+ *
+ */
+
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	stdhash "hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"golang.org/x/crypto/sha3"
+)
+
+const snapshotVersion = 1
+
+// 快照导入时每批写入的记录数
+const snapshotBatchSize = 10000
+
+// snapshotPrefixes 快照包含的数据：UTXO集、合约和账户状态、全局属性。
+// 地址到UTXO的索引在导入时重建，不包含在快照中
+var snapshotPrefixes = [][]byte{
+	constants.UTXO_PREFIX,
+	constants.CONTRACT_STATE_PREFIX,
+	constants.CONTRACT_TPL,
+	constants.CONTRACT_TPL_CODE,
+	constants.CONTRACT_DEPLOY,
+	constants.CONTRACT_DEPLOY_REQ,
+	constants.CONTRACT_STOP,
+	constants.CONTRACT_STOP_REQ,
+	constants.CONTRACT_INVOKE,
+	constants.CONTRACT_INVOKE_REQ,
+	constants.CONTRACT_SIGNATURE,
+	constants.CONTRACT_PREFIX,
+	constants.CONTRACT_TPL_INSTANCE_MAP,
+	constants.CONTRACT_JURY_PREFIX,
+	constants.MEDIATOR_INFO_PREFIX,
+	constants.DEPOSIT_JURY_BALANCE_PREFIX,
+	constants.ACCOUNT_INFO_PREFIX,
+	constants.ACCOUNT_PTN_BALANCE_PREFIX,
+	constants.GLOBAL_PROPERTY_HISTORY_PREFIX,
+	constants.GLOBALPROPERTY_KEY,
+	constants.DYNAMIC_GLOBALPROPERTY_KEY,
+	constants.MEDIATOR_SCHEDULE_KEY,
+}
+
+var (
+	ErrSnapshotHashMismatch = errors.New("snapshot hash mismatch")
+	ErrSnapshotNotCommitted = errors.New("snapshot is not committed by the unit")
+)
+
+// SnapshotMeta 快照对应的稳定单元
+type SnapshotMeta struct {
+	Version   uint32
+	UnitIndex uint64
+	UnitHash  common.Hash
+}
+
+type snapshotEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+type snapshotFooter struct {
+	Count uint64
+	Hash  common.Hash
+}
+
+// Snapshot 快照文件: Meta、稳定单元、所有的数据记录、Footer(记录数和快照Hash)，
+// 以及可选的提交了该快照Hash的单元Header
+type Snapshot struct {
+	Meta   *SnapshotMeta
+	Unit   *modules.Unit
+	Hash   common.Hash
+	Count  uint64
+	Commit *modules.Header
+}
+
+type snapshotHasher struct {
+	hasher stdhash.Hash
+	count  uint64
+}
+
+func newSnapshotHasher(meta *SnapshotMeta) (*snapshotHasher, error) {
+	h := &snapshotHasher{hasher: sha3.New256()}
+	if err := rlp.Encode(h.hasher, meta); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *snapshotHasher) add(entry *snapshotEntry) error {
+	h.count++
+	return rlp.Encode(h.hasher, entry)
+}
+
+func (h *snapshotHasher) sum() common.Hash {
+	return common.BytesToHash(h.hasher.Sum(nil))
+}
+
+// 按固定的顺序遍历快照包含的数据
+// SnapshotSource 可以按前缀遍历的数据源，数据库或者数据库的只读视图
+type SnapshotSource interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// SnapshotView 数据库在某一时刻的只读视图，之后写入数据库的数据不影响视图，用于在后台导出快照
+type SnapshotView struct {
+	snap *leveldb.Snapshot
+	mem  *ptndb.MemDatabase
+}
+
+// NewSnapshotView 创建数据库当前的只读视图，使用完后需要调用Release
+func NewSnapshotView(db ptndb.Database) (*SnapshotView, error) {
+	if ldb, ok := db.(interface{ LDB() *leveldb.DB }); ok {
+		snap, err := ldb.LDB().GetSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		return &SnapshotView{snap: snap}, nil
+	}
+	//其他数据库(如内存数据库)不支持快照，复制快照需要的数据
+	mem, _ := ptndb.NewMemDatabase()
+	err := forEachSnapshotEntry(db, func(entry *snapshotEntry) error {
+		return mem.Put(entry.Key, entry.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotView{mem: mem}, nil
+}
+
+func (v *SnapshotView) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	if v.snap != nil {
+		return v.snap.NewIterator(util.BytesPrefix(prefix), nil)
+	}
+	return v.mem.NewIteratorWithPrefix(prefix)
+}
+
+func (v *SnapshotView) Release() {
+	if v.snap != nil {
+		v.snap.Release()
+	}
+}
+
+func forEachSnapshotEntry(db SnapshotSource, fn func(entry *snapshotEntry) error) error {
+	for _, prefix := range snapshotPrefixes {
+		iter := db.NewIteratorWithPrefix(prefix)
+		for iter.Next() {
+			entry := &snapshotEntry{Key: common.CopyBytes(iter.Key()), Value: common.CopyBytes(iter.Value())}
+			if err := fn(entry); err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotHash 计算数据库中当前的UTXO集和状态的快照Hash，unit为数据库中最新的稳定单元
+func SnapshotHash(db SnapshotSource, unit *modules.Header) (common.Hash, error) {
+	h, err := newSnapshotHasher(&SnapshotMeta{Version: snapshotVersion, UnitIndex: unit.NumberU64(),
+		UnitHash: unit.Hash()})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := forEachSnapshotEntry(db, h.add); err != nil {
+		return common.Hash{}, err
+	}
+	return h.sum(), nil
+}
+
+// ExportSnapshot 将数据库中当前的UTXO集和状态写入w，unit为数据库中最新的稳定单元，
+// commit为提交了该快照Hash的单元Header，可以为nil
+func ExportSnapshot(db SnapshotSource, unit *modules.Unit, commit *modules.Header,
+	w io.Writer) (*Snapshot, error) {
+	meta := &SnapshotMeta{Version: snapshotVersion, UnitIndex: unit.NumberU64(), UnitHash: unit.Hash()}
+	h, err := newSnapshotHasher(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(w, meta); err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(w, unit); err != nil {
+		return nil, err
+	}
+	err = forEachSnapshotEntry(db, func(entry *snapshotEntry) error {
+		if err := h.add(entry); err != nil {
+			return err
+		}
+		return rlp.Encode(w, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 空记录表示数据结束
+	if err := rlp.Encode(w, &snapshotEntry{}); err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{Meta: meta, Unit: unit, Hash: h.sum(), Count: h.count, Commit: commit}
+	if err := rlp.Encode(w, &snapshotFooter{Count: snap.Count, Hash: snap.Hash}); err != nil {
+		return nil, err
+	}
+	if commit != nil {
+		if err := rlp.Encode(w, commit); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// SnapshotDir 快照点保存快照文件的目录，与数据库目录同级
+func SnapshotDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "snapshot")
+}
+
+// SnapshotFile 快照点保存的快照文件
+func SnapshotFile(dir string, index uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.snapshot", index))
+}
+
+// SaveSnapshotFile 快照点的单元稳定时，把数据库中当前的UTXO集和状态写入dir下的快照文件，返回快照Hash。
+// 数据库中的状态会随之后的单元变化，只有在快照点保存的文件才能用之后提交的快照Hash校验。
+// 只保留最近两个快照点的文件
+func SaveSnapshotFile(db SnapshotSource, unit *modules.Unit, dir string) (common.Hash, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return common.Hash{}, err
+	}
+	path := SnapshotFile(dir, unit.NumberU64())
+	f, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	w := bufio.NewWriter(f)
+	snap, err := ExportSnapshot(db, unit, nil, w)
+	if err == nil {
+		if err = w.Flush(); err == nil {
+			err = f.Sync()
+		}
+	}
+	f.Close()
+	if err == nil {
+		err = os.Rename(path+".new", path)
+	}
+	if err != nil {
+		os.Remove(path + ".new")
+		return common.Hash{}, err
+	}
+	if unit.NumberU64() > 2*modules.SnapshotInterval {
+		os.Remove(SnapshotFile(dir, unit.NumberU64()-2*modules.SnapshotInterval))
+	}
+	return snap.Hash, nil
+}
+
+// ExportSnapshotFile 校验快照点保存的快照文件，附加提交了快照Hash的单元Header后写入w
+func ExportSnapshotFile(path string, commit *modules.Header, w io.Writer) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := ReadSnapshot(bufio.NewReader(f), common.Hash{})
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if snap.Commit != nil {
+		return nil, errors.New("the snapshot file already has a commit header")
+	}
+	snap.Commit = commit
+	if err := snap.Verify(commit.Hash()); err != nil {
+		return nil, err
+	}
+	if f, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(w, commit); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// ReadSnapshot 读取快照文件并校验其完整性和提交，不写入数据库。
+// trusted不为空时，快照必须被Hash为trusted的单元提交。
+// 文件中的提交Header没有签名，trusted为空时只校验Header与快照一致，不能证明快照来自主链
+func ReadSnapshot(r io.Reader, trusted common.Hash) (*Snapshot, error) {
+	return readSnapshot(r, trusted, nil)
+}
+
+// ImportSnapshot 将快照写入数据库，并把快照的稳定单元设为数据库中最新的单元。
+// 数据库中只能有创世单元，创世单元的UTXO集和状态被快照替换。
+// 写入数据库之前应先用ReadSnapshot校验快照
+func ImportSnapshot(db ptndb.Database, r io.Reader, trusted common.Hash) (*Snapshot, error) {
+	dagdb := NewDagDb(db)
+	propdb := NewPropertyDb(db)
+	if _, err := dagdb.GetGenesisUnitHash(); err != nil {
+		return nil, errors.New("the genesis unit does not exist, init the node first")
+	}
+	cleared := false
+	batch := db.NewBatch()
+	snap, err := readSnapshot(r, trusted, func(unit *modules.Unit, entry *snapshotEntry) error {
+		if !cleared {
+			newest, err := propdb.GetNewestUnit(unit.GetAssetId())
+			if err != nil {
+				return err
+			}
+			if newest.ChainIndex.Index != 0 {
+				return fmt.Errorf("the database already has units, the newest is #%d", newest.ChainIndex.Index)
+			}
+			if err := clearSnapshotState(db); err != nil {
+				return err
+			}
+			cleared = true
+		}
+		if err := batch.Put(entry.Key, entry.Value); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ptndb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !cleared {
+		return nil, errors.New("the snapshot is empty")
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	// 重建地址到UTXO的索引
+	if err := rebuildUtxoIndex(db); err != nil {
+		return nil, err
+	}
+	// 保存快照的稳定单元，并设为最新单元
+	if err := saveSnapshotUnit(dagdb, snap.Unit); err != nil {
+		return nil, err
+	}
+	if err := propdb.SetNewestUnit(snap.Unit.Header()); err != nil {
+		return nil, err
+	}
+	log.Infof("Imported snapshot of unit[%s] #%d, entries:%d", snap.Unit.Hash().String(),
+		snap.Unit.NumberU64(), snap.Count)
+	return snap, nil
+}
+
+func readSnapshot(r io.Reader, trusted common.Hash,
+	fn func(unit *modules.Unit, entry *snapshotEntry) error) (*Snapshot, error) {
+	stream := rlp.NewStream(r, 0)
+	meta := new(SnapshotMeta)
+	if err := stream.Decode(meta); err != nil {
+		return nil, fmt.Errorf("decode snapshot meta failed: %s", err.Error())
+	}
+	if meta.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", meta.Version)
+	}
+	unit := new(modules.Unit)
+	if err := stream.Decode(unit); err != nil {
+		return nil, fmt.Errorf("decode snapshot unit failed: %s", err.Error())
+	}
+	if unit.Hash() != meta.UnitHash || unit.NumberU64() != meta.UnitIndex {
+		return nil, errors.New("the unit of snapshot mismatches the meta")
+	}
+	if core.DeriveSha(unit.Txs) != unit.UnitHeader.TxRoot() {
+		return nil, errors.New("the transactions of snapshot unit mismatch the tx root")
+	}
+	h, err := newSnapshotHasher(meta)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		entry := new(snapshotEntry)
+		if err := stream.Decode(entry); err != nil {
+			return nil, fmt.Errorf("decode snapshot entry failed: %s", err.Error())
+		}
+		if len(entry.Key) == 0 {
+			break
+		}
+		if !isSnapshotKey(entry.Key) {
+			return nil, fmt.Errorf("unexpected key %x in snapshot", entry.Key)
+		}
+		if err := h.add(entry); err != nil {
+			return nil, err
+		}
+		if fn != nil {
+			if err := fn(unit, entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	footer := new(snapshotFooter)
+	if err := stream.Decode(footer); err != nil {
+		return nil, fmt.Errorf("decode snapshot footer failed: %s", err.Error())
+	}
+	snap := &Snapshot{Meta: meta, Unit: unit, Hash: h.sum(), Count: h.count}
+	if footer.Hash != snap.Hash || footer.Count != snap.Count {
+		return nil, ErrSnapshotHashMismatch
+	}
+	commit := new(modules.Header)
+	if err := stream.Decode(commit); err == nil {
+		snap.Commit = commit
+	} else if err != io.EOF {
+		return nil, fmt.Errorf("decode snapshot commit header failed: %s", err.Error())
+	}
+	if err := snap.Verify(trusted); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Verify 校验快照Hash是否被单元Header提交。trusted不为空时，提交快照的单元必须是trusted
+func (snap *Snapshot) Verify(trusted common.Hash) error {
+	if snap.Commit == nil {
+		if common.EmptyHash(trusted) {
+			return nil
+		}
+		return ErrSnapshotNotCommitted
+	}
+	committed, index, ok := snap.Commit.GetSnapshotHash()
+	if !ok || index != snap.Meta.UnitIndex || committed != snap.Hash {
+		return ErrSnapshotNotCommitted
+	}
+	if snap.Commit.GetAssetId() != snap.Unit.GetAssetId() {
+		return ErrSnapshotNotCommitted
+	}
+	if !common.EmptyHash(trusted) && snap.Commit.Hash() != trusted {
+		return fmt.Errorf("the snapshot is committed by unit[%s], not the trusted unit[%s]",
+			snap.Commit.Hash().String(), trusted.String())
+	}
+	return nil
+}
+
+func isSnapshotKey(key []byte) bool {
+	for _, prefix := range snapshotPrefixes {
+		if len(key) >= len(prefix) && string(key[:len(prefix)]) == string(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 删除数据库中创世单元的UTXO集、地址索引和状态，由快照中的数据替换
+func clearSnapshotState(db ptndb.Database) error {
+	prefixes := append([][]byte{constants.ADDR_OUTPOINT_PREFIX, constants.OUTPOINT_ADDR_PREFIX},
+		snapshotPrefixes...)
+	for _, prefix := range prefixes {
+		iter := db.NewIteratorWithPrefix(prefix)
+		keys := make([][]byte, 0)
+		for iter.Next() {
+			keys = append(keys, common.CopyBytes(iter.Key()))
+		}
+		iter.Release()
+		for _, key := range keys {
+			if err := db.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func rebuildUtxoIndex(db ptndb.Database) error {
+	utxodb := NewUtxoDb(db, tokenengine.Instance)
+	view := make(map[modules.OutPoint]*modules.Utxo)
+	iter := db.NewIteratorWithPrefix(constants.UTXO_PREFIX)
+	defer iter.Release()
+	for iter.Next() {
+		utxo := new(modules.Utxo)
+		if err := rlp.DecodeBytes(iter.Value(), utxo); err != nil {
+			return err
+		}
+		view[*modules.KeyToOutpoint(iter.Key())] = utxo
+		if len(view) >= snapshotBatchSize {
+			if err := utxodb.SaveUtxoView(view); err != nil {
+				return err
+			}
+			view = make(map[modules.OutPoint]*modules.Utxo)
+		}
+	}
+	return utxodb.SaveUtxoView(view)
+}
+
+func saveSnapshotUnit(dagdb *DagDb, unit *modules.Unit) error {
+	if err := dagdb.SaveHeader(unit.UnitHeader); err != nil {
+		return err
+	}
+	hashes := make([]common.Hash, 0, len(unit.Txs))
+	for i, tx := range unit.Txs {
+		if err := dagdb.SaveTransaction(tx); err != nil {
+			return err
+		}
+		if err := dagdb.SaveTxLookupEntry(unit.Hash(), unit.NumberU64(), uint64(unit.Timestamp()), i,
+			tx); err != nil {
+			return err
+		}
+		hashes = append(hashes, tx.Hash())
+	}
+	return dagdb.SaveBody(unit.Hash(), hashes)
+}
@@ -0,0 +1,172 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2019
+ *
+ */
+
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshotDb4Test(t *testing.T, utxos map[modules.OutPoint]*modules.Utxo) ptndb.Database {
+	db, _ := ptndb.NewMemDatabase()
+	genesis := modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{},
+		[]uint16{}, modules.PTNCOIN, 0, 100)
+	assert.Nil(t, NewDagDb(db).SaveGenesisUnitHash(genesis.Hash()))
+	assert.Nil(t, NewPropertyDb(db).SetNewestUnit(genesis))
+	assert.Nil(t, NewUtxoDb(db, tokenengine.Instance).SaveUtxoView(utxos))
+	return db
+}
+
+func TestExportImportSnapshot(t *testing.T) {
+	addr, _ := common.StringToAddress("P1H4uUec5di1wCm8pKGLPxhXM6s7xVutKs9")
+	lock := tokenengine.Instance.GenerateLockScript(addr)
+	outpoint := modules.NewOutPoint(common.BytesToHash([]byte("snapshot")), 0, 0)
+	stale := modules.NewOutPoint(common.BytesToHash([]byte("genesis")), 0, 0)
+
+	src := newSnapshotDb4Test(t, map[modules.OutPoint]*modules.Utxo{
+		*outpoint: {Amount: 100, Asset: modules.NewPTNAsset(), PkScript: lock},
+	})
+	assert.Nil(t, src.Put(append(constants.CONTRACT_STATE_PREFIX, []byte("key")...), []byte("value")))
+	txs := modules.Transactions{}
+	header := modules.NewHeader([]common.Hash{common.BytesToHash([]byte("parent"))}, core.DeriveSha(txs),
+		[]byte{}, []byte{}, []byte{}, []byte{}, []uint16{}, modules.PTNCOIN, modules.SnapshotInterval, 200)
+	unit := modules.NewUnit(header, txs)
+	hash, err := SnapshotHash(src, header)
+	assert.Nil(t, err)
+	commit := modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, hash.Bytes(), []byte{},
+		[]uint16{}, modules.PTNCOIN, modules.SnapshotCommitIndex(modules.SnapshotInterval), 300)
+
+	buf := new(bytes.Buffer)
+	snap, err := ExportSnapshot(src, unit, commit, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, snap.Hash)
+	data := buf.Bytes()
+
+	//校验快照
+	_, err = ReadSnapshot(bytes.NewReader(data), commit.Hash())
+	assert.Nil(t, err)
+	_, err = ReadSnapshot(bytes.NewReader(data), common.BytesToHash([]byte("other")))
+	assert.NotNil(t, err)
+	tampered := common.CopyBytes(data)
+	i := bytes.Index(tampered, []byte("value"))
+	tampered[i] = 'V'
+	_, err = ReadSnapshot(bytes.NewReader(tampered), common.Hash{})
+	assert.Equal(t, ErrSnapshotHashMismatch, err)
+
+	//导入快照，创世单元的UTXO被快照替换
+	dst := newSnapshotDb4Test(t, map[modules.OutPoint]*modules.Utxo{
+		*stale: {Amount: 1, Asset: modules.NewPTNAsset(), PkScript: lock},
+	})
+	_, err = ImportSnapshot(dst, bytes.NewReader(data), commit.Hash())
+	assert.Nil(t, err)
+	hash2, err := SnapshotHash(dst, header)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, hash2)
+	utxodb := NewUtxoDb(dst, tokenengine.Instance)
+	outpoints, err := utxodb.GetAddrOutpoints(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, []modules.OutPoint{*outpoint}, outpoints)
+	newest, err := NewPropertyDb(dst).GetNewestUnit(modules.PTNCOIN)
+	assert.Nil(t, err)
+	assert.Equal(t, unit.Hash(), newest.Hash)
+
+	//已经有单元的数据库不能导入快照
+	_, err = ImportSnapshot(dst, bytes.NewReader(data), commit.Hash())
+	assert.NotNil(t, err)
+}
+
+func TestSaveExportSnapshotFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	addr, _ := common.StringToAddress("P1H4uUec5di1wCm8pKGLPxhXM6s7xVutKs9")
+	outpoint := modules.NewOutPoint(common.BytesToHash([]byte("snapshot")), 0, 0)
+	db := newSnapshotDb4Test(t, map[modules.OutPoint]*modules.Utxo{
+		*outpoint: {Amount: 100, Asset: modules.NewPTNAsset(), PkScript: tokenengine.Instance.GenerateLockScript(addr)},
+	})
+	txs := modules.Transactions{}
+	header := modules.NewHeader([]common.Hash{common.BytesToHash([]byte("parent"))}, core.DeriveSha(txs),
+		[]byte{}, []byte{}, []byte{}, []byte{}, []uint16{}, modules.PTNCOIN, modules.SnapshotInterval, 200)
+	unit := modules.NewUnit(header, txs)
+	hash, err := SaveSnapshotFile(db, unit, dir)
+	assert.Nil(t, err)
+
+	//快照点之后状态变化，导出的仍然是快照点保存的快照
+	assert.Nil(t, db.Put(append(constants.CONTRACT_STATE_PREFIX, []byte("key")...), []byte("value")))
+	commit := modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, hash.Bytes(), []byte{},
+		[]uint16{}, modules.PTNCOIN, modules.SnapshotCommitIndex(modules.SnapshotInterval), 300)
+	buf := new(bytes.Buffer)
+	snap, err := ExportSnapshotFile(SnapshotFile(dir, modules.SnapshotInterval), commit, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, snap.Hash)
+	snap, err = ReadSnapshot(bytes.NewReader(buf.Bytes()), commit.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, commit.Hash(), snap.Commit.Hash())
+
+	//提交的快照Hash与文件不一致
+	other := modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{},
+		common.BytesToHash([]byte("other")).Bytes(), []byte{}, []uint16{}, modules.PTNCOIN,
+		modules.SnapshotCommitIndex(modules.SnapshotInterval), 300)
+	_, err = ExportSnapshotFile(SnapshotFile(dir, modules.SnapshotInterval), other, new(bytes.Buffer))
+	assert.Equal(t, ErrSnapshotNotCommitted, err)
+}
+
+func TestSnapshotView(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotview")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ldb, err := ptndb.NewLDBDatabase(dir, 0, 0)
+	assert.Nil(t, err)
+	defer ldb.Close()
+	mem, _ := ptndb.NewMemDatabase()
+
+	header := modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{},
+		[]uint16{}, modules.PTNCOIN, modules.SnapshotInterval, 200)
+	key := append(constants.CONTRACT_STATE_PREFIX, []byte("key")...)
+	for _, db := range []ptndb.Database{ldb, mem} {
+		assert.Nil(t, db.Put(key, []byte("value")))
+		hash, err := SnapshotHash(db, header)
+		assert.Nil(t, err)
+		view, err := NewSnapshotView(db)
+		assert.Nil(t, err)
+
+		//创建视图之后的写入不影响视图
+		assert.Nil(t, db.Put(append(constants.CONTRACT_STATE_PREFIX, []byte("key2")...), []byte("value2")))
+		hash2, err := SnapshotHash(view, header)
+		assert.Nil(t, err)
+		assert.Equal(t, hash, hash2)
+		hash3, err := SnapshotHash(db, header)
+		assert.Nil(t, err)
+		assert.NotEqual(t, hash, hash3)
+		view.Release()
+	}
+}
@@ -21,6 +21,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -45,7 +46,16 @@ func (statedb *StateDb) SaveContract(contract *modules.Contract) error {
 	//	return errors.New("Contract[" + common.Bytes2Hex(contract.ContractId) + "]'s state existed!")
 	//}
 	log.Debugf("Save contract[%x]", contract.ContractId)
-	err := StoreToRlpBytes(statedb.db, key, contract)
+	//合约升级后模板改变，删除旧模板到合约的映射
+	old, err := statedb.GetContract(contract.ContractId)
+	if err == nil && !bytes.Equal(old.TemplateId, contract.TemplateId) {
+		oldKey := append(constants.CONTRACT_TPL_INSTANCE_MAP, old.TemplateId...)
+		oldKey = append(oldKey, contract.ContractId...)
+		if err := statedb.db.Delete(oldKey); err != nil {
+			return err
+		}
+	}
+	err = StoreToRlpBytes(statedb.db, key, contract)
 	if err != nil {
 		return err
 	}
@@ -263,6 +273,37 @@ func (statedb *StateDb) GetContractStatesByPrefix(id []byte,
 	return result, err
 }
 
+/**
+按Key的顺序获取合约在[startKey,endKey)范围内的属性，endKey为空表示没有上界，limit<=0表示不限制数量
+To get contract fields in range [startKey,endKey) by key order
+*/
+func (statedb *StateDb) GetContractStatesByRange(id []byte, startKey, endKey string,
+	limit int) ([]*modules.ContractStateKV, error) {
+	key := append(constants.CONTRACT_STATE_PREFIX, id...)
+	iter := statedb.db.NewIteratorWithPrefix(key)
+	defer iter.Release()
+	result := make([]*modules.ContractStateKV, 0)
+	//直接定位到startKey，不遍历之前的属性
+	for ok := iter.Seek(append(common.CopyBytes(key), startKey...)); ok; ok = iter.Next() {
+		realKey := string(iter.Key()[len(key):])
+		if realKey == "" {
+			continue
+		}
+		if endKey != "" && realKey >= endKey {
+			break
+		}
+		state, version, err := splitValueAndVersion(common.CopyBytes(iter.Value()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &modules.ContractStateKV{Key: realKey, Value: state, Version: version})
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, iter.Error()
+}
+
 /**
 获取合约某一个属性
 To get contract or contract template one field
@@ -176,7 +176,7 @@ func TestStateDb_DeleteContractState(t *testing.T) {
 	assert.Equal(t, 2, len(result))
 	// key := getContractStateKey(contractId, "AA")
 	// statedb.DeleteState(key)
-	ws3 := modules.ContractWriteSet{true, "AA", nil, nil}
+	ws3 := modules.ContractWriteSet{IsDelete: true, Key: "AA"}
 	ws = []modules.ContractWriteSet{}
 	ws = append(ws, ws3)
 	err = statedb.SaveContractStates(contractId, ws, version)
@@ -185,6 +185,48 @@ func TestStateDb_DeleteContractState(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(result))
 }
+func TestStateDb_GetContractStatesByRange(t *testing.T) {
+	mem, _ := ptndb.NewMemDatabase()
+	testGetContractStatesByRange(t, mem)
+	ldb, remove := newTestLDB()
+	defer remove()
+	testGetContractStatesByRange(t, ldb)
+}
+func testGetContractStatesByRange(t *testing.T, db ptndb.Database) {
+	statedb := NewStateDb(db)
+	contractId := []byte("RangeContract")
+	version := &modules.StateVersion{Height: &modules.ChainIndex{Index: 123}, TxIndex: 1}
+	ws := []modules.ContractWriteSet{}
+	for _, key := range []string{"d", "b", "a", "c"} {
+		ws = append(ws, *modules.NewWriteSet(key, []byte(key+"1")))
+	}
+	assert.Nil(t, statedb.SaveContractStates(contractId, ws, version))
+	assert.Nil(t, statedb.SaveContractState([]byte("OtherContract"), modules.NewWriteSet("b1", []byte("x")), version))
+
+	result, err := statedb.GetContractStatesByRange(contractId, "b", "d", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, "b", result[0].Key)
+	assert.Equal(t, "c", result[1].Key)
+	assert.Equal(t, []byte("c1"), result[1].Value)
+	assert.True(t, version.Equal(result[1].Version))
+	//endKey为空表示没有上界
+	result, err = statedb.GetContractStatesByRange(contractId, "b", "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(result))
+	result, err = statedb.GetContractStatesByRange(contractId, "", "", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, "a", result[0].Key)
+	//startKey不存在时从下一个属性开始
+	result, err = statedb.GetContractStatesByRange(contractId, "bb", "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, "c", result[0].Key)
+	result, err = statedb.GetContractStatesByRange(contractId, "e", "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result))
+}
 func newTestLDB() (*ptndb.LDBDatabase, func()) {
 	dirname, err := ioutil.TempDir(os.TempDir(), "ptndb_test_")
 	if err != nil {
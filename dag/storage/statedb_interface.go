@@ -35,6 +35,7 @@ type IStateDb interface {
 	SaveContractStates(id []byte, wset []modules.ContractWriteSet, version *modules.StateVersion) error
 	GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error)
 	GetContractStatesByPrefix(id []byte, prefix string) (map[string]*modules.ContractStateValue, error)
+	GetContractStatesByRange(id []byte, startKey, endKey string, limit int) ([]*modules.ContractStateKV, error)
 	GetContractStatesById(id []byte) (map[string]*modules.ContractStateValue, error)
 
 	UpdateStateByContractInvoke(invoke *modules.ContractInvokeRequestPayload,
@@ -83,6 +84,11 @@ type IStateDb interface {
 	LookupMediatorInfo() []*modules.MediatorInfo
 	IsMediator(address common.Address) bool
 	RetrieveMediatorInfo(address common.Address) (*modules.MediatorInfo, error)
+	StoreMediatorLiveness(add common.Address, ml *modules.MediatorLiveness) error
+	RetrieveMediatorLiveness(add common.Address) (*modules.MediatorLiveness, error)
+	StoreJurorReputation(add common.Address, jr *modules.JurorReputation) error
+	RetrieveJurorReputation(add common.Address) (*modules.JurorReputation, error)
+	SettleJurorReputations(unitTime uint64) error
 
 	GetCandidateMediatorList() (map[string]bool, error)
 	GetJuryCandidateList() (map[string]bool, error)
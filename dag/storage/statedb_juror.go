@@ -0,0 +1,72 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package storage
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/dag/errors"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+func GetJurorReputationKey(address common.Address) []byte {
+	key := append(common.CopyBytes(constants.JUROR_REPUTATION_PREFIX), address.Bytes()...)
+	return key
+}
+
+func (statedb *StateDb) StoreJurorReputation(add common.Address, jr *modules.JurorReputation) error {
+	return StoreToRlpBytes(statedb.db, GetJurorReputationKey(add), jr)
+}
+
+// 没有记录时返回一个空的记录
+func (statedb *StateDb) RetrieveJurorReputation(add common.Address) (*modules.JurorReputation, error) {
+	jr := modules.NewJurorReputation()
+	err := RetrieveFromRlpBytes(statedb.db, GetJurorReputationKey(add), jr)
+	if err != nil && !errors.IsNotFoundError(err) {
+		return nil, err
+	}
+
+	return jr, nil
+}
+
+// 维护周期结束时，固定所有陪审员当前的执行记录，下一个维护周期内分配合约执行费和选举都使用固定后的记录，
+// unitTime 为维护单元的时间
+func (statedb *StateDb) SettleJurorReputations(unitTime uint64) error {
+	for key, value := range getprefix(statedb.db, constants.JUROR_REPUTATION_PREFIX) {
+		jr := modules.NewJurorReputation()
+		if err := rlp.DecodeBytes(value, jr); err != nil {
+			log.Errorf("decode juror reputation(%x) err: %v", key, err.Error())
+			continue
+		}
+		//记录没有变化时，无论按哪次固定的记录计算结果都相同
+		if jr.Settled == jr.Current && jr.Previous == jr.Settled {
+			continue
+		}
+
+		jr.Settle(unitTime)
+		if err := StoreToRlpBytes(statedb.db, []byte(key), jr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer  <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+package storage
+
+import (
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettleJurorReputations(t *testing.T) {
+	db := MockStateMemDb()
+	a1, _ := common.StringToAddress("P1NzevLMVCFJKWr4KAcHxyyh9xXaVU8yv3N")
+	a2, _ := common.StringToAddress("P1MdMxNVaKZYdyxxwLZnTYQcJpxAYa6nEvz")
+
+	jr1 := modules.NewJurorReputation()
+	jr1.AddExecuted()
+	jr1.AddMissed()
+	assert.Nil(t, db.StoreJurorReputation(a1, jr1))
+	jr2 := modules.NewJurorReputation()
+	jr2.AddMissed()
+	assert.Nil(t, db.StoreJurorReputation(a2, jr2))
+
+	dbJr, err := db.RetrieveJurorReputation(a1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(100), dbJr.Score())
+
+	assert.Nil(t, db.SettleJurorReputations(1000))
+	dbJr, err = db.RetrieveJurorReputation(a1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(50), dbJr.Score())
+	dbJr, err = db.RetrieveJurorReputation(a2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), dbJr.Score())
+	//维护单元之前的单元上生效的仍是原来的记录
+	assert.Equal(t, uint32(100), dbJr.ScoreAt(999))
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/palletone/go-palletone/contracts/syscontract"
 	"github.com/palletone/go-palletone/core"
 	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/dag/modules"
 )
 
@@ -70,6 +71,26 @@ func (statedb *StateDb) RetrieveMediatorInfo(address common.Address) (*modules.M
 	return mi, nil
 }
 
+func GetMediatorLivenessKey(address common.Address) []byte {
+	key := append(common.CopyBytes(constants.MEDIATOR_LIVENESS_PREFIX), address.Bytes()...)
+	return key
+}
+
+func (statedb *StateDb) StoreMediatorLiveness(add common.Address, ml *modules.MediatorLiveness) error {
+	return StoreToRlpBytes(statedb.db, GetMediatorLivenessKey(add), ml)
+}
+
+// 没有记录时返回一个空的记录
+func (statedb *StateDb) RetrieveMediatorLiveness(add common.Address) (*modules.MediatorLiveness, error) {
+	ml := modules.NewMediatorLiveness()
+	err := RetrieveFromRlpBytes(statedb.db, GetMediatorLivenessKey(add), ml)
+	if err != nil && !errors.IsNotFoundError(err) {
+		return nil, err
+	}
+
+	return ml, nil
+}
+
 func (statedb *StateDb) RetrieveMediator(address common.Address) (*core.Mediator, error) {
 	mi, err := statedb.RetrieveMediatorInfo(address)
 	if err != nil {
@@ -64,6 +64,18 @@ type ContractDeployRsp struct {
 	ReqId      string `json:"reqId"`
 	ContractId string `json:"ContractId"`
 }
+//多签升级策略，Admins为管理员地址
+type ContractUpgradePolicyArgs struct {
+	Admins    []string `json:"admins"`
+	Threshold uint32   `json:"threshold"`
+}
+
+//管理员对升级请求的签名，都是16进制编码
+type ContractUpgradeSignArgs struct {
+	PubKey    string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
 type ContractInvokeRsp struct {
 	ReqId      string `json:"reqId"`
 	ContractId string `json:"ContractId"`
@@ -45,6 +45,7 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	EstimateFee(targetUnits uint32, asset string) (*txspool.FeeEstimate, error)
 	ChainDb() ptndb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
@@ -58,7 +59,7 @@ type Backend interface {
 	//GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	//GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
-	//SubscribeChainEvent(ch chan<- coredata.ChainEvent) event.Subscription
+	SubscribeChainEvent(ch chan<- modules.ChainEvent) event.Subscription
 	//SubscribeChainHeadEvent(ch chan<- coredata.ChainHeadEvent) event.Subscription
 	//SubscribeChainSideEvent(ch chan<- coredata.ChainSideEvent) event.Subscription
 	GetUnstableUnits() []*ptnjson.UnitSummaryJson
@@ -149,7 +150,8 @@ type Backend interface {
 	ContractInstallReqTx(from, to common.Address, daoAmount, daoFee uint64, tplName, path, version string,
 		description, abi, language string, addrs []common.Address) (reqId common.Hash, tplId []byte, err error)
 	ContractDeployReqTx(from, to common.Address, daoAmount, daoFee uint64, templateId []byte, args [][]byte,
-		extData []byte, timeout time.Duration) (reqId common.Hash, contractAddr common.Address, err error)
+		extData []byte, timeout time.Duration, policy *modules.ContractUpgradePolicy) (reqId common.Hash,
+		contractAddr common.Address, err error)
 	ContractInvokeReqTx(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
 		contractAddress common.Address, args [][]byte, timeout uint32) (reqId common.Hash, err error)
 	SendContractInvokeReqTx(requestTx *modules.Transaction) (reqId common.Hash, err error)
@@ -157,6 +159,10 @@ type Backend interface {
 		contractAddress common.Address, args [][]byte, timeout uint32) (reqId common.Hash, err error)
 	ContractStopReqTx(from, to common.Address, daoAmount, daoFee uint64, contractId common.Address,
 		deleteImage bool) (reqId common.Hash, err error)
+	ContractUpgradeReqTx(from, to common.Address, daoAmount, daoFee uint64, contractId common.Address,
+		templateId []byte, args [][]byte, timeout uint32, signs []modules.SignatureSet) (reqId common.Hash, err error)
+	ContractUpgradeHash(contractId common.Address, templateId []byte, args [][]byte, timeout uint32) (common.Hash,
+		error)
 	ContractInstallReqTxFee(from, to common.Address, daoAmount, daoFee uint64, tplName, path, version string,
 		description, abi, language string, addrs []common.Address) (fee float64, size float64, tm uint32, err error)
 	ContractDeployReqTxFee(from, to common.Address, daoAmount, daoFee uint64, templateId []byte,
@@ -166,10 +172,14 @@ type Backend interface {
 	ContractStopReqTxFee(from, to common.Address, daoAmount, daoFee uint64, contractId common.Address,
 		deleteImage bool) (fee float64, size float64, tm uint32, err error)
 	ContractQuery(id []byte, args [][]byte, timeout time.Duration) (rspPayload []byte, err error)
+	ContractSimulateInvoke(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
+		contractAddress common.Address, args [][]byte, timeout uint32, gasLimit uint64,
+		stable, trace bool) (*modules.ContractSimulateResult, error)
 
 	ElectionVrf(id uint32) ([]byte, error)
 	UpdateJuryAccount(addr common.Address, pwd string) bool
 	GetJuryAccount() []common.Address
+	GetJurorOutcome(juror common.Address) *modules.JurorOutcome
 
 	TxPool() txspool.ITxPool
 	Dag() dag.IDag
@@ -286,6 +296,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicMediatorAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "jury",
+			Version:   "1.0",
+			Service:   NewPublicJuryAPI(apiBackend),
+			Public:    true,
 		},
 	}
 }
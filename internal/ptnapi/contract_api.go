@@ -206,8 +206,9 @@ func (s *PrivateContractAPI) Ccinstalltx(ctx context.Context, from, to string, a
 
 	return rsp, err
 }
+//policy 可选的多签升级策略，不设置时只有创建者可以升级合约
 func (s *PrivateContractAPI) Ccdeploytx(ctx context.Context, from, to string, amount, fee decimal.Decimal,
-	tplId string, param []string, extData string) (*ContractDeployRsp, error) {
+	tplId string, param []string, extData string, policy *ContractUpgradePolicyArgs) (*ContractDeployRsp, error) {
 	fromAddr, _ := common.StringToAddress(from)
 	toAddr, _ := common.StringToAddress(to)
 	daoAmount := ptnjson.Ptn2Dao(amount)
@@ -227,7 +228,19 @@ func (s *PrivateContractAPI) Ccdeploytx(ctx context.Context, from, to string, am
 	}
 	fullArgs := [][]byte{defaultMsg0}
 	fullArgs = append(fullArgs, args...)
-	reqId, _, err := s.b.ContractDeployReqTx(fromAddr, toAddr, daoAmount, daoFee, templateId, fullArgs, extendData, 0)
+	var upgradePolicy *modules.ContractUpgradePolicy
+	if policy != nil {
+		upgradePolicy = &modules.ContractUpgradePolicy{Threshold: policy.Threshold}
+		for _, admin := range policy.Admins {
+			addr, err := common.StringToAddress(admin)
+			if err != nil {
+				return nil, fmt.Errorf("invalid upgrade policy admin %s", admin)
+			}
+			upgradePolicy.Admins = append(upgradePolicy.Admins, addr)
+		}
+	}
+	reqId, _, err := s.b.ContractDeployReqTx(fromAddr, toAddr, daoAmount, daoFee, templateId, fullArgs, extendData,
+		0, upgradePolicy)
 	contractAddr := crypto.RequestIdToContractAddress(reqId)
 	sReqId := hex.EncodeToString(reqId[:])
 	log.Debug("-----Ccdeploytx:", "reqId", sReqId, "depId", contractAddr.String())
@@ -360,6 +373,75 @@ func (s *PrivateContractAPI) Ccstoptx(ctx context.Context, from, to string, amou
 	return hex.EncodeToString(reqId[:]), err
 }
 
+//Ccupgradetx 把合约升级到新的模板，param为迁移函数的参数，合约声明了多签升级策略时需要signs
+func (s *PrivateContractAPI) Ccupgradetx(ctx context.Context, from, to string, amount, fee decimal.Decimal,
+	contractId, tplId string, param []string, signs *[]ContractUpgradeSignArgs) (string, error) {
+	fromAddr, _ := common.StringToAddress(from)
+	toAddr, _ := common.StringToAddress(to)
+	daoAmount := ptnjson.Ptn2Dao(amount)
+	daoFee := ptnjson.Ptn2Dao(fee)
+	contractAddr, _ := common.StringToAddress(contractId)
+	templateId, _ := hex.DecodeString(tplId)
+
+	log.Info("Ccupgradetx info:")
+	log.Infof("   fromAddr[%s], toAddr[%s]", fromAddr.String(), toAddr.String())
+	log.Infof("   daoAmount[%d], daoFee[%d]", daoAmount, daoFee)
+	log.Infof("   contractId[%s], templateId[%s]", contractAddr.String(), tplId)
+
+	args := make([][]byte, len(param))
+	for i, arg := range param {
+		args[i] = []byte(arg)
+	}
+	var sigs []modules.SignatureSet
+	if signs != nil {
+		for _, sign := range *signs {
+			pubKey, err := hex.DecodeString(sign.PubKey)
+			if err != nil {
+				return "", fmt.Errorf("invalid public key %s", sign.PubKey)
+			}
+			signature, err := hex.DecodeString(sign.Signature)
+			if err != nil {
+				return "", fmt.Errorf("invalid signature %s", sign.Signature)
+			}
+			sigs = append(sigs, modules.SignatureSet{PubKey: pubKey, Signature: signature})
+		}
+	}
+	reqId, err := s.b.ContractUpgradeReqTx(fromAddr, toAddr, daoAmount, daoFee, contractAddr, templateId, args, 0,
+		sigs)
+	log.Infof("   reqId[%s]", hex.EncodeToString(reqId[:]))
+	return hex.EncodeToString(reqId[:]), err
+}
+
+//Ccupgradesign 多签升级策略的管理员对升级请求签名，参数与Ccupgradetx相同
+func (s *PrivateContractAPI) Ccupgradesign(ctx context.Context, addr string, contractId, tplId string,
+	param []string, password string) (*ContractUpgradeSignArgs, error) {
+	adminAddr, err := common.StringToAddress(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s", addr)
+	}
+	contractAddr, _ := common.StringToAddress(contractId)
+	templateId, _ := hex.DecodeString(tplId)
+	args := make([][]byte, len(param))
+	for i, arg := range param {
+		args[i] = []byte(arg)
+	}
+	hash, err := s.b.ContractUpgradeHash(contractAddr, templateId, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	ks := s.b.GetKeyStore()
+	signature, err := ks.SignMessageWithPassphrase(accounts.Account{Address: adminAddr}, password, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := ks.GetPublicKey(adminAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &ContractUpgradeSignArgs{PubKey: hex.EncodeToString(pubKey),
+		Signature: hex.EncodeToString(signature)}, nil
+}
+
 func (s *PrivateContractAPI) Ccinstalltxfee(ctx context.Context, from, to string, amount, fee decimal.Decimal,
 	tplName, path, version, ccdescription, ccabi, cclanguage string, addr []string) (*ContractFeeRsp, error) {
 	fromAddr, _ := common.StringToAddress(from)
@@ -0,0 +1,51 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package ptnapi
+
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+type PublicJuryAPI struct {
+	Backend
+}
+
+func NewPublicJuryAPI(b Backend) *PublicJuryAPI {
+	return &PublicJuryAPI{b}
+}
+
+//GetStats 返回陪审员的链上信誉，以及本节点观察到的执行结果
+func (a *PublicJuryAPI) GetStats(addStr string) (*modules.JurorStatsJson, error) {
+	juror, err := common.StringToAddress(addStr)
+	if err != nil {
+		return nil, err
+	}
+
+	jr := a.Dag().GetJurorReputation(juror)
+	return &modules.JurorStatsJson{
+		Address:   addStr,
+		Candidate: a.Dag().IsActiveJury(juror),
+		Score:     jr.Score(),
+		Current:   jr.Current,
+		Settled:   jr.Settled,
+		Local:     a.GetJurorOutcome(juror),
+	}, nil
+}
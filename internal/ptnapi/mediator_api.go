@@ -192,6 +192,35 @@ func (a *PublicMediatorAPI) GetInfo(addStr string) (*modules.MediatorInfo, error
 	return a.Dag().GetMediatorInfo(mediator), nil
 }
 
+func (a *PublicMediatorAPI) GetLiveness(addStr string) (*modules.MediatorLivenessJson, error) {
+	mediator, err := core.StrToMedAdd(addStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ml := a.Dag().GetMediatorLiveness(mediator)
+	if ml == nil {
+		return nil, fmt.Errorf("cannot get liveness of mediator: %v", addStr)
+	}
+
+	cp := a.Dag().GetChainParameters()
+	scheduled, produced := ml.Totals()
+	requested, contributed := ml.SigShareTotals()
+	return &modules.MediatorLivenessJson{
+		Address:             addStr,
+		Score:               ml.Score(),
+		ProduceScore:        ml.ProduceScore(),
+		SigShareScore:       ml.SigShareScore(),
+		Scheduled:           scheduled,
+		Produced:            produced,
+		SigShareRequested:   requested,
+		SigShareContributed: contributed,
+		Inactive:            ml.IsInactive(cp.MediatorLivenessThreshold, cp.MediatorLivenessRounds),
+		Current:             ml.Current,
+		History:             ml.History,
+	}, nil
+}
+
 const DefaultResult = "Transaction executed locally, but may not be confirmed by the network yet!"
 
 type PrivateMediatorAPI struct {
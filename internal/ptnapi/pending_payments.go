@@ -0,0 +1,228 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package ptnapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+	"github.com/shopspring/decimal"
+)
+
+// 待确认支付的通知类型
+const (
+	PaymentPending  = "pending"  // 交易进入交易池
+	PaymentReplaced = "replaced" // 交易被手续费更高的交易替换(RBF)
+	PaymentDropped  = "dropped"  // 交易被交易池丢弃
+	PaymentIncluded = "included" // 交易被打包进单元
+)
+
+const (
+	// 订阅最多关注的地址数
+	maxPaymentWatchAddrs = 1000
+	// 检查已关注的交易是否被交易池丢弃的间隔
+	paymentDropCheckInterval = 10 * time.Second
+	txPreEventChanSize       = 4096
+	chainEventChanSize       = 10
+)
+
+// PendingPayment 交易中与关注地址相关的一笔收入或支出
+type PendingPayment struct {
+	Address   string          `json:"address"`
+	Direction string          `json:"direction"` // in:收入 out:支出
+	Asset     string          `json:"asset"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+// PendingPaymentEvent 推送给pendingPayments订阅者的通知
+type PendingPaymentEvent struct {
+	Type       string            `json:"type"`
+	TxHash     string            `json:"tx_hash"`
+	ReplacedBy string            `json:"replaced_by,omitempty"`
+	UnitHash   string            `json:"unit_hash,omitempty"`
+	UnitIndex  uint64            `json:"unit_index,omitempty"`
+	Payments   []*PendingPayment `json:"payments,omitempty"`
+}
+
+type paymentFilter struct {
+	addrs map[common.Address]bool
+	asset *modules.Asset // nil表示所有资产
+}
+
+func newPaymentFilter(addresses []string, assetId string) (*paymentFilter, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no address to watch")
+	}
+	if len(addresses) > maxPaymentWatchAddrs {
+		return nil, fmt.Errorf("too many addresses, the limit is %d", maxPaymentWatchAddrs)
+	}
+	filter := &paymentFilter{addrs: make(map[common.Address]bool)}
+	for _, str := range addresses {
+		addr, err := common.StringToAddress(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %s", str, err.Error())
+		}
+		filter.addrs[addr] = true
+	}
+	if assetId != "" {
+		asset, err := modules.StringToAsset(assetId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset %s: %s", assetId, err.Error())
+		}
+		filter.asset = asset
+	}
+	return filter, nil
+}
+
+func (f *paymentFilter) matchAsset(asset *modules.Asset) bool {
+	return f.asset == nil || (asset != nil && asset.AssetId == f.asset.AssetId)
+}
+
+// match 返回交易中支付给关注地址或者花费关注地址的记录
+func (f *paymentFilter) match(tx *modules.Transaction, queryUtxo modules.QueryUtxoFunc,
+	getAddr modules.GetAddressFromScriptFunc) []*PendingPayment {
+	payments := make([]*PendingPayment, 0)
+	for _, msg := range tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		pay, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, in := range pay.Inputs {
+			if in.PreviousOutPoint == nil {
+				continue
+			}
+			utxo, err := queryUtxo(in.PreviousOutPoint)
+			if err != nil || !f.matchAsset(utxo.Asset) {
+				continue
+			}
+			if addr, err := getAddr(utxo.PkScript); err == nil && f.addrs[addr] {
+				payments = append(payments, &PendingPayment{Address: addr.String(), Direction: "out",
+					Asset: utxo.Asset.String(), Amount: utxo.Asset.DisplayAmount(utxo.Amount)})
+			}
+		}
+		for _, out := range pay.Outputs {
+			if !f.matchAsset(out.Asset) {
+				continue
+			}
+			if addr, err := getAddr(out.PkScript); err == nil && f.addrs[addr] {
+				payments = append(payments, &PendingPayment{Address: addr.String(), Direction: "in",
+					Asset: out.Asset.String(), Amount: out.Asset.DisplayAmount(out.Value)})
+			}
+		}
+	}
+	return payments
+}
+
+// PendingPayments 订阅交易池中支付给关注地址或者花费关注地址的交易，
+// 这些交易被替换、丢弃或者打包进单元时也会推送通知
+func (s *PublicPalletOneAPI) PendingPayments(ctx context.Context, addresses []string,
+	assetId string) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	filter, err := newPaymentFilter(addresses, assetId)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		txs := make(chan modules.TxPreEvent, txPreEventChanSize)
+		txSub := s.b.SubscribeTxPreEvent(txs)
+		defer txSub.Unsubscribe()
+		units := make(chan modules.ChainEvent, chainEventChanSize)
+		unitSub := s.b.SubscribeChainEvent(units)
+		defer unitSub.Unsubscribe()
+		dropCheck := time.NewTicker(paymentDropCheckInterval)
+		defer dropCheck.Stop()
+
+		pool := s.b.TxPool()
+		getAddr := tokenengine.Instance.GetAddressFromScript
+		// 已经推送了pending通知，等待最终结果的交易
+		watching := make(map[common.Hash]bool)
+		notify := func(ev *PendingPaymentEvent) {
+			if err := notifier.Notify(rpcSub.ID, ev); err != nil {
+				log.Debug("Failed to notify pending payment", "tx", ev.TxHash, "err", err)
+			}
+		}
+		for {
+			select {
+			case ev := <-txs:
+				hash := ev.Tx.Hash()
+				for _, replaced := range ev.Replaced {
+					if watching[replaced] {
+						delete(watching, replaced)
+						notify(&PendingPaymentEvent{Type: PaymentReplaced, TxHash: replaced.String(),
+							ReplacedBy: hash.String()})
+					}
+				}
+				if payments := filter.match(ev.Tx, pool.GetUtxoEntry, getAddr); len(payments) > 0 {
+					watching[hash] = true
+					notify(&PendingPaymentEvent{Type: PaymentPending, TxHash: hash.String(), Payments: payments})
+				}
+			case ev := <-units:
+				if ev.Unit == nil {
+					continue
+				}
+				for _, tx := range ev.Unit.Transactions() {
+					hash := tx.Hash()
+					if watching[hash] {
+						delete(watching, hash)
+						notify(&PendingPaymentEvent{Type: PaymentIncluded, TxHash: hash.String(),
+							UnitHash: ev.Hash.String(), UnitIndex: ev.Unit.NumberU64()})
+					}
+				}
+			case <-dropCheck.C:
+				for hash := range watching {
+					if ptx, _ := pool.Get(hash); ptx != nil && !ptx.Discarded {
+						continue
+					}
+					delete(watching, hash)
+					// 交易已经不在交易池中，可能是错过了单元通知
+					if dag := s.b.Dag(); dag != nil {
+						if entry, err := dag.GetTxLookupEntry(hash); err == nil {
+							notify(&PendingPaymentEvent{Type: PaymentIncluded, TxHash: hash.String(),
+								UnitHash: entry.UnitHash.String(), UnitIndex: entry.UnitIndex})
+							continue
+						}
+					}
+					notify(&PendingPaymentEvent{Type: PaymentDropped, TxHash: hash.String()})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -0,0 +1,61 @@
+package ptnapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine"
+)
+
+func TestPaymentFilter(t *testing.T) {
+	watched, _ := common.StringToAddress("P1H4uUec5di1wCm8pKGLPxhXM6s7xVutKs9")
+	other, _ := common.StringToAddress("P1sn5uKz2SBvhcRKtQGEqrpGB7mkf73btd")
+	if _, err := newPaymentFilter(nil, ""); err == nil {
+		t.Error("empty address list should be rejected")
+	}
+	if _, err := newPaymentFilter([]string{"abc"}, ""); err == nil {
+		t.Error("invalid address should be rejected")
+	}
+	filter, err := newPaymentFilter([]string{watched.String()}, "PTN")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spent := modules.NewOutPoint(common.BytesToHash([]byte("utxo")), 0, 0)
+	utxos := map[modules.OutPoint]*modules.Utxo{
+		*spent: {Amount: 300000000, Asset: modules.NewPTNAsset(),
+			PkScript: tokenengine.Instance.GenerateLockScript(watched)},
+	}
+	queryUtxo := func(outpoint *modules.OutPoint) (*modules.Utxo, error) {
+		if utxo, ok := utxos[*outpoint]; ok {
+			return utxo, nil
+		}
+		return nil, errors.New("not found")
+	}
+	pay := modules.NewPaymentPayload([]*modules.Input{modules.NewTxIn(spent, nil)}, []*modules.Output{
+		modules.NewTxOut(100000000, tokenengine.Instance.GenerateLockScript(other), modules.NewPTNAsset()),
+		modules.NewTxOut(190000000, tokenengine.Instance.GenerateLockScript(watched), modules.NewPTNAsset()),
+	})
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, pay)})
+	payments := filter.match(tx, queryUtxo, tokenengine.Instance.GetAddressFromScript)
+	if len(payments) != 2 {
+		t.Fatalf("expect 2 payments, got %d", len(payments))
+	}
+	if payments[0].Direction != "out" || payments[0].Amount.String() != "3" {
+		t.Errorf("unexpected spending: %+v", payments[0])
+	}
+	if payments[1].Direction != "in" || payments[1].Amount.String() != "1.9" {
+		t.Errorf("unexpected receiving: %+v", payments[1])
+	}
+
+	//资产不匹配
+	filter, err = newPaymentFilter([]string{watched.String()}, "ABC+10KV5D6MNWANJ2G7AZ94")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.match(tx, queryUtxo, tokenengine.Instance.GetAddressFromScript)) != 0 {
+		t.Error("payments of other assets should be ignored")
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/palletone/go-palletone/dag/modules"
 	"github.com/palletone/go-palletone/ptnjson"
 	"github.com/palletone/go-palletone/ptnjson/statistics"
+	"github.com/palletone/go-palletone/txspool"
 	"github.com/shopspring/decimal"
 	"github.com/palletone/go-palletone/common/crypto"
 )
@@ -59,6 +60,13 @@ func (s *PublicPalletOneAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// EstimateFee returns the fee rates (dao per KByte) which let a transaction paying fee in
+// the given asset be packed within targetUnits units, at several confidence levels.
+func (s *PublicPalletOneAPI) EstimateFee(ctx context.Context, targetUnits uint32,
+	assetId string) (*txspool.FeeEstimate, error) {
+	return s.b.EstimateFee(targetUnits, assetId)
+}
+
 // ProtocolVersion returns the current PalletOne protocol version this node supports
 func (s *PublicPalletOneAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -382,6 +390,59 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	return 0, nil
 }
 
+// SimulateInvokeArgs represents the arguments of a simulated contract invoke.
+type SimulateInvokeArgs struct {
+	From         string          `json:"from"`
+	To           string          `json:"to"` //为空时与from相同
+	Amount       decimal.Decimal `json:"amount"`
+	Fee          decimal.Decimal `json:"fee"`
+	ContractAddr string          `json:"contract_addr"`
+	Params       []string        `json:"params"`
+	CertID       string          `json:"cert_id"`
+	Timeout      uint32          `json:"timeout"`
+	GasLimit     uint64          `json:"gas_limit"` //0表示使用默认的Gas上限
+	Stable       bool            `json:"stable"`    //只读取稳定单元的状态
+	Trace        bool            `json:"trace"`     //返回合约的每一次shim调用
+}
+
+// SimulateInvoke executes the contract invoke on the local node without sending
+// the request, and returns the read set, write set, token payouts, events, error
+// message and the estimated fee. No state is changed.
+func (s *PublicBlockChainAPI) SimulateInvoke(ctx context.Context,
+	args SimulateInvokeArgs) (*ptnjson.ContractSimulateJson, error) {
+	fromAddr, err := common.StringToAddress(args.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %s: %s", args.From, err.Error())
+	}
+	toAddr := fromAddr
+	if args.To != "" {
+		if toAddr, err = common.StringToAddress(args.To); err != nil {
+			return nil, fmt.Errorf("invalid to address %s: %s", args.To, err.Error())
+		}
+	}
+	contractAddr, err := common.StringToAddress(args.ContractAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contract address %s: %s", args.ContractAddr, err.Error())
+	}
+	intCertID := new(big.Int)
+	if len(args.CertID) > 0 {
+		if _, ok := intCertID.SetString(args.CertID, 10); !ok {
+			return nil, fmt.Errorf("certid is invalid")
+		}
+	}
+	params := make([][]byte, len(args.Params))
+	for i, arg := range args.Params {
+		params[i] = []byte(arg)
+	}
+	result, err := s.b.ContractSimulateInvoke(fromAddr, toAddr, ptnjson.Ptn2Dao(args.Amount),
+		ptnjson.Ptn2Dao(args.Fee), intCertID, contractAddr, params, args.Timeout, args.GasLimit, args.Stable,
+		args.Trace)
+	if err != nil {
+		return nil, err
+	}
+	return ptnjson.ConvertContractSimulate2Json(contractAddr.String(), result), nil
+}
+
 // Start forking command.
 func (s *PublicBlockChainAPI) Forking(ctx context.Context, rate uint64) uint64 {
 	return forking(ctx, s.b)
@@ -1710,3 +1710,150 @@ func (s *PrivateWalletAPI) generateTx(payment *modules.PaymentPayload, address c
 	}
 	return tx, nil
 }
+
+func parseSigHashType(hashtype string) (uint32, error) {
+	switch strings.ToUpper(hashtype) {
+	case ALL, "":
+		return tokenengine.SigHashAll, nil
+	case NONE:
+		return tokenengine.SigHashNone, nil
+	case SINGLE:
+		return tokenengine.SigHashSingle, nil
+	case "ALL|ANYONECANPAY":
+		return tokenengine.SigHashAll | tokenengine.SigHashAnyOneCanPay, nil
+	case "NONE|ANYONECANPAY":
+		return tokenengine.SigHashNone | tokenengine.SigHashAnyOneCanPay, nil
+	case "SINGLE|ANYONECANPAY":
+		return tokenengine.SigHashSingle | tokenengine.SigHashAnyOneCanPay, nil
+	}
+	return 0, errors.New("Hashtype is error,error type:" + hashtype)
+}
+
+//根据未签名的交易和多签的赎回脚本，创建一个部分签名交易，交给各个签名者离线签名
+func (s *PublicWalletAPI) CreatePartialSignedTx(ctx context.Context, rawTx string, redeemScripts []string,
+	hashtype string) (string, error) {
+	hashType, err := parseSigHashType(hashtype)
+	if err != nil {
+		return "", err
+	}
+	serializedTx, err := decodeHexStr(rawTx)
+	if err != nil {
+		return "", errors.New("rawTx is invalid")
+	}
+	tx := new(modules.Transaction)
+	if err := rlp.DecodeBytes(serializedTx, tx); err != nil {
+		return "", errors.New("rawTx decode is invalid")
+	}
+	redeems := make(map[common.Address][]byte)
+	for _, str := range redeemScripts {
+		rs, err := decodeHexStr(trimx(str))
+		if err != nil {
+			return "", errors.New("redeemScript is invalid")
+		}
+		lockScript := tokenengine.Instance.GenerateP2SHLockScript(crypto.Hash160(rs))
+		addr, err := tokenengine.Instance.GetAddressFromScript(lockScript)
+		if err != nil {
+			return "", err
+		}
+		redeems[addr] = rs
+	}
+	utxoLockScripts := make(map[modules.OutPoint][]byte)
+	for _, msg := range tx.TxMessages() {
+		payload, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, txin := range payload.Inputs {
+			if txin.PreviousOutPoint == nil {
+				continue
+			}
+			utxo, err := s.b.GetUtxoEntry(txin.PreviousOutPoint)
+			if err != nil {
+				return "", fmt.Errorf("get utxo[%s] error:%s", txin.PreviousOutPoint.String(), err.Error())
+			}
+			lockScript, err := decodeHexStr(trimx(utxo.PkScriptHex))
+			if err != nil {
+				return "", err
+			}
+			utxoLockScripts[*txin.PreviousOutPoint] = lockScript
+		}
+	}
+	pst, err := tokenengine.NewPartialSignedTx(tx, hashType, utxoLockScripts, redeems)
+	if err != nil {
+		return "", err
+	}
+	return pst.EncodeToString()
+}
+
+//合并多个签名者分别签名后的部分签名交易
+func (s *PublicWalletAPI) CombinePartialSignedTx(ctx context.Context, psts []string) (string, error) {
+	decoded := make([]*tokenengine.PartialSignedTx, 0, len(psts))
+	for _, str := range psts {
+		pst, err := tokenengine.DecodePartialSignedTx(str)
+		if err != nil {
+			return "", err
+		}
+		decoded = append(decoded, pst)
+	}
+	combined, err := tokenengine.CombinePartialSignedTx(decoded...)
+	if err != nil {
+		return "", err
+	}
+	return combined.EncodeToString()
+}
+
+//签名收集完成后，生成可以通过sendRawTransaction广播的已签名交易
+func (s *PublicWalletAPI) FinalizePartialSignedTx(ctx context.Context, pstStr string) (string, error) {
+	pst, err := tokenengine.DecodePartialSignedTx(pstStr)
+	if err != nil {
+		return "", err
+	}
+	tx, err := pst.Finalize()
+	if err != nil {
+		return "", err
+	}
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+//查看部分签名交易的内容和签名进度
+func (s *PublicWalletAPI) DecodePartialSignedTx(ctx context.Context, pstStr string) (*ptnjson.PartialSignedTxJson, error) {
+	pst, err := tokenengine.DecodePartialSignedTx(pstStr)
+	if err != nil {
+		return nil, err
+	}
+	return ptnjson.ConvertPartialSignedTx2Json(pst), nil
+}
+
+//使用本节点钱包中的账户对部分签名交易签名
+func (s *PrivateWalletAPI) SignPartialSignedTx(ctx context.Context, pstStr string, addr common.Address,
+	password string, duration *uint64) (string, error) {
+	pst, err := tokenengine.DecodePartialSignedTx(pstStr)
+	if err != nil {
+		return "", err
+	}
+	if err := s.unlockKS(addr, password, duration); err != nil {
+		return "", err
+	}
+	ks := s.b.GetKeyStore()
+	getPubKeyFn := func(a common.Address) ([]byte, error) {
+		if a != addr {
+			return nil, errors.New("not the sign address")
+		}
+		return ks.GetPublicKey(a)
+	}
+	getSignFn := func(a common.Address, msg []byte) ([]byte, error) {
+		return ks.SignMessage(a, msg)
+	}
+	signed, err := pst.Sign(getPubKeyFn, getSignFn)
+	if err != nil {
+		return "", err
+	}
+	if signed == 0 {
+		return "", fmt.Errorf("address[%s] has nothing to sign", addr.String())
+	}
+	return pst.EncodeToString()
+}
@@ -65,6 +65,18 @@ web3._extend({
         	params: 5, //from, to, daoAmount, daoFee, contractId
 			inputFormatter: [null, null, null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'ccupgradetx',
+        	call: 'contract_ccupgradetx',
+        	params: 8, //from, to, daoAmount, daoFee, contractId, templateId, args, signs
+			inputFormatter: [null, null, null, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'ccupgradesign',
+        	call: 'contract_ccupgradesign',
+        	params: 5, //addr, contractId, templateId, args, password
+			inputFormatter: [null, null, null, null, null]
+		}),
 		//cc fee
 		new web3._extend.Method({
 			name: 'ccinstalltxfee',
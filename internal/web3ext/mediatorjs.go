@@ -121,6 +121,16 @@ web3._extend({
 			call: 'mediator_isActive',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'getLiveness',
+			call: 'mediator_getLiveness',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getSigShareStats',
+			call: 'mediator_getSigShareStats',
+			params: 0,
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -39,11 +39,22 @@ web3._extend({
 			call: 'ptn_addressBalanceStatistics',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'estimateFee',
+			call: 'ptn_estimateFee',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'encodeTx',
 			call: 'ptn_encodeTx',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'simulateInvoke',
+			call: 'ptn_simulateInvoke',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'decodeTx',
 			call: 'ptn_decodeTx',
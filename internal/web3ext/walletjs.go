@@ -81,6 +81,31 @@ const Wallet_JS = `
 		    call: 'wallet_multiSignRawTransaction',
 		    params: 6
 		}),	
+		new web3._extend.Method({
+		    name: 'createPartialSignedTx',
+		    call: 'wallet_createPartialSignedTx',
+		    params: 3
+		}),
+		new web3._extend.Method({
+		    name: 'signPartialSignedTx',
+		    call: 'wallet_signPartialSignedTx',
+		    params: 4
+		}),
+		new web3._extend.Method({
+		    name: 'combinePartialSignedTx',
+		    call: 'wallet_combinePartialSignedTx',
+		    params: 1
+		}),
+		new web3._extend.Method({
+		    name: 'finalizePartialSignedTx',
+		    call: 'wallet_finalizePartialSignedTx',
+		    params: 1
+		}),
+		new web3._extend.Method({
+		    name: 'decodePartialSignedTx',
+		    call: 'wallet_decodePartialSignedTx',
+		    params: 1
+		}),
         new web3._extend.Method({
 		    name: 'sendRlpTransaction',
 		    call: 'wallet_sendRlpTransaction',
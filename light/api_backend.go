@@ -155,7 +155,7 @@ func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- modules.TxPreEvent) event.
 }
 
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- modules.ChainEvent) event.Subscription {
-	return nil //return b.eth.dag.SubscribeChainEvent(ch)
+	return b.ptn.dag.SubscribeChainEvent(ch)
 }
 
 func (b *LesApiBackend) SubscribeChainHeadEvent(ch chan<- modules.ChainHeadEvent) event.Subscription {
@@ -187,6 +187,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return nil, nil
 }
 
+func (b *LesApiBackend) EstimateFee(targetUnits uint32, asset string) (*txspool.FeeEstimate, error) {
+	return nil, errors.New("light node not support fee estimate")
+}
+
 func (b *LesApiBackend) ChainDb() ptndb.Database {
 	return b.ptn.unitDb
 }
@@ -419,8 +423,8 @@ func (b *LesApiBackend) ContractInstallReqTx(from, to common.Address, daoAmount,
 	return
 }
 func (b *LesApiBackend) ContractDeployReqTx(from, to common.Address, daoAmount, daoFee uint64,
-	templateId []byte, args [][]byte, extData []byte, timeout time.Duration) (reqId common.Hash,
-	depId common.Address, err error) {
+	templateId []byte, args [][]byte, extData []byte, timeout time.Duration,
+	policy *modules.ContractUpgradePolicy) (reqId common.Hash, depId common.Address, err error) {
 	return
 }
 func (b *LesApiBackend) ContractInvokeReqTx(from, to common.Address, daoAmount, daoFee uint64,
@@ -441,6 +445,17 @@ func (b *LesApiBackend) ContractStopReqTx(from, to common.Address, daoAmount, da
 	return
 }
 
+func (b *LesApiBackend) ContractUpgradeReqTx(from, to common.Address, daoAmount, daoFee uint64,
+	contractId common.Address, templateId []byte, args [][]byte, timeout uint32,
+	signs []modules.SignatureSet) (reqId common.Hash, err error) {
+	return
+}
+
+func (b *LesApiBackend) ContractUpgradeHash(contractId common.Address, templateId []byte, args [][]byte,
+	timeout uint32) (common.Hash, error) {
+	return common.Hash{}, nil
+}
+
 func (b *LesApiBackend) ContractInstallReqTxFee(from, to common.Address, daoAmount, daoFee uint64, tplName,
 	path, version string, description, abi, language string, addrs []common.Address) (fee float64, size float64, tm uint32,
 	err error) {
@@ -468,12 +483,21 @@ func (b *LesApiBackend) UpdateJuryAccount(addr common.Address, pwd string) bool
 func (b *LesApiBackend) GetJuryAccount() []common.Address {
 	return nil
 }
+func (b *LesApiBackend) GetJurorOutcome(juror common.Address) *modules.JurorOutcome {
+	return nil
+}
 
 func (b *LesApiBackend) ContractQuery(id []byte, args [][]byte,
 	timeout time.Duration) (rspPayload []byte, err error) {
 	return nil, nil
 }
 
+func (b *LesApiBackend) ContractSimulateInvoke(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
+	contractAddress common.Address, args [][]byte, timeout uint32, gasLimit uint64,
+	stable, trace bool) (*modules.ContractSimulateResult, error) {
+	return nil, errors.New("light node not support contract simulate")
+}
+
 func (b *LesApiBackend) Dag() dag.IDag {
 	//return b.Dag()
 	return nil
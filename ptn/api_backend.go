@@ -201,6 +201,14 @@ func (b *PtnApiBackend) SubscribeTxPreEvent(ch chan<- modules.TxPreEvent) event.
 	return b.ptn.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *PtnApiBackend) SubscribeChainEvent(ch chan<- modules.ChainEvent) event.Subscription {
+	return b.ptn.dag.SubscribeChainEvent(ch)
+}
+
+func (b *PtnApiBackend) SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription {
+	return b.ptn.dag.SubscribeStableUnitEvent(ch)
+}
+
 func (b *PtnApiBackend) Downloader() *downloader.Downloader {
 	return b.ptn.Downloader()
 }
@@ -213,6 +221,10 @@ func (b *PtnApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return &big.Int{}, nil
 }
 
+func (b *PtnApiBackend) EstimateFee(targetUnits uint32, asset string) (*txspool.FeeEstimate, error) {
+	return b.ptn.feeEstimator.EstimateFee(targetUnits, asset)
+}
+
 func (b *PtnApiBackend) ChainDb() ptndb.Database {
 	return nil
 }
@@ -226,13 +238,13 @@ func (b *PtnApiBackend) AccountManager() *accounts.Manager {
 }
 
 func (b *PtnApiBackend) BloomStatus() (uint64, uint64) {
-	return uint64(0), uint64(0)
+	return BloomBitsUnits, b.ptn.bloomIndexer.Sections()
 }
 
 func (b *PtnApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
-	//for i := 0; i < bloomFilterThreads; i++ {
-	//	go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.ptn.bloomRequests)
-	//}
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.ptn.bloomRequests)
+	}
 }
 
 //func (b *PtnApiBackend) WalletTokens(address string) (map[string]*modules.AccountToken, error) {
@@ -695,7 +707,7 @@ func (b *PtnApiBackend) ContractInvoke(deployId []byte, txid string, args [][]by
 	timeout time.Duration) ([]byte, error) {
 	log.Debugf("======>ContractInvoke:deployId[%s]txid[%s]", hex.EncodeToString(deployId), txid)
 	//channelId := "palletone"
-	unit, err := b.ptn.contract.Invoke(rwset.RwM, channelId, deployId, txid, args, timeout)
+	unit, err := b.ptn.contract.Invoke(rwset.RwM, channelId, deployId, txid, args, timeout, modules.DefaultContractGasLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -721,8 +733,10 @@ func (b *PtnApiBackend) ContractInstallReqTx(from, to common.Address, daoAmount,
 		version, description, abi, language, true, addrs)
 }
 func (b *PtnApiBackend) ContractDeployReqTx(from, to common.Address, daoAmount, daoFee uint64, templateId []byte,
-	args [][]byte, extData []byte, timeout time.Duration) (common.Hash, common.Address, error) {
-	return b.ptn.contractPorcessor.ContractDeployReq(from, to, daoAmount, daoFee, templateId, args, extData, timeout)
+	args [][]byte, extData []byte, timeout time.Duration, policy *modules.ContractUpgradePolicy) (common.Hash,
+	common.Address, error) {
+	return b.ptn.contractPorcessor.ContractDeployReq(from, to, daoAmount, daoFee, templateId, args, extData, timeout,
+		policy)
 }
 func (b *PtnApiBackend) ContractInvokeReqTx(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
 	contractAddress common.Address, args [][]byte, timeout uint32) (reqId common.Hash, err error) {
@@ -744,6 +758,16 @@ func (b *PtnApiBackend) ContractStopReqTx(from, to common.Address, daoAmount, da
 	deleteImage bool) (reqId common.Hash, err error) {
 	return b.ptn.contractPorcessor.ContractStopReq(from, to, daoAmount, daoFee, contractId, deleteImage)
 }
+func (b *PtnApiBackend) ContractUpgradeReqTx(from, to common.Address, daoAmount, daoFee uint64,
+	contractId common.Address, templateId []byte, args [][]byte, timeout uint32,
+	signs []modules.SignatureSet) (reqId common.Hash, err error) {
+	return b.ptn.contractPorcessor.ContractUpgradeReq(from, to, daoAmount, daoFee, contractId, templateId, args,
+		timeout, signs)
+}
+func (b *PtnApiBackend) ContractUpgradeHash(contractId common.Address, templateId []byte, args [][]byte,
+	timeout uint32) (common.Hash, error) {
+	return b.ptn.contractPorcessor.ContractUpgradeHash(contractId, templateId, args, timeout)
+}
 
 func (b *PtnApiBackend) ContractInstallReqTxFee(from, to common.Address, daoAmount, daoFee uint64, tplName,
 	path, version string, description, abi, language string, addrs []common.Address) (fee float64, size float64, tm uint32,
@@ -766,6 +790,12 @@ func (b *PtnApiBackend) ContractStopReqTxFee(from, to common.Address, daoAmount,
 func (b *PtnApiBackend) ContractQuery(id []byte, args [][]byte, timeout time.Duration) (rspPayload []byte, err error) {
 	return b.ptn.contractPorcessor.ContractQuery(id, args, timeout)
 }
+func (b *PtnApiBackend) ContractSimulateInvoke(from, to common.Address, daoAmount, daoFee uint64, certID *big.Int,
+	contractAddress common.Address, args [][]byte, timeout uint32, gasLimit uint64,
+	stable, trace bool) (*modules.ContractSimulateResult, error) {
+	return b.ptn.contractPorcessor.ContractSimulateInvoke(from, to, daoAmount, daoFee, certID, contractAddress, args,
+		timeout, gasLimit, stable, trace)
+}
 
 func (b *PtnApiBackend) ElectionVrf(id uint32) ([]byte, error) {
 	return b.ptn.contractPorcessor.ElectionVrfReq(id)
@@ -777,6 +807,10 @@ func (b *PtnApiBackend) UpdateJuryAccount(addr common.Address, pwd string) bool
 func (b *PtnApiBackend) GetJuryAccount() []common.Address {
 	return b.ptn.contractPorcessor.GetLocalJuryAddrs()
 }
+
+func (b *PtnApiBackend) GetJurorOutcome(juror common.Address) *modules.JurorOutcome {
+	return b.ptn.contractPorcessor.GetJurorOutcome(juror)
+}
 func (b *PtnApiBackend) SaveCommon(key, val []byte) error {
 	return b.ptn.dag.SaveCommon(key, val)
 }
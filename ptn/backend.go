@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/bloombits"
 	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/common/p2p"
@@ -44,6 +45,7 @@ import (
 	"github.com/palletone/go-palletone/dag/storage"
 	"github.com/palletone/go-palletone/internal/ptnapi"
 	"github.com/palletone/go-palletone/ptn/downloader"
+	"github.com/palletone/go-palletone/ptn/filters"
 	"github.com/palletone/go-palletone/ptnjson"
 	"github.com/palletone/go-palletone/tokenengine"
 	"github.com/palletone/go-palletone/txspool"
@@ -70,6 +72,7 @@ type PalletOne struct {
 
 	// Handlers
 	txPool          txspool.ITxPool
+	feeEstimator    *txspool.FeeEstimator
 	protocolManager *ProtocolManager
 	lesServer       LesServer
 
@@ -88,8 +91,8 @@ type PalletOne struct {
 
 	contract *contracts.Contract
 
-	//bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
-	//bloomIndexer  *indexer.ChainIndexer          // Bloom indexer operating during block imports
+	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer  *BloomIndexer                  // Bloom indexer of the contract events in stable units
 
 	// append by Albert·Gou
 	mediatorPlugin    *mp.MediatorPlugin
@@ -158,6 +161,8 @@ func New(ctx *node.ServiceContext, config *Config, cache palletcache.ICache, isT
 		dag:            dag,
 		unitDb:         db,
 		syncCh:         make(chan bool, 1),
+		bloomRequests:  make(chan chan *bloombits.Retrieval),
+		bloomIndexer:   NewBloomIndexer(db, dag, BloomBitsUnits),
 	}
 	log.Info("Initializing PalletOne protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
@@ -166,6 +171,7 @@ func New(ctx *node.ServiceContext, config *Config, cache palletcache.ICache, isT
 	}
 	//val:=validator.NewValidate(ptn.dag,ptn.dag,ptn.dag,ptn.dag,cache)
 	ptn.txPool = txspool.NewTxPool(config.TxPool, cache, dag)
+	ptn.feeEstimator = txspool.NewFeeEstimator(db)
 
 	//Test for P2P
 	ptn.engine = consensus.New(dag, ptn.txPool)
@@ -250,12 +256,12 @@ func (s *PalletOne) APIs() []rpc.API {
 			Service:   downloader.NewPublicDownloaderAPI(s.protocolManager.downloader, s.eventMux),
 			Public:    true,
 		},
-		/*{
+		{
 			Namespace: "ptn",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend),
 			Public:    true,
-		}, */{
+		}, {
 			Namespace: "admin",
 			Version:   "1.0",
 			//Service:   NewPrivateAdminAPI(s),
@@ -365,14 +371,18 @@ func (s *PalletOne) Start(srvr *p2p.Server, corss *p2p.Server) error {
 	}
 
 	s.protocolManager.Start(srvr, maxPeers, s.syncCh)
+	s.feeEstimator.Start(s.dag, s.txPool)
+	s.startBloomHandlers()
+	s.bloomIndexer.Start()
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // PalletOne protocol.
 func (s *PalletOne) Stop() error {
-	//s.bloomIndexer.Close()
+	s.bloomIndexer.Stop()
 	s.protocolManager.Stop()
+	s.feeEstimator.Stop()
 	s.txPool.Stop()
 	s.engine.Stop()
 	s.eventMux.Stop()
@@ -17,117 +17,247 @@
 package ptn
 
 import (
+	"encoding/binary"
+	"sync"
+	"time"
+
 	"github.com/palletone/go-palletone/common"
-	//"github.com/palletone/go-palletone/common/bitutil"
+	"github.com/palletone/go-palletone/common/bitutil"
 	"github.com/palletone/go-palletone/common/bloombits"
+	"github.com/palletone/go-palletone/common/event"
 	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/core/types"
 	"github.com/palletone/go-palletone/dag/modules"
 )
 
+const (
+	// BloomBitsUnits is the number of units a single bloom bit section vector
+	// contains.
+	BloomBitsUnits uint64 = 4096
+
+	// bloomServiceThreads is the number of goroutines used globally by a PalletOne
+	// instance to service bloombits lookups for all running filters.
+	bloomServiceThreads = 16
+
+	// bloomFilterThreads is the number of goroutines used locally per filter to
+	// multiplex requests onto the global servicing goroutines.
+	bloomFilterThreads = 3
+
+	// bloomRetrievalBatch is the maximum number of bloom bit retrievals to service
+	// in a single batch.
+	bloomRetrievalBatch = 16
+
+	// bloomRetrievalWait is the maximum time to wait for enough bloom bit requests
+	// to accumulate request an entire batch (avoiding hysteresis).
+	bloomRetrievalWait = time.Duration(0)
+)
+
 var (
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+
+	bloomBitsSectionsKey = append(append([]byte{}, BloomBitsIndexPrefix...), []byte("count")...)
 )
 
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
 // retrievals from possibly a range of filters and serving the data to satisfy.
-//func (eth *PalletOne) startBloomHandlers() {
-//for i := 0; i < bloomServiceThreads; i++ {
-//	go func() {
-//		for {
-//			select {
-//			case <-eth.shutdownChan:
-//				return
-//
-//			case request := <-eth.bloomRequests:
-//				task := <-request
-//				task.Bitsets = make([][]byte, len(task.Sections))
-//				//for i,section:= range task.Sections {
-//				//section = section
-//				//task.Error = errors.New("404 Not Found")
-//				//head := core.GetCanonicalHash(eth.chainDb, (section+1)*BloomBitsBlocks-1)
-//				//if compVector, err := core.GetBloomBits(eth.chainDb, task.Bit, section, head); err == nil {
-//				//	if blob, err := bitutil.DecompressBytes(compVector, int(BloomBitsBlocks)/8); err == nil {
-//				//		task.Bitsets[i] = blob
-//				//	} else {
-//				//		task.Error = err
-//				//	}
-//				//} else {
-//				//	task.Error = err
-//				//}
-//				//}
-//				request <- task
-//			}
-//		}
-//	}()
-//}
-//}
+func (s *PalletOne) startBloomHandlers() {
+	for i := 0; i < bloomServiceThreads; i++ {
+		go func() {
+			for {
+				select {
+				case <-s.shutdownChan:
+					return
 
-const (
-// bloomConfirms is the number of confirmation blocks before a bloom section is
-// considered probably final and its rotated bits are calculated.
-//bloomConfirms = 256
+				case request := <-s.bloomRequests:
+					task := <-request
+					task.Bitsets = make([][]byte, len(task.Sections))
+					for i, section := range task.Sections {
+						if blob, err := s.bloomIndexer.BloomBits(task.Bit, section); err == nil {
+							task.Bitsets[i] = blob
+						} else {
+							task.Error = err
+						}
+					}
+					request <- task
+				}
+			}
+		}()
+	}
+}
 
-// bloomThrottling is the time to wait between processing two consecutive index
-// sections. It's useful during chain upgrades to prevent disk overload.
-//bloomThrottling = 100 * time.Millisecond
-)
+// bloomDag is the part of the dag used by the bloom indexer
+type bloomDag interface {
+	GetStableChainIndex(token modules.AssetId) *modules.ChainIndex
+	GetHeaderByNumber(number *modules.ChainIndex) (*modules.Header, error)
+	GetContractEventBloom(unitIndex uint64) (types.Bloom, error)
+	SubscribeChainEvent(ch chan<- modules.ChainEvent) event.Subscription
+}
 
-// BloomIndexer implements a core.ChainIndexer, building up a rotated bloom bits index
-// for the Ethereum header bloom filters, permitting blazing fast filtering.
+// BloomIndexer builds up a rotated bloom bits index for the contract event
+// blooms of the stable units, permitting blazing fast filtering.
 type BloomIndexer struct {
 	size uint64 // section size to generate bloombits for
 
-	//db  ptndb.Database       // database instance to write index data and metadata into
+	db  ptndb.Database       // database instance to write index data and metadata into
+	dag bloomDag             // dag to read the contract event blooms of the units
 	gen *bloombits.Generator // generator to rotate the bloom bits crating the bloom index
 
 	section uint64      // Section is the section number being processed currently
 	head    common.Hash // Head is the hash of the last header processed
+
+	lock     sync.RWMutex
+	sections uint64 // Number of sections fully indexed
+	quit     chan struct{}
+	wg       sync.WaitGroup
 }
 
-// NewBloomIndexer returns a chain indexer that generates bloom bits data for the
-// canonical chain for fast logs filtering.
-//func NewBloomIndexer(db ptndb.Database, size uint64) *indexer.ChainIndexer {
-//	backend := &BloomIndexer{
-//		db:   db,
-//		size: size,
-//	}
-//	table := ptndb.NewTable(db, string(BloomBitsIndexPrefix))
-//
-//	return indexer.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "bloombits")
-//}
+// NewBloomIndexer returns a bloom indexer that generates bloom bits data for the
+// stable units for fast contract events filtering.
+func NewBloomIndexer(db ptndb.Database, dag bloomDag, size uint64) *BloomIndexer {
+	b := &BloomIndexer{db: db, dag: dag, size: size, quit: make(chan struct{})}
+	if data, err := db.Get(bloomBitsSectionsKey); err == nil && len(data) == 8 {
+		b.sections = binary.BigEndian.Uint64(data)
+	}
+	return b
+}
 
-// Reset implements core.ChainIndexerBackend, starting a new bloombits index
-// section.
+// Start indexes the sections which become stable after each new unit
+func (b *BloomIndexer) Start() {
+	chainCh := make(chan modules.ChainEvent, 16)
+	chainSub := b.dag.SubscribeChainEvent(chainCh)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer chainSub.Unsubscribe()
+		b.indexStableSections()
+		for {
+			select {
+			case <-chainCh:
+				b.indexStableSections()
+			case <-chainSub.Err():
+				return
+			case <-b.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (b *BloomIndexer) Stop() {
+	close(b.quit)
+	b.wg.Wait()
+}
+
+// Sections returns the number of sections fully indexed
+func (b *BloomIndexer) Sections() uint64 {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.sections
+}
+
+// BloomBits returns the bit vector of the bloom bit in the section
+func (b *BloomIndexer) BloomBits(bit uint, section uint64) ([]byte, error) {
+	data, err := b.db.Get(bloomBitsKey(bit, section))
+	if err != nil {
+		return nil, err
+	}
+	return bitutil.DecompressBytes(data, int(b.size/8))
+}
+
+//只索引全部单元都已经稳定的Section
+func (b *BloomIndexer) indexStableSections() {
+	stable := b.dag.GetStableChainIndex(modules.PTNCOIN)
+	if stable == nil {
+		return
+	}
+	for {
+		section := b.Sections()
+		if (section+1)*b.size-1 > stable.Index {
+			return
+		}
+		if err := b.indexSection(section); err != nil {
+			log.Warnf("Index contract event bloom bits of section %d failed:%s", section, err.Error())
+			return
+		}
+		select {
+		case <-b.quit:
+			return
+		default:
+		}
+	}
+}
+
+func (b *BloomIndexer) indexSection(section uint64) error {
+	if err := b.Reset(section, b.head); err != nil {
+		return err
+	}
+	for index := section * b.size; index < (section+1)*b.size; index++ {
+		header, err := b.dag.GetHeaderByNumber(&modules.ChainIndex{AssetID: modules.PTNCOIN, Index: index})
+		if err != nil {
+			return err
+		}
+		if err := b.Process(header); err != nil {
+			return err
+		}
+	}
+	return b.Commit()
+}
+
+// Reset starts a new bloombits index section.
 func (b *BloomIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
 	gen, err := bloombits.NewGenerator(uint(b.size))
 	b.gen, b.section, b.head = gen, section, common.Hash{}
 	return err
 }
 
-// Process implements core.ChainIndexerBackend, adding a new header's bloom into
-// the index.
-func (b *BloomIndexer) Process(header *modules.Header) {
-	log.Debug("========BloomIndexer Process========")
-	//TODO must be recover
-	//b.gen.AddBloom(uint(header.Number.Index-b.section*b.size), header.Bloom)
-	//b.head = header.Hash()
+// Process adds the contract event bloom of a new header into the index.
+func (b *BloomIndexer) Process(header *modules.Header) error {
+	bloom, err := b.dag.GetContractEventBloom(header.NumberU64())
+	if err != nil {
+		return err
+	}
+	if err := b.gen.AddBloom(uint(header.NumberU64()-b.section*b.size), bloom); err != nil {
+		return err
+	}
+	b.head = header.Hash()
+	return nil
 }
 
-// Commit implements core.ChainIndexerBackend, finalizing the bloom section and
-// writing it out into the database.
+// Commit finalizes the bloom section and writing it out into the database.
 func (b *BloomIndexer) Commit() error {
+	batch := b.db.NewBatch()
+	for i := 0; i < types.BloomBitLength; i++ {
+		bits, err := b.gen.Bitset(uint(i))
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(bloomBitsKey(uint(i), b.section), bitutil.CompressBytes(bits)); err != nil {
+			return err
+		}
+	}
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, b.section+1)
+	if err := batch.Put(bloomBitsSectionsKey, count); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	b.lock.Lock()
+	b.sections = b.section + 1
+	b.lock.Unlock()
+	log.Debugf("Contract event bloom bits of section %d indexed, head:%s", b.section, b.head.String())
 	return nil
-	//	batch := b.db.NewBatch()
-	//
-	//	for i := 0; i < types.BloomBitLength; i++ {
-	//		bits, err := b.gen.Bitset(uint(i))
-	//		if err != nil {
-	//			return err
-	//		}
-	//		bits = bits
-	//		//TODO must be recover
-	//		//core.WriteBloomBits(batch, uint(i), b.section, b.head, bitutil.CompressBytes(bits))
-	//	}
-	//	return batch.Write()
+}
+
+// bloomBitsKey = BloomBitsIndexPrefix + bit (uint16 big endian) + section (uint64 big endian)
+func bloomBitsKey(bit uint, section uint64) []byte {
+	key := append([]byte{}, BloomBitsIndexPrefix...)
+	key = append(key, byte(bit>>8), byte(bit))
+	sec := make([]byte, 8)
+	binary.BigEndian.PutUint64(sec, section)
+	return append(key, sec...)
 }
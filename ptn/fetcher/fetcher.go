@@ -19,6 +19,7 @@ package fetcher
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -68,6 +69,9 @@ type chainInsertFn func(modules.Units) (int, error)
 // peerDropFn is a callback type for dropping a peer detected as malicious.
 type peerDropFn func(id string)
 
+// equivocationFn is a callback type for reporting a mediator signing two conflicting units.
+type equivocationFn func(ev modules.MediatorEquivocationEvent)
+
 // announce is the hash notification of the availability of a new block in the
 // network.
 type announce struct {
@@ -137,6 +141,10 @@ type Fetcher struct {
 	chainHeight    chainHeightFn      // Retrieves the current chain's height
 	insertChain    chainInsertFn      // Injects a batch of blocks into the chain
 	dropPeer       peerDropFn         // Drops a peer for misbehaving
+	equivocation   equivocationFn     // Reports a mediator signing two conflicting units
+
+	// 收到的单元头，按Mediator的时间槽和父单元索引，用于发现Mediator作恶
+	signed map[string]*modules.Header
 
 	// Testing hooks
 	announceChangeHook func(common.Hash, bool) // Method to call upon adding or deleting a hash from the announce list
@@ -148,7 +156,7 @@ type Fetcher struct {
 
 // New creates a block fetcher to retrieve blocks based on hash announcements.
 func New(getBlock headerExistFn, verifyUnit unitVerifierFn, broadcastBlock blockBroadcasterFn,
-	chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn) *Fetcher {
+	chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn, equivocation equivocationFn) *Fetcher {
 	return &Fetcher{
 		notify:         make(chan *announce),
 		inject:         make(chan *inject),
@@ -171,6 +179,8 @@ func New(getBlock headerExistFn, verifyUnit unitVerifierFn, broadcastBlock block
 		chainHeight:    chainHeight,
 		insertChain:    insertChain,
 		dropPeer:       dropPeer,
+		equivocation:   equivocation,
+		signed:         make(map[string]*modules.Header),
 	}
 }
 
@@ -641,6 +651,7 @@ func (f *Fetcher) enqueue(peer string, block *modules.Unit) {
 		f.forgetHash(hash)
 		return
 	}
+	f.checkEquivocation(block.UnitHeader, heightChain)
 	// Schedule the block for future importing
 	if _, ok := f.queued[hash]; !ok {
 		op := &inject{
@@ -658,6 +669,44 @@ func (f *Fetcher) enqueue(peer string, block *modules.Unit) {
 	}
 }
 
+// checkEquivocation 检查是否收到过同一个Mediator在同一时间槽或同一父单元上签名的其他单元。
+// 冲突的单元可能在插入memdag前就被拒绝，所以在fetcher中也要检查
+func (f *Fetcher) checkEquivocation(header *modules.Header, heightChain int64) {
+	for key, h := range f.signed {
+		if int64(h.NumberU64()) < heightChain-maxUncleDist {
+			delete(f.signed, key)
+		}
+	}
+	if !modules.VerifyHeaderAuthor(header) {
+		return
+	}
+	author := header.Author().String()
+	keys := []string{fmt.Sprintf("slot-%s-%s-%d", header.GetAssetId().String(), author, header.Timestamp())}
+	if parents := header.ParentHash(); len(parents) > 0 {
+		keys = append(keys, fmt.Sprintf("parent-%s-%s", author, parents[0].String()))
+	}
+	for _, key := range keys {
+		other, ok := f.signed[key]
+		if !ok {
+			f.signed[key] = header
+			continue
+		}
+		if other.Hash() == header.Hash() {
+			continue
+		}
+		evidence := modules.NewMediatorEquivocationEvidence(header, other)
+		if err := evidence.Validate(); err != nil {
+			log.Debugf("invalid equivocation evidence of mediator[%s]: %s", author, err.Error())
+			continue
+		}
+		log.Warnf("mediator[%s] signed two conflicting units[%s, %s]", author, other.Hash().String(),
+			header.Hash().String())
+		if f.equivocation != nil {
+			f.equivocation(modules.MediatorEquivocationEvent{Evidence: evidence})
+		}
+	}
+}
+
 // insert spawns a new goroutine to run a block insertion into the chain. If the
 // block's number is at the same height as the current import phase, it updates
 // the phase states accordingly.
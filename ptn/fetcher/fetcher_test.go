@@ -19,6 +19,7 @@ package fetcher
 import (
 	"fmt"
 	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
 	"github.com/palletone/go-palletone/common/ptndb"
 	//dag2 "github.com/palletone/go-palletone/dag"
 	"github.com/palletone/go-palletone/dag/constants"
@@ -198,7 +199,7 @@ func newTester() *fetcherTester {
 		blocks: map[common.Hash]*modules.Unit{genesisUnit.Hash(): genesisUnit},
 		drops:  make(map[string]bool),
 	}
-	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer)
+	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer, nil)
 	tester.fetcher.Start()
 
 	return tester
@@ -724,3 +725,48 @@ func TestBlockMemoryExhaustionAttack(t *testing.T) {
 	verifyImportDone(t, imported)
 }
 */
+
+func newSignedTestHeader(key []byte, parent common.Hash, index uint64, t int64) *modules.Header {
+	pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(key)
+	h := modules.NewHeader([]common.Hash{parent}, common.Hash{}, []byte{}, []byte{}, []byte{}, []byte{},
+		[]uint16{}, modules.PTNCOIN, index, t)
+	sig, _ := crypto.MyCryptoLib.Sign(key, h.HashWithoutAuthor().Bytes())
+	h.SetAuthor(modules.Authentifier{PubKey: pubKey, Signature: sig})
+	return h
+}
+
+// Tests that the fetcher reports a mediator signing two units in the same slot
+// or on the same parent, but not two units of a normal fork.
+func TestFetcherEquivocation(t *testing.T) {
+	var reported []*modules.MediatorEquivocationEvidence
+	f := New(nil, nil, nil, nil, nil, nil, func(ev modules.MediatorEquivocationEvent) {
+		reported = append(reported, ev.Evidence)
+	})
+	key, _ := crypto.MyCryptoLib.KeyGen()
+	p1, p2, p3 := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+
+	a := newSignedTestHeader(key, p1, 10, 1000)
+	f.checkEquivocation(a, 10)
+	f.checkEquivocation(a, 10)
+	//同一高度的正常分叉
+	f.checkEquivocation(newSignedTestHeader(key, p2, 10, 1003), 10)
+	if len(reported) != 0 {
+		t.Fatalf("reported a normal fork: %d", len(reported))
+	}
+	//同一时间槽
+	b := newSignedTestHeader(key, p3, 11, 1000)
+	f.checkEquivocation(b, 10)
+	if len(reported) != 1 || reported[0].Hash() != modules.NewMediatorEquivocationEvidence(a, b).Hash() {
+		t.Fatalf("same slot units not reported: %d", len(reported))
+	}
+	//同一父单元
+	f.checkEquivocation(newSignedTestHeader(key, p1, 10, 1006), 10)
+	if len(reported) != 2 {
+		t.Fatalf("same parent units not reported: %d", len(reported))
+	}
+	//过期的单元头被清理
+	f.checkEquivocation(newSignedTestHeader(key, p3, 30, 2000), 30)
+	if len(f.signed) != 2 {
+		t.Fatalf("stale headers not pruned: %d", len(f.signed))
+	}
+}
@@ -16,392 +16,176 @@
 
 package filters
 
-//var (
-//	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
-//)
+import (
+	"context"
+	"errors"
+	"fmt"
 
-// filter is a helper struct that holds meta information over the filter type
-// and associated subscription in the event system.
-//type filter struct {
-//	typ      Type
-//	deadline *time.Timer // filter is inactiv when deadline triggers
-//	hashes   []common.Hash
-//	//crit     FilterCriteria
-//	//logs     []*types.Log
-//	s *Subscription // associated subscription in event system
-//}
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/dag/modules"
+)
 
-// PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
-// information related to the PalletOne protocol such als blocks, transactions and logs.
-//type PublicFilterAPI struct {
-//	backend Backend
-//	mux     *event.TypeMux
-//	//quit      chan struct{}
-//	chainDb   ptndb.Database
-//	events    *EventSystem
-//	filtersMu sync.Mutex
-//	filters   map[rpc.ID]*filter
-//}
-//
-//// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-//func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
-//	api := &PublicFilterAPI{
-//		backend: backend,
-//		mux:     backend.EventMux(),
-//		chainDb: backend.ChainDb(),
-//		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
-//		filters: make(map[rpc.ID]*filter),
-//	}
-//	go api.timeoutLoop()
-//
-//	return api
-//}
-//
-//// timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
-//// Tt is started when the api is created.
-//func (api *PublicFilterAPI) timeoutLoop() {
-//	ticker := time.NewTicker(5 * time.Minute)
-//	for {
-//		<-ticker.C
-//		api.filtersMu.Lock()
-//		for id, f := range api.filters {
-//			select {
-//			case <-f.deadline.C:
-//				f.s.Unsubscribe()
-//				delete(api.filters, id)
-//			default:
-//				continue
-//			}
-//		}
-//		api.filtersMu.Unlock()
-//	}
-//}
-//
-//// NewPendingTransactionFilter creates a filter that fetches pending transaction hashes
-//// as transactions enter the pending state.
-////
-//// It is part of the filter package because this filter can be used through the
-//// `eth_getFilterChanges` polling method that is also used for log filters.
-////
-//// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newpendingtransactionfilter
-//func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
-//	var (
-//		pendingTxs   = make(chan common.Hash)
-//		pendingTxSub = api.events.SubscribePendingTxEvents(pendingTxs)
-//	)
-//
-//	api.filtersMu.Lock()
-//	api.filters[pendingTxSub.ID] = &filter{typ: PendingTransactionsSubscription, deadline: time.NewTimer(deadline),
-//		hashes: make([]common.Hash, 0), s: pendingTxSub}
-//	api.filtersMu.Unlock()
-//
-//	go func() {
-//		for {
-//			select {
-//			case ph := <-pendingTxs:
-//				api.filtersMu.Lock()
-//				if f, found := api.filters[pendingTxSub.ID]; found {
-//					f.hashes = append(f.hashes, ph)
-//				}
-//				api.filtersMu.Unlock()
-//			case <-pendingTxSub.Err():
-//				api.filtersMu.Lock()
-//				delete(api.filters, pendingTxSub.ID)
-//				api.filtersMu.Unlock()
-//				return
-//			}
-//		}
-//	}()
-//
-//	return pendingTxSub.ID
-//}
-//
-//// NewPendingTransactions creates a subscription that is triggered each time a transaction
-//// enters the transaction pool and was signed from one of the transactions this nodes manages.
-//func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
-//	notifier, supported := rpc.NotifierFromContext(ctx)
-//	if !supported {
-//		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
-//	}
-//
-//	rpcSub := notifier.CreateSubscription()
-//
-//	go func() {
-//		txHashes := make(chan common.Hash)
-//		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
-//
-//		for {
-//			select {
-//			case h := <-txHashes:
-//				notifier.Notify(rpcSub.ID, h)
-//			case <-rpcSub.Err():
-//				pendingTxSub.Unsubscribe()
-//				return
-//			case <-notifier.Closed():
-//				pendingTxSub.Unsubscribe()
-//				return
-//			}
-//		}
-//	}()
-//
-//	return rpcSub, nil
-//}
-//
-//// NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
-//// It is part of the filter package since polling goes with eth_getFilterChanges.
-////
-//// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newblockfilter
-//func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
-//	var (
-//		headers   = make(chan *modules.Header)
-//		headerSub = api.events.SubscribeNewHeads(headers)
-//	)
-//
-//	api.filtersMu.Lock()
-//	api.filters[headerSub.ID] = &filter{typ: BlocksSubscription, deadline: time.NewTimer(deadline),
-//		hashes: make([]common.Hash, 0), s: headerSub}
-//	api.filtersMu.Unlock()
-//
-//	go func() {
-//		for {
-//			select {
-//			case h := <-headers:
-//				api.filtersMu.Lock()
-//				if f, found := api.filters[headerSub.ID]; found {
-//					f.hashes = append(f.hashes, h.Hash())
-//				}
-//				api.filtersMu.Unlock()
-//			case <-headerSub.Err():
-//				api.filtersMu.Lock()
-//				delete(api.filters, headerSub.ID)
-//				api.filtersMu.Unlock()
-//				return
-//			}
-//		}
-//	}()
-//
-//	return headerSub.ID
-//}
-//
-//// NewHeads send a notification each time a new (header) block is appended to the chain.
-//func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
-//	notifier, supported := rpc.NotifierFromContext(ctx)
-//	if !supported {
-//		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
-//	}
-//
-//	rpcSub := notifier.CreateSubscription()
-//
-//	go func() {
-//		headers := make(chan *modules.Header)
-//		headersSub := api.events.SubscribeNewHeads(headers)
-//
-//		for {
-//			select {
-//			case h := <-headers:
-//				notifier.Notify(rpcSub.ID, h)
-//			case <-rpcSub.Err():
-//				headersSub.Unsubscribe()
-//				return
-//			case <-notifier.Closed():
-//				headersSub.Unsubscribe()
-//				return
-//			}
-//		}
-//	}()
-//
-//	return rpcSub, nil
-//}
-//
-//// FilterCriteria represents a request to create a new filter.
-////
-//// TODO(karalabe): Kill this in favor of ethereum.FilterQuery.
-//type FilterCriteria struct {
-//	FromBlock *big.Int
-//	ToBlock   *big.Int
-//	Addresses []common.Address
-//	Topics    [][]common.Hash
-//}
-//
-//// NewFilter creates a new filter and returns the filter id. It can be
-//// used to retrieve logs when the state changes. This method cannot be
-//// used to fetch logs that are already stored in the state.
-////
-//// Default criteria for the from and to block are "latest".
-//// Using "latest" as block number will return logs for mined blocks.
-//// Using "pending" as block number returns logs for not yet mined (pending) blocks.
-//// In case logs are removed (chain reorg) previously returned logs are returned
-//// again but with the removed property set to true.
-////
-//// In case "fromBlock" > "toBlock" an error is returned.
-////
-//// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter
-//func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
-//	return "", nil
-//}
-//
-//// UninstallFilter removes the filter with the given filter id.
-////
-//// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_uninstallfilter
-//func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
-//	api.filtersMu.Lock()
-//	f, found := api.filters[id]
-//	if found {
-//		delete(api.filters, id)
-//	}
-//	api.filtersMu.Unlock()
-//	if found {
-//		f.s.Unsubscribe()
-//	}
-//
-//	return found
-//}
-//
-//// GetFilterChanges returns the logs for the filter with the given id since
-//// last time it was called. This can be used for polling.
-////
-//// For pending transaction and block filters the result is []common.Hash.
-//// (pending)Log filters return []Log.
-////
-//// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterchanges
-//func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
-//	api.filtersMu.Lock()
-//	defer api.filtersMu.Unlock()
-//
-//	if f, found := api.filters[id]; found {
-//		if !f.deadline.Stop() {
-//			// timer expired but filter is not yet removed in timeout loop
-//			// receive timer value and reset timer
-//			<-f.deadline.C
-//		}
-//		f.deadline.Reset(deadline)
-//
-//		switch f.typ {
-//		case PendingTransactionsSubscription, BlocksSubscription:
-//			hashes := f.hashes
-//			f.hashes = nil
-//			return returnHashes(hashes), nil
-//		}
-//	}
-//
-//	return []interface{}{}, fmt.Errorf("filter not found")
-//}
+const (
+	stableUnitChanSize = 10
+	// 每个订阅最多堆积的待发送事件数
+	eventNotifyChanSize = 1000
+	// 一次查询最多扫描的单元数
+	maxEventUnitRange = 10000
+)
 
-// returnHashes is a helper that will return an empty hash array case the given hash array is nil,
-// otherwise the given hashes array is returned.
-//func returnHashes(hashes []common.Hash) []common.Hash {
-//	if hashes == nil {
-//		return []common.Hash{}
-//	}
-//	return hashes
-//}
-//
-//// UnmarshalJSON sets *args fields with given data.
-//func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
-//	type input struct {
-//		From      *rpc.BlockNumber `json:"fromBlock"`
-//		ToBlock   *rpc.BlockNumber `json:"toBlock"`
-//		Addresses interface{}      `json:"address"`
-//		Topics    []interface{}    `json:"topics"`
-//	}
-//
-//	var raw input
-//	if err := json.Unmarshal(data, &raw); err != nil {
-//		return err
-//	}
-//
-//	if raw.From != nil {
-//		args.FromBlock = big.NewInt(raw.From.Int64())
-//	}
-//
-//	if raw.ToBlock != nil {
-//		args.ToBlock = big.NewInt(raw.ToBlock.Int64())
-//	}
-//
-//	args.Addresses = []common.Address{}
-//
-//	if raw.Addresses != nil {
-//		// raw.Address can contain a single address or an array of addresses
-//		switch rawAddr := raw.Addresses.(type) {
-//		case []interface{}:
-//			for i, addr := range rawAddr {
-//				if strAddr, ok := addr.(string); ok {
-//					addr, err := decodeAddress(strAddr)
-//					if err != nil {
-//						return fmt.Errorf("invalid address at index %d: %v", i, err)
-//					}
-//					args.Addresses = append(args.Addresses, addr)
-//				} else {
-//					return fmt.Errorf("non-string address at index %d", i)
-//				}
-//			}
-//		case string:
-//			addr, err := decodeAddress(rawAddr)
-//			if err != nil {
-//				return fmt.Errorf("invalid address: %v", err)
-//			}
-//			args.Addresses = []common.Address{addr}
-//		default:
-//			return errors.New("invalid addresses in query")
-//		}
-//	}
-//
-//	// topics is an array consisting of strings and/or arrays of strings.
-//	// JSON null values are converted to common.Hash{} and ignored by the filter manager.
-//	if len(raw.Topics) > 0 {
-//		args.Topics = make([][]common.Hash, len(raw.Topics))
-//		for i, t := range raw.Topics {
-//			switch topic := t.(type) {
-//			case nil:
-//				// ignore topic when matching logs
-//
-//			case string:
-//				// match specific topic
-//				top, err := decodeTopic(topic)
-//				if err != nil {
-//					return err
-//				}
-//				args.Topics[i] = []common.Hash{top}
-//
-//			case []interface{}:
-//				// or case e.g. [null, "topic0", "topic1"]
-//				for _, rawTopic := range topic {
-//					if rawTopic == nil {
-//						// null component, match all
-//						args.Topics[i] = nil
-//						break
-//					}
-//					if topic, ok := rawTopic.(string); ok {
-//						parsed, err := decodeTopic(topic)
-//						if err != nil {
-//							return err
-//						}
-//						args.Topics[i] = append(args.Topics[i], parsed)
-//					} else {
-//						return fmt.Errorf("invalid topic(s)")
-//					}
-//				}
-//			default:
-//				return fmt.Errorf("invalid topic(s)")
-//			}
-//		}
-//	}
-//
-//	return nil
-//}
-//
-//func decodeAddress(s string) (common.Address, error) {
-//	b, err := hexutil.Decode(s)
-//	if err == nil && len(b) != common.AddressLength {
-//		err = fmt.Errorf("hex has invalid length %d after decoding", len(b))
-//	}
-//	return common.BytesToAddress(b), err
-//}
-//
-//func decodeTopic(s string) (common.Hash, error) {
-//	b, err := hexutil.Decode(s)
-//	if err == nil && len(b) != common.HashLength {
-//		err = fmt.Errorf("hex has invalid length %d after decoding", len(b))
-//	}
-//	return common.BytesToHash(b), err
-//}
+// ContractEventCriteria 合约事件的过滤条件
+type ContractEventCriteria struct {
+	ContractIds []string `json:"contract_ids"` // 合约地址，为空表示所有合约
+	EventNames  []string `json:"event_names"`  // 事件名，为空表示所有事件
+	FromUnit    uint64   `json:"from_unit"`
+	ToUnit      uint64   `json:"to_unit"` // 查询时0表示最新的稳定单元，订阅时0表示不限制
+}
+
+// ContractEventJson 返回给调用者的合约事件
+type ContractEventJson struct {
+	ContractId string `json:"contract_id"`
+	EventName  string `json:"event_name"`
+	Payload    string `json:"payload"`
+	TxHash     string `json:"tx_hash"`
+	TxIndex    uint32 `json:"tx_index"`
+	UnitHash   string `json:"unit_hash"`
+	UnitIndex  uint64 `json:"unit_index"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+func newContractEventJson(l *modules.ContractEventLog) *ContractEventJson {
+	return &ContractEventJson{
+		ContractId: common.NewAddress(l.ContractId, common.ContractHash).String(),
+		EventName:  l.Name,
+		Payload:    string(l.Payload),
+		TxHash:     l.TxHash.String(),
+		TxIndex:    l.TxIndex,
+		UnitHash:   l.UnitHash.String(),
+		UnitIndex:  l.UnitIndex,
+		Timestamp:  int64(l.Timestamp),
+	}
+}
+
+func (crit *ContractEventCriteria) contractAddrs() ([]common.Address, error) {
+	addrs := make([]common.Address, 0, len(crit.ContractIds))
+	for _, id := range crit.ContractIds {
+		addr, err := common.StringToAddress(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contract id %s: %s", id, err.Error())
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// PublicFilterAPI offers support to query and subscribe the contract events.
+type PublicFilterAPI struct {
+	backend Backend
+}
+
+// NewPublicFilterAPI returns a new PublicFilterAPI instance.
+func NewPublicFilterAPI(backend Backend) *PublicFilterAPI {
+	return &PublicFilterAPI{backend: backend}
+}
+
+// GetContractEvents returns the contract events matching the given criteria
+// in the unit range.
+func (api *PublicFilterAPI) GetContractEvents(ctx context.Context,
+	crit ContractEventCriteria) ([]*ContractEventJson, error) {
+	addrs, err := crit.contractAddrs()
+	if err != nil {
+		return nil, err
+	}
+	end := crit.ToUnit
+	if end == 0 {
+		stable := api.backend.Dag().GetStableChainIndex(modules.PTNCOIN)
+		if stable == nil {
+			return nil, errors.New("stable unit not found")
+		}
+		end = stable.Index
+	}
+	if crit.FromUnit > end {
+		return nil, fmt.Errorf("invalid unit range [%d,%d]", crit.FromUnit, end)
+	}
+	if end-crit.FromUnit >= maxEventUnitRange {
+		return nil, fmt.Errorf("unit range [%d,%d] is too large, at most %d units per query",
+			crit.FromUnit, end, maxEventUnitRange)
+	}
+	logs, err := New(api.backend, crit.FromUnit, end, addrs, crit.EventNames).Events(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ContractEventJson, 0, len(logs))
+	for _, l := range logs {
+		result = append(result, newContractEventJson(l))
+	}
+	return result, nil
+}
+
+// ContractEvents creates a subscription that fires for the contract events of
+// the new stable units which match the given criteria. Unstable units may be
+// reverted, so their events are not notified.
+func (api *PublicFilterAPI) ContractEvents(ctx context.Context, crit ContractEventCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	addrs, err := crit.contractAddrs()
+	if err != nil {
+		return nil, err
+	}
+	if crit.ToUnit != 0 && crit.FromUnit > crit.ToUnit {
+		return nil, fmt.Errorf("invalid unit range [%d,%d]", crit.FromUnit, crit.ToUnit)
+	}
+	filter := New(api.backend, crit.FromUnit, crit.ToUnit, addrs, crit.EventNames)
+	rpcSub := notifier.CreateSubscription()
+
+	// 通知可能阻塞在写连接上，所以单独用一个协程发送，
+	// 客户端处理太慢导致待发送的事件堆积时取消订阅，不阻塞稳定单元事件的发送
+	pending := make(chan *ContractEventJson, eventNotifyChanSize)
+	go func() {
+		for ev := range pending {
+			if err := notifier.Notify(rpcSub.ID, ev); err != nil {
+				log.Debug("Failed to notify contract event", "tx", ev.TxHash, "err", err)
+			}
+		}
+	}()
+
+	go func() {
+		units := make(chan modules.StableUnitEvent, stableUnitChanSize)
+		unitSub := api.backend.SubscribeStableUnitEvent(units)
+		defer unitSub.Unsubscribe()
+		defer close(pending)
+
+		for {
+			select {
+			case ev := <-units:
+				if ev.Unit == nil {
+					continue
+				}
+				index := ev.Unit.NumberU64()
+				if index < crit.FromUnit || (crit.ToUnit != 0 && index > crit.ToUnit) {
+					continue
+				}
+				for _, l := range filter.filterEvents(modules.GetContractEventLogs(ev.Unit)) {
+					select {
+					case pending <- newContractEventJson(l):
+					default:
+						log.Warnf("contract event subscriber %v is too slow, unsubscribe it", rpcSub.ID)
+						return
+					}
+				}
+			case <-unitSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
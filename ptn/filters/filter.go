@@ -17,76 +17,228 @@
 package filters
 
 import (
+	"bytes"
 	"context"
-	//"math/big"
+	"fmt"
 
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/bloombits"
 	"github.com/palletone/go-palletone/common/event"
-	"github.com/palletone/go-palletone/common/ptndb"
-	"github.com/palletone/go-palletone/common/rpc"
+	"github.com/palletone/go-palletone/core/types"
+	"github.com/palletone/go-palletone/dag"
 	"github.com/palletone/go-palletone/dag/modules"
 )
 
+// 一次查询最多返回的合约事件数
+const maxContractEvents = 10000
+
 type Backend interface {
-	ChainDb() ptndb.Database
-	EventMux() *event.TypeMux
-	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*modules.Header, error)
-	//GetReceipts(ctx context.Context, blockHash common.Hash) (modules.Receipts, error)
-	//GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
-	SubscribeTxPreEvent(chan<- modules.TxPreEvent) event.Subscription
-	//SubscribeChainEvent(ch chan<- coredata.ChainEvent) event.Subscription
-	//SubscribeRemovedLogsEvent(ch chan<- coredata.RemovedLogsEvent) event.Subscription
-	//SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	Dag() dag.IDag
+	SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
-// Filter can be used to retrieve and filter logs.
+// Filter can be used to retrieve and filter contract events.
 type Filter struct {
 	backend Backend
 
-	db         ptndb.Database
-	begin, end int64
-	addresses  []common.Address
-	topics     [][]common.Hash
+	begin, end  uint64
+	contractIds [][]byte
+	eventNames  []string
 
 	matcher *bloombits.Matcher
 }
 
-// New creates a new filter which uses a bloom filter on blocks to figure out whether
-// a particular block is interesting or not.
-func New(backend Backend, begin, end int64, addresses []common.Address, topics [][]common.Hash) *Filter {
-	// Flatten the address and topic filter clauses into a single bloombits filter
-	// system. Since the bloombits are not positional, nil topics are permitted,
-	// which get flattened into a nil byte slice.
-	//var filters [][][]byte
+// New creates a new filter which uses a bloom filter on units to figure out
+// whether a particular unit is interesting or not.
+func New(backend Backend, begin, end uint64, contractIds []common.Address, eventNames []string) *Filter {
+	// Flatten the contract and event name clauses into a single bloombits filter
+	// system, an empty clause matches everything.
 	filters := [][][]byte{}
-	if len(addresses) > 0 {
-		filter := make([][]byte, len(addresses))
-		for i, address := range addresses {
-			filter[i] = address.Bytes()
+	f := &Filter{backend: backend, begin: begin, end: end, eventNames: eventNames}
+	if len(contractIds) > 0 {
+		filter := make([][]byte, len(contractIds))
+		for i, id := range contractIds {
+			filter[i] = id.Bytes()
+			f.contractIds = append(f.contractIds, id.Bytes())
 		}
 		filters = append(filters, filter)
 	}
-	for _, topicList := range topics {
-		filter := make([][]byte, len(topicList))
-		for i, topic := range topicList {
-			filter[i] = topic.Bytes()
+	if len(eventNames) > 0 {
+		filter := make([][]byte, len(eventNames))
+		for i, name := range eventNames {
+			filter[i] = []byte(name)
 		}
 		filters = append(filters, filter)
 	}
-	// Assemble and return the filter
 	size, _ := backend.BloomStatus()
+	f.matcher = bloombits.NewMatcher(size, filters)
+	return f
+}
+
+// Events searches the units for matching contract events, returning all the
+// results in one batch.
+func (f *Filter) Events(ctx context.Context) ([]*modules.ContractEventLog, error) {
+	var logs []*modules.ContractEventLog
+
+	size, sections := f.backend.BloomStatus()
+	if indexed := sections * size; indexed > f.begin {
+		end := f.end
+		if indexed-1 < end {
+			end = indexed - 1
+		}
+		found, err := f.indexedEvents(ctx, end)
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, found...)
+	}
+	rest, err := f.unindexedEvents(ctx, f.end, len(logs))
+	logs = append(logs, rest...)
+	return logs, err
+}
+
+// indexedEvents returns the events matching the filter criteria based on the
+// bloom bits indexed available locally.
+func (f *Filter) indexedEvents(ctx context.Context, end uint64) ([]*modules.ContractEventLog, error) {
+	// Create a matcher session and request servicing from the backend
+	matches := make(chan uint64, 64)
+
+	session, err := f.matcher.Start(ctx, f.begin, end, matches)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	f.backend.ServiceFilter(ctx, session)
+
+	var logs []*modules.ContractEventLog
+	for {
+		select {
+		case number, ok := <-matches:
+			// Abort if all matches have been fulfilled
+			if !ok {
+				err := session.Error()
+				if err == nil {
+					f.begin = end + 1
+				}
+				return logs, err
+			}
+			f.begin = number + 1
+
+			found, err := f.checkMatches(number)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			if len(logs) > maxContractEvents {
+				return logs, fmt.Errorf("too many contract events, the limit is %d", maxContractEvents)
+			}
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		}
+	}
+}
+
+// unindexedEvents returns the events matching the filter criteria based on
+// the contract event bloom of each unit.
+func (f *Filter) unindexedEvents(ctx context.Context, end uint64, count int) ([]*modules.ContractEventLog, error) {
+	var logs []*modules.ContractEventLog
 
-	return &Filter{
-		backend:   backend,
-		begin:     begin,
-		end:       end,
-		addresses: addresses,
-		topics:    topics,
-		db:        backend.ChainDb(),
-		matcher:   bloombits.NewMatcher(size, filters),
+	for ; f.begin <= end; f.begin++ {
+		select {
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		default:
+		}
+		bloom, err := f.backend.Dag().GetContractEventBloom(f.begin)
+		if err != nil {
+			return logs, err
+		}
+		if !f.bloomFilter(bloom) {
+			continue
+		}
+		found, err := f.checkMatches(f.begin)
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, found...)
+		if count+len(logs) > maxContractEvents {
+			return logs, fmt.Errorf("too many contract events, the limit is %d", maxContractEvents)
+		}
+	}
+	return logs, nil
+}
+
+// checkMatches checks if the events of the unit match the filter criteria.
+func (f *Filter) checkMatches(unitIndex uint64) ([]*modules.ContractEventLog, error) {
+	logs, err := f.backend.Dag().GetContractEvents(unitIndex)
+	if err != nil {
+		return nil, err
+	}
+	return f.filterEvents(logs), nil
+}
+
+func (f *Filter) bloomFilter(bloom types.Bloom) bool {
+	if len(f.contractIds) > 0 {
+		included := false
+		for _, id := range f.contractIds {
+			if bloom.Test(id) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(f.eventNames) > 0 {
+		included := false
+		for _, name := range f.eventNames {
+			if bloom.Test([]byte(name)) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// filterEvents returns the events matching the contract ids and event names
+func (f *Filter) filterEvents(logs []*modules.ContractEventLog) []*modules.ContractEventLog {
+	var ret []*modules.ContractEventLog
+Logs:
+	for _, l := range logs {
+		if len(f.contractIds) > 0 {
+			found := false
+			for _, id := range f.contractIds {
+				if bytes.Equal(id, l.ContractId) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue Logs
+			}
+		}
+		if len(f.eventNames) > 0 {
+			found := false
+			for _, name := range f.eventNames {
+				if name == l.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue Logs
+			}
+		}
+		ret = append(ret, l)
 	}
+	return ret
 }
@@ -0,0 +1,117 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/bloombits"
+	"github.com/palletone/go-palletone/common/event"
+	"github.com/palletone/go-palletone/core/types"
+	"github.com/palletone/go-palletone/dag"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSectionSize = 8
+
+type testBackend struct {
+	dag      dag.IDag
+	sections uint64
+	gen      *bloombits.Generator
+}
+
+func (b *testBackend) Dag() dag.IDag { return b.dag }
+func (b *testBackend) SubscribeStableUnitEvent(ch chan<- modules.StableUnitEvent) event.Subscription {
+	return nil
+}
+func (b *testBackend) BloomStatus() (uint64, uint64) { return testSectionSize, b.sections }
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	requests := make(chan chan *bloombits.Retrieval)
+	go session.Multiplex(16, 0, requests)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case request := <-requests:
+				task := <-request
+				task.Bitsets = make([][]byte, len(task.Sections))
+				for i := range task.Sections {
+					task.Bitsets[i], task.Error = b.gen.Bitset(task.Bit)
+				}
+				request <- task
+			}
+		}
+	}()
+}
+
+func TestFilter_Events(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	contract1 := common.NewAddress([]byte("contract1-0123456789"), common.ContractHash)
+	contract2 := common.NewAddress([]byte("contract2-0123456789"), common.ContractHash)
+	//单元3和9有contract1的事件，单元5有contract2的事件，单元0-7已经建立索引
+	events := map[uint64][]*modules.ContractEventLog{
+		3: {{ContractId: contract1.Bytes(), Name: "transfer", UnitIndex: 3},
+			{ContractId: contract1.Bytes(), Name: "approve", UnitIndex: 3}},
+		5: {{ContractId: contract2.Bytes(), Name: "transfer", UnitIndex: 5}},
+		9: {{ContractId: contract1.Bytes(), Name: "transfer", UnitIndex: 9}},
+	}
+	mdag := dag.NewMockIDag(mockCtrl)
+	mdag.EXPECT().GetContractEvents(gomock.Any()).DoAndReturn(func(index uint64) (
+		[]*modules.ContractEventLog, error) {
+		return events[index], nil
+	}).AnyTimes()
+	mdag.EXPECT().GetContractEventBloom(gomock.Any()).DoAndReturn(func(index uint64) (types.Bloom, error) {
+		return modules.CreateContractEventBloom(events[index]), nil
+	}).AnyTimes()
+
+	gen, _ := bloombits.NewGenerator(testSectionSize)
+	for i := uint64(0); i < testSectionSize; i++ {
+		gen.AddBloom(uint(i), modules.CreateContractEventBloom(events[i]))
+	}
+	backend := &testBackend{dag: mdag, sections: 1, gen: gen}
+
+	logs, err := New(backend, 0, 10, []common.Address{contract1}, []string{"transfer"}).Events(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, uint64(3), logs[0].UnitIndex)
+	assert.Equal(t, uint64(9), logs[1].UnitIndex)
+
+	logs, err = New(backend, 0, 10, nil, []string{"transfer"}).Events(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(logs))
+
+	logs, err = New(backend, 4, 8, nil, nil).Events(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+	assert.Equal(t, contract2.Bytes(), logs[0].ContractId)
+}
+
+func TestPublicFilterAPI_GetContractEventsRange(t *testing.T) {
+	api := NewPublicFilterAPI(&testBackend{})
+	_, err := api.GetContractEvents(context.Background(), ContractEventCriteria{FromUnit: 10, ToUnit: 5})
+	assert.NotNil(t, err)
+	_, err = api.GetContractEvents(context.Background(),
+		ContractEventCriteria{FromUnit: 1, ToUnit: maxEventUnitRange + 1})
+	assert.NotNil(t, err)
+}
@@ -60,6 +60,10 @@ const (
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
 
+	// maxPoolTxsFetch is the maximum number of outpoints whose transactions are
+	// served in one GetPoolTxsMsg request.
+	maxPoolTxsFetch = 64
+
 	cacheSize    = 10 * 1024 * 1024
 	cacheTimeout = 60 * 5
 )
@@ -316,7 +320,8 @@ func (pm *ProtocolManager) newFetcher() *fetcher.Fetcher {
 		return account, err
 
 	}
-	return fetcher.New(pm.dag.IsHeaderExist, validatorFn, pm.BroadcastUnit, heighter, inserter, pm.removePeer)
+	return fetcher.New(pm.dag.IsHeaderExist, validatorFn, pm.BroadcastUnit, heighter, inserter, pm.removePeer,
+		pm.dag.PostMediatorEquivocationEvent)
 }
 
 func (pm *ProtocolManager) removePeer(id string) {
@@ -751,6 +756,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	case msg.Code == GetLeafNodesMsg:
 		return pm.GetLeafNodesMsg(msg, p)
 
+		// 孤儿交易请求缺少的前驱交易
+	case msg.Code == GetPoolTxsMsg:
+		return pm.GetPoolTxsMsg(msg, p)
+
+	case msg.Code == PoolTxsMsg:
+		return pm.PoolTxsMsg(msg, p)
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -263,6 +263,15 @@ func (p *testTxPool) GetTxFee(tx *modules.Transaction) (*modules.AmountAsset, er
 func (p *testTxPool) ValidateOrphanTx(tx *modules.Transaction) (bool, error) {
 	return false, nil
 }
+func (p *testTxPool) MissingParents(hash common.Hash) []*modules.OutPoint {
+	return nil
+}
+func (p *testTxPool) GetTxPackage(hash common.Hash) []*modules.Transaction {
+	return nil
+}
+func (p *testTxPool) ProcessPackage(txs []*modules.Transaction) error {
+	return nil
+}
 func (p *testTxPool) DiscardTxs(hashs []common.Hash) error {
 	return nil
 }
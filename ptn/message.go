@@ -379,12 +379,71 @@ func (pm *ProtocolManager) TxMsg(msg p2p.Msg, p *peer) error {
 		_, err := pm.txpool.ProcessTransaction(tx, true, true, 0 /*pm.txpool.Tag(peer.ID())*/)
 		if err != nil {
 			log.Infof("the transaction %s not accepteable, err:%s", tx.Hash().String(), err.Error())
+			continue
+		}
+		// 孤儿交易向广播该交易的节点请求缺少的前驱交易
+		if missing := pm.txpool.MissingParents(txHash); len(missing) > 0 {
+			if err := p.RequestPoolTxs(missing); err != nil {
+				log.Debug("Request missing parents failed", "tx", txHash.String(), "err", err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// GetPoolTxsMsg 返回交易池中创建了指定OutPoint的交易，每个交易及其未打包的前驱交易作为一个交易包发送
+func (pm *ProtocolManager) GetPoolTxsMsg(msg p2p.Msg, p *peer) error {
+	var outpoints []*modules.OutPoint
+	if err := msg.Decode(&outpoints); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if len(outpoints) > maxPoolTxsFetch {
+		outpoints = outpoints[:maxPoolTxsFetch]
+	}
+	sent := make(map[common.Hash]bool)
+	for _, outpoint := range outpoints {
+		if outpoint == nil || sent[outpoint.TxHash] {
+			continue
+		}
+		sent[outpoint.TxHash] = true
+		txs := pm.txpool.GetTxPackage(outpoint.TxHash)
+		if len(txs) == 0 {
+			continue
+		}
+		if err := p.SendPoolTxs(txs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PoolTxsMsg 原子地接受对方发来的交易包
+func (pm *ProtocolManager) PoolTxsMsg(msg p2p.Msg, p *peer) error {
+	if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+		return nil
+	}
+	var txs []*modules.Transaction
+	if err := msg.Decode(&txs); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	pkg := make([]*modules.Transaction, 0, len(txs))
+	for i, tx := range txs {
+		if tx == nil {
+			return errResp(ErrDecode, "transaction %d is nil", i)
+		}
+		p.MarkTransaction(tx.Hash())
+		if tx.IsContractTx() && pm.contractProc.IsSystemContractTx(tx) {
+			continue
+		}
+		pkg = append(pkg, tx)
+	}
+	if err := pm.txpool.ProcessPackage(pkg); err != nil {
+		log.Infof("the transaction package from peer %s not accepteable, err:%s", p.id, err.Error())
+	}
+	return nil
+}
+
 func (pm *ProtocolManager) NewBlockMsg(msg p2p.Msg, p *peer) error {
 	// Retrieve and decode the propagated block
 	//unit := &modules.Unit{}
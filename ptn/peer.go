@@ -237,6 +237,21 @@ func (p *peer) SendTransactions(txs modules.Transactions) error {
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// RequestPoolTxs fetches the unpacked transactions which create the given outpoints
+// from the remote peer's transaction pool.
+func (p *peer) RequestPoolTxs(outpoints []*modules.OutPoint) error {
+	log.Debug("Fetching pool transactions", "count", len(outpoints))
+	return p2p.Send(p.rw, GetPoolTxsMsg, outpoints)
+}
+
+// SendPoolTxs sends a package of pool transactions (parents first) to the remote peer.
+func (p *peer) SendPoolTxs(txs modules.Transactions) error {
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, PoolTxsMsg, txs)
+}
+
 func (p *peer) SendContractTransaction(event jury.ContractEvent) error {
 	return p2p.Send(p.rw, ContractMsg, event)
 }
@@ -61,6 +61,8 @@ const (
 	ContractMsg        = 0x0f
 	ElectionMsg        = 0x10
 	AdapterMsg         = 0x11
+	GetPoolTxsMsg      = 0x12
+	PoolTxsMsg         = 0x13
 
 	GetNodeDataMsg = 0x20
 	NodeDataMsg    = 0x21
@@ -161,6 +163,9 @@ type txPool interface {
 	GetTxFee(tx *modules.Transaction) (*modules.AmountAsset, error)
 	OutPointIsSpend(outPoint *modules.OutPoint) (bool, error)
 	ValidateOrphanTx(tx *modules.Transaction) (bool, error)
+	MissingParents(hash common.Hash) []*modules.OutPoint
+	GetTxPackage(hash common.Hash) []*modules.Transaction
+	ProcessPackage(txs []*modules.Transaction) error
 }
 
 // statusData is the network packet for the status message.
@@ -0,0 +1,146 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package ptnjson
+
+import (
+	"strings"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/shopspring/decimal"
+)
+
+type SimulateReadJson struct {
+	ContractAddr string `json:"contract_addr"`
+	Key          string `json:"key"`
+	Version      string `json:"version"` //为空表示读取时Key不存在
+}
+
+type SimulateRangeReadJson struct {
+	ContractAddr string              `json:"contract_addr"`
+	StartKey     string              `json:"start_key"`
+	EndKey       string              `json:"end_key"`
+	Reads        []*SimulateReadJson `json:"reads"`
+}
+
+type SimulateWriteJson struct {
+	ContractAddr string `json:"contract_addr"`
+	Key          string `json:"key"`
+	Value        string `json:"value"`
+	IsDelete     bool   `json:"is_delete"`
+	IsPrivate    bool   `json:"is_private"` //私有状态的Value是明文的Hash
+}
+
+type SimulatePayoutJson struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Asset    string          `json:"asset"`
+	Amount   decimal.Decimal `json:"amount"`
+	LockTime uint32          `json:"lock_time"`
+}
+
+type SimulateEventJson struct {
+	ContractAddr string `json:"contract_addr"`
+	EventName    string `json:"event_name"`
+	Payload      string `json:"payload"`
+}
+
+// ContractSimulateJson 合约调用的模拟执行结果
+type ContractSimulateJson struct {
+	ContractAddr string                   `json:"contract_addr"`
+	Payload      string                   `json:"payload"`
+	ReadSet      []*SimulateReadJson      `json:"read_set"`
+	RangeReadSet []*SimulateRangeReadJson `json:"range_read_set"`
+	WriteSet     []*SimulateWriteJson     `json:"write_set"`
+	TokenPayOut  []*SimulatePayoutJson    `json:"token_payout"`
+	Events       []*SimulateEventJson     `json:"events"`
+	Callees      []string                 `json:"callees"`
+	GasUsed      uint64                   `json:"gas_used"`
+	ErrorCode    uint32                   `json:"error_code"`
+	ErrorMessage string                   `json:"error_message"`
+	Fee          decimal.Decimal          `json:"fee"`
+	TxSize       float64                  `json:"tx_size"`
+	Trace        []*modules.ShimCallTrace `json:"trace,omitempty"`
+}
+
+func convertSimulateRead2Json(read *modules.ContractReadSet) *SimulateReadJson {
+	rjson := &SimulateReadJson{ContractAddr: contractId2AddrString(read.ContractId), Key: read.Key}
+	if read.Version != nil {
+		rjson.Version = read.Version.String()
+	}
+	return rjson
+}
+
+func ConvertContractSimulate2Json(contractAddr string, simulate *modules.ContractSimulateResult) *ContractSimulateJson {
+	sjson := &ContractSimulateJson{
+		ContractAddr: contractAddr,
+		ReadSet:      []*SimulateReadJson{},
+		RangeReadSet: []*SimulateRangeReadJson{},
+		WriteSet:     []*SimulateWriteJson{},
+		TokenPayOut:  []*SimulatePayoutJson{},
+		Events:       []*SimulateEventJson{},
+		Callees:      []string{},
+		ErrorCode:    simulate.ErrMsg.Code,
+		ErrorMessage: simulate.ErrMsg.Message,
+		Fee:          Dao2Ptn(uint64(simulate.Fee) + 1),
+		TxSize:       simulate.Size,
+		Trace:        simulate.Trace,
+	}
+	result := simulate.Result
+	if result == nil {
+		return sjson
+	}
+	sjson.Payload = string(result.Payload)
+	sjson.GasUsed = result.GasUsed
+	for i := range result.ReadSet {
+		sjson.ReadSet = append(sjson.ReadSet, convertSimulateRead2Json(&result.ReadSet[i]))
+	}
+	for _, rr := range result.RangeReadSet {
+		rrjson := &SimulateRangeReadJson{ContractAddr: contractId2AddrString(rr.ContractId),
+			StartKey: rr.StartKey, EndKey: rr.EndKey, Reads: []*SimulateReadJson{}}
+		for i := range rr.Reads {
+			rrjson.Reads = append(rrjson.Reads, convertSimulateRead2Json(&rr.Reads[i]))
+		}
+		sjson.RangeReadSet = append(sjson.RangeReadSet, rrjson)
+	}
+	for _, w := range result.WriteSet {
+		//私有状态显示合约中使用的Key
+		key := strings.TrimPrefix(w.Key, modules.PrivateStateKeyPrefix)
+		sjson.WriteSet = append(sjson.WriteSet, &SimulateWriteJson{ContractAddr: contractId2AddrString(w.ContractId),
+			Key: key, Value: string(w.Value), IsDelete: w.IsDelete, IsPrivate: w.IsPrivate})
+	}
+	for _, pay := range result.TokenPayOut {
+		from := contractAddr
+		if pay.PayFrom != (common.Address{}) {
+			from = pay.PayFrom.String()
+		}
+		sjson.TokenPayOut = append(sjson.TokenPayOut, &SimulatePayoutJson{From: from, To: pay.PayTo.String(),
+			Asset: pay.Asset.String(), Amount: pay.Asset.DisplayAmount(pay.Amount), LockTime: pay.LockTime})
+	}
+	for _, ev := range result.Events {
+		sjson.Events = append(sjson.Events, &SimulateEventJson{ContractAddr: contractId2AddrString(ev.ContractId),
+			EventName: ev.Name, Payload: string(ev.Payload)})
+	}
+	for _, callee := range result.Callees {
+		sjson.Callees = append(sjson.Callees, callee.String())
+	}
+	return sjson
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package ptnjson
+
+import (
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/hexutil"
+	"github.com/palletone/go-palletone/tokenengine"
+)
+
+type PartialSignedTxJson struct {
+	Version  uint32                    `json:"version"`
+	TxHash   string                    `json:"tx_hash"`
+	Complete bool                      `json:"complete"`
+	Inputs   []*PartialSignedInputJson `json:"inputs"`
+}
+
+type PartialSignedInputJson struct {
+	MessageIndex       uint32   `json:"message_index"`
+	InputIndex         uint32   `json:"input_index"`
+	HashType           uint32   `json:"hash_type"`
+	LockScriptHex      string   `json:"lock_script_hex"`
+	LockScriptString   string   `json:"lock_script_string"`
+	RedeemScriptHex    string   `json:"redeem_script_hex"`
+	RedeemScriptString string   `json:"redeem_script_string"`
+	RequiredSigns      uint32   `json:"required_signs"`
+	Signers            []string `json:"signers"`   //可以签名的地址
+	SignedBy           []string `json:"signed_by"` //已经签名的地址
+	Complete           bool     `json:"complete"`
+}
+
+func ConvertPartialSignedTx2Json(pst *tokenengine.PartialSignedTx) *PartialSignedTxJson {
+	json := &PartialSignedTxJson{
+		Version:  pst.Version,
+		TxHash:   pst.Tx.Hash().String(),
+		Complete: pst.IsComplete(),
+	}
+	for _, psi := range pst.Inputs {
+		lockStr, _ := tokenengine.Instance.DisasmString(psi.LockScript)
+		in := &PartialSignedInputJson{
+			MessageIndex:     psi.MessageIndex,
+			InputIndex:       psi.InputIndex,
+			HashType:         psi.HashType,
+			LockScriptHex:    hexutil.Encode(psi.LockScript),
+			LockScriptString: lockStr,
+			RequiredSigns:    psi.RequiredSigns,
+			Complete:         psi.IsComplete(),
+		}
+		if len(psi.RedeemScript) > 0 {
+			in.RedeemScriptHex = hexutil.Encode(psi.RedeemScript)
+			in.RedeemScriptString, _ = tokenengine.Instance.DisasmString(psi.RedeemScript)
+		}
+		for _, addr := range psi.Signers {
+			in.Signers = append(in.Signers, addr.String())
+		}
+		for _, sig := range psi.Signatures {
+			in.SignedBy = append(in.SignedBy, crypto.PubkeyBytesToAddress(sig.PubKey).String())
+		}
+		json.Inputs = append(json.Inputs, in)
+	}
+	return json
+}
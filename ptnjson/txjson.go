@@ -49,6 +49,9 @@ type TxJson struct {
 	DeployRequest      *DeployRequestJson  `json:"deploy_request"`
 	InvokeRequest      *InvokeRequestJson  `json:"invoke_request"`
 	StopRequest        *StopRequestJson    `json:"stop_request"`
+	Upgrade            *UpgradeJson        `json:"contract_upgrade"`
+	UpgradeRequest     *UpgradeRequestJson `json:"upgrade_request"`
+	JuryUpdate         *JuryUpdateJson     `json:"contract_jury_update"`
 }
 type TxWithUnitInfoJson struct {
 	*TxJson
@@ -95,6 +98,23 @@ type StopJson struct {
 	ErrorCode    uint32 `json:"error_code"`
 	ErrorMessage string `json:"error_message"`
 }
+type UpgradeJson struct {
+	Number       int    `json:"row_number"`
+	ContractId   string `json:"contract_id"`
+	TemplateId   string `json:"tpl_id"`
+	ReadSet      string `json:"read_set"`
+	WriteSet     string `json:"write_set"`
+	ErrorCode    uint32 `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+type JuryUpdateJson struct {
+	Number     int      `json:"row_number"`
+	ContractId string   `json:"contract_id"`
+	PrevJury   string   `json:"prev_jury"`
+	SeedUnit   string   `json:"seed_unit"`
+	EleNode    string   `json:"election_node"`
+	Signatures []string `json:"signature_set"`
+}
 type SignatureJson struct {
 	Number     int      `json:"row_number"`
 	Signatures []string `json:"signature_set"` // the array of signature
@@ -131,6 +151,14 @@ type StopRequestJson struct {
 	ContractId  string `json:"contract_id"`
 	DeleteImage bool   `json:"delete_image"`
 }
+type UpgradeRequestJson struct {
+	Number     int           `json:"row_number"`
+	ContractId string        `json:"contract_id"`
+	TplId      string        `json:"tpl_id"`
+	Args       []string      `json:"arg_set"`
+	Timeout    time.Duration `json:"timeout"`
+	Signatures []string      `json:"signature_set"`
+}
 type DataJson struct {
 	Number    int    `json:"row_number"`
 	MainData  string `json:"main_data"`
@@ -199,6 +227,10 @@ func ConvertTx2FullJson(tx *modules.Transaction,
 			req := m.Payload.(*modules.ContractStopRequestPayload)
 			txjson.StopRequest = convertStopRequest2Json(req)
 			txjson.StopRequest.Number = i
+		} else if m.App == modules.APP_CONTRACT_UPGRADE_REQUEST {
+			req := m.Payload.(*modules.ContractUpgradeRequestPayload)
+			txjson.UpgradeRequest = convertUpgradeRequest2Json(req)
+			txjson.UpgradeRequest.Number = i
 		} else if m.App == modules.APP_CONTRACT_TPL {
 			tpl := m.Payload.(*modules.ContractTplPayload)
 			txjson.ContractTpl = convertTpl2Json(tpl)
@@ -215,6 +247,14 @@ func ConvertTx2FullJson(tx *modules.Transaction,
 			stop := m.Payload.(*modules.ContractStopPayload)
 			txjson.Stop = convertStop2Json(stop)
 			txjson.Stop.Number = i
+		} else if m.App == modules.APP_CONTRACT_UPGRADE {
+			upgrade := m.Payload.(*modules.ContractUpgradePayload)
+			txjson.Upgrade = convertUpgrade2Json(upgrade)
+			txjson.Upgrade.Number = i
+		} else if m.App == modules.APP_CONTRACT_JURY_UPDATE {
+			update := m.Payload.(*modules.ContractJuryUpdatePayload)
+			txjson.JuryUpdate = convertJuryUpdate2Json(update)
+			txjson.JuryUpdate.Number = i
 		} else if m.App == modules.APP_SIGNATURE {
 			sig := m.Payload.(*modules.SignaturePayload)
 			txjson.Signature = convertSig2Json(sig)
@@ -322,6 +362,30 @@ func convertStop2Json(stop *modules.ContractStopPayload) *StopJson {
 	sjson.ErrorMessage = stop.ErrMsg.Message
 	return sjson
 }
+func convertUpgrade2Json(upgrade *modules.ContractUpgradePayload) *UpgradeJson {
+	ujson := new(UpgradeJson)
+	ujson.ContractId = contractId2AddrString(upgrade.ContractId)
+	ujson.TemplateId = hex.EncodeToString(upgrade.TemplateId)
+	rset, _ := json.Marshal(upgrade.ReadSet)
+	ujson.ReadSet = string(rset)
+	wset, _ := json.Marshal(upgrade.WriteSet)
+	ujson.WriteSet = string(wset)
+	ujson.ErrorCode = upgrade.ErrMsg.Code
+	ujson.ErrorMessage = upgrade.ErrMsg.Message
+	return ujson
+}
+func convertJuryUpdate2Json(update *modules.ContractJuryUpdatePayload) *JuryUpdateJson {
+	ujson := new(JuryUpdateJson)
+	ujson.ContractId = contractId2AddrString(update.ContractId)
+	ujson.PrevJury = update.PrevJury.String()
+	ujson.SeedUnit = update.SeedUnit.String()
+	ele, _ := json.Marshal(update.EleNode)
+	ujson.EleNode = string(ele)
+	for _, sig := range update.Signatures {
+		ujson.Signatures = append(ujson.Signatures, fmt.Sprintf("pubkey:%x,signature:%x", sig.PubKey, sig.Signature))
+	}
+	return ujson
+}
 func convertSig2Json(sig *modules.SignaturePayload) *SignatureJson {
 	sigjson := new(SignatureJson)
 	for _, sig := range sig.Signatures {
@@ -380,6 +444,20 @@ func convertStopRequest2Json(req *modules.ContractStopRequestPayload) *StopReque
 
 	return reqJson
 }
+func convertUpgradeRequest2Json(req *modules.ContractUpgradeRequestPayload) *UpgradeRequestJson {
+	reqJson := &UpgradeRequestJson{}
+	reqJson.ContractId = contractId2AddrString(req.ContractId)
+	reqJson.TplId = hex.EncodeToString(req.TemplateId)
+	reqJson.Args = []string{}
+	for _, arg := range req.Args {
+		reqJson.Args = append(reqJson.Args, string(arg))
+	}
+	reqJson.Timeout = time.Duration(req.Timeout) * time.Second
+	for _, sig := range req.Signatures {
+		reqJson.Signatures = append(reqJson.Signatures, fmt.Sprintf("pubkey:%x,signature:%x", sig.PubKey, sig.Signature))
+	}
+	return reqJson
+}
 func convertAccountState2Json(accountState *modules.AccountStateUpdatePayload) *AccountStateJson {
 	jsonAcc := &AccountStateJson{}
 	writeSet, _ := json.Marshal(accountState.WriteSet)
@@ -55,6 +55,27 @@ type TokenEngine struct {
 
 var Instance ITokenEngine = &TokenEngine{signCache: txscript.NewSigCache(20000)}
 
+var txInputSigner TxInputSigner
+
+//SetTxInputSigner 设置需要完整交易才能签名的签名者，节点启动时设置
+func SetTxInputSigner(signer TxInputSigner) {
+	txInputSigner = signer
+}
+
+//CalcSignatureData 计算交易中指定Input需要签名的数据，签名者自己根据完整交易计算
+func CalcSignatureData(tx *modules.Transaction, hashType uint32, msgIdx, inputIdx int,
+	lockOrRedeemScript []byte) ([]byte, error) {
+	msgs := tx.TxMessages()
+	if msgIdx < 0 || msgIdx >= len(msgs) {
+		return nil, fmt.Errorf("invalid message index %d", msgIdx)
+	}
+	pay, ok := msgs[msgIdx].Payload.(*modules.PaymentPayload)
+	if !ok || inputIdx < 0 || inputIdx >= len(pay.Inputs) {
+		return nil, fmt.Errorf("invalid input index %d of message %d", inputIdx, msgIdx)
+	}
+	return txscript.CalcSignatureData(lockOrRedeemScript, txscript.SigHashType(hashType), tx, msgIdx, inputIdx)
+}
+
 //Generate a P2PKH lock script, just only need input 20bytes public key hash.
 //You can use Address.Bytes() to get address hash.
 func (engine *TokenEngine) GenerateP2PKHLockScript(pubKeyHash []byte) []byte {
@@ -295,6 +316,13 @@ func (engine *TokenEngine) GetScriptSigners(tx *modules.Transaction, msgIdx, inp
 	script := tx.Messages()[msgIdx].Payload.(*modules.PaymentPayload).Inputs[inputIndex].SignatureScript
 	scriptStr, _ := txscript.DisasmString(common.CopyBytes(script))
 	ops := strings.Fields(scriptStr)
+	if len(ops) > 2 {
+		//Taproot script path: sig1 sig2 ... leafScript controlBlock，去掉controlBlock后leafScript就是赎回脚本
+		last, _ := hex.DecodeString(ops[len(ops)-1])
+		if txscript.IsTaprootControlBlock(last) {
+			ops = ops[:len(ops)-1]
+		}
+	}
 	for i, op := range ops {
 		if op == "0" {
 			continue
@@ -318,12 +346,13 @@ func (engine *TokenEngine) GetScriptSigners(tx *modules.Transaction, msgIdx, inp
 		}
 	}
 	acc := &account{}
-	hash, _ := engine.CalcSignatureHash(tx, uint32(hashType), msgIdx, inputIndex, redeem)
+	//签名时对签名数据进行签名，而不是Hash
+	data, _ := txscript.CalcSignatureData(redeem, txscript.SigHashType(hashType), tx, msgIdx, inputIndex)
 	//根据签名，找到对应的pubkey
 	result := []common.Address{}
 	for _, sign := range signatures {
 		for _, pubkey := range pubkeys {
-			if pass, _ := acc.Verify(pubkey, sign, hash); pass {
+			if pass, _ := acc.Verify(pubkey, sign, data); pass {
 				addr := crypto.PubkeyBytesToAddress(pubkey)
 				result = append(result, addr)
 			}
@@ -375,6 +404,16 @@ func (a *account) Sign(address common.Address, msg []byte) ([]byte, error) {
 	//log.Debugf("Sign Addr:%s,Signature:%x,msg:%x", address.String(), signature, msg)
 	return signature, err
 }
+//SignTxInput 设置了需要完整交易的签名者时，由其对Input签名
+func (a *account) SignTxInput(address common.Address, tx *modules.Transaction, hashType txscript.SigHashType,
+	msgIdx, idx int, subScript []byte) ([]byte, bool, error) {
+	signer := txInputSigner
+	if signer == nil || !signer.IsTxInputSigner(address) {
+		return nil, false, nil
+	}
+	signature, err := signer.SignTxInput(address, tx, uint32(hashType), msgIdx, idx, subScript)
+	return signature, true, err
+}
 func (a *account) Verify(pubKey, signature, msg []byte) (bool, error) {
 	//log.Debugf("Verify Pubkey:%x,Signature:%x,msg:%x", pubKey, signature, msg)
 	return crypto.MyCryptoLib.Verify(pubKey, signature, msg)
@@ -479,3 +518,101 @@ func (engine *TokenEngine) MergeContractUnlockScript(signs [][]byte, redeemScrip
 	result, _ := builder.Script()
 	return result
 }
+
+//计算多个参与者的聚合Schnorr公钥，公钥顺序不影响结果
+func (engine *TokenEngine) AggregateSchnorrPubKeys(pubKeys [][]byte) ([]byte, error) {
+	return txscript.AggregateSchnorrPubKeys(pubKeys)
+}
+
+//根据内部公钥和备选脚本列表，计算Taproot的调整值tweak和输出公钥
+func TaprootOutputPubKey(internalPubKey []byte, leafScripts [][]byte) (tweak []byte, outputPubKey []byte, err error) {
+	merkleRoot, _ := txscript.TaprootMerkleRoot(leafScripts, 0)
+	return txscript.TaprootTweak(internalPubKey, merkleRoot)
+}
+
+//生成Taproot锁定脚本，leafScripts为空时只能通过Key path解锁
+//Generate a taproot lock script: <hash160(outputPubKey)> OP_CHECKTAPROOT
+func (engine *TokenEngine) GenerateTaprootLockScript(internalPubKey []byte, leafScripts [][]byte) ([]byte, error) {
+	_, outputPubKey, err := TaprootOutputPubKey(internalPubKey, leafScripts)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.NewScriptBuilder().AddData(crypto.Hash160(outputPubKey)).
+		AddOp(txscript.OP_CHECKTAPROOT).Script()
+}
+
+func (engine *TokenEngine) checkTaprootLockScript(utxoLockScript []byte,
+	internalPubKey []byte, leafScripts [][]byte) error {
+	lockScript, err := engine.GenerateTaprootLockScript(internalPubKey, leafScripts)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(lockScript, utxoLockScript) {
+		return errors.New("internal public key and leaf scripts not match utxo lock script")
+	}
+	return nil
+}
+
+//Key path签名，解锁脚本为：<schnorrSig||hashType> <outputPubKey>
+func (engine *TokenEngine) SignTaprootKeyPath(tx *modules.Transaction, hashType uint32, msgIdx, id int,
+	utxoLockScript []byte, internalPubKey []byte, leafScripts [][]byte,
+	signFn SchnorrGetSign) ([]byte, error) {
+	if err := engine.checkTaprootLockScript(utxoLockScript, internalPubKey, leafScripts); err != nil {
+		return nil, err
+	}
+	tweak, outputPubKey, err := TaprootOutputPubKey(internalPubKey, leafScripts)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := engine.CalcSignatureHash(tx, hashType, msgIdx, id, utxoLockScript)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := signFn(hash, tweak)
+	if err != nil {
+		return nil, err
+	}
+	if !txscript.SchnorrVerify(outputPubKey, sign, hash) {
+		return nil, errors.New("invalid schnorr signature for taproot output public key")
+	}
+	return txscript.NewScriptBuilder().AddData(append(sign, byte(hashType))).
+		AddData(outputPubKey).Script()
+}
+
+//Script path签名，解锁脚本为：<leaf script的解锁参数...> <leafScript> <controlBlock>
+func (engine *TokenEngine) SignTaprootScriptPath(tx *modules.Transaction, hashType uint32, msgIdx, id int,
+	utxoLockScript []byte, internalPubKey []byte, leafScripts [][]byte, leafIndex int,
+	pubKeyFn AddressGetPubKey, signFn AddressGetSign, previousScript []byte) ([]byte, error) {
+	if err := engine.checkTaprootLockScript(utxoLockScript, internalPubKey, leafScripts); err != nil {
+		return nil, err
+	}
+	controlBlock, err := txscript.TaprootControlBlock(internalPubKey, leafScripts, leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	tail, _ := txscript.NewScriptBuilder().AddData(leafScripts[leafIndex]).AddData(controlBlock).Script()
+	lookupScript := func(a common.Address) ([]byte, error) {
+		return tail, nil
+	}
+	tmpAcc := &account{pubKeyFn: pubKeyFn, signFn: signFn}
+	return txscript.SignTxOutput(tx, msgIdx, id, utxoLockScript, txscript.SigHashType(hashType),
+		tmpAcc, txscript.ScriptClosure(lookupScript), previousScript)
+}
+
+//多方聚合Schnorr签名的会话，用于Key path的多签
+type MuSigSession = txscript.MuSigSession
+
+//生成MuSig2签名用的两个随机数，secNonce只能使用一次
+func NewMuSigNonce() (secNonce []byte, pubNonce []byte, err error) {
+	return txscript.NewMuSigNonce()
+}
+
+//使用私钥（经过tweak调整）对Hash进行Schnorr签名，可用于单个内部公钥的Key path签名
+func SchnorrSign(privKey, tweak, hash []byte) ([]byte, error) {
+	return txscript.SchnorrSign(privKey, tweak, hash)
+}
+
+//验证Schnorr签名
+func SchnorrVerify(pubKey, signature, hash []byte) bool {
+	return txscript.SchnorrVerify(pubKey, signature, hash)
+}
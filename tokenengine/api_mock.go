@@ -265,3 +265,63 @@ func (mr *MockITokenEngineMockRecorder) ScriptValidate1Msg(utxoLockScripts, pick
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScriptValidate1Msg", reflect.TypeOf((*MockITokenEngine)(nil).ScriptValidate1Msg), utxoLockScripts, pickupJuryRedeemScript, tx, msgIdx)
 }
+
+// AggregateSchnorrPubKeys mocks base method
+func (m *MockITokenEngine) AggregateSchnorrPubKeys(pubKeys [][]byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AggregateSchnorrPubKeys", pubKeys)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AggregateSchnorrPubKeys indicates an expected call of AggregateSchnorrPubKeys
+func (mr *MockITokenEngineMockRecorder) AggregateSchnorrPubKeys(pubKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AggregateSchnorrPubKeys", reflect.TypeOf((*MockITokenEngine)(nil).AggregateSchnorrPubKeys), pubKeys)
+}
+
+// GenerateTaprootLockScript mocks base method
+func (m *MockITokenEngine) GenerateTaprootLockScript(internalPubKey []byte, leafScripts [][]byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateTaprootLockScript", internalPubKey, leafScripts)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateTaprootLockScript indicates an expected call of GenerateTaprootLockScript
+func (mr *MockITokenEngineMockRecorder) GenerateTaprootLockScript(internalPubKey, leafScripts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateTaprootLockScript", reflect.TypeOf((*MockITokenEngine)(nil).GenerateTaprootLockScript), internalPubKey, leafScripts)
+}
+
+// SignTaprootKeyPath mocks base method
+func (m *MockITokenEngine) SignTaprootKeyPath(tx *modules.Transaction, hashType uint32, msgIdx, id int, utxoLockScript, internalPubKey []byte, leafScripts [][]byte, signFunc SchnorrGetSign) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignTaprootKeyPath", tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, signFunc)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignTaprootKeyPath indicates an expected call of SignTaprootKeyPath
+func (mr *MockITokenEngineMockRecorder) SignTaprootKeyPath(tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, signFunc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignTaprootKeyPath", reflect.TypeOf((*MockITokenEngine)(nil).SignTaprootKeyPath), tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, signFunc)
+}
+
+// SignTaprootScriptPath mocks base method
+func (m *MockITokenEngine) SignTaprootScriptPath(tx *modules.Transaction, hashType uint32, msgIdx, id int, utxoLockScript, internalPubKey []byte, leafScripts [][]byte, leafIndex int, pubKeyFn AddressGetPubKey, signFunc AddressGetSign, previousScript []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignTaprootScriptPath", tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, leafIndex, pubKeyFn, signFunc, previousScript)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignTaprootScriptPath indicates an expected call of SignTaprootScriptPath
+func (mr *MockITokenEngineMockRecorder) SignTaprootScriptPath(tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, leafIndex, pubKeyFn, signFunc, previousScript interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignTaprootScriptPath", reflect.TypeOf((*MockITokenEngine)(nil).SignTaprootScriptPath), tx, hashType, msgIdx, id, utxoLockScript, internalPubKey, leafScripts, leafIndex, pubKeyFn, signFunc, previousScript)
+}
@@ -975,3 +975,162 @@ func TestSign1Msg(t *testing.T) {
 	data, _ = json.Marshal(rawTx)
 	log.Debugf("Signed tx:%s", string(data))
 }
+
+func buildTaprootTx() *modules.Transaction {
+	payment := &modules.PaymentPayload{}
+	utxoTxId := common.HexToHash("1111870aa8c894376dbd960a22171d0ad7be057a730e14d7103ed4a6dbb34873")
+	payment.AddTxIn(modules.NewTxIn(modules.NewOutPoint(utxoTxId, 0, 0), []byte{}))
+	p1lockScript := Instance.GenerateP2PKHLockScript(crypto.Hash160(pubKey1B))
+	payment.AddTxOut(modules.NewTxOut(1, p1lockScript, &modules.Asset{}))
+	return modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, payment)})
+}
+
+//3个用户的聚合公钥作为内部公钥，备选脚本为2/3多签和用户1单签
+func buildTaprootAddress(t *testing.T) ([]byte, []byte, [][]byte) {
+	internalPubKey, err := Instance.AggregateSchnorrPubKeys([][]byte{pubKey1B, pubKey2B, pubKey3B})
+	assert.Nil(t, err)
+	leaf0 := Instance.GenerateRedeemScript(2, [][]byte{pubKey1B, pubKey2B, pubKey3B})
+	leaf1 := Instance.GenerateRedeemScript(1, [][]byte{pubKey1B})
+	leafScripts := [][]byte{leaf0, leaf1}
+	lockScript, err := Instance.GenerateTaprootLockScript(internalPubKey, leafScripts)
+	assert.Nil(t, err)
+	return lockScript, internalPubKey, leafScripts
+}
+
+func TestTaprootKeyPath(t *testing.T) {
+	lockScript, internalPubKey, leafScripts := buildTaprootAddress(t)
+	addr, err := Instance.GetAddressFromScript(lockScript)
+	assert.Nil(t, err)
+	t.Logf("Taproot address:%s", addr.String())
+	assert.Equal(t, common.TaprootHash, addr.GetType())
+	assert.Equal(t, "PT", addr.String()[0:2])
+	assert.Equal(t, lockScript, Instance.GenerateLockScript(addr))
+
+	tx := buildTaprootTx()
+	prvKeys := [][]byte{prvKey1B, prvKey2B, prvKey3B}
+	pubKeys := [][]byte{pubKey1B, pubKey2B, pubKey3B}
+	//3个用户通过MuSig协议共同签名
+	musigSignFn := func(hash []byte, tweak []byte) ([]byte, error) {
+		session := &MuSigSession{PubKeys: pubKeys, Tweak: tweak, Hash: hash}
+		secNonces := make([][]byte, len(prvKeys))
+		pubNonces := make([][]byte, len(prvKeys))
+		for i := range prvKeys {
+			secNonces[i], pubNonces[i], err = NewMuSigNonce()
+			if err != nil {
+				return nil, err
+			}
+		}
+		partialSigs := make([][]byte, len(prvKeys))
+		for i, prvKey := range prvKeys {
+			partialSigs[i], err = session.PartialSign(prvKey, secNonces[i], pubNonces)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return session.Combine(pubNonces, partialSigs)
+	}
+	unlock, err := Instance.SignTaprootKeyPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts, musigSignFn)
+	assert.Nil(t, err)
+	str, _ := txscript.DisasmString(unlock)
+	t.Logf("Key path unlock script:%s", str)
+	tx.Messages()[0].Payload.(*modules.PaymentPayload).Inputs[0].SignatureScript = unlock
+	err = Instance.ScriptValidate(lockScript, nil, tx, 0, 0)
+	assert.Nil(t, err)
+
+	//自己的随机数不在会话中时拒绝签名，避免用不同的随机数组合对同一个secNonce签名两次
+	session := &MuSigSession{PubKeys: pubKeys, Hash: []byte("musig hash")}
+	secNonce, pubNonce, err := NewMuSigNonce()
+	assert.Nil(t, err)
+	_, otherNonce1, _ := NewMuSigNonce()
+	_, otherNonce2, _ := NewMuSigNonce()
+	_, otherNonce3, _ := NewMuSigNonce()
+	_, err = session.PartialSign(prvKey1B, secNonce, [][]byte{otherNonce1, otherNonce2, otherNonce3})
+	assert.NotNil(t, err)
+	_, err = session.PartialSign(prvKey1B, secNonce, [][]byte{pubNonce, otherNonce2, otherNonce3})
+	assert.Nil(t, err)
+	_, err = session.PartialSign(prvKey1B, secNonce[:32], [][]byte{pubNonce[:33], otherNonce2, otherNonce3})
+	assert.NotNil(t, err)
+
+	//只有部分用户签名，无法生成有效的聚合签名
+	partialSignFn := func(hash []byte, tweak []byte) ([]byte, error) {
+		return SchnorrSign(prvKey1B, tweak, hash)
+	}
+	_, err = Instance.SignTaprootKeyPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts, partialSignFn)
+	assert.NotNil(t, err)
+
+	//单个内部公钥
+	single, err := Instance.GenerateTaprootLockScript(pubKey4B, nil)
+	assert.Nil(t, err)
+	tx = buildTaprootTx()
+	singleSignFn := func(hash []byte, tweak []byte) ([]byte, error) {
+		return SchnorrSign(prvKey4B, tweak, hash)
+	}
+	unlock, err = Instance.SignTaprootKeyPath(tx, SigHashAll, 0, 0, single, pubKey4B, nil, singleSignFn)
+	assert.Nil(t, err)
+	tx.Messages()[0].Payload.(*modules.PaymentPayload).Inputs[0].SignatureScript = unlock
+	assert.Nil(t, Instance.ScriptValidate(single, nil, tx, 0, 0))
+	//修改交易后签名失效
+	tx.Messages()[0].Payload.(*modules.PaymentPayload).Outputs[0].Value = 2
+	assert.NotNil(t, Instance.ScriptValidate(single, nil, tx, 0, 0))
+}
+
+func TestTaprootScriptPath(t *testing.T) {
+	lockScript, internalPubKey, leafScripts := buildTaprootAddress(t)
+	tx := buildTaprootTx()
+	getPubKeyFn := func(addr common.Address) ([]byte, error) {
+		if addr == address1 {
+			return pubKey1B, nil
+		}
+		if addr == address2 {
+			return pubKey2B, nil
+		}
+		return nil, errors.New("not found")
+	}
+	getSign1Fn := func(addr common.Address, msg []byte) ([]byte, error) {
+		if addr == address1 {
+			return crypto.MyCryptoLib.Sign(prvKey1B, msg)
+		}
+		return nil, errors.New("not found")
+	}
+	getSign2Fn := func(addr common.Address, msg []byte) ([]byte, error) {
+		if addr == address2 {
+			return crypto.MyCryptoLib.Sign(prvKey2B, msg)
+		}
+		return nil, errors.New("not found")
+	}
+	//用户1和用户2分两步对2/3多签的备选脚本签名
+	sign1, err := Instance.SignTaprootScriptPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts, 0,
+		getPubKeyFn, getSign1Fn, nil)
+	assert.Nil(t, err)
+	pay := tx.Messages()[0].Payload.(*modules.PaymentPayload)
+	pay.Inputs[0].SignatureScript = sign1
+	assert.NotNil(t, Instance.ScriptValidate(lockScript, nil, tx, 0, 0))
+
+	sign12, err := Instance.SignTaprootScriptPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts, 0,
+		getPubKeyFn, getSign2Fn, sign1)
+	assert.Nil(t, err)
+	str, _ := txscript.DisasmString(sign12)
+	t.Logf("Script path unlock script:%s", str)
+	pay.Inputs[0].SignatureScript = sign12
+	err = Instance.ScriptValidate(lockScript, nil, tx, 0, 0)
+	assert.Nil(t, err, fmt.Sprintf("validate error:%s", err))
+
+	signers, err := Instance.GetScriptSigners(tx, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(signers))
+	assert.Contains(t, signers, address1)
+	assert.Contains(t, signers, address2)
+
+	//使用用户1单签的备选脚本
+	tx = buildTaprootTx()
+	sign, err := Instance.SignTaprootScriptPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts, 1,
+		getPubKeyFn, getSign1Fn, nil)
+	assert.Nil(t, err)
+	tx.Messages()[0].Payload.(*modules.PaymentPayload).Inputs[0].SignatureScript = sign
+	assert.Nil(t, Instance.ScriptValidate(lockScript, nil, tx, 0, 0))
+
+	//错误的备选脚本列表
+	_, err = Instance.SignTaprootScriptPath(tx, SigHashAll, 0, 0, lockScript, internalPubKey, leafScripts[:1], 0,
+		getPubKeyFn, getSign1Fn, nil)
+	assert.NotNil(t, err)
+}
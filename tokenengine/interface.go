@@ -49,4 +49,16 @@ type ITokenEngine interface {
 	ScriptValidate1Msg(utxoLockScripts map[string][]byte,
 		pickupJuryRedeemScript PickupJuryRedeemScript,
 		tx *modules.Transaction, msgIdx int) error
+	//根据多个参与者的公钥，计算聚合后的Schnorr公钥，可作为Taproot的内部公钥
+	AggregateSchnorrPubKeys(pubKeys [][]byte) ([]byte, error)
+	//根据内部公钥和备选脚本列表，生成Taproot锁定脚本
+	GenerateTaprootLockScript(internalPubKey []byte, leafScripts [][]byte) ([]byte, error)
+	//使用内部公钥对应的私钥（经过Tweak调整），对Taproot的input进行Key path签名
+	SignTaprootKeyPath(tx *modules.Transaction, hashType uint32, msgIdx, id int,
+		utxoLockScript []byte, internalPubKey []byte, leafScripts [][]byte,
+		signFunc SchnorrGetSign) ([]byte, error)
+	//使用第leafIndex个备选脚本，对Taproot的input进行Script path签名，如果已经有别人签名，则合并
+	SignTaprootScriptPath(tx *modules.Transaction, hashType uint32, msgIdx, id int,
+		utxoLockScript []byte, internalPubKey []byte, leafScripts [][]byte, leafIndex int,
+		pubKeyFn AddressGetPubKey, signFunc AddressGetSign, previousScript []byte) ([]byte, error)
 }
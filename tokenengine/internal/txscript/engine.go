@@ -63,6 +63,11 @@ const (
 	// ScriptVerifyStrictEncoding defines that signature scripts and
 	// public keys must follow the strict encoding requirements.
 	ScriptVerifyStrictEncoding
+
+	// ScriptVerifyCheckSequenceVerify defines whether to allow execution
+	// pathways of a script to be restricted based on the relative lock time
+	// (input sequence) of the spent output.  This is BIP0112.
+	ScriptVerifyCheckSequenceVerify
 )
 
 const (
@@ -97,6 +102,8 @@ type Engine struct {
 	flags                  ScriptFlags
 	bip16                  bool // treat execution as pay-to-script-hash
 	p2ch                   bool // pay to contract hash
+	taproot                bool // pay to taproot
+	taprootScriptPath      bool // taproot spend by script path, the leaf script need be executed
 }
 
 // hasFlag returns whether the script engine instance has the passed flag set.
@@ -291,9 +298,26 @@ func (vm *Engine) Step() (done bool, err error) {
 
 		vm.numOps = 0 // number of ops is per script.
 		vm.scriptOff = 0
-		if vm.scriptIdx == 0 && (vm.bip16 || vm.p2ch) {
+		if vm.scriptIdx == 0 && (vm.bip16 || vm.p2ch || vm.taproot) {
 			vm.scriptIdx++
 			vm.savedFirstStack = vm.GetStack()
+		} else if vm.scriptIdx == 1 && vm.taprootScriptPath {
+			// Put us past the end for CheckErrorCondition()
+			vm.scriptIdx++
+			err := vm.CheckErrorCondition(false)
+			if err != nil {
+				return false, err
+			}
+
+			// The leaf script is under the control block, set stack to
+			// be the stack from first script minus both of them.
+			script := vm.savedFirstStack[len(vm.savedFirstStack)-2]
+			pops, err := parseScript(script)
+			if err != nil {
+				return false, err
+			}
+			vm.scripts = append(vm.scripts, pops)
+			vm.SetStack(vm.savedFirstStack[:len(vm.savedFirstStack)-2])
 		} else if vm.scriptIdx == 1 && (vm.bip16 || vm.p2ch) {
 			// Put us past the end for CheckErrorCondition()
 			vm.scriptIdx++
@@ -648,6 +672,13 @@ func NewEngine(scriptPubKey []byte,
 		}
 		vm.p2ch = true
 	}
+	if isTaproot(vm.scripts[1]) {
+		// Only accept input scripts that push data for taproot.
+		if !isPushOnly(vm.scripts[0]) {
+			return nil, ErrStackTaprootNonPushOnly
+		}
+		vm.taproot = true
+	}
 	if vm.hasFlag(ScriptVerifyMinimalData) {
 		vm.dstack.verifyMinimalData = true
 		vm.astack.verifyMinimalData = true
@@ -96,6 +96,8 @@ var (
 		"pushonly input")
 	ErrStackP2CHNonPushOnly = errors.New("pay to contract hash with non " +
 		"pushonly input")
+	ErrStackTaprootNonPushOnly = errors.New("pay to taproot with non " +
+		"pushonly input")
 	// ErrStackInvalidParseType is an internal error returned from
 	// ScriptToAddrHash ony if the internal data tables are wrong.
 	ErrStackInvalidParseType = errors.New("internal error: invalid parsetype found")
@@ -152,4 +154,23 @@ var (
 	// ErrBadNumRequired is returned from MultiSigScript when nrequired is
 	// larger than the number of provided public keys.
 	ErrBadNumRequired = errors.New("more signatures required than keys present")
+
+	// ErrInvalidSchnorrPubKey is returned when a schnorr public key is not
+	// a compressed secp256k1 point.
+	ErrInvalidSchnorrPubKey = errors.New("invalid schnorr public key")
+
+	// ErrInvalidSchnorrKey is returned when a schnorr private key or tweaked
+	// private key is out of range.
+	ErrInvalidSchnorrKey = errors.New("invalid schnorr private key")
+
+	// ErrInvalidControlBlock is returned when the control block of a taproot
+	// script path spend is malformed.
+	ErrInvalidControlBlock = errors.New("invalid taproot control block")
+
+	// ErrInvalidLeafIndex is returned when the leaf index is out of the
+	// range of taproot leaf scripts.
+	ErrInvalidLeafIndex = errors.New("invalid taproot leaf index")
+
+	// ErrInvalidNonce is returned when a musig nonce is malformed.
+	ErrInvalidNonce = errors.New("invalid musig nonce")
 )
@@ -215,6 +215,7 @@ const (
 	OP_NOP2                = 0xb1 // 177
 	OP_CHECKLOCKTIMEVERIFY = 0xb1 // 177 - AKA OP_NOP2
 	OP_NOP3                = 0xb2 // 178
+	OP_CHECKSEQUENCEVERIFY = 0xb2 // 178 - AKA OP_NOP3
 	OP_NOP4                = 0xb3 // 179
 	OP_NOP5                = 0xb4 // 180
 	OP_NOP6                = 0xb5 // 181
@@ -237,7 +238,7 @@ const (
 	OP_UNKNOWN198          = 0xc6 // 198
 	OP_UNKNOWN199          = 0xc7 // 199
 	OP_JURY_REDEEM_EQUAL   = 0xc8 // 200
-	OP_UNKNOWN201          = 0xc9 // 201
+	OP_CHECKTAPROOT        = 0xc9 // 201
 	OP_UNKNOWN202          = 0xca // 202
 	OP_UNKNOWN203          = 0xcb // 203
 	OP_UNKNOWN204          = 0xcc // 204
@@ -417,6 +418,7 @@ var opcodeArray = [256]opcode{
 	//OP_RETURN:              {OP_RETURN, "OP_RETURN", 1, opcodeReturn},
         OP_RETURN:              {OP_RETURN, "OP_RETURN", 1, opcodeFalse},
 	OP_CHECKLOCKTIMEVERIFY: {OP_CHECKLOCKTIMEVERIFY, "OP_CHECKLOCKTIMEVERIFY", 1, opcodeCheckLockTimeVerify},
+	OP_CHECKSEQUENCEVERIFY: {OP_CHECKSEQUENCEVERIFY, "OP_CHECKSEQUENCEVERIFY", 1, opcodeCheckSequenceVerify},
 
 	// Stack opcodes.
 	OP_TOALTSTACK:   {OP_TOALTSTACK, "OP_TOALTSTACK", 1, opcodeToAltStack},
@@ -499,7 +501,6 @@ var opcodeArray = [256]opcode{
 
 	// Reserved opcodes.
 	OP_NOP1:  {OP_NOP1, "OP_NOP1", 1, opcodeNop},
-	OP_NOP3:  {OP_NOP3, "OP_NOP3", 1, opcodeNop},
 	OP_NOP4:  {OP_NOP4, "OP_NOP4", 1, opcodeNop},
 	OP_NOP5:  {OP_NOP5, "OP_NOP5", 1, opcodeNop},
 	OP_NOP6:  {OP_NOP6, "OP_NOP6", 1, opcodeNop},
@@ -524,7 +525,7 @@ var opcodeArray = [256]opcode{
 	OP_UNKNOWN198:        {OP_UNKNOWN198, "OP_UNKNOWN198", 1, opcodeInvalid},
 	OP_UNKNOWN199:        {OP_UNKNOWN199, "OP_UNKNOWN199", 1, opcodeInvalid},
 	OP_JURY_REDEEM_EQUAL: {OP_JURY_REDEEM_EQUAL, "OP_JURY_REDEEM_EQUAL", 1, opcodeCheckJuryRedeemEqual},
-	OP_UNKNOWN201:        {OP_UNKNOWN201, "OP_UNKNOWN201", 1, opcodeInvalid},
+	OP_CHECKTAPROOT:      {OP_CHECKTAPROOT, "OP_CHECKTAPROOT", 1, opcodeCheckTaproot},
 	OP_UNKNOWN202:        {OP_UNKNOWN202, "OP_UNKNOWN202", 1, opcodeInvalid},
 	OP_UNKNOWN203:        {OP_UNKNOWN203, "OP_UNKNOWN203", 1, opcodeInvalid},
 	OP_UNKNOWN204:        {OP_UNKNOWN204, "OP_UNKNOWN204", 1, opcodeInvalid},
@@ -876,7 +877,7 @@ func opcodeN(op *parsedOpcode, vm *Engine) error {
 // the flag to discourage use of NOPs is set for select opcodes.
 func opcodeNop(op *parsedOpcode, vm *Engine) error {
 	switch op.opcode.value {
-	case OP_NOP1, OP_NOP3, OP_NOP4, OP_NOP5,
+	case OP_NOP1, OP_NOP4, OP_NOP5,
 		OP_NOP6, OP_NOP7, OP_NOP8, OP_NOP9, OP_NOP10:
 		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
 			return fmt.Errorf("OP_NOP%d reserved for soft-fork "+
@@ -1091,6 +1092,93 @@ func opcodeCheckLockTimeVerify(op *parsedOpcode, vm *Engine) error {
 	return nil
 }
 
+// opcodeCheckSequenceVerify compares the top item on the data stack to the
+// Sequence field of the transaction input containing the script signature
+// validating if the transaction outputs are spendable yet.  The sequence of
+// the input is checked against the confirmation of the spent output by the
+// validator, so the script only needs to ensure the input commits to a lock
+// at least as long as the one required by the script.  If flag
+// ScriptVerifyCheckSequenceVerify is not set, the code continues as if OP_NOP3
+// were executed.
+func opcodeCheckSequenceVerify(op *parsedOpcode, vm *Engine) error {
+	// If the ScriptVerifyCheckSequenceVerify script flag is not set, treat
+	// opcode as OP_NOP3 instead.
+	if !vm.hasFlag(ScriptVerifyCheckSequenceVerify) {
+		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+			return errors.New("OP_NOP3 reserved for soft-fork " +
+				"upgrades")
+		}
+		return nil
+	}
+
+	// The current transaction sequence is a uint32 resulting in a maximum
+	// sequence of 2^32-1.  However, scriptNums are signed and therefore a
+	// standard 4-byte scriptNum would only support up to a maximum of
+	// 2^31-1.  Thus, a 5-byte scriptNum is used here since it will support
+	// up to 2^39-1 which allows sequences beyond the current sequence
+	// limit.
+	//
+	// PeekByteArray is used here instead of PeekInt because we do not want
+	// to be limited to a 4-byte integer for reasons specified above.
+	so, err := vm.dstack.PeekByteArray(0)
+	if err != nil {
+		return err
+	}
+	stackSequence, err := makeScriptNum(so, vm.dstack.verifyMinimalData, 5)
+	if err != nil {
+		return err
+	}
+
+	// In the rare event that the argument needs to be < 0 due to some
+	// arithmetic being done first, you can always use
+	// 0 OP_MAX OP_CHECKSEQUENCEVERIFY.
+	if stackSequence < 0 {
+		return fmt.Errorf("negative sequence: %d", stackSequence)
+	}
+
+	sequence := int64(stackSequence)
+
+	// To provide for future soft-fork extensibility, if the
+	// operand has the disabled lock-time flag set,
+	// CHECKSEQUENCEVERIFY behaves as a NOP.
+	if sequence&int64(modules.SequenceLockTimeDisabled) != 0 {
+		return nil
+	}
+
+	pay := vm.tx.TxMessages()[vm.msgIdx].Payload.(*modules.PaymentPayload)
+	txSequence := int64(pay.Inputs[vm.txIdx].Sequence)
+
+	// Sequence numbers with their most significant bit set are not
+	// consensus constrained. Testing that the transaction's sequence
+	// number does not have this bit set prevents using this property
+	// to get around a CHECKSEQUENCEVERIFY check.
+	if txSequence&int64(modules.SequenceLockTimeDisabled) != 0 {
+		str := "transaction sequence has sequence locktime " +
+			"disabled bit set: 0x%x"
+		return fmt.Errorf(str, txSequence)
+	}
+
+	// Mask off non-consensus bits before doing comparisons.
+	lockTimeMask := int64(modules.SequenceLockTimeIsSeconds |
+		modules.SequenceLockTimeMask)
+	txMasked := txSequence & lockTimeMask
+	stackMasked := sequence & lockTimeMask
+
+	// The relative lock-time of the input and the script must be of the
+	// same type, either units or seconds.
+	isSeconds := int64(modules.SequenceLockTimeIsSeconds)
+	if txMasked&isSeconds != stackMasked&isSeconds {
+		return fmt.Errorf("mismatched sequence types -- tx sequence %d, "+
+			"stack sequence %d", txMasked, stackMasked)
+	}
+	if stackMasked > txMasked {
+		str := "sequence requirement not satisfied -- sequence is " +
+			"greater than the transaction input sequence: %d > %d"
+		return fmt.Errorf(str, stackMasked, txMasked)
+	}
+	return nil
+}
+
 // opcodeToAltStack removes the top item from the main data stack and pushes it
 // onto the alternate data stack.
 //
@@ -2157,14 +2245,15 @@ func initOpcodeByName() map[string]byte {
 	var OpcodeByName = make(map[string]byte)
 	// Initialize the opcode name to value map using the contents of the
 	// opcode array.  Also add entries for "OP_FALSE", "OP_TRUE", and
-	// "OP_NOP2" since they are aliases for "OP_0", "OP_1",
-	// and "OP_CHECKLOCKTIMEVERIFY" respectively.
+	// "OP_NOP2" and "OP_NOP3" since they are aliases for "OP_0", "OP_1",
+	// "OP_CHECKLOCKTIMEVERIFY" and "OP_CHECKSEQUENCEVERIFY" respectively.
 	for _, op := range opcodeArray {
 		OpcodeByName[op.name] = op.value
 	}
 	OpcodeByName["OP_FALSE"] = OP_FALSE
 	OpcodeByName["OP_TRUE"] = OP_TRUE
 	OpcodeByName["OP_NOP2"] = OP_CHECKLOCKTIMEVERIFY
+	OpcodeByName["OP_NOP3"] = OP_CHECKSEQUENCEVERIFY
 	return OpcodeByName
 }
 
@@ -2206,3 +2295,57 @@ func opcodeCheckJuryRedeemEqual(op *parsedOpcode, vm *Engine) error {
 	vm.dstack.PushBool(result)
 	return nil
 }
+
+// opcodeCheckTaproot pops the hash160 of taproot output key, then checks the
+// witness on the stack. If the top item is a control block, it is a script
+// path spend, the output key is recomputed by the leaf script and the control
+// block, then the leaf script will be executed by the engine like the redeem
+// script of P2SH. Otherwise it is a key path spend, the output key and the
+// schnorr signature are popped and verified.
+//
+// Stack transformation (key path):
+// [... signature outputKey hash160] -> [... bool]
+// Stack transformation (script path):
+// [... leafScript controlBlock hash160] -> [... bool]
+func opcodeCheckTaproot(op *parsedOpcode, vm *Engine) error {
+	outputKeyHash, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	witness, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	if IsTaprootControlBlock(witness) {
+		leafScript, err := vm.dstack.PopByteArray()
+		if err != nil {
+			return err
+		}
+		outputKey, err := taprootOutputKeyFromControlBlock(witness, leafScript)
+		if err != nil {
+			vm.dstack.PushBool(false)
+			return nil
+		}
+		result := bytes.Equal(crypto.Hash160(outputKey), outputKeyHash)
+		vm.taprootScriptPath = result
+		vm.dstack.PushBool(result)
+		return nil
+	}
+
+	fullSigBytes, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	if len(fullSigBytes) < 1 || !bytes.Equal(crypto.Hash160(witness), outputKeyHash) {
+		vm.dstack.PushBool(false)
+		return nil
+	}
+	hashType := SigHashType(fullSigBytes[len(fullSigBytes)-1])
+	sigBytes := fullSigBytes[:len(fullSigBytes)-1]
+	if err := vm.checkHashTypeEncoding(hashType); err != nil {
+		return err
+	}
+	hash := calcSignatureHash(vm.subScript(), hashType, &vm.tx, vm.msgIdx, vm.txIdx, vm.crypto)
+	vm.dstack.PushBool(SchnorrVerify(witness, sigBytes, hash))
+	return nil
+}
@@ -10,6 +10,9 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
 )
 
 // TestOpcodeDisabled tests the opcodeDisabled function manually because all
@@ -32,6 +35,55 @@ func TestOpcodeDisabled(t *testing.T) {
 	}
 }
 
+// TestOpcodeCheckSequenceVerify ensures OP_CHECKSEQUENCEVERIFY compares the
+// relative lock time of the script with the sequence of the spending input.
+func TestOpcodeCheckSequenceVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		scriptSeq   int64
+		txSeq       uint32
+		flags       ScriptFlags
+		expectError bool
+	}{
+		{"equal units", 10, modules.NewSequenceLockByUnits(10), ScriptVerifyCheckSequenceVerify, false},
+		{"greater units", 10, modules.NewSequenceLockByUnits(20), ScriptVerifyCheckSequenceVerify, false},
+		{"less units", 10, modules.NewSequenceLockByUnits(5), ScriptVerifyCheckSequenceVerify, true},
+		{"mismatched type", 10, modules.NewSequenceLockBySeconds(10 * 512), ScriptVerifyCheckSequenceVerify, true},
+		{"seconds", int64(modules.NewSequenceLockBySeconds(1024)), modules.NewSequenceLockBySeconds(2048),
+			ScriptVerifyCheckSequenceVerify, false},
+		{"tx lock disabled", 10, modules.SequenceLockTimeDisabled | 10, ScriptVerifyCheckSequenceVerify, true},
+		{"script lock disabled", int64(modules.SequenceLockTimeDisabled), 0, ScriptVerifyCheckSequenceVerify, false},
+		{"negative", -1, 10, ScriptVerifyCheckSequenceVerify, true},
+		{"as nop3", 10, 0, 0, false},
+		{"discourage nop3", 10, 0, ScriptDiscourageUpgradableNops, true},
+	}
+	for _, test := range tests {
+		pay := modules.NewPaymentPayload(nil, nil)
+		input := modules.NewTxIn(modules.NewOutPoint(common.Hash{1}, 0, 0), nil)
+		input.Sequence = test.txSeq
+		pay.AddTxIn(input)
+		tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, pay)})
+		lockScript, err := NewScriptBuilder().AddInt64(test.scriptSeq).AddOp(OP_CHECKSEQUENCEVERIFY).
+			AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+		if err != nil {
+			t.Fatalf("%s: build script error: %v", test.name, err)
+		}
+		vm, err := NewEngine(lockScript, nil, tx, 0, 0, test.flags, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: create engine error: %v", test.name, err)
+		}
+		err = vm.Execute()
+		if test.expectError && err == nil {
+			t.Errorf("%s: expected error, got nil", test.name)
+		}
+		if !test.expectError && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
+
 // TestOpcodeDisasm tests the print function for all opcodes in both the oneline
 // and full modes to ensure it provides the expected disassembly.
 func TestOpcodeDisasm(t *testing.T) {
@@ -110,8 +162,11 @@ func TestOpcodeDisasm(t *testing.T) {
 		// OP_NOP1 through OP_NOP10.
 		case opcodeVal >= 0xb0 && opcodeVal <= 0xb9:
 			// OP_NOP2 is an alias of OP_CHECKLOCKTIMEVERIFY
+			// OP_NOP3 is an alias of OP_CHECKSEQUENCEVERIFY
 			if opcodeVal == 0xb1 {
 				expectedStr = "OP_CHECKLOCKTIMEVERIFY"
+			} else if opcodeVal == 0xb2 {
+				expectedStr = "OP_CHECKSEQUENCEVERIFY"
 			} else {
 				val := byte(opcodeVal - (0xb0 - 1))
 				expectedStr = "OP_NOP" + strconv.Itoa(int(val))
@@ -124,6 +179,9 @@ func TestOpcodeDisasm(t *testing.T) {
 		if opcodeVal == 200 {
 			expectedStr = "OP_JURY_REDEEM_EQUAL"
 		}
+		if opcodeVal == 201 {
+			expectedStr = "OP_CHECKTAPROOT"
+		}
 		pop := parsedOpcode{opcode: &opcodeArray[opcodeVal], data: data}
 		gotStr := pop.print(true)
 		if gotStr != expectedStr {
@@ -174,8 +232,11 @@ func TestOpcodeDisasm(t *testing.T) {
 		// OP_NOP1 through OP_NOP10.
 		case opcodeVal >= 0xb0 && opcodeVal <= 0xb9:
 			// OP_NOP2 is an alias of OP_CHECKLOCKTIMEVERIFY
+			// OP_NOP3 is an alias of OP_CHECKSEQUENCEVERIFY
 			if opcodeVal == 0xb1 {
 				expectedStr = "OP_CHECKLOCKTIMEVERIFY"
+			} else if opcodeVal == 0xb2 {
+				expectedStr = "OP_CHECKSEQUENCEVERIFY"
 			} else {
 				val := byte(opcodeVal - (0xb0 - 1))
 				expectedStr = "OP_NOP" + strconv.Itoa(int(val))
@@ -188,6 +249,9 @@ func TestOpcodeDisasm(t *testing.T) {
 		if opcodeVal == 200 {
 			expectedStr = "OP_JURY_REDEEM_EQUAL"
 		}
+		if opcodeVal == 201 {
+			expectedStr = "OP_CHECKTAPROOT"
+		}
 		pop := parsedOpcode{opcode: &opcodeArray[opcodeVal], data: data}
 		gotStr := pop.print(false)
 		if gotStr != expectedStr {
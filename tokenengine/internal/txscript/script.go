@@ -68,6 +68,14 @@ func isContractHash(pops []parsedOpcode) bool {
 		pops[1].opcode.value == OP_JURY_REDEEM_EQUAL
 }
 
+// isTaproot returns true if the script passed is a pay-to-taproot
+// transaction, false otherwise.
+func isTaproot(pops []parsedOpcode) bool {
+	return len(pops) == 2 &&
+		pops[0].opcode.value == OP_DATA_20 &&
+		pops[1].opcode.value == OP_CHECKTAPROOT
+}
+
 // IsPayToScriptHash returns true if the script is in the standard
 // pay-to-script-hash (P2SH) format, false otherwise.
 func IsPayToScriptHash(script []byte) bool {
@@ -281,6 +289,17 @@ func CalcSignatureHash(script []byte, hashType SigHashType,
 	}
 	return calcSignatureHash(parsedScript, hashType, tx, msgIdx, idx, crypto), nil
 }
+
+// CalcSignatureData returns the data signed by ICrypto.Sign, it is the
+// preimage of CalcSignatureHash.
+func CalcSignatureData(script []byte, hashType SigHashType,
+	tx *modules.Transaction, msgIdx, idx int) ([]byte, error) {
+	parsedScript, err := parseScript(script)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse output script: %v", err)
+	}
+	return calcSignatureData(parsedScript, hashType, tx, msgIdx, idx), nil
+}
 func calcSignatureHash(script []parsedOpcode, hashType SigHashType,
 	tx *modules.Transaction, msgIdx, idx int, crypto ICrypto) []byte {
 	data := calcSignatureData(script, hashType, tx, msgIdx, idx)
@@ -366,11 +385,11 @@ func calcSignatureData(script []parsedOpcode, hashType SigHashType,	otx *modules
 	switch hashType & sigHashMask {
 	case SigHashNone:
 		payCopy.Outputs = payCopy.Outputs[0:0] // Empty slice.
-		//for i := range payCopy.Inputs {
-		//	if i != idx {
-		//		payCopy.Inputs[i].Sequence = 0
-		//	}
-		//}
+		for i := range payCopy.Inputs {
+			if i != idx {
+				payCopy.Inputs[i].Sequence = 0
+			}
+		}
 
 	case SigHashSingle:
 		// Resize output array to up to and including requested index.
@@ -383,11 +402,11 @@ func calcSignatureData(script []parsedOpcode, hashType SigHashType,	otx *modules
 		}
 
 		// Sequence on all other inputs is 0, too.
-		//for i := range payCopy.TxIn {
-		//	if i != idx {
-		//		payCopy.TxIn[i].Sequence = 0
-		//	}
-		//}
+		for i := range payCopy.Inputs {
+			if i != idx {
+				payCopy.Inputs[i].Sequence = 0
+			}
+		}
 	default:
 		fallthrough
 	case SigHashOld:
@@ -31,13 +31,15 @@ const (
 		ScriptDiscourageUpgradableNops |
 		ScriptVerifyCleanStack |
 		ScriptVerifyCheckLockTimeVerify |
+		ScriptVerifyCheckSequenceVerify |
 		ScriptVerifyLowS
 	StandardVerifyExcludeSignFlags = ScriptBip16 |
 		ScriptVerifyMinimalData |
 		ScriptStrictMultiSig |
 		ScriptDiscourageUpgradableNops |
 		ScriptVerifyCleanStack |
-		ScriptVerifyCheckLockTimeVerify
+		ScriptVerifyCheckLockTimeVerify |
+		ScriptVerifyCheckSequenceVerify
 )
 
 // ScriptClass is an enumeration for the list of standard types of script.
@@ -52,6 +54,7 @@ const (
 	MultiSigTy                        // Multi signature.
 	NullDataTy                        // Empty data-only (provably prunable).
 	ContractHashTy                    // Pay to contract hash.
+	TaprootTy                         // Pay to taproot.
 )
 
 // scriptClassToName houses the human-readable strings which describe each
@@ -64,6 +67,7 @@ var scriptClassToName = []string{
 	MultiSigTy:     "multisig",
 	NullDataTy:     "nulldata",
 	ContractHashTy: "contracthash",
+	TaprootTy:      "taproot",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -159,6 +163,8 @@ func typeOfScript(pops []parsedOpcode) ScriptClass {
 		return ScriptHashTy
 	} else if isContractHash(pops) {
 		return ContractHashTy
+	} else if isTaproot(pops) {
+		return TaprootTy
 	} else if isMultiSig(pops) {
 		return MultiSigTy
 	} else if isNullData(pops) {
@@ -336,6 +342,13 @@ func payToContractHash(serializedPubKey []byte) ([]byte, error) {
 		AddOp(OP_JURY_REDEEM_EQUAL).Script()
 }
 
+// payToTaprootScript creates a new script to pay a transaction output to the
+// hash160 of a taproot output key.
+func payToTaprootScript(outputKeyHash []byte) ([]byte, error) {
+	return NewScriptBuilder().AddData(outputKeyHash).
+		AddOp(OP_CHECKTAPROOT).Script()
+}
+
 // PayToAddrScript creates a new script to pay a transaction output to a the
 // specified address.
 func PayToAddrScript(addr common.Address) ([]byte, error) {
@@ -352,6 +365,10 @@ func PayToAddrScript(addr common.Address) ([]byte, error) {
 	case common.ContractHash:
 
 		return payToContractHash(addr.Bytes())
+
+	case common.TaprootHash:
+
+		return payToTaprootScript(addr.Bytes())
 	}
 
 	return nil, ErrUnsupportedAddress
@@ -456,6 +473,11 @@ func ExtractPkScriptAddrs(pkScript []byte) (ScriptClass, []AddressOriginalData,
 		// if err == nil {
 		addrs = append(addrs, addr)
 		// }
+	case TaprootTy:
+		//<output key hash> OP_CHECKTAPROOT
+		requiredSigs = 1
+		addr := NewAddressOriginalData(pops[0].data, scriptClass)
+		addrs = append(addrs, addr)
 	case MultiSigTy:
 		// A multi-signature script is of the form:
 		//  <numsigs> <pubkey> <pubkey> <pubkey>... <numpubkeys> OP_CHECKMULTISIG
@@ -0,0 +1,494 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package txscript
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// Taproot锁定脚本：OP_DATA_20 <hash160(Q)> OP_CHECKTAPROOT
+// Q = P + t*G，P是内部公钥（通常是多个参与者Schnorr公钥的聚合），
+// t = H_TapTweak(P || MerkleRoot)，MerkleRoot是备选脚本构成的Merkle树根。
+// Key path解锁脚本：<schnorrSig||hashType> <Q>
+// Script path解锁脚本：<leaf args...> <leafScript> <controlBlock>
+// controlBlock = TaprootLeafVersion || P || MerklePath
+
+const (
+	// TaprootLeafVersion is the first byte of the control block, and also
+	// the prefix when calculate the leaf hash.
+	TaprootLeafVersion = 0xc0
+
+	// SchnorrSignatureSize is the length of a schnorr signature: R(33) || s(32)
+	SchnorrSignatureSize = 65
+
+	taprootPubKeySize       = 33
+	taprootNodeSize         = 32
+	taprootControlBlockBase = 1 + taprootPubKeySize
+	taprootMaxPathLength    = 128
+)
+
+var curve = btcec.S256()
+
+func taggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msgs {
+		h.Write(m)
+	}
+	return h.Sum(nil)
+}
+
+func hashToScalar(tag string, msgs ...[]byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash(tag, msgs...))
+	return e.Mod(e, curve.N)
+}
+
+func parsePoint(pubKey []byte) (*btcec.PublicKey, error) {
+	if len(pubKey) != taprootPubKeySize {
+		return nil, ErrInvalidSchnorrPubKey
+	}
+	pk, err := btcec.ParsePubKey(pubKey, curve)
+	if err != nil {
+		return nil, ErrInvalidSchnorrPubKey
+	}
+	return pk, nil
+}
+
+func serializePoint(x, y *big.Int) []byte {
+	return (&btcec.PublicKey{Curve: curve, X: x, Y: y}).SerializeCompressed()
+}
+
+func scalarBytes(s *big.Int) []byte {
+	b := make([]byte, 32)
+	sb := s.Bytes()
+	copy(b[32-len(sb):], sb)
+	return b
+}
+
+func parseScalar(b []byte) (*big.Int, error) {
+	if len(b) != 32 {
+		return nil, ErrInvalidSchnorrKey
+	}
+	s := new(big.Int).SetBytes(b)
+	if s.Sign() == 0 || s.Cmp(curve.N) >= 0 {
+		return nil, ErrInvalidSchnorrKey
+	}
+	return s, nil
+}
+
+// schnorrChallenge returns e = H(R || P || hash) mod N
+func schnorrChallenge(r, pubKey, hash []byte) *big.Int {
+	return hashToScalar("PalletOne/SchnorrChallenge", r, pubKey, hash)
+}
+
+// SchnorrSign signs the hash by the private key tweaked with tweak(can be nil),
+// the signature is R(33 bytes) || s(32 bytes).
+func SchnorrSign(privKey, tweak, hash []byte) ([]byte, error) {
+	d, err := parseScalar(privKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(tweak) > 0 {
+		t := new(big.Int).SetBytes(tweak)
+		d.Add(d, t).Mod(d, curve.N)
+		if d.Sign() == 0 {
+			return nil, ErrInvalidSchnorrKey
+		}
+	}
+	pubKey := serializePoint(curve.ScalarBaseMult(scalarBytes(d)))
+	// deterministic nonce, never reuse a nonce for different messages
+	k := hashToScalar("PalletOne/SchnorrNonce", scalarBytes(d), hash)
+	if k.Sign() == 0 {
+		return nil, ErrInvalidNonce
+	}
+	r := serializePoint(curve.ScalarBaseMult(scalarBytes(k)))
+	e := schnorrChallenge(r, pubKey, hash)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k).Mod(s, curve.N)
+	return append(r, scalarBytes(s)...), nil
+}
+
+// SchnorrVerify verifies the signature s*G == R + e*P
+func SchnorrVerify(pubKey, signature, hash []byte) bool {
+	if len(signature) != SchnorrSignatureSize {
+		return false
+	}
+	pk, err := parsePoint(pubKey)
+	if err != nil {
+		return false
+	}
+	r, err := parsePoint(signature[:taprootPubKeySize])
+	if err != nil {
+		return false
+	}
+	s := new(big.Int).SetBytes(signature[taprootPubKeySize:])
+	if s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	e := schnorrChallenge(signature[:taprootPubKeySize], pubKey, hash)
+	sx, sy := curve.ScalarBaseMult(scalarBytes(s))
+	ex, ey := curve.ScalarMult(pk.X, pk.Y, scalarBytes(e))
+	rx, ry := curve.Add(r.X, r.Y, ex, ey)
+	return sx.Cmp(rx) == 0 && sy.Cmp(ry) == 0
+}
+
+func sortPubKeys(pubKeys [][]byte) [][]byte {
+	sorted := make([][]byte, len(pubKeys))
+	copy(sorted, pubKeys)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return sorted
+}
+
+// keyAggCoefficient returns a_i = H(L || P_i), L = H(P_1 || ... || P_n)
+func keyAggCoefficient(sortedPubKeys [][]byte, pubKey []byte) *big.Int {
+	if len(sortedPubKeys) == 1 {
+		return big.NewInt(1)
+	}
+	l := taggedHash("PalletOne/KeyAggList", sortedPubKeys...)
+	return hashToScalar("PalletOne/KeyAggCoefficient", l, pubKey)
+}
+
+// AggregateSchnorrPubKeys returns P = sum(a_i * P_i), the order of pubKeys is not important.
+// The coefficient a_i avoid the rogue key attack.
+func AggregateSchnorrPubKeys(pubKeys [][]byte) ([]byte, error) {
+	if len(pubKeys) == 0 {
+		return nil, ErrInvalidSchnorrPubKey
+	}
+	sorted := sortPubKeys(pubKeys)
+	var x, y *big.Int
+	for _, pubKey := range sorted {
+		pk, err := parsePoint(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		a := keyAggCoefficient(sorted, pubKey)
+		px, py := curve.ScalarMult(pk.X, pk.Y, scalarBytes(a))
+		if x == nil {
+			x, y = px, py
+		} else {
+			x, y = curve.Add(x, y, px, py)
+		}
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidSchnorrPubKey
+	}
+	return serializePoint(x, y), nil
+}
+
+// TaprootLeafHash returns the hash of a leaf script in the script tree
+func TaprootLeafHash(script []byte) []byte {
+	return taggedHash("PalletOne/TapLeaf", []byte{TaprootLeafVersion}, script)
+}
+
+func taprootBranchHash(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return taggedHash("PalletOne/TapBranch", a, b)
+}
+
+// TaprootMerkleRoot builds the script tree of leafScripts, and returns
+// the merkle root and the merkle path of the leaf at leafIndex.
+// If leafScripts is empty, the root is nil.
+func TaprootMerkleRoot(leafScripts [][]byte, leafIndex int) ([]byte, [][]byte) {
+	if len(leafScripts) == 0 {
+		return nil, nil
+	}
+	level := make([][]byte, len(leafScripts))
+	for i, script := range leafScripts {
+		level[i] = TaprootLeafHash(script)
+	}
+	path := [][]byte{}
+	idx := leafIndex
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				//奇数个节点，最后一个直接提升到上一层
+				next = append(next, level[i])
+				continue
+			}
+			if idx == i {
+				path = append(path, level[i+1])
+			} else if idx == i+1 {
+				path = append(path, level[i])
+			}
+			next = append(next, taprootBranchHash(level[i], level[i+1]))
+		}
+		idx /= 2
+		level = next
+	}
+	return level[0], path
+}
+
+// TaprootTweak returns the tweak t = H(P || merkleRoot) and the output key Q = P + t*G
+func TaprootTweak(internalPubKey, merkleRoot []byte) (tweak []byte, outputPubKey []byte, err error) {
+	pk, err := parsePoint(internalPubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := hashToScalar("PalletOne/TapTweak", internalPubKey, merkleRoot)
+	tx, ty := curve.ScalarBaseMult(scalarBytes(t))
+	qx, qy := curve.Add(pk.X, pk.Y, tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, nil, ErrInvalidSchnorrPubKey
+	}
+	return scalarBytes(t), serializePoint(qx, qy), nil
+}
+
+// TaprootControlBlock returns the control block to spend the leaf at leafIndex by script path
+func TaprootControlBlock(internalPubKey []byte, leafScripts [][]byte, leafIndex int) ([]byte, error) {
+	if leafIndex < 0 || leafIndex >= len(leafScripts) {
+		return nil, ErrInvalidLeafIndex
+	}
+	if _, err := parsePoint(internalPubKey); err != nil {
+		return nil, err
+	}
+	_, path := TaprootMerkleRoot(leafScripts, leafIndex)
+	cb := make([]byte, 0, taprootControlBlockBase+len(path)*taprootNodeSize)
+	cb = append(cb, TaprootLeafVersion)
+	cb = append(cb, internalPubKey...)
+	for _, node := range path {
+		cb = append(cb, node...)
+	}
+	return cb, nil
+}
+
+func IsTaprootControlBlock(data []byte) bool {
+	return len(data) >= taprootControlBlockBase &&
+		data[0] == TaprootLeafVersion &&
+		(len(data)-taprootControlBlockBase)%taprootNodeSize == 0 &&
+		(len(data)-taprootControlBlockBase)/taprootNodeSize <= taprootMaxPathLength
+}
+
+// taprootOutputKeyFromControlBlock recompute the output key Q by the leaf script and control block
+func taprootOutputKeyFromControlBlock(controlBlock, leafScript []byte) ([]byte, error) {
+	if !IsTaprootControlBlock(controlBlock) {
+		return nil, ErrInvalidControlBlock
+	}
+	internalPubKey := controlBlock[1:taprootControlBlockBase]
+	node := TaprootLeafHash(leafScript)
+	for i := taprootControlBlockBase; i < len(controlBlock); i += taprootNodeSize {
+		node = taprootBranchHash(node, controlBlock[i:i+taprootNodeSize])
+	}
+	_, outputPubKey, err := TaprootTweak(internalPubKey, node)
+	return outputPubKey, err
+}
+
+// MuSigSession 多个参与者对同一个Hash进行聚合Schnorr签名，签名结果可以用聚合公钥（加上Tweak）验证。
+// 采用MuSig2两随机数方案：每个参与者提供两个随机数点(R_i1, R_i2)，最终随机数R = R1 + b*R2，
+// b由聚合后的随机数、聚合公钥和Hash决定，恶意参与者在看到其他人的随机数后再选择自己的随机数也无法控制R。
+// 流程：1.每个参与者调用NewMuSigNonce生成随机数，并交换PubNonce
+// 2.每个参与者调用PartialSign生成部分签名 3.任意一方调用Combine合并部分签名
+type MuSigSession struct {
+	PubKeys [][]byte //所有参与者的公钥
+	Tweak   []byte   //Taproot调整值，可以为空
+	Hash    []byte   //要签名的Hash
+}
+
+const (
+	muSigSecNonceSize = 2 * 32
+	muSigPubNonceSize = 2 * taprootPubKeySize
+)
+
+// NewMuSigNonce generate two random nonces k1,k2 and R1 = k1*G, R2 = k2*G for a musig session,
+// secNonce is k1 || k2, pubNonce is R1 || R2.
+// The secNonce must be used only once.
+func NewMuSigNonce() (secNonce []byte, pubNonce []byte, err error) {
+	secNonce = make([]byte, 0, muSigSecNonceSize)
+	for len(secNonce) < muSigSecNonceSize {
+		k, err := rand.Int(rand.Reader, curve.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		if k.Sign() == 0 {
+			continue
+		}
+		secNonce = append(secNonce, scalarBytes(k)...)
+	}
+	pubNonce, err = muSigPubNonce(secNonce)
+	return secNonce, pubNonce, err
+}
+
+func muSigPubNonce(secNonce []byte) ([]byte, error) {
+	if len(secNonce) != muSigSecNonceSize {
+		return nil, ErrInvalidNonce
+	}
+	pubNonce := make([]byte, 0, muSigPubNonceSize)
+	for i := 0; i < 2; i++ {
+		k, err := parseScalar(secNonce[i*32 : (i+1)*32])
+		if err != nil {
+			return nil, ErrInvalidNonce
+		}
+		pubNonce = append(pubNonce, serializePoint(curve.ScalarBaseMult(scalarBytes(k)))...)
+	}
+	return pubNonce, nil
+}
+
+// aggregateNonce returns R = R1 + b*R2 and b, R1 = sum(R_i1), R2 = sum(R_i2)
+func (s *MuSigSession) aggregateNonce(pubNonces [][]byte, q []byte) ([]byte, *big.Int, error) {
+	if len(pubNonces) != len(s.PubKeys) {
+		return nil, nil, ErrInvalidNonce
+	}
+	xs := make([]*big.Int, 2)
+	ys := make([]*big.Int, 2)
+	for _, n := range pubNonces {
+		if len(n) != muSigPubNonceSize {
+			return nil, nil, ErrInvalidNonce
+		}
+		for i := 0; i < 2; i++ {
+			r, err := parsePoint(n[i*taprootPubKeySize : (i+1)*taprootPubKeySize])
+			if err != nil {
+				return nil, nil, ErrInvalidNonce
+			}
+			if xs[i] == nil {
+				xs[i], ys[i] = r.X, r.Y
+			} else {
+				xs[i], ys[i] = curve.Add(xs[i], ys[i], r.X, r.Y)
+			}
+		}
+	}
+	aggNonce := make([]byte, 0, muSigPubNonceSize)
+	for i := 0; i < 2; i++ {
+		if xs[i].Sign() == 0 && ys[i].Sign() == 0 {
+			//无穷远点用全0表示，只会导致签名失败，不影响安全性
+			aggNonce = append(aggNonce, make([]byte, taprootPubKeySize)...)
+		} else {
+			aggNonce = append(aggNonce, serializePoint(xs[i], ys[i])...)
+		}
+	}
+	b := hashToScalar("PalletOne/MuSigNonceCoef", aggNonce, q, s.Hash)
+	x, y := xs[0], ys[0]
+	if xs[1].Sign() != 0 || ys[1].Sign() != 0 {
+		bx, by := curve.ScalarMult(xs[1], ys[1], scalarBytes(b))
+		x, y = curve.Add(x, y, bx, by)
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, ErrInvalidNonce
+	}
+	return serializePoint(x, y), b, nil
+}
+
+func (s *MuSigSession) outputPubKey() ([]byte, error) {
+	p, err := AggregateSchnorrPubKeys(s.PubKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Tweak) == 0 {
+		return p, nil
+	}
+	pk, _ := parsePoint(p)
+	tx, ty := curve.ScalarBaseMult(s.Tweak)
+	qx, qy := curve.Add(pk.X, pk.Y, tx, ty)
+	return serializePoint(qx, qy), nil
+}
+
+// challenge returns R, b and e = H(R || Q || hash)
+func (s *MuSigSession) challenge(pubNonces [][]byte) ([]byte, *big.Int, *big.Int, error) {
+	q, err := s.outputPubKey()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, b, err := s.aggregateNonce(pubNonces, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return r, b, schnorrChallenge(r, q, s.Hash), nil
+}
+
+// PartialSign returns s_i = k_i1 + b * k_i2 + e * a_i * d_i
+// pubNonces must contain the public nonce of secNonce, otherwise the partial signature would leak the private key
+func (s *MuSigSession) PartialSign(privKey, secNonce []byte, pubNonces [][]byte) ([]byte, error) {
+	d, err := parseScalar(privKey)
+	if err != nil {
+		return nil, err
+	}
+	pubNonce, err := muSigPubNonce(secNonce)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, n := range pubNonces {
+		if bytes.Equal(n, pubNonce) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New("public nonce of the signer is not in the musig session")
+	}
+	k1 := new(big.Int).SetBytes(secNonce[:32])
+	k2 := new(big.Int).SetBytes(secNonce[32:])
+	x, y := curve.ScalarBaseMult(privKey)
+	pubKey := serializePoint(x, y)
+	sorted := sortPubKeys(s.PubKeys)
+	found = false
+	for _, pk := range sorted {
+		if bytes.Equal(pk, pubKey) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New("private key is not a participant of the musig session")
+	}
+	_, b, e, err := s.challenge(pubNonces)
+	if err != nil {
+		return nil, err
+	}
+	a := keyAggCoefficient(sorted, pubKey)
+	si := new(big.Int).Mul(e, a)
+	si.Mul(si, d).Add(si, k1)
+	si.Add(si, new(big.Int).Mul(b, k2)).Mod(si, curve.N)
+	return scalarBytes(si), nil
+}
+
+// Combine returns the final signature R || s, s = sum(s_i) + e * t
+func (s *MuSigSession) Combine(pubNonces [][]byte, partialSigs [][]byte) ([]byte, error) {
+	if len(partialSigs) != len(s.PubKeys) {
+		return nil, errors.New("partial signature count not match public key count")
+	}
+	r, _, e, err := s.challenge(pubNonces)
+	if err != nil {
+		return nil, err
+	}
+	sum := new(big.Int)
+	for _, ps := range partialSigs {
+		if len(ps) != 32 {
+			return nil, errors.New("invalid partial signature")
+		}
+		sum.Add(sum, new(big.Int).SetBytes(ps))
+	}
+	if len(s.Tweak) > 0 {
+		sum.Add(sum, new(big.Int).Mul(e, new(big.Int).SetBytes(s.Tweak)))
+	}
+	sum.Mod(sum, curve.N)
+	return append(r, scalarBytes(sum)...), nil
+}
@@ -23,6 +23,7 @@ package txscript
 import (
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/dag/modules"
 )
 
 type AddressOriginalData struct {
@@ -45,6 +46,8 @@ func NewAddressOriginalData(data []byte, at ScriptClass) AddressOriginalData {
 
 	case ContractHashTy:
 		return AddressOriginalData{Address: common.NewAddress(hash160, common.ContractHash), Original: data}
+	case TaprootTy:
+		return AddressOriginalData{Address: common.NewAddress(hash160, common.TaprootHash), Original: data}
 	case PubKeyTy:
 
 		return AddressOriginalData{Address: common.NewAddress(hash160, common.PublicKeyHash), Original: data}
@@ -61,3 +64,11 @@ type ICrypto interface {
 	Verify(pubKey, signature, msg []byte) (bool, error)
 	GetPubKey(address common.Address) ([]byte, error)
 }
+
+// ITxInputSigner is optionally implemented by ICrypto whose signer needs the
+// whole transaction, instead of the signature data, to sign an input. The
+// returned bool is false if the address is not signed by it.
+type ITxInputSigner interface {
+	SignTxInput(address common.Address, tx *modules.Transaction, hashType SigHashType, msgIdx, idx int,
+		subScript []byte) ([]byte, bool, error)
+}
@@ -0,0 +1,528 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package tokenengine
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/tokenengine/internal/txscript"
+)
+
+//部分签名交易的格式版本，格式有变化时需要增加版本号
+const PartialSignedTxVersion uint32 = 1
+
+//部分签名交易，用于多方离线签名。
+//除了未签名的交易外，还携带了每个Input的锁定脚本、赎回脚本、需要的签名者和已经收集到的签名，
+//签名者不需要自己的节点即可完成签名，所有签名收集完成后，生成最终的解锁脚本。
+type PartialSignedTx struct {
+	Version uint32
+	Tx      *modules.Transaction
+	Inputs  []*PartialSignedInput
+}
+
+//部分签名交易中的一个Input
+type PartialSignedInput struct {
+	MessageIndex  uint32
+	InputIndex    uint32
+	HashType      uint32
+	LockScript    []byte           //UTXO的锁定脚本
+	RedeemScript  []byte           //P2SH的赎回脚本，其他类型为空
+	RequiredSigns uint32           //需要的签名数
+	Signers       []common.Address //可以签名的地址
+	Signatures    []*PartialSignature
+}
+
+//签名者对Input的签名
+type PartialSignature struct {
+	PubKey    []byte
+	Signature []byte //包含了最后一个字节的HashType
+}
+
+func unsignedTxHash(tx *modules.Transaction) common.Hash {
+	txCopy := tx.Clone()
+	for _, msg := range txCopy.Messages() {
+		if pay, ok := msg.Payload.(*modules.PaymentPayload); ok {
+			for _, in := range pay.Inputs {
+				in.SignatureScript = nil
+			}
+		}
+	}
+	return txCopy.Hash()
+}
+
+//根据未签名的交易、每个Input对应的锁定脚本和P2SH赎回脚本，构造一个部分签名交易
+func NewPartialSignedTx(tx *modules.Transaction, hashType uint32, utxoLockScripts map[modules.OutPoint][]byte,
+	redeemScripts map[common.Address][]byte) (*PartialSignedTx, error) {
+	pst := &PartialSignedTx{Version: PartialSignedTxVersion, Tx: tx.Clone()}
+	for msgIdx, msg := range pst.Tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		pay, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			return nil, errors.New("Invalid payment message")
+		}
+		for inIdx, input := range pay.Inputs {
+			if input.PreviousOutPoint == nil {
+				continue
+			}
+			lockScript, find := utxoLockScripts[*input.PreviousOutPoint]
+			if !find {
+				return nil, fmt.Errorf("Don't find utxo for outpoint[%s]", input.PreviousOutPoint.String())
+			}
+			psi := &PartialSignedInput{
+				MessageIndex: uint32(msgIdx),
+				InputIndex:   uint32(inIdx),
+				HashType:     hashType,
+				LockScript:   common.CopyBytes(lockScript),
+			}
+			if txscript.GetScriptClass(lockScript) == txscript.ScriptHashTy {
+				addr, _ := Instance.GetAddressFromScript(lockScript)
+				redeem, ok := redeemScripts[addr]
+				if !ok {
+					return nil, fmt.Errorf("Don't find redeem script for address[%s]", addr.String())
+				}
+				if !bytes.Equal(crypto.Hash160(redeem), addr.Bytes()) {
+					return nil, fmt.Errorf("Redeem script not match address[%s]", addr.String())
+				}
+				psi.RedeemScript = common.CopyBytes(redeem)
+			}
+			signers, required, err := psi.requiredSigners()
+			if err != nil {
+				return nil, fmt.Errorf("msg[%d] input[%d] %s", msgIdx, inIdx, err.Error())
+			}
+			psi.Signers = signers
+			psi.RequiredSigns = uint32(required)
+			//已经签名的Input，保留原来的解锁脚本
+			if len(input.SignatureScript) > 0 {
+				psi.Signatures = pst.extractSignatures(psi, input.SignatureScript)
+				input.SignatureScript = nil
+			}
+			pst.Inputs = append(pst.Inputs, psi)
+		}
+	}
+	if len(pst.Inputs) == 0 {
+		return nil, errors.New("Transaction has no input need to sign")
+	}
+	return pst, nil
+}
+
+//签名所用的子脚本，P2SH使用赎回脚本，其他使用锁定脚本
+func (psi *PartialSignedInput) subScript() []byte {
+	if len(psi.RedeemScript) > 0 {
+		return psi.RedeemScript
+	}
+	return psi.LockScript
+}
+
+func (psi *PartialSignedInput) requiredSigners() ([]common.Address, int, error) {
+	class, addrs, required, err := txscript.ExtractPkScriptAddrs(psi.subScript())
+	if err != nil {
+		return nil, 0, err
+	}
+	switch class {
+	case txscript.PubKeyHashTy, txscript.PubKeyTy, txscript.MultiSigTy:
+		signers := make([]common.Address, 0, len(addrs))
+		for _, addr := range addrs {
+			signers = append(signers, addr.Address)
+		}
+		return signers, required, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported script class[%s] for partial signed tx", class.String())
+}
+
+//从已有的解锁脚本中找出签名，并匹配对应的公钥
+func (pst *PartialSignedTx) extractSignatures(psi *PartialSignedInput, sigScript []byte) []*PartialSignature {
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil {
+		return nil
+	}
+	class, addrs, _, _ := txscript.ExtractPkScriptAddrs(psi.subScript())
+	pubKeys := [][]byte{}
+	if class == txscript.PubKeyHashTy {
+		if len(pushes) != 2 {
+			return nil
+		}
+		pubKeys = append(pubKeys, pushes[1])
+		pushes = pushes[:1]
+	} else {
+		for _, addr := range addrs {
+			pubKeys = append(pubKeys, addr.Original)
+		}
+	}
+	result := []*PartialSignature{}
+	for _, sig := range pushes {
+		if len(sig) < 2 {
+			continue
+		}
+		for _, pubKey := range pubKeys {
+			if pst.verifySignature(psi, pubKey, sig) {
+				result = append(result, &PartialSignature{PubKey: pubKey, Signature: sig})
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (pst *PartialSignedTx) signatureData(psi *PartialSignedInput, hashType byte) ([]byte, error) {
+	return txscript.CalcSignatureData(psi.subScript(), txscript.SigHashType(hashType), pst.Tx,
+		int(psi.MessageIndex), int(psi.InputIndex))
+}
+
+//signInput 需要完整交易的签名者直接对Input签名，其他签名者对签名数据签名
+func (pst *PartialSignedTx) signInput(psi *PartialSignedInput, addr common.Address, signFn AddressGetSign) (
+	[]byte, error) {
+	if signer := txInputSigner; signer != nil && signer.IsTxInputSigner(addr) {
+		return signer.SignTxInput(addr, pst.Tx, psi.HashType, int(psi.MessageIndex), int(psi.InputIndex),
+			psi.subScript())
+	}
+	data, err := pst.signatureData(psi, byte(psi.HashType))
+	if err != nil {
+		return nil, err
+	}
+	return signFn(addr, data)
+}
+
+func (pst *PartialSignedTx) verifySignature(psi *PartialSignedInput, pubKey, signature []byte) bool {
+	if len(signature) < 2 {
+		return false
+	}
+	hashType := signature[len(signature)-1]
+	if uint32(hashType) != psi.HashType {
+		return false
+	}
+	data, err := pst.signatureData(psi, hashType)
+	if err != nil {
+		return false
+	}
+	pass, _ := crypto.MyCryptoLib.Verify(pubKey, signature[:len(signature)-1], data)
+	return pass
+}
+
+func (psi *PartialSignedInput) hasSigned(pubKey []byte) bool {
+	return hasSignature(psi.Signatures, pubKey)
+}
+
+//是否已经收集到足够的签名
+func (psi *PartialSignedInput) IsComplete() bool {
+	return uint32(len(psi.Signatures)) >= psi.RequiredSigns
+}
+
+//所有的Input都已经收集到足够的签名
+func (pst *PartialSignedTx) IsComplete() bool {
+	for _, psi := range pst.Inputs {
+		if !psi.IsComplete() {
+			return false
+		}
+	}
+	return true
+}
+
+//使用本地的账户对所有能签名的Input进行签名，返回新增的签名数
+func (pst *PartialSignedTx) Sign(pubKeyFn AddressGetPubKey, signFn AddressGetSign) (int, error) {
+	signed := 0
+	for _, psi := range pst.Inputs {
+		if psi.IsComplete() {
+			continue
+		}
+		for _, addr := range psi.Signers {
+			pubKey, err := pubKeyFn(addr)
+			if err != nil || len(pubKey) == 0 || psi.hasSigned(pubKey) {
+				continue
+			}
+			if !bytes.Equal(crypto.Hash160(pubKey), addr.Bytes()) {
+				continue
+			}
+			sign, err := pst.signInput(psi, addr, signFn)
+			if err != nil {
+				return signed, err
+			}
+			psi.Signatures = append(psi.Signatures,
+				&PartialSignature{PubKey: pubKey, Signature: append(sign, byte(psi.HashType))})
+			signed++
+			if psi.IsComplete() {
+				break
+			}
+		}
+	}
+	return signed, nil
+}
+
+//合并多个签名者分别签名后的部分签名交易，要求版本、原始交易和Input信息一致。
+//签名者和需要的签名数根据锁定脚本重新计算，只保留签名者的有效签名
+func CombinePartialSignedTx(psts ...*PartialSignedTx) (*PartialSignedTx, error) {
+	if len(psts) == 0 {
+		return nil, errors.New("no partial signed tx to combine")
+	}
+	base := psts[0]
+	result := &PartialSignedTx{Version: base.Version, Tx: base.Tx.Clone()}
+	baseHash := unsignedTxHash(base.Tx)
+	for _, pst := range psts[1:] {
+		if pst.Version != base.Version {
+			return nil, fmt.Errorf("partial signed txs have different version %d and %d", base.Version, pst.Version)
+		}
+		if unsignedTxHash(pst.Tx) != baseHash {
+			return nil, errors.New("partial signed txs are not for the same transaction")
+		}
+		if len(pst.Inputs) != len(base.Inputs) {
+			return nil, errors.New("partial signed txs have different inputs")
+		}
+	}
+	for i, psi := range base.Inputs {
+		merged := &PartialSignedInput{
+			MessageIndex: psi.MessageIndex,
+			InputIndex:   psi.InputIndex,
+			HashType:     psi.HashType,
+			LockScript:   psi.LockScript,
+			RedeemScript: psi.RedeemScript,
+		}
+		signers, required, err := merged.requiredSigners()
+		if err != nil {
+			return nil, fmt.Errorf("msg[%d] input[%d] %s", psi.MessageIndex, psi.InputIndex, err.Error())
+		}
+		merged.Signers = signers
+		merged.RequiredSigns = uint32(required)
+		for _, pst := range psts {
+			other := pst.Inputs[i]
+			if other.MessageIndex != psi.MessageIndex || other.InputIndex != psi.InputIndex ||
+				other.HashType != psi.HashType ||
+				!bytes.Equal(other.LockScript, psi.LockScript) || !bytes.Equal(other.RedeemScript, psi.RedeemScript) {
+				return nil, fmt.Errorf("partial signed txs have different input[%d]", i)
+			}
+			for _, sig := range other.Signatures {
+				if sig == nil || !merged.isSigner(sig.PubKey) || merged.hasSigned(sig.PubKey) {
+					continue
+				}
+				//丢弃无效的签名
+				if !result.verifySignature(merged, sig.PubKey, sig.Signature) {
+					continue
+				}
+				merged.Signatures = append(merged.Signatures, sig)
+			}
+		}
+		result.Inputs = append(result.Inputs, merged)
+	}
+	return result, nil
+}
+
+func (pst *PartialSignedTx) unlockScript(psi *PartialSignedInput) ([]byte, error) {
+	class, addrs, required, err := txscript.ExtractPkScriptAddrs(psi.subScript())
+	if err != nil {
+		return nil, err
+	}
+	if required <= 0 || len(psi.Signatures) < required {
+		return nil, fmt.Errorf("msg[%d] input[%d] need %d signatures, but only %d",
+			psi.MessageIndex, psi.InputIndex, required, len(psi.Signatures))
+	}
+	builder := txscript.NewScriptBuilder()
+	switch class {
+	case txscript.PubKeyHashTy:
+		sig := psi.Signatures[0]
+		builder.AddData(sig.Signature).AddData(sig.PubKey)
+	case txscript.PubKeyTy:
+		builder.AddData(psi.Signatures[0].Signature)
+	case txscript.MultiSigTy:
+		//签名顺序必须与赎回脚本中的公钥顺序一致
+		builder.AddOp(txscript.OP_0)
+		count := 0
+		for _, addr := range addrs {
+			for _, sig := range psi.Signatures {
+				if bytes.Equal(sig.PubKey, addr.Original) {
+					builder.AddData(sig.Signature)
+					count++
+					break
+				}
+			}
+			if count == required {
+				break
+			}
+		}
+		if count < required {
+			return nil, fmt.Errorf("msg[%d] input[%d] need %d signatures, but only %d match the redeem script",
+				psi.MessageIndex, psi.InputIndex, required, count)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported script class[%s] for partial signed tx", class.String())
+	}
+	if len(psi.RedeemScript) > 0 {
+		builder.AddData(psi.RedeemScript)
+	}
+	return builder.Script()
+}
+
+//所有签名收集完成后，生成最终的解锁脚本并验证，返回可以广播的交易
+func (pst *PartialSignedTx) Finalize() (*modules.Transaction, error) {
+	if !pst.IsComplete() {
+		return nil, errors.New("partial signed tx is not complete")
+	}
+	tx := pst.Tx.Clone()
+	for _, psi := range pst.Inputs {
+		unlock, err := pst.unlockScript(psi)
+		if err != nil {
+			return nil, err
+		}
+		pay, err := paymentInput(tx, psi)
+		if err != nil {
+			return nil, err
+		}
+		pay.Inputs[psi.InputIndex].SignatureScript = unlock
+	}
+	for _, psi := range pst.Inputs {
+		err := Instance.ScriptValidate(psi.LockScript, nil, tx, int(psi.MessageIndex), int(psi.InputIndex))
+		if err != nil {
+			return nil, fmt.Errorf("msg[%d] input[%d] unlock script validate fail:%s",
+				psi.MessageIndex, psi.InputIndex, err.Error())
+		}
+	}
+	return tx, nil
+}
+
+//编码为Hex字符串，便于在签名者之间传递
+func (pst *PartialSignedTx) EncodeToString() (string, error) {
+	data, err := rlp.EncodeToBytes(pst)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+//从Hex字符串解码部分签名交易
+func DecodePartialSignedTx(str string) (*PartialSignedTx, error) {
+	data, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	pst := &PartialSignedTx{}
+	if err := rlp.DecodeBytes(data, pst); err != nil {
+		return nil, err
+	}
+	if pst.Version != PartialSignedTxVersion {
+		return nil, fmt.Errorf("unsupported partial signed tx version:%d", pst.Version)
+	}
+	if pst.Tx == nil {
+		return nil, errors.New("partial signed tx has no transaction")
+	}
+	if err := pst.checkInputs(); err != nil {
+		return nil, err
+	}
+	return pst, nil
+}
+
+//找到Input所在的Payment，检查序号是否越界
+func paymentInput(tx *modules.Transaction, psi *PartialSignedInput) (*modules.PaymentPayload, error) {
+	msgs := tx.Messages()
+	if int(psi.MessageIndex) >= len(msgs) || msgs[psi.MessageIndex].App != modules.APP_PAYMENT {
+		return nil, fmt.Errorf("msg[%d] is not a payment message", psi.MessageIndex)
+	}
+	pay, ok := msgs[psi.MessageIndex].Payload.(*modules.PaymentPayload)
+	if !ok {
+		return nil, fmt.Errorf("msg[%d] invalid payment payload", psi.MessageIndex)
+	}
+	if int(psi.InputIndex) >= len(pay.Inputs) || pay.Inputs[psi.InputIndex].PreviousOutPoint == nil {
+		return nil, fmt.Errorf("msg[%d] input[%d] not exist", psi.MessageIndex, psi.InputIndex)
+	}
+	return pay, nil
+}
+
+//解码后的部分签名交易来自其他人，不能信任其中的签名要求和签名：
+//检查每个Input在交易中存在，根据锁定脚本和赎回脚本重新计算签名者和需要的签名数，并丢弃无效的签名
+func (pst *PartialSignedTx) checkInputs() error {
+	if len(pst.Inputs) == 0 {
+		return errors.New("partial signed tx has no input")
+	}
+	exist := make(map[[2]uint32]bool)
+	for i, psi := range pst.Inputs {
+		if psi == nil {
+			return fmt.Errorf("partial signed tx input[%d] is empty", i)
+		}
+		if _, err := paymentInput(pst.Tx, psi); err != nil {
+			return err
+		}
+		key := [2]uint32{psi.MessageIndex, psi.InputIndex}
+		if exist[key] {
+			return fmt.Errorf("msg[%d] input[%d] duplicated", psi.MessageIndex, psi.InputIndex)
+		}
+		exist[key] = true
+
+		if txscript.GetScriptClass(psi.LockScript) == txscript.ScriptHashTy {
+			addr, err := Instance.GetAddressFromScript(psi.LockScript)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(crypto.Hash160(psi.RedeemScript), addr.Bytes()) {
+				return fmt.Errorf("msg[%d] input[%d] redeem script not match address[%s]",
+					psi.MessageIndex, psi.InputIndex, addr.String())
+			}
+		} else if len(psi.RedeemScript) > 0 {
+			return fmt.Errorf("msg[%d] input[%d] has redeem script, but lock script is not P2SH",
+				psi.MessageIndex, psi.InputIndex)
+		}
+		signers, required, err := psi.requiredSigners()
+		if err != nil {
+			return fmt.Errorf("msg[%d] input[%d] %s", psi.MessageIndex, psi.InputIndex, err.Error())
+		}
+		psi.Signers = signers
+		psi.RequiredSigns = uint32(required)
+
+		sigs := []*PartialSignature{}
+		for _, sig := range psi.Signatures {
+			if sig == nil || !psi.isSigner(sig.PubKey) || hasSignature(sigs, sig.PubKey) {
+				continue
+			}
+			if !pst.verifySignature(psi, sig.PubKey, sig.Signature) {
+				continue
+			}
+			sigs = append(sigs, sig)
+		}
+		psi.Signatures = sigs
+	}
+	return nil
+}
+
+func (psi *PartialSignedInput) isSigner(pubKey []byte) bool {
+	if len(pubKey) == 0 {
+		return false
+	}
+	hash := crypto.Hash160(pubKey)
+	for _, signer := range psi.Signers {
+		if bytes.Equal(hash, signer.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSignature(sigs []*PartialSignature, pubKey []byte) bool {
+	for _, sig := range sigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package tokenengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildSignerFn(prvKey []byte, pubKey []byte, addr common.Address) (AddressGetPubKey, AddressGetSign) {
+	pubKeyFn := func(a common.Address) ([]byte, error) {
+		if a == addr {
+			return pubKey, nil
+		}
+		return nil, errors.New("not found")
+	}
+	signFn := func(a common.Address, msg []byte) ([]byte, error) {
+		if a == addr {
+			return crypto.MyCryptoLib.Sign(prvKey, msg)
+		}
+		return nil, errors.New("not found")
+	}
+	return pubKeyFn, signFn
+}
+
+//一个2/3多签的Input和一个用户1的P2PKH Input，3个用户分别离线签名后合并
+func TestPartialSignedTx(t *testing.T) {
+	lockScript, redeemScript, addressMulti := build23Address()
+	p1lockScript := Instance.GenerateP2PKHLockScript(crypto.Hash160(pubKey1B))
+	outPoint0 := modules.NewOutPoint(common.HexToHash("1111870aa8c894376dbd960a22171d0ad7be057a730e14d7103ed4a6dbb34873"), 0, 0)
+	outPoint1 := modules.NewOutPoint(common.HexToHash("2222870aa8c894376dbd960a22171d0ad7be057a730e14d7103ed4a6dbb34873"), 0, 1)
+	payment := &modules.PaymentPayload{}
+	payment.AddTxIn(modules.NewTxIn(outPoint0, []byte{}))
+	payment.AddTxIn(modules.NewTxIn(outPoint1, []byte{}))
+	payment.AddTxOut(modules.NewTxOut(1, p1lockScript, &modules.Asset{}))
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, payment)})
+
+	utxoLockScripts := map[modules.OutPoint][]byte{*outPoint0: lockScript, *outPoint1: p1lockScript}
+	multiAddr, _ := common.StringToAddress(addressMulti)
+	_, err := NewPartialSignedTx(tx, SigHashAll, utxoLockScripts, nil)
+	assert.NotNil(t, err, "P2SH input need redeem script")
+	pst, err := NewPartialSignedTx(tx, SigHashAll, utxoLockScripts, map[common.Address][]byte{multiAddr: redeemScript})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pst.Inputs))
+	assert.Equal(t, uint32(2), pst.Inputs[0].RequiredSigns)
+	assert.Equal(t, 3, len(pst.Inputs[0].Signers))
+	assert.Equal(t, uint32(1), pst.Inputs[1].RequiredSigns)
+	assert.Equal(t, []common.Address{address1}, pst.Inputs[1].Signers)
+	encoded, err := pst.EncodeToString()
+	assert.Nil(t, err)
+
+	//每个签名者拿到编码后的部分签名交易，单独签名
+	signBy := func(prvKey []byte, pubKey []byte, addr common.Address) *PartialSignedTx {
+		p, err := DecodePartialSignedTx(encoded)
+		assert.Nil(t, err)
+		pubKeyFn, signFn := buildSignerFn(prvKey, pubKey, addr)
+		_, err = p.Sign(pubKeyFn, signFn)
+		assert.Nil(t, err)
+		return p
+	}
+	pst1 := signBy(prvKey1B, pubKey1B, address1)
+	assert.Equal(t, 1, len(pst1.Inputs[0].Signatures))
+	assert.True(t, pst1.Inputs[1].IsComplete())
+	assert.False(t, pst1.IsComplete())
+	_, err = pst1.Finalize()
+	assert.NotNil(t, err)
+
+	pst3 := signBy(prvKey3B, pubKey3B, address3)
+	combined, err := CombinePartialSignedTx(pst1, pst3, pst1)
+	assert.Nil(t, err)
+	assert.True(t, combined.IsComplete())
+	assert.Equal(t, 2, len(combined.Inputs[0].Signatures))
+
+	signedTx, err := combined.Finalize()
+	assert.Nil(t, err)
+	assert.Nil(t, Instance.ScriptValidate(lockScript, nil, signedTx, 0, 0))
+	assert.Nil(t, Instance.ScriptValidate(p1lockScript, nil, signedTx, 0, 1))
+	assert.Equal(t, unsignedTxHash(tx), unsignedTxHash(signedTx))
+
+	//已经签名的交易可以重新转换为部分签名交易
+	again, err := NewPartialSignedTx(signedTx, SigHashAll, utxoLockScripts,
+		map[common.Address][]byte{multiAddr: redeemScript})
+	assert.Nil(t, err)
+	assert.True(t, again.IsComplete())
+
+	//非签名者的签名即使有效也被丢弃
+	forged := signBy(prvKey2B, pubKey2B, address2)
+	data, err := forged.signatureData(forged.Inputs[1], byte(SigHashAll))
+	assert.Nil(t, err)
+	sig, err := crypto.MyCryptoLib.Sign(prvKey2B, data)
+	assert.Nil(t, err)
+	forged.Inputs[1].Signers = append(forged.Inputs[1].Signers, address2)
+	forged.Inputs[1].Signatures = append(forged.Inputs[1].Signatures,
+		&PartialSignature{PubKey: pubKey2B, Signature: append(sig, byte(SigHashAll))})
+	combined, err = CombinePartialSignedTx(forged)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(combined.Inputs[1].Signatures))
+	assert.Equal(t, []common.Address{address1}, combined.Inputs[1].Signers)
+	assert.Equal(t, 1, len(combined.Inputs[0].Signatures))
+
+	//不同版本不能合并
+	pst3.Version = PartialSignedTxVersion + 1
+	_, err = CombinePartialSignedTx(pst1, pst3)
+	assert.NotNil(t, err)
+
+	//不同交易不能合并
+	payment.Outputs[0].Value = 2
+	other, _ := NewPartialSignedTx(tx, SigHashAll, utxoLockScripts, map[common.Address][]byte{multiAddr: redeemScript})
+	_, err = CombinePartialSignedTx(pst1, other)
+	assert.NotNil(t, err)
+
+	//不支持的版本
+	pst1.Version = PartialSignedTxVersion + 1
+	unknown, _ := pst1.EncodeToString()
+	_, err = DecodePartialSignedTx(unknown)
+	assert.NotNil(t, err)
+}
+
+//伪造的部分签名交易不能导致panic，签名要求以锁定脚本为准
+func TestDecodeCraftedPartialSignedTx(t *testing.T) {
+	p1lockScript := Instance.GenerateP2PKHLockScript(crypto.Hash160(pubKey1B))
+	outPoint := modules.NewOutPoint(common.HexToHash("1111870aa8c894376dbd960a22171d0ad7be057a730e14d7103ed4a6dbb34873"), 0, 0)
+	payment := &modules.PaymentPayload{}
+	payment.AddTxIn(modules.NewTxIn(outPoint, []byte{}))
+	payment.AddTxOut(modules.NewTxOut(1, p1lockScript, &modules.Asset{}))
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, payment)})
+	build := func() *PartialSignedTx {
+		pst, err := NewPartialSignedTx(tx, SigHashAll, map[modules.OutPoint][]byte{*outPoint: p1lockScript}, nil)
+		assert.Nil(t, err)
+		return pst
+	}
+	decode := func(pst *PartialSignedTx) (*PartialSignedTx, error) {
+		encoded, err := pst.EncodeToString()
+		assert.Nil(t, err)
+		return DecodePartialSignedTx(encoded)
+	}
+
+	//不需要签名，也没有签名
+	pst := build()
+	pst.Inputs[0].RequiredSigns = 0
+	decoded, err := decode(pst)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), decoded.Inputs[0].RequiredSigns)
+	assert.False(t, decoded.IsComplete())
+	_, err = decoded.Finalize()
+	assert.NotNil(t, err)
+
+	//序号越界
+	pst = build()
+	pst.Inputs[0].MessageIndex = 1
+	_, err = decode(pst)
+	assert.NotNil(t, err)
+	pst = build()
+	pst.Inputs[0].InputIndex = 1
+	_, err = decode(pst)
+	assert.NotNil(t, err)
+
+	//非签名者的签名和无效的签名被丢弃
+	pst = build()
+	pubKeyFn, signFn := buildSignerFn(prvKey1B, pubKey1B, address1)
+	_, err = pst.Sign(pubKeyFn, signFn)
+	assert.Nil(t, err)
+	pst.Inputs[0].Signatures = append([]*PartialSignature{
+		{PubKey: pubKey2B, Signature: pst.Inputs[0].Signatures[0].Signature},
+		{PubKey: pubKey1B, Signature: []byte{1, 2, 3}},
+	}, pst.Inputs[0].Signatures...)
+	decoded, err = decode(pst)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(decoded.Inputs[0].Signatures))
+	_, err = decoded.Finalize()
+	assert.Nil(t, err)
+}
@@ -21,13 +21,29 @@
 
 package tokenengine
 
-import "github.com/palletone/go-palletone/common"
+import (
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/dag/modules"
+)
 
 //用指定地址对应的私钥对消息进行签名，并返回签名结果
 type AddressGetSign func(common.Address, []byte) ([]byte, error)
 
+//需要完整交易才能签名的签名者，例如按签名策略检查交易的外部签名进程，
+//这些地址签名交易时不再通过AddressGetSign对签名数据签名
+type TxInputSigner interface {
+	//是否由该签名者为地址签名
+	IsTxInputSigner(addr common.Address) bool
+	//对交易中指定的Input签名，返回的签名不包含hashType
+	SignTxInput(addr common.Address, tx *modules.Transaction, hashType uint32, msgIdx, inputIdx int,
+		subScript []byte) ([]byte, error)
+}
+
 //根据地址获得对应的公钥
 type AddressGetPubKey func(common.Address) ([]byte, error)
 
 //根据合约地址，获得该合约对应的陪审团赎回脚本
 type PickupJuryRedeemScript func(common.Address) ([]byte, error)
+
+//使用Taproot调整值tweak调整后的私钥，对Hash进行Schnorr签名，并返回签名结果
+type SchnorrGetSign func(hash []byte, tweak []byte) ([]byte, error)
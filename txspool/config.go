@@ -29,8 +29,8 @@ import (
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
 	NoLocals  bool          // Whether local transaction handling should be disabled
-	Journal   string        // Journal of local transactions to survive node restarts
-	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+	Journal   string        // Journal of pool transactions to survive node restarts and crashes
+	Rejournal time.Duration // Time interval to regenerate the transaction journal
 
 	FeeLimit  uint64 // Minimum tx's fee  to enforce for acceptance into the pool
 	PriceBump uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
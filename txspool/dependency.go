@@ -35,6 +35,7 @@ type dags interface {
 	GetTxFromAddress(tx *modules.Transaction) ([]common.Address, error)
 	GetTransactionOnly(hash common.Hash) (*modules.Transaction, error)
 	IsTransactionExist(hash common.Hash) (bool, error)
+	GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error)
 	GetHeaderByHash(common.Hash) (*modules.Header, error)
 	GetUtxoEntry(outpoint *modules.OutPoint) (*modules.Utxo, error)
 	SubscribeChainHeadEvent(ch chan<- modules.ChainHeadEvent) event.Subscription
@@ -42,6 +43,7 @@ type dags interface {
 	GetTxFee(pay *modules.Transaction) (*modules.AmountAsset, error)
 	GetStxoEntry(outpoint *modules.OutPoint) (*modules.Stxo, error)
 	GetContractTpl(tplId []byte) (*modules.ContractTemplate, error)
+	GetContract(id []byte) (*modules.Contract, error)
 	GetContractJury(contractId []byte) (*modules.ElectionNode, error)
 	GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error)
 	GetContractStatesByPrefix(id []byte, prefix string) (map[string]*modules.ContractStateValue, error)
@@ -51,13 +53,16 @@ type dags interface {
 	GetMediators() map[common.Address]bool
 	GetChainParameters() *core.ChainParameters
 	GetNewestUnitTimestamp(token modules.AssetId) (int64, error)
+	GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error)
 	GetScheduledMediator(slotNum uint32) common.Address
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 	GetSlotAtTime(when time.Time) uint32
 	GetMediator(add common.Address) *core.Mediator
 
 	GetBlacklistAddress() ([]common.Address, *modules.StateVersion, error)
 	GetJurorByAddrHash(addrHash common.Hash) (*modules.JurorDeposit, error)
 	GetJurorReward(jurorAdd common.Address) common.Address
+	GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation
 
 	CheckReadSetValid(contractId []byte, readSet []modules.ContractReadSet) bool
 }
@@ -0,0 +1,403 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2018-2019
+ */
+
+package txspool
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/event"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/common/ptndb"
+	"github.com/palletone/go-palletone/dag/constants"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/palletone/go-palletone/dag/parameter"
+)
+
+const (
+	// feeEstimateWindow 估算手续费时参考最近多少个Unit的数据
+	feeEstimateWindow = 1000
+	// feeEstimateSaveInterval 每处理多少个Unit持久化一次估算数据
+	feeEstimateSaveInterval = 10
+	// feeEstimateMinSamples 历史数据少于该数量时不使用历史数据估算
+	feeEstimateMinSamples = 5
+	// MaxFeeEstimateTarget 最多估算多少个Unit内被打包所需的手续费
+	MaxFeeEstimateTarget = 100
+)
+
+// FeeEstimateConfidences 估算手续费的置信度
+var FeeEstimateConfidences = []float64{0.5, 0.8, 0.95}
+
+var ErrInvalidEstimateTarget = errors.New("fee estimate target units out of range")
+
+// FeeRateEstimate 在给定置信度下，交易在目标Unit数内被打包所需的费率(dao/KByte)
+type FeeRateEstimate struct {
+	Confidence float64 `json:"confidence"`
+	FeeRate    uint64  `json:"fee_rate"`
+}
+
+// FeeEstimate 是ptn_estimateFee的返回结果
+type FeeEstimate struct {
+	TargetUnits uint32             `json:"target_units"`
+	Asset       string             `json:"asset"`
+	Samples     int                `json:"samples"`      // 参与估算的历史交易数
+	BacklogSize uint64             `json:"backlog_size"` // 交易池中等待打包的该资产手续费交易大小
+	Estimates   []*FeeRateEstimate `json:"estimates"`
+}
+
+// feeRecord 一个已打包交易的费率，以及它在交易池中等待了多少个Unit
+type feeRecord struct {
+	Asset   string
+	FeeRate uint64
+	Height  uint64
+	Waited  uint32
+}
+
+// observedTx 交易进入交易池时的费率和当时的Unit高度
+type observedTx struct {
+	asset   string
+	feeRate uint64
+	height  uint64
+}
+
+// feeEstimatorState 持久化到数据库中的估算数据
+type feeEstimatorState struct {
+	LastHeight uint64
+	Records    []*feeRecord
+}
+
+type chainEventSubscriber interface {
+	SubscribeChainEvent(ch chan<- modules.ChainEvent) event.Subscription
+}
+
+type feeEstimatorPool interface {
+	SubscribeTxPreEvent(ch chan<- modules.TxPreEvent) event.Subscription
+	Get(hash common.Hash) (*TxPoolTransaction, common.Hash)
+	AllTxpoolTxs() map[common.Hash]*TxPoolTransaction
+}
+
+// FeeEstimator 根据最近Unit中交易的费率和等待时间，以及交易池中的积压交易，
+// 估算交易在目标Unit数内被打包所需要的费率
+type FeeEstimator struct {
+	db   ptndb.Database
+	pool feeEstimatorPool
+
+	mu         sync.RWMutex
+	lastHeight uint64
+	observed   map[common.Hash]*observedTx
+	records    []*feeRecord
+	processed  int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFeeEstimator 创建FeeEstimator，并从数据库中恢复上次的估算数据
+func NewFeeEstimator(db ptndb.Database) *FeeEstimator {
+	fe := &FeeEstimator{
+		db:       db,
+		observed: make(map[common.Hash]*observedTx),
+		records:  make([]*feeRecord, 0),
+		quit:     make(chan struct{}),
+	}
+	if err := fe.load(); err != nil {
+		log.Warn("Failed to load fee estimator data", "err", err)
+	}
+	return fe
+}
+
+// Start 订阅交易池和DAG的事件，开始记录交易费率
+func (fe *FeeEstimator) Start(chain chainEventSubscriber, pool feeEstimatorPool) {
+	fe.pool = pool
+	chainCh := make(chan modules.ChainEvent, 16)
+	txCh := make(chan modules.TxPreEvent, 1024)
+	chainSub := chain.SubscribeChainEvent(chainCh)
+	txSub := pool.SubscribeTxPreEvent(txCh)
+
+	fe.wg.Add(1)
+	go func() {
+		defer fe.wg.Done()
+		defer chainSub.Unsubscribe()
+		defer txSub.Unsubscribe()
+		for {
+			select {
+			case ev := <-txCh:
+				if ptx, _ := pool.Get(ev.Tx.Hash()); ptx != nil {
+					fe.observeTx(ptx)
+				}
+				fe.removeObserved(ev.Replaced)
+			case ev := <-chainCh:
+				if ev.Unit != nil {
+					fe.processUnit(ev.Unit)
+				}
+			case <-chainSub.Err():
+				return
+			case <-txSub.Err():
+				return
+			case <-fe.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止记录并持久化估算数据
+func (fe *FeeEstimator) Stop() {
+	close(fe.quit)
+	fe.wg.Wait()
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+	if err := fe.save(); err != nil {
+		log.Warn("Failed to save fee estimator data", "err", err)
+	}
+}
+
+//交易的手续费资产和费率(dao/KByte)
+func txFeeRate(tx *TxPoolTransaction) (string, uint64) {
+	asset := modules.PTN
+	var fee uint64
+	for _, ad := range tx.TxFee {
+		if ad == nil {
+			continue
+		}
+		if ad.Asset != nil {
+			asset = ad.Asset.String()
+		}
+		fee += ad.Amount
+	}
+	size := uint64(tx.Tx.Size())
+	if size == 0 {
+		return asset, 0
+	}
+	return asset, fee * 1024 / size
+}
+
+func (fe *FeeEstimator) observeTx(tx *TxPoolTransaction) {
+	if len(tx.TxFee) == 0 {
+		return
+	}
+	asset, rate := txFeeRate(tx)
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	hash := tx.Tx.Hash()
+	if _, has := fe.observed[hash]; has {
+		return
+	}
+	fe.observed[hash] = &observedTx{asset: asset, feeRate: rate, height: fe.lastHeight}
+}
+
+func (fe *FeeEstimator) removeObserved(hashes []common.Hash) {
+	if len(hashes) == 0 {
+		return
+	}
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	for _, hash := range hashes {
+		delete(fe.observed, hash)
+	}
+}
+
+// processUnit 记录Unit中已观察到的交易从进入交易池到被打包等待的Unit数
+func (fe *FeeEstimator) processUnit(unit *modules.Unit) {
+	//只统计主链(Gas Token)的Unit
+	if unit.Number() == nil || unit.Number().AssetID != modules.PTNCOIN {
+		return
+	}
+	height := unit.NumberU64()
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	for _, tx := range unit.Transactions() {
+		hash := tx.Hash()
+		obs, has := fe.observed[hash]
+		if !has {
+			continue
+		}
+		delete(fe.observed, hash)
+		waited := uint32(1)
+		if height > obs.height+1 {
+			waited = uint32(height - obs.height)
+		}
+		fe.records = append(fe.records, &feeRecord{Asset: obs.asset, FeeRate: obs.feeRate, Height: height,
+			Waited: waited})
+	}
+	if height > fe.lastHeight {
+		fe.lastHeight = height
+	}
+	fe.prune()
+	fe.processed++
+	if fe.processed%feeEstimateSaveInterval == 0 {
+		if err := fe.save(); err != nil {
+			log.Warn("Failed to save fee estimator data", "err", err)
+		}
+	}
+}
+
+//删除超出估算窗口的历史数据和长时间未被打包的交易
+func (fe *FeeEstimator) prune() {
+	if fe.lastHeight <= feeEstimateWindow {
+		return
+	}
+	min := fe.lastHeight - feeEstimateWindow
+	records := fe.records[:0]
+	for _, r := range fe.records {
+		if r.Height > min {
+			records = append(records, r)
+		}
+	}
+	fe.records = records
+	for hash, obs := range fe.observed {
+		if obs.height <= min {
+			delete(fe.observed, hash)
+		}
+	}
+}
+
+// EstimateFee 估算使用asset支付手续费的交易在targetUnits个Unit内被打包所需要的费率
+func (fe *FeeEstimator) EstimateFee(targetUnits uint32, asset string) (*FeeEstimate, error) {
+	if targetUnits == 0 || targetUnits > MaxFeeEstimateTarget {
+		return nil, ErrInvalidEstimateTarget
+	}
+	if asset == "" {
+		asset = modules.PTN
+	}
+	result := &FeeEstimate{TargetUnits: targetUnits, Asset: asset}
+
+	fe.mu.RLock()
+	records := make([]*feeRecord, 0, len(fe.records))
+	for _, r := range fe.records {
+		if r.Asset == asset {
+			records = append(records, r)
+		}
+	}
+	fe.mu.RUnlock()
+	result.Samples = len(records)
+	// 费率从高到低排列
+	sort.SliceStable(records, func(i, j int) bool { return records[i].FeeRate > records[j].FeeRate })
+
+	backlogRate, backlogSize := fe.backlogFeeRate(targetUnits, asset)
+	result.BacklogSize = backlogSize
+	for _, confidence := range FeeEstimateConfidences {
+		rate := historyFeeRate(records, targetUnits, confidence)
+		if backlogRate > rate {
+			rate = backlogRate
+		}
+		result.Estimates = append(result.Estimates, &FeeRateEstimate{Confidence: confidence, FeeRate: rate})
+	}
+	return result, nil
+}
+
+// historyFeeRate 从高到低遍历历史费率，每凑够feeEstimateMinSamples个交易为一组，
+// 返回在targetUnits内被打包的比例仍不低于confidence的最低一组的费率
+func historyFeeRate(records []*feeRecord, targetUnits uint32, confidence float64) uint64 {
+	if len(records) < feeEstimateMinSamples {
+		return 0
+	}
+	var rate uint64
+	found := false
+	total, confirmed := 0, 0
+	for _, r := range records {
+		total++
+		if r.Waited <= targetUnits {
+			confirmed++
+		}
+		if total < feeEstimateMinSamples {
+			continue
+		}
+		if float64(confirmed)/float64(total) < confidence {
+			break
+		}
+		rate = r.FeeRate
+		found = true
+		total, confirmed = 0, 0
+	}
+	if !found {
+		// 历史上没有任何费率满足要求，需要高于已知的最高费率
+		return records[0].FeeRate + 1
+	}
+	return rate
+}
+
+// backlogFeeRate 交易池中排在前面的积压交易超过了targetUnits个Unit的容量时，新交易的费率需要高于容量边界处的交易
+func (fe *FeeEstimator) backlogFeeRate(targetUnits uint32, asset string) (uint64, uint64) {
+	if fe.pool == nil {
+		return 0, 0
+	}
+	type backlogTx struct {
+		rate uint64
+		size uint64
+	}
+	backlog := make([]backlogTx, 0)
+	var total uint64
+	for _, tx := range fe.pool.AllTxpoolTxs() {
+		if tx.Pending || tx.Discarded || tx.Confirmed || len(tx.TxFee) == 0 {
+			continue
+		}
+		txAsset, rate := txFeeRate(tx)
+		if txAsset != asset {
+			continue
+		}
+		size := uint64(tx.Tx.Size())
+		backlog = append(backlog, backlogTx{rate: rate, size: size})
+		total += size
+	}
+	sort.Slice(backlog, func(i, j int) bool { return backlog[i].rate > backlog[j].rate })
+	capacity := uint64(targetUnits) * parameter.CurrentSysParameters.UnitMaxSize
+	var size uint64
+	for _, tx := range backlog {
+		size += tx.size
+		if size > capacity {
+			return tx.rate + 1, total
+		}
+	}
+	return 0, total
+}
+
+func (fe *FeeEstimator) save() error {
+	if fe.db == nil {
+		return nil
+	}
+	data, err := rlp.EncodeToBytes(&feeEstimatorState{LastHeight: fe.lastHeight, Records: fe.records})
+	if err != nil {
+		return err
+	}
+	return fe.db.Put(constants.FEE_ESTIMATOR_KEY, data)
+}
+
+func (fe *FeeEstimator) load() error {
+	if fe.db == nil {
+		return nil
+	}
+	data, err := fe.db.Get(constants.FEE_ESTIMATOR_KEY)
+	if err != nil || len(data) == 0 {
+		//首次启动没有数据
+		return nil
+	}
+	state := new(feeEstimatorState)
+	if err := rlp.DecodeBytes(data, state); err != nil {
+		return err
+	}
+	fe.lastHeight = state.LastHeight
+	fe.records = state.Records
+	log.Debugf("Loaded %d fee records, last height:%d", len(fe.records), fe.lastHeight)
+	return nil
+}
@@ -58,4 +58,7 @@ type ITxPool interface {
 	GetTxFee(tx *modules.Transaction) (*modules.AmountAsset, error)
 	OutPointIsSpend(outPoint *modules.OutPoint) (bool, error)
 	ValidateOrphanTx(tx *modules.Transaction) (bool, error)
+	MissingParents(hash common.Hash) []*modules.OutPoint
+	GetTxPackage(hash common.Hash) []*modules.Transaction
+	ProcessPackage(txs []*modules.Transaction) error
 }
@@ -20,19 +20,47 @@
 package txspool
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
-	"github.com/palletone/go-palletone/dag/errors"
 	"github.com/palletone/go-palletone/common/log"
-	"path/filepath"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+const (
+	// journalMagic 日志文件头，没有该文件头的按旧格式(TxPoolTransaction的RLP流)解析
+	journalMagic = "PTNTXJ01"
+	// 每条记录的头: 4字节记录长度 + 4字节crc32校验和
+	journalRecordHeaderSize = 8
+	// 单条记录的最大长度，超过该长度认为日志已损坏
+	maxJournalRecordSize = 4 * 1024 * 1024
+	// 日志中的记录数超过交易池中交易数的journalCompactRatio倍时压缩日志
+	journalCompactRatio = 2
+	// 记录数少于该值时不压缩
+	minJournalCompactRecords = 1024
+)
+
+var (
+	// errNoActiveJournal is returned if a transaction is attempted to be inserted
+	// into the journal, but no such file is currently open.
+	errNoActiveJournal = errors.New("no active journal")
+
+	// errJournalChecksum is returned if the checksum of a record mismatches.
+	errJournalChecksum = errors.New("journal record checksum mismatch")
+
+	// errJournalRecordSize is returned if the length of a record is out of range,
+	// the rest of the journal can't be parsed any more.
+	errJournalRecordSize = errors.New("journal record size out of range")
 )
 
-// errNoActiveJournal is returned if a transaction is attempted to be inserted
-// into the journal, but no such file is currently open.
-var errNoActiveJournal = errors.New("no active journal")
 // devNull is a WriteCloser that just discards anything written into it. Its
 // goal is to allow the transaction journal to write into a fake journal when
 // loading transactions on startup without printing warnings due to no file
@@ -42,11 +70,15 @@ type devNull struct{}
 func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
 func (*devNull) Close() error                      { return nil }
 
-// txJournal is a rotating log of transactions with the aim of storing locally
-// created transactions to allow non-executed ones to survive node restarts.
+// txJournal is a log of the local and remote transactions accepted by the pool,
+// with the aim of allowing non-executed ones to survive node restarts and crashes.
+// Every record is prefixed with its length and crc32 checksum, so a torn tail
+// or a corrupted record written by a crash is detected and dropped on load.
 type txJournal struct {
-	path   string         // Filesystem path to store the transactions at
-	writer io.WriteCloser // Output stream to write new transactions into
+	path    string         // Filesystem path to store the transactions at
+	writer  io.WriteCloser // Output stream to write new transactions into
+	records int            // Number of records in the current journal file
+	mu      sync.Mutex
 }
 
 // newTxJournal creates a new transaction journal to
@@ -55,84 +87,58 @@ func newTxJournal(path string) *txJournal {
 		path: path,
 	}
 }
-func getFileSize(filename string) int64 {
-	var size int64
-	filepath.Walk(filename, func(path string, f os.FileInfo, err error) error {
-		size = f.Size()
-		return nil
-	})
-	return size
-}
 
-// load parses a transaction journal dump from disk, loading its contents into
-// the specified pool.
-func (journal *txJournal) load(add func(*TxPoolTransaction) error) error {
+// load parses a transaction journal dump from disk, returning the transactions
+// sorted by their dependencies, parents first.
+func (journal *txJournal) load() ([]*modules.Transaction, error) {
 	// Skip the parsing if the journal file doens't exist at all
-	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
-		return nil
-	}
-	if getFileSize(journal.path) <= 0 {
-		log.Infof("the transaction.rlp is empty file.")
-		return nil
-	}
-
-	// Open the journal for loading any past transactions
 	input, err := os.Open(journal.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer input.Close()
 
-	// Temporarily discard any journal additions (don't double add on load)
-	journal.writer = new(devNull)
-	defer func() { journal.writer = nil }()
-
-	// Inject all transactions from the journal into the pool
-	stream := rlp.NewStream(input, 0)
-	total, dropped := 0, 0
-
-	for {
-		// Parse the next transaction and terminate on error
-		tx := new(TxPoolTransaction)
-		if err = stream.Decode(tx); err != nil {
-			if err.Error() == errors.ErrEOF.Error() {
-				err = nil
-			} else {
-				log.Infof("decode error:%s", err.Error())
-			}
-			break
-		}
-		// Import the transaction and bump the appropriate progress counters
-		total++
-		if tx.Tx != nil {
-			if err = add(tx); err != nil {
-				log.Debug("Failed to add journaled transaction", "err", err)
-				dropped++
-				continue
-			}
-		} else {
-			log.Debug("journal decode tx failed. ", "error", "tx is nil.")
-		}
+	txs, dropped, err := ReadTxs(input)
+	if err != nil {
+		log.Warn("Failed to parse the whole transaction journal", "err", err)
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
-
-	return err
+	journal.mu.Lock()
+	journal.records = len(txs) + dropped
+	journal.mu.Unlock()
+	log.Info("Loaded transaction journal", "transactions", len(txs), "dropped", dropped)
+	return sortJournalTxs(txs), nil
 }
 
-// insert adds the specified transaction to the local disk journal.
-func (journal *txJournal) insert(tx *TxPoolTransaction) error {
+// insert adds the specified transaction to the disk journal.
+func (journal *txJournal) insert(tx *modules.Transaction) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
 	if journal.writer == nil {
 		return errNoActiveJournal
 	}
-	if err := rlp.Encode(journal.writer, tx); err != nil {
+	if err := writeJournalRecord(journal.writer, tx); err != nil {
 		return err
 	}
+	journal.records++
 	return nil
 }
 
+// needCompact 日志中的失效记录(已打包、被替换的交易)过多时需要压缩
+func (journal *txJournal) needCompact(live int) bool {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return journal.records >= minJournalCompactRecords && journal.records > journalCompactRatio*live
+}
+
 // rotate regenerates the transaction journal based on the current contents of
-// the transaction pool.
-func (journal *txJournal) rotate(all map[common.Hash]*TxPoolTransaction) error {
+// the transaction pool. The new journal is written to a temporary file, synced
+// and then renamed over the live one, so a crash never leaves a partial journal.
+func (journal *txJournal) rotate(txs []*modules.Transaction) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
 	// Close the current journal (if any is open)
 	if journal.writer != nil {
 		if err := journal.writer.Close(); err != nil {
@@ -141,40 +147,176 @@ func (journal *txJournal) rotate(all map[common.Hash]*TxPoolTransaction) error {
 		journal.writer = nil
 	}
 	// Generate a new journal with the contents of the current pool
-	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	journaled := 0
-	for _, tx := range all {
-		if err = rlp.Encode(replacement, tx); err != nil {
-			replacement.Close()
-			return err
+	w := bufio.NewWriter(replacement)
+	if err = WriteTxs(w, txs); err == nil {
+		if err = w.Flush(); err == nil {
+			err = replacement.Sync()
 		}
-		journaled += 1
 	}
 	replacement.Close()
-
+	if err != nil {
+		return err
+	}
 	// Replace the live journal with the newly generated one
 	if err = os.Rename(journal.path+".new", journal.path); err != nil {
 		return err
 	}
-	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0755)
+	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
 	journal.writer = sink
-	log.Debug("Regenerated local transaction journal", "transactions", journaled, "accounts", len(all))
+	journal.records = len(txs)
+	log.Debug("Regenerated transaction journal", "transactions", len(txs))
 
 	return nil
 }
 
 // close flushes the transaction journal contents to disk and closes the file.
 func (journal *txJournal) close() error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
 	var err error
 	if journal.writer != nil {
+		if f, ok := journal.writer.(*os.File); ok {
+			f.Sync()
+		}
 		err = journal.writer.Close()
 		journal.writer = nil
 	}
 	return err
 }
+
+// WriteTxs 以交易日志的格式(文件头+带校验和的记录)写入一组交易，
+// 用于日志压缩以及在节点之间迁移交易池(gptn txpool dump)
+func WriteTxs(w io.Writer, txs []*modules.Transaction) error {
+	if _, err := io.WriteString(w, journalMagic); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := writeJournalRecord(w, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTxs 读取交易日志格式的交易，返回成功解析的交易以及丢弃的损坏记录数。
+// 校验和不一致的记录被跳过，文件末尾不完整的记录(写入时崩溃)被丢弃。
+// 没有文件头的按旧版本日志格式解析。
+func ReadTxs(r io.Reader) ([]*modules.Transaction, int, error) {
+	input := bufio.NewReader(r)
+	magic, err := input.Peek(len(journalMagic))
+	if err == io.EOF && len(magic) == 0 {
+		return nil, 0, nil
+	}
+	if err != nil || string(magic) != journalMagic {
+		return readLegacyTxs(input)
+	}
+	input.Discard(len(journalMagic))
+
+	txs := make([]*modules.Transaction, 0)
+	dropped := 0
+	for {
+		tx, err := readJournalRecord(input)
+		switch err {
+		case nil:
+			txs = append(txs, tx)
+		case io.EOF:
+			return txs, dropped, nil
+		case io.ErrUnexpectedEOF:
+			log.Debug("Dropped the torn tail of transaction journal")
+			return txs, dropped + 1, nil
+		case errJournalChecksum:
+			dropped++
+		default:
+			// 记录长度已不可信，后续记录无法解析
+			return txs, dropped + 1, err
+		}
+	}
+}
+
+func writeJournalRecord(w io.Writer, tx *modules.Transaction) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxJournalRecordSize {
+		return errJournalRecordSize
+	}
+	record := make([]byte, journalRecordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[journalRecordHeaderSize:], data)
+	// 一次写入整条记录，崩溃时最多留下一条不完整的记录
+	_, err = w.Write(record)
+	return err
+}
+
+func readJournalRecord(r io.Reader) (*modules.Transaction, error) {
+	header := make([]byte, journalRecordHeaderSize)
+	if n, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF && n == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size == 0 || size > maxJournalRecordSize {
+		return nil, errJournalRecordSize
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errJournalChecksum
+	}
+	tx := new(modules.Transaction)
+	if err := rlp.Decode(bytes.NewReader(data), tx); err != nil {
+		return nil, errJournalChecksum
+	}
+	return tx, nil
+}
+
+//兼容旧版本的日志: TxPoolTransaction的RLP流
+func readLegacyTxs(r io.Reader) ([]*modules.Transaction, int, error) {
+	stream := rlp.NewStream(r, 0)
+	txs := make([]*modules.Transaction, 0)
+	for {
+		tx := new(TxPoolTransaction)
+		if err := stream.Decode(tx); err != nil {
+			if err == io.EOF {
+				return txs, 0, nil
+			}
+			return txs, 1, err
+		}
+		if tx.Tx != nil {
+			txs = append(txs, tx.Tx)
+		}
+	}
+}
+
+// sortJournalTxs 去除重复的交易，并按依赖关系排序，父交易在前
+func sortJournalTxs(txs []*modules.Transaction) []*modules.Transaction {
+	unique := make([]*modules.Transaction, 0, len(txs))
+	seen := make(map[common.Hash]bool)
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		unique = append(unique, tx)
+	}
+	sorted, err := sortPackageTxs(unique)
+	if err != nil {
+		log.Warn("Failed to sort journaled transactions", "err", err)
+		return unique
+	}
+	return sorted
+}
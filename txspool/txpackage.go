@@ -0,0 +1,317 @@
+/*
+   This file is part of go-palletone.
+   go-palletone is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   go-palletone is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2018-2019
+ */
+
+package txspool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/log"
+	"github.com/palletone/go-palletone/dag/modules"
+)
+
+// MaxPackageTxs 一个交易包中最多包含的交易数
+const MaxPackageTxs = 25
+
+var (
+	// ErrPackageIncomplete is returned if a transaction of the package still misses
+	// its parents after all the package transactions are applied.
+	ErrPackageIncomplete = errors.New("transaction package is incomplete")
+
+	// ErrPackageTooLarge is returned if a package contains too many transactions.
+	ErrPackageTooLarge = errors.New("transaction package is too large")
+)
+
+// MissingParents 返回孤儿交易缺少的前驱交易的OutPoint，交易不是孤儿交易时返回nil
+func (pool *TxPool) MissingParents(hash common.Hash) []*modules.OutPoint {
+	inter, has := pool.orphans.Load(hash)
+	if !has {
+		return nil
+	}
+	otx := inter.(*TxPoolTransaction)
+	missing := make([]*modules.OutPoint, 0)
+	for _, msg := range otx.Tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		payment, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, in := range payment.Inputs {
+			if in.PreviousOutPoint == nil || in.PreviousOutPoint.TxHash.IsSelfHash() {
+				continue
+			}
+			if _, err := pool.GetUtxoEntry(in.PreviousOutPoint); err != nil {
+				missing = append(missing, in.PreviousOutPoint)
+			}
+		}
+	}
+	return missing
+}
+
+// GetTxPackage 返回交易及其在交易池中尚未打包的前驱交易，前驱交易在前
+func (pool *TxPool) GetTxPackage(hash common.Hash) []*modules.Transaction {
+	ptx, _ := pool.Get(hash)
+	if ptx == nil || ptx.Discarded || ptx.Confirmed {
+		return nil
+	}
+	pkg := NewTxPackage(ptx, pool.getPrecusorTxs(ptx, pool.AllTxpoolTxs(), pool.AllOrphanTxs()))
+	if len(pkg.Txs) > MaxPackageTxs {
+		log.Debugf("the package of tx[%s] is too large, count:%d", hash.String(), len(pkg.Txs))
+		return nil
+	}
+	txs := make([]*modules.Transaction, 0, len(pkg.Txs))
+	for _, tx := range pkg.Txs {
+		txs = append(txs, tx.Tx)
+	}
+	return txs
+}
+
+// ProcessPackage 原子地接受一组相互依赖的交易(父交易+子交易)。
+// 交易按依赖关系排序后，依次基于交易池的UTXO视图校验并加入交易池，
+// 任何一个交易不能被接受时，已加入的包内交易全部回滚，被移出的孤儿交易恢复。
+// 交易池已满时需要驱逐的低优先级交易在整个交易包被接受后才驱逐。
+// 交易包被接受后，花费了包内交易输出的孤儿交易会被重新处理。
+func (pool *TxPool) ProcessPackage(txs []*modules.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	if len(txs) > MaxPackageTxs {
+		return ErrPackageTooLarge
+	}
+	sorted, err := sortPackageTxs(txs)
+	if err != nil {
+		return err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.pkgMu.Lock()
+	pool.pkgTxs = make(map[common.Hash]bool)
+	for _, tx := range sorted {
+		pool.pkgTxs[tx.Hash()] = true
+	}
+	pool.pkgEvents = make([]modules.TxPreEvent, 0, len(sorted))
+	pool.pkgMu.Unlock()
+
+	added, orphans, err := pool.addPackageTxs(sorted)
+
+	pool.pkgMu.Lock()
+	pkg, events := pool.pkgTxs, pool.pkgEvents
+	pool.pkgTxs, pool.pkgEvents = nil, nil
+	pool.pkgMu.Unlock()
+
+	if err != nil {
+		for i := len(added) - 1; i >= 0; i-- {
+			pool.DeleteTxByHash(added[i].Tx.Hash())
+		}
+		for _, otx := range orphans {
+			pool.addOrphan(otx, otx.Tag)
+		}
+		return err
+	}
+	pool.discardOverflow(pkg)
+	for _, ev := range events {
+		go pool.txFeed.Send(ev)
+	}
+	pool.processOrphans(added)
+	return nil
+}
+
+// addPackageTxs 依次加入包内交易，返回已加入的交易和被移出孤儿池的交易，用于失败时回滚
+func (pool *TxPool) addPackageTxs(sorted []*modules.Transaction) (
+	[]*TxPoolTransaction, []*TxPoolTransaction, error) {
+	added := make([]*TxPoolTransaction, 0, len(sorted))
+	orphans := make([]*TxPoolTransaction, 0)
+	for _, tx := range sorted {
+		hash := tx.Hash()
+		if _, has := pool.all.Load(hash); has {
+			continue
+		}
+		if IsCoinBase(tx) {
+			return added, orphans, fmt.Errorf("transaction %s is an individual coinbase", hash.String())
+		}
+		// 已经是孤儿交易的，随交易包重新处理
+		if inter, has := pool.orphans.Load(hash); has {
+			pool.orphans.Delete(hash)
+			orphans = append(orphans, inter.(*TxPoolTransaction))
+		}
+		ptx := TxtoTxpoolTx(tx)
+		if err := pool.checkPoolDoubleSpend(ptx); err != nil {
+			return added, orphans, err
+		}
+		if _, err := pool.add(ptx, !pool.config.NoLocals); err != nil {
+			return added, orphans, err
+		}
+		if pool.isOrphanInPool(hash) {
+			pool.orphans.Delete(hash)
+			pool.deleteTxOutputs(tx)
+			log.Debugf("the tx[%s] of package still misses parents", hash.String())
+			return added, orphans, ErrPackageIncomplete
+		}
+		added = append(added, ptx)
+	}
+	return added, orphans, nil
+}
+
+// discardOverflow 交易包被接受后，交易池超出容量时驱逐优先级最低的交易，不驱逐包内交易
+func (pool *TxPool) discardOverflow(pkg map[common.Hash]bool) {
+	count := pool.AllLength() - int(pool.config.GlobalSlots+pool.config.GlobalQueue)
+	if count <= 0 {
+		return
+	}
+	for _, tx := range pool.priority_sorted.Discard(count) {
+		if pkg[tx.Tx.Hash()] {
+			pool.priority_sorted.Put(tx)
+			continue
+		}
+		log.Trace("Discarding freshly underpriced transaction", "hash", tx.Tx.Hash(), "price", tx.GetTxFee().Int64())
+		pool.removeTransaction(tx, true)
+	}
+}
+
+func (pool *TxPool) isPackageTx(hash common.Hash) bool {
+	pool.pkgMu.Lock()
+	defer pool.pkgMu.Unlock()
+	return pool.pkgTxs != nil && pool.pkgTxs[hash]
+}
+
+// processOrphans 重新处理花费了已接受交易输出的孤儿交易
+func (pool *TxPool) processOrphans(accepted []*TxPoolTransaction) {
+	parents := make(map[common.Hash]bool)
+	for _, tx := range accepted {
+		parents[tx.Tx.Hash()] = true
+	}
+	for len(parents) > 0 {
+		next := make(map[common.Hash]bool)
+		for hash, otx := range pool.AllOrphanTxs() {
+			if !spendsAny(otx.Tx, parents) {
+				continue
+			}
+			if isOrphan, _ := pool.ValidateOrphanTx(otx.Tx); isOrphan {
+				continue
+			}
+			pool.orphans.Delete(hash)
+			otx.IsOrphan = false
+			if _, err := pool.add(otx, !pool.config.NoLocals); err != nil {
+				log.Debugf("the orphan tx[%s] is not accepted, err:%s", hash.String(), err.Error())
+				continue
+			}
+			if _, has := pool.all.Load(hash); has {
+				next[hash] = true
+			}
+		}
+		parents = next
+	}
+}
+
+func (pool *TxPool) deleteTxOutputs(tx *modules.Transaction) {
+	hash := tx.Hash()
+	for i, msg := range tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		if payment, ok := msg.Payload.(*modules.PaymentPayload); ok {
+			for j := range payment.Outputs {
+				pool.deleteOrphanTxOutputs(modules.OutPoint{TxHash: hash, MessageIndex: uint32(i), OutIndex: uint32(j)})
+			}
+		}
+	}
+}
+
+//交易包处理过程中，包内交易的通知延迟到整个交易包被接受后发送
+func (pool *TxPool) sendTxPreEvent(ev modules.TxPreEvent) {
+	pool.pkgMu.Lock()
+	if pool.pkgTxs != nil && pool.pkgTxs[ev.Tx.Hash()] {
+		pool.pkgEvents = append(pool.pkgEvents, ev)
+		pool.pkgMu.Unlock()
+		return
+	}
+	pool.pkgMu.Unlock()
+	go pool.txFeed.Send(ev)
+}
+
+//交易是否花费了hashes中任意一个交易的输出
+func spendsAny(tx *modules.Transaction, hashes map[common.Hash]bool) bool {
+	for _, msg := range tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		payment, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, in := range payment.Inputs {
+			if in.PreviousOutPoint != nil && hashes[in.PreviousOutPoint.TxHash] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortPackageTxs 按依赖关系对交易包排序，父交易在前
+func sortPackageTxs(txs []*modules.Transaction) ([]*modules.Transaction, error) {
+	pkg := make(map[common.Hash]*modules.Transaction)
+	for _, tx := range txs {
+		if tx == nil {
+			return nil, errors.New("nil transaction in package")
+		}
+		hash := tx.Hash()
+		if _, has := pkg[hash]; has {
+			return nil, fmt.Errorf("duplicate transaction %s in package", hash.String())
+		}
+		pkg[hash] = tx
+	}
+	sorted := make([]*modules.Transaction, 0, len(txs))
+	visited := make(map[common.Hash]bool)
+	visiting := make(map[common.Hash]bool)
+	var visit func(tx *modules.Transaction) error
+	visit = func(tx *modules.Transaction) error {
+		hash := tx.Hash()
+		if visited[hash] {
+			return nil
+		}
+		if visiting[hash] {
+			return fmt.Errorf("circular dependency of transaction %s in package", hash.String())
+		}
+		visiting[hash] = true
+		for _, outpoint := range tx.GetSpendOutpoints() {
+			if parent, has := pkg[outpoint.TxHash]; has && outpoint.TxHash != hash {
+				if err := visit(parent); err != nil {
+					return err
+				}
+			}
+		}
+		visiting[hash] = false
+		visited[hash] = true
+		sorted = append(sorted, tx)
+		return nil
+	}
+	for _, tx := range txs {
+		if err := visit(tx); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
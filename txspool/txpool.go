@@ -128,7 +128,7 @@ type TxPool struct {
 	txFeed      event.Feed
 	scope       event.SubscriptionScope
 	txValidator IValidator
-	journal     *txJournal // Journal of local transaction to back up to disk
+	journal     *txJournal // Journal of pool transactions to back up to disk
 
 	all             sync.Map          // All transactions to allow lookups
 	priority_sorted *txPrioritiedList // All transactions sorted by price and priority
@@ -137,6 +137,10 @@ type TxPool struct {
 	outputs         sync.Map          // 缓存 交易的outputs
 	sequenTxs       *SequeueTxPoolTxs
 
+	pkgMu     sync.Mutex
+	pkgTxs    map[common.Hash]bool // 正在处理的交易包中的交易
+	pkgEvents []modules.TxPreEvent // 交易包被接受后才发送的通知
+
 	mu             sync.RWMutex
 	wg             sync.WaitGroup // for shutdown sync
 	quit           chan struct{}  // used for exit
@@ -204,15 +208,17 @@ func NewTxPool4DI(config TxPoolConfig, cachedb palletcache.ICache, unit dags,
 	return pool
 }
 func (pool *TxPool) startJournal(config TxPoolConfig) {
-	// If local transactions and journaling is enabled, load from disk
-	if !config.NoLocals && config.Journal != "" {
+	// If journaling is enabled, replay the journaled transactions from disk
+	if config.Journal != "" {
 		log.Info("Journal path:" + config.Journal)
 		pool.journal = newTxJournal(config.Journal)
 
-		if err := pool.journal.load(pool.addLocal); err != nil {
+		txs, err := pool.journal.load()
+		if err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
 		}
-		if err := pool.journal.rotate(pool.local()); err != nil {
+		pool.replayJournal(txs)
+		if err := pool.journal.rotate(pool.journalTxs()); err != nil {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
@@ -273,13 +279,21 @@ func (pool *TxPool) loop() {
 
 			// Handle inactive account transaction eviction
 		case <-evict.C:
+			// 日志中的失效记录过多时压缩日志
+			if pool.journal != nil && pool.journal.needCompact(pool.AllLength()) {
+				pool.mu.Lock()
+				if err := pool.journal.rotate(pool.journalTxs()); err != nil {
+					log.Warn("Failed to compact tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 
-			// Handle local transaction journal rotation ----- once a honr -----
+			// Handle transaction journal rotation ----- once a honr -----
 		case <-journal.C:
 			if pool.journal != nil {
 				pool.mu.Lock()
-				if err := pool.journal.rotate(pool.local()); err != nil {
-					log.Warn("Failed to rotate local tx journal", "err", err)
+				if err := pool.journal.rotate(pool.journalTxs()); err != nil {
+					log.Warn("Failed to rotate tx journal", "err", err)
 				}
 				pool.mu.Unlock()
 			}
@@ -570,6 +584,18 @@ func (pool *TxPool) add(tx *TxPoolTransaction, local bool) (bool, error) {
 		}
 		tx.TxFee = append(tx.TxFee, addition...)
 	}
+	if len(tx.TxFee) == 0 {
+		if amount, err := pool.GetTxFee(tx.Tx); err == nil {
+			tx.TxFee = append(tx.TxFee, &modules.Addition{Amount: amount.Amount, Asset: amount.Asset})
+		}
+	}
+	// 与交易池中的交易双花时，只有冲突交易可替换且新交易手续费更高才接受(RBF)
+	replaced, err := pool.checkReplacement(tx)
+	if err != nil {
+		txInvalidPrometheus.Add(1)
+		log.Debug("Discarding double spend transaction", "hash", hash.String(), "err", err.Error())
+		return false, err
+	}
 	// 计算优先级
 	pool.setPriorityLvl(tx)
 
@@ -582,8 +608,9 @@ func (pool *TxPool) add(tx *TxPoolTransaction, local bool) (bool, error) {
 			return false, ErrUnderpriced
 		}
 		// New transaction is better than our worse ones, make room for it
+		// 交易包中的交易在整个交易包被接受后再腾出空间，交易包回滚时不会误删交易
 		count := length - int(pool.config.GlobalSlots+pool.config.GlobalQueue-1)
-		if count > 0 {
+		if count > 0 && !pool.isPackageTx(hash) {
 			drop := pool.priority_sorted.Discard(count)
 			for _, tx := range drop {
 				log.Trace("Discarding freshly underpriced transaction", "hash", hash, "price", tx.GetTxFee().Int64())
@@ -591,35 +618,66 @@ func (pool *TxPool) add(tx *TxPoolTransaction, local bool) (bool, error) {
 			}
 		}
 	}
+	// Evict the replaced transactions and their descendants.
+	replacedHashes := pool.removeReplaced(replaced)
+	if len(replacedHashes) > 0 {
+		log.Info("Replaced pool transactions", "hash", hash.String(), "replaced", len(replacedHashes))
+	}
 	// Add the transaction to the pool  and mark the referenced outpoints as spent by the pool.
 	go pool.priority_sorted.Put(tx)
 	pool.all.Store(hash, tx)
 	pool.addCache(tx)
-	go pool.journalTx(tx)
+	pool.journalTx(tx)
 	txValidPrometheus.Add(1)
 	// We've directly injected a replacement transaction, notify subsystems
-	go pool.txFeed.Send(modules.TxPreEvent{Tx: tx.Tx})
+	pool.sendTxPreEvent(modules.TxPreEvent{Tx: tx.Tx, Replaced: replacedHashes})
 	return true, nil
 }
 
-// journalTx adds the specified transaction to the local disk journal if it is
-// deemed to have been sent from a local account.
+// journalTx adds the specified transaction to the disk journal, both local and
+// remote transactions are journaled to survive node restarts.
 func (pool *TxPool) journalTx(tx *TxPoolTransaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.config.NoLocals {
+	// Only journal if it's enabled
+	if pool.journal == nil {
 		return
 	}
-	if len(tx.From) > 0 {
-		if pool.journal == nil {
-			log.Trace("Pool journal is nil.", "journal", pool.journal.path)
-			return
+	if err := pool.journal.insert(tx.Tx); err != nil {
+		log.Warn("Failed to journal transaction", "hash", tx.Tx.Hash().String(), "err", err)
+	}
+}
+
+// replayJournal 按依赖关系将日志中的交易重新提交到交易池，重放期间不写日志
+func (pool *TxPool) replayJournal(txs []*modules.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	pool.journal.mu.Lock()
+	pool.journal.writer = new(devNull)
+	pool.journal.mu.Unlock()
+
+	dropped := 0
+	for _, tx := range txs {
+		if _, err := pool.ProcessTransaction(tx, true, false, 0); err != nil {
+			log.Debug("Failed to add journaled transaction", "hash", tx.Hash().String(), "err", err)
+			dropped++
 		}
 	}
-	pool.mu.Lock()
-	defer pool.mu.Unlock()
-	if err := pool.journal.insert(tx); err != nil {
-		log.Warn("Failed to journal local transaction", "err", err)
+	pool.journal.mu.Lock()
+	pool.journal.writer = nil
+	pool.journal.mu.Unlock()
+	log.Info("Replayed transaction journal", "transactions", len(txs), "dropped", dropped)
+}
+
+// journalTxs 返回需要写入日志的交易：交易池中尚未确认的交易，父交易在前
+func (pool *TxPool) journalTxs() []*modules.Transaction {
+	txs := make([]*modules.Transaction, 0)
+	for _, tx := range pool.AllTxpoolTxs() {
+		if tx.Confirmed || tx.Discarded {
+			continue
+		}
+		txs = append(txs, tx.Tx)
 	}
+	return sortJournalTxs(txs)
 }
 
 // promoteTx adds a transaction to the pending (processable) list of transactions.
@@ -838,12 +896,8 @@ func (pool *TxPool) maybeAcceptTransaction(tx *modules.Transaction, rateLimit bo
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
+	//双花检查在add中进行，可替换的冲突交易会被替换(RBF)
 	p_tx := TxtoTxpoolTx(tx)
-	err = pool.checkPoolDoubleSpend(p_tx)
-	if err != nil {
-		log.Infof("the tx[%s] p2p send is double spend,don't add txpool. ", txHash.String())
-		return nil, err
-	}
 	_, err1 := pool.add(p_tx, !pool.config.NoLocals)
 	log.Debug("accepted tx and add pool.", "err", err1, "rateLimit", rateLimit)
 	if err1 != nil {
@@ -1253,6 +1307,130 @@ func (pool *TxPool) checkPoolDoubleSpend(tx *TxPoolTransaction) error {
 	return nil
 }
 
+// checkReplacement 检查交易是否与交易池中的交易双花。
+// 若所有冲突交易都声明了可替换(SequenceReplaceable)，且新交易的总手续费高于被替换交易及其后代交易的手续费之和，
+// 费率也高于每个冲突交易，则返回需要被驱逐的交易(冲突交易及其后代交易)。
+func (pool *TxPool) checkReplacement(tx *TxPoolTransaction) ([]*TxPoolTransaction, error) {
+	hash := tx.Tx.Hash()
+	conflicts := make(map[common.Hash]*TxPoolTransaction)
+	for _, msg := range tx.Tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		payment, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for _, input := range payment.Inputs {
+			if input.PreviousOutPoint == nil || input.PreviousOutPoint.TxHash.IsSelfHash() {
+				continue
+			}
+			inter, has := pool.outpoints.Load(*input.PreviousOutPoint)
+			if !has {
+				continue
+			}
+			ptx := inter.(*TxPoolTransaction)
+			ptxHash := ptx.Tx.Hash()
+			if ptxHash == hash {
+				continue
+			}
+			if ptx.Pending || ptx.Confirmed || !ptx.Tx.IsReplaceable() {
+				str := fmt.Sprintf("the outpoint: %s is already spent by transaction %s in the memory pool",
+					input.PreviousOutPoint.String(), ptxHash.String())
+				return nil, errors.New(str)
+			}
+			conflicts[ptxHash] = ptx
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+	evicted := make(map[common.Hash]*TxPoolTransaction)
+	for _, ptx := range conflicts {
+		pool.collectDescendants(ptx, evicted)
+	}
+	var evictedFee uint64
+	for ehash, ptx := range evicted {
+		if ptx.Pending || ptx.Confirmed {
+			return nil, fmt.Errorf("the descendant transaction %s of replaced tx has been packed", ehash.String())
+		}
+		evictedFee += ptx.GetTxFee().Uint64()
+	}
+	// 新交易不能花费被替换交易的输出
+	for _, outpoint := range tx.Tx.GetSpendOutpoints() {
+		if _, has := evicted[outpoint.TxHash]; has {
+			return nil, fmt.Errorf("replacement transaction spends output of replaced transaction %s",
+				outpoint.TxHash.String())
+		}
+	}
+	fee := tx.GetTxFee().Uint64()
+	if fee <= evictedFee {
+		log.Debug("Replacement fee too low", "hash", hash.String(), "fee", fee, "replaced_fee", evictedFee)
+		return nil, ErrReplaceUnderpriced
+	}
+	feeRate := float64(fee) / tx.Tx.Size().Float64()
+	for _, ptx := range conflicts {
+		if feeRate <= float64(ptx.GetTxFee().Uint64())/ptx.Tx.Size().Float64() {
+			log.Debug("Replacement fee rate too low", "hash", hash.String(), "conflict", ptx.Tx.Hash().String())
+			return nil, ErrReplaceUnderpriced
+		}
+	}
+	result := make([]*TxPoolTransaction, 0, len(evicted))
+	for _, ptx := range evicted {
+		result = append(result, ptx)
+	}
+	return result, nil
+}
+
+// collectDescendants 收集交易及其在交易池中的所有后代交易
+func (pool *TxPool) collectDescendants(tx *TxPoolTransaction, result map[common.Hash]*TxPoolTransaction) {
+	hash := tx.Tx.Hash()
+	if _, has := result[hash]; has {
+		return
+	}
+	result[hash] = tx
+	for i, msg := range tx.Tx.Messages() {
+		if msg.App != modules.APP_PAYMENT {
+			continue
+		}
+		payment, ok := msg.Payload.(*modules.PaymentPayload)
+		if !ok {
+			continue
+		}
+		for j := range payment.Outputs {
+			preout := modules.OutPoint{TxHash: hash, MessageIndex: uint32(i), OutIndex: uint32(j)}
+			if redeemer, has := pool.outpoints.Load(preout); has {
+				pool.collectDescendants(redeemer.(*TxPoolTransaction), result)
+			}
+		}
+	}
+}
+
+// removeReplaced 驱逐被替换的交易，并删除其在交易池中的输出，返回被驱逐交易的哈希
+func (pool *TxPool) removeReplaced(txs []*TxPoolTransaction) []common.Hash {
+	if len(txs) == 0 {
+		return nil
+	}
+	hashes := make([]common.Hash, 0, len(txs))
+	for _, ptx := range txs {
+		hash := ptx.Tx.Hash()
+		pool.removeTransaction(ptx, false)
+		for i, msg := range ptx.Tx.Messages() {
+			if msg.App != modules.APP_PAYMENT {
+				continue
+			}
+			if payment, ok := msg.Payload.(*modules.PaymentPayload); ok {
+				for j := range payment.Outputs {
+					pool.deleteOrphanTxOutputs(modules.OutPoint{TxHash: hash, MessageIndex: uint32(i), OutIndex: uint32(j)})
+				}
+			}
+		}
+		hashes = append(hashes, hash)
+	}
+	pool.priority_sorted.Removed()
+	return hashes
+}
+
 func (pool *TxPool) OutPointIsSpend(outPoint *modules.OutPoint) (bool, error) {
 	if outPoint.TxHash.IsSelfHash() {
 		return false, nil
@@ -1397,6 +1575,11 @@ func (pool *TxPool) Stop() {
 	pool.scope.Close()
 	// pool.wg.Wait()
 	if pool.journal != nil {
+		pool.mu.Lock()
+		if err := pool.journal.rotate(pool.journalTxs()); err != nil {
+			log.Warn("Failed to rotate tx journal", "err", err)
+		}
+		pool.mu.Unlock()
 		pool.journal.close()
 	}
 	log.Info("Transaction pool stopped")
@@ -1536,32 +1719,46 @@ func (pool *TxPool) GetSortedTxs(hash common.Hash, index uint64) ([]*TxPoolTrans
 		list = append(list, tx)
 		total += tx.Tx.Size()
 	}
+	// 每个交易与其前驱交易组成交易包，按交易包的整体优先级排序(CPFP)
+	packages := make(TxPackages, 0)
 	for {
 		if time.Since(t0) > time.Millisecond*800 {
-			log.Infof("get sorted timeout spent times: %s , count: %d ", time.Since(t0), len(list))
-			break
-		}
-		if total >= unit_size {
+			log.Infof("get sorted timeout spent times: %s , count: %d ", time.Since(t0), len(packages))
 			break
 		}
 		tx := pool.priority_sorted.Get()
 		if tx == nil {
-			log.Debugf("The task of txspool get priority_pricedtx has been finished,count:%d", len(list))
+			log.Debugf("The task of txspool get priority_pricedtx has been finished,count:%d", len(packages))
 			break
-		} else {
-			if !tx.Pending {
-				if has, _ := pool.unit.IsTransactionExist(tx.Tx.Hash()); has {
-					continue
-				}
-				// add precusorTxs 获取该交易的前驱交易列表
-				p_txs := pool.getPrecusorTxs(tx, poolTxs, orphanTxs)
-				for _, ptx := range p_txs {
-					list = append(list, ptx)
-					total += ptx.Tx.Size()
-				}
-				list = append(list, tx)
-				total += tx.Tx.Size()
+		}
+		if tx.Pending {
+			continue
+		}
+		if has, _ := pool.unit.IsTransactionExist(tx.Tx.Hash()); has {
+			continue
+		}
+		// add precusorTxs 获取该交易的前驱交易列表
+		p_txs := pool.getPrecusorTxs(tx, poolTxs, orphanTxs)
+		packages = append(packages, NewTxPackage(tx, p_txs))
+	}
+	sort.Stable(packages)
+	selected := make(map[common.Hash]bool)
+	for i, pkg := range packages {
+		if total >= unit_size {
+			// 放不下的交易重新放回优先级队列
+			for _, left := range packages[i:] {
+				pool.priority_sorted.Put(left.Txs[len(left.Txs)-1])
+			}
+			break
+		}
+		for _, ptx := range pkg.Txs {
+			thash := ptx.Tx.Hash()
+			if selected[thash] {
+				continue
 			}
+			selected[thash] = true
+			list = append(list, ptx)
+			total += ptx.Tx.Size()
 		}
 	}
 	t2 := time.Now()
@@ -409,3 +409,45 @@ func (mr *MockITxPoolMockRecorder) ValidateOrphanTx(tx interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateOrphanTx", reflect.TypeOf((*MockITxPool)(nil).ValidateOrphanTx), tx)
 }
+
+// MissingParents mocks base method
+func (m *MockITxPool) MissingParents(hash common.Hash) []*modules.OutPoint {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MissingParents", hash)
+	ret0, _ := ret[0].([]*modules.OutPoint)
+	return ret0
+}
+
+// MissingParents indicates an expected call of MissingParents
+func (mr *MockITxPoolMockRecorder) MissingParents(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MissingParents", reflect.TypeOf((*MockITxPool)(nil).MissingParents), hash)
+}
+
+// GetTxPackage mocks base method
+func (m *MockITxPool) GetTxPackage(hash common.Hash) []*modules.Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTxPackage", hash)
+	ret0, _ := ret[0].([]*modules.Transaction)
+	return ret0
+}
+
+// GetTxPackage indicates an expected call of GetTxPackage
+func (mr *MockITxPoolMockRecorder) GetTxPackage(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxPackage", reflect.TypeOf((*MockITxPool)(nil).GetTxPackage), hash)
+}
+
+// ProcessPackage mocks base method
+func (m *MockITxPool) ProcessPackage(txs []*modules.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessPackage", txs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessPackage indicates an expected call of ProcessPackage
+func (mr *MockITxPoolMockRecorder) ProcessPackage(txs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessPackage", reflect.TypeOf((*MockITxPool)(nil).ProcessPackage), txs)
+}
@@ -20,10 +20,14 @@
 package txspool
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"github.com/palletone/go-palletone/validator"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
@@ -103,6 +107,10 @@ func (q *UnitDag4Test) GetJurorReward(jurorAdd common.Address) common.Address {
 	return jurorAdd
 }
 
+func (q *UnitDag4Test) GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation {
+	return modules.NewJurorReputation()
+}
+
 func (q *UnitDag4Test) GetSlotAtTime(when time.Time) uint32 {
 	return 0
 }
@@ -115,10 +123,17 @@ func (q *UnitDag4Test) GetScheduledMediator(slotNum uint32) common.Address {
 	return common.Address{}
 }
 
+func (q *UnitDag4Test) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	return nil, fmt.Errorf("not found")
+}
+
 func (q *UnitDag4Test) GetNewestUnitTimestamp(token modules.AssetId) (int64, error) {
 	return 0, nil
 }
 
+func (q *UnitDag4Test) GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error) {
+	return common.Hash{}, modules.NewChainIndex(token, 0), nil
+}
 func (q *UnitDag4Test) GetChainParameters() *core.ChainParameters {
 	return nil
 }
@@ -129,6 +144,9 @@ func (ud *UnitDag4Test) StateAt(common.Hash) (*palletdb.MemDatabase, error) {
 func (ud *UnitDag4Test) GetHeaderByHash(common.Hash) (*modules.Header, error) {
 	return nil, nil
 }
+func (ud *UnitDag4Test) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
+	return nil, nil
+}
 func (ud *UnitDag4Test) IsTransactionExist(hash common.Hash) (bool, error) {
 	return false, nil
 }
@@ -206,6 +224,9 @@ func (ud *UnitDag4Test) GetBlacklistAddress() ([]common.Address, *modules.StateV
 func (ud *UnitDag4Test) GetContractJury(contractId []byte) (*modules.ElectionNode, error) {
 	return nil, nil
 }
+func (ud *UnitDag4Test) GetContract(id []byte) (*modules.Contract, error) {
+	return nil, fmt.Errorf("contract %x not found", id)
+}
 func (ud *UnitDag4Test) GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error) {
 	return nil, nil, nil
 }
@@ -270,6 +291,7 @@ func TestTransactionAddingTxs(t *testing.T) {
 	config := DefaultTxPoolConfig
 	config.GlobalSlots = 4096
 	config.NoLocals = true
+	config.Journal = ""
 
 	utxos := mockPtnUtxos()
 	for outpoint, utxo := range utxos {
@@ -490,3 +512,394 @@ func TestPriorityHeap(t *testing.T) {
 	log.Debug("best priority  tx: ", "info", biger)
 	log.Debug("bad priority  tx: ", "info", bad)
 }
+
+func newPaymentTx4Test(outpoint *modules.OutPoint, amount uint64, sequence uint32) *modules.Transaction {
+	addr, _ := common.StringToAddress("P13pBrshF6JU7QhMmzJjXx3mWHh13YHAUAa")
+	lockScript := tokenengine.Instance.GenerateLockScript(addr)
+	input := modules.NewTxIn(outpoint, []byte{})
+	input.Sequence = sequence
+	output := modules.NewTxOut(amount, lockScript, modules.NewPTNAsset())
+	pay := modules.NewPaymentPayload([]*modules.Input{input}, []*modules.Output{output})
+	return modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_PAYMENT, pay)})
+}
+
+func newTxPool4RBFTest(utxoAmounts ...uint64) (*TxPool, []*modules.OutPoint) {
+	unitchain := NewUnitDag4Test()
+	utxos := make(map[modules.OutPoint]*modules.Utxo)
+	outpoints := make([]*modules.OutPoint, 0, len(utxoAmounts))
+	for i, amount := range utxoAmounts {
+		outpoint := modules.NewOutPoint(common.BytesToHash([]byte("rbf test utxo")), uint32(i), 0)
+		utxos[*outpoint] = &modules.Utxo{Asset: modules.NewPTNAsset(), Amount: amount}
+		outpoints = append(outpoints, outpoint)
+	}
+	unitchain.outpoints["rbf"] = utxos
+	config := DefaultTxPoolConfig
+	config.NoLocals = true
+	pool := NewTxPool4DI(config, freecache.NewCache(1*1024*1024), unitchain,
+		tokenengine.Instance, &validator.ValidatorAllPass{})
+	return pool, outpoints
+}
+
+func TestReplaceByFee(t *testing.T) {
+	pool, outpoints := newTxPool4RBFTest(1000)
+	defer pool.Stop()
+	events := make(chan modules.TxPreEvent, 16)
+	sub := pool.SubscribeTxPreEvent(events)
+	defer sub.Unsubscribe()
+
+	// parent(fee 100) 可替换, child(fee 20) 花费parent的输出
+	parent := newPaymentTx4Test(outpoints[0], 900, modules.SequenceReplaceable)
+	child := newPaymentTx4Test(modules.NewOutPoint(parent.Hash(), 0, 0), 880, 0)
+	if err := pool.AddLocal(parent); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddLocal(child); err != nil {
+		t.Fatal(err)
+	}
+	// 手续费110，不高于被替换交易及其后代交易的手续费之和120
+	low := newPaymentTx4Test(outpoints[0], 890, 0)
+	if err := pool.AddLocal(low); err != ErrReplaceUnderpriced {
+		t.Fatalf("expect %v, got %v", ErrReplaceUnderpriced, err)
+	}
+	// 花费被替换交易的输出
+	spendReplaced := newPaymentTx4Test(modules.NewOutPoint(parent.Hash(), 0, 0), 100, 0)
+	spendReplaced.Messages()[0].Payload.(*modules.PaymentPayload).AddTxIn(modules.NewTxIn(outpoints[0], []byte{}))
+	if err := pool.AddLocal(spendReplaced); err == nil {
+		t.Fatal("replacement spending output of replaced tx should be rejected")
+	}
+
+	replacement := newPaymentTx4Test(outpoints[0], 700, 0)
+	if err := pool.AddLocal(replacement); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []common.Hash{parent.Hash(), child.Hash()} {
+		ptx, _ := pool.Get(hash)
+		if ptx == nil || !ptx.Discarded {
+			t.Errorf("tx %s should be discarded", hash.String())
+		}
+	}
+	if _, err := pool.GetUtxoEntry(modules.NewOutPoint(parent.Hash(), 0, 0)); err == nil {
+		t.Error("output of replaced tx should be removed from pool")
+	}
+	timeout := time.After(time.Second)
+	for found := false; !found; {
+		select {
+		case ev := <-events:
+			if ev.Tx.Hash() != replacement.Hash() {
+				continue
+			}
+			found = true
+			if len(ev.Replaced) != 2 {
+				t.Errorf("expect 2 replaced txs, got %d", len(ev.Replaced))
+			}
+		case <-timeout:
+			t.Fatal("replacement event not received")
+		}
+	}
+
+	// replacement没有声明可替换，不能被替换
+	another := newPaymentTx4Test(outpoints[0], 100, modules.SequenceReplaceable)
+	if err := pool.AddLocal(another); err == nil {
+		t.Fatal("non-replaceable tx should not be replaced")
+	}
+}
+
+func TestGetSortedTxsCPFP(t *testing.T) {
+	for _, c := range []struct {
+		childFee, otherFee uint64
+		parentFirst        bool
+	}{
+		{childFee: 200, otherFee: 80, parentFirst: true},
+		{childFee: 100, otherFee: 80, parentFirst: false},
+	} {
+		pool, outpoints := newTxPool4RBFTest(1000, 1000)
+		parent := newPaymentTx4Test(outpoints[0], 999, 0)
+		child := newPaymentTx4Test(modules.NewOutPoint(parent.Hash(), 0, 0), 999-c.childFee, 0)
+		other := newPaymentTx4Test(outpoints[1], 1000-c.otherFee, 0)
+		for _, err := range pool.AddLocals([]*modules.Transaction{parent, child, other}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		txs, _ := pool.GetSortedTxs(common.Hash{}, 1)
+		if len(txs) != 3 {
+			t.Fatalf("expect 3 txs, got %d", len(txs))
+		}
+		if txs[0].Tx.Hash() == parent.Hash() != c.parentFirst {
+			t.Errorf("unexpected order, child fee %d, other fee %d", c.childFee, c.otherFee)
+		}
+		for i, tx := range txs {
+			if tx.Tx.Hash() == parent.Hash() && (i+1 >= len(txs) || txs[i+1].Tx.Hash() != child.Hash()) {
+				t.Error("parent and child should be packed together")
+			}
+		}
+		pool.Stop()
+	}
+}
+
+func TestFeeEstimator(t *testing.T) {
+	db, _ := palletdb.NewMemDatabase()
+	fe := NewFeeEstimator(db)
+	newUnit := func(height uint64, txs modules.Transactions) *modules.Unit {
+		b := []byte("fee estimator")
+		h := modules.NewHeader([]common.Hash{}, common.Hash{}, b, b, b, b, []uint16{},
+			modules.PTNCOIN, height, int64(1598766666))
+		return modules.NewUnit(h, txs)
+	}
+	txs := createTxs("P13pBrshF6JU7QhMmzJjXx3mWHh13YHAUAa")
+	high, low := make(modules.Transactions, 0), make(modules.Transactions, 0)
+	for i, tx := range txs {
+		fee := uint64(10)
+		if i%2 == 0 {
+			fee = 1000
+			high = append(high, tx)
+		} else {
+			low = append(low, tx)
+		}
+		ptx := TxtoTxpoolTx(tx)
+		ptx.TxFee = []*modules.Addition{{Amount: fee, Asset: modules.NewPTNAsset()}}
+		fe.observeTx(ptx)
+	}
+	//高费率的交易在下一个Unit被打包，低费率的交易等待了5个Unit
+	fe.processUnit(newUnit(1, high))
+	for i := uint64(2); i < 5; i++ {
+		fe.processUnit(newUnit(i, nil))
+	}
+	fe.processUnit(newUnit(5, low))
+
+	if _, err := fe.EstimateFee(0, ""); err != ErrInvalidEstimateTarget {
+		t.Errorf("expect %v, got %v", ErrInvalidEstimateTarget, err)
+	}
+	_, highRate := txFeeRate(&TxPoolTransaction{Tx: high[0], TxFee: []*modules.Addition{{Amount: 1000}}})
+	_, lowRate := txFeeRate(&TxPoolTransaction{Tx: low[0], TxFee: []*modules.Addition{{Amount: 10}}})
+	check := func(fe *FeeEstimator) {
+		next, err := fe.EstimateFee(1, modules.PTN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Samples != len(txs) {
+			t.Errorf("expect %d samples, got %d", len(txs), next.Samples)
+		}
+		if rate := next.Estimates[len(next.Estimates)-1].FeeRate; rate != highRate {
+			t.Errorf("expect fee rate %d for next unit, got %d", highRate, rate)
+		}
+		later, _ := fe.EstimateFee(5, modules.PTN)
+		if rate := later.Estimates[len(later.Estimates)-1].FeeRate; rate != lowRate {
+			t.Errorf("expect fee rate %d for 5 units, got %d", lowRate, rate)
+		}
+	}
+	check(fe)
+
+	//重启后从数据库中恢复
+	fe.Stop()
+	check(NewFeeEstimator(db))
+}
+
+//找不到UTXO时返回孤儿交易
+type utxoValidator4Test struct {
+	pool *TxPool
+}
+
+func (v *utxoValidator4Test) ValidateTx(tx *modules.Transaction, isFullTx bool) ([]*modules.Addition,
+	validator.ValidationCode, error) {
+	for _, outpoint := range tx.GetSpendOutpoints() {
+		if _, err := v.pool.GetUtxoEntry(outpoint); err != nil {
+			return nil, validator.TxValidationCode_ORPHAN, err
+		}
+	}
+	return nil, validator.TxValidationCode_VALID, nil
+}
+
+func TestProcessPackage(t *testing.T) {
+	newPool := func() (*TxPool, []*modules.OutPoint) {
+		pool, outpoints := newTxPool4RBFTest(1000, 1000)
+		pool.txValidator = &utxoValidator4Test{pool: pool}
+		return pool, outpoints
+	}
+	source, outpoints := newPool()
+	defer source.Stop()
+	grandparent := newPaymentTx4Test(outpoints[0], 900, 0)
+	parent := newPaymentTx4Test(modules.NewOutPoint(grandparent.Hash(), 0, 0), 800, 0)
+	child := newPaymentTx4Test(modules.NewOutPoint(parent.Hash(), 0, 0), 700, 0)
+	for _, err := range source.AddLocals([]*modules.Transaction{grandparent, parent}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg := source.GetTxPackage(parent.Hash())
+	if len(pkg) != 2 || pkg[0].Hash() != grandparent.Hash() || pkg[1].Hash() != parent.Hash() {
+		t.Fatalf("unexpected package: %v", pkg)
+	}
+
+	pool, _ := newPool()
+	defer pool.Stop()
+	if err := pool.AddLocal(child); err != nil {
+		t.Fatal(err)
+	}
+	missing := pool.MissingParents(child.Hash())
+	if len(missing) != 1 || missing[0].TxHash != parent.Hash() {
+		t.Fatalf("unexpected missing parents: %v", missing)
+	}
+	//包内交易有缺失的前驱交易，整个交易包被拒绝
+	if err := pool.ProcessPackage([]*modules.Transaction{pkg[1]}); err != ErrPackageIncomplete {
+		t.Fatalf("expect %v, got %v", ErrPackageIncomplete, err)
+	}
+	if pool.isTransactionInPool(parent.Hash()) {
+		t.Fatal("incomplete package should be rolled back")
+	}
+	//乱序的交易包
+	if err := pool.ProcessPackage([]*modules.Transaction{pkg[1], pkg[0]}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range []*modules.Transaction{grandparent, parent, child} {
+		if _, has := pool.all.Load(tx.Hash()); !has {
+			t.Errorf("tx %s should be accepted", tx.Hash().String())
+		}
+	}
+	if pool.isOrphanInPool(child.Hash()) || len(pool.MissingParents(child.Hash())) != 0 {
+		t.Error("orphan child should be resolved by the package")
+	}
+
+	//交易包中的交易花费已被交易池中交易花费的输出，回滚整个交易包
+	conflictPool, outpoints := newPool()
+	defer conflictPool.Stop()
+	spent := newPaymentTx4Test(outpoints[1], 500, 0)
+	if err := conflictPool.AddLocal(spent); err != nil {
+		t.Fatal(err)
+	}
+	ok := newPaymentTx4Test(outpoints[0], 900, 0)
+	conflict := newPaymentTx4Test(outpoints[1], 400, 0)
+	if err := conflictPool.ProcessPackage([]*modules.Transaction{ok, conflict}); err == nil {
+		t.Fatal("package with double spend should be rejected")
+	}
+	if conflictPool.isTransactionInPool(ok.Hash()) {
+		t.Error("package should be rolled back")
+	}
+
+	//回滚时恢复被移出的孤儿交易
+	orphanPool, outpoints := newPool()
+	defer orphanPool.Stop()
+	if err := orphanPool.AddLocal(spent); err != nil {
+		t.Fatal(err)
+	}
+	if err := orphanPool.AddLocal(child); err != nil {
+		t.Fatal(err)
+	}
+	err := orphanPool.ProcessPackage([]*modules.Transaction{grandparent, parent, child, conflict})
+	if err == nil {
+		t.Fatal("package with double spend should be rejected")
+	}
+	if !orphanPool.isOrphanInPool(child.Hash()) {
+		t.Error("orphan tx should be restored after rollback")
+	}
+	if _, has := orphanPool.all.Load(child.Hash()); has {
+		t.Error("orphan tx should not stay in the pool after rollback")
+	}
+
+	//交易池已满时，回滚的交易包不会驱逐交易池中的交易
+	fullPool, outpoints := newPool()
+	defer fullPool.Stop()
+	fullPool.config.GlobalSlots, fullPool.config.GlobalQueue = 1, 1
+	low1 := newPaymentTx4Test(outpoints[1], 990, 0)
+	if err := fullPool.AddLocal(low1); err != nil {
+		t.Fatal(err)
+	}
+	low2 := newPaymentTx4Test(modules.NewOutPoint(low1.Hash(), 0, 0), 980, 0)
+	if err := fullPool.AddLocal(low2); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	high := newPaymentTx4Test(outpoints[0], 100, 0)
+	if err := fullPool.ProcessPackage([]*modules.Transaction{high, conflict}); err == nil {
+		t.Fatal("package with double spend should be rejected")
+	}
+	for _, tx := range []*modules.Transaction{low1, low2} {
+		inter, has := fullPool.all.Load(tx.Hash())
+		if !has || inter.(*TxPoolTransaction).Discarded {
+			t.Errorf("tx %s should not be discarded by a rolled back package", tx.Hash().String())
+		}
+	}
+	//交易包被接受后才驱逐低优先级的交易
+	if err := fullPool.ProcessPackage([]*modules.Transaction{high}); err != nil {
+		t.Fatal(err)
+	}
+	if inter, _ := fullPool.all.Load(high.Hash()); inter == nil || inter.(*TxPoolTransaction).Discarded {
+		t.Error("the package should be accepted")
+	}
+	inter, _ := fullPool.all.Load(low2.Hash())
+	if !inter.(*TxPoolTransaction).Discarded {
+		t.Error("underpriced tx should be discarded after the package is accepted")
+	}
+}
+
+func TestTxJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "transactions.rlp")
+
+	pool, outpoints := newTxPool4RBFTest(1000, 1000)
+	pool.journal = newTxJournal(path)
+	if err := pool.journal.rotate(nil); err != nil {
+		t.Fatal(err)
+	}
+	parent := newPaymentTx4Test(outpoints[0], 900, 0)
+	child := newPaymentTx4Test(modules.NewOutPoint(parent.Hash(), 0, 0), 800, 0)
+	other := newPaymentTx4Test(outpoints[1], 900, 0)
+	//本地交易和远程交易都写入日志
+	if err := pool.AddLocal(parent); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddRemote(child); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddRemote(other); err != nil {
+		t.Fatal(err)
+	}
+	pool.journal.close()
+	//模拟崩溃时写了一半的记录
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 1, 2})
+	f.Close()
+
+	//子交易在前也能按依赖关系重放
+	data, _ := ioutil.ReadFile(path)
+	txs, dropped, err := ReadTxs(bytes.NewReader(data))
+	if err != nil || len(txs) != 3 || dropped != 1 {
+		t.Fatalf("unexpected journal content: %d txs, %d dropped, err: %v", len(txs), dropped, err)
+	}
+	restarted, _ := newTxPool4RBFTest(1000, 1000)
+	defer restarted.Stop()
+	restarted.journal = newTxJournal(path)
+	loaded, err := restarted.journal.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.replayJournal(loaded)
+	for _, tx := range []*modules.Transaction{parent, child, other} {
+		if _, has := restarted.all.Load(tx.Hash()); !has {
+			t.Errorf("tx %s should be replayed", tx.Hash().String())
+		}
+	}
+	//压缩后的日志只包含交易池中的交易，校验和错误的记录被跳过
+	if err := restarted.journal.rotate(restarted.journalTxs()); err != nil {
+		t.Fatal(err)
+	}
+	restarted.journal.close()
+	data, _ = ioutil.ReadFile(path)
+	data[len(journalMagic)+journalRecordHeaderSize] ^= 0xff
+	txs, dropped, err = ReadTxs(bytes.NewReader(data))
+	if err != nil || len(txs) != 2 || dropped != 1 {
+		t.Fatalf("unexpected journal content: %d txs, %d dropped, err: %v", len(txs), dropped, err)
+	}
+	if sorted := sortJournalTxs([]*modules.Transaction{child, parent, child}); len(sorted) != 2 ||
+		sorted[0].Hash() != parent.Hash() {
+		t.Error("journal txs should be deduplicated and sorted")
+	}
+}
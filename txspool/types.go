@@ -143,6 +143,40 @@ func (s *TxByPriority) Pop() interface{} {
 	return x
 }
 
+// TxPackage 交易包：交易及其尚未打包的前驱交易，前驱交易在前。
+// 按交易包的整体优先级排序，使得子交易可以为父交易支付手续费(CPFP)。
+type TxPackage struct {
+	Txs      []*TxPoolTransaction
+	Priority float64
+}
+
+func NewTxPackage(tx *TxPoolTransaction, precusors []*TxPoolTransaction) *TxPackage {
+	pkg := &TxPackage{Txs: make([]*TxPoolTransaction, 0, len(precusors)+1)}
+	exist := make(map[common.Hash]bool)
+	var weight, size float64
+	for _, ptx := range append(precusors, tx) {
+		hash := ptx.Tx.Hash()
+		if exist[hash] {
+			continue
+		}
+		exist[hash] = true
+		pkg.Txs = append(pkg.Txs, ptx)
+		// 优先级约等于 fee/size，按交易大小加权即为交易包的整体费率
+		weight += ptx.GetPriorityfloat64() * ptx.Tx.Size().Float64()
+		size += ptx.Tx.Size().Float64()
+	}
+	if size > 0 {
+		pkg.Priority = weight / size
+	}
+	return pkg
+}
+
+type TxPackages []*TxPackage
+
+func (s TxPackages) Len() int           { return len(s) }
+func (s TxPackages) Less(i, j int) bool { return s[i].Priority > s[j].Priority }
+func (s TxPackages) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 type TxByCreationDate []*TxPoolTransaction
 
 func (tc TxByCreationDate) Len() int           { return len(tc) }
@@ -378,6 +412,27 @@ func (pooltx *TxPoolTransaction) DecodeRLP(s *rlp.Stream) error {
 				return err
 			}
 			m1.Payload = &payload
+		} else if m.App == modules.APP_CONTRACT_UPGRADE_REQUEST {
+			var payload modules.ContractUpgradeRequestPayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
+		} else if m.App == modules.APP_CONTRACT_UPGRADE {
+			var payload modules.ContractUpgradePayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
+		} else if m.App == modules.APP_CONTRACT_JURY_UPDATE {
+			var payload modules.ContractJuryUpdatePayload
+			err := rlp.DecodeBytes(m.Data, &payload)
+			if err != nil {
+				return err
+			}
+			m1.Payload = &payload
 		} else if m.App == modules.APP_SIGNATURE {
 			var sigPayload modules.SignaturePayload
 			err := rlp.DecodeBytes(m.Data, &sigPayload)
@@ -35,6 +35,7 @@ type IUtxoQuery interface {
 
 type IStateQuery interface {
 	GetContractTpl(tplId []byte) (*modules.ContractTemplate, error)
+	GetContract(id []byte) (*modules.Contract, error)
 	GetContractJury(contractId []byte) (*modules.ElectionNode, error)
 	GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error)
 	GetContractStatesByPrefix(id []byte, prefix string) (map[string]*modules.ContractStateValue, error)
@@ -43,6 +44,7 @@ type IStateQuery interface {
 	GetBlacklistAddress() ([]common.Address, *modules.StateVersion, error)
 	GetJurorByAddrHash(addrHash common.Hash) (*modules.JurorDeposit, error)
 	GetJurorReward(jurorAdd common.Address) common.Address
+	GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation
 	IsContractDeveloper(addr common.Address) bool
 }
 
@@ -52,11 +54,14 @@ type IDagQuery interface {
 	GetHeaderByHash(common.Hash) (*modules.Header, error)
 	CheckReadSetValid(contractId []byte, readSet []modules.ContractReadSet) bool
 	GetTxRequesterAddress(tx *modules.Transaction) (common.Address, error)
+	GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error)
 }
 
 type IPropQuery interface {
 	GetSlotAtTime(when time.Time) uint32
 	GetScheduledMediator(slotNum uint32) common.Address
 	GetNewestUnitTimestamp(token modules.AssetId) (int64, error)
+	GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error)
 	GetChainParameters() *core.ChainParameters
+	GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error)
 }
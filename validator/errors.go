@@ -64,6 +64,7 @@ const (
 	TxValidationCode_INVALID_TOKEN_STATUS         ValidationCode = 36
 	TxValidationCode_NOT_COMPARE_SIZE             ValidationCode = 37
 	TxValidationCode_NOT_TPL_DEVELOPER            ValidationCode = 38
+	TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED  ValidationCode = 39
 
 	TxValidationCode_ORPHAN               ValidationCode = 255
 	TxValidationCode_INVALID_OTHER_REASON ValidationCode = 251
@@ -121,6 +122,7 @@ var validationCode_name = map[byte]string{
 	35:  "DOUBLE_SPEND",
 	36:  "INVALID_TOKEN_STATUS",
 	37:  "NOT_COMPARE_SIZE",
+	39:  "SEQUENCE_LOCK_NOT_SATISFIED",
 	101: "AUTHOR_SIGNATURE_PASSED",
 	102: "UNIT_STATE_INVALID_MEDIATOR_SCHEDULE",
 	103: "INVALID_AUTHOR_SIGNATURE",
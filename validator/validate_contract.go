@@ -21,11 +21,18 @@
 package validator
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/palletone/go-palletone/common"
 	"github.com/palletone/go-palletone/common/crypto"
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/consensus/jury/vrf"
+	alg "github.com/palletone/go-palletone/consensus/jury/vrf/algorithm"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/palletone/go-palletone/core"
 	"github.com/palletone/go-palletone/dag/modules"
 	"math"
 )
@@ -40,6 +47,41 @@ func (validate *Validate) validateContractState(contractID []byte, readSet []mod
 	//	return TxValidationCode_CHAINCODE_VERSION_CONFLICT
 	//}
 	log.Debugf("contractID[%v], read set[%v]write set[%v]", contractID, readSet, writeSet)
+	//私有状态上链的只能是明文的Hash，且只能写在私有状态的Key前缀下
+	for _, ws := range writeSet {
+		if ws.IsPrivate != modules.IsPrivateStateKey(ws.Key) {
+			log.Warnf("contractID[%x] state[%s] private flag not match the key", contractID, ws.Key)
+			return TxValidationCode_BAD_RWSET
+		}
+		if ws.IsPrivate && !ws.IsDelete && len(ws.Value) != common.HashLength {
+			log.Warnf("contractID[%x] private state[%s] value is not a hash", contractID, ws.Key)
+			return TxValidationCode_BAD_RWSET
+		}
+	}
+	return TxValidationCode_VALID
+}
+
+/**
+检查范围查询读集的结构：范围有效，读到的Key按顺序排列且都在范围内。
+范围内是否有幻读由打包单元时重新执行范围查询来检查
+To validate range read set of one contract invoke
+*/
+func (validate *Validate) validateContractRangeReadSet(contractID []byte,
+	rangeReadSet []modules.ContractRangeReadSet) ValidationCode {
+	for _, rr := range rangeReadSet {
+		if rr.EndKey != "" && rr.StartKey >= rr.EndKey {
+			log.Warnf("contractID[%x] invalid range [%s,%s)", contractID, rr.StartKey, rr.EndKey)
+			return TxValidationCode_BAD_RWSET
+		}
+		for i, rd := range rr.Reads {
+			if rd.Key < rr.StartKey || (rr.EndKey != "" && rd.Key >= rr.EndKey) ||
+				(i > 0 && rd.Key <= rr.Reads[i-1].Key) {
+				log.Warnf("contractID[%x] key[%s] of range [%s,%s) is out of order", contractID, rd.Key,
+					rr.StartKey, rr.EndKey)
+				return TxValidationCode_BAD_RWSET
+			}
+		}
+	}
 	return TxValidationCode_VALID
 }
 
@@ -64,6 +106,218 @@ func (validate *Validate) validateContractDeploy(tplId []byte) ValidationCode {
 	return TxValidationCode_VALID
 }
 
+//验证合约升级请求：合约必须存在且未停止，请求者或者管理员签名满足合约的升级授权
+func (validate *Validate) validateContractUpgrade(tx *modules.Transaction,
+	payload *modules.ContractUpgradeRequestPayload) ValidationCode {
+	if len(payload.ContractId) == 0 || len(payload.TemplateId) == 0 {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if !common.IsUserContractId(payload.ContractId) {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if validate.statequery == nil || validate.dagquery == nil {
+		return TxValidationCode_VALID
+	}
+	contract, err := validate.statequery.GetContract(payload.ContractId)
+	if err != nil {
+		log.Debugf("validateContractUpgrade, GetContract[%x] err:%s", payload.ContractId, err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if contract.Status != 1 {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if _, err = validate.statequery.GetContractTpl(payload.TemplateId); err != nil {
+		log.Debugf("validateContractUpgrade, GetContractTpl[%x] err:%s", payload.TemplateId, err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	reqAddr, err := validate.dagquery.GetTxRequesterAddress(tx)
+	if err != nil {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if err = contract.CheckUpgradeAuth(reqAddr, payload); err != nil {
+		log.Debugf("validateContractUpgrade, contract[%x] %s", payload.ContractId, err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	return TxValidationCode_VALID
+}
+
+//验证合约升级结果：结果中的合约和模板必须与升级请求一致，防止陪审团把其他合约升级或者升级到其他模板
+func validateContractUpgradeResult(tx *modules.Transaction, payload *modules.ContractUpgradePayload) ValidationCode {
+	for _, msg := range tx.Messages() {
+		if msg.App != modules.APP_CONTRACT_UPGRADE_REQUEST {
+			continue
+		}
+		req, ok := msg.Payload.(*modules.ContractUpgradeRequestPayload)
+		if !ok {
+			return TxValidationCode_INVALID_CONTRACT
+		}
+		if !bytes.Equal(req.ContractId, payload.ContractId) || !bytes.Equal(req.TemplateId, payload.TemplateId) {
+			log.Debugf("validateContractUpgradeResult, result contract[%x] template[%x] not match request",
+				payload.ContractId, payload.TemplateId)
+			return TxValidationCode_INVALID_CONTRACT
+		}
+		return TxValidationCode_VALID
+	}
+	return TxValidationCode_INVALID_CONTRACT
+}
+
+//验证定时调用注册请求：只有目标合约的创建者可以为该合约注册定时任务
+func (validate *Validate) validateScheduleRegister(tx *modules.Transaction,
+	payload *modules.ContractInvokeRequestPayload) ValidationCode {
+	target, ok := modules.ScheduleRegisterTarget(payload.Args)
+	if !ok {
+		return TxValidationCode_VALID
+	}
+	if validate.statequery == nil || validate.dagquery == nil {
+		return TxValidationCode_VALID
+	}
+	contract, err := validate.statequery.GetContract(target.Bytes())
+	if err != nil {
+		log.Debugf("validateScheduleRegister, GetContract[%s] err:%s", target.String(), err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	reqAddr, err := validate.dagquery.GetTxRequesterAddress(tx)
+	if err != nil {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if err = modules.CheckScheduleRegisterAuth(contract, reqAddr); err != nil {
+		log.Debugf("validateScheduleRegister, contract[%s] %s", target.String(), err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	return TxValidationCode_VALID
+}
+
+//验证陪审团重新选举：只能替换用户合约当前的陪审团，新的陪审员必须是保证金合约中的陪审员候选人，
+//并且以ElectionSeed为种子通过了VRF选举
+func (validate *Validate) validateContractJuryUpdate(payload *modules.ContractJuryUpdatePayload) ValidationCode {
+	if !common.IsUserContractId(payload.ContractId) {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if validate.statequery == nil || validate.propquery == nil {
+		return TxValidationCode_VALID
+	}
+	contract, err := validate.statequery.GetContract(payload.ContractId)
+	if err != nil {
+		log.Debugf("validateContractJuryUpdate, GetContract[%x] err:%s", payload.ContractId, err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if contract.Status != 1 {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	jury, err := validate.statequery.GetContractJury(payload.ContractId)
+	if err != nil {
+		log.Debugf("validateContractJuryUpdate, GetContractJury[%x] err:%s", payload.ContractId, err.Error())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if jury.Hash() != payload.PrevJury {
+		log.Debugf("validateContractJuryUpdate, contract[%x] jury[%s] has been replaced",
+			payload.ContractId, payload.PrevJury.String())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if !validate.isRecentUnit(payload.SeedUnit) {
+		log.Debugf("validateContractJuryUpdate, seed unit[%s] is not a recent unit", payload.SeedUnit.String())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+
+	cp := validate.propquery.GetChainParameters()
+	eleNum := cp.ContractElectionNum
+	if eleNum < 1 {
+		eleNum = core.DefaultContractElectionNum
+	}
+	sigNum := cp.ContractSignatureNum
+	if sigNum < 1 {
+		sigNum = core.DefaultContractSignatureNum
+	}
+	if !validateJuryUpdateSignatures(payload, jury, modules.JuryUpdateSignThreshold(len(jury.EleList), sigNum)) {
+		log.Debugf("validateContractJuryUpdate, contract[%x] not enough signatures of jury[%s]",
+			payload.ContractId, payload.PrevJury.String())
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	if len(payload.EleNode.EleList) != eleNum {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	val, _, err := validate.statequery.GetContractState(syscontract.DepositContractAddress.Bytes(), modules.JuryList)
+	if err != nil {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	candidates := make(map[string]bool)
+	if err = json.Unmarshal(val, &candidates); err != nil || len(candidates) == 0 {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	//选举时的候选人数量与当前数量相差不超过10%
+	total := payload.EleNode.JuryCount
+	if math.Abs(float64(total)-float64(len(candidates)))*10 > float64(len(candidates)) {
+		log.Debugf("validateContractJuryUpdate, jury count[%d] not match candidates[%d]", total, len(candidates))
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	v, err := vrf.GetVrf(cp.VrfAlgorithm)
+	if err != nil {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	//选举权重按种子单元上生效的信誉计算，选举和验证之间发生维护时结果不变
+	seedHeader, err := validate.dagquery.GetHeaderByHash(payload.SeedUnit)
+	if err != nil || seedHeader == nil {
+		return TxValidationCode_INVALID_CONTRACT
+	}
+	seed := payload.ElectionSeed()
+	elected := make(map[common.Hash]bool)
+	for _, e := range payload.EleNode.EleList {
+		addr := crypto.PubkeyBytesToAddress(e.PublicKey)
+		if e.EType != 0 || e.AddrHash != util.RlpHash(addr) || elected[e.AddrHash] {
+			return TxValidationCode_INVALID_CONTRACT
+		}
+		elected[e.AddrHash] = true
+		if !candidates[addr.String()] {
+			log.Debugf("validateContractJuryUpdate, %s is not a jury candidate", addr.String())
+			return TxValidationCode_INVALID_CONTRACT
+		}
+		ok, sel, err := v.VrfVerify(e.PublicKey, seed, e.Proof)
+		score := validate.statequery.GetJurorReputation(addr).ScoreAt(uint64(seedHeader.Timestamp()))
+		weight := alg.ReputationWeight(alg.ElectionWeight(total), score)
+		if err != nil || !ok || alg.Selected(uint(eleNum), weight, total, sel) < 1 {
+			log.Debugf("validateContractJuryUpdate, %s verify vrf fail", addr.String())
+			return TxValidationCode_INVALID_CONTRACT
+		}
+	}
+	return TxValidationCode_VALID
+}
+
+//isRecentUnit 单元在本地存在，且与最新单元的高度相差不超过JuryUpdateSeedMaxAge
+func (validate *Validate) isRecentUnit(hash common.Hash) bool {
+	if validate.dagquery == nil {
+		return false
+	}
+	header, err := validate.dagquery.GetHeaderByHash(hash)
+	if err != nil || header == nil {
+		return false
+	}
+	_, index, err := validate.propquery.GetNewestUnit(header.GetAssetId())
+	if err != nil || index == nil {
+		return false
+	}
+	height := header.NumberU64()
+	return height <= index.Index && index.Index-height <= modules.JuryUpdateSeedMaxAge
+}
+
+//validateJuryUpdateSignatures 原陪审团中至少threshold个不同的陪审员对ElectionId签名
+func validateJuryUpdateSignatures(payload *modules.ContractJuryUpdatePayload, jury *modules.ElectionNode,
+	threshold int) bool {
+	eleId := payload.ElectionId()
+	signed := make(map[common.Hash]bool)
+	for _, sig := range payload.Signatures {
+		addrHash := util.RlpHash(crypto.PubkeyBytesToAddress(sig.PubKey))
+		if signed[addrHash] || !jury.HasAddrHash(addrHash) {
+			return false
+		}
+		pass, err := crypto.MyCryptoLib.Verify(sig.PubKey, sig.Signature, eleId.Bytes())
+		if err != nil || !pass {
+			return false
+		}
+		signed[addrHash] = true
+	}
+	return len(signed) >= threshold
+}
+
 //验证陪审团签名是否有效
 func (validate *Validate) validateContractSignature(signatures []modules.SignatureSet,
 	tx *modules.Transaction, isFullTx bool) ValidationCode {
@@ -74,6 +328,7 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 	var jury *modules.ElectionNode
 	var err error
 	var contractId []byte
+	isInvoke := false
 	for _, msg := range tx.TxMessages() {
 		if msg.App == modules.APP_CONTRACT_DEPLOY {
 			deploy := msg.Payload.(*modules.ContractDeployPayload)
@@ -81,12 +336,17 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 		} else if msg.App == modules.APP_CONTRACT_INVOKE_REQUEST {
 			invokeReq := msg.Payload.(*modules.ContractInvokeRequestPayload)
 			contractId = invokeReq.ContractId
+			isInvoke = true
 		} else if msg.App == modules.APP_CONTRACT_STOP_REQUEST {
 			stopReq := msg.Payload.(*modules.ContractStopRequestPayload)
 			contractId = stopReq.ContractId
+		} else if msg.App == modules.APP_CONTRACT_UPGRADE_REQUEST {
+			upgradeReq := msg.Payload.(*modules.ContractUpgradeRequestPayload)
+			contractId = upgradeReq.ContractId
 		}
 	}
-	// 1.对于用户合约，确认签名者都是Jury
+	// 1.对于用户合约，确认签名者都是Jury，跨陪审团调用时也可以是被调用合约的陪审员
+	var calleeJuries []*modules.ElectionNode
 	if common.IsUserContractId(contractId) { // user contract
 		jury, err = validate.statequery.GetContractJury(contractId)
 		if err != nil {
@@ -94,6 +354,13 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 				contractId, err.Error())
 			return TxValidationCode_INVALID_CONTRACT_SIGN
 		}
+		if isInvoke {
+			calleeJuries, err = validate.getContractCalleeJuries(tx, jury)
+			if err != nil {
+				log.Errorf("Tx[%s] get callee juries throw an error:%s", txHash, err.Error())
+				return TxValidationCode_INVALID_CONTRACT_SIGN
+			}
+		}
 	}
 	if jury != nil { //有陪审团信息,判断公钥和陪审员是否匹配
 		jurorCount := len(jury.EleList)
@@ -101,12 +368,9 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 		for _, s := range signatures {
 			jAddr := crypto.PubkeyBytesToAddress(s.PubKey)
 			jAddrHash := util.RlpHash(jAddr)
-			find := false
-			for _, node := range jury.EleList {
-				if jAddrHash == node.AddrHash {
-					find = true
-					break
-				}
+			find := jury.HasAddrHash(jAddrHash)
+			for _, callee := range calleeJuries {
+				find = find || callee.HasAddrHash(jAddrHash)
 			}
 			if !find { //签名者不是合法的陪审员
 				log.Warnf("Tx[%s] signature payload pubKey[%x] is not a valid juror", txHash, s.PubKey)
@@ -119,6 +383,7 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 	tx4Sign := tx.GetResultRawTx()
 	txBytes, _ := rlp.EncodeToBytes(tx4Sign)
 	passCount := 0
+	passed := make(map[common.Hash]bool)
 	for _, s := range signatures {
 		pass, err := crypto.MyCryptoLib.Verify(s.PubKey, s.Signature, txBytes)
 		if err != nil {
@@ -127,12 +392,54 @@ func (validate *Validate) validateContractSignature(signatures []modules.Signatu
 		}
 		if pass {
 			passCount++
+			passed[util.RlpHash(crypto.PubkeyBytesToAddress(s.PubKey))] = true
+		}
+	}
+	//3.确认签名数量满足系统要求，跨陪审团调用时每个陪审团都要满足
+	if !isFullTx {
+		return TxValidationCode_VALID
+	}
+	if len(calleeJuries) == 0 {
+		if passCount < needSign {
+			log.Errorf("Tx[%s] need signature count:%d, but current has %d", txHash, needSign, passCount)
+			return TxValidationCode_INVALID_CONTRACT_SIGN
 		}
+		return TxValidationCode_VALID
 	}
-	//3.确认签名数量满足系统要求
-	if isFullTx && passCount < needSign {
-		log.Errorf("Tx[%s] need signature count:%d, but current has %d", txHash, needSign, passCount)
-		return TxValidationCode_INVALID_CONTRACT_SIGN
+	for _, j := range append([]*modules.ElectionNode{jury}, calleeJuries...) {
+		need := int(math.Ceil((float64(len(j.EleList))*2 + 1) / 3))
+		count := 0
+		for _, e := range j.EleList {
+			if passed[e.AddrHash] {
+				count++
+			}
+		}
+		if count < need {
+			log.Errorf("Tx[%s] need signature count:%d of jury[%s], but current has %d",
+				txHash, need, j.Hash().String(), count)
+			return TxValidationCode_INVALID_CONTRACT_SIGN
+		}
 	}
 	return TxValidationCode_VALID
 }
+
+//getContractCalleeJuries 合约调用结果中被调用合约的陪审团，与调用合约陪审团相同的不计入
+func (validate *Validate) getContractCalleeJuries(tx *modules.Transaction,
+	callerJury *modules.ElectionNode) ([]*modules.ElectionNode, error) {
+	callees, err := tx.GetContractCalleeAddrs(validate.utxoquery.GetUtxoEntry,
+		validate.tokenEngine.GetAddressFromScript)
+	if err != nil {
+		return nil, err
+	}
+	juries := []*modules.ElectionNode{}
+	for _, callee := range callees {
+		jury, err := validate.statequery.GetContractJury(callee.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("get jury of callee %s fail:%s", callee.String(), err.Error())
+		}
+		if !callerJury.SameJurors(jury) {
+			juries = append(juries, jury)
+		}
+	}
+	return juries, nil
+}
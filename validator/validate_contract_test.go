@@ -0,0 +1,287 @@
+/*
+ *
+ *    This file is part of go-palletone.
+ *    go-palletone is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU General Public License as published by
+ *    the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *    go-palletone is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU General Public License for more details.
+ *    You should have received a copy of the GNU General Public License
+ *    along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+ * /
+ *
+ *  * @author PalletOne core developer <dev@pallet.one>
+ *  * @date 2018-2019
+ *
+ */
+
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/palletone/go-palletone/common"
+	"github.com/palletone/go-palletone/common/crypto"
+	"github.com/palletone/go-palletone/common/util"
+	"github.com/palletone/go-palletone/consensus/jury/vrf"
+	"github.com/palletone/go-palletone/contracts/syscontract"
+	"github.com/palletone/go-palletone/core"
+	"github.com/palletone/go-palletone/dag/modules"
+	"github.com/stretchr/testify/assert"
+)
+
+type juryUpdateStateQuery struct {
+	mockStatedbQuery
+	contract   *modules.Contract
+	jury       *modules.ElectionNode
+	candidates map[string]bool
+}
+
+func (q *juryUpdateStateQuery) GetContract(id []byte) (*modules.Contract, error) {
+	return q.contract, nil
+}
+
+func (q *juryUpdateStateQuery) GetContractJury(contractId []byte) (*modules.ElectionNode, error) {
+	return q.jury, nil
+}
+
+func (q *juryUpdateStateQuery) GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error) {
+	data, err := json.Marshal(q.candidates)
+	return data, nil, err
+}
+
+type juryUpdatePropQuery struct {
+	mockiPropQuery
+}
+
+func (ip *juryUpdatePropQuery) GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error) {
+	return common.Hash{}, modules.NewChainIndex(token, 100), nil
+}
+
+type juryUpdateDagQuery struct {
+	mockiDagQuery
+	headers map[common.Hash]*modules.Header
+}
+
+func (id *juryUpdateDagQuery) GetHeaderByHash(hash common.Hash) (*modules.Header, error) {
+	if h, ok := id.headers[hash]; ok {
+		return h, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func newJurors(n int) ([][]byte, *modules.ElectionNode) {
+	keys := make([][]byte, n)
+	jury := &modules.ElectionNode{JuryCount: 4}
+	for i := range keys {
+		keys[i], _ = crypto.MyCryptoLib.KeyGen()
+		pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(keys[i])
+		jury.EleList = append(jury.EleList, modules.ElectionInf{
+			AddrHash: util.RlpHash(crypto.PubkeyBytesToAddress(pubKey)), PublicKey: pubKey})
+	}
+	return keys, jury
+}
+
+//原陪审员对ElectionId签名
+func signJuryUpdate(t *testing.T, payload *modules.ContractJuryUpdatePayload, keys ...[]byte) {
+	payload.Signatures = nil
+	for _, key := range keys {
+		pubKey, _ := crypto.MyCryptoLib.PrivateKeyToPubKey(key)
+		sig, err := crypto.MyCryptoLib.Sign(key, payload.ElectionId().Bytes())
+		assert.Nil(t, err)
+		payload.Signatures = append(payload.Signatures, modules.SignatureSet{PubKey: pubKey, Signature: sig})
+	}
+}
+
+func (ip *juryUpdatePropQuery) GetChainParameters() *core.ChainParameters {
+	cp := core.NewChainParams()
+	cp.VrfAlgorithm = vrf.AlgorithmEss
+	return &cp
+}
+
+func newJuryUpdatePayload(t *testing.T, q *juryUpdateStateQuery, seedUnit common.Hash,
+	jurorNum int) *modules.ContractJuryUpdatePayload {
+	payload := &modules.ContractJuryUpdatePayload{
+		ContractId: q.contract.ContractId,
+		PrevJury:   q.jury.Hash(),
+		SeedUnit:   seedUnit,
+		EleNode:    modules.ElectionNode{JuryCount: uint64(jurorNum)},
+	}
+	q.candidates = make(map[string]bool)
+	v, _ := vrf.GetVrf(vrf.AlgorithmEss)
+	for i := 0; i < jurorNum; i++ {
+		key, _ := crypto.MyCryptoLib.KeyGen()
+		privKey, _ := crypto.ToECDSA(key)
+		pubKey := crypto.CompressPubkey(&privKey.PublicKey)
+		addr := crypto.PubkeyBytesToAddress(pubKey)
+		proof, _, err := v.VrfProve(privKey, payload.ElectionSeed())
+		assert.Nil(t, err)
+		q.candidates[addr.String()] = true
+		payload.EleNode.EleList = append(payload.EleNode.EleList,
+			modules.ElectionInf{AddrHash: util.RlpHash(addr), Proof: proof, PublicKey: pubKey})
+	}
+	return payload
+}
+
+func TestValidate_ValidateContractJuryUpdate(t *testing.T) {
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	keys, jury := newJurors(4)
+	q := &juryUpdateStateQuery{
+		contract: &modules.Contract{ContractId: contractId, Status: 1},
+		jury:     jury,
+	}
+	recent := modules.NewHeader(nil, common.Hash{}, nil, nil, nil, nil, nil, modules.PTNCOIN, 90, 0)
+	old := modules.NewHeader(nil, common.Hash{}, nil, nil, nil, nil, nil, modules.PTNCOIN,
+		100-modules.JuryUpdateSeedMaxAge-1, 0)
+	dq := &juryUpdateDagQuery{headers: map[common.Hash]*modules.Header{
+		common.HexToHash("0x10"): recent, common.HexToHash("0x11"): old, common.HexToHash("0x13"): recent}}
+	validate := NewValidate(dq, &mockUtxoQuery{}, q, &juryUpdatePropQuery{}, newCache(), false)
+	newPayload := func(jurorNum int) *modules.ContractJuryUpdatePayload {
+		payload := newJuryUpdatePayload(t, q, common.HexToHash("0x10"), jurorNum)
+		signJuryUpdate(t, payload, keys[0], keys[1])
+		return payload
+	}
+
+	payload := newPayload(4)
+	assert.Equal(t, TxValidationCode_VALID, validate.validateContractJuryUpdate(payload))
+
+	//4个陪审员需要3个签名时，至少2个原陪审员确认超时
+	signJuryUpdate(t, payload, keys[0])
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+	signJuryUpdate(t, payload, keys[0], keys[0])
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+	other, _ := crypto.MyCryptoLib.KeyGen()
+	signJuryUpdate(t, payload, keys[0], other)
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+
+	//选举种子绑定的单元太旧或者不存在，签名也不能用于其他单元
+	stale := newJuryUpdatePayload(t, q, common.HexToHash("0x11"), 4)
+	signJuryUpdate(t, stale, keys[0], keys[1])
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(stale))
+	unknown := newJuryUpdatePayload(t, q, common.HexToHash("0x12"), 4)
+	signJuryUpdate(t, unknown, keys[0], keys[1])
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(unknown))
+	payload = newPayload(4)
+	replayed := newJuryUpdatePayload(t, q, common.HexToHash("0x13"), 4)
+	replayed.Signatures = payload.Signatures
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(replayed))
+
+	//已经被替换过的陪审团
+	replaced := *payload
+	replaced.PrevJury = common.HexToHash("0x03")
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(&replaced))
+
+	//系统合约的陪审团不能替换
+	sysContract := *payload
+	sysContract.ContractId = common.HexToAddress("0x01").Bytes()
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(&sysContract))
+
+	//陪审员不是候选人
+	delete(q.candidates, crypto.PubkeyBytesToAddress(payload.EleNode.EleList[0].PublicKey).String())
+	q.candidates["P1HXNZReTByQHgWQNGMXotMyTkMG9XeEQfX"] = true
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+
+	//选举时的候选人数量与当前相差太多
+	payload = newPayload(4)
+	payload.EleNode.JuryCount = 8
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+
+	//VRF证明与种子不匹配
+	payload = newPayload(4)
+	payload.EleNode.EleList[1].Proof = payload.EleNode.EleList[0].Proof
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+
+	//选举数量不足
+	payload = newPayload(3)
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractJuryUpdate(payload))
+}
+
+type upgradeStateQuery struct {
+	mockStatedbQuery
+	contract *modules.Contract
+	tplId    []byte
+}
+
+func (q *upgradeStateQuery) GetContract(id []byte) (*modules.Contract, error) {
+	return q.contract, nil
+}
+
+func (q *upgradeStateQuery) GetContractTpl(tplId []byte) (*modules.ContractTemplate, error) {
+	if !bytes.Equal(tplId, q.tplId) {
+		return nil, errors.New("not found")
+	}
+	return &modules.ContractTemplate{TplId: tplId}, nil
+}
+
+func TestValidate_ValidateContractUpgrade(t *testing.T) {
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	q := &upgradeStateQuery{
+		contract: &modules.Contract{ContractId: contractId, Status: 1, Creator: common.Address{}.Bytes()},
+		tplId:    []byte("tpl-v2"),
+	}
+	validate := NewValidate(&mockiDagQuery{}, &mockUtxoQuery{}, q, &mockiPropQuery{}, newCache(), false)
+	tx := modules.NewTransaction(nil)
+
+	payload := &modules.ContractUpgradeRequestPayload{ContractId: contractId, TemplateId: []byte("tpl-v2")}
+	assert.Equal(t, TxValidationCode_VALID, validate.validateContractUpgrade(tx, payload))
+
+	//升级到不存在的模板
+	payload.TemplateId = []byte("tpl-v3")
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateContractUpgrade(tx, payload))
+}
+
+func TestValidateContractUpgradeResult(t *testing.T) {
+	contractId := crypto.RequestIdToContractAddress(common.HexToHash("0x01")).Bytes()
+	req := &modules.ContractUpgradeRequestPayload{ContractId: contractId, TemplateId: []byte("tpl-v2")}
+	tx := modules.NewTransaction([]*modules.Message{modules.NewMessage(modules.APP_CONTRACT_UPGRADE_REQUEST, req)})
+
+	result := &modules.ContractUpgradePayload{ContractId: contractId, TemplateId: []byte("tpl-v2")}
+	assert.Equal(t, TxValidationCode_VALID, validateContractUpgradeResult(tx, result))
+
+	//升级结果中的模板与请求不一致
+	result.TemplateId = []byte("tpl-v3")
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validateContractUpgradeResult(tx, result))
+
+	//升级结果中的合约与请求不一致
+	other := crypto.RequestIdToContractAddress(common.HexToHash("0x02")).Bytes()
+	result = &modules.ContractUpgradePayload{ContractId: other, TemplateId: []byte("tpl-v2")}
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validateContractUpgradeResult(tx, result))
+
+	//没有升级请求
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT,
+		validateContractUpgradeResult(modules.NewTransaction(nil), result))
+}
+
+func TestValidate_ValidateScheduleRegister(t *testing.T) {
+	contractAddr := crypto.RequestIdToContractAddress(common.HexToHash("0x01"))
+	q := &upgradeStateQuery{
+		contract: &modules.Contract{ContractId: contractAddr.Bytes(), Status: 1, Creator: common.Address{}.Bytes()},
+	}
+	validate := NewValidate(&mockiDagQuery{}, &mockUtxoQuery{}, q, &mockiPropQuery{}, newCache(), false)
+	tx := modules.NewTransaction(nil)
+
+	payload := &modules.ContractInvokeRequestPayload{
+		ContractId: syscontract.SchedulerContractAddress.Bytes(),
+		Args:       [][]byte{[]byte("register"), []byte(contractAddr.String())},
+	}
+	assert.Equal(t, TxValidationCode_VALID, validate.validateScheduleRegister(tx, payload))
+
+	//请求者不是合约的创建者
+	other, _ := common.StringToAddress("P1NzevLMVCFJKWr4KAcHxyyh9xXaVU8yv3N")
+	q.contract.Creator = other.Bytes()
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateScheduleRegister(tx, payload))
+
+	//无效的合约地址
+	payload.Args[1] = []byte("abc")
+	assert.Equal(t, TxValidationCode_INVALID_CONTRACT, validate.validateScheduleRegister(tx, payload))
+
+	//其他函数不检查
+	payload.Args[0] = []byte("cancel")
+	assert.Equal(t, TxValidationCode_VALID, validate.validateScheduleRegister(tx, payload))
+}
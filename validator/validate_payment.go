@@ -114,6 +114,13 @@ func (validate *Validate) validatePaymentPayload(tx *modules.Transaction, msgIdx
 					return TxValidationCode_ORPHAN
 				}
 			}
+			//check relative lock time
+			if in.HasRelativeLockTime() {
+				code := validate.checkSequenceLock(in, utxo)
+				if code != TxValidationCode_VALID {
+					return code
+				}
+			}
 			if asset == nil {
 				asset = utxo.Asset
 			} else {
@@ -185,6 +192,57 @@ func (validate *Validate) validatePaymentPayload(tx *modules.Transaction, msgIdx
 	}
 	return TxValidationCode_VALID
 }
+//检查Input的相对锁定时间，被花费的UTXO必须已经被确认了足够的Unit数量或者时间
+//Check the relative lock time of input, the spent utxo must be confirmed for enough units or seconds
+func (validate *Validate) checkSequenceLock(in *modules.Input, utxo *modules.Utxo) ValidationCode {
+	lock, isSeconds := in.RelativeLockTime()
+	if in.PreviousOutPoint.TxHash.IsSelfHash() {
+		//同一个交易中产生的UTXO还没有被确认
+		log.Infof("Utxo[%s] is not confirmed, relative lock not satisfied", in.PreviousOutPoint.String())
+		return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+	}
+	if validate.dagquery == nil || validate.propquery == nil {
+		log.Warn("Validate DagQuery or PropQuery doesn't set, cannot check relative lock time")
+		return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+	}
+	gasToken := dagconfig.DagConfig.GetGasToken()
+	unitIndex := validate.unitIndex
+	unitTime := validate.unitTime
+	if unitIndex == 0 {
+		//交易池中的交易，以最新Unit的下一个高度和最新Unit的时间作为参考
+		_, index, err := validate.propquery.GetNewestUnit(gasToken)
+		if err != nil {
+			log.Warnf("GetNewestUnit error:%s", err.Error())
+			return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+		}
+		unitIndex = index.Index + 1
+		unitTime, err = validate.propquery.GetNewestUnitTimestamp(gasToken)
+		if err != nil {
+			log.Warnf("GetNewestUnitTimestamp error:%s", err.Error())
+			return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+		}
+	}
+	if isSeconds {
+		if utxo.Timestamp == 0 || unitTime < utxo.GetTimestamp()+lock {
+			log.Infof("Utxo[%s] relative lock %d seconds not satisfied, utxo time:%d, unit time:%d",
+				in.PreviousOutPoint.String(), lock, utxo.Timestamp, unitTime)
+			return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+		}
+		return TxValidationCode_VALID
+	}
+	entry, err := validate.dagquery.GetTxLookupEntry(in.PreviousOutPoint.TxHash)
+	if err != nil || entry == nil {
+		log.Infof("Utxo[%s] is not confirmed, relative lock not satisfied", in.PreviousOutPoint.String())
+		return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+	}
+	if unitIndex < entry.UnitIndex+uint64(lock) {
+		log.Infof("Utxo[%s] relative lock %d units not satisfied, utxo unit index:%d, unit index:%d",
+			in.PreviousOutPoint.String(), lock, entry.UnitIndex, unitIndex)
+		return TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED
+	}
+	return TxValidationCode_VALID
+}
+
 func (validate *Validate) pickJuryFn(contractAddr common.Address) ([]byte, error) {
 	log.Debugf("Try to pickup jury for address:%s", contractAddr.String())
 	var redeemScript []byte
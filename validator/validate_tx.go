@@ -153,6 +153,10 @@ func (validate *Validate) validateTx(tx *modules.Transaction, isFullTx bool) (Va
 			if validateCode != TxValidationCode_VALID {
 				return validateCode, txFee
 			}
+			validateCode = validate.validateContractRangeReadSet(payload.ContractId, payload.RangeReadSet)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
 		case modules.APP_CONTRACT_TPL_REQUEST:
 			if hasRequestMsg { //一个Tx只有一个Request
 				return TxValidationCode_INVALID_MSG, txFee
@@ -187,6 +191,9 @@ func (validate *Validate) validateTx(tx *modules.Transaction, isFullTx bool) (Va
 			if validateCode != TxValidationCode_VALID {
 				return validateCode, txFee
 			}
+			if payload.UpgradePolicy != nil && payload.UpgradePolicy.Validate() != nil {
+				return TxValidationCode_INVALID_CONTRACT, txFee
+			}
 		case modules.APP_CONTRACT_INVOKE_REQUEST:
 			if hasRequestMsg { //一个Tx只有一个Request
 				return TxValidationCode_INVALID_MSG, txFee
@@ -202,6 +209,16 @@ func (validate *Validate) validateTx(tx *modules.Transaction, isFullTx bool) (Va
 			if common.IsSystemContractId(contractId) {
 				isSysContractCall = true
 			}
+			//迁移函数只能在合约升级时调用，否则会绕过升级权限检查
+			if modules.IsContractMigrateCall(payload.Args) {
+				return TxValidationCode_INVALID_CONTRACT, txFee
+			}
+			if bytes.Equal(contractId, syscontract.SchedulerContractAddress.Bytes()) {
+				validateCode := validate.validateScheduleRegister(tx, payload)
+				if validateCode != TxValidationCode_VALID {
+					return validateCode, txFee
+				}
+			}
 		case modules.APP_CONTRACT_STOP_REQUEST:
 			payload, _ := msg.Payload.(*modules.ContractStopRequestPayload)
 			if len(payload.ContractId) == 0 {
@@ -218,6 +235,37 @@ func (validate *Validate) validateTx(tx *modules.Transaction, isFullTx bool) (Va
 			if validateCode != TxValidationCode_VALID {
 				return validateCode, txFee
 			}
+		case modules.APP_CONTRACT_UPGRADE_REQUEST:
+			if hasRequestMsg { //一个Tx只有一个Request
+				return TxValidationCode_INVALID_MSG, txFee
+			}
+			hasRequestMsg = true
+			requestMsgIndex = msgIdx
+			payload, _ := msg.Payload.(*modules.ContractUpgradeRequestPayload)
+			validateCode := validate.validateContractUpgrade(tx, payload)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
+		case modules.APP_CONTRACT_UPGRADE:
+			payload, _ := msg.Payload.(*modules.ContractUpgradePayload)
+			validateCode := validateContractUpgradeResult(tx, payload)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
+			validateCode = validate.validateContractState(payload.ContractId, payload.ReadSet, payload.WriteSet)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
+			validateCode = validate.validateContractRangeReadSet(payload.ContractId, payload.RangeReadSet)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
+		case modules.APP_CONTRACT_JURY_UPDATE:
+			payload, _ := msg.Payload.(*modules.ContractJuryUpdatePayload)
+			validateCode := validate.validateContractJuryUpdate(payload)
+			if validateCode != TxValidationCode_VALID {
+				return validateCode, txFee
+			}
 		case modules.APP_SIGNATURE:
 			// 签名验证
 			payload, _ := msg.Payload.(*modules.SignaturePayload)
@@ -298,7 +346,7 @@ func (validate *Validate) ValidateTxFeeEnough(tx *modules.Transaction, extSize f
 
 	var onlyPayment = true
 	var timeout uint32
-	var opFee, sizeFee, timeFee, accountUpdateFee, appDataFee, allFee float64
+	var opFee, sizeFee, timeFee, accountUpdateFee, appDataFee, gasFee, allFee float64
 	reqId := tx.RequestHash()
 	txSize := tx.Size().Float64()
 
@@ -327,10 +375,17 @@ func (validate *Validate) ValidateTxFeeEnough(tx *modules.Transaction, extSize f
 			onlyPayment = false
 			opFee = cp.ContractTxInvokeFeeLevel
 			timeout = msg.Payload.(*modules.ContractInvokeRequestPayload).Timeout
+		case modules.APP_CONTRACT_INVOKE:
+			//合约执行使用的Gas
+			gasFee += float64(modules.ContractGasFee(msg.Payload.(*modules.ContractInvokePayload).GasUsed))
 		case modules.APP_CONTRACT_STOP_REQUEST:
 			onlyPayment = false
 			opFee = cp.ContractTxStopFeeLevel
-		case modules.APP_DATA:
+		case modules.APP_CONTRACT_UPGRADE_REQUEST:
+			onlyPayment = false
+			opFee = cp.ContractTxDeployFeeLevel
+			timeout = msg.Payload.(*modules.ContractUpgradeRequestPayload).Timeout
+		case modules.APP_DATA, modules.APP_CONTRACT_JURY_UPDATE:
 			onlyPayment = false
 			appDataFee = float64(cp.ChainParametersBase.TransferPtnPricePerKByte) * ((txSize + extSize) / 1024)
 		case modules.APP_ACCOUNT_UPDATE:
@@ -343,23 +398,28 @@ func (validate *Validate) ValidateTxFeeEnough(tx *modules.Transaction, extSize f
 	} else {
 		sizeFee = opFee * sizeUnitFee * (txSize + extSize)
 		timeFee = opFee * timeUnitFee * (float64(timeout) + extTime)
-		allFee = sizeFee + timeFee + accountUpdateFee + appDataFee
+		allFee = sizeFee + timeFee + accountUpdateFee + appDataFee + gasFee
 	}
 	val := math.Max(float64(fees.Amount), allFee) == float64(fees.Amount)
 	if !val {
-		log.Errorf("[%s]validateTxFeeEnough invalid, fee amount[%f]-fees[%f] (%f + %f + %f + %f), "+
+		log.Errorf("[%s]validateTxFeeEnough invalid, fee amount[%f]-fees[%f] (%f + %f + %f + %f + %f), "+
 			"txSize[%f], timeout[%d], extSize[%f], extTime[%f]",
 			shortId(reqId.String()), float64(fees.Amount), allFee, sizeFee, timeFee, accountUpdateFee, appDataFee,
-			txSize, timeout, extSize, extTime)
+			gasFee, txSize, timeout, extSize, extTime)
 	}
 
-	log.Debugf("[%s]validateTxFeeEnough is %v, fee amount[%f]-fees[%f](%f + %f + %f + %f), "+
+	log.Debugf("[%s]validateTxFeeEnough is %v, fee amount[%f]-fees[%f](%f + %f + %f + %f + %f), "+
 		"txSize[%f], timeout[%d], extSize[%f], extTime[%f]",
 		shortId(reqId.String()), val, float64(fees.Amount), allFee, sizeFee, timeFee, accountUpdateFee, appDataFee,
-		txSize, timeout, extSize, extTime)
+		gasFee, txSize, timeout, extSize, extTime)
 	return val
 }
 
+//陪审员的信誉，用于分配合约执行费和陪审员选举
+func (validate *Validate) getJurorScore(jurorAdd common.Address) uint32 {
+	return validate.statequery.GetJurorReputation(jurorAdd).Score()
+}
+
 //验证手续费是否合法，并返回手续费的分配情况
 func (validate *Validate) validateTxFeeValid(tx *modules.Transaction) (bool, []*modules.Addition) {
 	if tx == nil {
@@ -380,7 +440,8 @@ func (validate *Validate) validateTxFeeValid(tx *modules.Transaction) (bool, []*
 			return false, nil
 		}
 		feeAllocate, err := tx.GetTxFeeAllocate(validate.utxoquery.GetUtxoEntry,
-			validate.tokenEngine.GetScriptSigners, common.Address{}, validate.statequery.GetJurorReward)
+			validate.tokenEngine.GetScriptSigners, common.Address{}, validate.statequery.GetJurorReward,
+			validate.getJurorScore)
 		if err != nil {
 			log.Warnf("[%s]validateTxFeeValid, compute tx[%s] fee error:%s", shortId(reqId.String()), tx.Hash().String(), err.Error())
 			return false, nil
@@ -470,6 +531,18 @@ func validateMessageType(app modules.MessageType, payload interface{}) bool {
 		if app == modules.APP_CONTRACT_STOP {
 			return true
 		}
+	case *modules.ContractUpgradeRequestPayload:
+		if app == modules.APP_CONTRACT_UPGRADE_REQUEST {
+			return true
+		}
+	case *modules.ContractUpgradePayload:
+		if app == modules.APP_CONTRACT_UPGRADE {
+			return true
+		}
+	case *modules.ContractJuryUpdatePayload:
+		if app == modules.APP_CONTRACT_JURY_UPDATE {
+			return true
+		}
 
 	default:
 		log.Debug("The payload of message type is unexpected. ", "payload_type", t, "app type", app)
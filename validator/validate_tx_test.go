@@ -49,6 +49,9 @@ func (id *mockiDagQuery) GetTransactionOnly(hash common.Hash) (*modules.Transact
 func (id *mockiDagQuery) IsTransactionExist(hash common.Hash) (bool, error) {
 	return true, nil
 }
+func (id *mockiDagQuery) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
+	return nil, nil
+}
 func (id *mockiDagQuery) GetHeaderByHash(common.Hash) (*modules.Header, error) {
 	return nil, nil
 }
@@ -68,12 +71,18 @@ type mockiPropQuery struct{}
 func (ip *mockiPropQuery) GetSlotAtTime(when time.Time) uint32 {
 	return 0
 }
+func (ip *mockiPropQuery) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	return nil, errors.New("not found")
+}
 func (ip *mockiPropQuery) GetScheduledMediator(slotNum uint32) common.Address {
 	return common.Address{}
 }
 func (ip *mockiPropQuery) GetNewestUnitTimestamp(token modules.AssetId) (int64, error) {
 	return 0, nil
 }
+func (ip *mockiPropQuery) GetNewestUnit(token modules.AssetId) (common.Hash, *modules.ChainIndex, error) {
+	return common.Hash{}, modules.NewChainIndex(token, 0), nil
+}
 func (ip *mockiPropQuery) GetChainParameters() *core.ChainParameters {
 	cp := core.NewChainParams()
 	return &cp
@@ -285,3 +294,39 @@ func TestValidateLargeInputPayment(t *testing.T) {
 	t.Logf("Validate send time:%s", time.Since(t1))
 	assert.Nil(t, err)
 }
+
+type mockLookupDagQuery struct {
+	mockiDagQuery
+	entry *modules.TxLookupEntry
+}
+
+func (id *mockLookupDagQuery) GetTxLookupEntry(hash common.Hash) (*modules.TxLookupEntry, error) {
+	return id.entry, nil
+}
+
+func TestCheckSequenceLock(t *testing.T) {
+	dagq := &mockLookupDagQuery{entry: &modules.TxLookupEntry{UnitIndex: 100}}
+	validat := NewValidate(dagq, nil, &mockStatedbQuery{}, &mockiPropQuery{}, newCache(), false)
+	utxo := &modules.Utxo{Amount: 1, Timestamp: 1000}
+	in := modules.NewTxIn(modules.NewOutPoint(hash1, 0, 0), nil)
+
+	//by units
+	in.Sequence = modules.NewSequenceLockByUnits(10)
+	validat.unitIndex = 109
+	validat.unitTime = 2000
+	assert.Equal(t, TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED, validat.checkSequenceLock(in, utxo))
+	validat.unitIndex = 110
+	assert.Equal(t, TxValidationCode_VALID, validat.checkSequenceLock(in, utxo))
+
+	//by seconds
+	in.Sequence = modules.NewSequenceLockBySeconds(1024)
+	validat.unitTime = 2023
+	assert.Equal(t, TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED, validat.checkSequenceLock(in, utxo))
+	validat.unitTime = 2024
+	assert.Equal(t, TxValidationCode_VALID, validat.checkSequenceLock(in, utxo))
+
+	//utxo not confirmed
+	dagq.entry = nil
+	in.Sequence = modules.NewSequenceLockByUnits(1)
+	assert.Equal(t, TxValidationCode_SEQUENCE_LOCK_NOT_SATISFIED, validat.checkSequenceLock(in, utxo))
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/palletone/go-palletone/core"
 	"github.com/palletone/go-palletone/dag/dagconfig"
 	"github.com/palletone/go-palletone/dag/modules"
+	"go.dedis.ch/kyber/v3/sign/bls"
 )
 
 const ENABLE_TX_FEE_CHECK_TIME = 1570870800             //2019-10-12 17:00:00
@@ -247,6 +248,8 @@ func (validate *Validate) ValidateUnitExceptGroupSig(unit *modules.Unit) Validat
 	validate.enableContractSignCheck = unit.Timestamp() > ENABLE_CONTRACT_SIGN_CHECK_TIME   // 1.0.4升级，支持交易费检查
 	validate.enableDeveloperCheck = unit.Timestamp() > ENABLE_CONTRACT_DEVELOPER_CHECK_TIME // 1.0.5升级，支持合约模板部署时的开发者角色检查
 	validate.enableContractRwSetCheck = unit.Timestamp() > ENABLE_CONTRACT_RWSET_CHECK_TIME
+	validate.unitIndex = unit.NumberU64()
+	validate.unitTime = unit.Timestamp()
 	//if validate.enableTxFeeCheck{
 	//	log.Infof("Enable tx fee check since %d",unit.Timestamp())
 	//}
@@ -319,6 +322,9 @@ func (validate *Validate) validateHeaderExceptGroupSig(header *modules.Header, e
 		if parentHeader.GetNumber().Index+1 != header.GetNumber().Index {
 			return UNIT_STATE_INVALID_HEADER_NUMBER
 		}
+		if vcode := validatePrevGroupSign(header, parentHeader); vcode != TxValidationCode_VALID {
+			return vcode
+		}
 
 		if enableMediatorSchedule && !validate.light {
 			vcode := validate.validateMediatorSchedule(header)
@@ -327,9 +333,50 @@ func (validate *Validate) validateHeaderExceptGroupSig(header *modules.Header, e
 			}
 		}
 	}
+	validate.checkSnapshotHash(header)
 	return TxValidationCode_VALID
 }
 
+//validatePrevGroupSign 单元记录的父单元群签名必须能用父单元的群公钥验证，
+//否则生产者可以写入任意值来操纵该高度的随机数。生产者没有收到群签名时可以不记录
+func validatePrevGroupSign(header, parent *modules.Header) ValidationCode {
+	sign := header.GetPrevGroupSign()
+	if len(sign) == 0 {
+		return TxValidationCode_VALID
+	}
+	pubKey, err := parent.GetGroupPubKey()
+	if err != nil {
+		log.Warnf("Unit[%d] records group sign of parent, but the group public key is invalid:%s",
+			header.NumberU64(), err.Error())
+		return UNIT_STATE_INVALID_GROUP_SIGNATURE
+	}
+	if err := bls.Verify(core.Suite, pubKey, parent.Hash().Bytes(), sign); err != nil {
+		log.Warnf("Unit[%d] records invalid group sign of parent:%s", header.NumberU64(), err.Error())
+		return UNIT_STATE_INVALID_GROUP_SIGNATURE
+	}
+	return TxValidationCode_VALID
+}
+
+//checkSnapshotHash 比较单元Header中提交的快照Hash与本地在快照点计算的是否一致，
+//快照Hash依赖本地数据库的内容，只用于提示，不一致时记录日志，不拒绝单元。
+//本地还没有计算快照Hash(轻节点，或快照点单元尚未稳定)时无法比较
+func (validate *Validate) checkSnapshotHash(header *modules.Header) bool {
+	committed, index, ok := header.GetSnapshotHash()
+	if !ok || validate.propquery == nil {
+		return true
+	}
+	c, err := validate.propquery.GetSnapshotCommitment(index)
+	if err != nil {
+		return true
+	}
+	if c.Hash != committed {
+		log.Errorf("Unit[%d] commits snapshot hash %s of unit[%d], but the local is %s",
+			header.NumberU64(), committed.String(), index, c.Hash.String())
+		return false
+	}
+	return true
+}
+
 func (validate *Validate) ValidateHeader(h *modules.Header) ValidationCode {
 	hash := h.Hash()
 	has, code := validate.cache.HasHeaderValidateResult(hash)
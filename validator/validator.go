@@ -48,6 +48,9 @@ type Validate struct {
 	enableDeveloperCheck     bool
 	enableContractRwSetCheck bool
 	light                    bool
+	//当前正在验证的Unit的高度和时间，用于相对锁定时间的检查，为0表示验证的是交易池中的交易
+	unitIndex uint64
+	unitTime  int64
 }
 
 func NewValidate(dagdb IDagQuery, utxoRep IUtxoQuery, statedb IStateQuery, propquery IPropQuery,
@@ -218,6 +221,8 @@ func (validate *Validate) ValidateTx(tx *modules.Transaction, isFullTx bool) ([]
 	validate.enableContractSignCheck = true
 	validate.enableDeveloperCheck = true
 	validate.enableContractRwSetCheck = true
+	validate.unitIndex = 0
+	validate.unitTime = 0
 	code, addition := validate.validateTx(tx, isFullTx)
 	if code == TxValidationCode_VALID {
 		validate.cache.AddTxValidateResult(txId, addition)
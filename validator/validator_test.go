@@ -77,6 +77,10 @@ func (q *mockStatedbQuery) GetContractJury(contractId []byte) (*modules.Election
 	return nil, nil
 }
 
+func (q *mockStatedbQuery) GetContract(id []byte) (*modules.Contract, error) {
+	return nil, errors.New("contract not found")
+}
+
 func (q *mockStatedbQuery) GetContractState(id []byte, field string) ([]byte, *modules.StateVersion, error) {
 	return nil, nil, nil
 }
@@ -93,6 +97,10 @@ func (q *mockStatedbQuery) GetJurorReward(jurorAdd common.Address) common.Addres
 	return jurorAdd
 }
 
+func (q *mockStatedbQuery) GetJurorReputation(jurorAdd common.Address) *modules.JurorReputation {
+	return modules.NewJurorReputation()
+}
+
 func (q *mockStatedbQuery) GetTxRequesterAddress(tx *modules.Transaction) (common.Address, error) {
 	return common.Address{}, nil
 }
@@ -251,6 +259,37 @@ func TestValidate_ValidateHeader(t *testing.T) {
 	assert.Equal(t, vresult, TxValidationCode_VALID)
 }
 
+type snapshotPropQuery struct {
+	mockiPropQuery
+	commit *modules.SnapshotCommitment
+}
+
+func (q *snapshotPropQuery) GetSnapshotCommitment(index uint64) (*modules.SnapshotCommitment, error) {
+	if q.commit == nil || q.commit.UnitIndex != index {
+		return nil, errors.New("not found")
+	}
+	return q.commit, nil
+}
+
+func TestValidate_CheckSnapshotHash(t *testing.T) {
+	hash := common.BytesToHash([]byte("snapshot"))
+	newCommitHeader := func(extra []byte) *modules.Header {
+		return modules.NewHeader([]common.Hash{}, common.Hash{}, []byte{}, []byte{}, extra, []byte{},
+			[]uint16{}, modules.PTNCOIN, modules.SnapshotCommitIndex(modules.SnapshotInterval), 300)
+	}
+	q := &snapshotPropQuery{}
+	v := NewValidate(nil, nil, &mockStatedbQuery{}, q, newCache(), false)
+
+	//本地还没有计算快照Hash时无法验证
+	assert.True(t, v.checkSnapshotHash(newCommitHeader(hash.Bytes())))
+
+	q.commit = &modules.SnapshotCommitment{UnitIndex: modules.SnapshotInterval, Hash: hash}
+	assert.True(t, v.checkSnapshotHash(newCommitHeader(hash.Bytes())))
+	other := common.BytesToHash([]byte("other"))
+	//不一致时只记录日志
+	assert.False(t, v.checkSnapshotHash(newCommitHeader(other.Bytes())))
+}
+
 func TestSignAndVerifyATx(t *testing.T) {
 
 	privKeyBytes, _ := hex.DecodeString("2BE3B4B671FF5B8009E6876CCCC8808676C1C279EE824D0AB530294838DC1644")
@@ -22,6 +22,7 @@ package inproccontroller
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/palletone/go-palletone/common/log"
 	"github.com/palletone/go-palletone/contracts/shim"
@@ -42,6 +43,7 @@ type inprocContainer struct {
 var (
 	//log        = flogging.MustGetLogger("inproccontroller")
 	typeRegistry     = make(map[string]*inprocContainer)
+	typeRegistryLock sync.RWMutex //WASM合约部署和升级时会并发注册、注销
 	instRegistry     = make(map[string]*inprocContainer)
 	_shimStartInProc = shim.StartInProc
 	_logErrorf       = log.Errorf
@@ -58,6 +60,8 @@ func (s SysCCRegisteredErr) Error() string {
 
 //Register registers system chaincode with given path. The deploy should be called to initialize
 func Register(path string, cc shim.Chaincode) error {
+	typeRegistryLock.Lock()
+	defer typeRegistryLock.Unlock()
 	tmp := typeRegistry[path]
 	if tmp != nil {
 		return SysCCRegisteredErr(path)
@@ -67,6 +71,19 @@ func Register(path string, cc shim.Chaincode) error {
 	return nil
 }
 
+//Deregister removes the chaincode registered with given path, the instance must be stopped
+func Deregister(path string) {
+	typeRegistryLock.Lock()
+	defer typeRegistryLock.Unlock()
+	delete(typeRegistry, path)
+}
+
+func getRegistered(path string) *inprocContainer {
+	typeRegistryLock.RLock()
+	defer typeRegistryLock.RUnlock()
+	return typeRegistry[path]
+}
+
 //InprocVM is a vm. It is identified by a executable name
 type InprocVM struct {
 	//id string
@@ -88,7 +105,7 @@ func (vm *InprocVM) getInstance(_ context.Context, ipctemplate *inprocContainer,
 func (vm *InprocVM) Deploy(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, reader io.Reader) error {
 	path := ccid.ChaincodeSpec.ChaincodeId.Path
 
-	ipctemplate := typeRegistry[path]
+	ipctemplate := getRegistered(path)
 	if ipctemplate == nil {
 		return fmt.Errorf(fmt.Sprintf("%s not registered. Please register the system chaincode in inprocinstances.go", path))
 	}
@@ -162,7 +179,7 @@ func (ipc *inprocContainer) launchInProc(ctxt context.Context, id string, args [
 func (vm *InprocVM) Start(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, filesToUpload map[string][]byte, builder container.BuildSpecFactory, prelaunchFunc container.PrelaunchFunc) error {
 	path := ccid.ChaincodeSpec.ChaincodeId.Path
 
-	ipctemplate := typeRegistry[path]
+	ipctemplate := getRegistered(path)
 
 	if ipctemplate == nil {
 		return fmt.Errorf(fmt.Sprintf("%s not registered", path))
@@ -211,7 +228,7 @@ func (vm *InprocVM) Start(ctxt context.Context, ccid ccintf.CCID, args []string,
 func (vm *InprocVM) Stop(ctxt context.Context, ccid ccintf.CCID, timeout uint, dontkill bool, dontremove bool) error {
 	path := ccid.ChaincodeSpec.ChaincodeId.Path
 
-	ipctemplate := typeRegistry[path]
+	ipctemplate := getRegistered(path)
 	if ipctemplate == nil {
 		return fmt.Errorf("%s not registered", path)
 	}
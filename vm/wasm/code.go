@@ -0,0 +1,324 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	opUnreachable  = 0x00
+	opNop          = 0x01
+	opBlock        = 0x02
+	opLoop         = 0x03
+	opIf           = 0x04
+	opElse         = 0x05
+	opEnd          = 0x0b
+	opBr           = 0x0c
+	opBrIf         = 0x0d
+	opBrTable      = 0x0e
+	opReturn       = 0x0f
+	opCall         = 0x10
+	opCallIndirect = 0x11
+	opDrop         = 0x1a
+	opSelect       = 0x1b
+	opLocalGet     = 0x20
+	opLocalSet     = 0x21
+	opLocalTee     = 0x22
+	opGlobalGet    = 0x23
+	opGlobalSet    = 0x24
+	opI32Load      = 0x28
+	opI64Load      = 0x29
+	opI32Load8S    = 0x2c
+	opI32Load8U    = 0x2d
+	opI32Load16S   = 0x2e
+	opI32Load16U   = 0x2f
+	opI64Load8S    = 0x30
+	opI64Load8U    = 0x31
+	opI64Load16S   = 0x32
+	opI64Load16U   = 0x33
+	opI64Load32S   = 0x34
+	opI64Load32U   = 0x35
+	opI32Store     = 0x36
+	opI64Store     = 0x37
+	opI32Store8    = 0x3a
+	opI32Store16   = 0x3b
+	opI64Store8    = 0x3c
+	opI64Store16   = 0x3d
+	opI64Store32   = 0x3e
+	opMemorySize   = 0x3f
+	opMemoryGrow   = 0x40
+	opI32Const     = 0x41
+	opI64Const     = 0x42
+
+	opI32Eqz = 0x45
+	opI32Eq  = 0x46
+	opI32Ne  = 0x47
+	opI32LtS = 0x48
+	opI32LtU = 0x49
+	opI32GtS = 0x4a
+	opI32GtU = 0x4b
+	opI32LeS = 0x4c
+	opI32LeU = 0x4d
+	opI32GeS = 0x4e
+	opI32GeU = 0x4f
+	opI64Eqz = 0x50
+	opI64Eq  = 0x51
+	opI64Ne  = 0x52
+	opI64LtS = 0x53
+	opI64LtU = 0x54
+	opI64GtS = 0x55
+	opI64GtU = 0x56
+	opI64LeS = 0x57
+	opI64LeU = 0x58
+	opI64GeS = 0x59
+	opI64GeU = 0x5a
+
+	opI32Clz    = 0x67
+	opI32Ctz    = 0x68
+	opI32Popcnt = 0x69
+	opI32Add    = 0x6a
+	opI32Sub    = 0x6b
+	opI32Mul    = 0x6c
+	opI32DivS   = 0x6d
+	opI32DivU   = 0x6e
+	opI32RemS   = 0x6f
+	opI32RemU   = 0x70
+	opI32And    = 0x71
+	opI32Or     = 0x72
+	opI32Xor    = 0x73
+	opI32Shl    = 0x74
+	opI32ShrS   = 0x75
+	opI32ShrU   = 0x76
+	opI32Rotl   = 0x77
+	opI32Rotr   = 0x78
+	opI64Clz    = 0x79
+	opI64Ctz    = 0x7a
+	opI64Popcnt = 0x7b
+	opI64Add    = 0x7c
+	opI64Sub    = 0x7d
+	opI64Mul    = 0x7e
+	opI64DivS   = 0x7f
+	opI64DivU   = 0x80
+	opI64RemS   = 0x81
+	opI64RemU   = 0x82
+	opI64And    = 0x83
+	opI64Or     = 0x84
+	opI64Xor    = 0x85
+	opI64Shl    = 0x86
+	opI64ShrS   = 0x87
+	opI64ShrU   = 0x88
+	opI64Rotl   = 0x89
+	opI64Rotr   = 0x8a
+
+	opI32WrapI64    = 0xa7
+	opI64ExtendI32S = 0xac
+	opI64ExtendI32U = 0xad
+	opI32Extend8S   = 0xc0
+	opI32Extend16S  = 0xc1
+	opI64Extend8S   = 0xc2
+	opI64Extend16S  = 0xc3
+	opI64Extend32S  = 0xc4
+)
+
+// instr is a pre-decoded instruction, the targets of blocks are resolved when
+// the function body is decoded.
+type instr struct {
+	op    byte
+	imm   uint64   // 常量、局部/全局变量索引、跳转深度、函数索引、内存偏移
+	arity int      // block/loop/if的返回值个数
+	else_ int      // if对应的else位置，-1表示没有else
+	end   int      // block/loop/if对应的end位置
+	table []uint32 // br_table的跳转深度，最后一个为默认值
+}
+
+func isFloatOp(op byte) bool {
+	switch {
+	case op == 0x2a || op == 0x2b || op == 0x38 || op == 0x39: //f32/f64 load store
+		return true
+	case op == 0x43 || op == 0x44: //f32/f64 const
+		return true
+	case op >= 0x5b && op <= 0x66: //float compare
+		return true
+	case op >= 0x8b && op <= 0xa6: //float arithmetic
+		return true
+	case op >= 0xa8 && op <= 0xab: //trunc float to i32
+		return true
+	case op >= 0xae && op <= 0xbf: //float conversion and reinterpret
+		return true
+	}
+	return false
+}
+
+func (r *reader) blockType() (int, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 0x40:
+		return 0, nil
+	case byte(ValueTypeI32), byte(ValueTypeI64):
+		return 1, nil
+	case 0x7d, 0x7c:
+		return 0, ErrFloatUnsupported
+	}
+	return 0, fmt.Errorf("unsupported block type 0x%x", b)
+}
+
+func (m *Module) decodeBody(r *reader, nLocals uint32) ([]instr, error) {
+	code := make([]instr, 0, len(r.buf)/2)
+	blocks := []int{} //未结束的block/loop/if位置
+	for {
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if isFloatOp(op) {
+			return nil, ErrFloatUnsupported
+		}
+		ins := instr{op: op, else_: -1, end: -1}
+		switch {
+		case op == opBlock || op == opLoop || op == opIf:
+			if ins.arity, err = r.blockType(); err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, len(code))
+		case op == opElse:
+			if len(blocks) == 0 || code[blocks[len(blocks)-1]].op != opIf ||
+				code[blocks[len(blocks)-1]].else_ >= 0 {
+				return nil, errors.New("else without if")
+			}
+			code[blocks[len(blocks)-1]].else_ = len(code)
+		case op == opEnd:
+			if len(blocks) == 0 {
+				//函数体结束
+				code = append(code, ins)
+				if !r.eof() {
+					return nil, errors.New("data after the end of function body")
+				}
+				return code, nil
+			}
+			start := blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+			code[start].end = len(code)
+			if code[start].else_ >= 0 {
+				code[code[start].else_].end = len(code)
+			}
+		case op == opBr || op == opBrIf:
+			if ins.imm, err = m.index(r, uint32(len(blocks))+1, "label"); err != nil {
+				return nil, err
+			}
+		case op == opBrTable:
+			n, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if n > uint32(len(r.buf)) {
+				return nil, ErrUnexpectedEOF
+			}
+			ins.table = make([]uint32, n+1)
+			for i := range ins.table {
+				d, err := m.index(r, uint32(len(blocks))+1, "label")
+				if err != nil {
+					return nil, err
+				}
+				ins.table[i] = uint32(d)
+			}
+		case op == opCall:
+			if ins.imm, err = m.index(r, m.funcCount(), "function"); err != nil {
+				return nil, err
+			}
+		case op == opCallIndirect:
+			if ins.imm, err = m.index(r, uint32(len(m.Types)), "type"); err != nil {
+				return nil, err
+			}
+			reserved, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if reserved != 0 || m.Table == nil {
+				return nil, errors.New("call_indirect without table")
+			}
+		case op >= opLocalGet && op <= opLocalTee:
+			if ins.imm, err = m.index(r, nLocals, "local"); err != nil {
+				return nil, err
+			}
+		case op == opGlobalGet || op == opGlobalSet:
+			if ins.imm, err = m.index(r, uint32(len(m.Globals)), "global"); err != nil {
+				return nil, err
+			}
+			if op == opGlobalSet && !m.Globals[ins.imm].Mutable {
+				return nil, errors.New("global is immutable")
+			}
+		case op >= opI32Load && op <= opI64Store32:
+			if m.Memory == nil {
+				return nil, errors.New("memory access without memory")
+			}
+			if _, err = r.u32(); err != nil { //align
+				return nil, err
+			}
+			offset, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			ins.imm = uint64(offset)
+		case op == opMemorySize || op == opMemoryGrow:
+			if m.Memory == nil {
+				return nil, errors.New("memory access without memory")
+			}
+			if b, err := r.byte(); err != nil || b != 0 {
+				return nil, errors.New("invalid memory index")
+			}
+		case op == opI32Const:
+			v, err := r.s32()
+			if err != nil {
+				return nil, err
+			}
+			ins.imm = uint64(uint32(v))
+		case op == opI64Const:
+			v, err := r.s64()
+			if err != nil {
+				return nil, err
+			}
+			ins.imm = uint64(v)
+		case op == opUnreachable || op == opNop || op == opReturn || op == opDrop || op == opSelect:
+		case op >= opI32Eqz && op <= opI64GeU:
+		case op >= opI32Clz && op <= opI64Rotr:
+		case op == opI32WrapI64 || op == opI64ExtendI32S || op == opI64ExtendI32U:
+		case op >= opI32Extend8S && op <= opI64Extend32S:
+		default:
+			return nil, fmt.Errorf("unsupported opcode 0x%x", op)
+		}
+		code = append(code, ins)
+	}
+}
+
+func (m *Module) index(r *reader, max uint32, kind string) (uint64, error) {
+	idx, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if idx >= max {
+		return 0, fmt.Errorf("invalid %s index %d", kind, idx)
+	}
+	return uint64(idx), nil
+}
@@ -0,0 +1,708 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+// Package wasm implements a deterministic WebAssembly interpreter for chaincode.
+// Only the integer subset of the WebAssembly MVP is supported, floating point
+// instructions are rejected when the module is decoded so that every node gets
+// exactly the same result. Every executed instruction is metered by steps.
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var magic = []byte{0x00, 0x61, 0x73, 0x6d}
+var version = []byte{0x01, 0x00, 0x00, 0x00}
+
+const (
+	MaxPages     = 256 // 16MB
+	PageSize     = 65536
+	MaxFunctions = 10000
+	MaxLocals    = 50000
+	MaxTableSize = 10000
+)
+
+var (
+	ErrInvalidMagic     = errors.New("wasm: invalid magic number")
+	ErrInvalidVersion   = errors.New("wasm: unsupported version")
+	ErrUnexpectedEOF    = errors.New("wasm: unexpected end of module")
+	ErrFloatUnsupported = errors.New("wasm: floating point is not supported")
+)
+
+type ValueType byte
+
+const (
+	ValueTypeI32 ValueType = 0x7f
+	ValueTypeI64 ValueType = 0x7e
+)
+
+const (
+	ExternalFunction byte = 0x00
+	ExternalTable    byte = 0x01
+	ExternalMemory   byte = 0x02
+	ExternalGlobal   byte = 0x03
+)
+
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+func (t *FuncType) Equal(o *FuncType) bool {
+	return bytes.Equal(valueTypes(t.Params), valueTypes(o.Params)) &&
+		bytes.Equal(valueTypes(t.Results), valueTypes(o.Results))
+}
+
+func (t *FuncType) String() string {
+	return fmt.Sprintf("%x->%x", valueTypes(t.Params), valueTypes(t.Results))
+}
+
+func valueTypes(vts []ValueType) []byte {
+	b := make([]byte, len(vts))
+	for i, vt := range vts {
+		b[i] = byte(vt)
+	}
+	return b
+}
+
+type Limits struct {
+	Min    uint32
+	Max    uint32
+	HasMax bool
+}
+
+// 只支持导入函数
+type Import struct {
+	Module  string
+	Name    string
+	TypeIdx uint32
+}
+
+type Global struct {
+	Type    ValueType
+	Mutable bool
+	Init    uint64
+}
+
+type Export struct {
+	Name  string
+	Kind  byte
+	Index uint32
+}
+
+type Function struct {
+	TypeIdx uint32
+	Locals  []ValueType
+	body    []instr
+}
+
+type ElemSegment struct {
+	Offset uint32
+	Funcs  []uint32
+}
+
+type DataSegment struct {
+	Offset uint32
+	Data   []byte
+}
+
+// Module is a decoded and checked WebAssembly module
+type Module struct {
+	Types     []FuncType
+	Imports   []Import
+	Functions []*Function
+	Table     *Limits
+	Memory    *Limits
+	Globals   []Global
+	Exports   map[string]Export
+	Start     int64 // -1表示没有start函数
+	Elements  []ElemSegment
+	Data      []DataSegment
+}
+
+// funcType returns the type of the function index, imported functions first
+func (m *Module) funcType(idx uint32) *FuncType {
+	if idx < uint32(len(m.Imports)) {
+		return &m.Types[m.Imports[idx].TypeIdx]
+	}
+	return &m.Types[m.Functions[idx-uint32(len(m.Imports))].TypeIdx]
+}
+
+func (m *Module) funcCount() uint32 {
+	return uint32(len(m.Imports) + len(m.Functions))
+}
+
+// ExportedFunc returns the type of an exported function
+func (m *Module) ExportedFunc(name string) (*FuncType, bool) {
+	e, ok := m.Exports[name]
+	if !ok || e.Kind != ExternalFunction {
+		return nil, false
+	}
+	return m.funcType(e.Index), true
+}
+
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) eof() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n uint32) ([]byte, error) {
+	if uint64(r.pos)+uint64(n) > uint64(len(r.buf)) {
+		return nil, ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	var result uint32
+	var shift uint
+	for i := 0; i < 5; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if i == 4 && b > 0x0f {
+			return 0, errors.New("wasm: invalid u32")
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		shift += 7
+	}
+	return 0, errors.New("wasm: invalid u32")
+}
+
+func (r *reader) sleb(size uint) (int64, error) {
+	var result int64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+			break
+		}
+		if shift >= size+7 {
+			return 0, errors.New("wasm: invalid signed integer")
+		}
+	}
+	return result, nil
+}
+
+func (r *reader) s32() (int32, error) {
+	v, err := r.sleb(32)
+	return int32(v), err
+}
+
+func (r *reader) s64() (int64, error) {
+	return r.sleb(64)
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+func (r *reader) valueType() (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch ValueType(b) {
+	case ValueTypeI32, ValueTypeI64:
+		return ValueType(b), nil
+	case 0x7d, 0x7c:
+		return 0, ErrFloatUnsupported
+	}
+	return 0, fmt.Errorf("wasm: invalid value type 0x%x", b)
+}
+
+func (r *reader) limits(max uint32) (*Limits, error) {
+	flag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	l := &Limits{}
+	if l.Min, err = r.u32(); err != nil {
+		return nil, err
+	}
+	switch flag {
+	case 0:
+	case 1:
+		if l.Max, err = r.u32(); err != nil {
+			return nil, err
+		}
+		l.HasMax = true
+		if l.Max < l.Min {
+			return nil, errors.New("wasm: limits max is less than min")
+		}
+	default:
+		return nil, fmt.Errorf("wasm: invalid limits flag 0x%x", flag)
+	}
+	if l.Min > max {
+		return nil, fmt.Errorf("wasm: limits min %d exceeds %d", l.Min, max)
+	}
+	return l, nil
+}
+
+// 常量表达式只支持i32.const和i64.const
+func (r *reader) constExpr() (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	switch op {
+	case opI32Const:
+		c, err := r.s32()
+		if err != nil {
+			return 0, err
+		}
+		v = uint64(uint32(c))
+	case opI64Const:
+		c, err := r.s64()
+		if err != nil {
+			return 0, err
+		}
+		v = uint64(c)
+	default:
+		return 0, fmt.Errorf("wasm: unsupported constant expression 0x%x", op)
+	}
+	end, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if end != opEnd {
+		return 0, errors.New("wasm: constant expression is not terminated")
+	}
+	return v, nil
+}
+
+// DecodeModule decodes the binary WebAssembly module and checks that it only
+// uses the supported deterministic instruction subset.
+func DecodeModule(code []byte) (*Module, error) {
+	r := &reader{buf: code}
+	b, err := r.bytes(4)
+	if err != nil || !bytes.Equal(b, magic) {
+		return nil, ErrInvalidMagic
+	}
+	b, err = r.bytes(4)
+	if err != nil || !bytes.Equal(b, version) {
+		return nil, ErrInvalidVersion
+	}
+	m := &Module{Exports: make(map[string]Export), Start: -1}
+	var funcTypes []uint32
+	var lastId byte
+	for !r.eof() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(size)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 { //custom section
+			continue
+		}
+		if id <= lastId {
+			return nil, fmt.Errorf("wasm: section %d out of order", id)
+		}
+		lastId = id
+		sr := &reader{buf: payload}
+		switch id {
+		case 1:
+			err = m.readTypes(sr)
+		case 2:
+			err = m.readImports(sr)
+		case 3:
+			funcTypes, err = m.readFunctions(sr)
+		case 4:
+			err = m.readTable(sr)
+		case 5:
+			err = m.readMemory(sr)
+		case 6:
+			err = m.readGlobals(sr)
+		case 7:
+			err = m.readExports(sr)
+		case 8:
+			var start uint32
+			if start, err = sr.u32(); err == nil {
+				if start >= m.funcCount()+uint32(len(funcTypes)) {
+					err = errors.New("wasm: invalid start function")
+				}
+				m.Start = int64(start)
+			}
+		case 9:
+			err = m.readElements(sr, uint32(len(funcTypes)))
+		case 10:
+			err = m.readCode(sr, funcTypes)
+		case 11:
+			err = m.readData(sr)
+		default:
+			err = fmt.Errorf("wasm: unknown section %d", id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !sr.eof() {
+			return nil, fmt.Errorf("wasm: section %d size mismatch", id)
+		}
+	}
+	if len(funcTypes) != len(m.Functions) {
+		return nil, errors.New("wasm: function and code section mismatch")
+	}
+	for _, e := range m.Exports {
+		if e.Kind == ExternalFunction && e.Index >= m.funcCount() {
+			return nil, fmt.Errorf("wasm: export %s of invalid function %d", e.Name, e.Index)
+		}
+	}
+	if m.Start >= 0 {
+		ft := m.funcType(uint32(m.Start))
+		if len(ft.Params) != 0 || len(ft.Results) != 0 {
+			return nil, errors.New("wasm: start function must be []->[]")
+		}
+	}
+	return m, nil
+}
+
+func (m *Module) readTypes(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return fmt.Errorf("wasm: invalid function type form 0x%x", form)
+		}
+		ft := FuncType{}
+		for _, vts := range []*[]ValueType{&ft.Params, &ft.Results} {
+			count, err := r.u32()
+			if err != nil {
+				return err
+			}
+			for j := uint32(0); j < count; j++ {
+				vt, err := r.valueType()
+				if err != nil {
+					return err
+				}
+				*vts = append(*vts, vt)
+			}
+		}
+		if len(ft.Results) > 1 {
+			return errors.New("wasm: multiple results are not supported")
+		}
+		m.Types = append(m.Types, ft)
+	}
+	return nil
+}
+
+func (m *Module) readImports(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		imp := Import{}
+		if imp.Module, err = r.name(); err != nil {
+			return err
+		}
+		if imp.Name, err = r.name(); err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if kind != ExternalFunction {
+			return fmt.Errorf("wasm: import %s.%s, only function can be imported", imp.Module, imp.Name)
+		}
+		if imp.TypeIdx, err = r.u32(); err != nil {
+			return err
+		}
+		if imp.TypeIdx >= uint32(len(m.Types)) {
+			return fmt.Errorf("wasm: import %s.%s of invalid type", imp.Module, imp.Name)
+		}
+		m.Imports = append(m.Imports, imp)
+	}
+	return nil
+}
+
+func (m *Module) readFunctions(r *reader) ([]uint32, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxFunctions {
+		return nil, fmt.Errorf("wasm: too many functions %d", n)
+	}
+	types := make([]uint32, n)
+	for i := range types {
+		if types[i], err = r.u32(); err != nil {
+			return nil, err
+		}
+		if types[i] >= uint32(len(m.Types)) {
+			return nil, fmt.Errorf("wasm: function %d of invalid type", i)
+		}
+	}
+	return types, nil
+}
+
+func (m *Module) readTable(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("wasm: only one table is allowed")
+	}
+	if n == 1 {
+		elemType, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if elemType != 0x70 {
+			return fmt.Errorf("wasm: invalid table element type 0x%x", elemType)
+		}
+		if m.Table, err = r.limits(MaxTableSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) readMemory(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("wasm: only one memory is allowed")
+	}
+	if n == 1 {
+		if m.Memory, err = r.limits(MaxPages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) readGlobals(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		g := Global{}
+		if g.Type, err = r.valueType(); err != nil {
+			return err
+		}
+		mut, err := r.byte()
+		if err != nil {
+			return err
+		}
+		g.Mutable = mut == 1
+		if g.Init, err = r.constExpr(); err != nil {
+			return err
+		}
+		m.Globals = append(m.Globals, g)
+	}
+	return nil
+}
+
+func (m *Module) readExports(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		e := Export{}
+		if e.Name, err = r.name(); err != nil {
+			return err
+		}
+		if e.Kind, err = r.byte(); err != nil {
+			return err
+		}
+		if e.Index, err = r.u32(); err != nil {
+			return err
+		}
+		if _, ok := m.Exports[e.Name]; ok {
+			return fmt.Errorf("wasm: duplicate export %s", e.Name)
+		}
+		m.Exports[e.Name] = e
+	}
+	return nil
+}
+
+func (m *Module) readElements(r *reader, definedFuncs uint32) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		tableIdx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if tableIdx != 0 || m.Table == nil {
+			return errors.New("wasm: element segment of invalid table")
+		}
+		offset, err := r.constExpr()
+		if err != nil {
+			return err
+		}
+		count, err := r.u32()
+		if err != nil {
+			return err
+		}
+		seg := ElemSegment{Offset: uint32(offset), Funcs: make([]uint32, count)}
+		for j := range seg.Funcs {
+			if seg.Funcs[j], err = r.u32(); err != nil {
+				return err
+			}
+			if seg.Funcs[j] >= m.funcCount()+definedFuncs {
+				return errors.New("wasm: element segment of invalid function")
+			}
+		}
+		m.Elements = append(m.Elements, seg)
+	}
+	return nil
+}
+
+func (m *Module) readData(r *reader) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		memIdx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if memIdx != 0 || m.Memory == nil {
+			return errors.New("wasm: data segment of invalid memory")
+		}
+		offset, err := r.constExpr()
+		if err != nil {
+			return err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		data, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		m.Data = append(m.Data, DataSegment{Offset: uint32(offset), Data: data})
+	}
+	return nil
+}
+
+func (m *Module) readCode(r *reader, funcTypes []uint32) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if n != uint32(len(funcTypes)) {
+		return errors.New("wasm: function and code section mismatch")
+	}
+	//先确定所有函数，指令检查时需要函数总数
+	m.Functions = make([]*Function, n)
+	for i := range m.Functions {
+		m.Functions[i] = &Function{TypeIdx: funcTypes[i]}
+	}
+	for i := uint32(0); i < n; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		f := m.Functions[i]
+		br := &reader{buf: body}
+		groups, err := br.u32()
+		if err != nil {
+			return err
+		}
+		total := uint64(len(m.Types[f.TypeIdx].Params))
+		for j := uint32(0); j < groups; j++ {
+			count, err := br.u32()
+			if err != nil {
+				return err
+			}
+			vt, err := br.valueType()
+			if err != nil {
+				return err
+			}
+			total += uint64(count)
+			if total > MaxLocals {
+				return fmt.Errorf("wasm: function %d has too many locals", i)
+			}
+			for k := uint32(0); k < count; k++ {
+				f.Locals = append(f.Locals, vt)
+			}
+		}
+		if f.body, err = m.decodeBody(br, uint32(total)); err != nil {
+			if err == ErrFloatUnsupported || err == ErrUnexpectedEOF {
+				return err
+			}
+			return fmt.Errorf("wasm: function %d: %s", i, err.Error())
+		}
+	}
+	return nil
+}
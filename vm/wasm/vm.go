@@ -0,0 +1,652 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+const MaxCallDepth = 512
+
+var (
+	ErrUnreachable       = errors.New("wasm: unreachable executed")
+	ErrOutOfBounds       = errors.New("wasm: out of bounds memory access")
+	ErrDivideByZero      = errors.New("wasm: integer divide by zero")
+	ErrIntegerOverflow   = errors.New("wasm: integer overflow")
+	ErrStepLimitExceeded = errors.New("wasm: step limit exceeded")
+	ErrCallStackOverflow = errors.New("wasm: call stack overflow")
+	ErrUndefinedElement  = errors.New("wasm: undefined table element")
+	ErrIndirectCallType  = errors.New("wasm: indirect call type mismatch")
+	ErrInvalidStack      = errors.New("wasm: invalid operand stack")
+)
+
+// HostFunction is a function imported by the module, the result is ignored if
+// the function type has no result.
+type HostFunction struct {
+	Type FuncType
+	Fn   func(vm *VM, args []uint64) (uint64, error)
+}
+
+// VM is an instance of a module
+type VM struct {
+	module    *Module
+	hosts     []*HostFunction
+	memory    []byte
+	maxPages  uint32
+	globals   []uint64
+	table     []int64
+	stack     []uint64
+	depth     int
+	steps     uint64
+	stepLimit uint64
+}
+
+// trap在解释器内部通过panic传递，在Invoke中恢复
+type trap struct {
+	err error
+}
+
+func throw(err error) {
+	panic(trap{err})
+}
+
+// Instantiate creates an instance of the module. The imports are keyed by
+// "module.name", stepLimit limits the steps of every invocation.
+func Instantiate(m *Module, imports map[string]*HostFunction, stepLimit uint64) (*VM, error) {
+	vm := &VM{module: m, stepLimit: stepLimit, maxPages: MaxPages}
+	for _, imp := range m.Imports {
+		host, ok := imports[imp.Module+"."+imp.Name]
+		if !ok {
+			return nil, fmt.Errorf("wasm: unresolved import %s.%s", imp.Module, imp.Name)
+		}
+		if !host.Type.Equal(&m.Types[imp.TypeIdx]) {
+			return nil, fmt.Errorf("wasm: import %s.%s type mismatch, %s != %s", imp.Module, imp.Name,
+				m.Types[imp.TypeIdx].String(), host.Type.String())
+		}
+		vm.hosts = append(vm.hosts, host)
+	}
+	if m.Memory != nil {
+		vm.memory = make([]byte, uint64(m.Memory.Min)*PageSize)
+		if m.Memory.HasMax && m.Memory.Max < vm.maxPages {
+			vm.maxPages = m.Memory.Max
+		}
+	}
+	for _, g := range m.Globals {
+		vm.globals = append(vm.globals, g.Init)
+	}
+	if m.Table != nil {
+		vm.table = make([]int64, m.Table.Min)
+		for i := range vm.table {
+			vm.table[i] = -1
+		}
+	}
+	for _, seg := range m.Elements {
+		if uint64(seg.Offset)+uint64(len(seg.Funcs)) > uint64(len(vm.table)) {
+			return nil, errors.New("wasm: element segment out of bounds")
+		}
+		for i, f := range seg.Funcs {
+			vm.table[seg.Offset+uint32(i)] = int64(f)
+		}
+	}
+	for _, seg := range m.Data {
+		if uint64(seg.Offset)+uint64(len(seg.Data)) > uint64(len(vm.memory)) {
+			return nil, errors.New("wasm: data segment out of bounds")
+		}
+		copy(vm.memory[seg.Offset:], seg.Data)
+	}
+	if m.Start >= 0 {
+		if _, err := vm.invoke(uint32(m.Start), nil); err != nil {
+			return nil, err
+		}
+	}
+	return vm, nil
+}
+
+// Invoke calls the exported function with the arguments, i32 arguments and
+// results are zero extended to uint64. The steps are counted from zero.
+func (vm *VM) Invoke(name string, args ...uint64) (uint64, error) {
+	e, ok := vm.module.Exports[name]
+	if !ok || e.Kind != ExternalFunction {
+		return 0, fmt.Errorf("wasm: function %s not exported", name)
+	}
+	vm.steps = 0
+	return vm.invoke(e.Index, args)
+}
+
+func (vm *VM) invoke(fidx uint32, args []uint64) (result uint64, err error) {
+	ft := vm.module.funcType(fidx)
+	if len(args) != len(ft.Params) {
+		return 0, fmt.Errorf("wasm: function needs %d arguments, got %d", len(ft.Params), len(args))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if t, ok := r.(trap); ok {
+				err = t.err
+			} else {
+				//畸形模块导致的操作数栈越界等
+				err = ErrInvalidStack
+			}
+			result = 0
+		}
+		vm.stack = vm.stack[:0]
+		vm.depth = 0
+	}()
+	vm.stack = vm.stack[:0]
+	for i, arg := range args {
+		if ft.Params[i] == ValueTypeI32 {
+			arg = uint64(uint32(arg))
+		}
+		vm.stack = append(vm.stack, arg)
+	}
+	vm.call(fidx)
+	if len(ft.Results) > 0 {
+		return vm.pop(), nil
+	}
+	return 0, nil
+}
+
+// Steps returns the steps used by the current invocation
+func (vm *VM) Steps() uint64 {
+	return vm.steps
+}
+
+// UseSteps charges steps for host functions
+func (vm *VM) UseSteps(n uint64) error {
+	vm.steps += n
+	if vm.steps > vm.stepLimit || vm.steps < n {
+		return ErrStepLimitExceeded
+	}
+	return nil
+}
+
+// ReadMemory returns a copy of the memory
+func (vm *VM) ReadMemory(ptr, size uint32) ([]byte, error) {
+	if uint64(ptr)+uint64(size) > uint64(len(vm.memory)) {
+		return nil, ErrOutOfBounds
+	}
+	data := make([]byte, size)
+	copy(data, vm.memory[ptr:])
+	return data, nil
+}
+
+// WriteMemory copies the data into the memory
+func (vm *VM) WriteMemory(ptr uint32, data []byte) error {
+	if uint64(ptr)+uint64(len(data)) > uint64(len(vm.memory)) {
+		return ErrOutOfBounds
+	}
+	copy(vm.memory[ptr:], data)
+	return nil
+}
+
+func (vm *VM) push(v uint64) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() uint64 {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) pushBool(b bool) {
+	if b {
+		vm.push(1)
+	} else {
+		vm.push(0)
+	}
+}
+
+func (vm *VM) call(fidx uint32) {
+	vm.depth++
+	if vm.depth > MaxCallDepth {
+		throw(ErrCallStackOverflow)
+	}
+	ft := vm.module.funcType(fidx)
+	n := len(ft.Params)
+	if n > len(vm.stack) {
+		throw(ErrInvalidStack)
+	}
+	if fidx < uint32(len(vm.hosts)) {
+		args := make([]uint64, n)
+		copy(args, vm.stack[len(vm.stack)-n:])
+		vm.stack = vm.stack[:len(vm.stack)-n]
+		r, err := vm.hosts[fidx].Fn(vm, args)
+		if err != nil {
+			throw(err)
+		}
+		if len(ft.Results) > 0 {
+			if ft.Results[0] == ValueTypeI32 {
+				r = uint64(uint32(r))
+			}
+			vm.push(r)
+		}
+	} else {
+		f := vm.module.Functions[fidx-uint32(len(vm.hosts))]
+		locals := make([]uint64, n+len(f.Locals))
+		copy(locals, vm.stack[len(vm.stack)-n:])
+		vm.stack = vm.stack[:len(vm.stack)-n]
+		vm.execute(f.body, locals, len(ft.Results))
+	}
+	vm.depth--
+}
+
+type label struct {
+	height int
+	arity  int
+	target int
+	isLoop bool
+}
+
+func (vm *VM) memAddr(base uint64, offset uint64, size uint64) uint64 {
+	addr := uint64(uint32(base)) + offset
+	if addr+size > uint64(len(vm.memory)) {
+		throw(ErrOutOfBounds)
+	}
+	return addr
+}
+
+func (vm *VM) execute(code []instr, locals []uint64, arity int) {
+	base := len(vm.stack)
+	labels := make([]label, 0, 8)
+	//跳转到第depth层label，返回新的pc；depth等于label数量时表示函数返回
+	branch := func(depth int) int {
+		if depth >= len(labels) {
+			return -1
+		}
+		l := labels[len(labels)-1-depth]
+		n := l.arity
+		if l.isLoop {
+			n = 0
+		}
+		copy(vm.stack[l.height:], vm.stack[len(vm.stack)-n:])
+		vm.stack = vm.stack[:l.height+n]
+		if l.isLoop {
+			labels = labels[:len(labels)-depth]
+			return l.target + 1
+		}
+		labels = labels[:len(labels)-1-depth]
+		return l.target + 1
+	}
+	pc := 0
+	for pc >= 0 && pc < len(code) {
+		vm.steps++
+		if vm.steps > vm.stepLimit {
+			throw(ErrStepLimitExceeded)
+		}
+		ins := &code[pc]
+		pc++
+		switch ins.op {
+		case opUnreachable:
+			throw(ErrUnreachable)
+		case opNop:
+		case opBlock:
+			labels = append(labels, label{height: len(vm.stack), arity: ins.arity, target: ins.end})
+		case opLoop:
+			labels = append(labels, label{height: len(vm.stack), arity: ins.arity, target: pc - 1, isLoop: true})
+		case opIf:
+			cond := uint32(vm.pop())
+			labels = append(labels, label{height: len(vm.stack), arity: ins.arity, target: ins.end})
+			if cond == 0 {
+				if ins.else_ >= 0 {
+					pc = ins.else_ + 1
+				} else {
+					pc = ins.end
+				}
+			}
+		case opElse:
+			//then分支执行完毕，跳到end
+			pc = ins.end
+		case opEnd:
+			if len(labels) == 0 {
+				pc = -1
+			} else {
+				labels = labels[:len(labels)-1]
+			}
+		case opBr:
+			pc = branch(int(ins.imm))
+		case opBrIf:
+			if uint32(vm.pop()) != 0 {
+				pc = branch(int(ins.imm))
+			}
+		case opBrTable:
+			i := uint32(vm.pop())
+			if i >= uint32(len(ins.table)-1) {
+				i = uint32(len(ins.table) - 1)
+			}
+			pc = branch(int(ins.table[i]))
+		case opReturn:
+			pc = -1
+		case opCall:
+			vm.call(uint32(ins.imm))
+		case opCallIndirect:
+			i := uint32(vm.pop())
+			if i >= uint32(len(vm.table)) || vm.table[i] < 0 {
+				throw(ErrUndefinedElement)
+			}
+			fidx := uint32(vm.table[i])
+			if !vm.module.funcType(fidx).Equal(&vm.module.Types[ins.imm]) {
+				throw(ErrIndirectCallType)
+			}
+			vm.call(fidx)
+		case opDrop:
+			vm.pop()
+		case opSelect:
+			c := uint32(vm.pop())
+			v2 := vm.pop()
+			v1 := vm.pop()
+			if c != 0 {
+				vm.push(v1)
+			} else {
+				vm.push(v2)
+			}
+		case opLocalGet:
+			vm.push(locals[ins.imm])
+		case opLocalSet:
+			locals[ins.imm] = vm.pop()
+		case opLocalTee:
+			locals[ins.imm] = vm.stack[len(vm.stack)-1]
+		case opGlobalGet:
+			vm.push(vm.globals[ins.imm])
+		case opGlobalSet:
+			vm.globals[ins.imm] = vm.pop()
+		case opI32Load:
+			a := vm.memAddr(vm.pop(), ins.imm, 4)
+			vm.push(uint64(binary.LittleEndian.Uint32(vm.memory[a:])))
+		case opI64Load:
+			a := vm.memAddr(vm.pop(), ins.imm, 8)
+			vm.push(binary.LittleEndian.Uint64(vm.memory[a:]))
+		case opI32Load8S:
+			a := vm.memAddr(vm.pop(), ins.imm, 1)
+			vm.push(uint64(uint32(int32(int8(vm.memory[a])))))
+		case opI32Load8U, opI64Load8U:
+			a := vm.memAddr(vm.pop(), ins.imm, 1)
+			vm.push(uint64(vm.memory[a]))
+		case opI32Load16S:
+			a := vm.memAddr(vm.pop(), ins.imm, 2)
+			vm.push(uint64(uint32(int32(int16(binary.LittleEndian.Uint16(vm.memory[a:]))))))
+		case opI32Load16U, opI64Load16U:
+			a := vm.memAddr(vm.pop(), ins.imm, 2)
+			vm.push(uint64(binary.LittleEndian.Uint16(vm.memory[a:])))
+		case opI64Load8S:
+			a := vm.memAddr(vm.pop(), ins.imm, 1)
+			vm.push(uint64(int64(int8(vm.memory[a]))))
+		case opI64Load16S:
+			a := vm.memAddr(vm.pop(), ins.imm, 2)
+			vm.push(uint64(int64(int16(binary.LittleEndian.Uint16(vm.memory[a:])))))
+		case opI64Load32S:
+			a := vm.memAddr(vm.pop(), ins.imm, 4)
+			vm.push(uint64(int64(int32(binary.LittleEndian.Uint32(vm.memory[a:])))))
+		case opI64Load32U:
+			a := vm.memAddr(vm.pop(), ins.imm, 4)
+			vm.push(uint64(binary.LittleEndian.Uint32(vm.memory[a:])))
+		case opI32Store, opI64Store32:
+			v := vm.pop()
+			a := vm.memAddr(vm.pop(), ins.imm, 4)
+			binary.LittleEndian.PutUint32(vm.memory[a:], uint32(v))
+		case opI64Store:
+			v := vm.pop()
+			a := vm.memAddr(vm.pop(), ins.imm, 8)
+			binary.LittleEndian.PutUint64(vm.memory[a:], v)
+		case opI32Store8, opI64Store8:
+			v := vm.pop()
+			a := vm.memAddr(vm.pop(), ins.imm, 1)
+			vm.memory[a] = byte(v)
+		case opI32Store16, opI64Store16:
+			v := vm.pop()
+			a := vm.memAddr(vm.pop(), ins.imm, 2)
+			binary.LittleEndian.PutUint16(vm.memory[a:], uint16(v))
+		case opMemorySize:
+			vm.push(uint64(len(vm.memory) / PageSize))
+		case opMemoryGrow:
+			n := uint32(vm.pop())
+			old := uint32(len(vm.memory) / PageSize)
+			if uint64(old)+uint64(n) > uint64(vm.maxPages) {
+				vm.push(uint64(uint32(0xffffffff)))
+			} else {
+				//按新增内存计费
+				if err := vm.UseSteps(uint64(n) * PageSize / 64); err != nil {
+					throw(err)
+				}
+				vm.memory = append(vm.memory, make([]byte, uint64(n)*PageSize)...)
+				vm.push(uint64(old))
+			}
+		case opI32Const, opI64Const:
+			vm.push(ins.imm)
+		default:
+			vm.numeric(ins.op)
+		}
+	}
+	//函数返回
+	if len(vm.stack) < base+arity {
+		throw(ErrInvalidStack)
+	}
+	copy(vm.stack[base:], vm.stack[len(vm.stack)-arity:])
+	vm.stack = vm.stack[:base+arity]
+}
+
+func (vm *VM) numeric(op byte) {
+	switch {
+	case op == opI32Eqz:
+		vm.pushBool(uint32(vm.pop()) == 0)
+	case op == opI64Eqz:
+		vm.pushBool(vm.pop() == 0)
+	case op >= opI32Eq && op <= opI32GeU:
+		b := uint32(vm.pop())
+		a := uint32(vm.pop())
+		vm.pushBool(compare32(op, a, b))
+	case op >= opI64Eq && op <= opI64GeU:
+		b := vm.pop()
+		a := vm.pop()
+		vm.pushBool(compare64(op, a, b))
+	case op == opI32Clz:
+		vm.push(uint64(bits.LeadingZeros32(uint32(vm.pop()))))
+	case op == opI32Ctz:
+		vm.push(uint64(bits.TrailingZeros32(uint32(vm.pop()))))
+	case op == opI32Popcnt:
+		vm.push(uint64(bits.OnesCount32(uint32(vm.pop()))))
+	case op >= opI32Add && op <= opI32Rotr:
+		b := uint32(vm.pop())
+		a := uint32(vm.pop())
+		vm.push(uint64(binary32(op, a, b)))
+	case op == opI64Clz:
+		vm.push(uint64(bits.LeadingZeros64(vm.pop())))
+	case op == opI64Ctz:
+		vm.push(uint64(bits.TrailingZeros64(vm.pop())))
+	case op == opI64Popcnt:
+		vm.push(uint64(bits.OnesCount64(vm.pop())))
+	case op >= opI64Add && op <= opI64Rotr:
+		b := vm.pop()
+		a := vm.pop()
+		vm.push(binary64(op, a, b))
+	case op == opI32WrapI64:
+		vm.push(uint64(uint32(vm.pop())))
+	case op == opI64ExtendI32S:
+		vm.push(uint64(int64(int32(uint32(vm.pop())))))
+	case op == opI64ExtendI32U:
+		vm.push(uint64(uint32(vm.pop())))
+	case op == opI32Extend8S:
+		vm.push(uint64(uint32(int32(int8(vm.pop())))))
+	case op == opI32Extend16S:
+		vm.push(uint64(uint32(int32(int16(vm.pop())))))
+	case op == opI64Extend8S:
+		vm.push(uint64(int64(int8(vm.pop()))))
+	case op == opI64Extend16S:
+		vm.push(uint64(int64(int16(vm.pop()))))
+	case op == opI64Extend32S:
+		vm.push(uint64(int64(int32(vm.pop()))))
+	default:
+		throw(fmt.Errorf("wasm: unsupported opcode 0x%x", op))
+	}
+}
+
+func compare32(op byte, a, b uint32) bool {
+	switch op {
+	case opI32Eq:
+		return a == b
+	case opI32Ne:
+		return a != b
+	case opI32LtS:
+		return int32(a) < int32(b)
+	case opI32LtU:
+		return a < b
+	case opI32GtS:
+		return int32(a) > int32(b)
+	case opI32GtU:
+		return a > b
+	case opI32LeS:
+		return int32(a) <= int32(b)
+	case opI32LeU:
+		return a <= b
+	case opI32GeS:
+		return int32(a) >= int32(b)
+	}
+	return a >= b
+}
+
+func compare64(op byte, a, b uint64) bool {
+	switch op {
+	case opI64Eq:
+		return a == b
+	case opI64Ne:
+		return a != b
+	case opI64LtS:
+		return int64(a) < int64(b)
+	case opI64LtU:
+		return a < b
+	case opI64GtS:
+		return int64(a) > int64(b)
+	case opI64GtU:
+		return a > b
+	case opI64LeS:
+		return int64(a) <= int64(b)
+	case opI64LeU:
+		return a <= b
+	case opI64GeS:
+		return int64(a) >= int64(b)
+	}
+	return a >= b
+}
+
+func binary32(op byte, a, b uint32) uint32 {
+	switch op {
+	case opI32Add:
+		return a + b
+	case opI32Sub:
+		return a - b
+	case opI32Mul:
+		return a * b
+	case opI32DivS:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		if int32(a) == -1<<31 && int32(b) == -1 {
+			throw(ErrIntegerOverflow)
+		}
+		return uint32(int32(a) / int32(b))
+	case opI32DivU:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		return a / b
+	case opI32RemS:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		if int32(b) == -1 {
+			return 0
+		}
+		return uint32(int32(a) % int32(b))
+	case opI32RemU:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		return a % b
+	case opI32And:
+		return a & b
+	case opI32Or:
+		return a | b
+	case opI32Xor:
+		return a ^ b
+	case opI32Shl:
+		return a << (b & 31)
+	case opI32ShrS:
+		return uint32(int32(a) >> (b & 31))
+	case opI32ShrU:
+		return a >> (b & 31)
+	case opI32Rotl:
+		return bits.RotateLeft32(a, int(b&31))
+	}
+	return bits.RotateLeft32(a, -int(b&31))
+}
+
+func binary64(op byte, a, b uint64) uint64 {
+	switch op {
+	case opI64Add:
+		return a + b
+	case opI64Sub:
+		return a - b
+	case opI64Mul:
+		return a * b
+	case opI64DivS:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		if int64(a) == -1<<63 && int64(b) == -1 {
+			throw(ErrIntegerOverflow)
+		}
+		return uint64(int64(a) / int64(b))
+	case opI64DivU:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		return a / b
+	case opI64RemS:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case opI64RemU:
+		if b == 0 {
+			throw(ErrDivideByZero)
+		}
+		return a % b
+	case opI64And:
+		return a & b
+	case opI64Or:
+		return a | b
+	case opI64Xor:
+		return a ^ b
+	case opI64Shl:
+		return a << (b & 63)
+	case opI64ShrS:
+		return uint64(int64(a) >> (b & 63))
+	case opI64ShrU:
+		return a >> (b & 63)
+	case opI64Rotl:
+		return bits.RotateLeft64(a, int(b&63))
+	}
+	return bits.RotateLeft64(a, -int(b&63))
+}
@@ -0,0 +1,181 @@
+/*
+	This file is part of go-palletone.
+	go-palletone is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+	go-palletone is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+	You should have received a copy of the GNU General Public License
+	along with go-palletone.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+ * @author PalletOne core developers <dev@pallet.one>
+ * @date 2019
+ */
+
+package wasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func section(id byte, content ...byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+func module(sections ...[]byte) []byte {
+	code := append([]byte{}, magic...)
+	code = append(code, version...)
+	for _, s := range sections {
+		code = append(code, s...)
+	}
+	return code
+}
+
+func codeSection(bodies ...[]byte) []byte {
+	content := []byte{byte(len(bodies))}
+	for _, b := range bodies {
+		content = append(content, b...)
+	}
+	return section(10, content...)
+}
+
+func body(locals []byte, code ...byte) []byte {
+	b := append(append([]byte{}, locals...), code...)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+// (func (export "fac") (param i64) (result i64) 循环计算阶乘)
+// (func (export "div") (param i32 i32) (result i32) i32.div_s)
+// (func (export "loop") 死循环)
+// (func (export "store") (param i32 i32) 写内存)
+// (func (export "host") (param i32) (result i32) call $env.double)
+var testModule = module(
+	section(1, 5,
+		0x60, 1, 0x7e, 1, 0x7e, //0: i64->i64
+		0x60, 2, 0x7f, 0x7f, 1, 0x7f, //1: i32,i32->i32
+		0x60, 0, 0, //2: ->
+		0x60, 1, 0x7f, 1, 0x7f, //3: i32->i32
+		0x60, 2, 0x7f, 0x7f, 0, //4: i32,i32->
+	),
+	section(2, 1, 3, 'e', 'n', 'v', 6, 'd', 'o', 'u', 'b', 'l', 'e', 0, 3),
+	section(3, 5, 0, 1, 2, 4, 3),
+	section(5, 1, 0, 1),
+	section(7, 5,
+		3, 'f', 'a', 'c', 0, 1,
+		3, 'd', 'i', 'v', 0, 2,
+		4, 'l', 'o', 'o', 'p', 0, 3,
+		5, 's', 't', 'o', 'r', 'e', 0, 4,
+		4, 'h', 'o', 's', 't', 0, 5,
+	),
+	codeSection(
+		body([]byte{1, 1, 0x7e},
+			0x42, 1, 0x21, 1, //result=1
+			0x02, 0x40, //block
+			0x03, 0x40, //loop
+			0x20, 0, 0x50, 0x0d, 1, //n==0 br 1
+			0x20, 1, 0x20, 0, 0x7e, 0x21, 1, //result*=n
+			0x20, 0, 0x42, 1, 0x7d, 0x21, 0, //n--
+			0x0c, 0, //br 0
+			0x0b, 0x0b,
+			0x20, 1, 0x0b),
+		body([]byte{0}, 0x20, 0, 0x20, 1, 0x6d, 0x0b),
+		body([]byte{0}, 0x03, 0x40, 0x0c, 0, 0x0b, 0x0b),
+		body([]byte{0}, 0x20, 0, 0x20, 1, 0x36, 2, 0, 0x0b),
+		body([]byte{0}, 0x20, 0, 0x10, 0, 0x41, 1, 0x6a, 0x0b),
+	),
+)
+
+func newTestVM(t *testing.T, stepLimit uint64) *VM {
+	m, err := DecodeModule(testModule)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	imports := map[string]*HostFunction{
+		"env.double": {
+			Type: FuncType{Params: []ValueType{ValueTypeI32}, Results: []ValueType{ValueTypeI32}},
+			Fn: func(vm *VM, args []uint64) (uint64, error) {
+				return args[0] * 2, nil
+			},
+		},
+	}
+	vm, err := Instantiate(m, imports, stepLimit)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return vm
+}
+
+func TestVM_Invoke(t *testing.T) {
+	vm := newTestVM(t, 100000)
+	r, err := vm.Invoke("fac", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3628800), r)
+
+	r, err = vm.Invoke("div", uint64(uint32(0xfffffff6)), 3) //-10/3
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(uint32(0xfffffffd)), r)
+	_, err = vm.Invoke("div", 1, 0)
+	assert.Equal(t, ErrDivideByZero, err)
+	_, err = vm.Invoke("div", 0x80000000, 0xffffffff)
+	assert.Equal(t, ErrIntegerOverflow, err)
+
+	r, err = vm.Invoke("host", 20)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(41), r)
+
+	_, err = vm.Invoke("store", 8, 0x04030201)
+	assert.Nil(t, err)
+	data, err := vm.ReadMemory(8, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, data)
+	_, err = vm.Invoke("store", PageSize-2, 1)
+	assert.Equal(t, ErrOutOfBounds, err)
+
+	_, err = vm.Invoke("nothing")
+	assert.NotNil(t, err)
+}
+
+func TestVM_StepLimit(t *testing.T) {
+	vm := newTestVM(t, 1000)
+	_, err := vm.Invoke("loop")
+	assert.Equal(t, ErrStepLimitExceeded, err)
+	assert.True(t, vm.Steps() > 1000)
+
+	//相同的输入消耗相同的步数
+	_, err = vm.Invoke("fac", 5)
+	assert.Nil(t, err)
+	steps := vm.Steps()
+	_, err = vm.Invoke("fac", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, steps, vm.Steps())
+}
+
+func TestDecodeModule_Invalid(t *testing.T) {
+	_, err := DecodeModule([]byte{0, 1, 2, 3})
+	assert.Equal(t, ErrInvalidMagic, err)
+	//f32参数
+	_, err = DecodeModule(module(section(1, 1, 0x60, 1, 0x7d, 0)))
+	assert.Equal(t, ErrFloatUnsupported, err)
+	//函数体中使用f32.const
+	_, err = DecodeModule(module(
+		section(1, 1, 0x60, 0, 0),
+		section(3, 1, 0),
+		section(10, 1, 7, 0, 0x43, 0, 0, 0, 0, 0x0b),
+	))
+	assert.Equal(t, ErrFloatUnsupported, err)
+	//未解析的导入
+	m, err := DecodeModule(module(
+		section(1, 1, 0x60, 0, 0),
+		section(2, 1, 3, 'e', 'n', 'v', 1, 'x', 0, 0),
+	))
+	assert.Nil(t, err)
+	_, err = Instantiate(m, nil, 100)
+	assert.NotNil(t, err)
+}